@@ -2,4 +2,9 @@ module github.com/lehigh-university-libraries/hOCRedit
 
 go 1.24.3
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/gorilla/websocket v1.5.3
+	github.com/joho/godotenv v1.5.1
+	golang.org/x/image v0.28.0
+	golang.org/x/text v0.26.0
+)
@@ -0,0 +1,73 @@
+// Package accessibility checks exported documents against the basic criteria our
+// accessibility office reviews before distribution: a declared language, a
+// defined reading order, and alternative text for image regions.
+package accessibility
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Status string
+
+const (
+	Pass          Status = "pass"
+	Fail          Status = "fail"
+	NotApplicable Status = "not_applicable"
+)
+
+const (
+	CriterionLanguage     = "language"
+	CriterionReadingOrder = "reading_order"
+	CriterionAltText      = "alt_text"
+)
+
+// Finding is the outcome of one criterion
+type Finding struct {
+	Criterion string `json:"criterion"`
+	Status    Status `json:"status"`
+	Detail    string `json:"detail"`
+}
+
+// Report is the conformance report attached to an export
+type Report struct {
+	Format   string    `json:"format"`
+	Conforms bool      `json:"conforms"`
+	Findings []Finding `json:"findings"`
+}
+
+func newReport(format string, findings ...Finding) Report {
+	report := Report{Format: format, Conforms: true, Findings: findings}
+	for _, finding := range findings {
+		if finding.Status == Fail {
+			report.Conforms = false
+		}
+	}
+	return report
+}
+
+// Check evaluates an export in the given format: html (including hOCR), epub or pdf
+func Check(format string, data []byte) (Report, error) {
+	switch strings.ToLower(format) {
+	case "html", "xhtml", "hocr":
+		return CheckHTML(data)
+	case "epub":
+		return CheckEPUB(data)
+	case "pdf":
+		return CheckPDF(data), nil
+	}
+	return Report{}, fmt.Errorf("unsupported format: %s", format)
+}
+
+// FormatFor picks the checker for a content type, or "" when none applies
+func FormatFor(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "epub"):
+		return "epub"
+	case strings.Contains(contentType, "pdf"):
+		return "pdf"
+	case strings.Contains(contentType, "html"):
+		return "html"
+	}
+	return ""
+}
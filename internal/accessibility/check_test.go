@@ -0,0 +1,120 @@
+package accessibility
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+const orderedHOCR = `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en">
+<body>
+<div class='ocr_page' title='bbox 0 0 1000 1000'>
+<p class='ocr_par'>
+<span class='ocr_line' id='line_1' title='bbox 10 10 500 40'><span class='ocrx_word'>First</span></span>
+<span class='ocr_line' id='line_2' title='bbox 10 50 500 80'><span class='ocrx_word'>Second</span></span>
+</p>
+<p class='ocr_par'>
+<span class='ocr_line' id='line_3' title='bbox 510 10 900 40'><span class='ocrx_word'>Column</span></span>
+</p>
+<div class='ocr_photo' title='bbox 10 100 400 400' aria-label='Portrait of the founder'></div>
+</div>
+</body>
+</html>`
+
+const disorderedHOCR = `<html>
+<body>
+<p class='ocr_par'>
+<span class='ocr_line' title='bbox 10 50 500 80'>Second</span>
+<span class='ocr_line' title='bbox 10 10 500 40'>First</span>
+</p>
+<img src="figure.png">
+</body>
+</html>`
+
+func statuses(report Report) map[string]Status {
+	result := map[string]Status{}
+	for _, finding := range report.Findings {
+		result[finding.Criterion] = finding.Status
+	}
+	return result
+}
+
+func TestCheckHTML(t *testing.T) {
+	report, err := CheckHTML([]byte(orderedHOCR))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Conforms {
+		t.Errorf("expected conformance, got %+v", report.Findings)
+	}
+
+	report, err = CheckHTML([]byte(disorderedHOCR))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := statuses(report)
+	for _, criterion := range []string{CriterionLanguage, CriterionReadingOrder, CriterionAltText} {
+		if got[criterion] != Fail {
+			t.Errorf("%s: got %s, want fail", criterion, got[criterion])
+		}
+	}
+	if report.Conforms {
+		t.Error("expected the report not to conform")
+	}
+}
+
+func TestValidLanguageTag(t *testing.T) {
+	for tag, want := range map[string]bool{
+		"en": true, "de-1901": true, "zh-Hant-TW": true, "": false, "engl": false, "en-": false, "1en": false,
+	} {
+		if got := validLanguageTag(tag); got != want {
+			t.Errorf("validLanguageTag(%q) = %v, want %v", tag, got, want)
+		}
+	}
+}
+
+func TestCheckEPUB(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	files := map[string]string{
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`,
+		"OEBPS/content.opf": `<package><metadata><dc:language xmlns:dc="http://purl.org/dc/elements/1.1/">en</dc:language></metadata>
+<manifest><item id="p1" href="page1.xhtml"/><item id="p2" href="page2.xhtml"/></manifest>
+<spine><itemref idref="p1"/><itemref idref="p2"/></spine></package>`,
+		"OEBPS/page1.xhtml": orderedHOCR,
+		"OEBPS/page2.xhtml": `<html><body><p>No language</p></body></html>`,
+	}
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	archive.Close()
+
+	report, err := CheckEPUB(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := statuses(report)
+	if got[CriterionLanguage] != Fail || got[CriterionReadingOrder] != Pass || got[CriterionAltText] != Pass {
+		t.Errorf("unexpected findings: %+v", report.Findings)
+	}
+}
+
+func TestCheckPDF(t *testing.T) {
+	untagged := []byte("%PDF-1.4\n1 0 obj << /Type /XObject /Subtype /Image >> endobj")
+	got := statuses(CheckPDF(untagged))
+	if got[CriterionLanguage] != Fail || got[CriterionReadingOrder] != Fail || got[CriterionAltText] != Fail {
+		t.Errorf("untagged PDF: %+v", got)
+	}
+
+	tagged := []byte("%PDF-1.7\n1 0 obj << /Type /Catalog /Lang (en-US) /MarkInfo << /Marked true >> /StructTreeRoot 2 0 R >> endobj\n" +
+		"3 0 obj << /S /Figure /Alt (Map of campus) >> endobj\n4 0 obj << /Subtype /Image >> endobj")
+	report := CheckPDF(tagged)
+	if !report.Conforms {
+		t.Errorf("tagged PDF: %+v", report.Findings)
+	}
+}
@@ -0,0 +1,119 @@
+package accessibility
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Languages []string `xml:"metadata>language"`
+	Manifest  []struct {
+		ID   string `xml:"id,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// CheckEPUB evaluates the package metadata and every content document in the spine
+func CheckEPUB(data []byte) (Report, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to open EPUB: %w", err)
+	}
+
+	var container epubContainer
+	if err := readZipXML(archive, "META-INF/container.xml", &container); err != nil {
+		return Report{}, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return Report{}, fmt.Errorf("EPUB container names no package document")
+	}
+
+	packagePath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	if err := readZipXML(archive, packagePath, &pkg); err != nil {
+		return Report{}, err
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = path.Join(path.Dir(packagePath), item.Href)
+	}
+
+	// The spine is the EPUB's reading order, so each document is checked in turn
+	var total htmlScan
+	missingLang := 0
+	for _, itemref := range pkg.Spine {
+		href, ok := hrefs[itemref.IDRef]
+		if !ok {
+			return Report{}, fmt.Errorf("EPUB spine references unknown item %s", itemref.IDRef)
+		}
+		content, err := readZipFile(archive, href)
+		if err != nil {
+			return Report{}, err
+		}
+		scan, err := scanHTML(content)
+		if err != nil {
+			return Report{}, fmt.Errorf("%s: %w", href, err)
+		}
+		if !validLanguageTag(scan.lang) {
+			missingLang++
+		}
+		total.images += scan.images
+		total.missingAlt += scan.missingAlt
+		total.lines += scan.lines
+		total.outOfOrder += scan.outOfOrder
+	}
+
+	language := Finding{Criterion: CriterionLanguage, Status: Pass}
+	switch {
+	case len(pkg.Languages) == 0 || !validLanguageTag(strings.TrimSpace(pkg.Languages[0])):
+		language.Status = Fail
+		language.Detail = "package metadata does not declare dc:language"
+	case missingLang > 0:
+		language.Status = Fail
+		language.Detail = fmt.Sprintf("%d of %d content documents do not declare a language", missingLang, len(pkg.Spine))
+	default:
+		language.Detail = "publication language is " + strings.TrimSpace(pkg.Languages[0])
+	}
+
+	readingOrder := total.readingOrderFinding()
+	if len(pkg.Spine) == 0 {
+		readingOrder = Finding{Criterion: CriterionReadingOrder, Status: Fail, Detail: "package has no spine"}
+	}
+
+	return newReport("epub", language, readingOrder, total.altTextFinding()), nil
+}
+
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("EPUB is missing %s: %w", name, err)
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func readZipXML(archive *zip.Reader, name string, v any) error {
+	data, err := readZipFile(archive, name)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package accessibility
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// hOCR classes that group lines, so reading order is checked within each one
+var blockClasses = []string{"ocr_carea", "ocr_par", "ocr_column"}
+
+// hOCR classes for lines of text
+var lineClasses = []string{"ocr_line", "ocrx_line", "ocr_header", "ocr_caption", "ocr_textfloat"}
+
+// hOCR classes for regions that hold pictures rather than text
+var imageClasses = []string{"ocr_image", "ocr_photo", "ocr_linedrawing", "ocr_graphic"}
+
+// htmlScan collects what the criteria need from one HTML document
+type htmlScan struct {
+	lang       string
+	images     int
+	missingAlt int
+	lines      int
+	outOfOrder int
+}
+
+type lineBox struct {
+	top, height int
+}
+
+// CheckHTML evaluates an HTML or hOCR document
+func CheckHTML(data []byte) (Report, error) {
+	scan, err := scanHTML(data)
+	if err != nil {
+		return Report{}, err
+	}
+	return newReport("html", scan.languageFinding(), scan.readingOrderFinding(), scan.altTextFinding()), nil
+}
+
+func scanHTML(data []byte) (htmlScan, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var scan htmlScan
+	// blocks holds the last line seen in each open element; only line-grouping
+	// elements start a fresh comparison
+	blocks := []*lineBox{nil}
+	var opened []bool
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return scan, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			class := attr(element, "class")
+			isBlock := hasClass(class, blockClasses)
+			opened = append(opened, isBlock)
+			if isBlock {
+				blocks = append(blocks, nil)
+			}
+
+			switch {
+			case element.Name.Local == "html":
+				scan.lang = attr(element, "lang")
+			case element.Name.Local == "img":
+				scan.images++
+				if !hasAttr(element, "alt") {
+					scan.missingAlt++
+				}
+			case hasClass(class, imageClasses):
+				scan.images++
+				if attr(element, "aria-label") == "" && attr(element, "alt") == "" {
+					scan.missingAlt++
+				}
+			case hasClass(class, lineClasses):
+				box, ok := titleBox(attr(element, "title"))
+				if !ok {
+					continue
+				}
+				scan.lines++
+				last := blocks[len(blocks)-1]
+				// Lines on the same baseline can overlap, so allow half a line of slack
+				if last != nil && box.top < last.top-last.height/2 {
+					scan.outOfOrder++
+				}
+				blocks[len(blocks)-1] = &box
+			}
+		case xml.EndElement:
+			if len(opened) == 0 {
+				continue
+			}
+			if opened[len(opened)-1] && len(blocks) > 1 {
+				blocks = blocks[:len(blocks)-1]
+			}
+			opened = opened[:len(opened)-1]
+		}
+	}
+
+	return scan, nil
+}
+
+func (s htmlScan) languageFinding() Finding {
+	if !validLanguageTag(s.lang) {
+		return Finding{Criterion: CriterionLanguage, Status: Fail, Detail: "document does not declare a language"}
+	}
+	return Finding{Criterion: CriterionLanguage, Status: Pass, Detail: "document language is " + s.lang}
+}
+
+func (s htmlScan) readingOrderFinding() Finding {
+	switch {
+	case s.lines == 0:
+		return Finding{Criterion: CriterionReadingOrder, Status: Pass, Detail: "reading order follows document order"}
+	case s.outOfOrder > 0:
+		return Finding{Criterion: CriterionReadingOrder, Status: Fail,
+			Detail: fmt.Sprintf("%d of %d lines start above the line before them in the same block", s.outOfOrder, s.lines)}
+	}
+	return Finding{Criterion: CriterionReadingOrder, Status: Pass, Detail: fmt.Sprintf("%d lines run top to bottom within their blocks", s.lines)}
+}
+
+func (s htmlScan) altTextFinding() Finding {
+	switch {
+	case s.images == 0:
+		return Finding{Criterion: CriterionAltText, Status: NotApplicable, Detail: "no image regions"}
+	case s.missingAlt > 0:
+		return Finding{Criterion: CriterionAltText, Status: Fail, Detail: fmt.Sprintf("%d of %d image regions have no alternative text", s.missingAlt, s.images)}
+	}
+	return Finding{Criterion: CriterionAltText, Status: Pass, Detail: fmt.Sprintf("all %d image regions have alternative text", s.images)}
+}
+
+// validLanguageTag accepts BCP 47 shaped tags such as en, de-1901 or zh-Hant-TW
+func validLanguageTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for i, part := range strings.Split(tag, "-") {
+		if part == "" || len(part) > 8 || (i == 0 && len(part) > 3 && len(part) < 5) {
+			return false
+		}
+		for _, r := range part {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+func hasAttr(element xml.StartElement, name string) bool {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasClass(class string, classes []string) bool {
+	for _, c := range strings.Fields(class) {
+		if slices.Contains(classes, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// titleBox reads the bbox property of an hOCR title attribute
+func titleBox(title string) (lineBox, bool) {
+	for _, property := range strings.Split(title, ";") {
+		fields := strings.Fields(property)
+		if len(fields) != 5 || fields[0] != "bbox" {
+			continue
+		}
+		var coords [4]int
+		for i, field := range fields[1:] {
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return lineBox{}, false
+			}
+			coords[i] = value
+		}
+		return lineBox{top: coords[1], height: coords[3] - coords[1]}, true
+	}
+	return lineBox{}, false
+}
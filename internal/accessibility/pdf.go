@@ -0,0 +1,54 @@
+package accessibility
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+var (
+	pdfLang    = regexp.MustCompile(`/Lang\s*\(([^)]*)\)`)
+	pdfMarked  = regexp.MustCompile(`/Marked\s+true`)
+	pdfImage   = regexp.MustCompile(`/Subtype\s*/Image\b`)
+	pdfFigure  = regexp.MustCompile(`/S\s*/Figure\b`)
+	pdfAltText = regexp.MustCompile(`/Alt\s*\(`)
+	pdfObjStm  = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+)
+
+// CheckPDF looks for the tagged-PDF structures the criteria depend on: a catalog
+// /Lang, a structure tree for reading order, and /Alt on figures. Dictionaries
+// inside compressed object streams can't be seen, which the details call out.
+func CheckPDF(data []byte) Report {
+	compressed := pdfObjStm.Match(data)
+	hidden := ""
+	if compressed {
+		hidden = " (some objects are compressed and were not inspected)"
+	}
+
+	language := Finding{Criterion: CriterionLanguage, Status: Fail, Detail: "document catalog has no /Lang" + hidden}
+	if match := pdfLang.FindSubmatch(data); match != nil && validLanguageTag(string(match[1])) {
+		language = Finding{Criterion: CriterionLanguage, Status: Pass, Detail: "document language is " + string(match[1])}
+	}
+
+	tagged := bytes.Contains(data, []byte("/StructTreeRoot")) && pdfMarked.Match(data)
+	readingOrder := Finding{Criterion: CriterionReadingOrder, Status: Fail, Detail: "PDF is not tagged, so it has no logical reading order" + hidden}
+	if tagged {
+		readingOrder = Finding{Criterion: CriterionReadingOrder, Status: Pass, Detail: "tagged PDF structure tree defines the reading order"}
+	}
+
+	images := len(pdfImage.FindAllIndex(data, -1))
+	figures := len(pdfFigure.FindAllIndex(data, -1))
+	alts := len(pdfAltText.FindAllIndex(data, -1))
+	altText := Finding{Criterion: CriterionAltText, Status: NotApplicable, Detail: "no images"}
+	switch {
+	case images == 0 && figures == 0:
+	case !tagged || figures == 0:
+		altText = Finding{Criterion: CriterionAltText, Status: Fail, Detail: fmt.Sprintf("%d images but no figure tags to carry alternative text", images)}
+	case alts < figures:
+		altText = Finding{Criterion: CriterionAltText, Status: Fail, Detail: fmt.Sprintf("%d of %d figures have no /Alt", figures-alts, figures)}
+	default:
+		altText = Finding{Criterion: CriterionAltText, Status: Pass, Detail: fmt.Sprintf("all %d figures have /Alt", figures)}
+	}
+
+	return newReport("pdf", language, readingOrder, altText)
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// APIKeyHeader carries an API key; keys are also accepted as bearer tokens
+const APIKeyHeader = "X-API-Key"
+
+// ErrUnauthenticated is returned when credentials were presented but are not valid
+var ErrUnauthenticated = errors.New("invalid credentials")
+
+// Identity is who a request was authenticated as
+type Identity struct {
+	User   string
+	Roles  []string
+	Method string
+}
+
+// Authenticator identifies the caller from one kind of credential. It reports
+// ok=false when the request carries no credential of its kind, and an error when
+// it carries one that is not valid.
+type Authenticator interface {
+	Name() string
+	Authenticate(r *http.Request) (identity Identity, ok bool, err error)
+}
+
+// Authenticators are tried in order until one recognizes the request's
+// credentials. A nil list leaves the API open to anonymous users.
+type Authenticators []Authenticator
+
+// NewAuthenticators enables the methods listed in AUTH_PROVIDERS: proxy (trust
+// X-Remote-User from the reverse proxy), apikey (API_KEYS_FILE) and oidc (JWT
+// bearer tokens from OIDC_ISSUER). Misconfigured methods stop the server, since
+// skipping one would lock out its users or, worse, leave routes open.
+func NewAuthenticators() (Authenticators, error) {
+	names := os.Getenv("AUTH_PROVIDERS")
+	if names == "" {
+		return nil, nil
+	}
+
+	var authenticators Authenticators
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		switch name {
+		case "":
+			continue
+		case "proxy":
+			authenticators = append(authenticators, proxyAuthenticator{})
+		case "apikey":
+			keys, err := LoadAPIKeys(os.Getenv("API_KEYS_FILE"))
+			if err != nil {
+				return nil, err
+			}
+			authenticators = append(authenticators, keys)
+		case "oidc":
+			verifier, err := NewOIDCVerifier(os.Getenv("OIDC_ISSUER"), os.Getenv("OIDC_AUDIENCE"))
+			if err != nil {
+				return nil, err
+			}
+			authenticators = append(authenticators, verifier)
+		default:
+			return nil, fmt.Errorf("unknown auth provider: %s", name)
+		}
+	}
+
+	enabled := make([]string, len(authenticators))
+	for i, authenticator := range authenticators {
+		enabled[i] = authenticator.Name()
+	}
+	slog.Info("API authentication enabled", "providers", enabled)
+	return authenticators, nil
+}
+
+// TrustsProxy reports whether X-Remote-User headers come from a trusted proxy,
+// which is only when the proxy method is enabled. When they don't, the headers
+// must be dropped so clients can't claim a user.
+func (a Authenticators) TrustsProxy() bool {
+	for _, authenticator := range a {
+		if _, ok := authenticator.(proxyAuthenticator); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate returns the identity from the first method that recognizes the
+// request's credentials
+func (a Authenticators) Authenticate(r *http.Request) (Identity, bool, error) {
+	for _, authenticator := range a {
+		identity, ok, err := authenticator.Authenticate(r)
+		if err != nil {
+			return Identity{}, false, fmt.Errorf("%s: %w", authenticator.Name(), err)
+		}
+		if ok {
+			identity.Method = authenticator.Name()
+			return identity, true, nil
+		}
+	}
+	return Identity{}, false, nil
+}
+
+// bearerToken returns the token from an Authorization: Bearer header
+func bearerToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// looksLikeJWT tells JWTs apart from API keys sent as bearer tokens
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+type proxyAuthenticator struct{}
+
+func (proxyAuthenticator) Name() string { return "proxy" }
+
+func (proxyAuthenticator) Authenticate(r *http.Request) (Identity, bool, error) {
+	user := r.Header.Get(UserHeader)
+	if user == "" {
+		return Identity{}, false, nil
+	}
+	return Identity{User: user}, true, nil
+}
+
+// APIKey is one entry of the API keys file. Only the SHA-256 of the key is
+// stored, so the file doesn't hold usable secrets.
+type APIKey struct {
+	Name   string   `json:"name"`
+	SHA256 string   `json:"sha256"`
+	Roles  []string `json:"roles"`
+}
+
+// APIKeys authenticates scripts and integrations by static key. The key's name
+// becomes the user and its roles are checked against the permissions policy.
+type APIKeys struct {
+	keys []APIKey
+}
+
+// LoadAPIKeys reads a JSON list of API keys
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	if path == "" {
+		return nil, fmt.Errorf("API_KEYS_FILE is required for the apikey auth provider")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys file: %w", err)
+	}
+	for i, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("API key %d has no name", i)
+		}
+		if decoded, err := hex.DecodeString(key.SHA256); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("API key %s: sha256 must be a hex SHA-256 digest", key.Name)
+		}
+		keys[i].SHA256 = strings.ToLower(key.SHA256)
+	}
+
+	return &APIKeys{keys: keys}, nil
+}
+
+// HashAPIKey is the digest stored in the API keys file for a key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (k *APIKeys) Name() string { return "apikey" }
+
+func (k *APIKeys) Authenticate(r *http.Request) (Identity, bool, error) {
+	presented := r.Header.Get(APIKeyHeader)
+	if token := bearerToken(r); presented == "" && token != "" && !looksLikeJWT(token) {
+		presented = token
+	}
+	if presented == "" {
+		return Identity{}, false, nil
+	}
+
+	digest := HashAPIKey(presented)
+	for _, key := range k.keys {
+		if subtle.ConstantTimeCompare([]byte(digest), []byte(key.SHA256)) == 1 {
+			return Identity{User: key.Name, Roles: key.Roles}, true, nil
+		}
+	}
+	return Identity{}, false, ErrUnauthenticated
+}
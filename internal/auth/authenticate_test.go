@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	data := fmt.Sprintf(`[{"name": "drupal", "sha256": %q, "roles": ["librarian"]}]`, HashAPIKey("s3cret"))
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadAPIKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	if _, ok, err := keys.Authenticate(r); ok || err != nil {
+		t.Errorf("no key should be ignored, got ok=%v err=%v", ok, err)
+	}
+
+	r.Header.Set(APIKeyHeader, "s3cret")
+	identity, ok, err := keys.Authenticate(r)
+	if !ok || err != nil || identity.User != "drupal" || identity.Roles[0] != "librarian" {
+		t.Errorf("unexpected identity %+v ok=%v err=%v", identity, ok, err)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer s3cret")
+	if _, ok, _ := keys.Authenticate(r); !ok {
+		t.Error("keys should be accepted as bearer tokens")
+	}
+
+	r.Header.Set("Authorization", "Bearer wrong")
+	if _, _, err := keys.Authenticate(r); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
+
+func TestLoadAPIKeysRejectsPlaintext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "drupal", "sha256": "s3cret"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAPIKeys(path); err == nil {
+		t.Error("expected an error for a key that is not a digest")
+	}
+}
+
+func TestAuthenticatorsTrustProxy(t *testing.T) {
+	if Authenticators(nil).TrustsProxy() {
+		t.Error("proxy headers must not be trusted when no provider is configured")
+	}
+	if (Authenticators{&APIKeys{}}).TrustsProxy() {
+		t.Error("proxy headers must not be trusted unless the proxy provider is enabled")
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(UserHeader, "jdoe")
+	identity, ok, err := Authenticators{&APIKeys{}, proxyAuthenticator{}}.Authenticate(r)
+	if !ok || err != nil || identity.User != "jdoe" || identity.Method != "proxy" {
+		t.Errorf("unexpected identity %+v ok=%v err=%v", identity, ok, err)
+	}
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"jwks_uri": server.URL + "/jwks"})
+		case "/jwks":
+			json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+				"kty": "RSA", "kid": "k1", "use": "sig",
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("OIDC_JWKS_URL", "")
+	t.Setenv("OIDC_USER_CLAIM", "")
+	t.Setenv("OIDC_ROLES_CLAIM", "")
+	verifier, err := NewOIDCVerifier(server.URL, "hocredit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := map[string]any{
+		"iss": server.URL, "aud": []string{"hocredit"}, "sub": "u-1", "preferred_username": "jdoe",
+		"roles": []string{"librarian"}, "exp": time.Now().Add(time.Hour).Unix(),
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+signToken(t, key, "k1", valid))
+	identity, ok, err := verifier.Authenticate(r)
+	if !ok || err != nil || identity.User != "jdoe" || len(identity.Roles) != 1 || identity.Roles[0] != "librarian" {
+		t.Fatalf("unexpected identity %+v ok=%v err=%v", identity, ok, err)
+	}
+
+	invalid := map[string]map[string]any{
+		"expired":        {"exp": time.Now().Add(-time.Hour).Unix()},
+		"wrong audience": {"aud": "other"},
+		"wrong issuer":   {"iss": "https://evil.example.com"},
+	}
+	for name, override := range invalid {
+		claims := map[string]any{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		for k, v := range override {
+			claims[k] = v
+		}
+		if _, err := verifier.Verify(signToken(t, key, "k1", claims), time.Now()); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, err := verifier.Verify(signToken(t, other, "k1", valid), time.Now()); err == nil {
+		t.Error("expected an error for a token signed by another key")
+	}
+	if _, err := verifier.Verify(signToken(t, key, "k2", valid), time.Now()); err == nil {
+		t.Error("expected an error for an unknown key ID")
+	}
+}
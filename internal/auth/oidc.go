@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockSkew is how far token times may be off from the server's clock
+const clockSkew = time.Minute
+
+// jwksRefreshInterval limits how often an unknown key ID triggers a JWKS fetch
+const jwksRefreshInterval = time.Minute
+
+var oidcClient = &http.Client{Timeout: 10 * time.Second}
+
+// OIDCVerifier authenticates JWT bearer tokens signed by an OpenID Connect
+// provider. Signing keys come from the provider's JWKS and are refetched when a
+// token names a key that isn't known yet, so key rotation needs no restart.
+type OIDCVerifier struct {
+	issuer     string
+	audience   string
+	jwksURL    string
+	userClaim  string
+	rolesClaim string
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewOIDCVerifier discovers the JWKS of an issuer. OIDC_JWKS_URL skips discovery,
+// and OIDC_USER_CLAIM (default preferred_username, falling back to sub) and
+// OIDC_ROLES_CLAIM (default roles) pick the claims identifying the caller.
+func NewOIDCVerifier(issuer, audience string) (*OIDCVerifier, error) {
+	if issuer == "" || audience == "" {
+		return nil, fmt.Errorf("OIDC_ISSUER and OIDC_AUDIENCE are required for the oidc auth provider")
+	}
+
+	v := &OIDCVerifier{
+		issuer:     strings.TrimSuffix(issuer, "/"),
+		audience:   audience,
+		jwksURL:    os.Getenv("OIDC_JWKS_URL"),
+		userClaim:  os.Getenv("OIDC_USER_CLAIM"),
+		rolesClaim: os.Getenv("OIDC_ROLES_CLAIM"),
+	}
+	if v.userClaim == "" {
+		v.userClaim = "preferred_username"
+	}
+	if v.rolesClaim == "" {
+		v.rolesClaim = "roles"
+	}
+
+	if v.jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := getJSON(v.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, fmt.Errorf("failed to discover OIDC configuration: %w", err)
+		}
+		if discovery.JWKSURI == "" {
+			return nil, fmt.Errorf("OIDC configuration for %s has no jwks_uri", v.issuer)
+		}
+		v.jwksURL = discovery.JWKSURI
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.refreshKeys(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *OIDCVerifier) Name() string { return "oidc" }
+
+func (v *OIDCVerifier) Authenticate(r *http.Request) (Identity, bool, error) {
+	token := bearerToken(r)
+	if token == "" || !looksLikeJWT(token) {
+		return Identity{}, false, nil
+	}
+
+	claims, err := v.Verify(token, time.Now())
+	if err != nil {
+		return Identity{}, false, err
+	}
+
+	user, _ := claims[v.userClaim].(string)
+	if user == "" {
+		user, _ = claims["sub"].(string)
+	}
+	if user == "" {
+		return Identity{}, false, fmt.Errorf("token has no %s or sub claim", v.userClaim)
+	}
+	return Identity{User: user, Roles: claimStrings(claims[v.rolesClaim])}, true, nil
+}
+
+// Verify checks a token's signature, issuer, audience and validity period and
+// returns its claims
+func (v *OIDCVerifier) Verify(token string, now time.Time) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != v.issuer {
+		return nil, fmt.Errorf("token issued by %q, expected %q", iss, v.issuer)
+	}
+	if !slices.Contains(claimStrings(claims["aud"]), v.audience) {
+		return nil, fmt.Errorf("token is not for audience %q", v.audience)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("token not valid yet")
+	}
+
+	return claims, nil
+}
+
+// key finds a signing key, refetching the JWKS once per interval for unknown IDs
+func (v *OIDCVerifier) key(kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(v.fetched) > jwksRefreshInterval {
+		if err := v.refreshKeys(); err != nil {
+			slog.Warn("Failed to refresh OIDC signing keys", "err", err)
+		}
+		if key, ok := v.keys[kid]; ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// refreshKeys loads the JWKS; callers hold v.mu
+func (v *OIDCVerifier) refreshKeys() error {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(v.jwksURL, &set); err != nil {
+		return fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			slog.Warn("Skipping unusable OIDC signing key", "kid", jwk.Kid, "err", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	v.keys = keys
+	v.fetched = time.Now()
+	return nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+// verifySignature checks the asymmetric algorithms OIDC providers sign with.
+// "none" and HMAC are refused, since the key is public.
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			break
+		}
+		if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+			return fmt.Errorf("invalid token signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			break
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return fmt.Errorf("invalid token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("token algorithm %s does not match its signing key", alg)
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// claimStrings reads a claim holding a string, a space separated list or an array
+func claimStrings(claim any) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func getJSON(url string, v any) error {
+	resp, err := oidcClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,155 @@
+// Package auth decides which actions a user may take. Users are identified by the
+// reverse proxy (X-Remote-User), and their roles come from the policy file and the
+// optional X-Remote-Roles header. A policy looks like
+//
+//	{
+//	  "default_roles": ["student"],
+//	  "users": {"jdoe": ["librarian"]},
+//	  "roles": {
+//	    "student":   {"can_export_pdf": true},
+//	    "librarian": {"can_export_pdf": true, "can_publish_drupal": true, "can_delete_session": true}
+//	  },
+//	  "collections": {
+//	    "special-collections": {"librarian": {"can_export_pdf": false}}
+//	  }
+//	}
+//
+// A collection entry overrides the role's grants for sessions in that collection.
+// Permissions not granted anywhere are denied.
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+type Permission string
+
+const (
+	// ExportPDF covers downloading a session's transcriptions, metrics report and PDFs
+	ExportPDF     Permission = "can_export_pdf"
+	PublishDrupal Permission = "can_publish_drupal"
+	DeleteSession Permission = "can_delete_session"
+	// ViewMetrics covers reports that span every session
+	ViewMetrics Permission = "can_view_metrics"
+	// PublishRepository covers writing back to Fedora or OCFL
+	PublishRepository Permission = "can_publish_repository"
+	// ManageStorage covers cleaning up stored files across every session
+	ManageStorage Permission = "can_manage_storage"
+	// ManageMacros covers deleting shared macros, global ones or those of a collection
+	ManageMacros Permission = "can_manage_macros"
+)
+
+// Permissions lists every action-level permission
+var Permissions = []Permission{ExportPDF, PublishDrupal, DeleteSession, ViewMetrics, PublishRepository, ManageStorage, ManageMacros}
+
+const (
+	UserHeader  = "X-Remote-User"
+	RolesHeader = "X-Remote-Roles"
+)
+
+// Principal is the user making a request and the roles they hold
+type Principal struct {
+	User  string   `json:"user"`
+	Roles []string `json:"roles"`
+}
+
+// Grants records whether each permission is allowed
+type Grants map[Permission]bool
+
+// Policy assigns roles to users and permissions to roles. A nil Policy allows everything.
+type Policy struct {
+	DefaultRoles []string                     `json:"default_roles"`
+	Users        map[string][]string          `json:"users"`
+	Roles        map[string]Grants            `json:"roles"`
+	Collections  map[string]map[string]Grants `json:"collections"`
+}
+
+// LoadPolicy reads a policy file and rejects unknown permission names
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read permissions file: %w", err)
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse permissions file: %w", err)
+	}
+
+	for role, grants := range policy.Roles {
+		if err := validateGrants(grants); err != nil {
+			return nil, fmt.Errorf("role %s: %w", role, err)
+		}
+	}
+	for collection, roles := range policy.Collections {
+		for role, grants := range roles {
+			if err := validateGrants(grants); err != nil {
+				return nil, fmt.Errorf("collection %s role %s: %w", collection, role, err)
+			}
+		}
+	}
+
+	return &policy, nil
+}
+
+func validateGrants(grants Grants) error {
+	for permission := range grants {
+		if !slices.Contains(Permissions, permission) {
+			return fmt.Errorf("unknown permission: %s", permission)
+		}
+	}
+	return nil
+}
+
+// PrincipalFor identifies the user behind a request and collects their roles
+func (p *Policy) PrincipalFor(r *http.Request) Principal {
+	principal := Principal{User: r.Header.Get(UserHeader)}
+	if p == nil {
+		return principal
+	}
+
+	roles := slices.Clone(p.DefaultRoles)
+	roles = append(roles, p.Users[principal.User]...)
+	for role := range strings.SplitSeq(r.Header.Get(RolesHeader), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	slices.Sort(roles)
+	principal.Roles = slices.Compact(roles)
+
+	return principal
+}
+
+// Allowed reports whether any of the principal's roles grants the permission for
+// sessions in the given collection
+func (p *Policy) Allowed(principal Principal, collection string, permission Permission) bool {
+	if p == nil {
+		return true
+	}
+
+	for _, role := range principal.Roles {
+		allowed := p.Roles[role][permission]
+		if override, ok := p.Collections[collection][role][permission]; ok {
+			allowed = override
+		}
+		if allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Effective lists every permission with whether the principal holds it
+func (p *Policy) Effective(principal Principal, collection string) Grants {
+	grants := make(Grants, len(Permissions))
+	for _, permission := range Permissions {
+		grants[permission] = p.Allowed(principal, collection, permission)
+	}
+	return grants
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPolicy = `{
+  "default_roles": ["student"],
+  "users": {"jdoe": ["librarian"]},
+  "roles": {
+    "student": {"can_export_pdf": true},
+    "librarian": {"can_export_pdf": true, "can_publish_drupal": true, "can_delete_session": true}
+  },
+  "collections": {
+    "special": {"librarian": {"can_publish_drupal": false}, "student": {"can_export_pdf": false}}
+  }
+}`
+
+func loadTestPolicy(t *testing.T, data string) (*Policy, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "permissions.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadPolicy(path)
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	policy, err := loadTestPolicy(t, testPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal := func(user, roles string) Principal {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(UserHeader, user)
+		r.Header.Set(RolesHeader, roles)
+		return policy.PrincipalFor(r)
+	}
+
+	tests := []struct {
+		name       string
+		principal  Principal
+		collection string
+		permission Permission
+		want       bool
+	}{
+		{"student exports", principal("worker", ""), "", ExportPDF, true},
+		{"student can't publish", principal("worker", ""), "", PublishDrupal, false},
+		{"student can't delete", principal("worker", ""), "", DeleteSession, false},
+		{"librarian publishes", principal("jdoe", ""), "", PublishDrupal, true},
+		{"collection revokes publish", principal("jdoe", ""), "special", PublishDrupal, false},
+		{"collection keeps other grants", principal("jdoe", ""), "special", DeleteSession, true},
+		{"another role still grants", principal("jdoe", ""), "special", ExportPDF, true},
+		{"collection revokes export", principal("worker", ""), "special", ExportPDF, false},
+		{"role from header", principal("worker", "librarian"), "", DeleteSession, true},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Allowed(tt.principal, tt.collection, tt.permission); got != tt.want {
+			t.Errorf("%s: Allowed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNilPolicyAllowsEverything(t *testing.T) {
+	var policy *Policy
+	for _, permission := range Permissions {
+		if !policy.Allowed(Principal{}, "", permission) {
+			t.Errorf("nil policy denied %s", permission)
+		}
+	}
+}
+
+func TestLoadPolicyRejectsUnknownPermission(t *testing.T) {
+	if _, err := loadTestPolicy(t, `{"roles": {"student": {"can_fly": true}}}`); err == nil {
+		t.Error("expected an error for an unknown permission")
+	}
+}
@@ -0,0 +1,129 @@
+package authority
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Candidate is a possible authorized form for a transcribed name, place, or subject
+type Candidate struct {
+	Source      string `json:"source"`
+	URI         string `json:"uri"`
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+}
+
+// Provider looks up candidate authorized forms in a single authority file
+type Provider interface {
+	Name() string
+	Lookup(ctx context.Context, query string) ([]Candidate, error)
+}
+
+type Service struct {
+	providers map[string]Provider
+	order     []string
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// NewService enables the providers listed in AUTHORITY_PROVIDERS (default "viaf").
+// Providers that are listed but not configured are skipped with a warning.
+func NewService() *Service {
+	s := &Service{providers: make(map[string]Provider)}
+
+	names := os.Getenv("AUTHORITY_PROVIDERS")
+	if names == "" {
+		names = "viaf"
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		var provider Provider
+		switch name {
+		case "":
+			continue
+		case "viaf":
+			provider = &VIAF{}
+		case "geonames":
+			username := os.Getenv("GEONAMES_USERNAME")
+			if username == "" {
+				slog.Warn("GeoNames authority provider requires GEONAMES_USERNAME, skipping")
+				continue
+			}
+			provider = &GeoNames{Username: username}
+		case "lcsh":
+			lcsh, err := LoadLCSHCache(os.Getenv("LCSH_CACHE_PATH"))
+			if err != nil {
+				slog.Warn("Unable to load LCSH cache, skipping", "err", err)
+				continue
+			}
+			provider = lcsh
+		default:
+			slog.Warn("Unknown authority provider", "name", name)
+			continue
+		}
+
+		s.providers[name] = provider
+		s.order = append(s.order, name)
+	}
+
+	slog.Info("Authority lookup providers enabled", "providers", s.order)
+	return s
+}
+
+// Providers lists the enabled provider names
+func (s *Service) Providers() []string {
+	return s.order
+}
+
+// Lookup queries the named source, or every enabled provider in parallel when source is empty.
+// Errors from individual providers are logged so one unreachable service doesn't hide the rest.
+func (s *Service) Lookup(ctx context.Context, query, source string) ([]Candidate, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	names := s.order
+	if source != "" {
+		if _, ok := s.providers[source]; !ok {
+			return nil, fmt.Errorf("authority source %q is not enabled", source)
+		}
+		names = []string{source}
+	}
+
+	results := make([][]Candidate, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, provider Provider) {
+			defer wg.Done()
+			results[i], errs[i] = provider.Lookup(ctx, query)
+		}(i, s.providers[name])
+	}
+	wg.Wait()
+
+	var candidates []Candidate
+	var failed int
+	for i, name := range names {
+		if errs[i] != nil {
+			slog.Warn("Authority lookup failed", "source", name, "err", errs[i])
+			failed++
+			continue
+		}
+		candidates = append(candidates, results[i]...)
+	}
+
+	if failed > 0 && failed == len(names) {
+		return nil, fmt.Errorf("all authority lookups failed: %w", errs[0])
+	}
+
+	return candidates, nil
+}
@@ -0,0 +1,183 @@
+package authority
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const maxCandidates = 10
+
+// VIAF queries the Virtual International Authority File AutoSuggest API
+type VIAF struct{}
+
+func (v *VIAF) Name() string {
+	return "viaf"
+}
+
+func (v *VIAF) Lookup(ctx context.Context, query string) ([]Candidate, error) {
+	var response struct {
+		Result []struct {
+			Term        string `json:"term"`
+			DisplayForm string `json:"displayForm"`
+			NameType    string `json:"nametype"`
+			VIAFID      string `json:"viafid"`
+		} `json:"result"`
+	}
+
+	requestURL := "https://viaf.org/viaf/AutoSuggest?query=" + url.QueryEscape(query)
+	if err := getJSON(ctx, requestURL, &response); err != nil {
+		return nil, err
+	}
+
+	var candidates []Candidate
+	for _, result := range response.Result {
+		if len(candidates) == maxCandidates {
+			break
+		}
+		label := result.DisplayForm
+		if label == "" {
+			label = result.Term
+		}
+		candidates = append(candidates, Candidate{
+			Source:      v.Name(),
+			URI:         "http://viaf.org/viaf/" + result.VIAFID,
+			Label:       label,
+			Description: result.NameType,
+		})
+	}
+	return candidates, nil
+}
+
+// GeoNames queries the GeoNames search web service
+type GeoNames struct {
+	Username string
+}
+
+func (g *GeoNames) Name() string {
+	return "geonames"
+}
+
+func (g *GeoNames) Lookup(ctx context.Context, query string) ([]Candidate, error) {
+	var response struct {
+		GeoNames []struct {
+			GeonameID   int    `json:"geonameId"`
+			Name        string `json:"name"`
+			AdminName1  string `json:"adminName1"`
+			CountryName string `json:"countryName"`
+			FCodeName   string `json:"fcodeName"`
+		} `json:"geonames"`
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("maxRows", fmt.Sprintf("%d", maxCandidates))
+	params.Set("username", g.Username)
+	if err := getJSON(ctx, "https://secure.geonames.org/searchJSON?"+params.Encode(), &response); err != nil {
+		return nil, err
+	}
+
+	var candidates []Candidate
+	for _, place := range response.GeoNames {
+		var description []string
+		for _, part := range []string{place.AdminName1, place.CountryName, place.FCodeName} {
+			if part != "" {
+				description = append(description, part)
+			}
+		}
+		candidates = append(candidates, Candidate{
+			Source:      g.Name(),
+			URI:         fmt.Sprintf("https://sws.geonames.org/%d/", place.GeonameID),
+			Label:       place.Name,
+			Description: strings.Join(description, ", "),
+		})
+	}
+	return candidates, nil
+}
+
+// LCSH searches a locally cached subset of Library of Congress Subject Headings.
+// The cache is a tab separated file of URI and heading, one per line.
+type LCSH struct {
+	headings []Candidate
+}
+
+func LoadLCSHCache(path string) (*LCSH, error) {
+	if path == "" {
+		return nil, fmt.Errorf("LCSH_CACHE_PATH environment variable not set")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open LCSH cache: %w", err)
+	}
+	defer file.Close()
+
+	lcsh := &LCSH{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		uri, label, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok || uri == "" || label == "" {
+			continue
+		}
+		lcsh.headings = append(lcsh.headings, Candidate{Source: "lcsh", URI: uri, Label: label})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read LCSH cache: %w", err)
+	}
+
+	return lcsh, nil
+}
+
+func (l *LCSH) Name() string {
+	return "lcsh"
+}
+
+func (l *LCSH) Lookup(_ context.Context, query string) ([]Candidate, error) {
+	needle := strings.ToLower(query)
+
+	// Headings starting with the query rank ahead of those merely containing it
+	var prefixed, contained []Candidate
+	for _, heading := range l.headings {
+		label := strings.ToLower(heading.Label)
+		switch {
+		case strings.HasPrefix(label, needle):
+			prefixed = append(prefixed, heading)
+		case strings.Contains(label, needle):
+			contained = append(contained, heading)
+		}
+	}
+
+	candidates := append(prefixed, contained...)
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+	return candidates, nil
+}
+
+func getJSON(ctx context.Context, requestURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query authority: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("authority returned HTTP %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode authority response: %w", err)
+	}
+	return nil
+}
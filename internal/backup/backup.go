@@ -0,0 +1,250 @@
+// Package backup snapshots the server's persistent state (sessions, jobs,
+// uploads, caches and archived output) to a gzipped tar archive, and restores
+// one, so a deployment can move between hosts with its correction history.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Format is the version of the archive layout, bumped when it changes
+const Format = 1
+
+// manifestName is the archive's first entry
+const manifestName = "manifest.json"
+
+// ErrNotEmpty refuses a restore that would mix the archive with existing state
+var ErrNotEmpty = errors.New("target directories already hold files")
+
+// Area is a directory the archive holds under Name, so it can be restored to
+// wherever the same area is configured on the new host
+type Area struct {
+	Name string
+	Dir  string
+}
+
+// Manifest describes an archive
+type Manifest struct {
+	Format    int       `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	Areas     []string  `json:"areas"`
+}
+
+// Stats counts the files an archive holds
+type Stats struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Write archives every regular file of the areas to w, each under its area's
+// name. An area nested in another is left out of the outer one's walk, so
+// nothing is stored twice. Areas whose directory doesn't exist are recorded
+// as empty.
+func Write(w io.Writer, areas []Area) (Stats, error) {
+	var stats Stats
+	gz, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
+	if err != nil {
+		return stats, err
+	}
+	tw := tar.NewWriter(gz)
+
+	manifest := Manifest{Format: Format, CreatedAt: time.Now().UTC()}
+	roots := make(map[string]bool, len(areas))
+	for _, area := range areas {
+		manifest.Areas = append(manifest.Areas, area.Name)
+		roots[filepath.Clean(area.Dir)] = true
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return stats, err
+	}
+	header := &tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(data)), ModTime: manifest.CreatedAt, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return stats, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return stats, err
+	}
+
+	for _, area := range areas {
+		root := filepath.Clean(area.Dir)
+		err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if file == root && errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if entry.IsDir() {
+				if file != root && roots[file] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			// Links and other special files aren't state the server writes
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			size, err := archiveFile(tw, file, path.Join(area.Name, filepath.ToSlash(rel)))
+			if err != nil {
+				return err
+			}
+			stats.Files++
+			stats.Bytes += size
+			return nil
+		})
+		if err != nil {
+			return stats, fmt.Errorf("failed to archive %s: %w", area.Name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return stats, err
+	}
+	return stats, gz.Close()
+}
+
+// archiveFile adds one file to the archive. The size is taken once the file is
+// open, so a file replaced meanwhile is archived whole, old or new.
+func archiveFile(tw *tar.Writer, file, name string) (int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return 0, err
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return 0, err
+	}
+	if _, err := io.CopyN(tw, f, info.Size()); err != nil {
+		return 0, fmt.Errorf("%s changed while being archived: %w", file, err)
+	}
+	return info.Size(), nil
+}
+
+// Restore unpacks an archive made by Write into the areas of the same names,
+// keeping each file's modification time so retention counts from when it was
+// first stored. Unless replace is set it refuses, with ErrNotEmpty, when any
+// area already holds files; with it, files in the archive overwrite those of
+// the same name and others are left alone. Entries that would land outside
+// their area, or in an area not given, fail the restore.
+func Restore(r io.Reader, areas []Area, replace bool) (Manifest, Stats, error) {
+	var manifest Manifest
+	var stats Stats
+	dirs := make(map[string]string, len(areas))
+	for _, area := range areas {
+		dirs[area.Name] = area.Dir
+	}
+	if !replace {
+		for _, area := range areas {
+			if holdsFiles(area.Dir) {
+				return manifest, stats, fmt.Errorf("%w: %s (%s)", ErrNotEmpty, area.Name, area.Dir)
+			}
+		}
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return manifest, stats, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestName {
+		return manifest, stats, errors.New("not a backup archive: no manifest")
+	}
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return manifest, stats, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Format != Format {
+		return manifest, stats, fmt.Errorf("archive format %d is not supported, expected %d", manifest.Format, Format)
+	}
+	for _, name := range manifest.Areas {
+		if _, ok := dirs[name]; !ok {
+			return manifest, stats, fmt.Errorf("archive holds %s, which has no directory to restore to", name)
+		}
+	}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return manifest, stats, nil
+		}
+		if err != nil {
+			return manifest, stats, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, rel, _ := strings.Cut(header.Name, "/")
+		dir, ok := dirs[name]
+		if !ok || !slices.Contains(manifest.Areas, name) {
+			return manifest, stats, fmt.Errorf("entry %s is outside the archived areas", header.Name)
+		}
+		if rel == "" || !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return manifest, stats, fmt.Errorf("entry %s escapes its area", header.Name)
+		}
+		if err := restoreFile(tr, header, filepath.Join(dir, filepath.FromSlash(rel))); err != nil {
+			return manifest, stats, err
+		}
+		stats.Files++
+		stats.Bytes += header.Size
+	}
+}
+
+// restoreFile writes one entry to target
+func restoreFile(tr *tar.Reader, header *tar.Header, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, header.FileInfo().Mode().Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, tr); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to restore %s: %w", header.Name, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(target, header.ModTime, header.ModTime)
+}
+
+// holdsFiles reports whether any regular file is under dir
+func holdsFiles(dir string) bool {
+	found := false
+	filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type().IsRegular() {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
@@ -0,0 +1,109 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteRestore(t *testing.T) {
+	from := t.TempDir()
+	writeFile(t, filepath.Join(from, "uploads/abc.jpg"), "jpeg")
+	writeFile(t, filepath.Join(from, "uploads/originals/abc.tif"), "tiff")
+	writeFile(t, filepath.Join(from, "data/schema.json"), `{"version":3}`)
+	writeFile(t, filepath.Join(from, "data/sessions/s1.json"), `{"session":{}}`)
+	stored := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(from, "uploads/abc.jpg"), stored, stored); err != nil {
+		t.Fatal(err)
+	}
+	areas := func(root string) []Area {
+		return []Area{
+			{"uploads", filepath.Join(root, "uploads")},
+			{"cache", filepath.Join(root, "cache")},
+			{"data", filepath.Join(root, "data")},
+			{"sessions", filepath.Join(root, "data/sessions")},
+		}
+	}
+
+	var archive bytes.Buffer
+	stats, err := Write(&archive, areas(from))
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	// The session is archived once, under its own area, though data holds it too
+	if stats.Files != 4 {
+		t.Errorf("archived %d files, want 4", stats.Files)
+	}
+
+	// Sessions land wherever the new host keeps them
+	to := t.TempDir()
+	target := areas(to)
+	target[3].Dir = filepath.Join(to, "elsewhere")
+	manifest, restored, err := Restore(bytes.NewReader(archive.Bytes()), target, false)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if manifest.Format != Format || len(manifest.Areas) != 4 || restored != stats {
+		t.Errorf("restored %+v of %+v, manifest %+v", restored, stats, manifest)
+	}
+	for path, want := range map[string]string{
+		"uploads/abc.jpg":           "jpeg",
+		"uploads/originals/abc.tif": "tiff",
+		"data/schema.json":          `{"version":3}`,
+		"elsewhere/s1.json":         `{"session":{}}`,
+	} {
+		got, err := os.ReadFile(filepath.Join(to, path))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(to, "uploads/abc.jpg")); err != nil || !info.ModTime().Equal(stored) {
+		t.Errorf("modification time not kept: %v", info.ModTime())
+	}
+
+	// A second restore would mix states unless asked to replace
+	_, _, err = Restore(bytes.NewReader(archive.Bytes()), target, false)
+	if !errors.Is(err, ErrNotEmpty) {
+		t.Errorf("restore over existing files: %v, want ErrNotEmpty", err)
+	}
+	if _, _, err := Restore(bytes.NewReader(archive.Bytes()), target, true); err != nil {
+		t.Errorf("replacing restore failed: %v", err)
+	}
+}
+
+func TestRestoreRejectsEscapes(t *testing.T) {
+	for _, name := range []string{"uploads/../../etc/passwd", "uploads//abs", "other/file"} {
+		var archive bytes.Buffer
+		gz := gzip.NewWriter(&archive)
+		tw := tar.NewWriter(gz)
+		manifest := `{"format":1,"areas":["uploads"]}`
+		tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(manifest)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(manifest))
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 1, Typeflag: tar.TypeReg})
+		tw.Write([]byte("x"))
+		tw.Close()
+		gz.Close()
+
+		dir := t.TempDir()
+		_, _, err := Restore(&archive, []Area{{"uploads", filepath.Join(dir, "uploads")}}, false)
+		if err == nil || !strings.Contains(err.Error(), "entry") {
+			t.Errorf("%s: restored, err %v", name, err)
+		}
+	}
+}
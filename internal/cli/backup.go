@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/backup"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/handlers"
+)
+
+// stateFlags are the settings and directories flags of the commands that work
+// on a server's files rather than through its API
+type stateFlags struct {
+	config, uploads, cache, archive, data *string
+}
+
+func addStateFlags(fs *flag.FlagSet) stateFlags {
+	return stateFlags{
+		config:  fs.String("config", "", "file of settings in the sample.env format, or as flat YAML (KEY: value)"),
+		uploads: fs.String("uploads-dir", "", "uploaded images and their hOCR (UPLOADS_DIR, default uploads)"),
+		cache:   fs.String("cache-dir", "", "converted images, tiles and other rebuildable files (CACHE_DIR, default cache)"),
+		archive: fs.String("archive-dir", "", "raw engine output and job diagnostics (ARCHIVE_DIR, default archive)"),
+		data:    fs.String("data-dir", "", "sessions, jobs and the schema version (DATA_DIR, default data)"),
+	}
+}
+
+// dirs applies the config file and flags over the environment, as serve does
+func (f stateFlags) dirs() (handlers.Dirs, error) {
+	if *f.config != "" {
+		if err := loadConfig(*f.config); err != nil {
+			return handlers.Dirs{}, err
+		}
+	}
+	dirs := handlers.DirsFromEnv()
+	for dir, value := range map[*string]string{&dirs.Uploads: *f.uploads, &dirs.Cache: *f.cache, &dirs.Archive: *f.archive, &dirs.Data: *f.data} {
+		if value != "" {
+			*dir = value
+		}
+	}
+	return dirs, nil
+}
+
+func runBackup(args []string) error {
+	fs := newFlagSet("backup", "", "Archives sessions, jobs, uploads, archived engine output and the cache to a\ngzipped tar, for the restore command on another host. Stop the server first,\nor download /api/v1/admin/backup from it instead, so every session is on disk.")
+	state := addStateFlags(fs)
+	output := fs.String("o", "", "file to write, - for stdout (required)")
+	noCache := fs.Bool("no-cache", false, "leave out the cache, which the server rebuilds as it's needed")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := wantArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if *output == "" {
+		fmt.Fprintln(stderr, "-o is required")
+		fs.Usage()
+		return errUsage
+	}
+	dirs, err := state.dirs()
+	if err != nil {
+		return err
+	}
+
+	areas := dirs.BackupAreas(!*noCache)
+	var stats backup.Stats
+	if *output == "-" {
+		stats, err = backup.Write(stdout, areas)
+	} else {
+		f, createErr := os.Create(*output)
+		if createErr != nil {
+			return createErr
+		}
+		stats, err = backup.Write(f, areas)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("Backup written", "path", *output, "files", stats.Files, "bytes", stats.Bytes)
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := newFlagSet("restore", "<backup.tar.gz>", "Unpacks an archive made by backup into this host's directories, keeping the\nfiles' times. Run it before the server starts; its migrations then upgrade the\nstate if the archive came from an older release. \"-\" reads stdin.")
+	state := addStateFlags(fs)
+	replace := fs.Bool("replace", false, "restore over existing state, overwriting files of the same name")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := wantArgs(fs, args, 1); err != nil {
+		return err
+	}
+	dirs, err := state.dirs()
+	if err != nil {
+		return err
+	}
+
+	r := stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	manifest, stats, err := backup.Restore(r, dirs.BackupAreas(true), *replace)
+	if errors.Is(err, backup.ErrNotEmpty) {
+		return fmt.Errorf("%w; pass -replace to restore over it", err)
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("Backup restored", "path", args[0], "created_at", manifest.CreatedAt, "areas", manifest.Areas, "files", stats.Files, "bytes", stats.Bytes)
+	return nil
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+)
+
+// batchExtensions are the image files a batch picks up
+var batchExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".tif": true, ".tiff": true, ".jp2": true,
+}
+
+// batchResult is the outcome of one image of a batch
+type batchResult struct {
+	path    string
+	skipped bool
+	err     error
+}
+
+func runBatch(args []string) error {
+	flags := newFlagSet("batch", "<directory>", "OCRs every image under a directory, writing hOCR next to each file, and prints\na summary. Images that already have hOCR are skipped unless -overwrite is set.\nExits with status 1 when any image failed.")
+	concurrency := flags.Int("concurrency", 2, "images processed at once")
+	overwrite := flags.Bool("overwrite", false, "redo images that already have hOCR")
+	dryRun := flags.Bool("dry-run", false, "detect and stitch words, then print the estimated LLM tokens and cost of every image instead of transcribing")
+	var pipeline pipelineFlags
+	pipeline.register(flags)
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if err := wantArgs(flags, args, 1); err != nil {
+		return err
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+
+	images, err := findImages(args[0])
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		// Only the images the batch would process are estimated
+		var pending []string
+		for _, imagePath := range images {
+			if _, err := os.Stat(hocrPath(imagePath)); *overwrite || err != nil {
+				pending = append(pending, imagePath)
+			}
+		}
+		if len(pending) == 0 {
+			fmt.Fprintln(stdout, "Every image already has hOCR; nothing to estimate")
+			return nil
+		}
+		return estimateImages(pending, *concurrency, pipeline)
+	}
+	service := hocr.NewService(hocr.DirsFromEnv())
+	opts, err := pipeline.options(service)
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	slog.Info("Batch started", "dir", args[0], "images", len(images), "concurrency", *concurrency)
+	results := processBatch(images, *concurrency, func(imagePath string) (bool, error) {
+		output := hocrPath(imagePath)
+		if !*overwrite {
+			if _, err := os.Stat(output); err == nil {
+				return true, nil
+			}
+		}
+		hocrXML, err := service.ProcessImageToHOCR(imagePath, opts)
+		if err != nil {
+			return false, err
+		}
+		return false, os.WriteFile(output, []byte(hocrXML), 0644)
+	})
+
+	return printBatchSummary(results, time.Since(started))
+}
+
+// findImages walks a directory for image files, in lexical order
+func findImages(dir string) ([]string, error) {
+	var images []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && batchExtensions[strings.ToLower(filepath.Ext(path))] {
+			images = append(images, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images found in %s", dir)
+	}
+	return images, nil
+}
+
+// processBatch runs process over the images with at most concurrency at once,
+// returning the results in the order of the images
+func processBatch(images []string, concurrency int, process func(imagePath string) (skipped bool, err error)) []batchResult {
+	results := make([]batchResult, len(images))
+	indexes := make(chan int)
+	var finished atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < min(concurrency, len(images)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				imagePath := images[index]
+				skipped, err := process(imagePath)
+				results[index] = batchResult{path: imagePath, skipped: skipped, err: err}
+				done := finished.Add(1)
+				switch {
+				case err != nil:
+					slog.Error("Image failed", "image", imagePath, "err", err)
+				case skipped:
+					slog.Info("Image skipped, hOCR exists", "image", imagePath)
+				default:
+					slog.Info("Image processed", "image", imagePath, "done", done, "of", len(images))
+				}
+			}
+		}()
+	}
+	for i := range images {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// printBatchSummary writes the counts and each failure, returning an error
+// when anything failed
+func printBatchSummary(results []batchResult, elapsed time.Duration) error {
+	var processed, skipped int
+	var failures []batchResult
+	for _, result := range results {
+		switch {
+		case result.err != nil:
+			failures = append(failures, result)
+		case result.skipped:
+			skipped++
+		default:
+			processed++
+		}
+	}
+
+	fmt.Fprintf(stdout, "%d images: %d processed, %d skipped, %d failed in %s\n", len(results), processed, skipped, len(failures), elapsed.Round(time.Second))
+	if len(failures) == 0 {
+		return nil
+	}
+	fmt.Fprintln(stdout, "\nFailed:")
+	for _, failure := range failures {
+		fmt.Fprintf(stdout, "  %s: %v\n", failure.path, failure.err)
+	}
+	return errors.New("some images failed")
+}
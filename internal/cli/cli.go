@@ -0,0 +1,164 @@
+// Package cli runs hOCRedit from the command line. Each subcommand parses its
+// own flags; flags may come before or after the arguments, as in
+//
+//	hocredit ocr page.jpg -o page.hocr
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/logging"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// Exit statuses
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// errUsage reports bad arguments once the command's usage has been printed
+var errUsage = errors.New("invalid arguments")
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+func commands() []command {
+	return []command{
+		{name: "serve", summary: "Serve the editor and API (the default)", run: runServe},
+		{name: "ocr", summary: "OCR an image to hOCR", run: runOCR},
+		{name: "batch", summary: "OCR every image under a directory", run: runBatch},
+		{name: "eval", summary: "Score the pipeline against ground truth transcripts", run: runEval},
+		{name: "convert", summary: "Convert hOCR to ALTO, PAGE, text or a searchable PDF", run: runConvert},
+		{name: "pdf", summary: "Assemble page images and their hOCR into a searchable PDF", run: runPDF},
+		{name: "sessions", summary: "Pull a session's hOCR, images and metrics from a running server", run: runSessions},
+		{name: "backup", summary: "Archive sessions, uploads and caches for moving to another host", run: runBackup},
+		{name: "restore", summary: "Restore an archive made by backup", run: runRestore},
+	}
+}
+
+// stdin is read by commands given "-" for a file, stdout is where commands
+// write their results, and stderr usage, errors and logs
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// processEnv records the variables set before .env was read, which config
+// files don't override
+var processEnv = map[string]bool{}
+
+// Run loads .env, sets up logging, then runs the subcommand named by args[0]
+// with the rest of args and returns the process exit status
+func Run(args []string) int {
+	for _, variable := range os.Environ() {
+		key, _, _ := strings.Cut(variable, "=")
+		processEnv[key] = true
+	}
+	if err := godotenv.Load(); err != nil {
+		slog.Warn("Error loading .env file", "err", err)
+	}
+	setupLogging()
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage()
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	for _, cmd := range commands() {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:])
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case errors.Is(err, errUsage):
+			return exitUsage
+		default:
+			fmt.Fprintf(stderr, "hocredit %s: %v\n", cmd.name, err)
+			return exitFailure
+		}
+	}
+
+	fmt.Fprintf(stderr, "hocredit: unknown command %q\n\n", args[0])
+	usage()
+	return exitUsage
+}
+
+// setupLogging writes logs to stderr, redacted as configured, keeping recent
+// entries in memory for the diagnostic bundles of failed jobs
+func setupLogging() {
+	logging.Recent = logging.NewBuffer(utils.GetEnvInt("DIAGNOSTIC_LOG_ENTRIES", 2000))
+	textHandler := slog.NewTextHandler(os.Stderr, nil)
+	slog.SetDefault(slog.New(logging.NewRedactingHandler(logging.NewBufferHandler(textHandler, logging.Recent), logging.PolicyFromEnv())))
+}
+
+func usage() {
+	fmt.Fprintln(stderr, "Usage: hocredit <command> [flags] [arguments]")
+	fmt.Fprintln(stderr, "\nCommands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(stderr, "\nRun hocredit <command> -h for a command's flags.")
+}
+
+// newFlagSet starts a subcommand's flags, with usage naming its arguments
+func newFlagSet(name, arguments, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: hocredit %s [flags] %s\n\n%s\n\nFlags:\n", name, arguments, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses flags wherever they appear among the arguments, which are
+// returned in order. Everything after "--" is an argument.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
+		}
+		rest := fs.Args()
+		// flag stops at "--" after consuming it, so what's left is all arguments
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// wantArgs checks the number of arguments, printing the usage when it's wrong
+func wantArgs(fs *flag.FlagSet, args []string, n int) error {
+	if len(args) != n {
+		fmt.Fprintf(stderr, "expected %d argument(s), got %d\n", n, len(args))
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,351 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args       []string
+		want       []string
+		wantOutput string
+	}{
+		{args: []string{"page.jpg", "-o", "out.hocr"}, want: []string{"page.jpg"}, wantOutput: "out.hocr"},
+		{args: []string{"-o", "out.hocr", "a.jpg", "b.jpg"}, want: []string{"a.jpg", "b.jpg"}, wantOutput: "out.hocr"},
+		{args: []string{"a.jpg", "--o=out.hocr", "b.jpg"}, want: []string{"a.jpg", "b.jpg"}, wantOutput: "out.hocr"},
+		{args: []string{"a.jpg", "--", "-o", "b.jpg"}, want: []string{"a.jpg", "-o", "b.jpg"}},
+		{args: []string{"-"}, want: []string{"-"}},
+	}
+
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		output := fs.String("o", "", "")
+		got, err := parseArgs(fs, tt.args)
+		if err != nil {
+			t.Errorf("parseArgs(%q) failed: %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) || *output != tt.wantOutput {
+			t.Errorf("parseArgs(%q) = %q with -o %q, want %q with -o %q", tt.args, got, *output, tt.want, tt.wantOutput)
+		}
+	}
+}
+
+func TestRunExitStatus(t *testing.T) {
+	var out bytes.Buffer
+	defer func(original io.Writer) { stderr = original }(stderr)
+	stderr = &out
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{args: nil, want: exitUsage},
+		{args: []string{"help"}, want: exitOK},
+		{args: []string{"nope"}, want: exitUsage},
+		{args: []string{"ocr"}, want: exitUsage},
+		{args: []string{"ocr", "-unknown", "page.jpg"}, want: exitUsage},
+		{args: []string{"ocr", "-h"}, want: exitOK},
+		{args: []string{"ocr", "does-not-exist.jpg"}, want: exitFailure},
+	}
+	for _, tt := range tests {
+		out.Reset()
+		if got := Run(tt.args); got != tt.want {
+			t.Errorf("Run(%q) = %d, want %d; output:\n%s", tt.args, got, tt.want, out.String())
+		}
+	}
+}
+
+func TestHOCRPath(t *testing.T) {
+	if got := hocrPath("scans/page 1.tif"); got != "scans/page 1.hocr" {
+		t.Errorf("hocrPath = %q", got)
+	}
+}
+
+func TestReadStdinImage(t *testing.T) {
+	defer func(original io.Reader) { stdin = original }(stdin)
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	stdin = bytes.NewReader(b.Bytes())
+	path, err := readStdinImage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+	if filepath.Ext(path) != ".png" {
+		t.Errorf("stdin saved as %s, want a .png", path)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, b.Bytes()) {
+		t.Error("saved image differs from stdin")
+	}
+
+	stdin = strings.NewReader("not an image")
+	if _, err := readStdinImage(); err == nil {
+		t.Error("expected an error for text on stdin")
+	}
+}
+
+func TestFindImages(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b/page2.TIF", "a/page1.jpg", "notes.txt", "page1.hocr"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	images, err := findImages(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a/page1.jpg"), filepath.Join(dir, "b/page2.TIF")}
+	if !reflect.DeepEqual(images, want) {
+		t.Errorf("findImages = %q, want %q", images, want)
+	}
+
+	if _, err := findImages(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without images")
+	}
+}
+
+func TestProcessBatch(t *testing.T) {
+	images := []string{"1.jpg", "2.jpg", "3.jpg", "4.jpg", "5.jpg"}
+	var running, peak atomic.Int32
+	results := processBatch(images, 2, func(imagePath string) (bool, error) {
+		now := running.Add(1)
+		for {
+			old := peak.Load()
+			if now <= old || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		switch imagePath {
+		case "2.jpg":
+			return true, nil
+		case "4.jpg":
+			return false, errors.New("unreadable")
+		}
+		return false, nil
+	})
+
+	if peak.Load() > 2 {
+		t.Errorf("%d images processed at once, want at most 2", peak.Load())
+	}
+	for i, result := range results {
+		if result.path != images[i] {
+			t.Errorf("result %d is for %s, want %s", i, result.path, images[i])
+		}
+	}
+
+	var out bytes.Buffer
+	defer func(original io.Writer) { stdout = original }(stdout)
+	stdout = &out
+	if err := printBatchSummary(results, time.Second); err == nil {
+		t.Error("expected an error when an image failed")
+	}
+	if !strings.HasPrefix(out.String(), "5 images: 3 processed, 1 skipped, 1 failed") || !strings.Contains(out.String(), "4.jpg: unreadable") {
+		t.Errorf("unexpected summary:\n%s", out.String())
+	}
+}
+
+func TestPrintEstimates(t *testing.T) {
+	results := []batchResult{{path: "1.jpg"}, {path: "2.jpg", err: errors.New("unreadable")}, {path: "3.jpg"}}
+	estimates := []hocr.Estimate{
+		{Words: 100, Requests: 1, InputTokens: 1000, OutputTokens: 2000, CostUSD: 0.0225},
+		{},
+		{Words: 300, Requests: 2, Cached: 1, InputTokens: 2000, OutputTokens: 6000, CostUSD: 0.065},
+	}
+
+	var out bytes.Buffer
+	defer func(original io.Writer) { stdout = original }(stdout)
+	stdout = &out
+	if err := printEstimates(results, estimates, hocr.Pricing{Model: "gpt-4o", InputPerMillion: 2.5, OutputPerMillion: 10, Known: true}); err == nil {
+		t.Error("expected an error when an image failed")
+	}
+	for _, want := range []string{
+		"3.jpg: 300 words in 2 requests (1 cached), ~2000 input and ~6000 output tokens, ~$0.0650",
+		"2.jpg: unreadable",
+		"Total for 2 images: 400 words in 3 requests (1 cached), ~3000 input and ~8000 output tokens, ~$0.0875",
+		"Priced for gpt-4o",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestParseRows(t *testing.T) {
+	rows, err := parseRows("1, 3,5")
+	if err != nil || !reflect.DeepEqual(rows, []int{1, 3, 5}) {
+		t.Errorf("parseRows = %v, %v", rows, err)
+	}
+	for _, value := range []string{"0", "1,x", "2,,3"} {
+		if _, err := parseRows(value); err == nil {
+			t.Errorf("parseRows(%q) should fail", value)
+		}
+	}
+}
+
+// tesseractHOCR is hOCR as plain Tesseract writes it
+const tesseractHOCR = `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en">
+ <head><title></title><meta name="ocr-system" content="tesseract 5.3.0" /></head>
+ <body>
+  <div class='ocr_page' id='page_1' title='image "page.png"; bbox 0 0 600 300; ppageno 0; scan_res 300 300'>
+   <div class='ocr_carea' id='block_1_1' title="bbox 30 30 190 60">
+    <p class='ocr_par' id='par_1_1' lang='eng' title="bbox 30 30 190 60">
+     <span class='ocr_line' id='line_1_1' title="bbox 30 30 190 60; baseline 0 -5; x_size 30">
+      <span class='ocrx_word' id='word_1_1' title='bbox 30 30 150 60; x_wconf 91'>Lehigh</span>
+      <span class='ocrx_word' id='word_1_2' title='bbox 160 30 190 60; x_wconf 88'>Valley</span>
+     </span>
+    </p>
+   </div>
+  </div>
+ </body>
+</html>`
+
+func TestRunConvert(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "page.hocr")
+	if err := os.WriteFile(input, []byte(tesseractHOCR), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format string
+		output string
+		want   string
+	}{
+		{format: "text", output: "page.txt", want: "Lehigh Valley\n"},
+		{format: "alto", output: "page.alto.xml", want: `<fileName>page.png</fileName>`},
+		{format: "page", output: "page.page.xml", want: `imageWidth="600" imageHeight="300"`},
+	}
+	for _, tt := range tests {
+		if err := runConvert([]string{input, "--to", tt.format}); err != nil {
+			t.Fatalf("convert to %s: %v", tt.format, err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, tt.output))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), tt.want) {
+			t.Errorf("%s output missing %q:\n%s", tt.format, tt.want, data)
+		}
+	}
+
+	// The page image named in the hOCR isn't there
+	if err := runConvert([]string{input, "--to", "pdf"}); err == nil {
+		t.Error("expected an error without the page image")
+	}
+}
+
+func TestRunPDF(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"p1", "p2"} {
+		var b bytes.Buffer
+		if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, 600, 300))); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".png"), b.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "p1.hocr"), []byte(tesseractHOCR), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "out.pdf")
+
+	// p2 has no hOCR yet
+	err := runPDF([]string{dir, "-o", output})
+	if err == nil || !strings.Contains(err.Error(), "p2.png: no hOCR") {
+		t.Fatalf("expected p2 to be reported, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "p2.hocr"), []byte(tesseractHOCR), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runPDF([]string{dir, "-o", output}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"/Count 2 >>", "/Title (p1)", "/Title (p2)"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("PDF missing %q", want)
+		}
+	}
+}
+
+func TestRunSessionsPull(t *testing.T) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/sessions/s1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"id": "s1", "images": [
+			{"id": "img_1", "image_path": "abc.png", "image_url": "/static/uploads/abc.png", "original_hocr": "<original/>", "corrected_hocr": "<corrected/>"},
+			{"id": "img_2", "original_hocr": "<only-original/>"}
+		]}`))
+	})
+	mux.HandleFunc("/api/v1/sessions/s1/report", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"session_id": "s1"}`))
+	})
+	mux.HandleFunc("/static/uploads/abc.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(b.Bytes())
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	defer func(original io.Writer) { stdout = original }(stdout)
+	stdout = io.Discard
+	dir := filepath.Join(t.TempDir(), "pulled")
+	if err := runSessionsPull([]string{"s1", "-server", server.URL, "-token", "wrong", "-o", dir}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected the server's 401, got %v", err)
+	}
+	if err := runSessionsPull([]string{"s1", "-server", server.URL, "-token", "secret", "-o", dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"page_0001.hocr": "<corrected/>",
+		"page_0001.png":  b.String(),
+		"page_0002.hocr": "<only-original/>",
+		"metrics.json":   `{"session_id": "s1"}`,
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q (%v), want %q", name, data, err, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/export"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// convertExtensions are the default output extensions of each format
+var convertExtensions = map[string]string{
+	"alto": ".alto.xml",
+	"page": ".page.xml",
+	"text": ".txt",
+	"pdf":  ".pdf",
+}
+
+func runConvert(args []string) error {
+	fs := newFlagSet("convert", "<in.hocr>", "Converts hOCR, from this tool or any other engine, to ALTO, PAGE, plain text\nor a searchable PDF, using the same exporters as the editor.\n\nA PDF needs the page image: -image, or else the image named in the hOCR,\nfound relative to the hOCR file.")
+	to := fs.String("to", "", "output format: alto, page, text or pdf (required)")
+	output := fs.String("o", "", "file to write (default: the hOCR's name with the format's extension)")
+	imagePath := fs.String("image", "", "page image for a PDF")
+	language := fs.String("lang", "", "language of the text, for rejoining words hyphenated across lines in text output")
+	transliterate := fs.Bool("translit", false, "romanize Cyrillic, Greek, Hebrew and Arabic in text output, writing a .translit.txt companion by default")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := wantArgs(fs, args, 1); err != nil {
+		return err
+	}
+	extension, ok := convertExtensions[*to]
+	if !ok {
+		fmt.Fprintf(stderr, "-to must be alto, page, text or pdf\n")
+		fs.Usage()
+		return errUsage
+	}
+
+	input := args[0]
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	hocrXML := string(data)
+	lines, err := hocr.ParseHOCRLines(hocrXML)
+	if err != nil {
+		return fmt.Errorf("%s: %w", input, err)
+	}
+	page, err := hocr.ParsePage(hocrXML)
+	if err != nil {
+		return fmt.Errorf("%s: %w", input, err)
+	}
+	fileName := filepath.Base(page.Image)
+	if page.Image == "" {
+		fileName = filepath.Base(input)
+	}
+
+	var converted []byte
+	switch *to {
+	case "alto":
+		converted, err = export.ALTO(lines, page.Width, page.Height, fileName)
+	case "page":
+		converted, err = export.PAGE(lines, page.Width, page.Height, fileName)
+	case "text":
+		lang, err := hocr.ParseLanguage(*language)
+		if err != nil {
+			return err
+		}
+		if *transliterate {
+			lines = export.TransliterateLines(lines)
+			extension = ".translit" + extension
+		}
+		converted = []byte(export.PlainText(hocr.MarkHyphenation(lines, lang)))
+	case "pdf":
+		converted, err = convertPDF(input, *imagePath, page, lines)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(input, filepath.Ext(input)) + extension
+	}
+	if err := os.WriteFile(*output, converted, 0644); err != nil {
+		return err
+	}
+	slog.Info("Converted hOCR", "input", input, "format", *to, "output", *output)
+	return nil
+}
+
+// convertPDF lays the hOCR over its page image
+func convertPDF(input, imagePath string, page hocr.Page, lines []models.HOCRLine) ([]byte, error) {
+	if imagePath == "" {
+		if page.Image == "" {
+			return nil, fmt.Errorf("the hOCR names no image; pass -image")
+		}
+		imagePath = page.Image
+		if !filepath.IsAbs(imagePath) {
+			imagePath = filepath.Join(filepath.Dir(input), imagePath)
+		}
+	}
+	image, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	return export.SearchablePDF([]export.PDFPage{{
+		Image:  image,
+		Width:  page.Width,
+		Height: page.Height,
+		Lines:  lines,
+		DPI:    page.DPI,
+	}})
+}
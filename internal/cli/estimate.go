@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+)
+
+// estimateImages is the dry run of ocr and batch: words are detected and
+// stitched as for the LLM engine, and the tokens and cost of transcribing
+// them are printed instead
+func estimateImages(images []string, concurrency int, pipeline pipelineFlags) error {
+	if pipeline.engine != "" && pipeline.engine != hocr.EngineLLM {
+		return fmt.Errorf("a dry run estimates the %s engine; -engine %s makes no API calls", hocr.EngineLLM, pipeline.engine)
+	}
+
+	opts, err := pipeline.parse()
+	if err != nil {
+		return err
+	}
+	opts.Engine = hocr.EngineLLM
+	service := hocr.NewService(hocr.DirsFromEnv())
+	estimates := make([]hocr.Estimate, len(images))
+	var mu sync.Mutex
+	indexes := make(map[string]int, len(images))
+	for i, imagePath := range images {
+		indexes[imagePath] = i
+	}
+	results := processBatch(images, concurrency, func(imagePath string) (bool, error) {
+		estimate, err := service.EstimateLLM(imagePath, opts)
+		mu.Lock()
+		estimates[indexes[imagePath]] = estimate
+		mu.Unlock()
+		return false, err
+	})
+
+	return printEstimates(results, estimates, service.Pricing())
+}
+
+// printEstimates writes each image's estimate and the total, returning an error
+// when any image couldn't be estimated
+func printEstimates(results []batchResult, estimates []hocr.Estimate, pricing hocr.Pricing) error {
+	var total hocr.Estimate
+	var failed int
+	for i, result := range results {
+		if result.err != nil {
+			fmt.Fprintf(stdout, "%s: %v\n", result.path, result.err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: %s\n", result.path, formatEstimate(estimates[i], pricing))
+		total = total.Add(estimates[i])
+	}
+
+	if len(results) > 1 {
+		fmt.Fprintf(stdout, "\nTotal for %d images: %s\n", len(results)-failed, formatEstimate(total, pricing))
+	}
+	if pricing.Known {
+		fmt.Fprintf(stdout, "Priced for %s at $%.2f input and $%.2f output per million tokens; re-prompts and retries cost extra.\n", pricing.Model, pricing.InputPerMillion, pricing.OutputPerMillion)
+	} else {
+		fmt.Fprintf(stdout, "No price is listed for %s; set OPENAI_INPUT_PRICE_PER_MILLION and OPENAI_OUTPUT_PRICE_PER_MILLION to price it.\n", pricing.Model)
+	}
+	if failed > 0 {
+		return errors.New("some images couldn't be estimated")
+	}
+	return nil
+}
+
+func formatEstimate(estimate hocr.Estimate, pricing hocr.Pricing) string {
+	s := fmt.Sprintf("%d words in %d requests", estimate.Words, estimate.Requests)
+	if estimate.Cached > 0 {
+		s += fmt.Sprintf(" (%d cached)", estimate.Cached)
+	}
+	s += fmt.Sprintf(", ~%d input and ~%d output tokens", estimate.InputTokens, estimate.OutputTokens)
+	if pricing.Known {
+		s += fmt.Sprintf(", ~$%.4f", estimate.CostUSD)
+	}
+	return s
+}
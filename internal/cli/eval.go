@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/eval"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func runEval(args []string) error {
+	flags := newFlagSet("eval", "", "Runs the OCR pipeline over ground truth images and scores it against their\ntranscripts, writing eval_<timestamp>.json and .csv reports.\n\nThe CSV has a header row with image_path and transcript_path columns, and\noptionally identifier and public.")
+	csvPath := flags.String("csv", "", "ground truth CSV (required)")
+	rows := flags.String("rows", "", "comma separated row numbers to evaluate, counting from 1 after the header (default: all)")
+	outputDir := flags.String("o", "eval_results", "directory the reports are written to")
+	model := flags.String("model", "", "LLM model, overriding OPENAI_MODEL")
+	var normalization models.NormalizationConfig
+	flags.StringVar(&normalization.Unicode, "unicode", "", "unicode normalization before scoring: nfc, nfkc or none")
+	flags.BoolVar(&normalization.PreserveCase, "preserve-case", false, "count differences in case as errors")
+	flags.BoolVar(&normalization.StripPunctuation, "strip-punctuation", false, "ignore punctuation")
+	flags.BoolVar(&normalization.FoldHistorical, "fold-historical", false, "read long s as s and split ligatures")
+	var pipeline pipelineFlags
+	pipeline.register(flags)
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if err := wantArgs(flags, args, 0); err != nil {
+		return err
+	}
+	if *csvPath == "" {
+		fmt.Fprintln(stderr, "-csv is required")
+		flags.Usage()
+		return errUsage
+	}
+	selected, err := parseRows(*rows)
+	if err != nil {
+		return err
+	}
+
+	if *model != "" {
+		os.Setenv("OPENAI_MODEL", *model)
+	}
+	service := hocr.NewService(hocr.DirsFromEnv())
+	if _, err := pipeline.options(service); err != nil {
+		return err
+	}
+	config := models.EvalConfig{
+		CSVPath:       *csvPath,
+		TestRows:      selected,
+		Engine:        pipeline.engine,
+		Binarization:  pipeline.binarization,
+		Language:      pipeline.language,
+		DocumentType:  pipeline.documentType,
+		Characters:    pipeline.characters,
+		Normalization: normalization,
+	}
+	if config.Engine == "" {
+		config.Engine = service.DefaultEngine()
+	}
+	if config.Engine == hocr.EngineLLM {
+		config.Model = service.Model()
+	}
+
+	report, err := eval.Run(config, eval.ServiceTranscriber(service, config))
+	if err != nil {
+		return err
+	}
+	jsonPath, err := report.Write(*outputDir)
+	if err != nil {
+		return err
+	}
+	csvReport := strings.TrimSuffix(jsonPath, filepath.Ext(jsonPath)) + ".csv"
+	if err := writeEvalCSV(csvReport, report.Results); err != nil {
+		return err
+	}
+
+	summary := report.Summary
+	fmt.Fprintf(stdout, "%d rows, %d failed: CER %.4f, WER %.4f, word accuracy %.4f\n", summary.Rows, summary.Failed, summary.CharacterErrorRate, summary.WordErrorRate, summary.WordAccuracy)
+	for _, failure := range report.Failures {
+		fmt.Fprintf(stdout, "  row %d (%s): %s\n", failure.Row, failure.Identifier, failure.Error)
+	}
+	fmt.Fprintf(stdout, "Reports: %s, %s\n", jsonPath, csvReport)
+	if summary.Rows > 0 && summary.Failed == summary.Rows {
+		return fmt.Errorf("every row failed")
+	}
+	return nil
+}
+
+// parseRows reads a list of row numbers such as "1,3,5"
+func parseRows(value string) ([]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var rows []int
+	for _, field := range strings.Split(value, ",") {
+		row, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || row < 1 {
+			return nil, fmt.Errorf("invalid row number %q", field)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func writeEvalCSV(path string, results []models.EvalResult) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := eval.WriteCSV(file, results); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
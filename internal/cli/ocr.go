@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// pipelineFlags are the OCR settings shared by the commands that run the pipeline
+type pipelineFlags struct {
+	engine       string
+	language     string
+	documentType string
+	characters   models.CharacterConfig
+	binarization models.BinarizationConfig
+}
+
+func (p *pipelineFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&p.engine, "engine", "", "OCR engine: llm, tesseract or detect (default: the best available)")
+	fs.StringVar(&p.language, "lang", "", "language of the text, as Tesseract codes such as deu+lat, or ISO codes or names")
+	fs.StringVar(&p.documentType, "type", "", "how the pages were printed: fraktur, or modern (default)")
+	fs.StringVar(&p.characters.Allowed, "allow", "", "the only characters the text may hold, with sets such as {digits} or {latin}, as in {digits}.,-")
+	fs.StringVar(&p.characters.Forbidden, "forbid", "", "characters the text may not hold, with sets such as {cyrillic}")
+	fs.StringVar(&p.binarization.Method, "binarization", "", "binarization method: fixed, otsu or sauvola")
+	fs.Float64Var(&p.binarization.Threshold, "threshold", 0, "threshold percentage for fixed binarization")
+	fs.IntVar(&p.binarization.WindowSize, "window-size", 0, "window size for sauvola binarization")
+	fs.Float64Var(&p.binarization.K, "k", 0, "k for sauvola binarization")
+}
+
+// options checks the engine can run here and the language, document type and
+// characters are known, and builds the pipeline options
+func (p *pipelineFlags) options(service *hocr.Service) (hocr.Options, error) {
+	if err := service.ValidateEngine(p.engine); err != nil {
+		return hocr.Options{}, err
+	}
+	return p.parse()
+}
+
+// parse reads the language, document type and characters into the pipeline
+// options
+func (p *pipelineFlags) parse() (hocr.Options, error) {
+	language, err := hocr.ParseLanguage(p.language)
+	if err != nil {
+		return hocr.Options{}, err
+	}
+	documentType, err := hocr.ParseDocumentType(p.documentType)
+	if err != nil {
+		return hocr.Options{}, err
+	}
+	charset, err := hocr.ParseCharset(p.characters)
+	if err != nil {
+		return hocr.Options{}, err
+	}
+	p.language, p.documentType = language, documentType
+	return hocr.Options{Engine: p.engine, Binarization: p.binarization, Language: p.language, DocumentType: p.documentType, Characters: charset}, nil
+}
+
+// hocrPath is where an image's hOCR is written by default: beside it, with
+// the extension replaced
+func hocrPath(imagePath string) string {
+	return strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".hocr"
+}
+
+func runOCR(args []string) error {
+	fs := newFlagSet("ocr", "<image>", "Runs word detection and transcription on an image and writes its hOCR,\nwithout starting the server. An image of - is read from stdin, and its hOCR\nwritten to stdout; logs always go to stderr, as in\n\n  cat page.png | hocredit ocr - > page.hocr")
+	output := fs.String("o", "", "hOCR file to write, or - for stdout (default: the image's name with a .hocr extension, or stdout for stdin)")
+	dryRun := fs.Bool("dry-run", false, "detect and stitch words, then print the estimated LLM tokens and cost instead of transcribing")
+	var pipeline pipelineFlags
+	pipeline.register(fs)
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := wantArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	imagePath := args[0]
+	if imagePath == "-" {
+		if imagePath, err = readStdinImage(); err != nil {
+			return err
+		}
+		defer os.Remove(imagePath)
+		if *output == "" {
+			*output = "-"
+		}
+	} else if _, err := os.Stat(imagePath); err != nil {
+		return err
+	}
+	if *dryRun {
+		return estimateImages([]string{imagePath}, 1, pipeline)
+	}
+	service := hocr.NewService(hocr.DirsFromEnv())
+	opts, err := pipeline.options(service)
+	if err != nil {
+		return err
+	}
+
+	hocrXML, err := service.ProcessImageToHOCR(imagePath, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", imagePath, err)
+	}
+
+	if *output == "-" {
+		_, err := io.WriteString(stdout, hocrXML)
+		return err
+	}
+	if *output == "" {
+		*output = hocrPath(imagePath)
+	}
+	if err := os.WriteFile(*output, []byte(hocrXML), 0644); err != nil {
+		return err
+	}
+	slog.Info("Wrote hOCR", "image", imagePath, "output", *output)
+	return nil
+}
+
+// readStdinImage saves the image piped to stdin to a temporary file, named
+// with the extension of its format since the pipeline's tools go by it. The
+// caller removes the file.
+func readStdinImage() (string, error) {
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("no image on stdin")
+	}
+	_, ext, ok := utils.SniffImageType(data[:min(len(data), utils.SniffLength)])
+	if !ok {
+		return "", fmt.Errorf("stdin is not a supported image")
+	}
+
+	file, err := os.CreateTemp("", "hocredit-stdin-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/export"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+)
+
+func runPDF(args []string) error {
+	fs := newFlagSet("pdf", "<directory>", "Assembles the page images under a directory, in lexical order, into one\nsearchable PDF with a bookmark per page. Each image needs its corrected hOCR\nbeside it, named as the ocr and batch commands write it.")
+	output := fs.String("o", "", "PDF to write (default: the directory's name with a .pdf extension)")
+	dpi := fs.Int("dpi", 0, "resolution of the images, sizing the pages (default: the hOCR's scan_res, or 300)")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := wantArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	dir := filepath.Clean(args[0])
+	images, err := findImages(dir)
+	if err != nil {
+		return err
+	}
+	pages, err := pdfPages(dir, images, *dpi)
+	if err != nil {
+		return err
+	}
+	pdf, err := export.SearchablePDF(pages)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		*output = dir + ".pdf"
+	}
+	if err := os.WriteFile(*output, pdf, 0644); err != nil {
+		return err
+	}
+	slog.Info("Wrote PDF", "dir", dir, "pages", len(pages), "output", *output)
+	return nil
+}
+
+// pdfPages reads each image and its hOCR, bookmarking pages by their path
+// within the directory. Every missing or unusable page is reported at once.
+func pdfPages(dir string, images []string, dpi int) ([]export.PDFPage, error) {
+	var pages []export.PDFPage
+	var problems []string
+	for _, imagePath := range images {
+		if strings.EqualFold(filepath.Ext(imagePath), ".jp2") {
+			problems = append(problems, imagePath+": JPEG 2000 images can't be embedded")
+			continue
+		}
+		page, err := pdfPage(imagePath)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", imagePath, err))
+			continue
+		}
+		if dpi > 0 {
+			page.DPI = dpi
+		}
+		title, err := filepath.Rel(dir, imagePath)
+		if err != nil {
+			title = filepath.Base(imagePath)
+		}
+		page.Title = strings.TrimSuffix(title, filepath.Ext(title))
+		pages = append(pages, page)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%d of %d pages can't be used:\n  %s", len(problems), len(images), strings.Join(problems, "\n  "))
+	}
+	return pages, nil
+}
+
+func pdfPage(imagePath string) (export.PDFPage, error) {
+	data, err := os.ReadFile(hocrPath(imagePath))
+	if err != nil {
+		return export.PDFPage{}, fmt.Errorf("no hOCR: %w", err)
+	}
+	hocrXML := string(data)
+	lines, err := hocr.ParseHOCRLines(hocrXML)
+	if err != nil {
+		return export.PDFPage{}, err
+	}
+	page, err := hocr.ParsePage(hocrXML)
+	if err != nil {
+		return export.PDFPage{}, err
+	}
+	image, err := os.ReadFile(imagePath)
+	if err != nil {
+		return export.PDFPage{}, err
+	}
+	return export.PDFPage{Image: image, Width: page.Width, Height: page.Height, Lines: lines, DPI: page.DPI}, nil
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/handlers"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/migrate"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/sandbox"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+func runServe(args []string) error {
+	flags := newFlagSet("serve", "", "Runs pending migrations and serves the editor and API until interrupted.\nSettings come from the environment, then the -config file, then .env;\nflags override all of them.")
+	addr := flags.String("addr", ":8888", "address to listen on")
+	config := flags.String("config", "", "file of settings in the sample.env format, or as flat YAML (KEY: value)")
+	uploads := flags.String("uploads-dir", "", "where uploaded images and their hOCR are kept (UPLOADS_DIR, default uploads)")
+	static := flags.String("static-dir", "", "serve the editor's files from here instead of those built in, for development (STATIC_DIR)")
+	cache := flags.String("cache-dir", "", "converted images, tiles and other rebuildable files (CACHE_DIR, default cache)")
+	archive := flags.String("archive-dir", "", "raw engine output and job diagnostics (ARCHIVE_DIR, default archive)")
+	data := flags.String("data-dir", "", "sessions, jobs and the schema version (DATA_DIR, default data)")
+	temp := flags.String("temp-dir", "", "scratch files (TEMP_DIR, default the system temp directory)")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if err := wantArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	if *config != "" {
+		if err := loadConfig(*config); err != nil {
+			return err
+		}
+		// The config may change how logs are redacted and how many are kept
+		setupLogging()
+	}
+	for key, value := range map[string]string{
+		"UPLOADS_DIR": *uploads,
+		"STATIC_DIR":  *static,
+		"CACHE_DIR":   *cache,
+		"ARCHIVE_DIR": *archive,
+		"DATA_DIR":    *data,
+		"TEMP_DIR":    *temp,
+	} {
+		if value != "" {
+			os.Setenv(key, value)
+		}
+	}
+	dirs := handlers.DirsFromEnv()
+
+	migrations := migrate.All()
+	target := utils.GetEnvInt("MIGRATIONS_TARGET", migrate.Latest(migrations))
+	dryRun := os.Getenv("MIGRATIONS_DRY_RUN") == "true"
+	relocated := map[string]string{"uploads": dirs.Uploads, "cache": dirs.Cache, "archive": dirs.Archive, "data": dirs.Data}
+	if sessions := handlers.SessionStoreDir(dirs.Data); sessions != "" {
+		relocated["data/sessions"] = sessions
+	}
+	if err := migrate.Run(".", relocated, migrations, target, dryRun); err != nil {
+		return fmt.Errorf("migrations failed: %w", err)
+	}
+	if dryRun || target < migrate.Latest(migrations) {
+		// Dry runs only report, and a rolled back schema is for an older release to serve
+		slog.Info("Migrations finished, not starting server", "target", target, "dry_run", dryRun)
+		return nil
+	}
+
+	handler := handlers.New(dirs)
+	if sandbox.Enabled() {
+		go handler.RunSandboxPurge()
+	}
+	go handler.RunStorageCleanup()
+
+	mux := handler.Routes()
+	mux.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte("OK"))
+		if err != nil {
+			slog.Error("Unable to write healthcheck", "err", err)
+			os.Exit(1)
+		}
+	})
+	server := &http.Server{Addr: *addr, Handler: mux}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	go func() {
+		slog.Info("hOCR Editor interface available", "addr", *addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			utils.ExitOnError("Server failed to start", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+
+	// Jobs drain while the API still answers, so editors can follow them to the
+	// end; then open requests finish and everything is written to disk. Open
+	// requests get their own share of the timeout, so jobs that never finish
+	// can't leave them none.
+	timeout := time.Duration(utils.GetEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 120)) * time.Second
+	requestTimeout := timeout / 4
+	slog.Info("Shutting down", "timeout", timeout)
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), timeout-requestTimeout)
+	defer cancelDrain()
+	handler.Drain(drainCtx)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Requests still open at shutdown", "err", err)
+	}
+	if err := handler.Flush(); err != nil {
+		slog.Error("Failed to save state at shutdown", "err", err)
+	}
+	slog.Info("Shutdown complete")
+	return nil
+}
+
+// loadConfig applies the settings of a config file over those read from .env,
+// leaving variables set in the process environment alone
+func loadConfig(path string) error {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	for key, value := range values {
+		if !processEnv[key] {
+			os.Setenv(key, value)
+		}
+	}
+	slog.Info("Loaded config", "path", path, "settings", len(values))
+	return nil
+}
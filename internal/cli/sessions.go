@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/handlers"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+func runSessions(args []string) error {
+	if len(args) > 0 && args[0] == "pull" {
+		return runSessionsPull(args[1:])
+	}
+	fmt.Fprintln(stderr, "Usage: hocredit sessions pull [flags] <session-id>\n\nRun hocredit sessions pull -h for its flags.")
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help" || args[0] == "help") {
+		return flag.ErrHelp
+	}
+	return errUsage
+}
+
+// apiClient calls the JSON API of a running server
+type apiClient struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// get fetches a URL, which may be relative to the server, failing on anything but 200
+func (c apiClient) get(target string) ([]byte, error) {
+	base, err := url.Parse(c.server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL: %w", err)
+	}
+	ref, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	resolved := base.ResolveReference(ref)
+
+	req, err := http.NewRequest("GET", resolved.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// The token is only for the server, not for images hosted elsewhere
+	if c.token != "" && resolved.Host == base.Host {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s: %s", resolved, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func runSessionsPull(args []string) error {
+	fs := newFlagSet("sessions pull", "<session-id>", "Downloads a session from a running server for archiving: session.json with\nthe full record, metrics.json with each image's accuracy metrics, and for\neach image, in order, page_0001.jpg and page_0001.hocr holding its current\n(corrected, else original) hOCR. The directory can be passed to the pdf command.")
+	server := fs.String("server", envOrDefault("HOCREDIT_SERVER", "http://localhost:8888"), "server URL (HOCREDIT_SERVER)")
+	token := fs.String("token", os.Getenv("HOCREDIT_TOKEN"), "bearer token, when the server requires one (HOCREDIT_TOKEN)")
+	output := fs.String("o", "", "directory to write to (default: the session ID)")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := wantArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	sessionID := args[0]
+	if *output == "" {
+		*output = sessionID
+	}
+	client := apiClient{server: strings.TrimSuffix(*server, "/") + "/", token: *token, http: &http.Client{Timeout: 5 * time.Minute}}
+	sessionPath := strings.TrimPrefix(handlers.APIPrefix, "/") + "/sessions/" + url.PathEscape(sessionID)
+
+	sessionJSON, err := client.get(sessionPath)
+	if err != nil {
+		return err
+	}
+	var session models.CorrectionSession
+	if err := json.Unmarshal(sessionJSON, &session); err != nil {
+		return fmt.Errorf("invalid session: %w", err)
+	}
+	report, err := client.get(sessionPath + "/report")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*output, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(*output, "session.json"), sessionJSON, 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(*output, "metrics.json"), report, 0644); err != nil {
+		return err
+	}
+	for i, image := range session.Images {
+		name := fmt.Sprintf("page_%04d", i+1)
+		if err := pullImage(client, image, filepath.Join(*output, name)); err != nil {
+			return fmt.Errorf("image %s: %w", image.ID, err)
+		}
+		slog.Info("Pulled image", "image", image.ID, "file", name)
+	}
+
+	fmt.Fprintf(stdout, "Pulled %d images of %s to %s\n", len(session.Images), sessionID, *output)
+	return nil
+}
+
+// pullImage writes an image's file and current hOCR beside each other, the
+// image named with the extension of its format
+func pullImage(client apiClient, image models.ImageItem, base string) error {
+	hocrXML := image.CorrectedHOCR
+	if hocrXML == "" {
+		hocrXML = image.OriginalHOCR
+	}
+	if err := os.WriteFile(base+".hocr", []byte(hocrXML), 0644); err != nil {
+		return err
+	}
+	if image.ImageURL == "" {
+		return nil
+	}
+
+	data, err := client.get(image.ImageURL)
+	if err != nil {
+		return err
+	}
+	ext := strings.ToLower(path.Ext(image.ImagePath))
+	if _, sniffed, ok := utils.SniffImageType(data[:min(len(data), utils.SniffLength)]); ok {
+		ext = sniffed
+	}
+	return os.WriteFile(base+ext, data, 0644)
+}
+
+// envOrDefault reads an environment variable, falling back when it's unset
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,241 @@
+// Package drupal makes HTTP requests to Drupal (Islandora) sites. Sites that
+// aren't public get credentials per host from the JSON file in DRUPAL_AUTH_FILE:
+//
+//	{
+//	  "islandora.example.edu": {"type": "basic", "username": "ocr", "password": "${DRUPAL_PASSWORD}"},
+//	  "dams.example.edu": {"type": "jwt", "token": "${DAMS_JWT}"},
+//	  "archives.example.edu": {"type": "cookie", "username": "ocr", "password": "${ARCHIVES_PASSWORD}"}
+//	}
+//
+// ${VAR} references are expanded from the environment, so secrets can stay out of
+// the file. Cookie credentials log in through Drupal's /user/login?_format=json
+// and reuse the session cookie until Drupal rejects it. Requests to hosts without
+// credentials are sent as they are.
+//
+// Requests that time out, lose their connection or are turned away with 429,
+// 502, 503 or 504 are retried with backoff under the client's Policy.
+package drupal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Credential types
+const (
+	AuthBasic  = "basic"
+	AuthJWT    = "jwt"
+	AuthCookie = "cookie"
+)
+
+// Credentials authenticate requests to one Drupal host
+type Credentials struct {
+	Type     string `json:"type"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+	// LoginURL overrides https://{host}/user/login?_format=json for cookie credentials
+	LoginURL string `json:"login_url,omitempty"`
+}
+
+func (c Credentials) validate() error {
+	switch c.Type {
+	case AuthBasic, AuthCookie:
+		if c.Username == "" || c.Password == "" {
+			return fmt.Errorf("%s credentials need a username and password", c.Type)
+		}
+	case AuthJWT:
+		if c.Token == "" {
+			return fmt.Errorf("jwt credentials need a token")
+		}
+	default:
+		return fmt.Errorf("unknown credential type: %q", c.Type)
+	}
+	return nil
+}
+
+// LoadCredentials reads a credentials file, keyed by host (with a port if the
+// site isn't on the default one)
+func LoadCredentials(path string) (map[string]Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Drupal auth file: %w", err)
+	}
+
+	var entries map[string]Credentials
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse Drupal auth file: %w", err)
+	}
+	credentials := make(map[string]Credentials, len(entries))
+	for host, credential := range entries {
+		credential.Username = os.ExpandEnv(credential.Username)
+		credential.Password = os.ExpandEnv(credential.Password)
+		credential.Token = os.ExpandEnv(credential.Token)
+		if err := credential.validate(); err != nil {
+			return nil, fmt.Errorf("host %s: %w", host, err)
+		}
+		credentials[strings.ToLower(host)] = credential
+	}
+	return credentials, nil
+}
+
+// Client sends requests with the credentials of the host they're addressed to
+type Client struct {
+	http        *http.Client
+	credentials map[string]Credentials
+	// Policy times out and retries requests; the zero Policy sends each once
+	Policy Policy
+
+	mu sync.Mutex
+	// sessions holds the cookie header of each host logged in to
+	sessions map[string]string
+}
+
+// NewClient wraps an HTTP client, http.DefaultClient when nil
+func NewClient(httpClient *http.Client, credentials map[string]Credentials) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{http: httpClient, credentials: credentials, sessions: map[string]string{}}
+}
+
+// NewClientFromEnv loads credentials from DRUPAL_AUTH_FILE, if set, and the
+// retry policy from PolicyFromEnv
+func NewClientFromEnv() (*Client, error) {
+	var credentials map[string]Credentials
+	if path := os.Getenv("DRUPAL_AUTH_FILE"); path != "" {
+		var err error
+		if credentials, err = LoadCredentials(path); err != nil {
+			return nil, err
+		}
+		hosts := make([]string, 0, len(credentials))
+		for host := range credentials {
+			hosts = append(hosts, host)
+		}
+		slog.Info("Drupal credentials loaded", "hosts", hosts)
+	}
+
+	policy := PolicyFromEnv()
+	client := NewClient(&http.Client{Timeout: policy.Timeout}, credentials)
+	client.Policy = policy
+	return client, nil
+}
+
+// HasCredentials reports whether requests to the URL's host are authenticated
+func (c *Client) HasCredentials(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	_, ok := c.credentials[strings.ToLower(u.Host)]
+	return ok
+}
+
+// Get fetches a URL
+func (c *Client) Get(rawURL string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Do sends a request, adding the host's credentials. A cookie session Drupal
+// rejects is renewed and the request sent once more. When retries run out the
+// error is a *RequestError.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Host)
+	credential, ok := c.credentials[host]
+	if !ok {
+		return c.send(req)
+	}
+
+	switch credential.Type {
+	case AuthBasic:
+		req.SetBasicAuth(credential.Username, credential.Password)
+	case AuthJWT:
+		req.Header.Set("Authorization", "Bearer "+credential.Token)
+	case AuthCookie:
+		return c.doWithSession(req, host, credential)
+	}
+	return c.send(req)
+}
+
+func (c *Client) doWithSession(req *http.Request, host string, credential Credentials) (*http.Response, error) {
+	cookie, err := c.session(req.URL, host, credential, "")
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Cookie", cookie)
+	resp, err := c.send(req)
+	if err != nil || (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+		return resp, err
+	}
+
+	// The session may have expired; log in again unless the body can't be resent
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+	if cookie, err = c.session(req.URL, host, credential, cookie); err != nil {
+		return nil, err
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Cookie", cookie)
+	return c.send(retry)
+}
+
+// session returns the host's session cookie, logging in when there is none or
+// the current one is the stale cookie that was just rejected
+func (c *Client) session(target *url.URL, host string, credential Credentials, stale string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cookie := c.sessions[host]; cookie != "" && cookie != stale {
+		return cookie, nil
+	}
+
+	loginURL := credential.LoginURL
+	if loginURL == "" {
+		loginURL = (&url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/user/login", RawQuery: "_format=json"}).String()
+	}
+	body, _ := json.Marshal(map[string]string{"name": credential.Username, "pass": credential.Password})
+	req, err := http.NewRequest("POST", loginURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create Drupal login request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.send(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to Drupal: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("drupal login failed: HTTP %d - %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var cookies []string
+	for _, cookie := range resp.Cookies() {
+		cookies = append(cookies, cookie.Name+"="+cookie.Value)
+	}
+	if len(cookies) == 0 {
+		return "", fmt.Errorf("drupal login at %s returned no session cookie", loginURL)
+	}
+	c.sessions[host] = strings.Join(cookies, "; ")
+	slog.Info("Logged in to Drupal", "host", host, "user", credential.Username)
+	return c.sessions[host], nil
+}
@@ -0,0 +1,193 @@
+package drupal
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/retry"
+)
+
+func TestLoadCredentials(t *testing.T) {
+	t.Setenv("TEST_DRUPAL_PASSWORD", "s3cret")
+	path := filepath.Join(t.TempDir(), "drupal-auth.json")
+	os.WriteFile(path, []byte(`{"Islandora.example.edu": {"type": "basic", "username": "ocr", "password": "${TEST_DRUPAL_PASSWORD}"}}`), 0600)
+
+	credentials, err := LoadCredentials(path)
+	if err != nil {
+		t.Fatalf("LoadCredentials: %v", err)
+	}
+	if got := credentials["islandora.example.edu"]; got.Password != "s3cret" || got.Username != "ocr" {
+		t.Errorf("credentials = %+v", credentials)
+	}
+
+	os.WriteFile(path, []byte(`{"dams.example.edu": {"type": "jwt"}}`), 0600)
+	if _, err := LoadCredentials(path); err == nil {
+		t.Error("expected an error for a jwt credential without a token")
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	for _, tt := range []struct {
+		credential Credentials
+		want       string
+	}{
+		{Credentials{Type: AuthBasic, Username: "ocr", Password: "pw"}, "Basic b2NyOnB3"},
+		{Credentials{Type: AuthJWT, Token: "abc.def.ghi"}, "Bearer abc.def.ghi"},
+	} {
+		client := NewClient(nil, map[string]Credentials{host: tt.credential})
+		resp, err := client.Get(server.URL + "/node/1/hocr")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if gotAuth != tt.want {
+			t.Errorf("%s: Authorization = %q; want %q", tt.credential.Type, gotAuth, tt.want)
+		}
+	}
+
+	// Other hosts don't get the credentials
+	client := NewClient(nil, map[string]Credentials{"elsewhere.example.edu": {Type: AuthJWT, Token: "t"}})
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if gotAuth != "" {
+		t.Errorf("unexpected Authorization %q", gotAuth)
+	}
+}
+
+func TestClientCookieSession(t *testing.T) {
+	logins := 0
+	valid := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/user/login" {
+			logins++
+			valid = "SESSabc=" + strings.Repeat("x", logins)
+			name, value, _ := strings.Cut(valid, "=")
+			http.SetCookie(w, &http.Cookie{Name: name, Value: value})
+			return
+		}
+		if r.Header.Get("Cookie") != valid {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	client := NewClient(nil, map[string]Credentials{u.Host: {Type: AuthCookie, Username: "ocr", Password: "pw"}})
+	post := func() string {
+		req, _ := http.NewRequest("POST", server.URL+"/media", strings.NewReader("hocr"))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("HTTP %d", resp.StatusCode)
+		}
+		return string(body)
+	}
+
+	if post() != "hocr" || post() != "hocr" || logins != 1 {
+		t.Fatalf("expected one login to serve both requests, got %d", logins)
+	}
+
+	// Expire the session; the client logs in again and resends the body
+	valid = "expired"
+	if got := post(); got != "hocr" || logins != 2 {
+		t.Errorf("after expiry: body %q, %d logins", got, logins)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := NewClient(nil, nil)
+	client.Policy = Policy{Backoff: retry.Backoff{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+
+	req, _ := http.NewRequest("POST", server.URL+"/media", strings.NewReader("hocr"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hocr" || attempts != 3 {
+		t.Errorf("body %q after %d attempts; want the body resent on the third", body, attempts)
+	}
+
+	attempts = 0
+	_, err = client.Get(server.URL + "/down")
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) || requestErr.Status != http.StatusBadGateway || requestErr.Attempts != 4 {
+		t.Fatalf("err = %v; want a 502 RequestError after 4 attempts", err)
+	}
+
+	// Drupal may have saved a POST that a proxy answered with a 502
+	attempts = 0
+	req, _ = http.NewRequest("POST", server.URL+"/down", strings.NewReader("hocr"))
+	resp, err = client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusBadGateway || attempts != 1 {
+		t.Fatalf("got %v after %d attempts; want one 502", err, attempts)
+	}
+	resp.Body.Close()
+
+	// A 404 is Drupal's answer, not a failure to answer
+	attempts = 0
+	resp, err = client.Get(server.URL + "/missing")
+	if err != nil || resp.StatusCode != http.StatusNotFound || attempts != 1 {
+		t.Errorf("got %v after %d attempts; want one 404", err, attempts)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := NewClient(&http.Client{Timeout: 10 * time.Millisecond}, nil)
+	_, err := client.Get(server.URL)
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) || !requestErr.Timeout {
+		t.Fatalf("err = %v; want a timeout", err)
+	}
+}
@@ -0,0 +1,134 @@
+package drupal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/retry"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// Policy bounds each Drupal request and retries the ones that fail because
+// Drupal is overloaded or unreachable. The zero Policy sends every request once
+// without a timeout.
+type Policy struct {
+	// Timeout applies to each attempt, including reading the response body
+	Timeout time.Duration
+	retry.Backoff
+}
+
+// PolicyFromEnv reads DRUPAL_TIMEOUT_SECONDS, DRUPAL_MAX_RETRIES,
+// DRUPAL_RETRY_BASE_DELAY_MS and DRUPAL_RETRY_MAX_DELAY_MS
+func PolicyFromEnv() Policy {
+	return Policy{
+		Timeout: time.Duration(utils.GetEnvInt("DRUPAL_TIMEOUT_SECONDS", 60)) * time.Second,
+		Backoff: retry.Backoff{
+			MaxRetries: max(utils.GetEnvInt("DRUPAL_MAX_RETRIES", 3), 0),
+			BaseDelay:  time.Duration(utils.GetEnvInt("DRUPAL_RETRY_BASE_DELAY_MS", 500)) * time.Millisecond,
+			MaxDelay:   time.Duration(utils.GetEnvInt("DRUPAL_RETRY_MAX_DELAY_MS", 10000)) * time.Millisecond,
+		},
+	}
+}
+
+// isRetryableStatus reports whether Drupal, or a proxy in front of it, failed
+// to answer a request with the method. A 502 or 504 may come after Drupal has
+// handled the request, so only idempotent requests are retried on those; a
+// POST is retried only when it was turned away before being handled.
+func isRetryableStatus(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return retry.Idempotent(method)
+	}
+	return false
+}
+
+// RequestError is a Drupal request that still failed after its retries
+type RequestError struct {
+	Method   string
+	URL      string
+	Attempts int
+	// Status is the last HTTP status, 0 when no response arrived
+	Status  int
+	Timeout bool
+	Err     error
+}
+
+func (e *RequestError) Error() string {
+	var cause string
+	switch {
+	case e.Timeout:
+		cause = "timed out"
+	case e.Status != 0:
+		cause = fmt.Sprintf("HTTP %d %s", e.Status, http.StatusText(e.Status))
+	default:
+		cause = e.Err.Error()
+	}
+	message := fmt.Sprintf("drupal %s %s: %s", e.Method, e.URL, cause)
+	if e.Attempts > 1 {
+		message += fmt.Sprintf(" (after %d attempts)", e.Attempts)
+	}
+	return message
+}
+
+func (e *RequestError) Unwrap() error { return e.Err }
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// send makes a request, retrying overloaded responses while the body can be
+// sent again. Dropped connections are retried for idempotent requests only,
+// since Drupal may have handled the request before the connection went.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	replayable := req.Body == nil || req.GetBody != nil
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.http.Do(req)
+		failure := &RequestError{Method: req.Method, URL: req.URL.Redacted(), Attempts: attempt + 1, Err: err}
+		var retryAfter time.Duration
+		retryable := true
+		if err != nil {
+			failure.Timeout = isTimeout(err)
+			retryable = retry.Idempotent(req.Method)
+		} else if isRetryableStatus(req.Method, resp.StatusCode) {
+			failure.Status = resp.StatusCode
+			failure.Err = errors.New(resp.Status)
+			retryAfter = min(retry.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), c.Policy.MaxDelay)
+		} else {
+			return resp, nil
+		}
+
+		if !retryable || !replayable || attempt >= c.Policy.MaxRetries || req.Context().Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, failure
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		delay := c.Policy.Delay(attempt, retryAfter)
+		slog.Warn("Drupal request failed, retrying", "method", req.Method, "url", failure.URL, "retry", attempt+1, "max_retries", c.Policy.MaxRetries, "delay", delay, "err", failure.Err)
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, failure
+		}
+	}
+}
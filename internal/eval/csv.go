@@ -0,0 +1,42 @@
+package eval
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// csvColumns name the CSV columns after the JSON fields of EvalResult, leaving
+// out the raw engine response
+var csvColumns = []string{
+	"identifier", "image_path", "transcript_path", "public",
+	"character_similarity", "word_similarity", "word_accuracy", "word_error_rate",
+	"character_error_rate", "character_error_rate_bare", "total_chars_original", "character_errors",
+	"total_words_original", "total_words_transcribed", "correct_words", "substitutions", "deletions", "insertions",
+}
+
+// WriteCSV writes one row per result under a header row
+func WriteCSV(w io.Writer, results []models.EvalResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+
+	rate := func(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) }
+	for _, result := range results {
+		record := []string{
+			result.Identifier, result.ImagePath, result.TranscriptPath, strconv.FormatBool(result.Public),
+			rate(result.CharacterSimilarity), rate(result.WordSimilarity), rate(result.WordAccuracy), rate(result.WordErrorRate),
+			rate(result.CharacterErrorRate), rate(result.CharacterErrorRateBare), strconv.Itoa(result.TotalCharsOriginal), strconv.Itoa(result.CharacterErrors),
+			strconv.Itoa(result.TotalWordsOriginal), strconv.Itoa(result.TotalWordsTranscribed), strconv.Itoa(result.CorrectWords),
+			strconv.Itoa(result.Substitutions), strconv.Itoa(result.Deletions), strconv.Itoa(result.Insertions),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,249 @@
+// Package eval measures an OCR engine against ground truth transcriptions. The
+// ground truth is a CSV with a header row naming its columns:
+//
+//	identifier,image_path,transcript_path,public
+//	letter-001,images/letter-001.jpg,transcripts/letter-001.txt,true
+//
+// image_path and transcript_path are required, and relative paths are resolved
+// against the CSV's directory. Transcripts are plain text.
+package eval
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/export"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/metrics"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// TimestampFormat matches the timestamps recorded on sessions
+const TimestampFormat = "2006-01-02_15-04-05"
+
+// Transcriber runs the configured engine over an image and returns its hOCR
+type Transcriber func(imagePath string) (string, error)
+
+// ServiceTranscriber runs the OCR pipeline with the engine and binarization
+// the config selects
+func ServiceTranscriber(service *hocr.Service, config models.EvalConfig) Transcriber {
+	return func(imagePath string) (string, error) {
+		charset, err := hocr.ParseCharset(config.Characters)
+		if err != nil {
+			return "", err
+		}
+		return service.ProcessImageToHOCR(imagePath, hocr.Options{Engine: config.Engine, Binarization: config.Binarization, Language: config.Language, DocumentType: config.DocumentType, Characters: charset})
+	}
+}
+
+// Row is one page of ground truth. Number counts data rows from 1, not
+// counting the header.
+type Row struct {
+	Number         int
+	Identifier     string
+	ImagePath      string
+	TranscriptPath string
+	Public         bool
+}
+
+// Failure records a row that couldn't be evaluated
+type Failure struct {
+	Row        int    `json:"row"`
+	Identifier string `json:"identifier"`
+	Error      string `json:"error"`
+}
+
+// Summary totals the evaluated rows. Rates are computed over all characters
+// and words rather than averaged per page, so long pages weigh more.
+type Summary struct {
+	Rows               int     `json:"rows"`
+	Failed             int     `json:"failed"`
+	CharacterErrorRate float64 `json:"character_error_rate"`
+	WordErrorRate      float64 `json:"word_error_rate"`
+	WordAccuracy       float64 `json:"word_accuracy"`
+}
+
+// Report is the outcome of one evaluation run
+type Report struct {
+	Config   models.EvalConfig   `json:"config"`
+	Results  []models.EvalResult `json:"results"`
+	Failures []Failure           `json:"failures,omitempty"`
+	Summary  Summary             `json:"summary"`
+}
+
+// ReadRows loads the ground truth CSV, keeping only the selected row numbers
+// when any are given
+func ReadRows(csvPath string, selected []int) ([]Row, error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ground truth CSV: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ground truth CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"image_path", "transcript_path"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("ground truth CSV has no %s column", required)
+		}
+	}
+
+	dir := filepath.Dir(csvPath)
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	var rows []Row
+	for number := 1; ; number++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ground truth CSV: %w", err)
+		}
+		if len(selected) > 0 && !slices.Contains(selected, number) {
+			continue
+		}
+
+		row := Row{
+			Number:         number,
+			Identifier:     field(record, "identifier"),
+			ImagePath:      resolve(field(record, "image_path")),
+			TranscriptPath: resolve(field(record, "transcript_path")),
+		}
+		if row.ImagePath == "" || row.TranscriptPath == "" {
+			return nil, fmt.Errorf("ground truth row %d is missing an image or transcript path", number)
+		}
+		if row.Identifier == "" {
+			row.Identifier = strings.TrimSuffix(filepath.Base(row.ImagePath), filepath.Ext(row.ImagePath))
+		}
+		if public := field(record, "public"); public != "" {
+			row.Public, err = strconv.ParseBool(public)
+			if err != nil {
+				return nil, fmt.Errorf("ground truth row %d: invalid public value %q", number, public)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	for _, number := range selected {
+		if !slices.ContainsFunc(rows, func(row Row) bool { return row.Number == number }) {
+			return nil, fmt.Errorf("ground truth CSV has no row %d", number)
+		}
+	}
+	return rows, nil
+}
+
+// Run transcribes every selected row and scores it against its transcript.
+// Rows that fail are recorded in the report rather than ending the run.
+func Run(config models.EvalConfig, transcribe Transcriber) (Report, error) {
+	if err := metrics.ValidateNormalization(config.Normalization); err != nil {
+		return Report{}, err
+	}
+	rows, err := ReadRows(config.CSVPath, config.TestRows)
+	if err != nil {
+		return Report{}, err
+	}
+	if config.Timestamp == "" {
+		config.Timestamp = time.Now().Format(TimestampFormat)
+	}
+
+	report := Report{Config: config, Results: []models.EvalResult{}}
+	for _, row := range rows {
+		result, err := evaluateRow(row, transcribe, config.Normalization)
+		if err != nil {
+			report.Failures = append(report.Failures, Failure{Row: row.Number, Identifier: row.Identifier, Error: err.Error()})
+			continue
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Summary = Summarize(report.Results, len(report.Failures))
+	return report, nil
+}
+
+func evaluateRow(row Row, transcribe Transcriber, normalization models.NormalizationConfig) (models.EvalResult, error) {
+	truth, err := os.ReadFile(row.TranscriptPath)
+	if err != nil {
+		return models.EvalResult{}, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	hocrXML, err := transcribe(row.ImagePath)
+	if err != nil {
+		return models.EvalResult{}, fmt.Errorf("failed to transcribe image: %w", err)
+	}
+	lines, err := hocr.ParseHOCRLines(hocrXML)
+	if err != nil {
+		return models.EvalResult{}, fmt.Errorf("failed to parse hOCR: %w", err)
+	}
+
+	transcribed := export.PlainText(lines)
+	result := metrics.CalculateAccuracyMetrics(string(truth), transcribed, normalization)
+	result.Identifier = row.Identifier
+	result.ImagePath = row.ImagePath
+	result.TranscriptPath = row.TranscriptPath
+	result.Public = row.Public
+	result.OpenAIResponse = transcribed
+	return result, nil
+}
+
+// Summarize totals results, counting failed rows that produced none
+func Summarize(results []models.EvalResult, failed int) Summary {
+	summary := Summary{Rows: len(results) + failed, Failed: failed}
+	var chars, charErrors, words, wordErrors int
+	for _, result := range results {
+		chars += result.TotalCharsOriginal
+		charErrors += result.CharacterErrors
+		words += result.TotalWordsOriginal
+		wordErrors += result.Substitutions + result.Deletions + result.Insertions
+	}
+	if chars > 0 {
+		summary.CharacterErrorRate = float64(charErrors) / float64(chars)
+	}
+	if words > 0 {
+		summary.WordErrorRate = float64(wordErrors) / float64(words)
+		summary.WordAccuracy = 1 - summary.WordErrorRate
+	}
+	return summary
+}
+
+// Write saves the report as eval_<timestamp>.json in dir and returns its path
+func (r Report) Write(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create report directory: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode report: %w", err)
+	}
+	path := filepath.Join(dir, "eval_"+r.Config.Timestamp+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, nil
+}
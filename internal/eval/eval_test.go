@@ -0,0 +1,127 @@
+package eval
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hocrFor(text string) string {
+	var b strings.Builder
+	b.WriteString(`<div class="ocr_page"><span class="ocr_line" id="line_1" title="bbox 0 0 100 20">`)
+	for i, word := range strings.Fields(text) {
+		b.WriteString(`<span class="ocrx_word" id="word_` + string(rune('a'+i)) + `" title="bbox 0 0 10 10">` + word + `</span> `)
+	}
+	b.WriteString(`</span></div>`)
+	return b.String()
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "groundtruth.csv")
+	writeFile(t, csvPath, "identifier,image_path,transcript_path,public\n"+
+		"one,images/one.jpg,text/one.txt,true\n"+
+		",images/two.jpg,text/two.txt,\n"+
+		"three,images/three.jpg,text/three.txt,false\n")
+	writeFile(t, filepath.Join(dir, "text/one.txt"), "the quick brown fox")
+	writeFile(t, filepath.Join(dir, "text/two.txt"), "jumps over")
+
+	transcripts := map[string]string{
+		filepath.Join(dir, "images/one.jpg"): "the qnick brown fox",
+		filepath.Join(dir, "images/two.jpg"): "jumps over",
+	}
+	transcribe := func(imagePath string) (string, error) {
+		text, ok := transcripts[imagePath]
+		if !ok {
+			return "", errors.New("engine failed")
+		}
+		return hocrFor(text), nil
+	}
+
+	report, err := Run(models.EvalConfig{CSVPath: csvPath, Timestamp: "2025-01-02_03-04-05"}, transcribe)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(report.Results) != 2 || len(report.Failures) != 1 {
+		t.Fatalf("got %d results and %d failures, want 2 and 1", len(report.Results), len(report.Failures))
+	}
+	if first := report.Results[0]; first.Identifier != "one" || !first.Public || first.Substitutions != 1 {
+		t.Errorf("first result = %+v", first)
+	}
+	if second := report.Results[1]; second.Identifier != "two" || second.WordErrorRate != 0 {
+		t.Errorf("second result = %+v", second)
+	}
+	if failure := report.Failures[0]; failure.Row != 3 || failure.Identifier != "three" {
+		t.Errorf("failure = %+v", failure)
+	}
+
+	want := Summary{Rows: 3, Failed: 1, CharacterErrorRate: 1.0 / 29, WordErrorRate: 1.0 / 6, WordAccuracy: 5.0 / 6}
+	if report.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", report.Summary, want)
+	}
+
+	path, err := report.Write(filepath.Join(dir, "reports"))
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if filepath.Base(path) != "eval_2025-01-02_03-04-05.json" {
+		t.Errorf("report written to %s", path)
+	}
+}
+
+func TestReadRowsSelected(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "groundtruth.csv")
+	writeFile(t, csvPath, "image_path,transcript_path\na.jpg,a.txt\n/abs/b.jpg,b.txt\n")
+
+	rows, err := ReadRows(csvPath, []int{2})
+	if err != nil {
+		t.Fatalf("ReadRows: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Identifier != "b" || rows[0].ImagePath != "/abs/b.jpg" || rows[0].TranscriptPath != filepath.Join(dir, "b.txt") {
+		t.Errorf("rows = %+v", rows)
+	}
+
+	if _, err := ReadRows(csvPath, []int{5}); err == nil {
+		t.Error("expected an error for a missing row")
+	}
+	writeFile(t, csvPath, "identifier,image_path\nx,a.jpg\n")
+	if _, err := ReadRows(csvPath, nil); err == nil {
+		t.Error("expected an error without a transcript_path column")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b strings.Builder
+	results := []models.EvalResult{{Identifier: "img_1", ImagePath: "a.jpg", WordErrorRate: 0.25, TotalWordsOriginal: 4, Substitutions: 1}}
+	if err := WriteCSV(&b, results); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header and one row, got %q", b.String())
+	}
+	if !strings.HasPrefix(lines[0], "identifier,image_path,") {
+		t.Errorf("header = %s", lines[0])
+	}
+	want := "img_1,a.jpg,,false,0.000000,0.000000,0.000000,0.250000,0.000000,0.000000,0,0,4,0,0,1,0,0"
+	if lines[1] != want {
+		t.Errorf("row = %s, want %s", lines[1], want)
+	}
+}
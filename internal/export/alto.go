@@ -0,0 +1,113 @@
+package export
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// ALTONamespace is the ALTO v4 schema namespace
+const ALTONamespace = "http://www.loc.gov/standards/alto/ns-v4#"
+
+type altoDocument struct {
+	XMLName     xml.Name        `xml:"alto"`
+	Namespace   string          `xml:"xmlns,attr"`
+	Description altoDescription `xml:"Description"`
+	Layout      altoLayout      `xml:"Layout"`
+}
+
+type altoDescription struct {
+	MeasurementUnit string `xml:"MeasurementUnit"`
+	FileName        string `xml:"sourceImageInformation>fileName,omitempty"`
+}
+
+type altoLayout struct {
+	Page altoPage `xml:"Page"`
+}
+
+type altoPage struct {
+	ID          string         `xml:"ID,attr"`
+	Width       int            `xml:"WIDTH,attr"`
+	Height      int            `xml:"HEIGHT,attr"`
+	PhysicalNum int            `xml:"PHYSICAL_IMG_NR,attr"`
+	PrintSpace  altoPrintSpace `xml:"PrintSpace"`
+}
+
+type altoPrintSpace struct {
+	TextBlock altoTextBlock `xml:"TextBlock"`
+}
+
+type altoTextBlock struct {
+	ID    string         `xml:"ID,attr"`
+	Lines []altoTextLine `xml:"TextLine"`
+}
+
+type altoTextLine struct {
+	ID     string `xml:"ID,attr"`
+	HPos   int    `xml:"HPOS,attr"`
+	VPos   int    `xml:"VPOS,attr"`
+	Width  int    `xml:"WIDTH,attr"`
+	Height int    `xml:"HEIGHT,attr"`
+	// Items alternates String and SP elements
+	Items []any
+}
+
+type altoString struct {
+	XMLName xml.Name `xml:"String"`
+	ID      string   `xml:"ID,attr"`
+	Content string   `xml:"CONTENT,attr"`
+	HPos    int      `xml:"HPOS,attr"`
+	VPos    int      `xml:"VPOS,attr"`
+	Width   int      `xml:"WIDTH,attr"`
+	Height  int      `xml:"HEIGHT,attr"`
+	WC      string   `xml:"WC,attr,omitempty"`
+}
+
+type altoSpace struct {
+	XMLName xml.Name `xml:"SP"`
+}
+
+// ALTO converts a page's lines to an ALTO v4 document measured in pixels.
+// Word confidences (0-100) become WC values (0-1); unscored words have none.
+func ALTO(lines []models.HOCRLine, width, height int, fileName string) ([]byte, error) {
+	block := altoTextBlock{ID: "block_1"}
+	for i, line := range lines {
+		textLine := altoTextLine{ID: line.ID, HPos: line.BBox.X1, VPos: line.BBox.Y1, Width: line.BBox.X2 - line.BBox.X1, Height: line.BBox.Y2 - line.BBox.Y1}
+		if textLine.ID == "" {
+			textLine.ID = "line_" + strconv.Itoa(i+1)
+		}
+		for _, word := range line.Words {
+			text := strings.TrimSpace(word.Text)
+			if text == "" {
+				continue
+			}
+			if len(textLine.Items) > 0 {
+				textLine.Items = append(textLine.Items, altoSpace{})
+			}
+			item := altoString{ID: word.ID, Content: text, HPos: word.BBox.X1, VPos: word.BBox.Y1, Width: word.BBox.X2 - word.BBox.X1, Height: word.BBox.Y2 - word.BBox.Y1}
+			if word.Confidence > 0 {
+				item.WC = strconv.FormatFloat(word.Confidence/100, 'f', -1, 64)
+			}
+			textLine.Items = append(textLine.Items, item)
+		}
+		if len(textLine.Items) > 0 {
+			block.Lines = append(block.Lines, textLine)
+		}
+	}
+
+	doc := altoDocument{
+		Namespace:   ALTONamespace,
+		Description: altoDescription{MeasurementUnit: "pixel", FileName: fileName},
+		Layout: altoLayout{Page: altoPage{
+			ID: "page_1", Width: width, Height: height, PhysicalNum: 1,
+			PrintSpace: altoPrintSpace{TextBlock: block},
+		}},
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,41 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestALTO(t *testing.T) {
+	lines := []models.HOCRLine{{
+		ID:   "line_1",
+		BBox: models.BBox{X1: 10, Y1: 20, X2: 110, Y2: 40},
+		Words: []models.HOCRWord{
+			{ID: "word_1", Text: "Lehigh", BBox: models.BBox{X1: 10, Y1: 20, X2: 60, Y2: 40}, Confidence: 95},
+			{ID: "word_2", Text: "A&M", BBox: models.BBox{X1: 70, Y1: 20, X2: 110, Y2: 40}},
+		},
+	}, {ID: "line_2", Words: []models.HOCRWord{{Text: " "}}}}
+
+	data, err := ALTO(lines, 800, 1000, "page.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(data)
+	for _, want := range []string{
+		`<alto xmlns="` + ALTONamespace + `">`,
+		`<fileName>page.jpg</fileName>`,
+		`<Page ID="page_1" WIDTH="800" HEIGHT="1000" PHYSICAL_IMG_NR="1">`,
+		`<TextLine ID="line_1" HPOS="10" VPOS="20" WIDTH="100" HEIGHT="20">`,
+		`<String ID="word_1" CONTENT="Lehigh" HPOS="10" VPOS="20" WIDTH="50" HEIGHT="20" WC="0.95"></String>`,
+		`<SP></SP>`,
+		`<String ID="word_2" CONTENT="A&amp;M" HPOS="70" VPOS="20" WIDTH="40" HEIGHT="20"></String>`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("missing %s in\n%s", want, doc)
+		}
+	}
+	if strings.Contains(doc, "line_2") {
+		t.Error("empty line was exported")
+	}
+}
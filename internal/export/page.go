@@ -0,0 +1,126 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// PAGENamespace is the PAGE 2019 schema namespace
+const PAGENamespace = "http://schema.primaresearch.org/PAGE/gts/pagecontent/2019-07-15"
+
+type pageDocument struct {
+	XMLName   xml.Name     `xml:"PcGts"`
+	Namespace string       `xml:"xmlns,attr"`
+	Metadata  pageMetadata `xml:"Metadata"`
+	Page      pagePage     `xml:"Page"`
+}
+
+type pageMetadata struct {
+	Creator    string `xml:"Creator"`
+	Created    string `xml:"Created"`
+	LastChange string `xml:"LastChange"`
+}
+
+type pagePage struct {
+	ImageFilename string           `xml:"imageFilename,attr"`
+	ImageWidth    int              `xml:"imageWidth,attr"`
+	ImageHeight   int              `xml:"imageHeight,attr"`
+	Regions       []pageTextRegion `xml:"TextRegion,omitempty"`
+}
+
+type pageTextRegion struct {
+	ID        string         `xml:"id,attr"`
+	Coords    pageCoords     `xml:"Coords"`
+	Lines     []pageTextLine `xml:"TextLine"`
+	TextEquiv pageTextEquiv  `xml:"TextEquiv"`
+}
+
+type pageTextLine struct {
+	ID        string        `xml:"id,attr"`
+	Coords    pageCoords    `xml:"Coords"`
+	Words     []pageWord    `xml:"Word"`
+	TextEquiv pageTextEquiv `xml:"TextEquiv"`
+}
+
+type pageWord struct {
+	ID        string        `xml:"id,attr"`
+	Coords    pageCoords    `xml:"Coords"`
+	TextEquiv pageTextEquiv `xml:"TextEquiv"`
+}
+
+type pageCoords struct {
+	Points string `xml:"points,attr"`
+}
+
+type pageTextEquiv struct {
+	Conf    string `xml:"conf,attr,omitempty"`
+	Unicode string `xml:"Unicode"`
+}
+
+// points outlines a box as PAGE polygon points, clockwise from the top left
+func points(box models.BBox) pageCoords {
+	return pageCoords{Points: fmt.Sprintf("%d,%d %d,%d %d,%d %d,%d", box.X1, box.Y1, box.X2, box.Y1, box.X2, box.Y2, box.X1, box.Y2)}
+}
+
+// PAGE converts a page's lines to a PAGE XML document holding them in one
+// text region. Word confidences (0-100) become conf values (0-1).
+func PAGE(lines []models.HOCRLine, width, height int, fileName string) ([]byte, error) {
+	region := pageTextRegion{ID: "region_1"}
+	var regionBox models.BBox
+	var regionText []string
+	for i, line := range lines {
+		textLine := pageTextLine{ID: line.ID, Coords: points(line.BBox)}
+		if textLine.ID == "" {
+			textLine.ID = "line_" + strconv.Itoa(i+1)
+		}
+		var texts []string
+		for j, word := range line.Words {
+			text := strings.TrimSpace(word.Text)
+			if text == "" {
+				continue
+			}
+			item := pageWord{ID: word.ID, Coords: points(word.BBox), TextEquiv: pageTextEquiv{Unicode: text}}
+			if item.ID == "" {
+				item.ID = textLine.ID + "_word_" + strconv.Itoa(j+1)
+			}
+			if word.Confidence > 0 {
+				item.TextEquiv.Conf = strconv.FormatFloat(word.Confidence/100, 'f', -1, 64)
+			}
+			textLine.Words = append(textLine.Words, item)
+			texts = append(texts, text)
+		}
+		if len(texts) == 0 {
+			continue
+		}
+		textLine.TextEquiv.Unicode = strings.Join(texts, " ")
+		region.Lines = append(region.Lines, textLine)
+		regionText = append(regionText, textLine.TextEquiv.Unicode)
+		if len(region.Lines) == 1 {
+			regionBox = line.BBox
+		} else {
+			regionBox = models.BBox{X1: min(regionBox.X1, line.BBox.X1), Y1: min(regionBox.Y1, line.BBox.Y1), X2: max(regionBox.X2, line.BBox.X2), Y2: max(regionBox.Y2, line.BBox.Y2)}
+		}
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	doc := pageDocument{
+		Namespace: PAGENamespace,
+		Metadata:  pageMetadata{Creator: "hOCRedit", Created: now, LastChange: now},
+		Page:      pagePage{ImageFilename: fileName, ImageWidth: width, ImageHeight: height},
+	}
+	if len(region.Lines) > 0 {
+		region.Coords = points(regionBox)
+		region.TextEquiv.Unicode = strings.Join(regionText, "\n")
+		doc.Page.Regions = []pageTextRegion{region}
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,42 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestPAGE(t *testing.T) {
+	lines := []models.HOCRLine{{
+		ID:   "line_1",
+		BBox: models.BBox{X1: 10, Y1: 20, X2: 110, Y2: 40},
+		Words: []models.HOCRWord{
+			{ID: "word_1", Text: "Lehigh", BBox: models.BBox{X1: 10, Y1: 20, X2: 60, Y2: 40}, Confidence: 95},
+			{ID: "word_2", Text: "A&M", BBox: models.BBox{X1: 70, Y1: 20, X2: 110, Y2: 40}},
+		},
+	}, {ID: "line_2", Words: []models.HOCRWord{{Text: " "}}}}
+
+	data, err := PAGE(lines, 800, 1000, "page.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(data)
+	for _, want := range []string{
+		`<PcGts xmlns="` + PAGENamespace + `">`,
+		`<Page imageFilename="page.jpg" imageWidth="800" imageHeight="1000">`,
+		`<TextRegion id="region_1">`,
+		`<TextLine id="line_1">`,
+		`<Coords points="10,20 60,20 60,40 10,40"></Coords>`,
+		`<TextEquiv conf="0.95">`,
+		`<Unicode>A&amp;M</Unicode>`,
+		`<Unicode>Lehigh A&amp;M</Unicode>`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("missing %s in\n%s", want, doc)
+		}
+	}
+	if strings.Contains(doc, "line_2") {
+		t.Error("empty line was exported")
+	}
+}
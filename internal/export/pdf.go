@@ -0,0 +1,373 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	_ "golang.org/x/image/tiff"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// defaultPDFDPI sizes pages whose resolution isn't known
+const defaultPDFDPI = 300
+
+// pdfGlyphWidth is the advance of every glyph of the text layer's font, in
+// thousandths of the font size; words are stretched to their boxes with Tz
+const pdfGlyphWidth = 500
+
+// PDFPage is a page image and the hOCR lines read from it
+type PDFPage struct {
+	// Image is a JPEG, PNG, GIF or TIFF file. JPEGs are embedded as they are.
+	Image []byte
+	// Width and Height are the size the hOCR is measured in; zero means the
+	// image's size in pixels
+	Width, Height int
+	Lines         []models.HOCRLine
+	// DPI gives the printed page size; zero means 300
+	DPI int
+	// Title, when set, adds a bookmark for the page
+	Title string
+}
+
+// SearchablePDF draws each page's image with its words laid over it as
+// invisible text, so the PDF can be searched and its text selected and copied.
+// The text uses a font without glyphs whose ToUnicode map carries every
+// character, so no script needs an installed font.
+func SearchablePDF(pages []PDFPage) ([]byte, error) {
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("a PDF needs at least one page")
+	}
+
+	w := &pdfWriter{}
+	catalog, info, pagesRoot := w.reserve(), w.reserve(), w.reserve()
+	fonts := newPDFFonts(pages)
+	fontObjects := fonts.write(w)
+
+	var kids []string
+	pageObjects := make([]int, len(pages))
+	for i, page := range pages {
+		imageDict, imageData, imageWidth, imageHeight, err := pdfImage(page.Image)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", i+1, err)
+		}
+		dpi := page.DPI
+		if dpi <= 0 {
+			dpi = defaultPDFDPI
+		}
+		width, height := float64(imageWidth)*72/float64(dpi), float64(imageHeight)*72/float64(dpi)
+		hocrWidth, hocrHeight := page.Width, page.Height
+		if hocrWidth <= 0 || hocrHeight <= 0 {
+			hocrWidth, hocrHeight = imageWidth, imageHeight
+		}
+
+		var content strings.Builder
+		fmt.Fprintf(&content, "q %s 0 0 %s 0 0 cm /Im0 Do Q\n", pdfNumber(width), pdfNumber(height))
+		fonts.drawText(&content, page.Lines, width/float64(hocrWidth), height/float64(hocrHeight), height)
+
+		imageObject, contentObject, pageObject := w.reserve(), w.reserve(), w.reserve()
+		w.stream(imageObject, imageDict, imageData)
+		w.stream(contentObject, "", []byte(content.String()))
+
+		var fontRefs strings.Builder
+		for k, object := range fontObjects {
+			fmt.Fprintf(&fontRefs, "/F%d %d 0 R ", k, object)
+		}
+		w.set(pageObject, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /XObject << /Im0 %d 0 R >> /Font << %s>> >> /Contents %d 0 R >>",
+			pagesRoot, pdfNumber(width), pdfNumber(height), imageObject, fontRefs.String(), contentObject))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObject))
+		pageObjects[i] = pageObject
+	}
+	w.set(pagesRoot, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+
+	if outlines := w.outlines(pages, pageObjects); outlines != 0 {
+		w.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R /Outlines %d 0 R /PageMode /UseOutlines >>", pagesRoot, outlines))
+	} else {
+		w.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesRoot))
+	}
+	w.set(info, "<< /Producer (hOCRedit) >>")
+	return w.bytes(catalog, info), nil
+}
+
+// pdfWriter collects numbered objects and writes them with their cross-reference table
+type pdfWriter struct {
+	objects [][]byte
+}
+
+// reserve numbers an object so others can refer to it before it's written
+func (w *pdfWriter) reserve() int {
+	w.objects = append(w.objects, nil)
+	return len(w.objects)
+}
+
+func (w *pdfWriter) set(object int, body string) {
+	w.objects[object-1] = []byte(body)
+}
+
+func (w *pdfWriter) stream(object int, dict string, data []byte) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<< %s/Length %d >>\nstream\n", dict, len(data))
+	b.Write(data)
+	b.WriteString("\nendstream")
+	w.objects[object-1] = b.Bytes()
+}
+
+func (w *pdfWriter) bytes(catalog, info int) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(w.objects))
+	for i, body := range w.objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		b.Write(body)
+		b.WriteString("\nendobj\n")
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(w.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.objects)+1, catalog, info, xref)
+	return b.Bytes()
+}
+
+// outlines bookmarks the pages that have titles, returning the outline root
+// or 0 when none do
+func (w *pdfWriter) outlines(pages []PDFPage, pageObjects []int) int {
+	var titled []int
+	for i, page := range pages {
+		if page.Title != "" {
+			titled = append(titled, i)
+		}
+	}
+	if len(titled) == 0 {
+		return 0
+	}
+
+	root := w.reserve()
+	items := make([]int, len(titled))
+	for i := range titled {
+		items[i] = w.reserve()
+	}
+	for i, pageIndex := range titled {
+		var links strings.Builder
+		if i > 0 {
+			fmt.Fprintf(&links, " /Prev %d 0 R", items[i-1])
+		}
+		if i < len(items)-1 {
+			fmt.Fprintf(&links, " /Next %d 0 R", items[i+1])
+		}
+		w.set(items[i], fmt.Sprintf("<< /Title %s /Parent %d 0 R%s /Dest [%d 0 R /Fit] >>", pdfString(pages[pageIndex].Title), root, links.String(), pageObjects[pageIndex]))
+	}
+	w.set(root, fmt.Sprintf("<< /Type /Outlines /First %d 0 R /Last %d 0 R /Count %d >>", items[0], items[len(items)-1], len(items)))
+	return root
+}
+
+// pdfFonts assigns every character of the text layer a code in one of a set of
+// Type 3 fonts, 256 characters to a font
+type pdfFonts struct {
+	runes []rune
+	codes map[rune]int
+}
+
+func newPDFFonts(pages []PDFPage) *pdfFonts {
+	fonts := &pdfFonts{codes: map[rune]int{}}
+	for _, page := range pages {
+		for _, line := range page.Lines {
+			for _, word := range line.Words {
+				for _, r := range strings.TrimSpace(word.Text) {
+					if _, ok := fonts.codes[r]; !ok && unicode.IsPrint(r) {
+						fonts.codes[r] = len(fonts.runes)
+						fonts.runes = append(fonts.runes, r)
+					}
+				}
+			}
+		}
+	}
+	return fonts
+}
+
+// write adds the fonts, returning their object numbers
+func (f *pdfFonts) write(w *pdfWriter) []int {
+	if len(f.runes) == 0 {
+		return nil
+	}
+	// Every glyph is the same empty procedure, setting only the advance
+	glyph := w.reserve()
+	w.stream(glyph, "", []byte(fmt.Sprintf("%d 0 0 0 0 0 d1", pdfGlyphWidth)))
+
+	var objects []int
+	for start := 0; start < len(f.runes); start += 256 {
+		runes := f.runes[start:min(start+256, len(f.runes))]
+		font, toUnicode := w.reserve(), w.reserve()
+
+		var names, procs, widths strings.Builder
+		for code := range runes {
+			fmt.Fprintf(&names, "/g%d ", code)
+			fmt.Fprintf(&procs, "/g%d %d 0 R ", code, glyph)
+			fmt.Fprintf(&widths, "%d ", pdfGlyphWidth)
+		}
+		w.set(font, fmt.Sprintf("<< /Type /Font /Subtype /Type3 /FontBBox [0 0 %d 1000] /FontMatrix [0.001 0 0 0.001 0 0] /CharProcs << %s>> /Encoding << /Type /Encoding /Differences [0 %s] >> /FirstChar 0 /LastChar %d /Widths [%s] /Resources << >> /ToUnicode %d 0 R >>",
+			pdfGlyphWidth, procs.String(), strings.TrimSpace(names.String()), len(runes)-1, strings.TrimSpace(widths.String()), toUnicode))
+		w.stream(toUnicode, "", toUnicodeCMap(runes))
+		objects = append(objects, font)
+	}
+	return objects
+}
+
+// toUnicodeCMap maps single byte codes to the characters they stand for
+func toUnicodeCMap(runes []rune) []byte {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<00> <FF>\nendcodespacerange\n")
+	// bfchar blocks hold at most 100 entries
+	for start := 0; start < len(runes); start += 100 {
+		block := runes[start:min(start+100, len(runes))]
+		fmt.Fprintf(&b, "%d beginbfchar\n", len(block))
+		for i, r := range block {
+			fmt.Fprintf(&b, "<%02X> <", start+i)
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, "%04X", unit)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+	return []byte(b.String())
+}
+
+// drawText writes the words as invisible text, each stretched over its box.
+// scaleX and scaleY convert hOCR pixels to points; PDF measures y from the bottom.
+func (f *pdfFonts) drawText(b *strings.Builder, lines []models.HOCRLine, scaleX, scaleY, pageHeight float64) {
+	b.WriteString("BT 3 Tr\n")
+	for _, line := range lines {
+		for _, word := range line.Words {
+			var codes []int
+			for _, r := range strings.TrimSpace(word.Text) {
+				if code, ok := f.codes[r]; ok {
+					codes = append(codes, code)
+				}
+			}
+			if len(codes) == 0 {
+				continue
+			}
+
+			width := float64(word.BBox.X2-word.BBox.X1) * scaleX
+			size := max(float64(word.BBox.Y2-word.BBox.Y1)*scaleY, 1)
+			stretch := width * 100 / (float64(len(codes)) * size * pdfGlyphWidth / 1000)
+			fmt.Fprintf(b, "%s Tz 1 0 0 1 %s %s Tm\n", pdfNumber(max(stretch, 1)), pdfNumber(float64(word.BBox.X1)*scaleX), pdfNumber(pageHeight-float64(word.BBox.Y2)*scaleY))
+
+			// Consecutive characters of the same font are shown together
+			font := -1
+			for i := 0; i < len(codes); {
+				if codes[i]/256 != font {
+					font = codes[i] / 256
+					fmt.Fprintf(b, "/F%d %s Tf ", font, pdfNumber(size))
+				}
+				b.WriteByte('<')
+				for ; i < len(codes) && codes[i]/256 == font; i++ {
+					fmt.Fprintf(b, "%02X", codes[i]%256)
+				}
+				b.WriteString("> Tj\n")
+			}
+		}
+	}
+	b.WriteString("ET\n")
+}
+
+// pdfImage makes an image XObject of an image file. JPEGs are passed through;
+// anything else is decoded and compressed as RGB or grayscale.
+func pdfImage(data []byte) (dict string, stream []byte, width, height int, err error) {
+	if bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}) {
+		config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return "", nil, 0, 0, fmt.Errorf("invalid JPEG: %w", err)
+		}
+		switch config.ColorModel {
+		case color.GrayModel:
+			return fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode ", config.Width, config.Height), data, config.Width, config.Height, nil
+		case color.YCbCrModel:
+			return fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode ", config.Width, config.Height), data, config.Width, config.Height, nil
+		}
+		// CMYK JPEGs are converted rather than guessing at their inversion
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, 0, 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+	bounds := img.Bounds()
+	colorSpace, channels := "/DeviceRGB", 3
+	var pixels []byte
+	if gray, ok := img.(*image.Gray); ok {
+		colorSpace, channels = "/DeviceGray", 1
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			start := gray.PixOffset(bounds.Min.X, y)
+			pixels = append(pixels, gray.Pix[start:start+bounds.Dx()]...)
+		}
+	} else {
+		// Transparency is flattened onto white paper
+		rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Over)
+		pixels = make([]byte, 0, bounds.Dx()*bounds.Dy()*channels)
+		for i := 0; i < len(rgba.Pix); i += 4 {
+			pixels = append(pixels, rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2])
+		}
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(pixels); err != nil {
+		return "", nil, 0, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return "", nil, 0, 0, err
+	}
+	return fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /FlateDecode ", bounds.Dx(), bounds.Dy(), colorSpace), compressed.Bytes(), bounds.Dx(), bounds.Dy(), nil
+}
+
+// pdfNumber formats a real number with at most two decimals
+func pdfNumber(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "" || s == "-" {
+		return "0"
+	}
+	return s
+}
+
+// pdfString writes text as a literal string, or as UTF-16 when it isn't ASCII
+func pdfString(text string) string {
+	ascii := true
+	for _, r := range text {
+		if r < 0x20 || r > 0x7E {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(text) + ")"
+	}
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestSearchablePDF(t *testing.T) {
+	lines := []models.HOCRLine{{Words: []models.HOCRWord{
+		{Text: "Straße", BBox: models.BBox{X1: 30, Y1: 30, X2: 150, Y2: 60}},
+		{Text: "𝔄", BBox: models.BBox{X1: 160, Y1: 30, X2: 190, Y2: 60}},
+	}}}
+	data, err := SearchablePDF([]PDFPage{
+		{Image: testPNG(t, 600, 300), Lines: lines, Title: "Page 1"},
+		{Image: testPNG(t, 600, 300), Title: "Seite zwei – Rückseite"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(data)
+	for _, want := range []string{
+		"%PDF-1.4",
+		"/Subtype /Type3",
+		"/MediaBox [0 0 144 72]",
+		"/Count 2",
+		"/PageMode /UseOutlines",
+		"/Title (Page 1)",
+		"<00DF>",     // ß in the ToUnicode map
+		"<D835DD04>", // 𝔄 as a surrogate pair
+		"3 Tr",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+
+	// Every cross-reference entry points at its object
+	xref := strings.LastIndex(doc, "\nxref\n") + 1
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(doc[xref:], -1)
+	if len(entries) == 0 {
+		t.Fatal("no xref entries")
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		if want := strconv.Itoa(i+1) + " 0 obj"; !strings.HasPrefix(doc[offset:], want) {
+			t.Errorf("xref entry %d points at %q", i+1, doc[offset:offset+10])
+		}
+	}
+	start := regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(doc)
+	if start == nil || start[1] != strconv.Itoa(xref) {
+		t.Errorf("startxref is %v, want %d", start, xref)
+	}
+}
+
+func TestSearchablePDFNoPages(t *testing.T) {
+	if _, err := SearchablePDF(nil); err == nil {
+		t.Error("expected an error without pages")
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	if got := pdfString("a (b)"); got != `(a \(b\))` {
+		t.Errorf("got %s", got)
+	}
+	if got := pdfString("é"); got != "<FEFF00E9>" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,67 @@
+// Package export renders session transcriptions in download formats and
+// computes the statistics attached to every export.
+package export
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// wordsPerMinute is a typical adult silent reading speed for prose
+const wordsPerMinute = 200
+
+// Stats summarizes a transcription for reporting
+type Stats struct {
+	Pages      int `json:"pages"`
+	Lines      int `json:"lines"`
+	Words      int `json:"words"`
+	Characters int `json:"characters"`
+	// ReadingMinutes is an estimate at 200 words per minute, rounded up
+	ReadingMinutes int `json:"reading_minutes"`
+}
+
+// PageStats counts a single page. Lines without any text are skipped and
+// characters exclude whitespace.
+func PageStats(lines []models.HOCRLine) Stats {
+	stats := Stats{Pages: 1}
+	for _, line := range lines {
+		lineHasText := false
+		for _, word := range line.Words {
+			text := strings.TrimSpace(word.Text)
+			if text == "" {
+				continue
+			}
+			lineHasText = true
+			stats.Words++
+			for _, r := range text {
+				if !unicode.IsSpace(r) {
+					stats.Characters++
+				}
+			}
+		}
+		if lineHasText {
+			stats.Lines++
+		}
+	}
+	stats.ReadingMinutes = readingMinutes(stats.Words)
+	return stats
+}
+
+// Add combines page or document totals
+func (s Stats) Add(other Stats) Stats {
+	total := Stats{
+		Pages:      s.Pages + other.Pages,
+		Lines:      s.Lines + other.Lines,
+		Words:      s.Words + other.Words,
+		Characters: s.Characters + other.Characters,
+	}
+	total.ReadingMinutes = readingMinutes(total.Words)
+	return total
+}
+
+func readingMinutes(words int) int {
+	return int(math.Ceil(float64(words) / wordsPerMinute))
+}
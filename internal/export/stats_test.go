@@ -0,0 +1,43 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func line(words ...string) models.HOCRLine {
+	var line models.HOCRLine
+	for _, w := range words {
+		line.Words = append(line.Words, models.HOCRWord{Text: w})
+	}
+	return line
+}
+
+func TestPageStats(t *testing.T) {
+	lines := []models.HOCRLine{
+		line("Lehigh", "Valley"),
+		line(" ", ""),
+		line("Bethlehem,", "Pa."),
+	}
+
+	got := PageStats(lines)
+	want := Stats{Pages: 1, Lines: 2, Words: 4, Characters: 25, ReadingMinutes: 1}
+	if got != want {
+		t.Errorf("PageStats = %+v, want %+v", got, want)
+	}
+
+	if text := PlainText(lines); text != "Lehigh Valley\nBethlehem, Pa.\n" {
+		t.Errorf("PlainText = %q", text)
+	}
+}
+
+func TestStatsAdd(t *testing.T) {
+	page := Stats{Pages: 1, Lines: 30, Words: 150, Characters: 700, ReadingMinutes: 1}
+	total := page.Add(page).Add(page)
+
+	want := Stats{Pages: 3, Lines: 90, Words: 450, Characters: 2100, ReadingMinutes: 3}
+	if total != want {
+		t.Errorf("total = %+v, want %+v", total, want)
+	}
+}
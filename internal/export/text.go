@@ -0,0 +1,44 @@
+package export
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// PlainText joins words with spaces and lines with newlines. A word broken
+// across a line end, marked Hyphenated, is rejoined without its hyphen at the
+// end of the line it starts on.
+func PlainText(lines []models.HOCRLine) string {
+	var b strings.Builder
+	joined := false
+	for i, line := range lines {
+		words := line.Words
+		// The first word went to the end of the line before
+		if joined && len(words) > 0 {
+			words = words[1:]
+		}
+		joined = false
+
+		var texts []string
+		for j, word := range words {
+			text := strings.TrimSpace(word.Text)
+			if text == "" {
+				continue
+			}
+			if word.Hyphenated && j == len(words)-1 && i+1 < len(lines) && len(lines[i+1].Words) > 0 {
+				_, size := utf8.DecodeLastRuneInString(text)
+				text = text[:len(text)-size] + strings.TrimSpace(lines[i+1].Words[0].Text)
+				joined = true
+			}
+			texts = append(texts, text)
+		}
+		if len(texts) == 0 {
+			continue
+		}
+		b.WriteString(strings.Join(texts, " "))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
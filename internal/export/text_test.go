@@ -0,0 +1,17 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestPlainTextRejoinsHyphenation(t *testing.T) {
+	lines := []models.HOCRLine{line("the", "infor-"), line("mation", "age"), line("Zei⸗"), line("tung")}
+	lines[0].Words[1].Hyphenated = true
+	lines[2].Words[0].Hyphenated = true
+
+	if text := PlainText(lines); text != "the information\nage\nZeitung\n" {
+		t.Errorf("PlainText = %q", text)
+	}
+}
@@ -0,0 +1,192 @@
+package export
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// Marks that change how a letter before them is romanized. Other combining
+// marks, such as Greek accents and Hebrew vowel points, are dropped.
+const (
+	roughBreathing = '̔'
+	hebrewDagesh   = 'ּ'
+	arabicShadda   = 'ّ'
+)
+
+// romanizations are the Library of Congress (ALA-LC) romanizations of Cyrillic,
+// following Russian, Greek, Hebrew and Arabic, without the ligature ties that
+// catalogers' search indexes drop anyway. Hebrew and Arabic written without
+// vowels are romanized without them.
+var romanizations = map[rune]string{
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "ë", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "ĭ", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "ʺ", 'ы': "y", 'ь': "ʹ", 'э': "ė", 'ю': "iu",
+	'я': "ia", 'і': "i", 'ї': "ï", 'є': "ie", 'ґ': "g", 'ў': "ŭ", 'ѣ': "ie", 'ѳ': "f",
+	'ѵ': "y", 'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "ć", 'џ': "dž", 'ђ': "đ",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "ē", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "ph", 'χ': "ch", 'ψ': "ps",
+	'ω': "ō",
+	// Hebrew
+	'א': "ʼ", 'ב': "v", 'ג': "g", 'ד': "d", 'ה': "h", 'ו': "v", 'ז': "z", 'ח': "ḥ",
+	'ט': "ṭ", 'י': "y", 'כ': "kh", 'ך': "kh", 'ל': "l", 'מ': "m", 'ם': "m", 'נ': "n",
+	'ן': "n", 'ס': "s", 'ע': "ʻ", 'פ': "f", 'ף': "f", 'צ': "ts", 'ץ': "ts", 'ק': "ḳ",
+	'ר': "r", 'ש': "sh", 'ת': "t",
+	// Arabic
+	'ء': "ʼ", 'آ': "ā", 'أ': "a", 'إ': "i", 'ؤ': "ʼ", 'ئ': "ʼ", 'ا': "ā", 'ب': "b",
+	'ة': "h", 'ت': "t", 'ث': "th", 'ج': "j", 'ح': "ḥ", 'خ': "kh", 'د': "d", 'ذ': "dh",
+	'ر': "r", 'ز': "z", 'س': "s", 'ش': "sh", 'ص': "ṣ", 'ض': "ḍ", 'ط': "ṭ", 'ظ': "ẓ",
+	'ع': "ʻ", 'غ': "gh", 'ف': "f", 'ق': "q", 'ك': "k", 'ل': "l", 'م': "m", 'ن': "n",
+	'ه': "h", 'و': "w", 'ي': "y", 'ى': "á",
+	'ً': "an", 'ٌ': "un", 'ٍ': "in", 'َ': "a", 'ُ': "u", 'ِ': "i",
+	// Punctuation of Arabic and Hebrew
+	'،': ",", '؛': ";", '؟': "?", '־': "-",
+}
+
+// longVowels are the Arabic letters that lengthen the short vowel before them
+var longVowels = map[string]struct {
+	letter rune
+	roman  string
+}{
+	"a": {'ا', "ā"},
+	"i": {'ي', "ī"},
+	"u": {'و', "ū"},
+}
+
+// hardened are the Hebrew letters a dagesh turns from fricatives into stops
+var hardened = map[rune]string{'ב': "b", 'כ': "k", 'ך': "k", 'פ': "p", 'ף': "p"}
+
+// Transliterate romanizes the Cyrillic, Greek, Hebrew and Arabic in text,
+// keeping the case of capitals, for catalogers to search pages by the
+// romanized forms of names and titles. Other text is left as it is.
+func Transliterate(text string) string {
+	if !needsTransliteration(text) {
+		return text
+	}
+	runes := []rune(norm.NFC.String(text))
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		start, r := i, runes[i]
+		lower := unicode.ToLower(r)
+		roman, ok := romanizations[lower]
+		// Letters with accents or breathings are romanized as their base
+		// letter, unless they have their own romanization, such as й
+		var marks []rune
+		if decomposed := []rune(norm.NFD.String(string(r))); !ok && len(decomposed) > 1 {
+			lower = unicode.ToLower(decomposed[0])
+			roman, ok = romanizations[lower]
+			marks = decomposed[1:]
+		}
+		if !ok {
+			if !unicode.Is(unicode.Mn, r) {
+				b.WriteRune(r)
+			}
+			continue
+		}
+
+		// The marks after a letter: a dagesh hardens it, a shadda doubles it,
+		// and a rough breathing puts an h before it
+		for i+1 < len(runes) && unicode.Is(unicode.Mn, runes[i+1]) {
+			i++
+			marks = append(marks, runes[i])
+		}
+		for _, mark := range marks {
+			switch mark {
+			case hebrewDagesh:
+				if hard, ok := hardened[lower]; ok {
+					roman = hard
+				}
+			case arabicShadda:
+				roman += roman
+			case roughBreathing:
+				if lower == 'ρ' {
+					roman += "h"
+				} else {
+					roman = "h" + roman
+				}
+			}
+		}
+		// Greek upsilon is u in a diphthong
+		if lower == 'υ' && start > 0 && strings.ContainsRune("αεηο", baseLetter(runes[start-1])) {
+			roman = "u"
+		}
+		if unicode.IsUpper(r) {
+			first := []rune(roman)
+			first[0] = unicode.ToUpper(first[0])
+			roman = string(first)
+		}
+		// A short vowel before the letter that lengthens it is written long
+		vowel := romanizedMarks(marks)
+		if long, ok := longVowels[vowel]; ok && i+1 < len(runes) && runes[i+1] == long.letter {
+			vowel = long.roman
+			i++
+		}
+		b.WriteString(roman)
+		b.WriteString(vowel)
+	}
+	return b.String()
+}
+
+// needsTransliteration reports whether text has any letters to romanize
+func needsTransliteration(text string) bool {
+	return strings.ContainsFunc(text, func(r rune) bool {
+		return unicode.In(r, unicode.Cyrillic, unicode.Greek, unicode.Hebrew, unicode.Arabic)
+	})
+}
+
+// baseLetter is a letter in lower case without its accents
+func baseLetter(r rune) rune {
+	return unicode.ToLower([]rune(norm.NFD.String(string(r)))[0])
+}
+
+// romanizedMarks are the Arabic vowel marks among marks, which are written
+// after the consonant they follow
+func romanizedMarks(marks []rune) string {
+	var b strings.Builder
+	for _, mark := range marks {
+		b.WriteString(romanizations[mark])
+	}
+	return b.String()
+}
+
+// TransliterateLines romanizes the words of lines, returning a copy
+func TransliterateLines(lines []models.HOCRLine) []models.HOCRLine {
+	romanized := make([]models.HOCRLine, len(lines))
+	for i, line := range lines {
+		line.Words = append([]models.HOCRWord{}, line.Words...)
+		for j := range line.Words {
+			line.Words[j].Text = Transliterate(line.Words[j].Text)
+		}
+		romanized[i] = line
+	}
+	return romanized
+}
+
+// translitWordSpan matches a word span of hOCR, its title, and its text
+var translitWordSpan = regexp.MustCompile(`(<span\s[^>]*class=['"]ocrx_word['"][^>]*title=(['"]))([^'"]*)(['"][^>]*>)([^<]*)</span>`)
+
+// TransliterateHOCR adds the romanized text of each word of hOCR that has
+// any, as x_translit in its title, as in title='bbox 10 10 90 30; x_translit
+// "Moskva"'. The text itself is left as it is.
+func TransliterateHOCR(hocrXML string) string {
+	return translitWordSpan.ReplaceAllStringFunc(hocrXML, func(span string) string {
+		parts := translitWordSpan.FindStringSubmatch(span)
+		text := html.UnescapeString(parts[5])
+		romanized := Transliterate(text)
+		if romanized == text {
+			return span
+		}
+		title := parts[3] + "; x_translit " + html.EscapeString(strconv.Quote(romanized))
+		return parts[1] + title + parts[4] + parts[5] + "</span>"
+	})
+}
@@ -0,0 +1,40 @@
+package export
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	for text, want := range map[string]string{
+		"Москва":          "Moskva",
+		"Щедрин, Чехов":   "Shchedrin, Chekhov",
+		"Толстой":         "Tolstoĭ",
+		"Пушкин":          "Pushkin",
+		"Ἡρόδοτος":        "Hērodotos",
+		"Ὅμηρος":          "Homēros",
+		"οὐρανός":         "ouranos",
+		"Εὐριπίδης":       "Euripidēs",
+		"בְּרֵאשִׁית":     "brʼshyt",
+		"שלום":            "shlvm",
+		"كِتَاب":          "kitāb",
+		"مُحَمَّد":        "muḥammad",
+		"نُور":            "nūr",
+		"Berlin, 1848":    "Berlin, 1848",
+		"Verlag «Наука»":  "Verlag «Nauka»",
+		"Homer (Ὅμηρος);": "Homer (Homēros);",
+	} {
+		if got := Transliterate(text); got != want {
+			t.Errorf("Transliterate(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestTransliterateHOCR(t *testing.T) {
+	hocrXML := `<span class='ocr_line' id='line_1' title='bbox 0 0 90 10'><span class='ocrx_word' id='word_1' title='bbox 0 0 40 10; x_wconf 90'>Moscow</span> <span class='ocrx_word' id='word_2' title='bbox 50 0 90 10; x_wconf 80'>Москва</span></span>`
+	got := TransliterateHOCR(hocrXML)
+	want := strings.Replace(hocrXML, "x_wconf 80'", "x_wconf 80; x_translit &#34;Moskva&#34;'", 1)
+	if got != want {
+		t.Errorf("TransliterateHOCR =\n%s\nwant\n%s", got, want)
+	}
+}
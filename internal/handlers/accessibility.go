@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/accessibility"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// maxCheckedExportSize bounds documents posted to the accessibility checker
+const maxCheckedExportSize = 200 << 20
+
+// setConformanceHeaders attaches an export's accessibility report summary to the
+// download, linking to the full report when one can be fetched later
+func setConformanceHeaders(w http.ResponseWriter, report accessibility.Report, reportURL string) {
+	w.Header().Set("X-Accessibility-Conforms", strconv.FormatBool(report.Conforms))
+	for _, finding := range report.Findings {
+		w.Header().Add("X-Accessibility-Finding", fmt.Sprintf("%s=%s", finding.Criterion, finding.Status))
+	}
+	if reportURL != "" {
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="describedby"`, reportURL))
+	}
+}
+
+// handleAccessibility reports how each page's hOCR export, or one page with
+// ?image_id=, meets the accessibility criteria
+func (h *Handler) handleAccessibility(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	imageID := r.URL.Query().Get("image_id")
+	conforms := true
+	var pages []PageAccessibility
+	for i := range session.Images {
+		image := &session.Images[i]
+		if imageID != "" && image.ID != imageID {
+			continue
+		}
+		report, err := accessibility.CheckHTML([]byte(currentHOCR(image)))
+		if err != nil {
+			h.writeError(w, fmt.Sprintf("Failed to check %s: %v", image.ID, err), http.StatusUnprocessableEntity)
+			return
+		}
+		conforms = conforms && report.Conforms
+		pages = append(pages, PageAccessibility{ID: image.ID, Report: report})
+	}
+
+	if imageID != "" && len(pages) == 0 {
+		h.writeError(w, "Image not found", http.StatusNotFound)
+		return
+	}
+
+	h.writeJSON(w, AccessibilityResponse{SessionID: session.ID, Conforms: conforms, Pages: pages})
+}
+
+// HandleAccessibilityCheck evaluates an uploaded HTML, EPUB or PDF export. The
+// format comes from ?format= or the request's Content-Type.
+func (h *Handler) HandleAccessibilityCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = accessibility.FormatFor(r.Header.Get("Content-Type"))
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCheckedExportSize))
+	if err != nil {
+		h.writeError(w, "Failed to read document: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := accessibility.Check(format, data)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.writeJSON(w, report)
+}
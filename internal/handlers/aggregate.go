@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/eval"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// Periods aggregate metrics can be grouped by
+const (
+	periodDay   = "day"
+	periodWeek  = "week"
+	periodMonth = "month"
+)
+
+// periodKey labels the day, ISO week or month a time falls in
+func periodKey(t time.Time, period string) string {
+	switch period {
+	case periodDay:
+		return t.Format(time.DateOnly)
+	case periodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	default:
+		return t.Format("2006-01")
+	}
+}
+
+// parseDateParam reads a YYYY-MM-DD query parameter, returning the zero time when absent
+func parseDateParam(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a date like 2025-01-31", name)
+	}
+	return date, nil
+}
+
+// HandleAggregateMetrics totals the accuracy of completed pages across every
+// session, grouped by when the session was created and the engine that ran, so
+// engine quality can be followed over time. Sessions can be limited to a
+// collection and to a from/to date range (inclusive).
+func (h *Handler) HandleAggregateMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	collection := query.Get("collection")
+	if !h.requirePermission(w, r, collection, auth.ViewMetrics) {
+		return
+	}
+	from, err := parseDateParam(r, "from")
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseDateParam(r, "to")
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	period := query.Get("group")
+	switch period {
+	case "":
+		period = periodMonth
+	case periodDay, periodWeek, periodMonth:
+	default:
+		h.writeError(w, "group must be day, week or month", http.StatusBadRequest)
+		return
+	}
+
+	type groupKey struct{ period, engine string }
+	results := map[groupKey][]models.EvalResult{}
+	sessions := map[groupKey]int{}
+	var all []models.EvalResult
+	sessionCount := 0
+	for _, session := range h.sessionStore.GetAll() {
+		if collection != "" && session.Collection != collection {
+			continue
+		}
+		if !from.IsZero() && session.CreatedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !session.CreatedAt.Before(to.AddDate(0, 0, 1)) {
+			continue
+		}
+
+		var completed []models.EvalResult
+		for _, result := range sessionReport(session) {
+			if image := findImage(session, result.Identifier); image != nil && image.Completed {
+				completed = append(completed, result)
+			}
+		}
+		if len(completed) == 0 {
+			continue
+		}
+		key := groupKey{periodKey(session.CreatedAt, period), session.Config.Engine}
+		results[key] = append(results[key], completed...)
+		sessions[key]++
+		all = append(all, completed...)
+		sessionCount++
+	}
+
+	groups := make([]MetricsGroup, 0, len(results))
+	for key, groupResults := range results {
+		groups = append(groups, metricsGroup(key.period, key.engine, sessions[key], groupResults))
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Period != groups[j].Period {
+			return groups[i].Period < groups[j].Period
+		}
+		return groups[i].Engine < groups[j].Engine
+	})
+
+	h.writeJSON(w, AggregateMetrics{
+		Collection: collection,
+		Group:      period,
+		Total:      metricsGroup("", "", sessionCount, all),
+		Groups:     groups,
+	})
+}
+
+// metricsGroup totals accuracy and correction effort over some pages
+func metricsGroup(period, engine string, sessions int, results []models.EvalResult) MetricsGroup {
+	group := MetricsGroup{Period: period, Engine: engine, Sessions: sessions, Pages: len(results), Summary: eval.Summarize(results, 0)}
+	for _, result := range results {
+		group.WordEdits += result.Substitutions + result.Deletions + result.Insertions
+		group.CharacterEdits += result.CharacterErrors
+	}
+	if group.Pages > 0 {
+		group.WordEditsPerPage = float64(group.WordEdits) / float64(group.Pages)
+	}
+	return group
+}
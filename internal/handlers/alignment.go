@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/metrics"
+)
+
+// HandleAlignment returns the edit operations that turn the original text into
+// the corrected one, for rendering a side-by-side diff
+func (h *Handler) HandleAlignment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request AlignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	spans, err := metrics.AlignText(request.Original, request.Corrected, request.Unit)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	unit := request.Unit
+	if unit == "" {
+		unit = metrics.UnitWord
+	}
+	h.writeJSON(w, AlignmentResponse{Unit: unit, Spans: spans})
+}
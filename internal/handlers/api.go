@@ -0,0 +1,734 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/accessibility"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/authority"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/eval"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/export"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/metrics"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// Request and response bodies of the JSON API. These are the types the OpenAPI
+// document at /api/v1/openapi.json is generated from, so handlers decode and encode
+// them rather than anonymous structs or maps.
+
+// StatusResponse acknowledges a request that has nothing else to return
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+var statusSuccess = StatusResponse{Status: "success"}
+
+// UploadForm is the multipart body of POST /api/v1/upload
+type UploadForm struct {
+	Files        []byte `json:"files" openapi:"binary"`
+	Engine       string `json:"engine,omitempty"`
+	Language     string `json:"language,omitempty"`
+	DocumentType string `json:"document_type,omitempty"`
+	// AllowedCharacters and ForbiddenCharacters limit the characters of the
+	// text, as in "{digits}.,-"
+	AllowedCharacters   string  `json:"allowed_characters,omitempty"`
+	ForbiddenCharacters string  `json:"forbidden_characters,omitempty"`
+	Binarization        string  `json:"binarization,omitempty"`
+	Threshold           float64 `json:"threshold,omitempty"`
+	WindowSize          int     `json:"window_size,omitempty"`
+	K                   float64 `json:"k,omitempty"`
+}
+
+// UploadURLRequest is the JSON body of POST /api/v1/upload
+type UploadURLRequest struct {
+	ImageURL     string                    `json:"image_url"`
+	Engine       string                    `json:"engine,omitempty"`
+	Language     string                    `json:"language,omitempty"`
+	DocumentType string                    `json:"document_type,omitempty"`
+	Characters   models.CharacterConfig    `json:"characters"`
+	Binarization models.BinarizationConfig `json:"binarization"`
+}
+
+// UploadResult is the result of a finished upload job
+type UploadResult struct {
+	SessionID string `json:"session_id"`
+	Message   string `json:"message"`
+	Images    int    `json:"images"`
+	CacheUsed bool   `json:"cache_used"`
+	Digest    string `json:"digest,omitempty"`
+	Source    string `json:"source,omitempty"`
+}
+
+// JobAccepted answers a request whose work was queued as a background job
+type JobAccepted struct {
+	JobID     string `json:"job_id"`
+	Status    string `json:"status"`
+	StatusURL string `json:"status_url"`
+}
+
+// BatchUploadForm is the multipart body of POST /api/v1/upload/batch
+type BatchUploadForm struct {
+	UploadForm
+	Mode string `json:"mode,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// BatchUploadRequest is the JSON body of POST /api/v1/upload/batch
+type BatchUploadRequest struct {
+	URLs         []string                  `json:"urls"`
+	Mode         string                    `json:"mode,omitempty"`
+	Name         string                    `json:"name,omitempty"`
+	Engine       string                    `json:"engine,omitempty"`
+	Language     string                    `json:"language,omitempty"`
+	DocumentType string                    `json:"document_type,omitempty"`
+	Characters   models.CharacterConfig    `json:"characters"`
+	Binarization models.BinarizationConfig `json:"binarization"`
+}
+
+// DrupalBookRequest loads the child pages of a Drupal node, as one session
+// (mode "single", the default) or one per page ("separate")
+type DrupalBookRequest struct {
+	NID          string                    `json:"nid"`
+	Mode         string                    `json:"mode,omitempty"`
+	Engine       string                    `json:"engine,omitempty"`
+	Language     string                    `json:"language,omitempty"`
+	DocumentType string                    `json:"document_type,omitempty"`
+	Characters   models.CharacterConfig    `json:"characters"`
+	Binarization models.BinarizationConfig `json:"binarization"`
+	// CallbackURL is notified when every page of the session is completed
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// RepositoryItem is a page image in a Fedora or OCFL repository
+type RepositoryItem struct {
+	Object string `json:"object"`
+	Path   string `json:"path"`
+}
+
+// RepositoryRequest loads page images from a Fedora or OCFL repository, in order
+type RepositoryRequest struct {
+	Repository   string                    `json:"repository"`
+	Items        []RepositoryItem          `json:"items"`
+	Mode         string                    `json:"mode,omitempty"`
+	Engine       string                    `json:"engine,omitempty"`
+	Language     string                    `json:"language,omitempty"`
+	DocumentType string                    `json:"document_type,omitempty"`
+	Characters   models.CharacterConfig    `json:"characters"`
+	Binarization models.BinarizationConfig `json:"binarization"`
+	CallbackURL  string                    `json:"callback_url,omitempty"`
+}
+
+// RepositoryPublished lists the files written back to a repository
+type RepositoryPublished struct {
+	Status string   `json:"status"`
+	Kind   string   `json:"kind"`
+	Object string   `json:"object"`
+	Paths  []string `json:"paths"`
+}
+
+// BatchSession is one session a batch upload will create
+type BatchSession struct {
+	SessionID string `json:"session_id"`
+	Source    string `json:"source,omitempty"`
+	Items     int    `json:"items,omitempty"`
+	JobID     string `json:"job_id,omitempty"`
+	StatusURL string `json:"status_url,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type BatchUploadResponse struct {
+	Mode     string         `json:"mode"`
+	Queued   int            `json:"queued"`
+	Sessions []BatchSession `json:"sessions"`
+}
+
+// ImageEstimate is the LLM transcription estimate of one uploaded file
+type ImageEstimate struct {
+	Filename string `json:"filename"`
+	hocr.Estimate
+	Error string `json:"error,omitempty"`
+}
+
+// CostEstimate is the result of the job queued by POST /api/v1/estimate
+type CostEstimate struct {
+	Pricing hocr.Pricing    `json:"pricing"`
+	Images  []ImageEstimate `json:"images"`
+	Total   hocr.Estimate   `json:"total"`
+	Failed  int             `json:"failed"`
+}
+
+// BatchResult is the result of a finished single-session batch job
+type BatchResult struct {
+	SessionID string `json:"session_id"`
+	Message   string `json:"message"`
+	Images    int    `json:"images"`
+}
+
+type PrefetchRequest struct {
+	URLs        []string `json:"urls,omitempty"`
+	ManifestURL string   `json:"manifest_url,omitempty"`
+}
+
+type PrefetchAccepted struct {
+	ID    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+type HOCRUpdateRequest struct {
+	SessionID string `json:"session_id"`
+	ImageID   string `json:"image_id"`
+	HOCR      string `json:"hocr"`
+	// Draft saves the hOCR without marking the image completed
+	Draft bool `json:"draft,omitempty"`
+}
+
+type HOCRParseRequest struct {
+	HOCR string `json:"hocr"`
+}
+
+type HOCRParseResponse struct {
+	Words []models.HOCRWord `json:"words"`
+}
+
+type MetricsRequest struct {
+	Original  string `json:"original"`
+	Corrected string `json:"corrected"`
+	// With both versions' hOCR the response also breaks accuracy down by line
+	// and word, and the texts default to the hOCR's
+	OriginalHOCR  string `json:"original_hocr,omitempty"`
+	CorrectedHOCR string `json:"corrected_hocr,omitempty"`
+	// Normalization defaults to NFC and ignoring case
+	Normalization models.NormalizationConfig `json:"normalization"`
+	// Language is the text's, for rejoining words hyphenated across lines in
+	// the texts taken from the hOCR
+	Language string `json:"language,omitempty"`
+}
+
+// AlignmentRequest asks for the alignment of two texts by word (the default) or char
+type AlignmentRequest struct {
+	Original  string `json:"original"`
+	Corrected string `json:"corrected"`
+	Unit      string `json:"unit,omitempty"`
+}
+
+// AlignmentResponse lists the spans of the alignment in text order
+type AlignmentResponse struct {
+	Unit  string         `json:"unit"`
+	Spans []metrics.Span `json:"spans"`
+}
+
+// MetricsResponse is the accuracy of a correction, with per-line and per-word
+// outcomes when hOCR was compared
+type MetricsResponse struct {
+	models.EvalResult
+	Lines []models.LineAccuracy `json:"lines,omitempty"`
+}
+
+// CloneRequest branches a session, re-running OCR when a config is given
+type CloneRequest struct {
+	Name   string         `json:"name,omitempty"`
+	Config *SessionConfig `json:"config,omitempty"`
+}
+
+type MergeRequest struct {
+	SourceSessionID string `json:"source_session_id"`
+	Position        string `json:"position,omitempty"`
+	KeepSource      bool   `json:"keep_source,omitempty"`
+}
+
+type MergeResponse struct {
+	Session    *models.CorrectionSession `json:"session"`
+	Duplicates int                       `json:"duplicates"`
+	// ImageIDs maps each merged session's old image IDs to their new ones
+	ImageIDs map[string]map[string]string `json:"image_ids"`
+}
+
+// RightsRequest sets rights on the session, or on one image when ImageID is set
+type RightsRequest struct {
+	ImageID string `json:"image_id,omitempty"`
+	models.Rights
+}
+
+type RightsResponse struct {
+	Session models.Rights            `json:"session"`
+	Images  map[string]models.Rights `json:"images"`
+}
+
+// PublicImage is what the public viewer may see of an image
+type PublicImage struct {
+	ID          string        `json:"id"`
+	ImageURL    string        `json:"image_url"`
+	HOCR        string        `json:"hocr"`
+	ImageWidth  int           `json:"image_width"`
+	ImageHeight int           `json:"image_height"`
+	Rights      models.Rights `json:"rights"`
+}
+
+type PublicSession struct {
+	ID     string        `json:"id"`
+	Images []PublicImage `json:"images"`
+}
+
+type PublishRequest struct {
+	ImageID string `json:"image_id"`
+	// HOCR overrides the image's current hOCR
+	HOCR string `json:"hocr,omitempty"`
+}
+
+// PublishResponse reports a publish to Drupal, with the media revision it saved
+type PublishResponse struct {
+	Status     string `json:"status"`
+	RevisionID string `json:"revision_id,omitempty"`
+	Warning    string `json:"warning,omitempty"`
+}
+
+// DrupalSyncImage is whether one image's hOCR has made it back to Drupal
+type DrupalSyncImage struct {
+	ImageID string `json:"image_id"`
+	Nid     string `json:"nid,omitempty"`
+	models.DrupalSync
+}
+
+// DrupalSyncReport counts a session's Drupal images by sync state, listing
+// those in the state asked for
+type DrupalSyncReport struct {
+	SessionID string            `json:"session_id"`
+	Counts    map[string]int    `json:"counts"`
+	Images    []DrupalSyncImage `json:"images"`
+}
+
+// DrupalSyncOverview is the sync status of Drupal images across sessions
+type DrupalSyncOverview struct {
+	Counts   map[string]int     `json:"counts"`
+	Sessions []DrupalSyncReport `json:"sessions"`
+}
+
+// DrupalSyncRetryRequest picks the images to upload again; by default, every
+// failed one
+type DrupalSyncRetryRequest struct {
+	ImageIDs []string `json:"image_ids,omitempty"`
+}
+
+// DrupalSyncRetryResult is the outcome of a retry job
+type DrupalSyncRetryResult struct {
+	SessionID string            `json:"session_id"`
+	Uploaded  int               `json:"uploaded"`
+	Failed    int               `json:"failed"`
+	Images    []DrupalSyncImage `json:"images"`
+}
+
+// ExportPage is one image's transcription with its statistics, and its
+// romanization when one was asked for
+type ExportPage struct {
+	ID              string       `json:"id"`
+	Text            string       `json:"text"`
+	Transliteration string       `json:"transliteration,omitempty"`
+	Stats           export.Stats `json:"stats"`
+}
+
+type SessionExport struct {
+	SessionID string       `json:"session_id"`
+	Stats     export.Stats `json:"stats"`
+	Pages     []ExportPage `json:"pages"`
+	Watermark string       `json:"watermark,omitempty"`
+}
+
+type SessionSummary struct {
+	ID         string         `json:"id"`
+	Collection string         `json:"collection"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Images     int            `json:"images"`
+	Statuses   map[string]int `json:"statuses"`
+	Stats      export.Stats   `json:"stats"`
+}
+
+// MetricsReport is the accuracy of every image's correction in a session
+type MetricsReport struct {
+	SessionID string              `json:"session_id"`
+	Results   []models.EvalResult `json:"results"`
+	Summary   eval.Summary        `json:"summary"`
+}
+
+// CallbackRequest registers the URL notified when a session is completed
+type CallbackRequest struct {
+	URL string `json:"url"`
+}
+
+// CompletionEvent is posted to a session's callback URL once all of its
+// images are completed
+type CompletionEvent struct {
+	Event       string           `json:"event"`
+	SessionID   string           `json:"session_id"`
+	Collection  string           `json:"collection,omitempty"`
+	CompletedAt time.Time        `json:"completed_at"`
+	Summary     eval.Summary     `json:"summary"`
+	Images      []CompletedImage `json:"images"`
+}
+
+// CompletedImage says where a completed image's hOCR can be fetched, and the
+// Drupal node it belongs to when there is one
+type CompletedImage struct {
+	ImageID         string     `json:"image_id"`
+	HOCRURL         string     `json:"hocr_url"`
+	DrupalNid       string     `json:"drupal_nid,omitempty"`
+	DrupalUploadURL string     `json:"drupal_upload_url,omitempty"`
+	PublishedAt     *time.Time `json:"published_at,omitempty"`
+}
+
+// MetricsGroup is the accuracy of, and effort spent correcting, the completed
+// pages of sessions in one period that used one engine
+type MetricsGroup struct {
+	Period   string       `json:"period,omitempty"`
+	Engine   string       `json:"engine,omitempty"`
+	Sessions int          `json:"sessions"`
+	Pages    int          `json:"pages"`
+	Summary  eval.Summary `json:"summary"`
+	// Correction effort is the number of edits editors made to the OCR
+	WordEdits        int     `json:"word_edits"`
+	CharacterEdits   int     `json:"character_edits"`
+	WordEditsPerPage float64 `json:"word_edits_per_page"`
+}
+
+// AggregateMetrics totals metrics across sessions, overall and per group
+type AggregateMetrics struct {
+	Collection string         `json:"collection,omitempty"`
+	Group      string         `json:"group"`
+	Total      MetricsGroup   `json:"total"`
+	Groups     []MetricsGroup `json:"groups"`
+}
+
+type PageAccessibility struct {
+	ID     string               `json:"id"`
+	Report accessibility.Report `json:"report"`
+}
+
+type AccessibilityResponse struct {
+	SessionID string              `json:"session_id"`
+	Conforms  bool                `json:"conforms"`
+	Pages     []PageAccessibility `json:"pages"`
+}
+
+type AuthorityLookupResponse struct {
+	Query      string                `json:"query"`
+	Providers  []string              `json:"providers,omitempty"`
+	WordIDs    []string              `json:"word_ids,omitempty"`
+	Candidates []authority.Candidate `json:"candidates"`
+}
+
+// SpellcheckInfo is the size of the spellcheck dictionary and the domain
+// lexicons that can be added to it
+type SpellcheckInfo struct {
+	Words    int      `json:"words"`
+	Lexicons []string `json:"lexicons"`
+}
+
+// SpellcheckRequest checks a transcription's words. The lexicon named after
+// the session's collection, if any, is added to those listed.
+type SpellcheckRequest struct {
+	SessionID string   `json:"session_id,omitempty"`
+	Words     []string `json:"words"`
+	Lexicons  []string `json:"lexicons,omitempty"`
+	Limit     int      `json:"limit,omitempty"`
+}
+
+type SpellcheckResponse struct {
+	Lexicons []string         `json:"lexicons"`
+	Checked  int              `json:"checked"`
+	Flagged  []SpellcheckFlag `json:"flagged"`
+}
+
+// SpellcheckFlag is a word missing from the dictionary and lexicons. Index
+// counts words in the request, or in reading order for an image.
+type SpellcheckFlag struct {
+	Index       int          `json:"index"`
+	Text        string       `json:"text"`
+	WordID      string       `json:"word_id,omitempty"`
+	LineID      string       `json:"line_id,omitempty"`
+	BBox        *models.BBox `json:"bbox,omitempty"`
+	Suggestions []string     `json:"suggestions"`
+}
+
+// ReviewQueue is the words of a session most in need of a look, most
+// suspicious first. Total counts every queued word, before the limit.
+type ReviewQueue struct {
+	SessionID string       `json:"session_id"`
+	Threshold float64      `json:"threshold"`
+	Compare   string       `json:"compare"`
+	Lexicons  []string     `json:"lexicons"`
+	Total     int          `json:"total"`
+	Items     []ReviewItem `json:"items"`
+}
+
+// ReviewItem is one queued word. Reasons are low_confidence, dictionary,
+// disagreement, auto_corrected and disallowed_characters; Alternative is the
+// other engine's reading when they disagree, AutoCorrected what the engine
+// read before post-correction replaced it, and Disallowed the characters the
+// session doesn't allow.
+type ReviewItem struct {
+	ImageID       string      `json:"image_id"`
+	WordID        string      `json:"word_id"`
+	LineID        string      `json:"line_id"`
+	Text          string      `json:"text"`
+	BBox          models.BBox `json:"bbox"`
+	Confidence    float64     `json:"confidence"`
+	Score         float64     `json:"score"`
+	Reasons       []string    `json:"reasons"`
+	Suggestions   []string    `json:"suggestions,omitempty"`
+	Alternative   string      `json:"alternative,omitempty"`
+	AutoCorrected string      `json:"auto_corrected,omitempty"`
+	Disallowed    string      `json:"disallowed,omitempty"`
+}
+
+// ImageProvenance accounts for each word of an image's current hOCR. Compared
+// reports whether the compare engine's reading of the image was cached.
+type ImageProvenance struct {
+	ImageID  string           `json:"image_id"`
+	Engine   string           `json:"engine"`
+	Compare  string           `json:"compare"`
+	Compared bool             `json:"compared"`
+	Words    []WordProvenance `json:"words"`
+}
+
+// WordProvenance is where a word's text came from: the engine that read the
+// page, manual or reread. Confidence is null for typed words and engines that
+// give none, and Agreed is null where the compare engine has no word.
+type WordProvenance struct {
+	WordID       string      `json:"word_id"`
+	LineID       string      `json:"line_id"`
+	Text         string      `json:"text"`
+	BBox         models.BBox `json:"bbox"`
+	Source       string      `json:"source"`
+	Confidence   *float64    `json:"confidence"`
+	OriginalText string      `json:"original_text,omitempty"`
+	Agreed       *bool       `json:"agreed"`
+	Alternative  string      `json:"alternative,omitempty"`
+}
+
+// StorageCleanup is what a cleanup removed, or would remove on a dry run,
+// by area: uploads, originals, houdini, tiles, archive and prefetch.
+// SharedImages counts uploads used by more than one session.
+type StorageCleanup struct {
+	DryRun           bool                    `json:"dry_run"`
+	RetentionHours   int                     `json:"retention_hours"`
+	ReferencedImages int                     `json:"referenced_images"`
+	SharedImages     int                     `json:"shared_images"`
+	Entries          int                     `json:"entries"`
+	Bytes            int64                   `json:"bytes"`
+	Removed          map[string]StorageUsage `json:"removed"`
+	// UsedBytes is what uploads, caches and archived output hold afterwards,
+	// against a QuotaBytes of 0 when STORAGE_QUOTA_BYTES sets no limit
+	UsedBytes  int64 `json:"used_bytes"`
+	QuotaBytes int64 `json:"quota_bytes"`
+}
+
+type StorageUsage struct {
+	Entries int   `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+}
+
+type ApplyMacroRequest struct {
+	MacroID string       `json:"macro_id"`
+	Region  *models.BBox `json:"region,omitempty"`
+	DryRun  bool         `json:"dry_run,omitempty"`
+}
+
+type ApplyMacroResponse struct {
+	HOCR    string `json:"hocr"`
+	Changed int    `json:"changed"`
+	DryRun  bool   `json:"dry_run"`
+}
+
+// SplitWordRequest is the body of POST /api/v1/sessions/{id}/images/{image_id}/words/split.
+// X is the page coordinate to split at, or Y for a word on a vertically set line;
+// Texts, when given, holds the text of each half.
+type SplitWordRequest struct {
+	WordID string   `json:"word_id"`
+	X      int      `json:"x"`
+	Y      int      `json:"y,omitempty"`
+	Texts  []string `json:"texts,omitempty"`
+}
+
+// MergeWordsRequest is the body of POST /api/v1/sessions/{id}/images/{image_id}/words/merge.
+// Separator joins the words' texts, by default with nothing.
+type MergeWordsRequest struct {
+	WordIDs   []string `json:"word_ids"`
+	Separator string   `json:"separator,omitempty"`
+}
+
+// MoveWordRequest is the body of POST /api/v1/sessions/{id}/images/{image_id}/words/move.
+// Index is the word's position on its new line; without it the word is placed by x.
+type MoveWordRequest struct {
+	WordID string `json:"word_id"`
+	LineID string `json:"line_id"`
+	Index  *int   `json:"index,omitempty"`
+}
+
+// UpdateWordRequest is the body of PATCH /api/v1/sessions/{id}/images/{image_id}/words/{word_id};
+// fields left out are unchanged
+type UpdateWordRequest struct {
+	BBox *models.BBox `json:"bbox,omitempty"`
+	Text *string      `json:"text,omitempty"`
+}
+
+// WordEditResponse is the regenerated hOCR after an edit, with the IDs of the
+// words it produced
+type WordEditResponse struct {
+	HOCR    string   `json:"hocr"`
+	WordIDs []string `json:"word_ids"`
+}
+
+// ReorderLinesRequest is the body of POST /api/v1/sessions/{id}/images/{image_id}/lines/order,
+// listing every line in its new order
+type ReorderLinesRequest struct {
+	LineIDs []string `json:"line_ids"`
+}
+
+// SplitLineRequest is the body of POST /api/v1/sessions/{id}/images/{image_id}/lines/split.
+// The new line starts at WordID.
+type SplitLineRequest struct {
+	LineID string `json:"line_id"`
+	WordID string `json:"word_id"`
+}
+
+// MergeLinesRequest is the body of POST /api/v1/sessions/{id}/images/{image_id}/lines/merge.
+// The words of the other lines are appended to the first, in order.
+type MergeLinesRequest struct {
+	LineIDs []string `json:"line_ids"`
+}
+
+// RegenerateOCRRequest runs OCR on a whole image again, with the session's
+// engine, model, prompt and binarization unless these override them
+type RegenerateOCRRequest struct {
+	Engine string `json:"engine,omitempty"`
+	Model  string `json:"model,omitempty"`
+	// Prompt replaces the LLM's transcription instructions for this run
+	Prompt       string                     `json:"prompt,omitempty"`
+	Binarization *models.BinarizationConfig `json:"binarization,omitempty"`
+	// DiscardCorrections replaces an image that has corrections; without it
+	// such an image is refused
+	DiscardCorrections bool `json:"discard_corrections,omitempty"`
+}
+
+// OCRRegenerated is the result of a regenerate_ocr job
+type OCRRegenerated struct {
+	ImageID string `json:"image_id"`
+	Engine  string `json:"engine"`
+	Model   string `json:"model,omitempty"`
+	HOCR    string `json:"hocr"`
+}
+
+// OCRCacheInvalidation reports which cached hOCR was dropped for an image
+type OCRCacheInvalidation struct {
+	ImageID   string `json:"image_id"`
+	Engine    string `json:"engine"`
+	CacheFile string `json:"cache_file"`
+	// Removed is false when nothing was cached for these settings
+	Removed bool `json:"removed"`
+}
+
+// RegionOCRRequest reads one region of an image again. Engine and
+// binarization default to the session's; model overrides OPENAI_MODEL for the
+// llm engine.
+type RegionOCRRequest struct {
+	BBox         models.BBox                `json:"bbox"`
+	Engine       string                     `json:"engine,omitempty"`
+	Model        string                     `json:"model,omitempty"`
+	Binarization *models.BinarizationConfig `json:"binarization,omitempty"`
+}
+
+// AddWordRequest declares a word the detector missed. It joins line_id, or
+// the line it sits on, or a line of its own; without text the box is read by
+// OCR with the engine and model given, or the session's.
+type AddWordRequest struct {
+	BBox   models.BBox `json:"bbox"`
+	Text   string      `json:"text,omitempty"`
+	LineID string      `json:"line_id,omitempty"`
+	Engine string      `json:"engine,omitempty"`
+	Model  string      `json:"model,omitempty"`
+}
+
+// AddLineRequest declares a line the detector missed. Text is spread across
+// the box as words; without it the box is read by OCR.
+type AddLineRequest struct {
+	BBox   models.BBox `json:"bbox"`
+	Text   string      `json:"text,omitempty"`
+	Engine string      `json:"engine,omitempty"`
+	Model  string      `json:"model,omitempty"`
+}
+
+// LineEditResponse is the regenerated hOCR after a line edit, with the IDs of
+// the lines it produced
+type LineEditResponse struct {
+	HOCR    string   `json:"hocr"`
+	LineIDs []string `json:"line_ids"`
+}
+
+type SandboxConfig struct {
+	MaxPages  int    `json:"max_pages"`
+	PurgeHour int    `json:"purge_hour"`
+	Watermark string `json:"watermark"`
+}
+
+type ConfigResponse struct {
+	Profile       string            `json:"profile"`
+	LLMEnabled    bool              `json:"llm_enabled"`
+	DefaultEngine string            `json:"default_engine"`
+	Engines       []hocr.EngineInfo `json:"engines"`
+	Model         string            `json:"model,omitempty"`
+	Sandbox       *SandboxConfig    `json:"sandbox,omitempty"`
+}
+
+type PermissionsResponse struct {
+	User        string      `json:"user"`
+	Roles       []string    `json:"roles"`
+	Collection  string      `json:"collection"`
+	Permissions auth.Grants `json:"permissions"`
+}
+
+// QAItem is a page flagged by an automatic check
+type QAItem struct {
+	SessionID string                 `json:"session_id"`
+	ImageID   string                 `json:"image_id"`
+	Reason    string                 `json:"reason"`
+	LineOrder *models.LineOrderCheck `json:"line_order,omitempty"`
+}
+
+type QAResponse struct {
+	Count int      `json:"count"`
+	Items []QAItem `json:"items"`
+}
+
+type ExternalJobRequest struct {
+	Engine   string `json:"engine"`
+	RemoteID string `json:"remote_id,omitempty"`
+}
+
+type ExternalJobCreated struct {
+	Job         *models.ExternalJob `json:"job"`
+	CallbackURL string              `json:"callback_url"`
+}
+
+// OCRWebhookPayload is what an asynchronous engine posts when it finishes.
+// JobID repeats the job in the callback URL, binding the signature to it.
+type OCRWebhookPayload struct {
+	JobID  string `json:"job_id"`
+	Status string `json:"status"`
+	HOCR   string `json:"hocr,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthCheck is the outcome of one dependency check. Optional checks report
+// "warn" instead of failing readiness.
+type HealthCheck struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Required bool   `json:"required"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// HealthResponse is the body of /healthz and /readyz
+type HealthResponse struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
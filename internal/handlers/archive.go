@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+// ArtifactManifest lists every OCR run archived for an image and settings, oldest first
+type ArtifactManifest struct {
+	ImageDigest string        `json:"image_digest"`
+	HOCRFile    string        `json:"hocr_file"`
+	Runs        []ArtifactRun `json:"runs"`
+}
+
+// ArtifactRun describes the raw engine outputs kept for one OCR run, which are
+// stored in a directory named after its ID
+type ArtifactRun struct {
+	ID           string                    `json:"id"`
+	Binarization models.BinarizationConfig `json:"binarization"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	Retries      int                       `json:"retries"`
+	Files        []string                  `json:"files"`
+}
+
+// manifestMu serializes runs appending to the same manifest
+var manifestMu sync.Mutex
+
+// artifactArchive collects engine outputs during OCR so they can be written
+// together once the run succeeds
+type artifactArchive struct {
+	mu      sync.Mutex
+	names   []string
+	files   map[string][]byte
+	retries int
+}
+
+func newArtifactArchive() *artifactArchive {
+	return &artifactArchive{files: make(map[string][]byte)}
+}
+
+func (a *artifactArchive) add(name string, data []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, exists := a.files[name]; !exists {
+		a.names = append(a.names, name)
+	}
+	a.files[name] = data
+}
+
+// retried counts API retries so the manifest shows how hard a run was
+func (a *artifactArchive) retried(retry int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.retries++
+}
+
+// artifactDirFor keys archived outputs the same way as the hOCR cache, so a
+// cached transcription can always be traced back to the run that produced it
+func (h *Handler) artifactDirFor(digest string, config SessionConfig) string {
+	return filepath.Join(h.dirs.Archive, strings.TrimSuffix(hocrCacheFilename(digest, config), ".xml"))
+}
+
+// write stores the run's outputs in a directory of their own and appends the
+// run to the manifest, so a rerun that hits the LLM cache doesn't replace the
+// outputs of the run that filled it
+func (a *artifactArchive) write(dir, digest string, config SessionConfig) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	manifest, err := readArtifactManifest(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		manifest = &ArtifactManifest{ImageDigest: digest, HOCRFile: hocrCacheFilename(digest, config)}
+	} else if err != nil {
+		return fmt.Errorf("failed to read archive manifest: %w", err)
+	}
+
+	run := ArtifactRun{
+		ID:           strconv.Itoa(len(manifest.Runs) + 1),
+		Binarization: config.Binarization,
+		CreatedAt:    time.Now(),
+		Retries:      a.retries,
+		Files:        a.names,
+	}
+	runDir := filepath.Join(dir, run.ID)
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	for _, name := range a.names {
+		if err := os.WriteFile(filepath.Join(runDir, name), a.files[name], 0644); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", name, err)
+		}
+	}
+
+	manifest.Runs = append(manifest.Runs, run)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return storage.WriteFileAtomic(filepath.Join(dir, "manifest.json"), data)
+}
+
+func readArtifactManifest(dir string) (*ArtifactManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest ArtifactManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// handleArtifacts lists the archived engine outputs for an image, or serves one
+// by run ID and name
+func (h *Handler) handleArtifacts(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem, path string) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	dir := h.artifactDirFor(imageHash(image), sessionConfigOf(session))
+	manifest, err := readArtifactManifest(dir)
+	if err != nil {
+		h.writeError(w, "No archived engine output for this image", http.StatusNotFound)
+		return
+	}
+
+	if path == "" {
+		h.writeJSON(w, manifest)
+		return
+	}
+
+	runID, name, _ := strings.Cut(path, "/")
+	i := slices.IndexFunc(manifest.Runs, func(run ArtifactRun) bool { return run.ID == runID })
+	if i < 0 || !slices.Contains(manifest.Runs[i].Files, name) {
+		h.writeError(w, "Artifact not found", http.StatusNotFound)
+		return
+	}
+
+	slog.Info("Serving archived artifact", "session_id", session.ID, "image_id", image.ID, "run", runID, "artifact", name)
+	http.ServeFile(w, r, filepath.Join(dir, runID, name))
+}
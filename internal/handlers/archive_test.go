@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestArchiveKeepsEveryRun(t *testing.T) {
+	h := &Handler{dirs: Dirs{Archive: t.TempDir()}}
+	session := &models.CorrectionSession{ID: "s1", Images: []models.ImageItem{{ID: "img", ImagePath: "abc.png"}}}
+	image := &session.Images[0]
+	config := sessionConfigOf(session)
+	dir := h.artifactDirFor(imageHash(image), config)
+
+	first := newArtifactArchive()
+	first.add("llm_response.json", []byte(`{"usage":{"total_tokens":42}}`))
+	second := newArtifactArchive()
+	second.add("llm_cache_hit.txt", []byte("key"))
+	for _, archive := range []*artifactArchive{first, second} {
+		if err := archive.write(dir, imageHash(image), config); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.handleArtifacts(w, httptest.NewRequest("GET", "/", nil), session, image, path)
+		return w
+	}
+
+	var manifest ArtifactManifest
+	if err := json.NewDecoder(get("").Body).Decode(&manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Runs) != 2 || manifest.Runs[0].ID != "1" || manifest.Runs[1].ID != "2" {
+		t.Fatalf("manifest runs: got %+v, want runs 1 and 2", manifest.Runs)
+	}
+
+	artifacts := []struct {
+		path string
+		want int
+	}{
+		{"1/llm_response.json", http.StatusOK},
+		{"2/llm_cache_hit.txt", http.StatusOK},
+		{"2/llm_response.json", http.StatusNotFound},
+		{"1/../manifest.json", http.StatusNotFound},
+	}
+	for _, test := range artifacts {
+		if w := get(test.path); w.Code != test.want {
+			t.Errorf("artifact %s: got %d, want %d", test.path, w.Code, test.want)
+		}
+	}
+	if body := get("1/llm_response.json").Body.String(); body != `{"usage":{"total_tokens":42}}` {
+		t.Errorf("first run's response was replaced: got %s", body)
+	}
+}
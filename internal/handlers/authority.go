@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/iiif"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// HandleAuthorityLookup searches the enabled authority files for a free-text query
+func (h *Handler) HandleAuthorityLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	candidates, err := h.authorityService.Lookup(r.Context(), query.Get("q"), query.Get("source"))
+	if err != nil {
+		h.writeError(w, "Authority lookup failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	h.writeJSON(w, AuthorityLookupResponse{
+		Query:      query.Get("q"),
+		Providers:  h.authorityService.Providers(),
+		Candidates: candidates,
+	})
+}
+
+// handleWordAuthorityLookup looks up the phrase formed by the given word ids
+func (h *Handler) handleWordAuthorityLookup(w http.ResponseWriter, r *http.Request, image *models.ImageItem) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	wordIDs := strings.Split(r.URL.Query().Get("word_ids"), ",")
+	phrase, err := phraseForWords(image, wordIDs)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	candidates, err := h.authorityService.Lookup(r.Context(), phrase, r.URL.Query().Get("source"))
+	if err != nil {
+		h.writeError(w, "Authority lookup failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	h.writeJSON(w, AuthorityLookupResponse{
+		Query:      phrase,
+		WordIDs:    wordIDs,
+		Candidates: candidates,
+	})
+}
+
+// handleAnnotations lists, creates, and deletes authority links anchored to words
+func (h *Handler) handleAnnotations(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem, annotationID string) {
+	switch r.Method {
+	case "GET":
+		if r.URL.Query().Get("format") == "jsonld" {
+			h.writeAnnotationPage(w, r, session, image)
+			return
+		}
+		h.writeJSON(w, image.Annotations)
+	case "POST":
+		var annotation models.Annotation
+		if err := json.NewDecoder(r.Body).Decode(&annotation); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if annotation.URI == "" || len(annotation.WordIDs) == 0 {
+			h.writeError(w, "uri and word_ids are required", http.StatusBadRequest)
+			return
+		}
+
+		phrase, err := phraseForWords(image, annotation.WordIDs)
+		if err != nil {
+			h.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		annotation.ID = fmt.Sprintf("anno_%d", time.Now().UnixNano())
+		annotation.Text = phrase
+		annotation.CreatedBy = requestUser(r)
+		annotation.CreatedAt = time.Now()
+		image.Annotations = append(image.Annotations, annotation)
+
+		h.sessionStore.Set(session.ID, session)
+		h.writeJSON(w, annotation)
+	case "DELETE":
+		for i, annotation := range image.Annotations {
+			if annotation.ID == annotationID {
+				image.Annotations = append(image.Annotations[:i], image.Annotations[i+1:]...)
+				h.sessionStore.Set(session.ID, session)
+				h.writeJSON(w, statusSuccess)
+				return
+			}
+		}
+		h.writeError(w, "Annotation not found", http.StatusNotFound)
+	default:
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeAnnotationPage exports annotations as a W3C Web Annotation page, with each
+// target pointing at the image region covered by the linked words
+func (h *Handler) writeAnnotationPage(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	words, _ := hocr.ParseHOCRWords(currentHOCR(image))
+	boxes := make(map[string]models.BBox, len(words))
+	for _, word := range words {
+		boxes[word.ID] = word.BBox
+	}
+
+	base := publicBaseURL(r)
+	imageURL := base + image.ImageURL
+	pageID := fmt.Sprintf("%s%s/sessions/%s/images/%s/annotations", base, APIPrefix, session.ID, image.ID)
+
+	items := make([]map[string]any, 0, len(image.Annotations))
+	for _, annotation := range image.Annotations {
+		target := map[string]any{"source": imageURL}
+		box, ok := unionBoxes(boxes, annotation.WordIDs)
+		// Point at the canvas instead of the derivative image when we know it
+		if image.IIIF != nil && image.IIIF.CanvasID != "" {
+			target["source"] = image.IIIF.CanvasID
+			box = iiif.ScaleToCanvas(*image.IIIF, box, image.ImageWidth, image.ImageHeight)
+		}
+		if ok {
+			target["selector"] = map[string]string{
+				"type":       "FragmentSelector",
+				"conformsTo": "http://www.w3.org/TR/media-frags/",
+				"value":      fmt.Sprintf("xywh=%d,%d,%d,%d", box.X1, box.Y1, box.X2-box.X1, box.Y2-box.Y1),
+			}
+		}
+
+		items = append(items, map[string]any{
+			"id":         pageID + "/" + annotation.ID,
+			"type":       "Annotation",
+			"motivation": "identifying",
+			"created":    annotation.CreatedAt.Format(time.RFC3339),
+			"body": map[string]string{
+				"id":    annotation.URI,
+				"type":  "SpecificResource",
+				"label": annotation.Label,
+			},
+			"target": target,
+		})
+	}
+
+	w.Header().Set("Content-Type", `application/ld+json; profile="http://www.w3.org/ns/anno.jsonld"`)
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"@context": "http://www.w3.org/ns/anno.jsonld",
+		"id":       pageID,
+		"type":     "AnnotationPage",
+		"items":    items,
+	}); err != nil {
+		h.writeError(w, "Unable to encode annotations", http.StatusInternalServerError)
+	}
+}
+
+// phraseForWords joins the text of the given words in the order requested
+func phraseForWords(image *models.ImageItem, wordIDs []string) (string, error) {
+	words, err := hocr.ParseHOCRWords(currentHOCR(image))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse hOCR: %w", err)
+	}
+
+	text := make(map[string]string, len(words))
+	for _, word := range words {
+		text[word.ID] = word.Text
+	}
+
+	var parts []string
+	for _, id := range wordIDs {
+		t, ok := text[strings.TrimSpace(id)]
+		if !ok {
+			return "", fmt.Errorf("word %q not found", id)
+		}
+		parts = append(parts, t)
+	}
+
+	if len(parts) == 0 {
+		return "", fmt.Errorf("word_ids is required")
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func unionBoxes(boxes map[string]models.BBox, wordIDs []string) (models.BBox, bool) {
+	var result models.BBox
+	found := false
+	for _, id := range wordIDs {
+		box, ok := boxes[id]
+		if !ok {
+			continue
+		}
+		if !found {
+			result = box
+			found = true
+			continue
+		}
+		result.X1 = min(result.X1, box.X1)
+		result.Y1 = min(result.Y1, box.Y1)
+		result.X2 = max(result.X2, box.X2)
+		result.Y2 = max(result.Y2, box.Y2)
+	}
+	return result, found
+}
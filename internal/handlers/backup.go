@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/backup"
+)
+
+// BackupAreas are the directories a backup holds: uploads, archived output,
+// the data directory with its jobs and schema version, the sessions, and,
+// unless left out, the cache, which can be rebuilt but saves running OCR and
+// LLM transcriptions again. Sessions are an area of their own, restored to
+// wherever SESSION_STORE_DIR puts them on the new host.
+func (d Dirs) BackupAreas(cache bool) []backup.Area {
+	areas := []backup.Area{
+		{Name: "uploads", Dir: d.Uploads},
+		{Name: "archive", Dir: d.Archive},
+		{Name: "data", Dir: d.Data},
+		{Name: "sessions", Dir: d.sessionsDir()},
+	}
+	if cache {
+		areas = append(areas, backup.Area{Name: "cache", Dir: d.Cache})
+	}
+	return areas
+}
+
+// sessionsDir is where sessions are persisted, SESSION_STORE_DIR or sessions in
+// the data directory
+func (d Dirs) sessionsDir() string {
+	if dir := os.Getenv("SESSION_STORE_DIR"); dir != "" && dir != "memory" {
+		return dir
+	}
+	return filepath.Join(d.Data, "sessions")
+}
+
+// HandleBackup streams a gzipped tar of the server's state at GET
+// /admin/backup, for moving it to another host with the restore command.
+// Sessions and jobs are written to disk first, and storage cleanup waits until
+// the archive is done. cache=false leaves the cache out.
+func (h *Handler) HandleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requirePermission(w, r, "", auth.ManageStorage) {
+		return
+	}
+	cache := true
+	if value := r.URL.Query().Get("cache"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			h.writeError(w, "cache must be true or false", http.StatusBadRequest)
+			return
+		}
+		cache = parsed
+	}
+	if os.Getenv("SESSION_STORE_DIR") == "memory" {
+		h.writeError(w, "sessions are kept in memory only and can't be backed up", http.StatusConflict)
+		return
+	}
+
+	h.cleanupMu.Lock()
+	defer h.cleanupMu.Unlock()
+	if err := h.Flush(); err != nil {
+		h.writeError(w, "Failed to save state: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="hocredit-backup-%s.tar.gz"`, time.Now().UTC().Format("20060102T150405Z")))
+	stats, err := backup.Write(w, h.dirs.BackupAreas(cache))
+	if err != nil {
+		// The status is sent; a truncated archive fails to restore
+		slog.Error("Backup failed", "err", err)
+		return
+	}
+	slog.Info("Backup written", "user", requestUser(r), "files", stats.Files, "bytes", stats.Bytes, "cache", cache)
+}
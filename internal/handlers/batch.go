@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// Batch upload modes
+const (
+	batchSeparate = "separate"
+	batchSingle   = "single"
+)
+
+// batchItem is one file or URL of a batch upload
+type batchItem struct {
+	source   string
+	filename string
+	file     *spooledFile
+	url      string
+}
+
+// name is what the item's session is called
+func (item batchItem) name() string {
+	if item.url == "" {
+		return fileSessionName(item.filename)
+	}
+	return urlSessionName(item.url)
+}
+
+// removeSpooled removes the spooled files of uploaded items
+func removeSpooled(items []batchItem) {
+	for _, item := range items {
+		item.file.remove()
+	}
+}
+
+// urlSessionName names a session after a URL before the image is downloaded,
+// using the IIIF identifier for info.json URLs
+func urlSessionName(imageURL string) string {
+	parsed, err := url.Parse(imageURL)
+	if err != nil {
+		return "url"
+	}
+
+	name := path.Base(parsed.Path)
+	if name == "info.json" {
+		name = path.Base(path.Dir(parsed.Path))
+	}
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if name == "" || name == "." || name == "/" {
+		return "url"
+	}
+	return name
+}
+
+// HandleBatchUpload queues a whole folder of files, or a list of URLs, at once.
+// Each item becomes its own session, or with mode "single" all of them become
+// pages of one session. Session IDs are assigned up front so they can be
+// returned immediately alongside the jobs that fill them.
+func (h *Handler) HandleBatchUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireStorage(w) {
+		return
+	}
+
+	var (
+		items  []batchItem
+		config SessionConfig
+		mode   string
+		name   string
+		err    error
+	)
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		items, config, mode, name, err = batchFromJSON(r)
+	} else {
+		items, config, mode, name, err = h.batchFromForm(w, r)
+	}
+	if err != nil {
+		h.writeError(w, err.Error(), uploadErrorStatus(err))
+		return
+	}
+	// Spooled files are removed by the jobs they're queued for, or here when
+	// they aren't queued
+	queued := make([]bool, len(items))
+	defer func() {
+		for i, item := range items {
+			if !queued[i] {
+				item.file.remove()
+			}
+		}
+	}()
+
+	if len(items) == 0 {
+		h.writeError(w, "files or urls are required", http.StatusBadRequest)
+		return
+	}
+	if limit := batchMaxItems(); len(items) > limit {
+		h.writeError(w, fmt.Sprintf("a batch may hold at most %d items", limit), http.StatusBadRequest)
+		return
+	}
+	if err := h.hocrService.ValidateEngine(config.Engine); err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.ensureUploadsDir(); err != nil {
+		h.writeError(w, "Failed to create uploads directory: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user := requestUser(r)
+	taken := make(map[string]bool)
+	response := BatchUploadResponse{Mode: mode}
+
+	enqueue := func(entry BatchSession, kind string, run jobFunc) bool {
+		job, err := h.enqueueJob(kind, user, run)
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.JobID = job.ID
+			entry.StatusURL = APIPrefix + "/jobs/" + job.ID
+			response.Queued++
+		}
+		response.Sessions = append(response.Sessions, entry)
+		return err == nil
+	}
+
+	switch mode {
+	case batchSingle:
+		if name == "" {
+			name = items[0].name()
+		}
+		sessionID := h.batchSessionID(name, taken)
+		if enqueue(BatchSession{SessionID: sessionID, Items: len(items)}, "upload_batch", h.combinedUploadJob(items, sessionID, config)) {
+			for i := range queued {
+				queued[i] = true
+			}
+		}
+	default:
+		for i, item := range items {
+			sessionID := h.batchSessionID(item.name(), taken)
+			entry := BatchSession{Source: item.source, SessionID: sessionID}
+			if item.url != "" {
+				enqueue(entry, "upload_url", h.urlUploadJob(item.url, sessionID, config))
+			} else {
+				queued[i] = enqueue(entry, "upload_file", h.fileUploadJob(item.file, item.filename, sessionID, config))
+			}
+		}
+	}
+
+	status := http.StatusAccepted
+	if response.Queued == 0 {
+		status = http.StatusServiceUnavailable
+	}
+	h.writeJSONStatus(w, status, response)
+}
+
+func batchFromJSON(r *http.Request) ([]batchItem, SessionConfig, string, string, error) {
+	var request BatchUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, SessionConfig{}, "", "", fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	mode, err := batchMode(request.Mode)
+	if err != nil {
+		return nil, SessionConfig{}, "", "", err
+	}
+
+	var items []batchItem
+	for _, imageURL := range request.URLs {
+		if imageURL = strings.TrimSpace(imageURL); imageURL != "" {
+			items = append(items, batchItem{source: imageURL, url: imageURL})
+		}
+	}
+
+	config, err := pipelineConfig(request.Engine, request.Language, request.DocumentType, request.Characters, request.Binarization)
+	if err != nil {
+		return nil, SessionConfig{}, "", "", err
+	}
+	return items, config, mode, request.Name, nil
+}
+
+// batchMaxItems is the most files or URLs one batch may hold
+func batchMaxItems() int {
+	return utils.GetEnvInt("BATCH_MAX_ITEMS", 100)
+}
+
+func (h *Handler) batchFromForm(w http.ResponseWriter, r *http.Request) ([]batchItem, SessionConfig, string, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes()*int64(batchMaxItems())+uploadFormOverhead)
+	if err := r.ParseMultipartForm(uploadMemory); err != nil {
+		return nil, SessionConfig{}, "", "", fmt.Errorf("failed to read form: %w", err)
+	}
+
+	mode, err := batchMode(r.FormValue("mode"))
+	if err != nil {
+		return nil, SessionConfig{}, "", "", err
+	}
+	config, err := sessionConfigFromForm(r)
+	if err != nil {
+		return nil, SessionConfig{}, "", "", err
+	}
+
+	var items []batchItem
+	for _, header := range r.MultipartForm.File["files"] {
+		file, filename, err := h.spoolUpload(header)
+		if err != nil {
+			removeSpooled(items)
+			return nil, SessionConfig{}, "", "", err
+		}
+		items = append(items, batchItem{source: header.Filename, filename: filename, file: file})
+	}
+
+	return items, config, mode, r.FormValue("name"), nil
+}
+
+func batchMode(mode string) (string, error) {
+	switch mode {
+	case "", batchSeparate:
+		return batchSeparate, nil
+	case batchSingle:
+		return batchSingle, nil
+	}
+	return "", fmt.Errorf("mode must be %s or %s", batchSeparate, batchSingle)
+}
+
+// batchSessionID names a session with a timestamp like single uploads do, adding
+// a counter when two items of the batch, or an existing session, share the name
+func (h *Handler) batchSessionID(name string, taken map[string]bool) string {
+	base := fmt.Sprintf("%s_%d", name, time.Now().Unix())
+	sessionID := base
+	for n := 2; ; n++ {
+		if _, exists := h.sessionStore.Get(sessionID); !exists && !taken[sessionID] {
+			break
+		}
+		sessionID = fmt.Sprintf("%s_%d", base, n)
+	}
+	taken[sessionID] = true
+	return sessionID
+}
+
+// combinedUploadJob processes every item of a batch into pages of one session,
+// removing the spooled files once they're stored
+func (h *Handler) combinedUploadJob(items []batchItem, sessionID string, config SessionConfig) jobFunc {
+	return func(trace *jobTrace) (string, any, error) {
+		defer removeSpooled(items)
+		sources := make([]string, len(items))
+		for i, item := range items {
+			sources[i] = item.source
+		}
+		trace.input("sources", sources)
+		trace.input("config", config)
+		config.trace = trace
+
+		// Items are processed concurrently, and their pages kept in order
+		processed := make([]*urlImages, len(items))
+		err := h.pages.each(len(items), func(i int) error {
+			item := items[i]
+			if item.url == "" {
+				results, err := h.processUploadedFile(item.file, item.filename, config)
+				if err != nil {
+					return fmt.Errorf("%s: %w", item.source, err)
+				}
+				processed[i] = &urlImages{results: results}
+				return nil
+			}
+
+			images, err := h.processURL(item.url, config)
+			if err != nil {
+				return fmt.Errorf("%s: %w", item.source, err)
+			}
+			processed[i] = images
+			return nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+
+		var results []*ImageProcessResult
+		var iiifSources []*models.IIIFSource
+		for _, images := range processed {
+			results = append(results, images.results...)
+			for range images.results {
+				iiifSources = append(iiifSources, images.iiifSource)
+			}
+		}
+
+		if err := checkSandboxPages(len(results)); err != nil {
+			return "", nil, err
+		}
+
+		session := h.createMultiImageSession(sessionID, results, config)
+		for i := range session.Images {
+			setIIIFSource(session.Images[i:i+1], iiifSources[i])
+		}
+		h.sessionStore.Set(sessionID, session)
+
+		return sessionID, BatchResult{
+			SessionID: sessionID,
+			Message:   fmt.Sprintf("Successfully processed %d items", len(items)),
+			Images:    len(results),
+		}, nil
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/metrics"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// defaultBucketWidth splits confidences into tenths
+const defaultBucketWidth = 10
+
+// handleCalibration buckets the OCR words of a session's completed pages by
+// engine confidence and reports how often editors corrected each bucket.
+// Pages still in progress are left out, as their unreviewed words would count
+// as accepted.
+func (h *Handler) handleCalibration(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	width := defaultBucketWidth
+	if value := r.URL.Query().Get("bucket_width"); value != "" {
+		var err error
+		width, err = strconv.Atoi(value)
+		if err != nil || width < 1 || width > 100 {
+			h.writeError(w, "bucket_width must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+	}
+
+	calibration := metrics.NewCalibration(width)
+	for i := range session.Images {
+		image := &session.Images[i]
+		if !image.Completed {
+			continue
+		}
+		original, err := hocr.ParseHOCRLines(image.OriginalHOCR)
+		if err != nil {
+			calibration.Skipped++
+			continue
+		}
+		corrected, err := hocr.ParseHOCRLines(currentHOCR(image))
+		if err != nil {
+			calibration.Skipped++
+			continue
+		}
+		calibration.AddPage(original, corrected, models.NormalizationConfig{})
+	}
+	h.writeJSON(w, calibration)
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/eval"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/webhook"
+)
+
+// EventSessionCompleted is the event posted to completion callbacks
+const EventSessionCompleted = "session.completed"
+
+// callbackAttempts is how many times a completion callback is posted before
+// the job fails
+const callbackAttempts = 3
+
+// callbackClient dials callbacks directly rather than through HTTP_PROXY, so
+// the address checked is the one connected to, redirects and DNS changes included
+var callbackClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				return checkCallbackAddress(net.ParseIP(host))
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+}
+
+// checkCallbackAddress refuses loopback, private, link-local (cloud metadata
+// included) and other non-public addresses, so a session editor can't have the
+// server post to the services beside it. CALLBACK_ALLOW_PRIVATE=true lifts this
+// for callbacks to hosts on the campus network.
+func checkCallbackAddress(ip net.IP) error {
+	if os.Getenv("CALLBACK_ALLOW_PRIVATE") == "true" {
+		return nil
+	}
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("callback address %s is not public", ip)
+	}
+	return nil
+}
+
+// sharedAddressSpace is carrier-grade NAT (RFC 6598), private in practice
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// validateCallbackURL refuses URLs that aren't absolute http(s) or whose host
+// resolves to an address callbacks may not be posted to. Delivery checks again,
+// since the host's addresses may change in between.
+func validateCallbackURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("callback url must be an absolute http or https URL")
+	}
+
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil {
+		return fmt.Errorf("callback host can't be resolved: %w", err)
+	}
+	for _, ip := range ips {
+		if err := checkCallbackAddress(ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleCallback shows, registers or removes the URL notified when every image
+// of the session is completed
+func (h *Handler) handleCallback(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	switch r.Method {
+	case "GET":
+		if session.Callback == nil {
+			h.writeError(w, "No callback registered", http.StatusNotFound)
+			return
+		}
+		h.writeJSON(w, session.Callback)
+	case "PUT":
+		var request CallbackRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateCallbackURL(request.URL); err != nil {
+			h.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		session.Callback = &models.Callback{URL: request.URL}
+		h.sessionStore.Set(session.ID, session)
+		slog.Info("Completion callback registered", "session_id", session.ID, "url", request.URL)
+		// A session that is already finished is reported right away
+		h.notifyCompletion(r, session)
+		h.writeJSON(w, session.Callback)
+	case "DELETE":
+		session.Callback = nil
+		h.sessionStore.Set(session.ID, session)
+		h.writeJSON(w, statusSuccess)
+	default:
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func sessionCompleted(session *models.CorrectionSession) bool {
+	for i := range session.Images {
+		if !session.Images[i].Completed {
+			return false
+		}
+	}
+	return len(session.Images) > 0
+}
+
+// notifyCompletion queues the session's callback when its last image has just
+// been completed. Call it after saving changes to images.
+func (h *Handler) notifyCompletion(r *http.Request, session *models.CorrectionSession) {
+	callback := session.Callback
+	if callback == nil {
+		return
+	}
+	if !sessionCompleted(session) {
+		if callback.SentAt != nil {
+			callback.SentAt = nil
+			h.sessionStore.Set(session.ID, session)
+		}
+		return
+	}
+	if callback.SentAt != nil {
+		return
+	}
+
+	now := time.Now()
+	callback.SentAt = &now
+	h.sessionStore.Set(session.ID, session)
+
+	event := completionEvent(publicBaseURL(r), session, now)
+	if _, err := h.enqueueJob("completion_callback", requestUser(r), h.completionCallbackJob(session.ID, callback.URL, event)); err != nil {
+		slog.Error("Failed to queue completion callback", "session_id", session.ID, "err", err)
+	}
+}
+
+// completionEvent describes a completed session, with absolute hOCR URLs
+func completionEvent(base string, session *models.CorrectionSession, completedAt time.Time) CompletionEvent {
+	results := sessionReport(session)
+	event := CompletionEvent{
+		Event:       EventSessionCompleted,
+		SessionID:   session.ID,
+		Collection:  session.Collection,
+		CompletedAt: completedAt,
+		Summary:     eval.Summarize(results, 0),
+		Images:      make([]CompletedImage, 0, len(session.Images)),
+	}
+	for i := range session.Images {
+		image := &session.Images[i]
+		event.Images = append(event.Images, CompletedImage{
+			ImageID:         image.ID,
+			HOCRURL:         fmt.Sprintf("%s%s/sessions/%s/export?format=hocr&image_id=%s", base, APIPrefix, url.PathEscape(session.ID), url.QueryEscape(image.ID)),
+			DrupalNid:       image.DrupalNid,
+			DrupalUploadURL: image.DrupalUploadURL,
+			PublishedAt:     image.PublishedAt,
+		})
+	}
+	return event
+}
+
+// completionCallbackJob posts the event, signed with COMPLETION_WEBHOOK_SECRET
+// when it is set. A callback that can't be delivered is marked unsent, so the
+// next save of the session tries again.
+func (h *Handler) completionCallbackJob(sessionID, callbackURL string, event CompletionEvent) jobFunc {
+	return func(trace *jobTrace) (string, any, error) {
+		trace.input("session_id", sessionID)
+		trace.input("callback_url", callbackURL)
+
+		body, err := json.Marshal(event)
+		if err != nil {
+			return sessionID, nil, fmt.Errorf("failed to encode completion event: %w", err)
+		}
+
+		for attempt := 1; ; attempt++ {
+			done := trace.stage(fmt.Sprintf("post %s (attempt %d)", callbackURL, attempt))
+			err = postCallback(callbackURL, body)
+			done(err)
+			if err == nil || attempt == callbackAttempts {
+				break
+			}
+			time.Sleep(time.Duration(attempt) * 2 * time.Second)
+		}
+		if err != nil {
+			if session, ok := h.sessionStore.Get(sessionID); ok && session.Callback != nil && session.Callback.URL == callbackURL {
+				session.Callback.SentAt = nil
+				h.sessionStore.Set(sessionID, session)
+			}
+			return sessionID, nil, fmt.Errorf("completion callback to %s failed after %d attempts: %w", callbackURL, callbackAttempts, err)
+		}
+
+		trace.log().Info("Completion callback delivered", "session_id", sessionID, "url", callbackURL)
+		return sessionID, map[string]string{"callback_url": callbackURL}, nil
+	}
+}
+
+func postCallback(callbackURL string, body []byte) error {
+	req, err := http.NewRequest("POST", callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret := os.Getenv("COMPLETION_WEBHOOK_SECRET"); secret != "" {
+		now := time.Now()
+		req.Header.Set(webhook.TimestampHeader, fmt.Sprintf("%d", now.Unix()))
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, now, body))
+	}
+
+	resp, err := callbackClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("HTTP %d - %s", resp.StatusCode, string(message))
+	}
+	return nil
+}
+
+// publicBaseURL is where clients reach the app: PUBLIC_BASE_URL when set, since
+// behind a TLS-terminating proxy the request only shows the internal address,
+// or else the scheme and host the request was addressed to
+func publicBaseURL(r *http.Request) string {
+	if base := os.Getenv("PUBLIC_BASE_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	if r.TLS != nil {
+		return "https://" + r.Host
+	}
+	return "http://" + r.Host
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCallbacksRefusePrivateAddresses(t *testing.T) {
+	for _, rawURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.5/hook",
+		"http://[::1]/hook",
+		"ftp://example.edu/hook",
+	} {
+		if err := validateCallbackURL(rawURL); err == nil {
+			t.Errorf("%s was accepted", rawURL)
+		}
+	}
+
+	// Delivery checks the address it connects to, whatever was registered
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	if err := postCallback(server.URL, []byte(`{}`)); err == nil {
+		t.Error("callback was posted to a loopback address")
+	}
+
+	t.Setenv("CALLBACK_ALLOW_PRIVATE", "true")
+	if err := validateCallbackURL(server.URL); err != nil {
+		t.Errorf("private callback refused when allowed: %v", err)
+	}
+	if err := postCallback(server.URL, []byte(`{}`)); err != nil {
+		t.Errorf("private callback not posted when allowed: %v", err)
+	}
+}
+
+func TestPublicBaseURL(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://10.0.0.5:8080/api/v1/sessions", nil)
+	if got := publicBaseURL(r); got != "http://10.0.0.5:8080" {
+		t.Errorf("without PUBLIC_BASE_URL got %s", got)
+	}
+	t.Setenv("PUBLIC_BASE_URL", "https://hocredit.example.edu/")
+	if got := publicBaseURL(r); got != "https://hocredit.example.edu" {
+		t.Errorf("with PUBLIC_BASE_URL got %s", got)
+	}
+}
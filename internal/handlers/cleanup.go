@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// storageRetention is how long files no session refers to are kept, from
+// STORAGE_RETENTION_HOURS (default 168, a week, and at least 1). It leaves
+// time for uploads still being processed, prefetched images waiting for a
+// session, and sessions deleted by mistake to be recreated from the cache.
+func storageRetention() time.Duration {
+	return time.Duration(max(1, utils.GetEnvInt("STORAGE_RETENTION_HOURS", 168))) * time.Hour
+}
+
+// storageCleanupInterval is how often the background cleaner runs, from
+// STORAGE_CLEANUP_INTERVAL_HOURS (default 24); 0 turns it off
+func storageCleanupInterval() time.Duration {
+	return time.Duration(max(0, utils.GetEnvInt("STORAGE_CLEANUP_INTERVAL_HOURS", 24))) * time.Hour
+}
+
+// storageArea is a directory whose entries are named after upload hashes
+type storageArea struct {
+	name string
+	dir  string
+}
+
+// storageAreas are where an upload and everything derived from it are kept
+func (h *Handler) storageAreas() []storageArea {
+	return []storageArea{
+		{"uploads", h.dirs.Uploads},
+		{"originals", h.originalsDir()},
+		{"houdini", h.houdiniDir()},
+		{"tiles", h.tilesDir()},
+		{"archive", h.dirs.Archive},
+	}
+}
+
+// uploadHashOf is the upload digest a stored file is named after: a SHA-256,
+// or an MD5 for older uploads, in hex and followed by an extension, a suffix
+// or nothing
+func uploadHashOf(name string) (string, bool) {
+	for _, length := range []int{64, 32} {
+		if len(name) < length {
+			continue
+		}
+		if _, err := hex.DecodeString(name[:length]); err != nil {
+			continue
+		}
+		if len(name) > length && name[length] != '.' && name[length] != '_' {
+			continue
+		}
+		return name[:length], true
+	}
+	return "", false
+}
+
+// RunStorageCleanup removes stored files no session refers to every
+// STORAGE_CLEANUP_INTERVAL_HOURS. It returns at once when that is 0, and
+// otherwise never.
+func (h *Handler) RunStorageCleanup() {
+	interval := storageCleanupInterval()
+	if interval == 0 {
+		slog.Info("Storage cleanup disabled")
+		return
+	}
+	slog.Info("Storage cleanup scheduled", "interval", interval, "retention", storageRetention())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.cleanStorage(storageRetention(), false)
+	}
+}
+
+// cleanStorage removes the uploads, cached hOCR and derivatives of images no
+// session refers to once they're older than retention, then the prefetch
+// records left pointing at removed images. A dry run only counts them.
+func (h *Handler) cleanStorage(retention time.Duration, dryRun bool) StorageCleanup {
+	h.cleanupMu.Lock()
+	defer h.cleanupMu.Unlock()
+
+	// Sessions hold their uploads as they're saved; images added to a session
+	// in place, without a save, are found by looking
+	held := h.blobs.Held()
+	referenced := make(map[string]bool, len(held))
+	shared := 0
+	for digest, holders := range held {
+		referenced[digest] = true
+		if holders > 1 {
+			shared++
+		}
+	}
+	for _, session := range h.sessionStore.GetAll() {
+		for i := range session.Images {
+			referenced[imageHash(&session.Images[i])] = true
+		}
+	}
+
+	result := StorageCleanup{
+		DryRun:           dryRun,
+		RetentionHours:   int(retention.Hours()),
+		ReferencedImages: len(referenced),
+		SharedImages:     shared,
+		Removed:          make(map[string]StorageUsage),
+	}
+	cutoff := time.Now().Add(-retention)
+	removed := make(map[string]bool)
+	count := func(area, path string, size int64) {
+		usage := result.Removed[area]
+		usage.Entries++
+		usage.Bytes += size
+		result.Removed[area] = usage
+		result.Entries++
+		result.Bytes += size
+		removed[path] = true
+	}
+
+	for _, area := range h.storageAreas() {
+		entries, err := os.ReadDir(area.dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				slog.Warn("Failed to list stored files", "dir", area.dir, "err", err)
+			}
+			continue
+		}
+		for _, entry := range entries {
+			hash, ok := uploadHashOf(entry.Name())
+			if !ok || referenced[hash] {
+				continue
+			}
+			path := filepath.Join(area.dir, entry.Name())
+			if size, ok := h.removeUnused(path, hash, cutoff, dryRun); ok {
+				count(area.name, path, size)
+			}
+		}
+	}
+
+	records, _ := filepath.Glob(filepath.Join(h.prefetchDir(), "*.json"))
+	for _, record := range records {
+		data, err := os.ReadFile(record)
+		if err != nil {
+			continue
+		}
+		var prefetched ImageProcessResult
+		if err := json.Unmarshal(data, &prefetched); err != nil {
+			continue
+		}
+		if _, err := os.Stat(prefetched.ImageFilePath); err == nil && !removed[prefetched.ImageFilePath] {
+			continue
+		}
+		info, err := os.Stat(record)
+		if err != nil {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(record); err != nil {
+				slog.Warn("Failed to remove prefetch record", "path", record, "err", err)
+				continue
+			}
+		}
+		count("prefetch", record, info.Size())
+	}
+
+	if !dryRun {
+		h.remeasureStorage()
+	}
+	result.UsedBytes, result.QuotaBytes = h.storageUsed(), storageQuotaBytes()
+	slog.Info("Storage cleaned", "dry_run", dryRun, "retention", retention, "referenced_images", result.ReferencedImages, "entries", result.Entries, "bytes", result.Bytes)
+	return result
+}
+
+// removeUnused removes a stored file of an upload nobody holds once it's older
+// than cutoff, returning its size. The upload's lock is held throughout, so a
+// Put reusing the upload either refreshes its time before the check or waits
+// until the removal is done and stores it again.
+func (h *Handler) removeUnused(path, hash string, cutoff time.Time, dryRun bool) (int64, bool) {
+	unlock := h.blobs.Lock(hash)
+	defer unlock()
+
+	info, err := os.Lstat(path)
+	if err != nil || info.ModTime().After(cutoff) || h.blobs.Holders(hash) > 0 {
+		return 0, false
+	}
+	size := diskUsage(path, info)
+	if !dryRun {
+		if err := os.RemoveAll(path); err != nil {
+			slog.Warn("Failed to remove stored file", "path", path, "err", err)
+			return 0, false
+		}
+	}
+	return size, true
+}
+
+// diskUsage is the size of a file, or of everything under a directory
+func diskUsage(path string, info fs.FileInfo) int64 {
+	if !info.IsDir() {
+		return info.Size()
+	}
+	var size int64
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// HandleStorageCleanup reports on GET what a cleanup of stored files would
+// remove, and runs one on POST. retention_hours overrides
+// STORAGE_RETENTION_HOURS for the one run.
+func (h *Handler) HandleStorageCleanup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requirePermission(w, r, "", auth.ManageStorage) {
+		return
+	}
+
+	retention := storageRetention()
+	if value := r.URL.Query().Get("retention_hours"); value != "" {
+		hours, err := strconv.Atoi(value)
+		if err != nil || hours < 1 {
+			h.writeError(w, "retention_hours must be a whole number of hours, at least 1", http.StatusBadRequest)
+			return
+		}
+		retention = time.Duration(hours) * time.Hour
+	}
+
+	h.writeJSON(w, h.cleanStorage(retention, r.Method == "GET"))
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+func TestCleanStorageRetentionAndHolds(t *testing.T) {
+	uploads := t.TempDir()
+	h := &Handler{
+		dirs:         Dirs{Uploads: uploads, Cache: t.TempDir(), Archive: t.TempDir()},
+		sessionStore: storage.New(),
+		blobs:        storage.NewBlobStore(uploads),
+	}
+	retention := 24 * time.Hour
+
+	digest := func(name string) string { return storage.ContentDigest([]byte(name)) }
+	store := func(name string, age time.Duration) string {
+		path := h.blobs.Path(digest(name), ".png")
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modified := time.Now().Add(-age)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	expired := store("expired", retention+time.Hour)
+	recent := store("recent", retention-time.Hour)
+	held := store("held", 30*retention)
+	reused := store("reused", 30*retention)
+	h.blobs.Hold("s1", []string{digest("held")})
+	// An upload matching a stored blob before its session is saved
+	if _, err := h.blobs.Put(digest("reused"), ".png", func(string) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	dry := h.cleanStorage(retention, true)
+	if dry.Entries != 1 {
+		t.Errorf("dry run counted %d entries, want 1", dry.Entries)
+	}
+	if _, err := os.Stat(expired); err != nil {
+		t.Errorf("dry run removed an upload: %v", err)
+	}
+
+	result := h.cleanStorage(retention, false)
+	if result.Entries != 1 || result.Removed["uploads"].Entries != 1 {
+		t.Errorf("cleanup removed %+v, want only the expired upload", result.Removed)
+	}
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Errorf("unheld upload past retention survived: %v", err)
+	}
+	for name, path := range map[string]string{"recent": recent, "held": held, "reused": reused} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s upload was removed: %v", name, err)
+		}
+	}
+
+	h.blobs.Hold("s1", nil)
+	if result := h.cleanStorage(retention, false); result.Entries != 1 {
+		t.Errorf("released upload: cleanup removed %d entries, want 1", result.Entries)
+	}
+	if _, err := os.Stat(held); !os.IsNotExist(err) {
+		t.Errorf("released upload past retention survived: %v", err)
+	}
+}
+
+func TestCleanStorageWaitsForPut(t *testing.T) {
+	uploads := t.TempDir()
+	h := &Handler{
+		dirs:         Dirs{Uploads: uploads, Cache: t.TempDir(), Archive: t.TempDir()},
+		sessionStore: storage.New(),
+		blobs:        storage.NewBlobStore(uploads),
+	}
+	digest := storage.ContentDigest([]byte("scan"))
+	path := h.blobs.Path(digest, ".png")
+	if err := os.WriteFile(path, []byte("scan"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// A Put reusing the blob holds its lock while the sweep looks at it, then
+	// refreshes it, so the sweep must leave it alone
+	unlock := h.blobs.Lock(digest)
+	done := make(chan StorageCleanup)
+	go func() { done <- h.cleanStorage(24*time.Hour, false) }()
+	time.Sleep(20 * time.Millisecond)
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+
+	if result := <-done; result.Entries != 0 {
+		t.Errorf("sweep removed %d entries while the blob was being reused", result.Entries)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("reused blob was removed: %v", err)
+	}
+}
@@ -2,20 +2,55 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/authority"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/drupal"
 	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
 	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/notify"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/repository"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/spell"
 	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
 )
 
 type Handler struct {
-	sessionStore *storage.SessionStore
-	hocrService  *hocr.Service
+	dirs             Dirs
+	static           fs.FS
+	sessionStore     *storage.SessionStore
+	blobs            *storage.BlobStore
+	macroStore       *storage.MacroStore
+	externalJobStore *storage.ExternalJobStore
+	jobStore         *storage.JobStore
+	jobQueue         chan queuedJob
+	queueMu          sync.RWMutex
+	draining         bool
+	workers          sync.WaitGroup
+	pages            *pagePool
+	parsed           *parseCache
+	hocrService      *hocr.Service
+	authorityService *authority.Service
+	drupal           *drupal.Client
+	repositories     map[string]repository.Store
+	permissions      *auth.Policy
+	authenticators   auth.Authenticators
+	notifier         *notify.Service
+	spellService     *spell.Service
+	live             *liveHub
+	llmHealth        llmHealth
+	prefetchStore    *storage.PrefetchStore
+	cleanupMu        sync.Mutex
+	quota            storageQuota
 }
 
 type ImageProcessResult struct {
@@ -24,26 +59,284 @@ type ImageProcessResult struct {
 	HOCRXML       string
 	Width         int
 	Height        int
-	MD5Hash       string
+	// Digest is the SHA-256 of the source image, naming the upload and its derivatives
+	Digest string
+	// LegacyDigest is the MD5 of the source image, which named its cached hOCR
+	// before uploads were keyed by SHA-256, when the source was at hand
+	LegacyDigest string
 }
 
 type SessionConfig struct {
-	Model       string
-	Prompt      string
-	Temperature float64
-	Prefix      string
+	Model        string                    `json:"model,omitempty"`
+	Prompt       string                    `json:"prompt,omitempty"`
+	Temperature  float64                   `json:"temperature,omitempty"`
+	Prefix       string                    `json:"prefix,omitempty"`
+	Engine       string                    `json:"engine,omitempty"`
+	Binarization models.BinarizationConfig `json:"binarization"`
+	// Language is what the text is in, as hocr.ParseLanguage returns it
+	Language string `json:"language,omitempty"`
+	// DocumentType is how the pages were printed, as hocr.ParseDocumentType
+	// returns it
+	DocumentType string `json:"document_type,omitempty"`
+	// Characters limits the characters of the text, as hocr.ParseCharset reads
+	// them
+	Characters models.CharacterConfig `json:"characters"`
+	// LLM is what the LLM engine is asked with, set from Model, Prompt and
+	// Temperature for a branch trying them; those alone only label a session
+	LLM models.LLMConfig `json:"-"`
+
+	// trace collects diagnostics when the config is processed as a background job
+	trace *jobTrace
 }
 
-func New() *Handler {
-	return &Handler{
-		sessionStore: storage.New(),
-		hocrService:  hocr.NewService(),
+// sessionConfigOf recovers the pipeline settings a session was processed with
+func sessionConfigOf(session *models.CorrectionSession) SessionConfig {
+	return SessionConfig{
+		Model:        session.Config.Model,
+		Prompt:       session.Config.Prompt,
+		Temperature:  session.Config.Temperature,
+		Engine:       session.Config.Engine,
+		Binarization: session.Config.Binarization,
+		Language:     session.Config.Language,
+		DocumentType: session.Config.DocumentType,
+		Characters:   session.Config.Characters,
+		LLM:          session.Config.LLM,
 	}
 }
 
+// pipelineConfig is the settings an upload asked for, with the language read
+// by hocr.ParseLanguage, the document type by hocr.ParseDocumentType and the
+// characters checked by hocr.ParseCharset
+func pipelineConfig(engine, language, documentType string, characters models.CharacterConfig, binarization models.BinarizationConfig) (SessionConfig, error) {
+	language, err := hocr.ParseLanguage(language)
+	if err != nil {
+		return SessionConfig{}, err
+	}
+	documentType, err = hocr.ParseDocumentType(documentType)
+	if err != nil {
+		return SessionConfig{}, err
+	}
+	if _, err := hocr.ParseCharset(characters); err != nil {
+		return SessionConfig{}, err
+	}
+	return SessionConfig{Engine: engine, Binarization: binarization, Language: language, DocumentType: documentType, Characters: characters}, nil
+}
+
+// resolveEngine fills in the deployment's default engine so sessions and cache
+// keys record which engine actually ran
+func (h *Handler) resolveEngine(config SessionConfig) SessionConfig {
+	if config.Engine == "" {
+		config.Engine = h.hocrService.DefaultEngine()
+	}
+	return config
+}
+
+// New builds the handler around the given directories, loading the sessions
+// and jobs kept under them
+func New(dirs Dirs) *Handler {
+	h := &Handler{
+		dirs:             dirs,
+		static:           staticFiles(dirs.Static),
+		sessionStore:     newSessionStore(dirs.Data),
+		blobs:            storage.NewBlobStore(dirs.Uploads),
+		macroStore:       newMacroStore(macroStorePath(dirs.Data)),
+		externalJobStore: newExternalJobStore(externalJobStorePath(dirs.Data)),
+		jobStore:         newJobStore(jobStorePath(dirs.Data)),
+		prefetchStore:    newPrefetchStore(prefetchStorePath(dirs.Data)),
+		hocrService:      hocr.NewService(hocr.Dirs{Temp: dirs.Temp, LLMCache: hocr.LLMCacheDir(dirs.Cache)}),
+		authorityService: authority.NewService(),
+		drupal:           newDrupalClient(),
+		repositories:     newRepositories(),
+		permissions:      newPermissionPolicy(),
+		authenticators:   newAuthenticators(),
+		notifier:         notify.NewService(),
+		spellService:     spell.NewService(),
+		live:             newLiveHub(),
+		pages:            newPagePool(),
+		parsed:           newParseCache(),
+	}
+	h.holdSessionImages()
+	h.startJobWorkers()
+	return h
+}
+
+// holdSessionImages has every session hold the uploads its images use, now
+// and as sessions are saved or deleted, so shared uploads are counted
+func (h *Handler) holdSessionImages() {
+	hold := func(sessionID string, session *models.CorrectionSession) {
+		var digests []string
+		if session != nil {
+			for i := range session.Images {
+				digests = append(digests, imageHash(&session.Images[i]))
+			}
+		}
+		h.blobs.Hold(sessionID, digests)
+	}
+	for sessionID, session := range h.sessionStore.GetAll() {
+		hold(sessionID, session)
+	}
+	h.sessionStore.OnChange(hold)
+}
+
+// newDrupalClient authenticates Drupal requests with DRUPAL_AUTH_FILE
+func newDrupalClient() *drupal.Client {
+	client, err := drupal.NewClientFromEnv()
+	if err != nil {
+		utils.ExitOnError("Unable to load Drupal credentials", err)
+	}
+	return client
+}
+
+// SessionStoreDir is where sessions are persisted, SESSION_STORE_DIR (default
+// sessions in the data directory), or "" when it is set to "memory" and they
+// are kept in memory only
+func SessionStoreDir(dataDir string) string {
+	dir := os.Getenv("SESSION_STORE_DIR")
+	if dir == "memory" {
+		return ""
+	}
+	if dir == "" {
+		dir = filepath.Join(dataDir, "sessions")
+	}
+	return dir
+}
+
+func newSessionStore(dataDir string) *storage.SessionStore {
+	dir := SessionStoreDir(dataDir)
+	if dir == "" {
+		return storage.New()
+	}
+
+	store, err := storage.NewPersistent(dir)
+	if err != nil {
+		utils.ExitOnError("Unable to load session store", err)
+	}
+	return store
+}
+
+// jobStorePath is where jobs are saved at shutdown, JOB_STORE_FILE (default
+// jobs.json in the data directory), or "" when sessions are kept in memory only
+func jobStorePath(dataDir string) string {
+	if os.Getenv("SESSION_STORE_DIR") == "memory" {
+		return ""
+	}
+	if path := os.Getenv("JOB_STORE_FILE"); path != "" {
+		return path
+	}
+	return filepath.Join(dataDir, "jobs.json")
+}
+
+// macroStorePath is where macros are kept, MACRO_STORE_FILE (default macros.json
+// in the data directory), or "" when sessions are kept in memory only
+func macroStorePath(dataDir string) string {
+	if os.Getenv("SESSION_STORE_DIR") == "memory" {
+		return ""
+	}
+	if path := os.Getenv("MACRO_STORE_FILE"); path != "" {
+		return path
+	}
+	return filepath.Join(dataDir, "macros.json")
+}
+
+func newMacroStore(path string) *storage.MacroStore {
+	if path == "" {
+		return storage.NewMacroStore()
+	}
+
+	store, err := storage.NewPersistentMacroStore(path)
+	if err != nil {
+		utils.ExitOnError("Unable to load macro store", err)
+	}
+	return store
+}
+
+// externalJobStorePath is where jobs registered for asynchronous engines are
+// kept, EXTERNAL_JOB_STORE_FILE (default external_jobs.json in the data
+// directory), or "" when sessions are kept in memory only
+func externalJobStorePath(dataDir string) string {
+	if os.Getenv("SESSION_STORE_DIR") == "memory" {
+		return ""
+	}
+	if path := os.Getenv("EXTERNAL_JOB_STORE_FILE"); path != "" {
+		return path
+	}
+	return filepath.Join(dataDir, "external_jobs.json")
+}
+
+func newExternalJobStore(path string) *storage.ExternalJobStore {
+	if path == "" {
+		return storage.NewExternalJobStore()
+	}
+
+	store, err := storage.NewPersistentExternalJobStore(path)
+	if err != nil {
+		utils.ExitOnError("Unable to load external job store", err)
+	}
+	return store
+}
+
+// prefetchStorePath is where prefetch runs are kept, PREFETCH_STORE_FILE
+// (default prefetch.json in the data directory), or "" when sessions are kept
+// in memory only
+func prefetchStorePath(dataDir string) string {
+	if os.Getenv("SESSION_STORE_DIR") == "memory" {
+		return ""
+	}
+	if path := os.Getenv("PREFETCH_STORE_FILE"); path != "" {
+		return path
+	}
+	return filepath.Join(dataDir, "prefetch.json")
+}
+
+func newPrefetchStore(path string) *storage.PrefetchStore {
+	if path == "" {
+		return storage.NewPrefetchStore()
+	}
+
+	store, err := storage.NewPersistentPrefetchStore(path)
+	if err != nil {
+		utils.ExitOnError("Unable to load prefetch store", err)
+	}
+	// Runs left unfinished by a restart will never finish
+	finished := time.Now()
+	for _, id := range store.Unfinished() {
+		store.Update(id, func(run *models.PrefetchRun) {
+			run.Errors = append(run.Errors, errJobInterrupted.Error())
+			run.FinishedAt = &finished
+		})
+	}
+	return store
+}
+
+func newJobStore(path string) *storage.JobStore {
+	if path == "" {
+		return storage.NewJobStore()
+	}
+
+	store, err := storage.LoadJobStore(path)
+	if err != nil {
+		utils.ExitOnError("Unable to load job store", err)
+	}
+	// Jobs left unfinished by a crash will never run
+	finished := time.Now()
+	for _, id := range store.Unfinished() {
+		store.Update(id, func(job *models.Job) {
+			job.Status = models.JobFailed
+			job.Error = errJobInterrupted.Error()
+			job.FinishedAt = &finished
+		})
+	}
+	return store
+}
+
 // Response helpers
 func (h *Handler) writeJSON(w http.ResponseWriter, data interface{}) {
+	h.writeJSONStatus(w, http.StatusOK, data)
+}
+
+func (h *Handler) writeJSONStatus(w http.ResponseWriter, code int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		slog.Error("Unable to encode JSON response", "err", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -65,49 +358,159 @@ func (h *Handler) getSessionOrError(w http.ResponseWriter, sessionID string) (*m
 	return session, true
 }
 
+// requestUser identifies the caller from the X-Remote-User header set by the authenticating proxy
+func requestUser(r *http.Request) string {
+	return r.Header.Get("X-Remote-User")
+}
+
+// currentHOCR returns the corrected hOCR for an image, falling back to the OCR output
+func currentHOCR(image *models.ImageItem) string {
+	if image.CorrectedHOCR != "" {
+		return image.CorrectedHOCR
+	}
+	return image.OriginalHOCR
+}
+
+// imageFilePath is where an image's upload is stored on disk
+func (h *Handler) imageFilePath(image *models.ImageItem) string {
+	return filepath.Join(h.dirs.Uploads, image.ImagePath)
+}
+
+// imageHash recovers the upload digest that names the image file and its cached
+// hOCR: the SHA-256 of the source image, or its MD5 for uploads stored before
+// uploads were content addressed
+func imageHash(image *models.ImageItem) string {
+	return strings.TrimSuffix(image.ImagePath, filepath.Ext(image.ImagePath))
+}
+
+func findImage(session *models.CorrectionSession, imageID string) *models.ImageItem {
+	for i := range session.Images {
+		if session.Images[i].ID == imageID {
+			return &session.Images[i]
+		}
+	}
+	return nil
+}
+
 // File operation helpers
 func (h *Handler) ensureUploadsDir() error {
-	uploadsDir := "uploads"
-	return os.MkdirAll(uploadsDir, 0755)
+	return os.MkdirAll(h.dirs.Uploads, 0755)
 }
 
-func (h *Handler) wasCacheUsed(md5Hash string) bool {
-	hocrFilename := md5Hash + ".xml"
-	hocrFilePath := filepath.Join("uploads", hocrFilename)
+func (h *Handler) wasCacheUsed(digest string, config SessionConfig) bool {
+	config = h.resolveEngine(config)
+	hocrFilename := hocrCacheFilename(digest, config)
+	hocrFilePath := filepath.Join(h.dirs.Uploads, hocrFilename)
 	_, err := os.Stat(hocrFilePath)
 	return err == nil
 }
 
 func (h *Handler) createImageSession(sessionID string, result *ImageProcessResult, config SessionConfig) *models.CorrectionSession {
+	return h.createMultiImageSession(sessionID, []*ImageProcessResult{result}, config)
+}
+
+// createMultiImageSession creates a session with one image per result, in order
+func (h *Handler) createMultiImageSession(sessionID string, results []*ImageProcessResult, config SessionConfig) *models.CorrectionSession {
 	session := &models.CorrectionSession{
 		ID:        sessionID,
 		Images:    []models.ImageItem{},
 		Current:   0,
 		CreatedAt: time.Now(),
 		Config: models.EvalConfig{
-			Model:       config.Model,
-			Prompt:      config.Prompt,
-			Temperature: config.Temperature,
-			Timestamp:   time.Now().Format("2006-01-02_15-04-05"),
+			Model:        config.Model,
+			Prompt:       config.Prompt,
+			Temperature:  config.Temperature,
+			Timestamp:    time.Now().Format("2006-01-02_15-04-05"),
+			Engine:       h.resolveEngine(config).Engine,
+			Binarization: config.Binarization,
+			Language:     config.Language,
+			DocumentType: config.DocumentType,
+			Characters:   config.Characters,
+			LLM:          config.LLM,
 		},
 	}
 
-	imageItem := models.ImageItem{
-		ID:            "img_1",
-		ImagePath:     result.ImageFilename,
-		ImageURL:      "/static/uploads/" + result.ImageFilename,
-		OriginalHOCR:  result.HOCRXML,
-		CorrectedHOCR: "",
-		Completed:     false,
-		ImageWidth:    result.Width,
-		ImageHeight:   result.Height,
+	for i, result := range results {
+		session.Images = append(session.Images, models.ImageItem{
+			ID:            fmt.Sprintf("img_%d", i+1),
+			ImagePath:     result.ImageFilename,
+			ImageURL:      "/static/uploads/" + result.ImageFilename,
+			OriginalHOCR:  result.HOCRXML,
+			CorrectedHOCR: "",
+			Completed:     false,
+			ImageWidth:    result.Width,
+			ImageHeight:   result.Height,
+			LineOrder:     checkLineOrder(result.HOCRXML, session.Config),
+		})
 	}
 
-	session.Images = []models.ImageItem{imageItem}
 	return session
 }
 
-func (h *Handler) getOCRForImage(imagePath string) (string, error) {
+func (h *Handler) getOCRForImage(imagePath string, config SessionConfig, opts hocr.Options, archive *artifactArchive) (string, error) {
 	// Use the simplified OCR service that bundles word detection + ChatGPT transcription
-	return h.hocrService.ProcessImageToHOCR(imagePath)
+	opts.Engine = config.Engine
+	opts.Binarization = config.Binarization
+	opts.Language = config.Language
+	opts.DocumentType = config.DocumentType
+	charset, err := hocr.ParseCharset(config.Characters)
+	if err != nil {
+		return "", err
+	}
+	opts.Characters = charset
+	if opts.Model == "" {
+		opts.Model = config.LLM.Model
+	}
+	if opts.Prompt == "" {
+		opts.Prompt = config.LLM.Prompt
+	}
+	if opts.Temperature == 0 {
+		opts.Temperature = config.LLM.Temperature
+	}
+	opts.Archive = archive.add
+	opts.OnRetry = func(retry int, err error) {
+		archive.retried(retry, err)
+		config.trace.retried()
+	}
+	opts.Logger = config.trace.log()
+	return h.hocrService.ProcessImageToHOCR(imagePath, opts)
+}
+
+// binarizationKey is the binarization part of an hOCR cache filename, empty for
+// the default settings whether or not they were given explicitly
+func binarizationKey(binarization models.BinarizationConfig) string {
+	normalized, err := hocr.NormalizeBinarization(binarization)
+	if err != nil {
+		normalized = binarization
+	}
+	if defaults, _ := hocr.NormalizeBinarization(models.BinarizationConfig{}); normalized == defaults {
+		return ""
+	}
+	settings := fmt.Sprintf("%s_%g_%d_%g", normalized.Method, normalized.Threshold, normalized.WindowSize, normalized.K)
+	return "_" + utils.CalculateDataMD5([]byte(settings))[:8]
+}
+
+// hocrCacheFilename keys cached hOCR by image hash, plus the pipeline settings when
+// they differ from the defaults so alternate preprocessing doesn't reuse stale output.
+// A language, a document type, limits on the characters, engines other than
+// the LLM, and the LLM's model, prompt or temperature when overridden, get
+// their own suffixes.
+func hocrCacheFilename(digest string, config SessionConfig) string {
+	name := digest + binarizationKey(config.Binarization)
+	if config.Language != "" {
+		name += "_" + strings.ReplaceAll(config.Language, "+", "-")
+	}
+	if config.DocumentType != hocr.DocumentModern {
+		name += "_" + config.DocumentType
+	}
+	if config.Characters != (models.CharacterConfig{}) {
+		name += "_" + utils.CalculateDataMD5([]byte(config.Characters.Allowed + "\x00" + config.Characters.Forbidden))[:8]
+	}
+	if config.Engine != "" && config.Engine != hocr.EngineLLM {
+		name += "_" + config.Engine
+	} else if config.LLM != (models.LLMConfig{}) {
+		settings := fmt.Sprintf("%s\x00%s\x00%g", config.LLM.Model, config.LLM.Prompt, config.LLM.Temperature)
+		name += "_llm_" + utils.CalculateDataMD5([]byte(settings))[:8]
+	}
+	return name + ".xml"
 }
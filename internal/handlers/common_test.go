@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+func TestHOCRCacheFilenameLLMOverrides(t *testing.T) {
+	base := hocrCacheFilename("abc", SessionConfig{Engine: hocr.EngineLLM})
+	if base != "abc.xml" {
+		t.Errorf("default cache file is %s", base)
+	}
+
+	seen := map[string]bool{base: true}
+	for _, llm := range []models.LLMConfig{{Model: "gpt-4.1"}, {Prompt: "Transcribe."}, {Temperature: 0.7}} {
+		name := hocrCacheFilename("abc", SessionConfig{Engine: hocr.EngineLLM, LLM: llm})
+		if seen[name] {
+			t.Errorf("%+v shares the cache file %s", llm, name)
+		}
+		seen[name] = true
+	}
+
+	// Engines that don't ask the LLM share a file whatever it would be asked
+	tesseract := hocrCacheFilename("abc", SessionConfig{Engine: hocr.EngineTesseract})
+	if got := hocrCacheFilename("abc", SessionConfig{Engine: hocr.EngineTesseract, LLM: models.LLMConfig{Model: "gpt-4.1"}}); got != tesseract {
+		t.Errorf("tesseract cache file is %s, want %s", got, tesseract)
+	}
+}
+
+func TestHOCRCacheFilenameNormalizesBinarization(t *testing.T) {
+	tests := []struct {
+		a, b models.BinarizationConfig
+		same bool
+	}{
+		{models.BinarizationConfig{}, models.BinarizationConfig{Method: models.BinarizeFixed, Threshold: 75}, true},
+		{models.BinarizationConfig{}, models.BinarizationConfig{Method: models.BinarizeFixed, WindowSize: 31}, true},
+		{models.BinarizationConfig{Method: models.BinarizeSauvola}, models.BinarizationConfig{Method: models.BinarizeSauvola, WindowSize: 24, K: 0.34}, true},
+		{models.BinarizationConfig{Method: models.BinarizeOtsu}, models.BinarizationConfig{Method: models.BinarizeOtsu, Threshold: 40}, true},
+		{models.BinarizationConfig{}, models.BinarizationConfig{Threshold: 60}, false},
+		{models.BinarizationConfig{}, models.BinarizationConfig{Method: models.BinarizeOtsu}, false},
+	}
+
+	for _, tt := range tests {
+		a := hocrCacheFilename("abc", SessionConfig{Binarization: tt.a})
+		b := hocrCacheFilename("abc", SessionConfig{Binarization: tt.b})
+		if (a == b) != tt.same {
+			t.Errorf("%+v and %+v: got %s and %s, want same=%v", tt.a, tt.b, a, b, tt.same)
+		}
+	}
+}
+
+func TestAdoptLegacyHOCR(t *testing.T) {
+	uploads := t.TempDir()
+	h := &Handler{dirs: Dirs{Uploads: uploads}, blobs: storage.NewBlobStore(uploads)}
+	config := SessionConfig{Engine: hocr.EngineTesseract}
+	source := []byte("page scan")
+	result := &ImageProcessResult{Digest: storage.ContentDigest(source), LegacyDigest: utils.CalculateDataMD5(source)}
+
+	legacy := filepath.Join(uploads, hocrCacheFilename(result.LegacyDigest, config))
+	if err := os.WriteFile(legacy, []byte("<html>cached</html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h.adoptLegacyHOCR(result, config)
+	data, err := os.ReadFile(filepath.Join(uploads, hocrCacheFilename(result.Digest, config)))
+	if err != nil || string(data) != "<html>cached</html>" {
+		t.Errorf("hOCR under the SHA-256 name is %q, %v", data, err)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Errorf("the MD5 copy should stay for sessions naming it: %v", err)
+	}
+}
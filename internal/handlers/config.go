@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/sandbox"
+)
+
+// HandleConfig reports the deployment profile and which OCR engines can run, so
+// the editor can hide modes that need services this site doesn't have
+func (h *Handler) HandleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	profile := h.hocrService.Profile()
+	config := ConfigResponse{
+		Profile:       profile,
+		LLMEnabled:    profile == hocr.ProfileFull,
+		DefaultEngine: h.hocrService.DefaultEngine(),
+		Engines:       h.hocrService.Engines(),
+	}
+	if profile == hocr.ProfileFull {
+		config.Model = h.hocrService.Model()
+	}
+	if sandbox.Enabled() {
+		config.Sandbox = &SandboxConfig{
+			MaxPages:  sandbox.MaxPages(),
+			PurgeHour: sandbox.PurgeHour(),
+			Watermark: sandbox.Watermark,
+		}
+	}
+
+	h.writeJSON(w, config)
+}
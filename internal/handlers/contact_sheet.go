@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"fmt"
+	"image/color"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/accessibility"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/sandbox"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/textrender"
+)
+
+// Page states shown on contact sheet badges
+const (
+	pageStatusError     = "error"
+	pageStatusOCR       = "ocr done"
+	pageStatusCorrected = "corrected"
+	pageStatusPublished = "published"
+)
+
+var pageStatusColors = map[string]color.RGBA{
+	pageStatusError:     {R: 0xc0, G: 0x39, B: 0x2b, A: 0xff},
+	pageStatusOCR:       {R: 0x7f, G: 0x8c, B: 0x8d, A: 0xff},
+	pageStatusCorrected: {R: 0x29, G: 0x80, B: 0xb9, A: 0xff},
+	pageStatusPublished: {R: 0x27, G: 0xae, B: 0x60, A: 0xff},
+}
+
+const (
+	contactThumbnailWidth = 240
+	contactBadgeHeight    = 32
+	contactBadgeFontSize  = 16
+)
+
+// pageStatus reports the furthest state an image has reached
+func pageStatus(image *models.ImageItem) string {
+	switch {
+	case image.OriginalHOCR == "" && image.CorrectedHOCR == "":
+		return pageStatusError
+	case image.PublishedAt != nil:
+		return pageStatusPublished
+	case image.Completed:
+		return pageStatusCorrected
+	default:
+		return pageStatusOCR
+	}
+}
+
+// handleContactSheet renders thumbnails of every page in the session, each with a
+// status badge, as a PNG or (with ?format=pdf) a PDF
+func (h *Handler) handleContactSheet(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "png"
+	}
+	contentType := map[string]string{"png": "image/png", "pdf": "application/pdf"}[format]
+	if contentType == "" {
+		h.writeError(w, "format must be png or pdf", http.StatusBadRequest)
+		return
+	}
+	if format == "pdf" && !h.requirePermission(w, r, session.Collection, auth.ExportPDF) {
+		return
+	}
+
+	if len(session.Images) == 0 {
+		h.writeError(w, "Session has no images", http.StatusBadRequest)
+		return
+	}
+
+	tempDir, err := os.MkdirTemp(h.dirs.Temp, "contact_sheet_")
+	if err != nil {
+		h.writeError(w, "Failed to create workspace", http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	var thumbnails []string
+	for i := range session.Images {
+		image := &session.Images[i]
+		thumbnail := filepath.Join(tempDir, fmt.Sprintf("thumb_%04d.png", i))
+		if err := renderContactThumbnail(h.imageFilePath(image), thumbnail, image.ID, pageStatus(image)); err != nil {
+			slog.Warn("Failed to render contact sheet thumbnail", "session_id", session.ID, "image_id", image.ID, "err", err)
+			continue
+		}
+		thumbnails = append(thumbnails, thumbnail)
+	}
+
+	if len(thumbnails) == 0 {
+		h.writeError(w, "Failed to render any page thumbnails", http.StatusInternalServerError)
+		return
+	}
+
+	outputPath := filepath.Join(tempDir, "contact_sheet."+format)
+	args := append([]string{"montage"}, thumbnails...)
+	args = append(args, "-tile", "6x", "-geometry", "+8+8", "-background", "white")
+	if sandbox.Enabled() {
+		args = append(args, "-title", sandbox.Watermark)
+	}
+	args = append(args, outputPath)
+	if output, err := exec.Command("magick", args...).CombinedOutput(); err != nil {
+		h.writeError(w, fmt.Sprintf("Failed to build contact sheet: %s", strings.TrimSpace(string(output))), http.StatusInternalServerError)
+		return
+	}
+
+	if format == "pdf" {
+		if data, err := os.ReadFile(outputPath); err == nil {
+			setConformanceHeaders(w, accessibility.CheckPDF(data), "")
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s_contact_sheet.%s"`, session.ID, format))
+	http.ServeFile(w, r, outputPath)
+}
+
+// renderContactThumbnail scales a page down and adds a colored status badge under it.
+// Missing images still get a placeholder so the grid keeps page order.
+func renderContactThumbnail(imagePath, outputPath, label, status string) error {
+	source := imagePath + "[0]"
+	if _, err := os.Stat(imagePath); err != nil {
+		source = "xc:#eeeeee"
+		status = pageStatusError
+	}
+
+	// The badge is drawn in-process so labels don't depend on installed fonts
+	text := fmt.Sprintf("%s · %s", label, status)
+	textWidth, err := textrender.Width(text, false, contactBadgeFontSize)
+	if err != nil {
+		return err
+	}
+	badgePath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "_badge.png"
+	if err := textrender.WritePNG(badgePath, text, textrender.Options{
+		Width:      contactThumbnailWidth,
+		Height:     contactBadgeHeight,
+		Size:       contactBadgeFontSize,
+		X:          max(0, (contactThumbnailWidth-textWidth)/2),
+		Baseline:   contactBadgeHeight - 10,
+		Foreground: color.White,
+		Background: pageStatusColors[status],
+	}); err != nil {
+		return err
+	}
+	defer os.Remove(badgePath)
+
+	cmd := exec.Command("magick",
+		"-size", "240x320", source,
+		"-thumbnail", "240x320",
+		"-gravity", "center",
+		"-background", "white",
+		"-extent", "240x320",
+		badgePath,
+		"-append",
+		outputPath)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/logging"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/notify"
+)
+
+// StageTiming records how long one step of a job took
+type StageTiming struct {
+	Name      string        `json:"name"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// DiagnosticBundle is everything needed to debug a failed job without access to the server
+type DiagnosticBundle struct {
+	Job          models.Job        `json:"job"`
+	Inputs       map[string]any    `json:"inputs"`
+	Stages       []StageTiming     `json:"stages"`
+	EngineOutput map[string]string `json:"engine_output,omitempty"`
+	Logs         []logging.Entry   `json:"logs"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// jobTrace collects what a diagnostic bundle needs while a job runs. A nil trace
+// collects nothing, so code shared with synchronous requests can call it freely.
+type jobTrace struct {
+	mu      sync.Mutex
+	jobID   string
+	started time.Time
+	logger  *slog.Logger
+	inputs  map[string]any
+	stages  []StageTiming
+	outputs map[string]string
+	retries int
+	// onRetry, when set, is told the running count of retries
+	onRetry func(retries int)
+}
+
+func newJobTrace(jobID string) *jobTrace {
+	return &jobTrace{
+		jobID:   jobID,
+		started: time.Now(),
+		logger:  slog.With("job_id", jobID),
+		inputs:  make(map[string]any),
+		outputs: make(map[string]string),
+	}
+}
+
+// log is the logger for work done on behalf of the job. Its records are tagged
+// with the job's id, which is how they find their way into its bundle.
+func (t *jobTrace) log() *slog.Logger {
+	if t == nil {
+		return slog.Default()
+	}
+	return t.logger
+}
+
+func (t *jobTrace) input(key string, value any) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inputs[key] = value
+}
+
+// stage starts timing a step; call the returned function with the step's error when it ends
+func (t *jobTrace) stage(name string) func(err error) {
+	started := time.Now()
+	return func(err error) {
+		if t == nil {
+			return
+		}
+		timing := StageTiming{Name: name, StartedAt: started, Duration: time.Since(started)}
+		if err != nil {
+			timing.Error = err.Error()
+		}
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.stages = append(t.stages, timing)
+	}
+}
+
+// retried counts an API call retried on the job's behalf
+func (t *jobTrace) retried() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.retries++
+	retries := t.retries
+	t.mu.Unlock()
+
+	if t.onRetry != nil {
+		t.onRetry(retries)
+	}
+}
+
+// engineOutput keeps the raw outputs of an OCR run that never reached the archive
+func (t *jobTrace) engineOutput(prefix string, archive *artifactArchive) {
+	if t == nil {
+		return
+	}
+	archive.mu.Lock()
+	defer archive.mu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, name := range archive.names {
+		t.outputs[prefix+"/"+name] = string(archive.files[name])
+	}
+}
+
+// diagnosticsPath keeps bundles next to the archived engine output
+func (h *Handler) diagnosticsPath(jobID string) string {
+	return filepath.Join(h.dirs.Archive, "diagnostics", jobID+".json")
+}
+
+// diagnosticsURL links to a job's bundle, absolute when PUBLIC_BASE_URL is set so
+// notifications can be followed from outside the app
+func diagnosticsURL(jobID string) string {
+	return strings.TrimSuffix(os.Getenv("PUBLIC_BASE_URL"), "/") + APIPrefix + "/jobs/" + jobID + "/diagnostics"
+}
+
+// reportJobFailure stores the diagnostic bundle for a failed job, links it from
+// the job and notifies operators
+func (h *Handler) reportJobFailure(job models.Job, trace *jobTrace) {
+	bundle := DiagnosticBundle{Job: job, CreatedAt: time.Now()}
+	if trace != nil {
+		trace.mu.Lock()
+		bundle.Inputs = trace.inputs
+		bundle.Stages = trace.stages
+		bundle.EngineOutput = trace.outputs
+		trace.mu.Unlock()
+	}
+
+	trace.log().Error("Job failed", "kind", job.Kind, "err", job.Error)
+
+	// Only entries logged through the job's trace are kept, since other jobs and
+	// requests log at the same time
+	from := job.CreatedAt
+	to := time.Now()
+	bundle.Logs = logging.Recent.Between(from, to, func(entry logging.Entry) bool {
+		return entry.Attrs["job_id"] == job.ID
+	})
+
+	url := ""
+	if err := writeDiagnosticBundle(h.diagnosticsPath(job.ID), bundle); err != nil {
+		slog.Warn("Failed to store diagnostic bundle", "job_id", job.ID, "err", err)
+	} else {
+		url = diagnosticsURL(job.ID)
+		h.jobStore.Update(job.ID, func(j *models.Job) {
+			j.Diagnostics = url
+		})
+	}
+
+	h.notifier.Notify(notify.Failure{
+		JobID:          job.ID,
+		Kind:           job.Kind,
+		SessionID:      job.SessionID,
+		User:           job.User,
+		Error:          job.Error,
+		DiagnosticsURL: url,
+		FailedAt:       to,
+	})
+}
+
+func writeDiagnosticBundle(path string, bundle DiagnosticBundle) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create diagnostics directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// handleJobDiagnostics serves the bundle stored for a failed job
+func (h *Handler) handleJobDiagnostics(w http.ResponseWriter, r *http.Request, jobID string) {
+	if jobID == "" || jobID != filepath.Base(jobID) || strings.HasPrefix(jobID, ".") {
+		h.writeError(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	path := h.diagnosticsPath(jobID)
+	if _, err := os.Stat(path); err != nil {
+		h.writeError(w, "No diagnostic bundle for this job", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-diagnostics.json"`, jobID))
+	http.ServeFile(w, r, path)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/logging"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/notify"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+func TestJobFailureKeepsOnlyTheJobsLogs(t *testing.T) {
+	recent, logger := logging.Recent, slog.Default()
+	t.Cleanup(func() {
+		logging.Recent = recent
+		slog.SetDefault(logger)
+	})
+	logging.Recent = logging.NewBuffer(16)
+	slog.SetDefault(slog.New(logging.NewBufferHandler(slog.NewTextHandler(io.Discard, nil), logging.Recent)))
+
+	h := &Handler{
+		dirs:     Dirs{Archive: t.TempDir()},
+		jobStore: storage.NewJobStore(),
+		notifier: &notify.Service{},
+	}
+	trace := newJobTrace(newJobID())
+	other := newJobTrace(trace.jobID + "_other")
+
+	trace.log().Info("mine")
+	other.log().Info("another job's")
+	slog.Info("untagged")
+	job := h.recordFailedJob("test", "", "", trace, io.ErrUnexpectedEOF)
+
+	data, err := os.ReadFile(h.diagnosticsPath(job.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bundle DiagnosticBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, entry := range bundle.Logs {
+		messages = append(messages, entry.Message)
+	}
+	if len(messages) != 2 || messages[0] != "mine" || messages[1] != "Job failed" {
+		t.Errorf("bundle logs are %q, want the job's own entries", messages)
+	}
+}
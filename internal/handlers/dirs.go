@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Dirs are where the server keeps its files. With them on writable volumes the
+// root filesystem can be mounted read-only.
+type Dirs struct {
+	// Uploads holds uploaded images and their cached hOCR
+	Uploads string
+	// Static overrides the editor's files embedded in the binary, for working
+	// on the front-end; "" serves the embedded ones
+	Static string
+	// Cache holds what can be rebuilt: converted images, tile pyramids,
+	// prefetch records and LLM transcriptions
+	Cache string
+	// Archive holds raw engine output and the diagnostics of failed jobs
+	Archive string
+	// Data holds sessions, jobs and the schema version
+	Data string
+	// Temp holds scratch files while a request or job runs
+	Temp string
+}
+
+// DirsFromEnv reads UPLOADS_DIR (default uploads), STATIC_DIR (default none),
+// CACHE_DIR (default cache), ARCHIVE_DIR (default archive), DATA_DIR (default
+// data) and TEMP_DIR (default the system temp directory)
+func DirsFromEnv() Dirs {
+	return Dirs{
+		Uploads: envOr("UPLOADS_DIR", "uploads"),
+		Static:  os.Getenv("STATIC_DIR"),
+		Cache:   envOr("CACHE_DIR", "cache"),
+		Archive: envOr("ARCHIVE_DIR", "archive"),
+		Data:    envOr("DATA_DIR", "data"),
+		Temp:    envOr("TEMP_DIR", os.TempDir()),
+	}
+}
+
+// tilesDir holds a tile pyramid per upload
+func (h *Handler) tilesDir() string {
+	return filepath.Join(h.dirs.Cache, "tiles")
+}
+
+// houdiniDir holds converted JP2 and TIFF sources
+func (h *Handler) houdiniDir() string {
+	return filepath.Join(h.dirs.Cache, "houdini")
+}
+
+// originalsDir keeps uploads as they arrived when normalizing rewrote them
+func (h *Handler) originalsDir() string {
+	return filepath.Join(h.dirs.Uploads, "originals")
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -9,6 +10,11 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/drupal"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
 )
 
 // DrupalFileObject represents a single file object from Drupal
@@ -18,40 +24,159 @@ type DrupalFileObject struct {
 	TID      string `json:"tid"`
 	NID      string `json:"nid"`
 	ViewNode string `json:"view_node"`
+	// MID is the media id, when the view lists it; publishing needs it to save
+	// a revision of existing media
+	MID string `json:"mid,omitempty"`
+	// Language is what the node's text is in, when the view lists it: its
+	// langcode or a field such as field_language, in any form
+	// hocr.ParseLanguage reads
+	Language string `json:"language,omitempty"`
 }
 
 // DrupalHOCRData represents the JSON response from Drupal HOCR endpoint (array of file objects)
 type DrupalHOCRData []DrupalFileObject
 
-// createSessionFromDrupalNode creates a session from a Drupal node ID
-func (h *Handler) createSessionFromDrupalNode(nid string) (string, error) {
+// drupalPage is a Drupal node's service file, saved and transcribed
+type drupalPage struct {
+	nid          string
+	imageURL     string
+	uploadURL    string
+	mediaURL     string
+	existingHOCR bool
+	// createMedia is set when the node has no hOCR media yet, so publishing
+	// creates it
+	createMedia bool
+	// language is what the node was read in
+	language string
+	result   *ImageProcessResult
+}
+
+// processDrupalNode downloads a node's service file and reuses the node's hOCR
+// when it already has some, or runs OCR otherwise, in the node's language
+// unless config names one. A node without hOCR media is an error unless
+// DRUPAL_MISSING_HOCR=create, which defers making the media to the first
+// publish.
+func (h *Handler) processDrupalNode(nid string, config SessionConfig) (*drupalPage, error) {
 	drupalData, err := h.fetchDrupalData(nid)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	serviceFile, hocrFile, err := h.extractDrupalFiles(drupalData)
+	mapping := loadDrupalMapping()
+	serviceFile, hocrFile, err := h.extractDrupalFiles(drupalData, mapping)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	tid := mapping.hocrTermID
+	if hocrFile != nil {
+		tid = hocrFile.TID
+	}
+	imageURL, hocrUploadURL := h.buildDrupalURLs(serviceFile, tid, nid, mapping)
+	if config.Language == "" {
+		config.Language = drupalLanguage(nid, drupalData)
+	}
+	page := &drupalPage{nid: nid, imageURL: imageURL, uploadURL: hocrUploadURL, createMedia: hocrFile == nil, language: config.Language}
+	if hocrFile != nil && hocrFile.MID != "" {
+		page.mediaURL = strings.ReplaceAll(mapping.expand(mapping.mediaURL, nid, serviceFile, tid), "{mid}", hocrFile.MID)
 	}
 
-	imageURL, hocrUploadURL := h.buildDrupalURLs(serviceFile, hocrFile, nid)
+	if hocrFile == nil || !strings.Contains(hocrFile.URI, mapping.reusableHOCRPath) {
+		page.result, err = h.processImageFromURL(imageURL, config)
+		return page, err
+	}
+
+	file, contentType, err := h.downloadImageFromURL(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer file.remove()
+	if page.result, err = h.saveImageFromFile(file, contentType, imageURL); err != nil {
+		return nil, err
+	}
+	hocrURL := hocrFile.ViewNode + hocrFile.URI
+	hocrData, err := h.downloadHOCR(hocrURL)
+	if err != nil {
+		return nil, err
+	}
+	page.result.HOCRXML = string(hocrData)
+	page.existingHOCR = true
+	slog.Info("Using existing hOCR from Drupal", "nid", nid, "hocr_url", hocrURL)
+	return page, nil
+}
+
+// drupalLanguage is the language a node's files are listed with. One Drupal
+// doesn't recognize is logged and left out, rather than failing the node.
+func drupalLanguage(nid string, drupalData DrupalHOCRData) string {
+	for _, fileObj := range drupalData {
+		if fileObj.Language == "" {
+			continue
+		}
+		language, err := hocr.ParseLanguage(fileObj.Language)
+		if err != nil {
+			slog.Warn("Ignoring the language of a Drupal node", "nid", nid, "language", fileObj.Language, "err", err)
+			continue
+		}
+		return language
+	}
+	return ""
+}
 
-	// Create session based on whether we have existing hOCR
-	var sessionID string
-	if strings.Contains(hocrFile.URI, "gcloud") {
-		sessionID, err = h.createSessionFromDrupalWithExistingHOCR(imageURL, hocrFile.ViewNode+hocrFile.URI, nid)
+// drupalSessionConfig records where a Drupal session's transcription came from
+func drupalSessionConfig(existingHOCR bool, config SessionConfig) SessionConfig {
+	config.Temperature = 0.0
+	if existingHOCR {
+		config.Model = "drupal_existing_hocr"
+		config.Prompt = "Using existing hOCR from Drupal"
 	} else {
-		sessionID, err = h.createSessionFromDrupalWithNewHOCR(imageURL, nid)
+		config.Model = "custom_with_chatgpt"
+		config.Prompt = "Custom word detection + ChatGPT OCR with hOCR conversion for Drupal"
+	}
+	return config
+}
+
+// setDrupalPages links each image of a session to the node it came from
+func setDrupalPages(session *models.CorrectionSession, pages []*drupalPage) {
+	for i, page := range pages {
+		if i < len(session.Images) {
+			session.Images[i].DrupalUploadURL = page.uploadURL
+			session.Images[i].DrupalNid = page.nid
+			session.Images[i].DrupalMediaPending = page.createMedia
+			session.Images[i].DrupalMediaURL = page.mediaURL
+		}
+	}
+}
+
+// drupalErrorStatus picks the status for a failed Drupal request: 504 when Drupal
+// timed out, 502 when it failed or couldn't be reached after retrying, and
+// fallback for anything else
+func drupalErrorStatus(err error, fallback int) int {
+	var requestErr *drupal.RequestError
+	if !errors.As(err, &requestErr) {
+		return fallback
+	}
+	if requestErr.Timeout {
+		return http.StatusGatewayTimeout
 	}
+	return http.StatusBadGateway
+}
 
+// createSessionFromDrupalNode creates a session from a Drupal node ID
+func (h *Handler) createSessionFromDrupalNode(nid string) (string, error) {
+	page, err := h.processDrupalNode(nid, SessionConfig{})
 	if err != nil {
 		return "", fmt.Errorf("failed to create session from Drupal: %w", err)
 	}
 
-	// Add Drupal metadata to session
-	h.addDrupalMetadataToSession(sessionID, nid, hocrUploadURL)
+	filename := h.extractFilenameFromURL(page.imageURL, page.result.Digest)
+	sessionID := fmt.Sprintf("drupal_%s_%s_%d", nid, filename, time.Now().Unix())
+
+	session := h.createImageSession(sessionID, page.result, drupalSessionConfig(page.existingHOCR, SessionConfig{Language: page.language}))
+	session.Config.Prompt = fmt.Sprintf("Drupal Node %s - %s", nid, session.Config.Prompt)
+	setDrupalPages(session, []*drupalPage{page})
+	h.sessionStore.Set(sessionID, session)
 
+	slog.Info("Session created from Drupal", "session_id", sessionID, "nid", nid, "existing_hocr", page.existingHOCR)
 	return sessionID, nil
 }
 
@@ -64,7 +189,7 @@ func (h *Handler) fetchDrupalData(nid string) (DrupalHOCRData, error) {
 	requestURL := fmt.Sprintf(drupalURL, nid)
 	slog.Info("Fetching Drupal HOCR data", "nid", nid, "url", requestURL)
 
-	resp, err := http.Get(requestURL)
+	resp, err := h.drupal.Get(requestURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Drupal data: %w", err)
 	}
@@ -86,123 +211,186 @@ func (h *Handler) fetchDrupalData(nid string) (DrupalHOCRData, error) {
 	return drupalData, nil
 }
 
-func (h *Handler) extractDrupalFiles(drupalData DrupalHOCRData) (*DrupalFileObject, *DrupalFileObject, error) {
-
+// extractDrupalFiles picks the service file and hOCR media out of a node's
+// files by their media use terms. The hOCR is nil when the node has none and
+// the mapping allows creating it.
+func (h *Handler) extractDrupalFiles(drupalData DrupalHOCRData, mapping drupalMapping) (*DrupalFileObject, *DrupalFileObject, error) {
 	var serviceFile, hocrFile *DrupalFileObject
 	for i, fileObj := range drupalData {
-		switch fileObj.TermName {
-		case "Service File":
+		switch {
+		case strings.EqualFold(fileObj.TermName, mapping.serviceFileTerm):
 			serviceFile = &drupalData[i]
-		case "hOCR":
+		case strings.EqualFold(fileObj.TermName, mapping.hocrTerm):
 			hocrFile = &drupalData[i]
 		}
 	}
 
 	if serviceFile == nil {
-		return nil, nil, fmt.Errorf("no Service File found in Drupal response")
+		return nil, nil, fmt.Errorf("no %q media found in Drupal response", mapping.serviceFileTerm)
 	}
 
 	if hocrFile == nil {
-		return nil, nil, fmt.Errorf("no hOCR file found in Drupal response")
+		if mapping.missingHOCR != drupalCreateMissingHOCR {
+			return nil, nil, fmt.Errorf("no %q media found in Drupal response (set DRUPAL_MISSING_HOCR=create to create it on publish)", mapping.hocrTerm)
+		}
+		if mapping.hocrTermID == "" {
+			return nil, nil, fmt.Errorf("no %q media found in Drupal response, and DRUPAL_HOCR_TERM_ID is needed to create it", mapping.hocrTerm)
+		}
 	}
 
 	return serviceFile, hocrFile, nil
 }
 
-func (h *Handler) buildDrupalURLs(serviceFile, hocrFile *DrupalFileObject, nid string) (string, string) {
-	drupalURL := os.Getenv("DRUPAL_HOCR_URL")
-	baseUrl := strings.Replace(drupalURL, "/node/%s/hocr", "", 1)
-
-	imageURL := baseUrl + serviceFile.ViewNode + serviceFile.URI
-	hocrUploadURL := fmt.Sprintf("%s/node/%s%s/media/file/%s", baseUrl, nid, serviceFile.ViewNode, hocrFile.TID)
+// buildDrupalURLs returns where to download the service file and where to
+// upload corrected hOCR as the file of the node's media with the hOCR term
+func (h *Handler) buildDrupalURLs(serviceFile *DrupalFileObject, tid, nid string, mapping drupalMapping) (string, string) {
+	imageURL := mapping.expand(mapping.imageURL, nid, serviceFile, tid)
+	hocrUploadURL := mapping.expand(mapping.uploadURL, nid, serviceFile, tid)
 
 	slog.Info("Retrieved Drupal data", "nid", nid, "image_url", imageURL, "hocr_upload", hocrUploadURL)
 	return imageURL, hocrUploadURL
 }
 
-func (h *Handler) addDrupalMetadataToSession(sessionID, nid, hocrUploadURL string) {
-	session, exists := h.sessionStore.Get(sessionID)
-	if exists {
-		session.Config.Prompt = fmt.Sprintf("Drupal Node %s - %s", nid, session.Config.Prompt)
-
-		if len(session.Images) > 0 {
-			session.Images[0].DrupalUploadURL = hocrUploadURL
-			session.Images[0].DrupalNid = nid
-		}
+func (h *Handler) downloadHOCR(hocrURL string) ([]byte, error) {
+	resp, err := h.drupal.Get(hocrURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download existing hOCR: %w", err)
+	}
+	defer resp.Body.Close()
 
-		h.sessionStore.Set(sessionID, session)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download hOCR: HTTP %d", resp.StatusCode)
 	}
-}
 
-func (h *Handler) createSessionFromDrupalWithExistingHOCR(imageURL, hocrURL, nid string) (string, error) {
-	result, err := h.processImageFromURL(imageURL)
+	hocrData, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to read hOCR data: %w", err)
 	}
 
-	// Download and override with existing hOCR
-	hocrData, err := h.downloadHOCR(hocrURL)
-	if err != nil {
-		return "", err
+	return hocrData, nil
+}
+
+// handlePublish uploads an image's hOCR to its Drupal media endpoint, or writes
+// it back to the Fedora or OCFL repository the image came from. Publishing is
+// refused while the image is under embargo or restricted to staff.
+func (h *Handler) handlePublish(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
-	result.HOCRXML = string(hocrData)
 
-	slog.Info("Using existing hOCR from Drupal", "nid", nid, "hocr_url", hocrURL)
+	var request PublishRequest
 
-	// Create session with Drupal prefix
-	filename := h.extractFilenameFromURL(imageURL, result.MD5Hash)
-	sessionID := fmt.Sprintf("drupal_%s_%s_%d", nid, filename, time.Now().Unix())
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	config := SessionConfig{
-		Model:       "drupal_existing_hocr",
-		Prompt:      "Using existing hOCR from Drupal",
-		Temperature: 0.0,
+	image := findImage(session, request.ImageID)
+	if image == nil {
+		h.writeError(w, "Image not found", http.StatusNotFound)
+		return
 	}
 
-	session := h.createImageSession(sessionID, result, config)
-	h.sessionStore.Set(sessionID, session)
+	permission := auth.PublishDrupal
+	if image.Repository != nil {
+		permission = auth.PublishRepository
+	} else if image.DrupalUploadURL == "" {
+		h.writeError(w, "This image was not created from a Drupal node or repository", http.StatusBadRequest)
+		return
+	}
+	if !h.requirePermission(w, r, session.Collection, permission) {
+		return
+	}
 
-	slog.Info("Session created from Drupal with existing hOCR", "session_id", sessionID, "nid", nid)
-	return sessionID, nil
-}
+	if err := rightsRefusal(session, image, "published"); err != nil {
+		h.writeError(w, err.Error(), http.StatusForbidden)
+		return
+	}
 
-func (h *Handler) downloadHOCR(hocrURL string) ([]byte, error) {
-	resp, err := http.Get(hocrURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download existing hOCR: %w", err)
+	hocrData := request.HOCR
+	if hocrData == "" {
+		hocrData = currentHOCR(image)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to download hOCR: HTTP %d", resp.StatusCode)
+	if image.Repository != nil {
+		trace := newJobTrace(newJobID())
+		published, err := h.publishToRepository(session, image, hocrData, requestUser(r))
+		if err != nil {
+			trace.input("session_id", session.ID)
+			trace.input("image_id", image.ID)
+			trace.input("repository", image.Repository)
+			job := h.recordFailedJob("publish_repository", requestUser(r), session.ID, trace, err)
+			w.Header().Set("X-Job-ID", job.ID)
+			h.writeError(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		publishedAt := time.Now()
+		image.PublishedAt = &publishedAt
+		h.sessionStore.Set(session.ID, session)
+
+		slog.Info("Published hOCR to repository", "session_id", session.ID, "image_id", image.ID, "repository", published.Kind, "object", published.Object, "paths", published.Paths)
+		h.writeJSON(w, published)
+		return
 	}
 
-	hocrData, err := io.ReadAll(resp.Body)
+	response, err := h.publishToDrupal(session, image, hocrData, requestUser(r), r.Header.Get("Cookie"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read hOCR data: %w", err)
+		w.Header().Set("X-Job-ID", image.DrupalSync.JobID)
+		h.writeError(w, err.Error(), drupalErrorStatus(err, http.StatusBadGateway))
+		return
 	}
+	h.writeJSON(w, response)
+}
 
-	return hocrData, nil
+// publishMessage describes a publish in the revision logs of repositories
+func publishMessage(sessionID, user string) string {
+	message := fmt.Sprintf("Corrected OCR from hOCRedit session %s", sessionID)
+	if user != "" {
+		message += " by " + user
+	}
+	return message
 }
 
-func (h *Handler) createSessionFromDrupalWithNewHOCR(imageURL, nid string) (string, error) {
-	result, err := h.processImageFromURL(imageURL)
+// uploadHOCRToDrupal posts hOCR to the media file endpoint. Hosts with configured
+// credentials use them; otherwise the editor's Drupal session cookie is forwarded
+// so the upload runs as the logged in user. Images whose node had no hOCR media
+// are PUT with a filename instead, which has Islandora create the media. It
+// returns what Drupal answered, and the media's URL when Drupal says where it is.
+func (h *Handler) uploadHOCRToDrupal(image *models.ImageItem, hocrData, cookie string) (drupalUpload, error) {
+	method := "POST"
+	if image.DrupalMediaPending {
+		method = "PUT"
+	}
+	req, err := http.NewRequest(method, image.DrupalUploadURL, strings.NewReader(hocrData))
 	if err != nil {
-		return "", err
+		return drupalUpload{}, fmt.Errorf("failed to create Drupal request: %w", err)
 	}
 
-	filename := h.extractFilenameFromURL(imageURL, result.MD5Hash)
-	sessionID := fmt.Sprintf("drupal_%s_%s_%d", nid, filename, time.Now().Unix())
+	req.Header.Set("Content-Type", "text/vnd.hocr+html")
+	req.Header.Set("Content-Location", loadDrupalMapping().fileLocation(image.DrupalNid, time.Now()))
+	if image.DrupalMediaPending {
+		req.Header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.hocr"`, image.DrupalNid))
+	}
+	if cookie != "" && !h.drupal.HasCredentials(image.DrupalUploadURL) {
+		req.Header.Set("Cookie", cookie)
+	}
 
-	config := SessionConfig{
-		Model:       "custom_with_chatgpt",
-		Prompt:      "Custom word detection + ChatGPT OCR with hOCR conversion for Drupal",
-		Temperature: 0.0,
+	resp, err := h.drupal.Do(req)
+	if err != nil {
+		return drupalUpload{}, fmt.Errorf("failed to upload hOCR to Drupal: %w", err)
 	}
+	defer resp.Body.Close()
 
-	session := h.createImageSession(sessionID, result, config)
-	h.sessionStore.Set(sessionID, session)
+	upload := drupalUpload{response: resp.Status}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		upload.response = strings.TrimSpace(resp.Status + " - " + string(body))
+		return upload, fmt.Errorf("drupal upload failed: HTTP %d - %s", resp.StatusCode, string(body))
+	}
 
-	slog.Info("Session created from Drupal with new hOCR", "session_id", sessionID, "nid", nid)
-	return sessionID, nil
+	if location, err := resp.Location(); err == nil {
+		upload.mediaURL = location.String()
+	}
+	return upload, nil
 }
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// DrupalMember is one child node of a book or collection, as listed by the
+// members view
+type DrupalMember struct {
+	NID    string `json:"nid"`
+	Title  string `json:"title,omitempty"`
+	Weight string `json:"weight,omitempty"`
+}
+
+// drupalMembersURL is the template for listing a node's children,
+// DRUPAL_MEMBERS_URL or DRUPAL_HOCR_URL with /hocr replaced by /members
+func drupalMembersURL() (string, error) {
+	if template := os.Getenv("DRUPAL_MEMBERS_URL"); template != "" {
+		return template, nil
+	}
+	template := os.Getenv("DRUPAL_HOCR_URL")
+	if template == "" {
+		return "", fmt.Errorf("DRUPAL_MEMBERS_URL environment variable not set")
+	}
+	return strings.Replace(template, "/hocr", "/members", 1), nil
+}
+
+// fetchDrupalMembers lists a parent node's children in reading order: by
+// weight where the view provides one, otherwise as listed
+func (h *Handler) fetchDrupalMembers(nid string) ([]DrupalMember, error) {
+	template, err := drupalMembersURL()
+	if err != nil {
+		return nil, err
+	}
+
+	requestURL := fmt.Sprintf(template, nid)
+	slog.Info("Fetching Drupal members", "nid", nid, "url", requestURL)
+	resp, err := h.drupal.Get(requestURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Drupal members: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("drupal returned HTTP %d listing members of node %s", resp.StatusCode, nid)
+	}
+
+	var members []DrupalMember
+	if err := json.NewDecoder(resp.Body).Decode(&members); err != nil {
+		return nil, fmt.Errorf("failed to parse Drupal members JSON: %w", err)
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		a, errA := strconv.Atoi(members[i].Weight)
+		b, errB := strconv.Atoi(members[j].Weight)
+		return errA == nil && errB == nil && a < b
+	})
+	return members, nil
+}
+
+// HandleDrupalBook loads every page of a Drupal book or collection for
+// correction. The parent node's children are listed right away, then each is
+// processed in a background job, as pages of one session (mode "single", the
+// default) or as a session per page.
+func (h *Handler) HandleDrupalBook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireStorage(w) {
+		return
+	}
+
+	var request DrupalBookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.NID == "" {
+		h.writeError(w, "nid is required", http.StatusBadRequest)
+		return
+	}
+	if request.Mode == "" {
+		request.Mode = batchSingle
+	}
+	mode, err := batchMode(request.Mode)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.CallbackURL != "" {
+		if err := validateCallbackURL(request.CallbackURL); err != nil {
+			h.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	config, err := pipelineConfig(request.Engine, request.Language, request.DocumentType, request.Characters, request.Binarization)
+	if err == nil {
+		err = h.hocrService.ValidateEngine(config.Engine)
+	}
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	members, err := h.fetchDrupalMembers(request.NID)
+	if err != nil {
+		h.writeError(w, err.Error(), drupalErrorStatus(err, http.StatusBadGateway))
+		return
+	}
+	if len(members) == 0 {
+		h.writeError(w, fmt.Sprintf("node %s has no child pages", request.NID), http.StatusNotFound)
+		return
+	}
+	if limit := batchMaxItems(); len(members) > limit {
+		h.writeError(w, fmt.Sprintf("node %s has %d pages, more than the %d a batch may hold", request.NID, len(members), limit), http.StatusBadRequest)
+		return
+	}
+	if err := h.ensureUploadsDir(); err != nil {
+		h.writeError(w, "Failed to create uploads directory: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user := requestUser(r)
+	taken := make(map[string]bool)
+	response := BatchUploadResponse{Mode: mode}
+	enqueue := func(entry BatchSession, run jobFunc) {
+		job, err := h.enqueueJob("drupal_book", user, run)
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.JobID = job.ID
+			entry.StatusURL = APIPrefix + "/jobs/" + job.ID
+			response.Queued++
+		}
+		response.Sessions = append(response.Sessions, entry)
+	}
+
+	nids := make([]string, len(members))
+	for i, member := range members {
+		nids[i] = member.NID
+	}
+	switch mode {
+	case batchSingle:
+		sessionID := h.batchSessionID("drupal_"+request.NID, taken)
+		enqueue(BatchSession{SessionID: sessionID, Source: request.NID, Items: len(nids)}, h.drupalBookJob(request.NID, nids, sessionID, config, request.CallbackURL))
+	default:
+		for _, nid := range nids {
+			sessionID := h.batchSessionID("drupal_"+nid, taken)
+			enqueue(BatchSession{SessionID: sessionID, Source: nid}, h.drupalBookJob(request.NID, []string{nid}, sessionID, config, request.CallbackURL))
+		}
+	}
+
+	status := http.StatusAccepted
+	if response.Queued == 0 {
+		status = http.StatusServiceUnavailable
+	}
+	h.writeJSONStatus(w, status, response)
+}
+
+// drupalBookJob processes child nodes of a book into the pages of one session,
+// registering the completion callback when one is given
+func (h *Handler) drupalBookJob(parent string, nids []string, sessionID string, config SessionConfig, callbackURL string) jobFunc {
+	return func(trace *jobTrace) (string, any, error) {
+		trace.input("parent_nid", parent)
+		trace.input("nids", nids)
+		trace.input("config", config)
+		config.trace = trace
+
+		pages := make([]*drupalPage, len(nids))
+		err := h.pages.each(len(nids), func(i int) error {
+			page, err := h.processDrupalNode(nids[i], config)
+			if err != nil {
+				return fmt.Errorf("node %s: %w", nids[i], err)
+			}
+			pages[i] = page
+			return nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+
+		results := make([]*ImageProcessResult, 0, len(nids))
+		existing := 0
+		for _, page := range pages {
+			results = append(results, page.result)
+			if page.existingHOCR {
+				existing++
+			}
+		}
+
+		if err := checkSandboxPages(len(results)); err != nil {
+			return "", nil, err
+		}
+
+		// Without a language asked for, the session records its first page's
+		if config.Language == "" && len(pages) > 0 {
+			config.Language = pages[0].language
+		}
+		session := h.createMultiImageSession(sessionID, results, drupalSessionConfig(existing == len(pages), config))
+		session.Config.Prompt = fmt.Sprintf("Drupal Node %s - %s", parent, session.Config.Prompt)
+		setDrupalPages(session, pages)
+		if callbackURL != "" {
+			session.Callback = &models.Callback{URL: callbackURL}
+		}
+		h.sessionStore.Set(sessionID, session)
+
+		trace.log().Info("Session created from Drupal book", "session_id", sessionID, "parent_nid", parent, "pages", len(pages), "existing_hocr", existing)
+		return sessionID, BatchResult{
+			SessionID: sessionID,
+			Message:   fmt.Sprintf("Successfully processed %d pages of node %s", len(pages), parent),
+			Images:    len(results),
+		}, nil
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// drupalMapping describes how a site's Islandora taxonomy and routes are laid
+// out. The defaults match a stock Islandora install; sites with other media use
+// terms or routes override them with the DRUPAL_* variables in sample.env.
+//
+// The image and upload URL templates may use {base}, {nid}, {tid} (the hOCR
+// media's term), {view_node} and {uri} (the service file's); the upload
+// Content-Location {nid} and {timestamp}. Existing hOCR whose URI contains
+// reusableHOCRPath is loaded instead of running OCR again.
+type drupalMapping struct {
+	base             string
+	serviceFileTerm  string
+	hocrTerm         string
+	imageURL         string
+	uploadURL        string
+	contentLocation  string
+	reusableHOCRPath string
+	// missingHOCR is what to do with a node that has no hOCR media: fail, or
+	// create the media, with the term hocrTermID, when the page is published
+	missingHOCR string
+	hocrTermID  string
+	// revisions keeps each published file and saves a media revision logging
+	// who published it; mediaURL ({base} and {mid}) addresses the media entity
+	revisions   bool
+	mediaURL    string
+	mediaBundle string
+}
+
+// Values of DRUPAL_MISSING_HOCR
+const (
+	drupalFailMissingHOCR   = "fail"
+	drupalCreateMissingHOCR = "create"
+)
+
+func loadDrupalMapping() drupalMapping {
+	return drupalMapping{
+		base:             drupalBaseURL(),
+		serviceFileTerm:  envOr("DRUPAL_SERVICE_FILE_TERM", "Service File"),
+		hocrTerm:         envOr("DRUPAL_HOCR_TERM", "hOCR"),
+		imageURL:         envOr("DRUPAL_IMAGE_URL", "{base}{view_node}{uri}"),
+		uploadURL:        envOr("DRUPAL_UPLOAD_URL", "{base}/node/{nid}{view_node}/media/file/{tid}"),
+		contentLocation:  envOr("DRUPAL_HOCR_CONTENT_LOCATION", "private://derivatives/hocr/gcloud/{nid}.hocr"),
+		reusableHOCRPath: envOr("DRUPAL_REUSE_HOCR_MATCH", "gcloud"),
+		missingHOCR:      strings.ToLower(envOr("DRUPAL_MISSING_HOCR", drupalFailMissingHOCR)),
+		hocrTermID:       os.Getenv("DRUPAL_HOCR_TERM_ID"),
+		revisions:        envBool("DRUPAL_MEDIA_REVISIONS", true),
+		mediaURL:         envOr("DRUPAL_MEDIA_URL", "{base}/media/{mid}"),
+		mediaBundle:      envOr("DRUPAL_HOCR_MEDIA_BUNDLE", "file"),
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envBool(key string, fallback bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// drupalBaseURL is DRUPAL_BASE_URL, or the part of DRUPAL_HOCR_URL before its
+// /node/%s/hocr route, or failing that its scheme and host
+func drupalBaseURL() string {
+	if base := os.Getenv("DRUPAL_BASE_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	hocrURL := os.Getenv("DRUPAL_HOCR_URL")
+	if base, _, found := strings.Cut(hocrURL, "/node/%s/hocr"); found {
+		return base
+	}
+	if u, err := url.Parse(hocrURL); err == nil && u.Host != "" {
+		return u.Scheme + "://" + u.Host
+	}
+	return ""
+}
+
+// fileLocation is the Content-Location of an upload. With revisions on, each
+// upload gets its own file, named with {timestamp} or, when the template
+// doesn't use it, a timestamp before the extension.
+func (m drupalMapping) fileLocation(nid string, now time.Time) string {
+	location := strings.ReplaceAll(m.contentLocation, "{nid}", nid)
+	if !m.revisions {
+		return location
+	}
+	timestamp := now.Format("20060102T150405")
+	if strings.Contains(location, "{timestamp}") {
+		return strings.ReplaceAll(location, "{timestamp}", timestamp)
+	}
+	ext := path.Ext(location)
+	return strings.TrimSuffix(location, ext) + "-" + timestamp + ext
+}
+
+// expand fills a template with a node's values
+func (m drupalMapping) expand(template, nid string, file *DrupalFileObject, tid string) string {
+	return strings.NewReplacer(
+		"{base}", m.base,
+		"{nid}", nid,
+		"{tid}", tid,
+		"{view_node}", file.ViewNode,
+		"{uri}", file.URI,
+	).Replace(template)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// saveDrupalRevision saves a new revision of the image's hOCR media through
+// Drupal's REST API, logging the message, and returns the revision id. The
+// media must be known: from a mid in the node's file listing, or from where
+// Drupal said it created it.
+func (h *Handler) saveDrupalRevision(image *models.ImageItem, message, cookie string) (string, error) {
+	if image.DrupalMediaURL == "" {
+		return "", fmt.Errorf("the hOCR media of node %s is unknown; add its mid to the Drupal hOCR view", image.DrupalNid)
+	}
+	mediaURL := image.DrupalMediaURL
+	if !strings.Contains(mediaURL, "_format=") {
+		separator := "?"
+		if strings.Contains(mediaURL, "?") {
+			separator = "&"
+		}
+		mediaURL += separator + "_format=json"
+	}
+
+	// Drupal needs the bundle to denormalize a PATCH; new_revision asks for a
+	// revision even where the media type doesn't make one by default
+	body, err := json.Marshal(map[string]any{
+		"bundle":               []map[string]string{{"target_id": loadDrupalMapping().mediaBundle}},
+		"new_revision":         []map[string]bool{{"value": true}},
+		"revision_log_message": []map[string]string{{"value": message}},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("PATCH", mediaURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create Drupal request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cookie != "" && !h.drupal.HasCredentials(mediaURL) {
+		req.Header.Set("Cookie", cookie)
+	}
+
+	resp, err := h.drupal.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to save Drupal media revision: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("drupal media revision failed: HTTP %d - %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var media struct {
+		VID []struct {
+			Value json.Number `json:"value"`
+		} `json:"vid"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&media); err != nil {
+		return "", fmt.Errorf("failed to parse Drupal media: %w", err)
+	}
+	if len(media.VID) == 0 || media.VID[0].Value == "" {
+		return "", fmt.Errorf("drupal didn't return the media's revision id")
+	}
+	return media.VID[0].Value.String(), nil
+}
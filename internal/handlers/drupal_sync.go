@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// drupalUpload is what Drupal answered to an hOCR upload
+type drupalUpload struct {
+	response string
+	mediaURL string
+}
+
+// publishToDrupal uploads an image's hOCR and saves a media revision of it,
+// recording the outcome in the image's sync status. A failed upload is kept as
+// a failed job whose id the sync status points to. The caller stores the session.
+func (h *Handler) publishToDrupal(session *models.CorrectionSession, image *models.ImageItem, hocrData, user, cookie string) (PublishResponse, error) {
+	trace := newJobTrace(newJobID())
+	done := trace.stage("upload " + image.DrupalUploadURL)
+	upload, err := h.uploadHOCRToDrupal(image, hocrData, cookie)
+	done(err)
+	sync := recordDrupalSync(image, upload, err)
+	if err != nil {
+		trace.input("session_id", session.ID)
+		trace.input("image_id", image.ID)
+		trace.input("nid", image.DrupalNid)
+		trace.input("upload_url", image.DrupalUploadURL)
+		trace.input("hocr_length", len(hocrData))
+		job := h.recordFailedJob("publish_drupal", user, session.ID, trace, err)
+		sync.JobID = job.ID
+		h.sessionStore.Set(session.ID, session)
+		return PublishResponse{}, err
+	}
+
+	publishedAt := time.Now()
+	image.PublishedAt = &publishedAt
+	image.DrupalMediaPending = false
+	if upload.mediaURL != "" {
+		image.DrupalMediaURL = upload.mediaURL
+	}
+
+	// The file is published either way; a revision that can't be saved is reported
+	// alongside rather than failing the request
+	response := PublishResponse{Status: statusSuccess.Status}
+	if loadDrupalMapping().revisions {
+		revisionID, err := h.saveDrupalRevision(image, publishMessage(session.ID, user), cookie)
+		if err != nil {
+			slog.Warn("Published hOCR without a Drupal media revision", "session_id", session.ID, "image_id", image.ID, "nid", image.DrupalNid, "err", err)
+			response.Warning = "Published, but no media revision was saved: " + err.Error()
+		} else {
+			image.DrupalRevisionID = revisionID
+			response.RevisionID = revisionID
+		}
+	}
+	h.sessionStore.Set(session.ID, session)
+
+	slog.Info("Published hOCR to Drupal", "session_id", session.ID, "image_id", image.ID, "nid", image.DrupalNid, "revision_id", image.DrupalRevisionID)
+	return response, nil
+}
+
+// recordDrupalSync notes an upload attempt in the image's sync status
+func recordDrupalSync(image *models.ImageItem, upload drupalUpload, err error) *models.DrupalSync {
+	if image.DrupalSync == nil {
+		image.DrupalSync = &models.DrupalSync{}
+	}
+	sync := image.DrupalSync
+	attempted := time.Now()
+	sync.Attempts++
+	sync.LastAttempt = &attempted
+	sync.Response = upload.response
+	sync.JobID = ""
+	if err != nil {
+		sync.State = models.SyncFailed
+		sync.Error = err.Error()
+		return sync
+	}
+	sync.State = models.SyncUploaded
+	sync.Error = ""
+	return sync
+}
+
+// markDrupalSyncPending flags a Drupal image whose corrections have changed as
+// needing another upload
+func markDrupalSyncPending(image *models.ImageItem) {
+	if image.DrupalUploadURL == "" {
+		return
+	}
+	if image.DrupalSync == nil {
+		image.DrupalSync = &models.DrupalSync{}
+	}
+	image.DrupalSync.State = models.SyncPending
+}
+
+// syncState is an image's sync state; Drupal images never uploaded are pending
+func syncState(image *models.ImageItem) string {
+	if image.DrupalSync == nil {
+		return models.SyncPending
+	}
+	return image.DrupalSync.State
+}
+
+// drupalSyncReport lists the sync status of a session's Drupal images,
+// optionally only those in one state
+func drupalSyncReport(session *models.CorrectionSession, state string) DrupalSyncReport {
+	report := DrupalSyncReport{SessionID: session.ID, Counts: map[string]int{}, Images: []DrupalSyncImage{}}
+	for i := range session.Images {
+		image := &session.Images[i]
+		if image.DrupalUploadURL == "" {
+			continue
+		}
+		current := syncState(image)
+		report.Counts[current]++
+		if state != "" && current != state {
+			continue
+		}
+		report.Images = append(report.Images, syncImage(image))
+	}
+	return report
+}
+
+// syncImage is the sync status of one image
+func syncImage(image *models.ImageItem) DrupalSyncImage {
+	entry := DrupalSyncImage{ImageID: image.ID, Nid: image.DrupalNid}
+	if image.DrupalSync != nil {
+		entry.DrupalSync = *image.DrupalSync
+	}
+	entry.State = syncState(image)
+	return entry
+}
+
+func validSyncState(state string) error {
+	switch state {
+	case "", models.SyncPending, models.SyncUploaded, models.SyncFailed:
+		return nil
+	}
+	return fmt.Errorf("unknown sync state %q: use pending, uploaded or failed", state)
+}
+
+// handleDrupalSync reports a session's Drupal sync status, or with POST to
+// /sync/retry uploads its failed images again
+func (h *Handler) handleDrupalSync(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, subpath string) {
+	switch {
+	case subpath == "" && r.Method == "GET":
+		state := r.URL.Query().Get("state")
+		if err := validSyncState(state); err != nil {
+			h.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.writeJSON(w, drupalSyncReport(session, state))
+	case subpath == "retry" && r.Method == "POST":
+		h.handleDrupalSyncRetry(w, r, session)
+	case subpath == "" || subpath == "retry":
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		h.writeError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleDrupalSyncRetry queues a job uploading the given images again, or every
+// failed one when none are given. The editor's cookie is passed along for hosts
+// without configured credentials.
+func (h *Handler) handleDrupalSyncRetry(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	if !h.requirePermission(w, r, session.Collection, auth.PublishDrupal) {
+		return
+	}
+
+	var request DrupalSyncRetryRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	imageIDs := request.ImageIDs
+	if len(imageIDs) == 0 {
+		for _, image := range session.Images {
+			if image.DrupalUploadURL != "" && syncState(&image) == models.SyncFailed {
+				imageIDs = append(imageIDs, image.ID)
+			}
+		}
+		if len(imageIDs) == 0 {
+			h.writeError(w, "No images have failed to upload", http.StatusConflict)
+			return
+		}
+	}
+	for _, imageID := range imageIDs {
+		image := findImage(session, imageID)
+		if image == nil {
+			h.writeError(w, "Image not found: "+imageID, http.StatusNotFound)
+			return
+		}
+		if image.DrupalUploadURL == "" {
+			h.writeError(w, fmt.Sprintf("Image %s was not created from a Drupal node", imageID), http.StatusBadRequest)
+			return
+		}
+	}
+
+	user := requestUser(r)
+	job, err := h.enqueueJob("drupal_sync_retry", user, h.drupalSyncRetryJob(session.ID, imageIDs, user, r.Header.Get("Cookie")))
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	h.writeJSONStatus(w, http.StatusAccepted, JobAccepted{JobID: job.ID, Status: job.Status, StatusURL: APIPrefix + "/jobs/" + job.ID})
+}
+
+// drupalSyncRetryJob uploads the current hOCR of each image again. The job fails
+// only when none of them could be uploaded.
+func (h *Handler) drupalSyncRetryJob(sessionID string, imageIDs []string, user, cookie string) jobFunc {
+	return func(trace *jobTrace) (string, any, error) {
+		trace.input("session_id", sessionID)
+		trace.input("image_ids", imageIDs)
+
+		// The session is loaded again as it may have changed while the job waited
+		session, ok := h.sessionStore.Get(sessionID)
+		if !ok {
+			return "", nil, fmt.Errorf("session %s no longer exists", sessionID)
+		}
+
+		result := DrupalSyncRetryResult{SessionID: sessionID}
+		for _, imageID := range imageIDs {
+			image := findImage(session, imageID)
+			if image == nil {
+				continue
+			}
+			err := rightsRefusal(session, image, "published")
+			if err == nil {
+				done := trace.stage("publish " + image.ID)
+				_, err = h.publishToDrupal(session, image, currentHOCR(image), user, cookie)
+				done(err)
+			}
+			if err != nil {
+				result.Failed++
+			} else {
+				result.Uploaded++
+			}
+			entry := syncImage(image)
+			if err != nil && entry.Error == "" {
+				entry.Error = err.Error()
+			}
+			result.Images = append(result.Images, entry)
+		}
+
+		if result.Uploaded == 0 && result.Failed > 0 {
+			return sessionID, nil, fmt.Errorf("none of the %d images could be uploaded to Drupal", result.Failed)
+		}
+		trace.log().Info("Retried Drupal uploads", "session_id", sessionID, "uploaded", result.Uploaded, "failed", result.Failed)
+		return sessionID, result, nil
+	}
+}
+
+// HandleDrupalSync lists the Drupal images of every session, or those of a
+// collection, by sync state, so operators can see which corrected pages didn't
+// make it back to the repository
+func (h *Handler) HandleDrupalSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	collection := r.URL.Query().Get("collection")
+	if !h.requirePermission(w, r, collection, auth.PublishDrupal) {
+		return
+	}
+	state := r.URL.Query().Get("state")
+	if err := validSyncState(state); err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := DrupalSyncOverview{Counts: map[string]int{}, Sessions: []DrupalSyncReport{}}
+	for _, session := range h.sessionStore.GetAll() {
+		if collection != "" && session.Collection != collection {
+			continue
+		}
+		report := drupalSyncReport(session, state)
+		if len(report.Counts) == 0 {
+			continue
+		}
+		for current, count := range report.Counts {
+			response.Counts[current] += count
+		}
+		if len(report.Images) > 0 {
+			response.Sessions = append(response.Sessions, report)
+		}
+	}
+	sort.Slice(response.Sessions, func(i, j int) bool {
+		return response.Sessions[i].SessionID < response.Sessions[j].SessionID
+	})
+	h.writeJSON(w, response)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+)
+
+// HandleEstimate queues a dry run of the LLM engine over uploaded files: words
+// are detected and stitched as they would be for transcription, and the job's
+// result estimates the tokens and cost without calling the API
+func (h *Handler) HandleEstimate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	items, config, _, _, err := h.batchFromForm(w, r)
+	if err != nil {
+		h.writeError(w, err.Error(), uploadErrorStatus(err))
+		return
+	}
+	if len(items) == 0 {
+		h.writeError(w, "files are required", http.StatusBadRequest)
+		return
+	}
+	if len(items) > batchMaxItems() {
+		removeSpooled(items)
+		h.writeError(w, fmt.Sprintf("at most %d files can be estimated at once", batchMaxItems()), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.enqueueJob("estimate", requestUser(r), h.estimateJob(items, config))
+	if err != nil {
+		removeSpooled(items)
+	}
+	h.writeJobAccepted(w, job, err)
+}
+
+// estimateJob estimates each file in turn. The job fails only when none of
+// them could be estimated.
+func (h *Handler) estimateJob(items []batchItem, config SessionConfig) jobFunc {
+	return func(trace *jobTrace) (string, any, error) {
+		defer removeSpooled(items)
+		trace.input("files", len(items))
+		trace.input("config", config)
+
+		dir, err := os.MkdirTemp(h.dirs.Temp, "hocredit-estimate-")
+		if err != nil {
+			return "", nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		charset, err := hocr.ParseCharset(config.Characters)
+		if err != nil {
+			return "", nil, err
+		}
+		opts := hocr.Options{Engine: hocr.EngineLLM, Binarization: config.Binarization, Language: config.Language, DocumentType: config.DocumentType, Characters: charset}
+		result := CostEstimate{Pricing: h.hocrService.Pricing()}
+		for i, item := range items {
+			entry := ImageEstimate{Filename: item.filename}
+			imagePath := filepath.Join(dir, fmt.Sprintf("%d%s", i, filepath.Ext(item.filename)))
+			err := os.Rename(item.file.path, imagePath)
+			if err == nil {
+				done := trace.stage("estimate " + item.filename)
+				entry.Estimate, err = h.hocrService.EstimateLLM(imagePath, opts)
+				done(err)
+			}
+			if err != nil {
+				entry.Error = err.Error()
+				result.Failed++
+			}
+			result.Total = result.Total.Add(entry.Estimate)
+			result.Images = append(result.Images, entry)
+		}
+
+		if result.Failed == len(items) {
+			return "", result, fmt.Errorf("no file could be estimated")
+		}
+		return "", result, nil
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/accessibility"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/eval"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/export"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/metrics"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/sandbox"
+)
+
+// sessionExport gathers the current transcription of each requested image,
+// rejoining words hyphenated across lines in the session's language, and its
+// romanization when transliterate is set
+func sessionExport(images []*models.ImageItem, language string, transliterate bool) ([]ExportPage, export.Stats) {
+	var total export.Stats
+	pages := make([]ExportPage, 0, len(images))
+	for _, image := range images {
+		lines, _ := hocr.ParseHOCRLines(currentHOCR(image))
+		stats := export.PageStats(lines)
+		total = total.Add(stats)
+		page := ExportPage{ID: image.ID, Text: export.PlainText(hocr.MarkHyphenation(lines, language)), Stats: stats}
+		if transliterate {
+			page.Transliteration = export.PlainText(hocr.MarkHyphenation(export.TransliterateLines(lines), language))
+		}
+		pages = append(pages, page)
+	}
+	return pages, total
+}
+
+// setStatsHeaders attaches statistics to exports whose body can't carry them
+func setStatsHeaders(w http.ResponseWriter, stats export.Stats) {
+	w.Header().Set("X-Export-Pages", strconv.Itoa(stats.Pages))
+	w.Header().Set("X-Export-Lines", strconv.Itoa(stats.Lines))
+	w.Header().Set("X-Export-Words", strconv.Itoa(stats.Words))
+	w.Header().Set("X-Export-Characters", strconv.Itoa(stats.Characters))
+	w.Header().Set("X-Export-Reading-Minutes", strconv.Itoa(stats.ReadingMinutes))
+}
+
+// handleExport downloads the session, or one image with ?image_id=, as
+// text, hocr (single image only) or json. Statistics are included in the json
+// body and as X-Export-* headers on every format. With ?transliterate=true,
+// Cyrillic, Greek, Hebrew and Arabic are romanized in a parallel layer: each
+// page's transliteration in json, x_translit in the title of each word in
+// hocr, and in place of the text as a companion .translit.txt file. Exports
+// need can_export_pdf and are refused while any page is embargoed or restricted.
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requirePermission(w, r, session.Collection, auth.ExportPDF) {
+		return
+	}
+
+	images := make([]*models.ImageItem, 0, len(session.Images))
+	if imageID := r.URL.Query().Get("image_id"); imageID != "" {
+		image := findImage(session, imageID)
+		if image == nil {
+			h.writeError(w, "Image not found", http.StatusNotFound)
+			return
+		}
+		images = append(images, image)
+	} else {
+		for i := range session.Images {
+			images = append(images, &session.Images[i])
+		}
+	}
+	if err := exportRefusal(session, images); err != nil {
+		h.writeError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	transliterate := false
+	if value := r.URL.Query().Get("transliterate"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			h.writeError(w, "transliterate must be true or false", http.StatusBadRequest)
+			return
+		}
+		transliterate = parsed
+	}
+
+	pages, stats := sessionExport(images, session.Config.Language, transliterate)
+	setStatsHeaders(w, stats)
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "text":
+		texts := make([]string, len(pages))
+		for i, page := range pages {
+			texts[i] = page.Text
+			if transliterate {
+				texts[i] = page.Transliteration
+			}
+		}
+		extension := ".txt"
+		if transliterate {
+			extension = ".translit.txt"
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s"`, session.ID, extension))
+		// Form feeds mark page breaks, as in Tesseract's text output
+		text := strings.Join(texts, "\f")
+		if sandbox.Enabled() {
+			text = sandbox.WatermarkText(text)
+		}
+		fmt.Fprint(w, text)
+	case "hocr":
+		if len(images) != 1 {
+			h.writeError(w, "hocr export requires image_id", http.StatusBadRequest)
+			return
+		}
+		hocrXML := currentHOCR(images[0])
+		if transliterate {
+			hocrXML = export.TransliterateHOCR(hocrXML)
+		}
+		if sandbox.Enabled() {
+			hocrXML = sandbox.WatermarkHOCR(hocrXML)
+		}
+		if report, err := accessibility.CheckHTML([]byte(hocrXML)); err == nil {
+			setConformanceHeaders(w, report, fmt.Sprintf("%s/sessions/%s/accessibility?image_id=%s", APIPrefix, session.ID, images[0].ID))
+		}
+		w.Header().Set("Content-Type", "text/vnd.hocr+html; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_%s.hocr"`, session.ID, images[0].ID))
+		fmt.Fprint(w, hocrXML)
+	case "json":
+		body := SessionExport{SessionID: session.ID, Stats: stats, Pages: pages}
+		if sandbox.Enabled() {
+			body.Watermark = sandbox.Watermark
+		}
+		h.writeJSON(w, body)
+	default:
+		h.writeError(w, "format must be text, hocr or json", http.StatusBadRequest)
+	}
+}
+
+// handleSummary reports a session's progress and transcription statistics
+func (h *Handler) handleSummary(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	images := make([]*models.ImageItem, len(session.Images))
+	statuses := map[string]int{}
+	for i := range session.Images {
+		images[i] = &session.Images[i]
+		statuses[pageStatus(images[i])]++
+	}
+
+	_, stats := sessionExport(images, session.Config.Language, false)
+	h.writeJSON(w, SessionSummary{
+		ID:         session.ID,
+		Collection: session.Collection,
+		CreatedAt:  session.CreatedAt,
+		Images:     len(session.Images),
+		Statuses:   statuses,
+		Stats:      stats,
+	})
+}
+
+// sessionReport measures every image's correction against its OCR
+func sessionReport(session *models.CorrectionSession) []models.EvalResult {
+	now := time.Now()
+	results := make([]models.EvalResult, 0, len(session.Images))
+	for i := range session.Images {
+		image := &session.Images[i]
+		result := metrics.CalculateAccuracyMetrics(hocrPlainText(image.OriginalHOCR, session.Config.Language), hocrPlainText(currentHOCR(image), session.Config.Language), models.NormalizationConfig{})
+		result.Identifier = image.ID
+		result.ImagePath = image.ImagePath
+		result.Public = isPubliclyViewable(effectiveRights(session, image), now)
+		results = append(results, result)
+	}
+	return results
+}
+
+// handleReport downloads the accuracy metrics of every image as json (the
+// default) or csv for spreadsheets, under the same permission and rights as exports
+func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requirePermission(w, r, session.Collection, auth.ExportPDF) {
+		return
+	}
+	images := make([]*models.ImageItem, len(session.Images))
+	for i := range session.Images {
+		images[i] = &session.Images[i]
+	}
+	if err := exportRefusal(session, images); err != nil {
+		h.writeError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	results := sessionReport(session)
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		h.writeJSON(w, MetricsReport{SessionID: session.ID, Results: results, Summary: eval.Summarize(results, 0)})
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_report.csv"`, session.ID))
+		if err := eval.WriteCSV(w, results); err != nil {
+			slog.Error("Failed to write metrics report", "session_id", session.ID, "err", err)
+		}
+	default:
+		h.writeError(w, "format must be json or csv", http.StatusBadRequest)
+	}
+}
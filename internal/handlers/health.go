@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+)
+
+const (
+	healthOK   = "ok"
+	healthWarn = "warn"
+	healthFail = "fail"
+)
+
+// llmProbeInterval keeps readiness probes from calling the LLM endpoint on every request
+const llmProbeInterval = 30 * time.Second
+
+// llmHealth caches the last LLM endpoint probe
+type llmHealth struct {
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// HandleHealthz is the liveness probe: it fails only when the process itself is
+// wedged, which a restart would fix
+func (h *Handler) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	h.writeHealth(w, []HealthCheck{h.checkSessionStore()})
+}
+
+// HandleReadyz is the readiness probe: it also fails while a dependency needed to
+// process uploads is missing, so traffic is routed elsewhere until it recovers
+func (h *Handler) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	checks := []HealthCheck{
+		h.checkShutdown(),
+		h.checkSessionStore(),
+		h.checkUploadsDir(),
+		h.checkStorage(),
+		checkBinary("magick", true),
+		checkBinary("vips", false),
+		h.checkTesseract(),
+		h.checkLLM(ctx),
+	}
+	h.writeHealth(w, checks)
+}
+
+func (h *Handler) writeHealth(w http.ResponseWriter, checks []HealthCheck) {
+	response := HealthResponse{Status: healthOK, Checks: checks}
+	status := http.StatusOK
+	for _, check := range checks {
+		if check.Status == healthFail {
+			response.Status = healthFail
+			status = http.StatusServiceUnavailable
+		}
+	}
+	h.writeJSONStatus(w, status, response)
+}
+
+// newHealthCheck records err as a failure, or a warning for optional checks
+func newHealthCheck(name string, required bool, err error) HealthCheck {
+	check := HealthCheck{Name: name, Status: healthOK, Required: required}
+	if err != nil {
+		check.Status = healthWarn
+		if required {
+			check.Status = healthFail
+		}
+		check.Detail = err.Error()
+	}
+	return check
+}
+
+// checkShutdown fails readiness once draining starts, so no new uploads are routed here
+func (h *Handler) checkShutdown() HealthCheck {
+	var err error
+	if h.isDraining() {
+		err = errShuttingDown
+	}
+	return newHealthCheck("shutdown", true, err)
+}
+
+func (h *Handler) checkSessionStore() HealthCheck {
+	done := make(chan error, 1)
+	go func() { done <- h.sessionStore.Check() }()
+
+	select {
+	case err := <-done:
+		return newHealthCheck("session_store", true, err)
+	case <-time.After(2 * time.Second):
+		return newHealthCheck("session_store", true, context.DeadlineExceeded)
+	}
+}
+
+func (h *Handler) checkUploadsDir() HealthCheck {
+	probe, err := os.CreateTemp(h.dirs.Uploads, ".health-*")
+	if err == nil {
+		probe.Close()
+		err = os.Remove(probe.Name())
+	}
+	return newHealthCheck("uploads_dir", true, err)
+}
+
+func checkBinary(name string, required bool) HealthCheck {
+	_, err := exec.LookPath(name)
+	return newHealthCheck(name, required, err)
+}
+
+// checkTesseract is only required when Tesseract is the default engine; otherwise
+// its absence just hides the engine
+func (h *Handler) checkTesseract() HealthCheck {
+	required := h.hocrService.DefaultEngine() == hocr.EngineTesseract
+	return checkBinary("tesseract", required)
+}
+
+// checkLLM probes the LLM endpoint when this deployment uses it
+func (h *Handler) checkLLM(ctx context.Context) HealthCheck {
+	if h.hocrService.Profile() != hocr.ProfileFull {
+		return HealthCheck{Name: "llm", Status: healthOK, Detail: "not used in the " + h.hocrService.Profile() + " profile"}
+	}
+
+	h.llmHealth.mu.Lock()
+	defer h.llmHealth.mu.Unlock()
+	if time.Since(h.llmHealth.checked) > llmProbeInterval {
+		h.llmHealth.err = h.hocrService.CheckLLM(ctx)
+		h.llmHealth.checked = time.Now()
+	}
+	return newHealthCheck("llm", true, h.llmHealth.err)
+}
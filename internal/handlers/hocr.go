@@ -5,7 +5,6 @@ import (
 	"log/slog"
 	"net/http"
 
-	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
 	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
 )
 
@@ -15,11 +14,7 @@ func (h *Handler) HandleHOCRUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var request struct {
-		SessionID string `json:"session_id"`
-		ImageID   string `json:"image_id"`
-		HOCR      string `json:"hocr"`
-	}
+	var request HOCRUpdateRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
@@ -31,45 +26,65 @@ func (h *Handler) HandleHOCRUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	for i, image := range session.Images {
-		if image.ID == request.ImageID {
-			session.Images[i].CorrectedHOCR = request.HOCR
-			session.Images[i].Completed = true
-			break
+	image := findImage(session, request.ImageID)
+	if image != nil {
+		h.parsed.forget(currentHOCR(image))
+		image.CorrectedHOCR = request.HOCR
+		// Drafts keep the image's state, so partial work isn't reported as done
+		if !request.Draft {
+			image.Completed = true
 		}
+		markDrupalSyncPending(image)
 	}
 
 	h.sessionStore.Set(request.SessionID, session)
-	h.writeJSON(w, map[string]string{"status": "success"})
+	if image != nil {
+		h.publishHOCRUpdate(r, session, image)
+		if !request.Draft {
+			h.notifyCompletion(r, session)
+		}
+	}
+	h.writeJSON(w, statusSuccess)
 }
 
-func (h *Handler) HandleHOCRParse(w http.ResponseWriter, r *http.Request) {
+// handleCompletion marks an image done at POST
+// /sessions/{id}/images/{imageID}/complete, or back in progress at .../reopen,
+// without touching its hOCR
+func (h *Handler) handleCompletion(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem, completed bool) {
 	if r.Method != "POST" {
 		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var request struct {
-		HOCR string `json:"hocr"`
+	image.Completed = completed
+	h.sessionStore.Set(session.ID, session)
+	h.publishHOCRUpdate(r, session, image)
+	// Reopening an image clears a sent callback, so finishing it again reports anew
+	h.notifyCompletion(r, session)
+
+	slog.Info("Image completion changed", "session_id", session.ID, "image_id", image.ID, "completed", completed)
+	h.writeJSON(w, statusSuccess)
+}
+
+func (h *Handler) HandleHOCRParse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
+	var request HOCRParseRequest
+
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	words, err := hocr.ParseHOCRWords(request.HOCR)
+	words, err := h.parsed.words(request.HOCR)
 	if err != nil {
 		slog.Error("Unable to parse hocr", "hocr", request.HOCR, "err", err)
 		h.writeError(w, "Failed to parse hOCR: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	response := struct {
-		Words []models.HOCRWord `json:"words"`
-	}{
-		Words: words,
-	}
-
-	h.writeJSON(w, response)
+	h.writeJSON(w, HOCRParseResponse{Words: words})
 }
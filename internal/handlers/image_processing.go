@@ -2,51 +2,58 @@ package handlers
 
 import (
 	"bytes"
-	"crypto/md5"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/iiif"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
 	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
 )
 
-func (h *Handler) processImageFile(fileData []byte, filename string) (*ImageProcessResult, error) {
-	md5Hash := utils.CalculateDataMD5(fileData)
-	ext := filepath.Ext(filename)
-	imageFilename := md5Hash + ext
-	imageFilePath := filepath.Join("uploads", imageFilename)
-
-	if err := os.WriteFile(imageFilePath, fileData, 0644); err != nil {
-		return nil, fmt.Errorf("failed to save image: %w", err)
+// processImageFile stores and OCRs an uploaded image
+func (h *Handler) processImageFile(file *spooledFile, filename string, config SessionConfig) (*ImageProcessResult, error) {
+	content, err := file.open()
+	if err != nil {
+		return nil, err
 	}
+	defer content.Close()
 
-	slog.Info("Image saved", "filename", imageFilename, "md5", md5Hash)
+	result, err := h.storeImage(file.digest, filepath.Ext(filename), func(path string) error {
+		return saveFrom(path, content)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.LegacyDigest = file.legacyDigest
+	return h.addHOCR(result, config)
+}
 
-	width, height := utils.GetImageDimensions(imageFilePath)
-	hocrXML, err := h.processHOCR(imageFilePath, md5Hash)
+// addHOCR OCRs a stored image, or finds its cached hOCR
+func (h *Handler) addHOCR(result *ImageProcessResult, config SessionConfig) (*ImageProcessResult, error) {
+	h.adoptLegacyHOCR(result, config)
+	hocrXML, err := h.processHOCR(result.ImageFilePath, result.Digest, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process hOCR: %w", err)
 	}
-
-	return &ImageProcessResult{
-		ImageFilename: imageFilename,
-		ImageFilePath: imageFilePath,
-		HOCRXML:       hocrXML,
-		Width:         width,
-		Height:        height,
-		MD5Hash:       md5Hash,
-	}, nil
+	result.HOCRXML = hocrXML
+	return result, nil
 }
 
-func (h *Handler) downloadImageFromURL(imageURL string) ([]byte, string, error) {
-	resp, err := http.Get(imageURL)
+// downloadImageFromURL fetches an image to a spooled file, which the caller
+// removes, with Drupal credentials when the host has them
+func (h *Handler) downloadImageFromURL(imageURL string) (*spooledFile, string, error) {
+	resp, err := h.drupal.Get(imageURL)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to download image: %w", err)
 	}
@@ -56,80 +63,152 @@ func (h *Handler) downloadImageFromURL(imageURL string) ([]byte, string, error)
 		return nil, "", fmt.Errorf("failed to download image: HTTP %d", resp.StatusCode)
 	}
 
-	imageData, err := io.ReadAll(resp.Body)
+	limit := maxUploadBytes()
+	if resp.ContentLength > limit {
+		return nil, "", fmt.Errorf("image at %s: %w (%d bytes, the limit is %d)", imageURL, errUploadTooLarge, resp.ContentLength, limit)
+	}
+	file, err := h.spool(resp.Body, limit)
+	if errors.Is(err, errUploadTooLarge) {
+		return nil, "", fmt.Errorf("image at %s: %w", imageURL, err)
+	}
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to read image data: %w", err)
 	}
 
 	contentType := resp.Header.Get("Content-Type")
-	return imageData, contentType, nil
+	return file, contentType, nil
+}
+
+// processPrefetchedImage OCRs the image a prefetch run already downloaded and
+// converted for imageURL
+func (h *Handler) processPrefetchedImage(imageURL string, result *ImageProcessResult, config SessionConfig) (*ImageProcessResult, error) {
+	config.trace.log().Info("Using prefetched image", "url", imageURL, "filename", result.ImageFilename)
+	return h.addHOCR(result, config)
 }
 
-func (h *Handler) processImageFromURL(imageURL string) (*ImageProcessResult, error) {
+func (h *Handler) processImageFromURL(imageURL string, config SessionConfig) (*ImageProcessResult, error) {
+	if result, ok := h.lookupPrefetchedImage(imageURL); ok {
+		return h.processPrefetchedImage(imageURL, result, config)
+	}
+
 	// Download image from URL
-	imageData, contentType, err := h.downloadImageFromURL(imageURL)
+	file, contentType, err := h.downloadImageFromURL(imageURL)
 	if err != nil {
 		return nil, err
 	}
+	defer file.remove()
 
-	return h.processImageFromData(imageData, contentType, imageURL)
+	return h.processImageFromFile(file, contentType, imageURL, config)
 }
 
-func (h *Handler) processImageFromData(imageData []byte, contentType, sourceURL string) (*ImageProcessResult, error) {
-	// Convert JP2/TIFF images using Houdini if needed
-	originalImageData := imageData
-	if needsHoudiniConversion(contentType, sourceURL) {
-		slog.Info("Image requires Houdini conversion", "content_type", contentType, "url", sourceURL)
-		convertedData, err := h.convertImageViaHoudini(imageData, contentType)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert image via Houdini: %w", err)
-		}
-		imageData = convertedData
-		contentType = "image/jpeg"
+func (h *Handler) processImageFromData(imageData []byte, contentType, sourceURL string, config SessionConfig) (*ImageProcessResult, error) {
+	result, err := h.saveImageFromData(imageData, contentType, sourceURL)
+	if err != nil {
+		return nil, err
 	}
+	return h.addHOCR(result, config)
+}
 
-	// Calculate MD5 hash of the original image data for consistent caching
-	md5Hash := utils.CalculateDataMD5(originalImageData)
-
-	// Determine file extension from content type
-	ext := h.getFileExtension(contentType, sourceURL)
-
-	if err := h.ensureUploadsDir(); err != nil {
-		return nil, fmt.Errorf("failed to create uploads directory: %w", err)
+// processImageFromFile is processImageFromData for a download spooled to disk
+func (h *Handler) processImageFromFile(file *spooledFile, contentType, sourceURL string, config SessionConfig) (*ImageProcessResult, error) {
+	result, err := h.saveImageFromFile(file, contentType, sourceURL)
+	if err != nil {
+		return nil, err
 	}
+	return h.addHOCR(result, config)
+}
 
-	imageFilename := md5Hash + ext
-	imageFilePath := filepath.Join("uploads", imageFilename)
+// saveImageFromData converts the image if needed and stores it in uploads without running OCR
+func (h *Handler) saveImageFromData(imageData []byte, contentType, sourceURL string) (*ImageProcessResult, error) {
+	result, err := h.saveImage(storage.ContentDigest(imageData), bytes.NewReader(imageData), contentType, sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	result.LegacyDigest = utils.CalculateDataMD5(imageData)
+	return result, nil
+}
 
-	// Save image file
-	if err := os.WriteFile(imageFilePath, imageData, 0644); err != nil {
-		return nil, fmt.Errorf("failed to save image: %w", err)
+// saveImageFromFile is saveImageFromData for a download spooled to disk
+func (h *Handler) saveImageFromFile(file *spooledFile, contentType, sourceURL string) (*ImageProcessResult, error) {
+	content, err := file.open()
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	result, err := h.saveImage(file.digest, content, contentType, sourceURL)
+	if err != nil {
+		return nil, err
 	}
+	result.LegacyDigest = file.legacyDigest
+	return result, nil
+}
 
-	slog.Info("Image processed and saved", "filename", imageFilename, "md5", md5Hash, "source", sourceURL)
+// saveImage stores content, converting it if needed. The digest is of the
+// image as received, so a source that needs converting finds its stored
+// conversion without converting again.
+func (h *Handler) saveImage(digest string, content io.Reader, contentType, sourceURL string) (*ImageProcessResult, error) {
+	convert := needsHoudiniConversion(contentType, sourceURL)
+	ext := ".jpg"
+	if !convert {
+		ext = h.getFileExtension(contentType, sourceURL)
+	}
 
-	// Get image dimensions
-	width, height := utils.GetImageDimensions(imageFilePath)
+	return h.storeImage(digest, ext, func(path string) error {
+		if !convert {
+			return saveFrom(path, content)
+		}
+		slog.Info("Image requires Houdini conversion", "content_type", contentType, "url", sourceURL)
+		if err := h.convertImageViaHoudini(content, digest, path); err != nil {
+			return fmt.Errorf("failed to convert image via Houdini: %w", err)
+		}
+		return nil
+	})
+}
 
-	// Process hOCR
-	hocrXML, err := h.processHOCR(imageFilePath, md5Hash)
+// storeImage keeps an image in uploads under the digest of its source content.
+// The first upload of a page has write put it at the path given, and
+// normalizes it; later ones, from any session, reuse the stored file as it is.
+func (h *Handler) storeImage(digest, ext string, write func(path string) error) (*ImageProcessResult, error) {
+	imageFilename := digest + ext
+	created, err := h.blobs.Put(digest, ext, func(path string) error {
+		if err := write(path); err != nil {
+			return err
+		}
+		// The quota is checked once the size is known, and the file is
+		// dropped with the blob's temporary path when it doesn't fit
+		if err := h.checkStorageQuota(h.storedSize(path)); err != nil {
+			return err
+		}
+		if err := normalizeImage(path, filepath.Join(h.originalsDir(), imageFilename)); err != nil {
+			slog.Warn("Failed to normalize image, using it as uploaded", "error", err, "filename", imageFilename)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to process hOCR: %w", err)
+		return nil, err
+	}
+
+	imageFilePath := h.blobs.Path(digest, ext)
+	slog.Info("Image stored", "filename", imageFilename, "reused", !created)
+	if created {
+		h.addStorageUsed(h.storedSize(imageFilePath))
 	}
 
+	width, height := utils.GetImageDimensions(imageFilePath)
 	return &ImageProcessResult{
 		ImageFilename: imageFilename,
 		ImageFilePath: imageFilePath,
-		HOCRXML:       hocrXML,
 		Width:         width,
 		Height:        height,
-		MD5Hash:       md5Hash,
+		Digest:        digest,
 	}, nil
 }
 
 func (h *Handler) getFileExtension(contentType, sourceURL string) string {
 	ext := ".jpg" // default
 	switch contentType {
+	case "image/jpeg":
+		ext = ".jpg"
 	case "image/png":
 		ext = ".png"
 	case "image/gif":
@@ -145,103 +224,299 @@ func (h *Handler) getFileExtension(contentType, sourceURL string) string {
 	return ext
 }
 
-func (h *Handler) processHOCR(imageFilePath, md5Hash string) (string, error) {
-	hocrFilename := md5Hash + ".xml"
-	hocrFilePath := filepath.Join("uploads", hocrFilename)
+func (h *Handler) processHOCR(imageFilePath, digest string, config SessionConfig) (string, error) {
+	config = h.resolveEngine(config)
+	hocrFilename := hocrCacheFilename(digest, config)
+	hocrFilePath := filepath.Join(h.dirs.Uploads, hocrFilename)
+
+	// Sessions sharing a page wait for one OCR run instead of each paying for it
+	unlock := h.blobs.Lock(hocrFilename)
+	defer unlock()
 
 	// Check cache first
 	if _, err := os.Stat(hocrFilePath); err == nil {
 		hocrData, err := os.ReadFile(hocrFilePath)
 		if err != nil {
-			slog.Warn("Failed to read existing hOCR file", "error", err, "path", hocrFilePath)
+			config.trace.log().Warn("Failed to read existing hOCR file", "error", err, "path", hocrFilePath)
 		} else {
-			slog.Info("Using cached hOCR", "filename", hocrFilename)
-			return string(hocrData), nil
+			config.trace.log().Info("Using cached hOCR", "filename", hocrFilename)
+			return h.postCorrect(string(hocrData), config), nil
 		}
 	}
 
-	// Generate new hOCR
-	hocrXML, err := h.getOCRForImage(imageFilePath)
+	hocrXML, err := h.runHOCR(imageFilePath, digest, config, hocr.Options{})
 	if err != nil {
+		return "", err
+	}
+	return h.postCorrect(hocrXML, config), nil
+}
+
+// adoptLegacyHOCR copies hOCR cached under the MD5 of the source image, as it
+// was before uploads were keyed by SHA-256, to the name processHOCR looks for,
+// so pages OCRed by an earlier release aren't sent to the engine again. The
+// MD5 copy stays for the sessions that still name their upload by it.
+func (h *Handler) adoptLegacyHOCR(result *ImageProcessResult, config SessionConfig) {
+	if result.LegacyDigest == "" {
+		return
+	}
+	config = h.resolveEngine(config)
+	hocrFilename := hocrCacheFilename(result.Digest, config)
+	unlock := h.blobs.Lock(hocrFilename)
+	defer unlock()
+
+	hocrFilePath := filepath.Join(h.dirs.Uploads, hocrFilename)
+	if _, err := os.Stat(hocrFilePath); err == nil {
+		return
+	}
+	legacyFilename := hocrCacheFilename(result.LegacyDigest, config)
+	data, err := os.ReadFile(filepath.Join(h.dirs.Uploads, legacyFilename))
+	if err != nil {
+		return
+	}
+	if err := storage.WriteFileAtomic(hocrFilePath, data); err != nil {
+		config.trace.log().Warn("Failed to adopt hOCR cached under the upload's MD5", "error", err, "filename", legacyFilename)
+		return
+	}
+	config.trace.log().Info("Adopted hOCR cached under the upload's MD5", "filename", legacyFilename, "as", hocrFilename)
+}
+
+// regenerateHOCR runs OCR on an image again whether or not its hOCR is cached,
+// asking the LLM afresh, and replaces the cached copy. A model or prompt in
+// config.LLM has a cache file of its own, leaving the default reading to the
+// sessions that use it. The cache keeps the engine's own reading;
+// post-correction runs on the way out.
+func (h *Handler) regenerateHOCR(imageFilePath, digest string, config SessionConfig) (string, error) {
+	config = h.resolveEngine(config)
+	unlock := h.blobs.Lock(hocrCacheFilename(digest, config))
+	defer unlock()
+
+	hocrXML, err := h.runHOCR(imageFilePath, digest, config, hocr.Options{Refresh: true})
+	if err != nil {
+		return "", err
+	}
+	return h.postCorrect(hocrXML, config), nil
+}
+
+// invalidateHOCR removes an image's cached hOCR for a config, so the next
+// session to use the image runs OCR again. removed is false when none was cached.
+func (h *Handler) invalidateHOCR(digest string, config SessionConfig) (removed bool, err error) {
+	config = h.resolveEngine(config)
+	hocrFilename := hocrCacheFilename(digest, config)
+	unlock := h.blobs.Lock(hocrFilename)
+	defer unlock()
+
+	err = os.Remove(filepath.Join(h.dirs.Uploads, hocrFilename))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	config.trace.log().Info("hOCR cache invalidated", "filename", hocrFilename)
+	return true, nil
+}
+
+// runHOCR generates hOCR and caches it, keeping the raw engine output for
+// later audits. The caller holds the lock on the cache file.
+func (h *Handler) runHOCR(imageFilePath, digest string, config SessionConfig, opts hocr.Options) (string, error) {
+	hocrFilename := hocrCacheFilename(digest, config)
+	hocrFilePath := filepath.Join(h.dirs.Uploads, hocrFilename)
+
+	archive := newArtifactArchive()
+	var hocrXML string
+	err := h.pages.run(func() error {
+		done := config.trace.stage("ocr " + digest)
+		var err error
+		hocrXML, err = h.getOCRForImage(imageFilePath, config, opts, archive)
+		done(err)
+		return err
+	})
+	if err != nil {
+		config.trace.engineOutput(digest, archive)
 		return "", fmt.Errorf("failed to process image with OCR: %w", err)
 	}
 
+	if err := archive.write(h.artifactDirFor(digest, config), digest, config); err != nil {
+		config.trace.log().Warn("Failed to archive engine output", "error", err, "digest", digest)
+	}
+
 	// Cache the result
-	if err := os.WriteFile(hocrFilePath, []byte(hocrXML), 0644); err != nil {
-		slog.Warn("Failed to save hOCR file", "error", err)
+	if err := storage.WriteFileAtomic(hocrFilePath, []byte(hocrXML)); err != nil {
+		config.trace.log().Warn("Failed to save hOCR file", "error", err)
 	} else {
-		slog.Info("hOCR cached", "filename", hocrFilename)
+		config.trace.log().Info("hOCR cached", "filename", hocrFilename)
 	}
 
 	return hocrXML, nil
 }
 
-func (h *Handler) extractFilenameFromURL(imageURL, md5Hash string) string {
+func (h *Handler) extractFilenameFromURL(imageURL, digest string) string {
 	if urlParts := strings.Split(imageURL, "/"); len(urlParts) > 0 {
 		lastPart := urlParts[len(urlParts)-1]
 		if lastPart != "" && strings.Contains(lastPart, ".") {
 			return strings.TrimSuffix(lastPart, filepath.Ext(lastPart))
 		}
 	}
-	return md5Hash
+	return digest
+}
+
+// urlImages is what processing one URL produced, before it is put in a session
+type urlImages struct {
+	results    []*ImageProcessResult
+	iiifSource *models.IIIFSource
+	imageURL   string
+}
+
+// sessionName names a session after the image, or the IIIF identifier since
+// every IIIF image is named default.jpg
+func (u *urlImages) sessionName(h *Handler) string {
+	if u.iiifSource != nil && u.iiifSource.ImageService != "" {
+		return path.Base(u.iiifSource.ImageService)
+	}
+	return h.extractFilenameFromURL(u.imageURL, u.results[0].Digest)
 }
 
-func (h *Handler) createSessionFromURL(imageURL string) (string, error) {
-	result, err := h.processImageFromURL(imageURL)
+// processURL downloads and OCRs an image URL, following IIIF sources to the
+// image, or OCRs the image a prefetch run already downloaded and converted
+func (h *Handler) processURL(imageURL string, config SessionConfig) (*urlImages, error) {
+	if result, ok := h.lookupPrefetchedImage(imageURL); ok {
+		result, err := h.processPrefetchedImage(imageURL, result, config)
+		if err != nil {
+			return nil, err
+		}
+		return &urlImages{results: []*ImageProcessResult{result}, imageURL: imageURL}, nil
+	}
+
+	done := config.trace.stage("download " + imageURL)
+	file, contentType, err := h.downloadImageFromURL(imageURL)
+	done(err)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	defer file.remove()
 
-	// Extract filename from URL or use md5 hash
-	filename := h.extractFilenameFromURL(imageURL, result.MD5Hash)
-	sessionID := fmt.Sprintf("%s_%d", filename, time.Now().Unix())
+	// A IIIF info.json or canvas points at the image rather than being one
+	var iiifSource *models.IIIFSource
+	if isJSONContent(contentType, imageURL) {
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			return nil, err
+		}
+		var fullImageURL string
+		iiifSource, fullImageURL, err = iiif.ResolveSource(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if result, ok := h.lookupPrefetchedImage(fullImageURL); ok {
+			result, err := h.processPrefetchedImage(fullImageURL, result, config)
+			if err != nil {
+				return nil, err
+			}
+			return &urlImages{results: []*ImageProcessResult{result}, iiifSource: iiifSource, imageURL: fullImageURL}, nil
+		}
+
+		config.trace.log().Info("Fetching full image from IIIF source", "url", imageURL, "image_url", fullImageURL)
+		done := config.trace.stage("download " + fullImageURL)
+		file, contentType, err = h.downloadImageFromURL(fullImageURL)
+		done(err)
+		if err != nil {
+			return nil, err
+		}
+		defer file.remove()
+		imageURL = fullImageURL
+	}
 
-	config := SessionConfig{
-		Model:       "",
-		Prompt:      "",
-		Temperature: 0.0,
+	results, err := h.processImagesFromFile(file, contentType, imageURL, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &urlImages{results: results, iiifSource: iiifSource, imageURL: imageURL}, nil
+}
+
+// setIIIFSource records where images came from so viewers can use the image service
+func setIIIFSource(images []models.ImageItem, iiifSource *models.IIIFSource) {
+	if iiifSource == nil {
+		return
 	}
+	for i := range images {
+		source := *iiifSource
+		images[i].IIIF = &source
+	}
+}
 
-	session := h.createImageSession(sessionID, result, config)
+// createSessionFromURL processes an image URL into a new session. An empty
+// sessionID is replaced by one derived from the image name.
+func (h *Handler) createSessionFromURL(imageURL, sessionID string, config SessionConfig) (string, error) {
+	processed, err := h.processURL(imageURL, config)
+	if err != nil {
+		return "", err
+	}
+
+	if sessionID == "" {
+		sessionID = fmt.Sprintf("%s_%d", processed.sessionName(h), time.Now().Unix())
+	}
+
+	session := h.createMultiImageSession(sessionID, processed.results, config)
+	setIIIFSource(session.Images, processed.iiifSource)
 	h.sessionStore.Set(sessionID, session)
 
-	slog.Info("Session created from URL", "session_id", sessionID, "url", imageURL)
+	config.trace.log().Info("Session created from URL", "session_id", sessionID, "url", processed.imageURL, "images", len(processed.results))
 	return sessionID, nil
 }
 
-// convertImageViaHoudini converts JP2/TIFF images to JPG using Houdini service
-func (h *Handler) convertImageViaHoudini(imageData []byte, contentType string) ([]byte, error) {
+func isJSONContent(contentType, url string) bool {
+	return strings.Contains(contentType, "json") || strings.HasSuffix(url, "/info.json")
+}
 
-	hash := md5.Sum(imageData)
-	cacheKey := hex.EncodeToString(hash[:])
-	cacheFilename := cacheKey + "_converted.jpg"
-	cacheDir := "cache/houdini"
+// convertImageViaHoudini converts JP2/TIFF images to a JPG at dest using
+// Houdini service, caching the result under the source's digest
+func (h *Handler) convertImageViaHoudini(input io.Reader, digest, dest string) error {
+	cacheFilename := digest + "_converted.jpg"
+	cacheDir := h.houdiniDir()
 	cachePath := filepath.Join(cacheDir, cacheFilename)
 
 	// Check cache first
-	if cachedData, err := os.ReadFile(cachePath); err == nil {
-		slog.Info("Using cached Houdini conversion", "cache_key", cacheKey)
-		return cachedData, nil
+	if cached, err := os.Open(cachePath); err == nil {
+		defer cached.Close()
+		slog.Info("Using cached Houdini conversion", "cache_key", digest)
+		return writeFrom(dest, cached)
 	}
 	// Create cache directory
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		slog.Warn("Failed to create Houdini cache directory", "error", err)
 	}
 
-	// Convert to grayscale, enhance contrast, and apply morphological operations
-	cmd := exec.Command("magick", "-", cachePath)
-	cmd.Stdin = bytes.NewReader(imageData)
+	// Convert beside the cache file and move it into place, so a conversion
+	// running at the same time never reads half of it
+	tmp, err := os.CreateTemp(cacheDir, ".houdini-*.jpg")
+	if err != nil {
+		return fmt.Errorf("failed to create conversion file: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	cmd := exec.Command("magick", "-", tmp.Name())
+	cmd.Stdin = input
 	slog.Info("Converting image", "cmd", cmd.String())
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("imagemagick preprocessing failed: %w", err)
+		return fmt.Errorf("imagemagick preprocessing failed: %w", err)
 	}
 
-	convertedData, err := os.ReadFile(cachePath)
+	converted, err := os.Open(tmp.Name())
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	return convertedData, nil
+	err = writeFrom(dest, converted)
+	converted.Close()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), cachePath); err != nil {
+		slog.Warn("Failed to cache Houdini conversion", "error", err)
+	}
+	return nil
 }
 
 // needsHoudiniConversion checks if the image format requires Houdini conversion
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"image/png"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// handleImageRoute dispatches /api/v1/sessions/{id}/images/{imageID}/{action}
+func (h *Handler) handleImageRoute(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, path string) {
+	imageID, action, _ := strings.Cut(path, "/")
+	action, subpath, _ := strings.Cut(action, "/")
+
+	image := findImage(session, imageID)
+	if image == nil {
+		h.writeError(w, "Image not found", http.StatusNotFound)
+		return
+	}
+
+	switch action {
+	case "binarized":
+		h.handleBinarizedPreview(w, r, session, image)
+	case "macro":
+		h.handleApplyMacro(w, r, session, image)
+	case "authority":
+		h.handleWordAuthorityLookup(w, r, image)
+	case "spellcheck":
+		h.handleImageSpellcheck(w, r, session, image)
+	case "provenance":
+		h.handleProvenance(w, r, session, image)
+	case "annotations":
+		h.handleAnnotations(w, r, session, image, subpath)
+	case "artifacts":
+		h.handleArtifacts(w, r, session, image, subpath)
+	case "external-jobs":
+		h.handleExternalJobs(w, r, session, image)
+	case "words":
+		h.handleWords(w, r, session, image, subpath)
+	case "lines":
+		h.handleLines(w, r, session, image, subpath)
+	case "region":
+		h.handleRegionOCR(w, r, session, image)
+	case "ocr":
+		h.handleImageOCR(w, r, session, image)
+	case "complete":
+		h.handleCompletion(w, r, session, image, true)
+	case "reopen":
+		h.handleCompletion(w, r, session, image, false)
+	default:
+		h.writeError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleBinarizedPreview renders the thresholded image word detection would see,
+// using the session's settings overridden by any query parameters
+func (h *Handler) handleBinarizedPreview(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	binarization, err := binarizationFromValues(r.URL.Query(), session.Config.Binarization)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	img, err := h.hocrService.BinarizeImage(h.imageFilePath(image), binarization, session.Config.DocumentType)
+	if err != nil {
+		h.writeError(w, "Failed to binarize image: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	if err := png.Encode(w, img); err != nil {
+		slog.Error("Unable to encode binarized preview", "err", err)
+	}
+}
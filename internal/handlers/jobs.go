@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+var (
+	errJobQueueFull   = errors.New("job queue is full, try again later")
+	errShuttingDown   = errors.New("server is shutting down, try again shortly")
+	errJobInterrupted = errors.New("interrupted by a server shutdown before it finished, submit it again")
+)
+
+// jobFunc does a job's work, returning the session it produced and the payload
+// reported when the job succeeds. What it records in the trace ends up in the
+// diagnostic bundle if it fails.
+type jobFunc func(trace *jobTrace) (sessionID string, result any, err error)
+
+type queuedJob struct {
+	id    string
+	run   jobFunc
+	trace *jobTrace
+}
+
+// startJobWorkers runs JOB_WORKERS (default 2) workers over a queue holding up to
+// JOB_QUEUE_SIZE (default 100) waiting jobs
+func (h *Handler) startJobWorkers() {
+	workers := max(1, utils.GetEnvInt("JOB_WORKERS", 2))
+	h.jobQueue = make(chan queuedJob, max(1, utils.GetEnvInt("JOB_QUEUE_SIZE", 100)))
+	for i := 0; i < workers; i++ {
+		h.workers.Add(1)
+		go func() {
+			defer h.workers.Done()
+			for job := range h.jobQueue {
+				h.runJob(job)
+			}
+		}()
+	}
+}
+
+// enqueueJob records a queued job for a user and hands it to the workers
+func (h *Handler) enqueueJob(kind, user string, run jobFunc) (models.Job, error) {
+	job := newJob(kind, user)
+	h.jobStore.Set(job)
+
+	// Holding the read lock keeps Drain from closing the queue mid-send
+	h.queueMu.RLock()
+	defer h.queueMu.RUnlock()
+	if h.draining {
+		h.finishJob(job.ID, "", nil, errShuttingDown)
+		return models.Job{}, errShuttingDown
+	}
+
+	select {
+	case h.jobQueue <- queuedJob{id: job.ID, run: run, trace: newJobTrace(job.ID)}:
+	default:
+		h.finishJob(job.ID, "", nil, errJobQueueFull)
+		return models.Job{}, errJobQueueFull
+	}
+
+	slog.Info("Job queued", "job_id", job.ID, "kind", kind)
+	return *job, nil
+}
+
+func newJob(kind, user string) *models.Job {
+	return &models.Job{
+		ID:        newJobID(),
+		Kind:      kind,
+		Status:    models.JobQueued,
+		User:      user,
+		CreatedAt: time.Now(),
+	}
+}
+
+func newJobID() string {
+	return fmt.Sprintf("job_%d", time.Now().UnixNano())
+}
+
+// recordFailedJob keeps a failure from work done inside a request, such as a
+// publish, so it gets a diagnostic bundle like a background job would. The job
+// takes the trace's id and start, so what was logged through it is included.
+func (h *Handler) recordFailedJob(kind, user, sessionID string, trace *jobTrace, err error) models.Job {
+	job := newJob(kind, user)
+	job.ID = trace.jobID
+	job.CreatedAt = trace.started
+	job.SessionID = sessionID
+	h.jobStore.Set(job)
+	h.finishJob(job.ID, "", nil, err)
+
+	failed, _ := h.jobStore.Get(job.ID)
+	h.reportJobFailure(failed, trace)
+	failed, _ = h.jobStore.Get(job.ID)
+	return failed
+}
+
+func (h *Handler) runJob(job queuedJob) {
+	started := time.Now()
+	h.jobStore.Update(job.id, func(j *models.Job) {
+		j.Status = models.JobRunning
+		j.StartedAt = &started
+	})
+	h.publishJob(job.id)
+
+	// Retries show on the job while it runs, so a slow job can be told from a stuck one
+	job.trace.onRetry = func(retries int) {
+		h.jobStore.Update(job.id, func(j *models.Job) {
+			j.Retries = retries
+		})
+	}
+	sessionID, result, err := job.run(job.trace)
+	h.finishJob(job.id, sessionID, result, err)
+	defer h.publishJob(job.id)
+
+	if err != nil {
+		failed, _ := h.jobStore.Get(job.id)
+		h.reportJobFailure(failed, job.trace)
+		return
+	}
+	slog.Info("Job succeeded", "job_id", job.id, "session_id", sessionID, "duration", time.Since(started))
+}
+
+func (h *Handler) finishJob(jobID, sessionID string, result any, err error) {
+	finished := time.Now()
+	h.jobStore.Update(jobID, func(j *models.Job) {
+		j.FinishedAt = &finished
+		if sessionID != "" {
+			j.SessionID = sessionID
+		}
+		if err != nil {
+			j.Status = models.JobFailed
+			j.Error = err.Error()
+			return
+		}
+		j.Status = models.JobSucceeded
+		j.Result = result
+	})
+}
+
+// writeJobAccepted answers a request whose work was queued, or explains why it wasn't
+func (h *Handler) writeJobAccepted(w http.ResponseWriter, job models.Job, err error) {
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	statusURL := APIPrefix + "/jobs/" + job.ID
+	w.Header().Set("Location", statusURL)
+	h.writeJSONStatus(w, http.StatusAccepted, JobAccepted{JobID: job.ID, Status: job.Status, StatusURL: statusURL})
+}
+
+// HandleJobs reports the state of a background job at /api/v1/jobs/{id}, and serves
+// the diagnostic bundle of a failed one at /api/v1/jobs/{id}/diagnostics
+func (h *Handler) HandleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, APIPrefix+"/jobs/"), "/")
+	switch action {
+	case "":
+	case "diagnostics":
+		// Bundles outlive the in-memory job list, so they're looked up on disk
+		h.handleJobDiagnostics(w, r, id)
+		return
+	default:
+		h.writeError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	job, ok := h.jobStore.Get(id)
+	if !ok {
+		h.writeError(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	h.writeJSON(w, job)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+func TestJobReportsRetries(t *testing.T) {
+	h := &Handler{jobStore: storage.NewJobStore(), live: newLiveHub()}
+	job := newJob("test", "")
+	h.jobStore.Set(job)
+
+	h.runJob(queuedJob{id: job.ID, trace: newJobTrace(job.ID), run: func(trace *jobTrace) (string, any, error) {
+		// Two OCR calls retried on the job's behalf
+		trace.retried()
+		trace.retried()
+		return "", nil, nil
+	}})
+
+	w := httptest.NewRecorder()
+	h.HandleJobs(w, httptest.NewRequest("GET", APIPrefix+"/jobs/"+job.ID, nil))
+	var got models.Job
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.JobSucceeded || got.Retries != 2 {
+		t.Errorf("job is %s with %d retries, want succeeded with 2", got.Status, got.Retries)
+	}
+}
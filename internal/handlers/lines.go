@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// handleLines reorders, splits, merges and adds lines at
+// /sessions/{id}/images/{imageID}/lines/{order,split,merge,add}, saving the
+// result as the image's corrected hOCR
+func (h *Handler) handleLines(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem, subpath string) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subpath == "add" {
+		h.handleAddLine(w, r, session, image)
+		return
+	}
+
+	lines, err := hocr.ParseHOCRLines(currentHOCR(image))
+	if err != nil {
+		h.writeError(w, "Failed to parse hOCR: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var lineIDs []string
+	switch subpath {
+	case "order":
+		var request ReorderLinesRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		lines, err = hocr.ReorderLines(lines, request.LineIDs)
+		lineIDs = request.LineIDs
+	case "split":
+		var request SplitLineRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		var lineID string
+		lines, lineID, err = hocr.SplitLine(lines, request.LineID, request.WordID)
+		lineIDs = []string{request.LineID, lineID}
+	case "merge":
+		var request MergeLinesRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		lines, err = hocr.MergeLines(lines, request.LineIDs)
+		if len(request.LineIDs) > 0 {
+			lineIDs = request.LineIDs[:1]
+		}
+	default:
+		h.writeError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, hocr.ErrLineNotFound) || errors.Is(err, hocr.ErrWordNotFound) {
+		h.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hocrXML := h.saveEditedLines(r, session, image, lines)
+	if subpath == "order" {
+		// The reading order was fixed by hand, so the QA flag follows the correction
+		image.LineOrder = checkLineOrder(hocrXML, session.Config)
+		h.sessionStore.Set(session.ID, session)
+	}
+	slog.Info("Lines edited", "session_id", session.ID, "image_id", image.ID, "edit", subpath, "line_ids", lineIDs)
+	h.writeJSON(w, LineEditResponse{HOCR: hocrXML, LineIDs: lineIDs})
+}
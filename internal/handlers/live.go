@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/export"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/metrics"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// Live event types pushed over /ws
+const (
+	EventJob         = "job"
+	EventHOCRUpdated = "hocr_updated"
+	EventMetrics     = "metrics"
+)
+
+// ClientIDHeader lets an editor mark its own saves so they aren't echoed back to it
+const ClientIDHeader = "X-Client-ID"
+
+const (
+	livePingInterval = 30 * time.Second
+	liveWriteTimeout = 10 * time.Second
+	// Events queued for a client that stops reading before it is disconnected
+	liveSendBuffer = 64
+)
+
+// LiveEvent is one message pushed to editors watching a session or job
+type LiveEvent struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"session_id,omitempty"`
+	ImageID   string    `json:"image_id,omitempty"`
+	JobID     string    `json:"job_id,omitempty"`
+	User      string    `json:"user,omitempty"`
+	Origin    string    `json:"origin,omitempty"`
+	Data      any       `json:"data,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+type liveClient struct {
+	id   string
+	send chan []byte
+}
+
+// liveHub fans events out to the clients subscribed to each session and job
+type liveHub struct {
+	mu       sync.RWMutex
+	sessions map[string]map[*liveClient]bool
+	jobs     map[string]map[*liveClient]bool
+}
+
+func newLiveHub() *liveHub {
+	return &liveHub{
+		sessions: make(map[string]map[*liveClient]bool),
+		jobs:     make(map[string]map[*liveClient]bool),
+	}
+}
+
+func (hub *liveHub) subscribe(client *liveClient, sessionIDs, jobIDs []string) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for _, id := range sessionIDs {
+		addLiveClient(hub.sessions, id, client)
+	}
+	for _, id := range jobIDs {
+		addLiveClient(hub.jobs, id, client)
+	}
+}
+
+func addLiveClient(topics map[string]map[*liveClient]bool, id string, client *liveClient) {
+	if topics[id] == nil {
+		topics[id] = make(map[*liveClient]bool)
+	}
+	topics[id][client] = true
+}
+
+func (hub *liveHub) unsubscribe(client *liveClient) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for _, topics := range []map[string]map[*liveClient]bool{hub.sessions, hub.jobs} {
+		for id, clients := range topics {
+			delete(clients, client)
+			if len(clients) == 0 {
+				delete(topics, id)
+			}
+		}
+	}
+}
+
+// publish sends an event to everyone watching its session or job except the
+// client that caused it. Clients too slow to keep up miss the event.
+func (hub *liveHub) publish(event LiveEvent) {
+	event.At = time.Now()
+	data, err := json.Marshal(event)
+	if err != nil {
+		slog.Warn("Unable to encode live event", "type", event.Type, "err", err)
+		return
+	}
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	sent := make(map[*liveClient]bool)
+	for _, clients := range []map[*liveClient]bool{hub.sessions[event.SessionID], hub.jobs[event.JobID]} {
+		for client := range clients {
+			if sent[client] || (event.Origin != "" && client.id == event.Origin) {
+				continue
+			}
+			sent[client] = true
+			select {
+			case client.send <- data:
+			default:
+				slog.Warn("Dropping live event for slow client", "type", event.Type, "client", client.id)
+			}
+		}
+	}
+}
+
+var liveUpgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+// HandleWebSocket streams live events for the sessions and jobs named in the
+// session and job query parameters, e.g. /ws?session=abc&job=job_123
+func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if len(query["session"]) == 0 && len(query["job"]) == 0 {
+		h.writeError(w, "session or job is required", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := liveUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written the error response
+		slog.Warn("WebSocket upgrade failed", "err", err)
+		return
+	}
+
+	client := &liveClient{id: query.Get("client"), send: make(chan []byte, liveSendBuffer)}
+	h.live.subscribe(client, query["session"], query["job"])
+	slog.Info("Live client connected", "client", client.id, "sessions", query["session"], "jobs", query["job"])
+
+	done := make(chan struct{})
+	go func() {
+		// Clients only listen, so reading just notices when they go away
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(livePingInterval)
+	defer func() {
+		ticker.Stop()
+		h.live.unsubscribe(client)
+		conn.Close()
+		slog.Info("Live client disconnected", "client", client.id)
+	}()
+
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case data := <-client.send:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			err = conn.WriteMessage(websocket.TextMessage, data)
+		case <-ticker.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteTimeout))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// publishHOCRUpdate tells other editors an image's hOCR changed and pushes the
+// recalculated accuracy metrics for it
+func (h *Handler) publishHOCRUpdate(r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	origin, user := "", ""
+	if r != nil {
+		origin, user = r.Header.Get(ClientIDHeader), requestUser(r)
+	}
+
+	h.live.publish(LiveEvent{
+		Type:      EventHOCRUpdated,
+		SessionID: session.ID,
+		ImageID:   image.ID,
+		User:      user,
+		Origin:    origin,
+		Data: map[string]any{
+			"corrected_hocr": image.CorrectedHOCR,
+			"original_hocr":  image.OriginalHOCR,
+			"completed":      image.Completed,
+		},
+	})
+
+	h.live.publish(LiveEvent{
+		Type:      EventMetrics,
+		SessionID: session.ID,
+		ImageID:   image.ID,
+		User:      user,
+		Origin:    origin,
+		Data:      hocrMetrics(image, session.Config.Language),
+	})
+}
+
+// hocrMetrics compares an image's correction with its OCR, falling back to text
+// only when the hOCR can't be parsed
+func hocrMetrics(image *models.ImageItem, language string) MetricsResponse {
+	response, err := compareHOCR(MetricsRequest{OriginalHOCR: image.OriginalHOCR, CorrectedHOCR: currentHOCR(image), Language: language})
+	if err != nil {
+		return MetricsResponse{EvalResult: metrics.CalculateAccuracyMetrics(hocrPlainText(image.OriginalHOCR, language), hocrPlainText(currentHOCR(image), language), models.NormalizationConfig{})}
+	}
+	return response
+}
+
+// hocrPlainText is the text of an hOCR document, with words hyphenated across
+// lines in the language rejoined
+func hocrPlainText(hocrXML, language string) string {
+	lines, err := hocr.ParseHOCRLines(hocrXML)
+	if err != nil {
+		return ""
+	}
+	return export.PlainText(hocr.MarkHyphenation(lines, language))
+}
+
+// publishJob pushes a job's current state to anyone watching it
+func (h *Handler) publishJob(jobID string) {
+	job, ok := h.jobStore.Get(jobID)
+	if !ok {
+		return
+	}
+	h.live.publish(LiveEvent{Type: EventJob, JobID: job.ID, SessionID: job.SessionID, User: job.User, Data: job})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+// HandleMacros lists and creates correction macros
+func (h *Handler) HandleMacros(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		macros := h.macroStore.Visible(requestUser(r), r.URL.Query().Get("collection"))
+		h.writeJSON(w, macros)
+	case "POST":
+		var macro models.CorrectionMacro
+		if err := json.NewDecoder(r.Body).Decode(&macro); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if macro.Name == "" {
+			h.writeError(w, "name is required", http.StatusBadRequest)
+			return
+		}
+
+		user := requestUser(r)
+		switch macro.Scope {
+		case "", models.MacroScopeUser:
+			if user == "" {
+				h.writeError(w, "User-scoped macros require an authenticated user", http.StatusBadRequest)
+				return
+			}
+			macro.Scope = models.MacroScopeUser
+			macro.Owner = user
+		case models.MacroScopeCollection:
+			if macro.Owner == "" {
+				h.writeError(w, "owner must name the collection for collection-scoped macros", http.StatusBadRequest)
+				return
+			}
+		case models.MacroScopeGlobal:
+			macro.Owner = ""
+		default:
+			h.writeError(w, "Invalid scope: "+macro.Scope, http.StatusBadRequest)
+			return
+		}
+
+		if err := hocr.ValidateMacro(macro); err != nil {
+			h.writeError(w, "Invalid macro: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		macro.ID = fmt.Sprintf("macro_%d", time.Now().UnixNano())
+		macro.CreatedBy = user
+		macro.CreatedAt = time.Now()
+		h.macroStore.Set(&macro)
+
+		slog.Info("Macro created", "macro_id", macro.ID, "name", macro.Name, "scope", macro.Scope)
+		h.writeJSON(w, macro)
+	default:
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleMacroDetail returns or deletes a single macro visible to the user in
+// the collection query parameter. Shared macros are deleted with can_manage_macros.
+func (h *Handler) HandleMacroDetail(w http.ResponseWriter, r *http.Request) {
+	macroID := strings.TrimPrefix(r.URL.Path, APIPrefix+"/macros/")
+	macro, exists := h.macroStore.Get(macroID)
+	if !exists || !storage.MacroVisible(macro, requestUser(r), r.URL.Query().Get("collection")) {
+		h.writeError(w, "Macro not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		h.writeJSON(w, macro)
+	case "DELETE":
+		switch macro.Scope {
+		case models.MacroScopeUser:
+			if macro.Owner != requestUser(r) {
+				h.writeError(w, "Only the owner can delete a user-scoped macro", http.StatusForbidden)
+				return
+			}
+		case models.MacroScopeCollection:
+			if !h.requirePermission(w, r, macro.Owner, auth.ManageMacros) {
+				return
+			}
+		default:
+			if !h.requirePermission(w, r, "", auth.ManageMacros) {
+				return
+			}
+		}
+		h.macroStore.Delete(macroID)
+		h.writeJSON(w, statusSuccess)
+	default:
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleApplyMacro runs a macro against an image's current hOCR and saves the result
+// as the corrected hOCR without marking the image completed
+func (h *Handler) handleApplyMacro(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request ApplyMacroRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	macro, exists := h.macroStore.Get(request.MacroID)
+	if !exists || !storage.MacroVisible(macro, requestUser(r), session.Collection) {
+		h.writeError(w, "Macro not found", http.StatusNotFound)
+		return
+	}
+
+	lines, err := hocr.ParseHOCRLines(currentHOCR(image))
+	if err != nil {
+		h.writeError(w, "Failed to parse hOCR: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lines, changed, err := hocr.ApplyMacro(lines, *macro, request.Region)
+	if err != nil {
+		h.writeError(w, "Failed to apply macro: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hocrXML := sessionConverter(session).ConvertHOCRLinesToXML(lines, image.ImageWidth, image.ImageHeight)
+	if !request.DryRun && changed > 0 {
+		h.parsed.forget(currentHOCR(image))
+		image.CorrectedHOCR = hocrXML
+		h.sessionStore.Set(session.ID, session)
+		h.publishHOCRUpdate(r, session, image)
+	}
+
+	slog.Info("Macro applied", "session_id", session.ID, "image_id", image.ID, "macro_id", macro.ID, "changed", changed, "dry_run", request.DryRun)
+	h.writeJSON(w, ApplyMacroResponse{HOCR: hocrXML, Changed: changed, DryRun: request.DryRun})
+}
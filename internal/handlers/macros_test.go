@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+func TestMacroDetailScopes(t *testing.T) {
+	store, err := storage.NewPersistentMacroStore(filepath.Join(t.TempDir(), "macros.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set(&models.CorrectionMacro{ID: "global", Scope: models.MacroScopeGlobal})
+	store.Set(&models.CorrectionMacro{ID: "mine", Scope: models.MacroScopeUser, Owner: "jdoe"})
+	store.Set(&models.CorrectionMacro{ID: "shared", Scope: models.MacroScopeCollection, Owner: "special"})
+
+	h := &Handler{
+		macroStore: store,
+		permissions: &auth.Policy{
+			Users: map[string][]string{"curator": {"admin"}},
+			Roles: map[string]auth.Grants{"admin": {auth.ManageMacros: true}},
+		},
+	}
+
+	tests := []struct {
+		method, path, user string
+		want               int
+	}{
+		{"GET", "/macros/mine", "jdoe", http.StatusOK},
+		{"GET", "/macros/mine", "other", http.StatusNotFound},
+		{"GET", "/macros/shared", "jdoe", http.StatusNotFound},
+		{"GET", "/macros/shared?collection=special", "jdoe", http.StatusOK},
+		{"DELETE", "/macros/mine", "other", http.StatusNotFound},
+		{"DELETE", "/macros/global", "jdoe", http.StatusForbidden},
+		{"DELETE", "/macros/shared?collection=special", "jdoe", http.StatusForbidden},
+		{"DELETE", "/macros/shared?collection=special", "curator", http.StatusOK},
+		{"DELETE", "/macros/global", "curator", http.StatusOK},
+		{"DELETE", "/macros/mine", "jdoe", http.StatusOK},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, APIPrefix+test.path, nil)
+		r.Header.Set(auth.UserHeader, test.user)
+		w := httptest.NewRecorder()
+		h.HandleMacroDetail(w, r)
+		if w.Code != test.want {
+			t.Errorf("%s %s as %s: got %d, want %d", test.method, test.path, test.user, w.Code, test.want)
+		}
+	}
+}
+
+func TestMacroStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "macros.json")
+	store, err := storage.NewPersistentMacroStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set(&models.CorrectionMacro{ID: "kept", Name: "Long s", Scope: models.MacroScopeGlobal})
+	store.Set(&models.CorrectionMacro{ID: "dropped", Scope: models.MacroScopeGlobal})
+	store.Delete("dropped")
+
+	reloaded, err := storage.NewPersistentMacroStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if macro, ok := reloaded.Get("kept"); !ok || macro.Name != "Long s" {
+		t.Errorf("kept macro reloaded as %+v, %v", macro, ok)
+	}
+	if _, ok := reloaded.Get("dropped"); ok {
+		t.Error("deleted macro came back")
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// imageProgress ranks how much work an image holds, so de-duplication keeps the
+// copy someone has already corrected
+func imageProgress(image *models.ImageItem) int {
+	switch {
+	case image.PublishedAt != nil:
+		return 3
+	case image.Completed:
+		return 2
+	case image.CorrectedHOCR != "":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// mergeImages combines two ordered image lists, dropping repeats of the same
+// upload and renumbering the result. It returns the old-to-new ID mapping for
+// each input list.
+func mergeImages(first, second []models.ImageItem) ([]models.ImageItem, map[string]string, map[string]string) {
+	merged := make([]models.ImageItem, 0, len(first)+len(second))
+	byHash := map[string]int{}
+	firstIDs := map[string]string{}
+	secondIDs := map[string]string{}
+
+	add := func(image models.ImageItem, ids map[string]string) {
+		oldID := image.ID
+		hash := imageHash(&image)
+		if i, seen := byHash[hash]; seen {
+			if imageProgress(&image) > imageProgress(&merged[i]) {
+				image.ID = merged[i].ID
+				merged[i] = image
+			}
+			ids[oldID] = merged[i].ID
+			return
+		}
+
+		image.ID = fmt.Sprintf("img_%d", len(merged)+1)
+		byHash[hash] = len(merged)
+		merged = append(merged, image)
+		ids[oldID] = image.ID
+	}
+
+	for _, image := range first {
+		add(image, firstIDs)
+	}
+	for _, image := range second {
+		add(image, secondIDs)
+	}
+
+	return merged, firstIDs, secondIDs
+}
+
+// handleMerge folds another session's pages into this one, e.g. when a volume was
+// uploaded over several days. Pages are appended (or prepended with
+// "position": "prepend"), duplicates of the same image are collapsed and the
+// source session is deleted unless keep_source is set.
+func (h *Handler) handleMerge(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.SourceSessionID == "" || request.SourceSessionID == session.ID {
+		h.writeError(w, "source_session_id must name another session", http.StatusBadRequest)
+		return
+	}
+	if request.Position != "" && request.Position != "append" && request.Position != "prepend" {
+		h.writeError(w, "position must be append or prepend", http.StatusBadRequest)
+		return
+	}
+
+	source, ok := h.getSessionOrError(w, request.SourceSessionID)
+	if !ok {
+		return
+	}
+	if !request.KeepSource && !h.requirePermission(w, r, source.Collection, auth.DeleteSession) {
+		return
+	}
+
+	// Pages keep the rights they had in their own session
+	sourceImages := cloneSession(source).Images
+	for i := range sourceImages {
+		rights := effectiveRights(source, &sourceImages[i])
+		if rights != session.Rights {
+			sourceImages[i].Rights = &rights
+		}
+	}
+
+	first, second := session.Images, sourceImages
+	if request.Position == "prepend" {
+		first, second = sourceImages, session.Images
+	}
+
+	merged, firstIDs, secondIDs := mergeImages(first, second)
+	targetIDs, sourceIDs := firstIDs, secondIDs
+	if request.Position == "prepend" {
+		targetIDs, sourceIDs = secondIDs, firstIDs
+	}
+
+	if err := checkSandboxPages(len(merged)); err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	before := len(session.Images)
+	session.Images = merged
+	session.Current = 0
+	h.sessionStore.Set(session.ID, session)
+
+	h.externalJobStore.Reassign(session.ID, session.ID, targetIDs)
+	h.externalJobStore.Reassign(source.ID, session.ID, sourceIDs)
+
+	if !request.KeepSource {
+		h.sessionStore.Delete(source.ID)
+	}
+
+	slog.Info("Sessions merged", "session_id", session.ID, "source_id", source.ID, "images", len(merged),
+		"duplicates", before+len(source.Images)-len(merged), "source_deleted", !request.KeepSource)
+	h.writeJSON(w, MergeResponse{
+		Session:    session,
+		Duplicates: before + len(source.Images) - len(merged),
+		ImageIDs:   map[string]map[string]string{session.ID: targetIDs, source.ID: sourceIDs},
+	})
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// pdfRasterDensity is the DPI PDF pages are rendered at before OCR
+const pdfRasterDensity = "300"
+
+// isMultiPageFormat reports whether a file may hold several pages that need splitting
+func isMultiPageFormat(contentType, filename string) bool {
+	switch contentType {
+	case "application/pdf", "image/tiff", "image/tif":
+		return true
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pdf", ".tif", ".tiff":
+		return true
+	}
+
+	return false
+}
+
+func isPDF(contentType, filename string) bool {
+	return contentType == "application/pdf" || strings.EqualFold(filepath.Ext(filename), ".pdf")
+}
+
+// splitPages renders every page of a PDF or frame of a TIFF at inputPath to
+// its own JPEG, working in a directory under tempDir
+func splitPages(tempDir, inputPath, filename string, pdf bool) ([][]byte, error) {
+	workDir, err := os.MkdirTemp(tempDir, "pages_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page workspace: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	// The input is named for the spool rather than its type, so the format is given
+	format := "tiff:"
+	var args []string
+	if pdf {
+		format = "pdf:"
+		args = append(args, "-density", pdfRasterDensity)
+	}
+	args = append(args, format+inputPath, "-background", "white", "-alpha", "remove", "+adjoin", "-quality", "92",
+		filepath.Join(workDir, "page_%04d.jpg"))
+
+	cmd := exec.Command("magick", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to split pages: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	pagePaths, err := filepath.Glob(filepath.Join(workDir, "page_*.jpg"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(pagePaths)
+
+	pages := make([][]byte, 0, len(pagePaths))
+	for _, pagePath := range pagePaths {
+		page, err := os.ReadFile(pagePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read split page: %w", err)
+		}
+		pages = append(pages, page)
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages found in %s", filename)
+	}
+
+	return pages, nil
+}
+
+// processUploadedFile stores and OCRs an uploaded file, splitting PDFs and multi-page
+// TIFFs so each page becomes its own image
+func (h *Handler) processUploadedFile(file *spooledFile, filename string, config SessionConfig) ([]*ImageProcessResult, error) {
+	if !isMultiPageFormat("", filename) {
+		result, err := h.processImageFile(file, filename, config)
+		if err != nil {
+			return nil, err
+		}
+		return []*ImageProcessResult{result}, nil
+	}
+
+	pdf := isPDF("", filename)
+	pages, err := splitPages(h.dirs.Temp, file.path, filename, pdf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep single-frame TIFFs as uploaded
+	if len(pages) == 1 && !pdf {
+		result, err := h.processImageFile(file, filename, config)
+		if err != nil {
+			return nil, err
+		}
+		return []*ImageProcessResult{result}, nil
+	}
+
+	return h.processPages(pages, filename, config)
+}
+
+// processImagesFromFile is the URL counterpart of processUploadedFile
+func (h *Handler) processImagesFromFile(file *spooledFile, contentType, sourceURL string, config SessionConfig) ([]*ImageProcessResult, error) {
+	if isMultiPageFormat(contentType, sourceURL) {
+		pdf := isPDF(contentType, sourceURL)
+		pages, err := splitPages(h.dirs.Temp, file.path, sourceURL, pdf)
+		if err != nil {
+			return nil, err
+		}
+		if len(pages) > 1 || pdf {
+			return h.processPages(pages, sourceURL, config)
+		}
+	}
+
+	result, err := h.processImageFromFile(file, contentType, sourceURL, config)
+	if err != nil {
+		return nil, err
+	}
+	return []*ImageProcessResult{result}, nil
+}
+
+func (h *Handler) processPages(pages [][]byte, source string, config SessionConfig) ([]*ImageProcessResult, error) {
+	if err := checkSandboxPages(len(pages)); err != nil {
+		return nil, err
+	}
+	slog.Info("Processing multi-page document", "source", source, "pages", len(pages))
+
+	results := make([]*ImageProcessResult, len(pages))
+	err := h.pages.each(len(pages), func(i int) error {
+		// The page fragment keeps the source's extension from triggering another conversion
+		pageSource := fmt.Sprintf("%s#page=%d", source, i+1)
+		result, err := h.processImageFromData(pages[i], "image/jpeg", pageSource, config)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", i+1, err)
+		}
+		results[i] = result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// normalizeImage rewrites an ingested image upright, and in sRGB where its color
+// profile can be converted, so the file word detection runs on, the editor
+// displays, and hOCR coordinates refer to are the same pixels. Phone captures otherwise carry an EXIF rotation only some readers
+// honor. The untouched upload is kept at originalPath.
+func normalizeImage(imagePath, originalPath string) error {
+	cmd := exec.Command("magick", "identify", "-format", "%[orientation]|%[profiles]|", imagePath)
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to inspect image: %w", err)
+	}
+
+	// Multi-frame files print once per frame; the first is what gets processed
+	fields := strings.Split(strings.TrimSpace(string(output)), "|")
+	orientation := fields[0]
+	profiles := ""
+	if len(fields) > 1 {
+		profiles = fields[1]
+	}
+
+	rotated := orientation != "" && orientation != "TopLeft" && orientation != "Undefined"
+	hasICC := strings.Contains(strings.ToLower(profiles), "icc")
+	args := normalizeArgs(originalPath, imagePath, rotated, hasICC, os.Getenv("SRGB_ICC_PROFILE"))
+	if args == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(originalPath), 0755); err != nil {
+		return fmt.Errorf("failed to create originals directory: %w", err)
+	}
+	if err := os.Rename(imagePath, originalPath); err != nil {
+		return fmt.Errorf("failed to keep original image: %w", err)
+	}
+
+	if output, err := exec.Command("magick", args...).CombinedOutput(); err != nil {
+		// Put the original back so ingest can carry on with it
+		if restoreErr := os.Rename(originalPath, imagePath); restoreErr != nil {
+			slog.Error("Failed to restore original image", "path", imagePath, "err", restoreErr)
+		}
+		return fmt.Errorf("failed to normalize image: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	slog.Info("Normalized image orientation and color", "path", imagePath, "orientation", orientation, "icc", hasICC)
+	return nil
+}
+
+// normalizeArgs are the ImageMagick arguments rewriting originalPath to
+// imagePath, or nil when the image needs no rewrite. An embedded color profile
+// is only converted through when srgbProfile names the sRGB profile to convert
+// to. Otherwise it is kept, since dropping it without converting shifts the
+// colors of Adobe RGB and other wide-gamut scans.
+func normalizeArgs(originalPath, imagePath string, rotated, hasICC bool, srgbProfile string) []string {
+	convert := hasICC && srgbProfile != ""
+	if !rotated && !convert {
+		return nil
+	}
+
+	args := []string{originalPath + "[0]", "-auto-orient"}
+	switch {
+	case convert:
+		args = append(args, "-profile", srgbProfile, "-strip")
+	case hasICC:
+		args = append(args, "+profile", "!icc,*")
+	default:
+		args = append(args, "-colorspace", "sRGB", "-strip")
+	}
+	return append(args, imagePath)
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeArgsKeepUnconvertedProfiles(t *testing.T) {
+	tests := []struct {
+		name            string
+		rotated, hasICC bool
+		srgbProfile     string
+		want            []string
+	}{
+		{"upright without a profile", false, false, "", nil},
+		{"profile with nothing to convert it to", false, true, "", nil},
+		{"rotated with a kept profile", true, true, "", []string{"in.jpg[0]", "-auto-orient", "+profile", "!icc,*", "out.jpg"}},
+		{"profile converted to sRGB", false, true, "/icc/sRGB.icc", []string{"in.jpg[0]", "-auto-orient", "-profile", "/icc/sRGB.icc", "-strip", "out.jpg"}},
+		{"rotated without a profile", true, false, "/icc/sRGB.icc", []string{"in.jpg[0]", "-auto-orient", "-colorspace", "sRGB", "-strip", "out.jpg"}},
+	}
+	for _, test := range tests {
+		got := normalizeArgs("in.jpg", "out.jpg", test.rotated, test.hasICC, test.srgbProfile)
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/accessibility"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/metrics"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/openapi"
+)
+
+// apiVersion is the version of the HTTP API described at /api/v1/openapi.json
+const apiVersion = "1.0.0"
+
+// apiRoute describes one operation for the OpenAPI document
+type apiRoute struct {
+	ID      string
+	Method  string
+	Path    string
+	Summary string
+	Query   []string
+	// Request and Form are the JSON and multipart bodies; Raw is the content
+	// type of any other body
+	Request any
+	Form    any
+	Raw     string
+	// Status is the success status, 200 when zero
+	Status   int
+	Response any
+	// Produces is the content type of a response that isn't JSON
+	Produces string
+}
+
+var apiRoutes = []apiRoute{
+	{ID: "listSessions", Method: "GET", Path: "/sessions", Summary: "List sessions", Response: []models.CorrectionSession{}},
+	{ID: "getSession", Method: "GET", Path: "/sessions/{session_id}", Summary: "Get a session", Response: models.CorrectionSession{}},
+	{ID: "updateSession", Method: "PUT", Path: "/sessions/{session_id}", Summary: "Replace a session", Request: models.CorrectionSession{}, Response: models.CorrectionSession{}},
+	{ID: "deleteSession", Method: "DELETE", Path: "/sessions/{session_id}", Summary: "Delete a session", Response: StatusResponse{}},
+	{ID: "calculateMetrics", Method: "POST", Path: "/sessions/{session_id}/metrics", Summary: "Compare two transcriptions", Request: MetricsRequest{}, Response: MetricsResponse{}},
+	{ID: "getRights", Method: "GET", Path: "/sessions/{session_id}/rights", Summary: "Get session and image rights", Response: RightsResponse{}},
+	{ID: "setRights", Method: "PUT", Path: "/sessions/{session_id}/rights", Summary: "Set session or image rights", Request: RightsRequest{}, Response: StatusResponse{}},
+	{ID: "publishSession", Method: "POST", Path: "/sessions/{session_id}/publish", Summary: "Publish an image's hOCR to Drupal, Fedora or OCFL", Request: PublishRequest{}, Response: PublishResponse{}},
+	{ID: "cloneSession", Method: "POST", Path: "/sessions/{session_id}/clone", Summary: "Branch a session, re-running OCR when a config is given", Request: CloneRequest{}, Status: http.StatusAccepted, Response: JobAccepted{}},
+	{ID: "mergeSessions", Method: "POST", Path: "/sessions/{session_id}/merge", Summary: "Merge another session into this one", Request: MergeRequest{}, Response: MergeResponse{}},
+	{ID: "getContactSheet", Method: "GET", Path: "/sessions/{session_id}/contact-sheet", Summary: "Render page thumbnails", Query: []string{"format"}, Produces: "image/png"},
+	{ID: "exportSession", Method: "GET", Path: "/sessions/{session_id}/export", Summary: "Export transcriptions as text, hOCR or JSON", Query: []string{"format", "image_id", "transliterate"}, Response: SessionExport{}},
+	{ID: "getSessionSummary", Method: "GET", Path: "/sessions/{session_id}/summary", Summary: "Summarize progress and statistics", Response: SessionSummary{}},
+	{ID: "checkSessionAccessibility", Method: "GET", Path: "/sessions/{session_id}/accessibility", Summary: "Check pages against accessibility criteria", Query: []string{"image_id"}, Response: AccessibilityResponse{}},
+	{ID: "getMetricsReport", Method: "GET", Path: "/sessions/{session_id}/report", Summary: "Accuracy metrics for every image as JSON or CSV", Query: []string{"format"}, Response: MetricsReport{}},
+	{ID: "getReviewQueue", Method: "GET", Path: "/sessions/{session_id}/review-queue", Summary: "Words most in need of review, most suspicious first", Query: []string{"threshold", "limit", "lexicons", "compare", "include_completed"}, Response: ReviewQueue{}},
+	{ID: "getConfidenceCalibration", Method: "GET", Path: "/sessions/{session_id}/calibration", Summary: "Correction rates by engine confidence on completed pages", Query: []string{"bucket_width"}, Response: metrics.Calibration{}},
+	{ID: "getCallback", Method: "GET", Path: "/sessions/{session_id}/callback", Summary: "Get the session's completion callback", Response: models.Callback{}},
+	{ID: "setCallback", Method: "PUT", Path: "/sessions/{session_id}/callback", Summary: "Register a URL notified when every image is completed", Request: CallbackRequest{}, Response: models.Callback{}},
+	{ID: "deleteCallback", Method: "DELETE", Path: "/sessions/{session_id}/callback", Summary: "Remove the session's completion callback", Response: StatusResponse{}},
+	{ID: "getDrupalSync", Method: "GET", Path: "/sessions/{session_id}/sync", Summary: "Whether each Drupal image's hOCR has been uploaded", Query: []string{"state"}, Response: DrupalSyncReport{}},
+	{ID: "retryDrupalSync", Method: "POST", Path: "/sessions/{session_id}/sync/retry", Summary: "Upload failed images to Drupal again", Request: DrupalSyncRetryRequest{}, Status: http.StatusAccepted, Response: JobAccepted{}},
+	{ID: "getBinarizedImage", Method: "GET", Path: "/sessions/{session_id}/images/{image_id}/binarized", Summary: "Preview the binarized image", Query: []string{"binarization", "threshold", "window_size", "k"}, Produces: "image/png"},
+	{ID: "applyMacro", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/macro", Summary: "Apply a correction macro", Request: ApplyMacroRequest{}, Response: ApplyMacroResponse{}},
+	{ID: "lookupWordAuthority", Method: "GET", Path: "/sessions/{session_id}/images/{image_id}/authority", Summary: "Look up the phrase formed by words", Query: []string{"word_ids", "source"}, Response: AuthorityLookupResponse{}},
+	{ID: "spellcheckImage", Method: "GET", Path: "/sessions/{session_id}/images/{image_id}/spellcheck", Summary: "Flag misspelled words with suggestions", Query: []string{"lexicons", "limit"}, Response: SpellcheckResponse{}},
+	{ID: "getProvenance", Method: "GET", Path: "/sessions/{session_id}/images/{image_id}/provenance", Summary: "Where each word came from and whether the engines agreed on it", Query: []string{"compare"}, Response: ImageProvenance{}},
+	{ID: "listAnnotations", Method: "GET", Path: "/sessions/{session_id}/images/{image_id}/annotations", Summary: "List authority annotations", Query: []string{"format"}, Response: []models.Annotation{}},
+	{ID: "createAnnotation", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/annotations", Summary: "Link words to an authority", Request: models.Annotation{}, Response: models.Annotation{}},
+	{ID: "deleteAnnotation", Method: "DELETE", Path: "/sessions/{session_id}/images/{image_id}/annotations/{annotation_id}", Summary: "Delete an annotation", Response: StatusResponse{}},
+	{ID: "listArtifacts", Method: "GET", Path: "/sessions/{session_id}/images/{image_id}/artifacts", Summary: "List archived engine output", Response: ArtifactManifest{}},
+	{ID: "getArtifact", Method: "GET", Path: "/sessions/{session_id}/images/{image_id}/artifacts/{run_id}/{name}", Summary: "Download archived engine output", Produces: "application/octet-stream"},
+	{ID: "listExternalJobs", Method: "GET", Path: "/sessions/{session_id}/images/{image_id}/external-jobs", Summary: "List asynchronous engine jobs", Response: []models.ExternalJob{}},
+	{ID: "createExternalJob", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/external-jobs", Summary: "Register OCR submitted to an asynchronous engine", Request: ExternalJobRequest{}, Status: http.StatusCreated, Response: ExternalJobCreated{}},
+	{ID: "splitWord", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/words/split", Summary: "Split a word in two at an x coordinate, or a y on a vertical line", Request: SplitWordRequest{}, Response: WordEditResponse{}},
+	{ID: "mergeWords", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/words/merge", Summary: "Merge adjacent words of a line into one", Request: MergeWordsRequest{}, Response: WordEditResponse{}},
+	{ID: "moveWord", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/words/move", Summary: "Move a word to another line", Request: MoveWordRequest{}, Response: WordEditResponse{}},
+	{ID: "addWord", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/words/add", Summary: "Add a word the detector missed; without text it is read by OCR in a job", Request: AddWordRequest{}, Response: WordEditResponse{}},
+	{ID: "updateWord", Method: "PATCH", Path: "/sessions/{session_id}/images/{image_id}/words/{word_id}", Summary: "Change a word's box or text", Request: UpdateWordRequest{}, Response: WordEditResponse{}},
+	{ID: "reorderLines", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/lines/order", Summary: "Put the lines in a new reading order", Request: ReorderLinesRequest{}, Response: LineEditResponse{}},
+	{ID: "splitLine", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/lines/split", Summary: "Start a new line at a word", Request: SplitLineRequest{}, Response: LineEditResponse{}},
+	{ID: "mergeLines", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/lines/merge", Summary: "Merge lines into the first of them", Request: MergeLinesRequest{}, Response: LineEditResponse{}},
+	{ID: "addLine", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/lines/add", Summary: "Add a line the detector missed; without text it is read by OCR in a job", Request: AddLineRequest{}, Response: LineEditResponse{}},
+	{ID: "regenerateOCR", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/ocr", Summary: "Run OCR on an image again, bypassing the caches, and replace its hOCR", Request: RegenerateOCRRequest{}, Status: http.StatusAccepted, Response: JobAccepted{}},
+	{ID: "invalidateOCRCache", Method: "DELETE", Path: "/sessions/{session_id}/images/{image_id}/ocr", Summary: "Drop an image's cached hOCR so it is read again next time", Query: []string{"engine"}, Response: OCRCacheInvalidation{}},
+	{ID: "ocrRegion", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/region", Summary: "Read one region of an image again and splice it into the hOCR", Request: RegionOCRRequest{}, Status: http.StatusAccepted, Response: JobAccepted{}},
+	{ID: "completeImage", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/complete", Summary: "Mark an image completed", Response: StatusResponse{}},
+	{ID: "reopenImage", Method: "POST", Path: "/sessions/{session_id}/images/{image_id}/reopen", Summary: "Mark a completed image in progress again", Response: StatusResponse{}},
+	{ID: "getPublicSession", Method: "GET", Path: "/public/sessions/{session_id}", Summary: "Get the publicly viewable pages of a session", Response: PublicSession{}},
+	{ID: "listMacros", Method: "GET", Path: "/macros", Summary: "List visible macros", Query: []string{"collection"}, Response: []models.CorrectionMacro{}},
+	{ID: "createMacro", Method: "POST", Path: "/macros", Summary: "Create a macro", Request: models.CorrectionMacro{}, Response: models.CorrectionMacro{}},
+	{ID: "getMacro", Method: "GET", Path: "/macros/{macro_id}", Summary: "Get a macro", Query: []string{"collection"}, Response: models.CorrectionMacro{}},
+	{ID: "deleteMacro", Method: "DELETE", Path: "/macros/{macro_id}", Summary: "Delete a macro", Query: []string{"collection"}, Response: StatusResponse{}},
+	{ID: "lookupAuthority", Method: "GET", Path: "/authority", Summary: "Search authority files", Query: []string{"q", "source"}, Response: AuthorityLookupResponse{}},
+	{ID: "startPrefetch", Method: "POST", Path: "/prefetch", Summary: "Download and convert images ahead of time", Request: PrefetchRequest{}, Status: http.StatusAccepted, Response: PrefetchAccepted{}},
+	{ID: "getPrefetch", Method: "GET", Path: "/prefetch/{prefetch_id}", Summary: "Get prefetch progress", Response: models.PrefetchRun{}},
+	{ID: "getTiles", Method: "GET", Path: "/tiles/{name}", Summary: "Deep Zoom descriptor ({hash}.dzi) or tile", Produces: "application/octet-stream"},
+	{ID: "receiveOCRWebhook", Method: "POST", Path: "/webhooks/ocr/{job_id}", Summary: "Completion callback from an asynchronous engine", Request: OCRWebhookPayload{}, Response: models.ExternalJob{}},
+	{ID: "getConfig", Method: "GET", Path: "/config", Summary: "Deployment profile and available engines", Response: ConfigResponse{}},
+	{ID: "getPermissions", Method: "GET", Path: "/permissions", Summary: "The caller's effective permissions", Query: []string{"collection"}, Response: PermissionsResponse{}},
+	{ID: "checkAccessibility", Method: "POST", Path: "/accessibility/check", Summary: "Check an HTML, EPUB or PDF export", Query: []string{"format"}, Raw: "application/octet-stream", Response: accessibility.Report{}},
+	{ID: "listQA", Method: "GET", Path: "/qa", Summary: "Pages flagged by automatic checks", Query: []string{"collection"}, Response: QAResponse{}},
+	{ID: "getSpellcheck", Method: "GET", Path: "/spellcheck", Summary: "Spellcheck dictionary size and lexicons", Response: SpellcheckInfo{}},
+	{ID: "spellcheck", Method: "POST", Path: "/spellcheck", Summary: "Flag misspelled words with suggestions", Request: SpellcheckRequest{}, Response: SpellcheckResponse{}},
+	{ID: "upload", Method: "POST", Path: "/upload", Summary: "Upload a file or image URL for OCR", Form: UploadForm{}, Request: UploadURLRequest{}, Status: http.StatusAccepted, Response: JobAccepted{}},
+	{ID: "uploadBatch", Method: "POST", Path: "/upload/batch", Summary: "Upload several files or URLs", Form: BatchUploadForm{}, Request: BatchUploadRequest{}, Status: http.StatusAccepted, Response: BatchUploadResponse{}},
+	{ID: "estimateCost", Method: "POST", Path: "/estimate", Summary: "Estimate the LLM tokens and cost of transcribing files, without calling it", Form: UploadForm{}, Status: http.StatusAccepted, Response: JobAccepted{}},
+	{ID: "loadDrupalBook", Method: "POST", Path: "/drupal/books", Summary: "Load every child page of a Drupal node", Request: DrupalBookRequest{}, Status: http.StatusAccepted, Response: BatchUploadResponse{}},
+	{ID: "listDrupalSync", Method: "GET", Path: "/drupal/sync", Summary: "Drupal images of every session by sync state", Query: []string{"state", "collection"}, Response: DrupalSyncOverview{}},
+	{ID: "loadRepositoryImages", Method: "POST", Path: "/repository/sessions", Summary: "Load page images from Fedora or OCFL", Request: RepositoryRequest{}, Status: http.StatusAccepted, Response: BatchUploadResponse{}},
+	{ID: "getJob", Method: "GET", Path: "/jobs/{job_id}", Summary: "Get a background job", Response: models.Job{}},
+	{ID: "getJobDiagnostics", Method: "GET", Path: "/jobs/{job_id}/diagnostics", Summary: "Download a failed job's diagnostic bundle", Response: DiagnosticBundle{}},
+	{ID: "parseHOCR", Method: "POST", Path: "/hocr/parse", Summary: "Parse hOCR into words", Request: HOCRParseRequest{}, Response: HOCRParseResponse{}},
+	{ID: "updateHOCR", Method: "POST", Path: "/hocr/update", Summary: "Save an image's corrected hOCR", Request: HOCRUpdateRequest{}, Response: StatusResponse{}},
+	{ID: "alignTexts", Method: "POST", Path: "/alignment", Summary: "Align two texts into equal, substitute, insert and delete spans", Request: AlignmentRequest{}, Response: AlignmentResponse{}},
+	{ID: "previewStorageCleanup", Method: "GET", Path: "/admin/storage", Summary: "What a cleanup of unreferenced stored files would remove", Query: []string{"retention_hours"}, Response: StorageCleanup{}},
+	{ID: "cleanStorage", Method: "POST", Path: "/admin/storage", Summary: "Remove stored files no session refers to", Query: []string{"retention_hours"}, Response: StorageCleanup{}},
+	{ID: "downloadBackup", Method: "GET", Path: "/admin/backup", Summary: "Archive sessions, jobs, uploads and caches for restoring on another host", Query: []string{"cache"}, Produces: "application/gzip"},
+	{ID: "getAggregateMetrics", Method: "GET", Path: "/admin/metrics", Summary: "Accuracy and correction effort across sessions", Query: []string{"collection", "from", "to", "group"}, Response: AggregateMetrics{}},
+	{ID: "getOpenAPI", Method: "GET", Path: "/openapi.json", Summary: "This document", Response: map[string]any{}},
+}
+
+var pathParam = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// buildOpenAPI generates the document from the route table and the API types
+func buildOpenAPI() openapi.Document {
+	builder := openapi.New(openapi.Info{
+		Title:       "hOCRedit",
+		Version:     apiVersion,
+		Description: "Upload page images for OCR, correct the hOCR and publish it. Errors are returned as plain text.",
+	})
+
+	for _, route := range apiRoutes {
+		operation := &openapi.Operation{
+			OperationID: route.ID,
+			Summary:     route.Summary,
+			Tags:        []string{strings.Split(strings.TrimPrefix(route.Path, "/"), "/")[0]},
+			Responses:   map[string]openapi.Response{},
+		}
+
+		for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			operation.Parameters = append(operation.Parameters, openapi.Parameter{
+				Name: match[1], In: "path", Required: true, Schema: &openapi.Schema{Type: "string"},
+			})
+		}
+		for _, name := range route.Query {
+			operation.Parameters = append(operation.Parameters, openapi.Parameter{
+				Name: name, In: "query", Schema: &openapi.Schema{Type: "string"},
+			})
+		}
+
+		content := map[string]openapi.MediaType{}
+		if route.Request != nil {
+			content["application/json"] = openapi.MediaType{Schema: builder.SchemaFor(route.Request)}
+		}
+		if route.Form != nil {
+			content["multipart/form-data"] = openapi.MediaType{Schema: builder.SchemaFor(route.Form)}
+		}
+		if route.Raw != "" {
+			content[route.Raw] = openapi.MediaType{Schema: &openapi.Schema{Type: "string", Format: "binary"}}
+		}
+		if len(content) > 0 {
+			operation.RequestBody = &openapi.RequestBody{Required: true, Content: content}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := openapi.Response{Description: http.StatusText(status), Content: map[string]openapi.MediaType{}}
+		if route.Response != nil {
+			success.Content["application/json"] = openapi.MediaType{Schema: builder.SchemaFor(route.Response)}
+		}
+		if route.Produces != "" {
+			success.Content[route.Produces] = openapi.MediaType{Schema: &openapi.Schema{Type: "string", Format: "binary"}}
+		}
+		operation.Responses[strconv.Itoa(status)] = success
+		operation.Responses["default"] = openapi.Response{
+			Description: "Error",
+			Content:     map[string]openapi.MediaType{"text/plain": {Schema: &openapi.Schema{Type: "string"}}},
+		}
+
+		builder.Add(route.Method, route.Path, operation)
+	}
+
+	doc := builder.Document()
+	doc.Servers = []openapi.Server{{URL: APIPrefix}}
+	return doc
+}
+
+var (
+	openAPIOnce     sync.Once
+	openAPIDocument openapi.Document
+)
+
+// HandleOpenAPI serves the OpenAPI 3 description of the JSON API
+func (h *Handler) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	openAPIOnce.Do(func() {
+		openAPIDocument = buildOpenAPI()
+	})
+	h.writeJSON(w, openAPIDocument)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// parseCache keeps the words of recently parsed hOCR by the digest of the
+// document, since the editor asks for the same large page to be parsed again
+// on every interaction. Beyond HOCR_PARSE_CACHE_SIZE documents (default 32)
+// the least recently used is dropped. The words are shared, so callers don't
+// modify them.
+type parseCache struct {
+	mu      sync.Mutex
+	limit   int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type parsedHOCR struct {
+	digest string
+	words  []models.HOCRWord
+}
+
+func newParseCache() *parseCache {
+	return &parseCache{
+		limit:   max(1, utils.GetEnvInt("HOCR_PARSE_CACHE_SIZE", 32)),
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// words parses hocrXML, or returns the words of the last time it was parsed.
+// Documents that fail to parse aren't kept.
+func (c *parseCache) words(hocrXML string) ([]models.HOCRWord, error) {
+	digest := storage.ContentDigest([]byte(hocrXML))
+	c.mu.Lock()
+	if entry, ok := c.entries[digest]; ok {
+		c.order.MoveToFront(entry)
+		c.mu.Unlock()
+		return entry.Value.(*parsedHOCR).words, nil
+	}
+	c.mu.Unlock()
+
+	// Parsed outside the lock, so a large page doesn't hold up the others
+	words, err := hocr.ParseHOCRWords(hocrXML)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[digest]; !ok {
+		c.entries[digest] = c.order.PushFront(&parsedHOCR{digest: digest, words: words})
+		for c.order.Len() > c.limit {
+			oldest := c.order.Remove(c.order.Back()).(*parsedHOCR)
+			delete(c.entries, oldest.digest)
+		}
+	}
+	return words, nil
+}
+
+// forget drops the words of hOCR an update has replaced, which the editor
+// won't ask for again
+func (c *parseCache) forget(hocrXML string) {
+	digest := storage.ContentDigest([]byte(hocrXML))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[digest]; ok {
+		c.order.Remove(entry)
+		delete(c.entries, digest)
+	}
+}
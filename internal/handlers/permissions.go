@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// newPermissionPolicy loads PERMISSIONS_FILE. Without one every user may take every action.
+func newPermissionPolicy() *auth.Policy {
+	path := os.Getenv("PERMISSIONS_FILE")
+	if path == "" {
+		return nil
+	}
+
+	policy, err := auth.LoadPolicy(path)
+	if err != nil {
+		utils.ExitOnError("Unable to load permissions", err)
+	}
+	return policy
+}
+
+// newAuthenticators enables the AUTH_PROVIDERS methods. Without any, the API is
+// as open as the network in front of it and every caller is anonymous.
+func newAuthenticators() auth.Authenticators {
+	authenticators, err := auth.NewAuthenticators()
+	if err != nil {
+		utils.ExitOnError("Unable to configure authentication", err)
+	}
+	if authenticators == nil {
+		slog.Warn("AUTH_PROVIDERS is not set, the API is not authenticated and X-Remote-User headers are ignored")
+	}
+	return authenticators
+}
+
+// publicRoutes lists API patterns, relative to the API prefix, that answer
+// without credentials. AUTH_PUBLIC_ROUTES replaces the default of published
+// sessions, signed OCR callbacks and the OpenAPI document.
+func publicRoutes() map[string]bool {
+	routes := os.Getenv("AUTH_PUBLIC_ROUTES")
+	if routes == "" {
+		routes = "/public/sessions/,/webhooks/ocr/,/openapi.json"
+	}
+
+	public := make(map[string]bool)
+	for _, route := range strings.Split(routes, ",") {
+		if route = strings.TrimSpace(route); route != "" {
+			public[route] = true
+		}
+	}
+	return public
+}
+
+// authenticate identifies the caller before a route runs and rejects requests
+// to protected routes that carry no valid credentials. The identity is passed
+// on as the X-Remote-User and X-Remote-Roles headers the permission policy
+// reads, after dropping any the client sent itself when no proxy is trusted.
+func (h *Handler) authenticate(public bool, handle http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.authenticators.TrustsProxy() {
+			r.Header.Del(auth.UserHeader)
+			r.Header.Del(auth.RolesHeader)
+		}
+		if h.authenticators == nil {
+			handle(w, r)
+			return
+		}
+
+		identity, ok, err := h.authenticators.Authenticate(r)
+		if err != nil {
+			slog.Warn("Authentication failed", "path", r.URL.Path, "err", err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			h.writeError(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !ok && !public {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			h.writeError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if ok && identity.Method != "proxy" {
+			r.Header.Set(auth.UserHeader, identity.User)
+			r.Header.Set(auth.RolesHeader, strings.Join(identity.Roles, ","))
+		}
+		handle(w, r)
+	}
+}
+
+// requirePermission writes a 403 unless the requesting user holds the permission
+// for sessions in the given collection
+func (h *Handler) requirePermission(w http.ResponseWriter, r *http.Request, collection string, permission auth.Permission) bool {
+	principal := h.permissions.PrincipalFor(r)
+	if h.permissions.Allowed(principal, collection, permission) {
+		return true
+	}
+
+	slog.Warn("Permission denied", "user", principal.User, "roles", principal.Roles, "collection", collection, "permission", permission)
+	h.writeError(w, "Permission denied: "+string(permission), http.StatusForbidden)
+	return false
+}
+
+// HandlePermissions reports the requesting user's roles and what they may do,
+// optionally within a collection, so the editor can hide actions they can't take
+func (h *Handler) HandlePermissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal := h.permissions.PrincipalFor(r)
+	collection := r.URL.Query().Get("collection")
+	h.writeJSON(w, PermissionsResponse{
+		User:        principal.User,
+		Roles:       principal.Roles,
+		Collection:  collection,
+		Permissions: h.permissions.Effective(principal, collection),
+	})
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+)
+
+func TestAuthenticateDropsClientIdentityWithoutProxy(t *testing.T) {
+	h := &Handler{
+		permissions: &auth.Policy{Roles: map[string]auth.Grants{"admin": {auth.DeleteSession: true}}},
+	}
+	var user, roles string
+	handle := h.authenticate(false, func(w http.ResponseWriter, r *http.Request) {
+		user, roles = r.Header.Get(auth.UserHeader), r.Header.Get(auth.RolesHeader)
+		if !h.requirePermission(w, r, "", auth.DeleteSession) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r := httptest.NewRequest("DELETE", APIPrefix+"/sessions/s1", nil)
+	r.Header.Set(auth.UserHeader, "admin")
+	r.Header.Set(auth.RolesHeader, "admin")
+	w := httptest.NewRecorder()
+	handle(w, r)
+
+	if user != "" || roles != "" {
+		t.Errorf("client headers reached the route: user %q, roles %q", user, roles)
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("claimed admin without a trusted proxy: got %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
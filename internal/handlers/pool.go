@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// pagePool bounds how many pages are OCRed at once across every upload,
+// batch, repository and Drupal job, from OCR_WORKERS (default 4). The stages
+// within a page have their own limits in the hOCR service, so a page can be
+// detected while others wait on the LLM.
+type pagePool struct {
+	slots chan struct{}
+}
+
+func newPagePool() *pagePool {
+	return &pagePool{slots: make(chan struct{}, max(1, utils.GetEnvInt("OCR_WORKERS", 4)))}
+}
+
+// run does one page's OCR once a slot is free
+func (p *pagePool) run(fn func() error) error {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		slog.Info("Waiting for an OCR worker", "limit", cap(p.slots))
+		p.slots <- struct{}{}
+	}
+	defer func() { <-p.slots }()
+	return fn()
+}
+
+// each calls fn with 0 to n-1, as many at once as the pool has workers, and
+// returns the error of the first that failed; none are started after a
+// failure. The calls fetch and store pages around their OCR, which takes a
+// worker with run, so downloads overlap with other pages' OCR. Calls nest, as
+// for the pages of a PDF in a batch, without holding workers while they wait.
+func (p *pagePool) each(n int, fn func(i int) error) error {
+	errs := make([]error, n)
+	limit := make(chan struct{}, cap(p.slots))
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		limit <- struct{}{}
+		if failed.Load() {
+			<-limit
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-limit }()
+			if errs[i] = fn(i); errs[i] != nil {
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+)
+
+// postCorrectionEnabled reports whether fresh OCR output gets a dictionary
+// post-correction pass, from POST_CORRECTION (default false)
+func postCorrectionEnabled() bool {
+	return envBool("POST_CORRECTION", false)
+}
+
+// postCorrect replaces the words of an engine's reading that are surely OCR
+// confusions of a dictionary word, such as rn read for m or 1 for l, keeping
+// what was read on each so reviewers can check it. The hOCR comes back as it
+// was when post-correction is off, there's no dictionary, or nothing changed.
+func (h *Handler) postCorrect(hocrXML string, config SessionConfig) string {
+	if !postCorrectionEnabled() || h.spellService.Words() == 0 {
+		return hocrXML
+	}
+	checker, err := h.spellService.Checker()
+	if err != nil {
+		return hocrXML
+	}
+	lines, err := hocr.ParseHOCRLines(hocrXML)
+	if err != nil {
+		slog.Warn("Skipping post-correction of unreadable hOCR", "err", err)
+		return hocrXML
+	}
+
+	corrected := 0
+	for i := range lines {
+		for j := range lines[i].Words {
+			word := &lines[i].Words[j]
+			// Already corrected, and what a person typed, stay as they are
+			if word.AutoCorrected != "" || word.Confidence >= typedConfidence {
+				continue
+			}
+			if text, ok := checker.Correct(word.Text); ok {
+				word.AutoCorrected, word.Text = word.Text, text
+				corrected++
+			}
+		}
+	}
+	if corrected == 0 {
+		return hocrXML
+	}
+
+	page, err := hocr.ParsePage(hocrXML)
+	if err != nil {
+		slog.Warn("Skipping post-correction of hOCR without a page", "err", err)
+		return hocrXML
+	}
+	converter := hocr.NewConverter()
+	converter.Language = config.Language
+	slog.Info("Post-corrected OCR output", "words", corrected)
+	return converter.ConvertHOCRLinesToXML(lines, page.Width, page.Height)
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/iiif"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// manifestClient fetches IIIF manifests for prefetch runs, so a slow server
+// fails the request instead of holding it open
+var manifestClient = &http.Client{Timeout: 30 * time.Second}
+
+// maxManifestBytes bounds how much of a IIIF manifest is read
+const maxManifestBytes = 16 << 20
+
+// HandlePrefetch starts downloading and converting a list of images, or every image
+// in a IIIF manifest, so the network and conversion work is done before anyone opens
+// them for correction. OCR is not run.
+func (h *Handler) HandlePrefetch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireStorage(w) {
+		return
+	}
+
+	var request PrefetchRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	urls := request.URLs
+	if request.ManifestURL != "" {
+		manifestURLs, err := fetchManifestImageURLs(request.ManifestURL)
+		if err != nil {
+			h.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		urls = append(urls, manifestURLs...)
+	}
+
+	if len(urls) == 0 {
+		h.writeError(w, "urls or manifest_url is required", http.StatusBadRequest)
+		return
+	}
+
+	run := models.PrefetchRun{
+		ID:        fmt.Sprintf("prefetch_%d", time.Now().UnixNano()),
+		Total:     len(urls),
+		StartedAt: time.Now(),
+	}
+	h.prefetchStore.Set(run)
+
+	go h.runPrefetch(run, urls)
+
+	h.writeJSONStatus(w, http.StatusAccepted, PrefetchAccepted{ID: run.ID, Total: run.Total})
+}
+
+// HandlePrefetchStatus reports progress of a prefetch run
+func (h *Handler) HandlePrefetchStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, APIPrefix+"/prefetch/")
+	run, ok := h.prefetchStore.Get(id)
+	if !ok {
+		h.writeError(w, "Prefetch run not found", http.StatusNotFound)
+		return
+	}
+
+	h.writeJSON(w, run)
+}
+
+func (h *Handler) runPrefetch(run models.PrefetchRun, urls []string) {
+	concurrency := max(1, utils.GetEnvInt("PREFETCH_CONCURRENCY", 2))
+	slog.Info("Prefetch started", "id", run.ID, "images", len(urls), "concurrency", concurrency)
+
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for imageURL := range queue {
+				err := h.prefetchImage(imageURL)
+
+				h.prefetchStore.Update(run.ID, func(run *models.PrefetchRun) {
+					if err != nil {
+						run.Failed++
+						run.Errors = append(run.Errors, fmt.Sprintf("%s: %s", imageURL, err.Error()))
+					} else {
+						run.Completed++
+					}
+				})
+			}
+		}()
+	}
+
+	for _, imageURL := range urls {
+		queue <- imageURL
+	}
+	close(queue)
+	wg.Wait()
+
+	finished := time.Now()
+	h.prefetchStore.Update(run.ID, func(run *models.PrefetchRun) {
+		run.FinishedAt = &finished
+	})
+	run, _ = h.prefetchStore.Get(run.ID)
+
+	slog.Info("Prefetch finished", "id", run.ID, "completed", run.Completed, "failed", run.Failed, "duration", finished.Sub(run.StartedAt))
+}
+
+func (h *Handler) prefetchImage(imageURL string) error {
+	file, contentType, err := h.downloadImageFromURL(imageURL)
+	if err != nil {
+		slog.Warn("Prefetch download failed", "url", imageURL, "err", err)
+		return err
+	}
+	defer file.remove()
+
+	result, err := h.saveImageFromFile(file, contentType, imageURL)
+	if err != nil {
+		slog.Warn("Prefetch conversion failed", "url", imageURL, "err", err)
+		return err
+	}
+
+	return h.recordPrefetchedImage(imageURL, result)
+}
+
+// prefetchDir holds a record of where each prefetched image was stored
+func (h *Handler) prefetchDir() string {
+	return filepath.Join(h.dirs.Cache, "prefetch")
+}
+
+// prefetchIndexPath maps a source URL to the record of where its image was stored
+func (h *Handler) prefetchIndexPath(imageURL string) string {
+	return filepath.Join(h.prefetchDir(), utils.CalculateDataMD5([]byte(imageURL))+".json")
+}
+
+func (h *Handler) recordPrefetchedImage(imageURL string, result *ImageProcessResult) error {
+	indexPath := h.prefetchIndexPath(imageURL)
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return fmt.Errorf("failed to create prefetch index: %w", err)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(indexPath, data, 0644)
+}
+
+// lookupPrefetchedImage returns the stored image for a URL warmed by a prefetch run
+func (h *Handler) lookupPrefetchedImage(imageURL string) (*ImageProcessResult, bool) {
+	data, err := os.ReadFile(h.prefetchIndexPath(imageURL))
+	if err != nil {
+		return nil, false
+	}
+
+	// Records from before uploads were content addressed carry no digest
+	var result ImageProcessResult
+	if err := json.Unmarshal(data, &result); err != nil || result.Digest == "" {
+		return nil, false
+	}
+
+	if _, err := os.Stat(result.ImageFilePath); err != nil {
+		return nil, false
+	}
+
+	return &result, true
+}
+
+func fetchManifestImageURLs(manifestURL string) ([]string, error) {
+	resp, err := manifestClient.Get(manifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IIIF manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch IIIF manifest: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IIIF manifest: %w", err)
+	}
+	if len(data) > maxManifestBytes {
+		return nil, fmt.Errorf("IIIF manifest is larger than %d bytes", maxManifestBytes)
+	}
+
+	return iiif.ImageURLsFromManifest(data)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestManifestFetchTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := manifestClient
+	manifestClient = &http.Client{Timeout: 50 * time.Millisecond}
+	defer func() { manifestClient = client }()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := fetchManifestImageURLs(server.URL + "/manifest.json")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("a manifest that never arrived was accepted")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("manifest fetch hung on a slow server")
+	}
+}
+
+func TestPrefetchRunsSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prefetch.json")
+	store := newPrefetchStore(path)
+	finished := time.Now()
+	store.Set(models.PrefetchRun{ID: "prefetch_done", Total: 2, Completed: 2, FinishedAt: &finished})
+	store.Set(models.PrefetchRun{ID: "prefetch_running", Total: 3, Completed: 1})
+
+	h := &Handler{prefetchStore: newPrefetchStore(path)}
+	status := func(id string) (int, models.PrefetchRun) {
+		w := httptest.NewRecorder()
+		h.HandlePrefetchStatus(w, httptest.NewRequest("GET", APIPrefix+"/prefetch/"+id, nil))
+		var run models.PrefetchRun
+		_ = json.NewDecoder(w.Body).Decode(&run)
+		return w.Code, run
+	}
+
+	if code, run := status("prefetch_done"); code != http.StatusOK || run.Completed != 2 || len(run.Errors) != 0 {
+		t.Errorf("finished run after a restart: got %d %+v", code, run)
+	}
+	code, run := status("prefetch_running")
+	if code != http.StatusOK || run.FinishedAt == nil || run.Completed != 1 {
+		t.Errorf("interrupted run after a restart: got %d %+v, want it finished", code, run)
+	}
+	if len(run.Errors) != 1 || run.Errors[0] != errJobInterrupted.Error() {
+		t.Errorf("interrupted run errors: got %q", run.Errors)
+	}
+	if code, _ := status("prefetch_unknown"); code != http.StatusNotFound {
+		t.Errorf("unknown run: got %d, want %d", code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// Sources of a word's text besides the engine that read the page
+const (
+	sourceManual = "manual"
+	sourceReread = "reread"
+)
+
+// handleProvenance describes where each word of an image's current hOCR came
+// from at GET /sessions/{id}/images/{imageID}/provenance, as a companion to
+// the hOCR for colouring words by how far they can be trusted.
+//
+// Words are matched to the OCR output by box. A word whose text a person
+// changed, or that was typed in, is manual; one whose text and confidence both
+// changed, or that is new with an engine confidence, came from reading a region
+// again. Agreement is with the cached reading of the engine named by compare,
+// by default the other of llm and tesseract, and is left out where that engine
+// has no word in the same place.
+func (h *Handler) handleProvenance(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	config, other, err := h.comparisonConfigs(session, r.URL.Query().Get("compare"))
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lines, err := hocr.ParseHOCRLines(currentHOCR(image))
+	if err != nil {
+		h.writeError(w, "Failed to parse hOCR: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	original, _ := hocr.ParseHOCRLines(image.OriginalHOCR)
+	otherLines := h.otherReading(image, other)
+
+	response := ImageProvenance{
+		ImageID:  image.ID,
+		Engine:   config.Engine,
+		Compare:  other.Engine,
+		Compared: otherLines != nil,
+		Words:    []WordProvenance{},
+	}
+	for _, line := range lines {
+		for _, word := range line.Words {
+			response.Words = append(response.Words, wordProvenance(word, line.ID, config.Engine, original, otherLines))
+		}
+	}
+	h.writeJSON(w, response)
+}
+
+// wordProvenance attributes one word to the engine, a person or a reread region
+func wordProvenance(word models.HOCRWord, lineID, engine string, original, other []models.HOCRLine) WordProvenance {
+	provenance := WordProvenance{
+		WordID: word.ID,
+		LineID: lineID,
+		Text:   word.Text,
+		BBox:   word.BBox,
+		Source: engine,
+	}
+
+	ocr, matched := wordAt(original, word.BBox)
+	switch {
+	case word.Confidence >= typedConfidence:
+		provenance.Source = sourceManual
+	case !matched:
+		provenance.Source = sourceReread
+	case ocr.Text != word.Text && ocr.Confidence == word.Confidence:
+		provenance.Source = sourceManual
+	case ocr.Text != word.Text:
+		provenance.Source = sourceReread
+	}
+	if matched && ocr.Text != word.Text {
+		provenance.OriginalText = ocr.Text
+	}
+	// Typed words carry a stand-in confidence, and engines that give none leave zero
+	if provenance.Source != sourceManual && word.Confidence > 0 {
+		confidence := word.Confidence
+		provenance.Confidence = &confidence
+	}
+
+	if alternative, ok := wordAt(other, word.BBox); ok {
+		agreed := alternative.Text == word.Text
+		provenance.Agreed = &agreed
+		if !agreed {
+			provenance.Alternative = alternative.Text
+		}
+	}
+	return provenance
+}
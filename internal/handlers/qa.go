@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/lm"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// lineOrderThreshold is the plausibility below which a page's line order is flagged,
+// from LINE_ORDER_MIN_PLAUSIBILITY (default 0.3)
+func lineOrderThreshold() float64 {
+	if value, err := strconv.ParseFloat(os.Getenv("LINE_ORDER_MIN_PLAUSIBILITY"), 64); err == nil {
+		return value
+	}
+	return 0.3
+}
+
+// lineOrderApplies reports whether the bundled model can judge the reading
+// order of a session's pages: their first language is the model's or isn't
+// known, and they aren't set in Fraktur, which is German
+func lineOrderApplies(config models.EvalConfig) bool {
+	primary, _, _ := strings.Cut(config.Language, "+")
+	return (primary == "" || primary == lm.Language) && config.DocumentType != hocr.DocumentFraktur
+}
+
+// checkLineOrder scores the reading order of OCR output with the local language
+// model, or returns nil for sessions in a language it doesn't know. Pages
+// shorter than LINE_ORDER_MIN_LINES (default 5) are never flagged.
+func checkLineOrder(hocrXML string, config models.EvalConfig) *models.LineOrderCheck {
+	if !lineOrderApplies(config) {
+		return nil
+	}
+
+	lines, err := hocr.ParseHOCRLines(hocrXML)
+	if err != nil {
+		slog.Warn("Unable to parse hOCR for line order check", "err", err)
+		return nil
+	}
+
+	texts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		words := make([]string, 0, len(line.Words))
+		for _, word := range line.Words {
+			words = append(words, word.Text)
+		}
+		texts = append(texts, strings.Join(words, " "))
+	}
+
+	score := lm.Default().ScoreLineOrder(texts)
+	return &models.LineOrderCheck{
+		Lines:        score.Lines,
+		Plausibility: score.Plausibility,
+		Flagged:      score.Lines >= utils.GetEnvInt("LINE_ORDER_MIN_LINES", 5) && score.Plausibility < lineOrderThreshold(),
+		CheckedAt:    time.Now(),
+	}
+}
+
+// HandleQA lists pages flagged by automatic checks, least plausible first, in
+// the sessions the user may view metrics for, optionally only those of one
+// collection. Pages from before the check existed are scored for the listing
+// without being saved; sessions in languages the check doesn't know are skipped.
+func (h *Handler) HandleQA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	collection := r.URL.Query().Get("collection")
+	if !h.requirePermission(w, r, collection, auth.ViewMetrics) {
+		return
+	}
+	principal := h.permissions.PrincipalFor(r)
+
+	items := []QAItem{}
+	for _, session := range h.sessionStore.GetAll() {
+		if collection != "" && session.Collection != collection {
+			continue
+		}
+		if !h.permissions.Allowed(principal, session.Collection, auth.ViewMetrics) || !lineOrderApplies(session.Config) {
+			continue
+		}
+		for i := range session.Images {
+			image := &session.Images[i]
+			check := image.LineOrder
+			if check == nil && image.OriginalHOCR != "" {
+				check = checkLineOrder(image.OriginalHOCR, session.Config)
+			}
+			if check != nil && check.Flagged {
+				items = append(items, QAItem{
+					SessionID: session.ID,
+					ImageID:   image.ID,
+					Reason:    "line_order",
+					LineOrder: check,
+				})
+			}
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].LineOrder.Plausibility != items[j].LineOrder.Plausibility {
+			return items[i].LineOrder.Plausibility < items[j].LineOrder.Plausibility
+		}
+		return items[i].SessionID+items[i].ImageID < items[j].SessionID+items[j].ImageID
+	})
+
+	h.writeJSON(w, QAResponse{Count: len(items), Items: items})
+}
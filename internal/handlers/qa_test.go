@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+func TestQAFiltersSessionsAndDoesNotSave(t *testing.T) {
+	flagged := &models.LineOrderCheck{Flagged: true}
+	sessions := []*models.CorrectionSession{
+		{ID: "english", Collection: "open", Config: models.EvalConfig{Language: "eng"}, Images: []models.ImageItem{
+			{ID: "flagged", LineOrder: flagged},
+			{ID: "unscored", OriginalHOCR: "<html/>"},
+		}},
+		{ID: "german", Collection: "open", Config: models.EvalConfig{Language: "deu+eng"}, Images: []models.ImageItem{
+			{ID: "flagged", LineOrder: flagged},
+		}},
+		{ID: "fraktur", Collection: "open", Config: models.EvalConfig{Language: "eng", DocumentType: hocr.DocumentFraktur}, Images: []models.ImageItem{
+			{ID: "flagged", LineOrder: flagged},
+		}},
+		{ID: "restricted", Collection: "staff", Images: []models.ImageItem{
+			{ID: "flagged", LineOrder: flagged},
+		}},
+	}
+	h := &Handler{
+		sessionStore: storage.New(),
+		permissions: &auth.Policy{
+			Roles:       map[string]auth.Grants{"curator": {auth.ViewMetrics: true}},
+			Collections: map[string]map[string]auth.Grants{"staff": {"curator": {auth.ViewMetrics: false}}},
+		},
+	}
+	for _, session := range sessions {
+		h.sessionStore.Set(session.ID, session)
+	}
+
+	qa := func(query, roles string) (int, QAResponse) {
+		r := httptest.NewRequest("GET", APIPrefix+"/qa"+query, nil)
+		r.Header.Set(auth.RolesHeader, roles)
+		w := httptest.NewRecorder()
+		h.HandleQA(w, r)
+		var response QAResponse
+		_ = json.NewDecoder(w.Body).Decode(&response)
+		return w.Code, response
+	}
+
+	code, response := qa("", "curator")
+	if code != http.StatusOK {
+		t.Fatalf("QA as curator: got %d, want %d", code, http.StatusOK)
+	}
+	if len(response.Items) != 1 || response.Items[0].SessionID != "english" {
+		t.Errorf("QA listed %+v, want only the English session's flagged page", response.Items)
+	}
+	if sessions[0].Images[1].LineOrder != nil {
+		t.Error("QA saved a line order check onto an unscored page")
+	}
+
+	if code, _ := qa("?collection=staff", "curator"); code != http.StatusForbidden {
+		t.Errorf("QA for a collection without the grant: got %d, want %d", code, http.StatusForbidden)
+	}
+	if code, _ := qa("", ""); code != http.StatusForbidden {
+		t.Errorf("QA without a role: got %d, want %d", code, http.StatusForbidden)
+	}
+}
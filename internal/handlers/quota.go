@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// quotaMeasureInterval is how long a measurement of the data directories is
+// trusted; uploads stored in between are added to it as they land
+const quotaMeasureInterval = time.Minute
+
+var errStorageFull = errors.New("storage quota exceeded, try again once space is freed")
+
+// storageQuota caches how many bytes the data directories hold, so checking an
+// upload doesn't walk the disk every time
+type storageQuota struct {
+	mu       sync.Mutex
+	measured time.Time
+	used     int64
+}
+
+// storageQuotaBytes is the most uploads, caches and archived output may hold,
+// from STORAGE_QUOTA_BYTES; 0, the default, sets no limit
+func storageQuotaBytes() int64 {
+	return int64(max(0, utils.GetEnvInt("STORAGE_QUOTA_BYTES", 0)))
+}
+
+// storageUsed is how many bytes uploads, caches and archived output hold,
+// measured at most once per quotaMeasureInterval
+func (h *Handler) storageUsed() int64 {
+	h.quota.mu.Lock()
+	defer h.quota.mu.Unlock()
+
+	if time.Since(h.quota.measured) < quotaMeasureInterval {
+		return h.quota.used
+	}
+	var used int64
+	for _, dir := range []string{h.dirs.Uploads, h.dirs.Cache, h.dirs.Archive} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			continue
+		}
+		used += diskUsage(dir, info)
+	}
+	h.quota.used, h.quota.measured = used, time.Now()
+	return used
+}
+
+// addStorageUsed counts bytes written since the last measurement
+func (h *Handler) addStorageUsed(bytes int64) {
+	h.quota.mu.Lock()
+	defer h.quota.mu.Unlock()
+	h.quota.used += bytes
+}
+
+// remeasureStorage has the next check walk the disk again, after files were removed
+func (h *Handler) remeasureStorage() {
+	h.quota.mu.Lock()
+	defer h.quota.mu.Unlock()
+	h.quota.measured = time.Time{}
+}
+
+// checkStorageQuota refuses incoming bytes that would take the data
+// directories past the quota. Pass 0 when the size isn't known yet, as for a
+// URL still to be downloaded, to refuse only once the quota is reached.
+func (h *Handler) checkStorageQuota(incoming int64) error {
+	quota := storageQuotaBytes()
+	if quota == 0 {
+		return nil
+	}
+	used := h.storageUsed()
+	if used >= quota || (incoming > 0 && used+incoming > quota) {
+		slog.Warn("Upload refused, storage quota exceeded", "used", used, "incoming", incoming, "quota", quota)
+		return fmt.Errorf("%w (%d of %d bytes used)", errStorageFull, used, quota)
+	}
+	return nil
+}
+
+// requireStorage answers 507 Insufficient Storage, before any upload is read
+// or queued, once the quota is reached
+func (h *Handler) requireStorage(w http.ResponseWriter) bool {
+	if err := h.checkStorageQuota(0); err != nil {
+		h.writeError(w, err.Error(), http.StatusInsufficientStorage)
+		return false
+	}
+	return true
+}
+
+// checkStorage reports how close the data directories are to the quota. It
+// only warns: editing and exporting still work with the quota reached.
+func (h *Handler) checkStorage() HealthCheck {
+	quota := storageQuotaBytes()
+	if quota == 0 {
+		return newHealthCheck("storage_quota", false, nil)
+	}
+	used := h.storageUsed()
+	if used >= quota {
+		return newHealthCheck("storage_quota", false, fmt.Errorf("%w (%d of %d bytes used)", errStorageFull, used, quota))
+	}
+	check := newHealthCheck("storage_quota", false, nil)
+	check.Detail = fmt.Sprintf("%d of %d bytes used", used, quota)
+	return check
+}
+
+// storedSize is the size of a stored upload along with the original kept
+// when normalizing rewrote it
+func (h *Handler) storedSize(path string) int64 {
+	var size int64
+	for _, file := range []string{path, filepath.Join(h.originalsDir(), filepath.Base(path))} {
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("Failed to size stored upload", "path", file, "err", err)
+		}
+	}
+	return size
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// regionSplice puts the lines read from a region into a page's lines,
+// returning the IDs of the words or lines it added
+type regionSplice func(lines, read []models.HOCRLine) ([]models.HOCRLine, []string, error)
+
+// errNothingRead is returned when OCR of a region declared missed finds no text
+var errNothingRead = errors.New("no text was found in the region")
+
+// handleRegionOCR queues a fresh reading of one region of an image at POST
+// /sessions/{id}/images/{imageID}/region. The job's result is the hOCR with the
+// region's words replaced by the new lines.
+func (h *Handler) handleRegionOCR(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request RegionOCRRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.checkRegion(w, image, request.BBox) {
+		return
+	}
+	config, ok := h.regionConfig(w, session, request.Engine, request.Model, request.Binarization)
+	if !ok {
+		return
+	}
+
+	splice := func(lines, read []models.HOCRLine) ([]models.HOCRLine, []string, error) {
+		lines, lineIDs := hocr.ReplaceRegion(lines, request.BBox, read)
+		return lines, lineIDs, nil
+	}
+	job, err := h.enqueueJob("region_ocr", requestUser(r), h.regionOCRJob(session.ID, image.ID, request.BBox, config, request.Model, splice, false))
+	h.writeJobAccepted(w, job, err)
+}
+
+// handleAddWord adds a word the detector missed at POST
+// /sessions/{id}/images/{imageID}/words/add. Without text the box is read by
+// OCR in a background job, whose result is the edited hOCR.
+func (h *Handler) handleAddWord(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	var request AddWordRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.checkRegion(w, image, request.BBox) {
+		return
+	}
+
+	if request.Text == "" {
+		config, ok := h.regionConfig(w, session, request.Engine, request.Model, nil)
+		if !ok {
+			return
+		}
+		// Everything read joins one line: the one asked for, or the one the
+		// first word lands on
+		splice := func(lines, read []models.HOCRLine) ([]models.HOCRLine, []string, error) {
+			lineID := request.LineID
+			var wordIDs []string
+			for _, line := range read {
+				for _, word := range line.Words {
+					var wordID string
+					var err error
+					lines, wordID, lineID, err = hocr.AddWord(lines, word.BBox, word.Text, lineID)
+					if err != nil {
+						return nil, nil, err
+					}
+					wordIDs = append(wordIDs, wordID)
+				}
+			}
+			if len(wordIDs) == 0 {
+				return nil, nil, errNothingRead
+			}
+			return lines, wordIDs, nil
+		}
+		job, err := h.enqueueJob("region_ocr", requestUser(r), h.regionOCRJob(session.ID, image.ID, request.BBox, config, request.Model, splice, true))
+		h.writeJobAccepted(w, job, err)
+		return
+	}
+
+	lines, err := hocr.ParseHOCRLines(currentHOCR(image))
+	if err != nil {
+		h.writeError(w, "Failed to parse hOCR: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	lines, wordID, lineID, err := hocr.AddWord(lines, request.BBox, request.Text, request.LineID)
+	if errors.Is(err, hocr.ErrLineNotFound) {
+		h.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hocrXML := h.saveEditedLines(r, session, image, lines)
+	slog.Info("Word added", "session_id", session.ID, "image_id", image.ID, "word_id", wordID, "line_id", lineID)
+	h.writeJSON(w, WordEditResponse{HOCR: hocrXML, WordIDs: []string{wordID}})
+}
+
+// handleAddLine adds a line the detector missed at POST
+// /sessions/{id}/images/{imageID}/lines/add. Without text the box is read by
+// OCR in a background job, whose result is the edited hOCR.
+func (h *Handler) handleAddLine(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	var request AddLineRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.checkRegion(w, image, request.BBox) {
+		return
+	}
+
+	if request.Text == "" {
+		config, ok := h.regionConfig(w, session, request.Engine, request.Model, nil)
+		if !ok {
+			return
+		}
+		splice := func(lines, read []models.HOCRLine) ([]models.HOCRLine, []string, error) {
+			lines, lineIDs := hocr.InsertLines(lines, request.BBox, read)
+			if len(lineIDs) == 0 {
+				return nil, nil, errNothingRead
+			}
+			return lines, lineIDs, nil
+		}
+		job, err := h.enqueueJob("region_ocr", requestUser(r), h.regionOCRJob(session.ID, image.ID, request.BBox, config, request.Model, splice, false))
+		h.writeJobAccepted(w, job, err)
+		return
+	}
+
+	lines, err := hocr.ParseHOCRLines(currentHOCR(image))
+	if err != nil {
+		h.writeError(w, "Failed to parse hOCR: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	lines, lineID, err := hocr.AddLine(lines, request.BBox, request.Text)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hocrXML := h.saveEditedLines(r, session, image, lines)
+	slog.Info("Line added", "session_id", session.ID, "image_id", image.ID, "line_id", lineID)
+	h.writeJSON(w, LineEditResponse{HOCR: hocrXML, LineIDs: []string{lineID}})
+}
+
+// checkRegion rejects boxes that are empty, inverted or off the page
+func (h *Handler) checkRegion(w http.ResponseWriter, image *models.ImageItem, box models.BBox) bool {
+	if box.X1 < 0 || box.Y1 < 0 || box.X2 <= box.X1 || box.Y2 <= box.Y1 {
+		h.writeError(w, "bbox must have x1 < x2 and y1 < y2, none negative", http.StatusBadRequest)
+		return false
+	}
+	if image.ImageWidth > 0 && image.ImageHeight > 0 && (box.X2 > image.ImageWidth || box.Y2 > image.ImageHeight) {
+		h.writeError(w, fmt.Sprintf("bbox must lie within the %dx%d page", image.ImageWidth, image.ImageHeight), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// regionConfig is the session's pipeline settings with the engine and
+// binarization a request picked, once they're known to be usable
+func (h *Handler) regionConfig(w http.ResponseWriter, session *models.CorrectionSession, engine, model string, binarization *models.BinarizationConfig) (SessionConfig, bool) {
+	config := sessionConfigOf(session)
+	if engine != "" {
+		config.Engine = engine
+	}
+	if binarization != nil {
+		config.Binarization = *binarization
+	}
+	config = h.resolveEngine(config)
+	if err := h.hocrService.ValidateEngine(config.Engine); err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return config, false
+	}
+	if model != "" && config.Engine != hocr.EngineLLM {
+		h.writeError(w, "model only applies to the llm engine", http.StatusBadRequest)
+		return config, false
+	}
+	return config, true
+}
+
+// regionOCRJob reads the region, then splices it into the image's hOCR as it
+// stands when the reading finishes, so edits made meanwhile are kept. The
+// result reports word IDs when words is set, line IDs otherwise.
+func (h *Handler) regionOCRJob(sessionID, imageID string, region models.BBox, config SessionConfig, model string, splice regionSplice, words bool) jobFunc {
+	return func(trace *jobTrace) (string, any, error) {
+		trace.input("image_id", imageID)
+		trace.input("bbox", region)
+		trace.input("config", config)
+		trace.input("model", model)
+
+		session, ok := h.sessionStore.Get(sessionID)
+		if !ok {
+			return sessionID, nil, fmt.Errorf("session %s no longer exists", sessionID)
+		}
+		image := findImage(session, imageID)
+		if image == nil {
+			return sessionID, nil, fmt.Errorf("image %s no longer exists", imageID)
+		}
+
+		charset, err := hocr.ParseCharset(config.Characters)
+		if err != nil {
+			return sessionID, nil, err
+		}
+		opts := hocr.Options{Engine: config.Engine, Model: model, Binarization: config.Binarization, Language: config.Language, DocumentType: config.DocumentType, Characters: charset}
+		done := trace.stage("ocr region")
+		read, err := h.hocrService.ProcessRegion(h.imageFilePath(image), region, opts)
+		done(err)
+		if err != nil {
+			return sessionID, nil, err
+		}
+
+		lines, err := hocr.ParseHOCRLines(currentHOCR(image))
+		if err != nil {
+			return sessionID, nil, fmt.Errorf("failed to parse hOCR: %w", err)
+		}
+		lines, ids, err := splice(lines, read)
+		if err != nil {
+			return sessionID, nil, err
+		}
+		hocrXML := h.saveEditedLines(nil, session, image, lines)
+		if words {
+			return sessionID, WordEditResponse{HOCR: hocrXML, WordIDs: ids}, nil
+		}
+		return sessionID, LineEditResponse{HOCR: hocrXML, LineIDs: ids}, nil
+	}
+}
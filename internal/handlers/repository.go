@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/export"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/repository"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// newRepositories connects to the Fedora and OCFL repositories configured in
+// the environment
+func newRepositories() map[string]repository.Store {
+	stores, err := repository.FromEnv()
+	if err != nil {
+		utils.ExitOnError("Unable to configure repositories", err)
+	}
+	return stores
+}
+
+// HandleRepositorySessions loads page images from Fedora or OCFL for
+// correction, as pages of one session (mode "single", the default) or as a
+// session per page, each processed in a background job
+func (h *Handler) HandleRepositorySessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request RepositoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	store, ok := h.repositories[request.Repository]
+	if !ok {
+		h.writeError(w, fmt.Sprintf("repository %q is not configured", request.Repository), http.StatusBadRequest)
+		return
+	}
+	if len(request.Items) == 0 {
+		h.writeError(w, "items is required", http.StatusBadRequest)
+		return
+	}
+	if limit := batchMaxItems(); len(request.Items) > limit {
+		h.writeError(w, fmt.Sprintf("%d items is more than the %d a batch may hold", len(request.Items), limit), http.StatusBadRequest)
+		return
+	}
+	for i, item := range request.Items {
+		if item.Object == "" || item.Path == "" {
+			h.writeError(w, fmt.Sprintf("item %d needs an object and a path", i+1), http.StatusBadRequest)
+			return
+		}
+	}
+	if request.Mode == "" {
+		request.Mode = batchSingle
+	}
+	mode, err := batchMode(request.Mode)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	config, err := pipelineConfig(request.Engine, request.Language, request.DocumentType, request.Characters, request.Binarization)
+	if err == nil {
+		err = h.hocrService.ValidateEngine(config.Engine)
+	}
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.CallbackURL != "" {
+		if err := validateCallbackURL(request.CallbackURL); err != nil {
+			h.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := h.ensureUploadsDir(); err != nil {
+		h.writeError(w, "Failed to create uploads directory: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user := requestUser(r)
+	taken := make(map[string]bool)
+	response := BatchUploadResponse{Mode: mode}
+	enqueue := func(entry BatchSession, run jobFunc) {
+		job, err := h.enqueueJob("repository_load", user, run)
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.JobID = job.ID
+			entry.StatusURL = APIPrefix + "/jobs/" + job.ID
+			response.Queued++
+		}
+		response.Sessions = append(response.Sessions, entry)
+	}
+
+	switch mode {
+	case batchSingle:
+		first := request.Items[0].Object
+		sessionID := h.batchSessionID(store.Kind()+"_"+path.Base(first), taken)
+		enqueue(BatchSession{SessionID: sessionID, Source: first, Items: len(request.Items)}, h.repositoryJob(store, request.Items, sessionID, config, request.CallbackURL))
+	default:
+		for _, item := range request.Items {
+			sessionID := h.batchSessionID(store.Kind()+"_"+path.Base(item.Object), taken)
+			enqueue(BatchSession{SessionID: sessionID, Source: item.Object + "/" + item.Path}, h.repositoryJob(store, []RepositoryItem{item}, sessionID, config, request.CallbackURL))
+		}
+	}
+
+	status := http.StatusAccepted
+	if response.Queued == 0 {
+		status = http.StatusServiceUnavailable
+	}
+	h.writeJSONStatus(w, status, response)
+}
+
+// repositoryJob fetches and transcribes repository images into the pages of one session
+func (h *Handler) repositoryJob(store repository.Store, items []RepositoryItem, sessionID string, config SessionConfig, callbackURL string) jobFunc {
+	return func(trace *jobTrace) (string, any, error) {
+		trace.input("repository", store.Kind())
+		trace.input("items", items)
+		trace.input("config", config)
+		config.trace = trace
+
+		results := make([]*ImageProcessResult, len(items))
+		err := h.pages.each(len(items), func(i int) error {
+			item := items[i]
+			done := trace.stage(fmt.Sprintf("fetch %s %s/%s", store.Kind(), item.Object, item.Path))
+			data, contentType, err := store.Fetch(item.Object, item.Path)
+			done(err)
+			if err != nil {
+				return err
+			}
+			if contentType == "" {
+				contentType = http.DetectContentType(data)
+			}
+			result, err := h.processImageFromData(data, contentType, item.Path, config)
+			if err != nil {
+				return fmt.Errorf("%s/%s: %w", item.Object, item.Path, err)
+			}
+			results[i] = result
+			return nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+
+		if err := checkSandboxPages(len(results)); err != nil {
+			return "", nil, err
+		}
+
+		session := h.createMultiImageSession(sessionID, results, config)
+		for i, item := range items {
+			session.Images[i].Repository = &models.RepositorySource{Kind: store.Kind(), Object: item.Object, Path: item.Path}
+		}
+		if callbackURL != "" {
+			session.Callback = &models.Callback{URL: callbackURL}
+		}
+		h.sessionStore.Set(sessionID, session)
+
+		trace.log().Info("Session created from repository", "session_id", sessionID, "repository", store.Kind(), "pages", len(items))
+		return sessionID, BatchResult{
+			SessionID: sessionID,
+			Message:   fmt.Sprintf("Successfully processed %d images from %s", len(items), store.Kind()),
+			Images:    len(results),
+		}, nil
+	}
+}
+
+// repositoryOutputPath fills a REPOSITORY_*_PATH template for an image: {stem}
+// is its path without the extension and {path} the whole of it. "none" turns
+// the output off.
+func repositoryOutputPath(template, imagePath string) string {
+	if template == "none" {
+		return ""
+	}
+	stem := strings.TrimSuffix(imagePath, path.Ext(imagePath))
+	return strings.NewReplacer("{stem}", stem, "{path}", imagePath).Replace(template)
+}
+
+// publishToRepository writes an image's hOCR, and ALTO converted from it, next
+// to the image in the repository it came from
+func (h *Handler) publishToRepository(session *models.CorrectionSession, image *models.ImageItem, hocrData, user string) (RepositoryPublished, error) {
+	source := image.Repository
+	published := RepositoryPublished{Status: "success", Kind: source.Kind, Object: source.Object}
+	store, ok := h.repositories[source.Kind]
+	if !ok {
+		return published, fmt.Errorf("repository %q is no longer configured", source.Kind)
+	}
+
+	message := publishMessage(session.ID, user)
+
+	if hocrPath := repositoryOutputPath(envOr("REPOSITORY_HOCR_PATH", "{stem}.hocr"), source.Path); hocrPath != "" {
+		if err := store.Put(source.Object, hocrPath, []byte(hocrData), "text/vnd.hocr+html", message); err != nil {
+			return published, fmt.Errorf("failed to write hOCR: %w", err)
+		}
+		published.Paths = append(published.Paths, hocrPath)
+	}
+
+	if altoPath := repositoryOutputPath(envOr("REPOSITORY_ALTO_PATH", "{stem}.alto.xml"), source.Path); altoPath != "" {
+		lines, err := hocr.ParseHOCRLines(hocrData)
+		if err != nil {
+			return published, fmt.Errorf("failed to parse hOCR for ALTO: %w", err)
+		}
+		alto, err := export.ALTO(lines, image.ImageWidth, image.ImageHeight, path.Base(source.Path))
+		if err != nil {
+			return published, fmt.Errorf("failed to build ALTO: %w", err)
+		}
+		if err := store.Put(source.Object, altoPath, alto, "application/xml", message); err != nil {
+			return published, fmt.Errorf("failed to write ALTO: %w", err)
+		}
+		published.Paths = append(published.Paths, altoPath)
+	}
+	return published, nil
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+var errCorrectedMeanwhile = errors.New("image was corrected while OCR ran; set discard_corrections to replace the corrections")
+
+// handleImageOCR manages an image's cached hOCR at
+// /sessions/{id}/images/{imageID}/ocr. DELETE drops the cached copy for the
+// session's settings, or the engine given, so the next session to use the
+// image runs OCR again. POST runs OCR again now in a background job, with the
+// engine, model, prompt and binarization asked for, bypassing both the hOCR and LLM
+// caches, and makes the result the image's hOCR.
+func (h *Handler) handleImageOCR(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	switch r.Method {
+	case "DELETE":
+		h.handleInvalidateOCR(w, r, session, image)
+	case "POST":
+		h.handleRegenerateOCR(w, r, session, image)
+	default:
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleInvalidateOCR(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	// A cache can be dropped for an engine this deployment no longer runs
+	config := sessionConfigOf(session)
+	if engine := r.URL.Query().Get("engine"); engine != "" {
+		config.Engine = engine
+	}
+	config = h.resolveEngine(config)
+	if !slices.ContainsFunc(h.hocrService.Engines(), func(info hocr.EngineInfo) bool { return info.Name == config.Engine }) {
+		h.writeError(w, "unknown engine: "+config.Engine, http.StatusBadRequest)
+		return
+	}
+	removed, err := h.invalidateHOCR(imageHash(image), config)
+	if err != nil {
+		h.writeError(w, "Failed to remove cached hOCR: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, OCRCacheInvalidation{
+		ImageID:   image.ID,
+		Engine:    config.Engine,
+		CacheFile: hocrCacheFilename(imageHash(image), config),
+		Removed:   removed,
+	})
+}
+
+func (h *Handler) handleRegenerateOCR(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	var request RegenerateOCRRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	config, ok := h.regionConfig(w, session, request.Engine, request.Model, request.Binarization)
+	if !ok {
+		return
+	}
+	if request.Prompt != "" && config.Engine != hocr.EngineLLM {
+		h.writeError(w, "prompt only applies to the llm engine", http.StatusBadRequest)
+		return
+	}
+	if request.Model != "" {
+		config.LLM.Model = request.Model
+	}
+	if request.Prompt != "" {
+		config.LLM.Prompt = request.Prompt
+	}
+	// Corrections are made against the old reading and can't follow it to a new one
+	if image.CorrectedHOCR != "" && !request.DiscardCorrections {
+		h.writeError(w, "image has corrections; set discard_corrections to replace them with the new reading", http.StatusConflict)
+		return
+	}
+
+	job, err := h.enqueueJob("regenerate_ocr", requestUser(r), h.regenerateOCRJob(session.ID, image.ID, config, request.DiscardCorrections))
+	h.writeJobAccepted(w, job, err)
+}
+
+// regenerateOCRJob reads the whole image again and replaces its hOCR, leaving
+// it for review once more
+func (h *Handler) regenerateOCRJob(sessionID, imageID string, config SessionConfig, discard bool) jobFunc {
+	return func(trace *jobTrace) (string, any, error) {
+		trace.input("image_id", imageID)
+		trace.input("config", config)
+		trace.input("llm", config.LLM)
+		config.trace = trace
+
+		session, ok := h.sessionStore.Get(sessionID)
+		if !ok {
+			return sessionID, nil, fmt.Errorf("session %s no longer exists", sessionID)
+		}
+		image := findImage(session, imageID)
+		if image == nil {
+			return sessionID, nil, fmt.Errorf("image %s no longer exists", imageID)
+		}
+
+		hocrXML, err := h.regenerateHOCR(h.imageFilePath(image), imageHash(image), config)
+		if err != nil {
+			return sessionID, nil, err
+		}
+
+		// Reading takes a while, so look again for edits made in the meantime
+		session, ok = h.sessionStore.Get(sessionID)
+		if !ok {
+			return sessionID, nil, fmt.Errorf("session %s no longer exists", sessionID)
+		}
+		image = findImage(session, imageID)
+		if image == nil {
+			return sessionID, nil, fmt.Errorf("image %s no longer exists", imageID)
+		}
+		if image.CorrectedHOCR != "" && !discard {
+			return sessionID, nil, errCorrectedMeanwhile
+		}
+
+		h.parsed.forget(currentHOCR(image))
+		image.OriginalHOCR = hocrXML
+		image.CorrectedHOCR = ""
+		image.Completed = false
+		markDrupalSyncPending(image)
+		h.sessionStore.Set(session.ID, session)
+		h.publishHOCRUpdate(nil, session, image)
+
+		trace.log().Info("OCR regenerated", "session_id", sessionID, "image_id", imageID, "engine", config.Engine, "model", config.LLM.Model)
+		return sessionID, OCRRegenerated{ImageID: imageID, Engine: config.Engine, Model: config.LLM.Model, HOCR: hocrXML}, nil
+	}
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/metrics"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/spell"
+)
+
+const (
+	defaultReviewLimit = 100
+	maxReviewLimit     = 1000
+
+	// minReadingOverlap is the intersection over union above which words of
+	// two engines' readings are taken to be the same word
+	minReadingOverlap = 0.5
+
+	// typedConfidence is what words a person typed in carry; they're never queued
+	typedConfidence = 100
+)
+
+// Reasons a word is queued for review
+const (
+	reviewLowConfidence = "low_confidence"
+	reviewDictionary    = "dictionary"
+	reviewDisagreement  = "disagreement"
+	reviewAutoCorrected = "auto_corrected"
+	reviewDisallowed    = "disallowed_characters"
+)
+
+// reviewThreshold is the confidence below which a word is queued, from
+// REVIEW_CONFIDENCE_THRESHOLD (default 60, where the editor starts colouring
+// lines as low confidence)
+func reviewThreshold() float64 {
+	if value, err := strconv.ParseFloat(os.Getenv("REVIEW_CONFIDENCE_THRESHOLD"), 64); err == nil {
+		return value
+	}
+	return 60
+}
+
+// handleReviewQueue lists the words of a session most in need of a look at GET
+// /sessions/{id}/review-queue, most suspicious first. A word is queued for low
+// confidence, for missing from the dictionary and lexicons, or for reading
+// differently in the cached output of the other engine, and for having been
+// replaced by post-correction with a word nobody has checked, or for holding
+// characters the session doesn't allow. Each reason adds one
+// to its score, and low confidence adds how far below the threshold it falls,
+// so the editor can step through the queue from the keyboard.
+func (h *Handler) handleReviewQueue(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	threshold := reviewThreshold()
+	if value := query.Get("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 || parsed > 100 {
+			h.writeError(w, "threshold must be a number from 0 to 100", http.StatusBadRequest)
+			return
+		}
+		threshold = parsed
+	}
+	limit := defaultReviewLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			h.writeError(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxReviewLimit)
+	}
+	includeCompleted, _ := strconv.ParseBool(query.Get("include_completed"))
+
+	var requested []string
+	if value := query.Get("lexicons"); value != "" {
+		requested = strings.Split(value, ",")
+	}
+	lexicons := h.sessionLexicons(session, requested)
+	checker, err := h.spellService.Checker(lexicons...)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Without a dictionary every word would be a miss, drowning the other signals
+	if h.spellService.Words() == 0 {
+		checker = nil
+	}
+
+	charset, err := hocr.ParseCharset(session.Config.Characters)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, other, err := h.comparisonConfigs(session, query.Get("compare"))
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	queue := ReviewQueue{SessionID: session.ID, Threshold: threshold, Compare: other.Engine, Lexicons: lexicons, Items: []ReviewItem{}}
+	for i := range session.Images {
+		image := &session.Images[i]
+		if image.Completed && !includeCompleted {
+			continue
+		}
+		lines, err := hocr.ParseHOCRLines(currentHOCR(image))
+		if err != nil {
+			continue
+		}
+		queue.Items = append(queue.Items, reviewImage(image, lines, h.otherReading(image, other), checker, charset, threshold)...)
+	}
+
+	sort.SliceStable(queue.Items, func(i, j int) bool {
+		a, b := queue.Items[i], queue.Items[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Confidence < b.Confidence
+	})
+	queue.Total = len(queue.Items)
+	if len(queue.Items) > limit {
+		queue.Items = queue.Items[:limit]
+	}
+
+	// Suggestions are the slow part, so only the words returned get them
+	for i := range queue.Items {
+		if slices.Contains(queue.Items[i].Reasons, reviewDictionary) {
+			queue.Items[i].Suggestions = checker.Suggest(queue.Items[i].Text, defaultSuggestions)
+		}
+	}
+	h.writeJSON(w, queue)
+}
+
+// reviewImage scores the words of one image, returning those with a reason to
+// be looked at in reading order. A nil checker skips the dictionary.
+func reviewImage(image *models.ImageItem, lines, other []models.HOCRLine, checker *spell.Checker, charset hocr.Charset, threshold float64) []ReviewItem {
+	var items []ReviewItem
+	for _, line := range lines {
+		for _, word := range line.Words {
+			if word.Confidence >= typedConfidence {
+				continue
+			}
+			item := ReviewItem{
+				ImageID:    image.ID,
+				WordID:     word.ID,
+				LineID:     line.ID,
+				Text:       word.Text,
+				BBox:       word.BBox,
+				Confidence: word.Confidence,
+			}
+			// Engines that give no confidence leave it at zero
+			if word.Confidence > 0 && word.Confidence < threshold {
+				item.Reasons = append(item.Reasons, reviewLowConfidence)
+				item.Score += 1 + (threshold-word.Confidence)/threshold
+			}
+			if checker != nil && !checker.Known(word.Text) {
+				item.Reasons = append(item.Reasons, reviewDictionary)
+				item.Score++
+			}
+			if word.AutoCorrected != "" {
+				item.Reasons = append(item.Reasons, reviewAutoCorrected)
+				item.AutoCorrected = word.AutoCorrected
+				item.Score++
+			}
+			if disallowed := charset.Disallowed(word.Text); disallowed != "" {
+				item.Reasons = append(item.Reasons, reviewDisallowed)
+				item.Disallowed = disallowed
+				item.Score++
+			}
+			if alternative, ok := wordAt(other, word.BBox); ok && alternative.Text != word.Text {
+				item.Reasons = append(item.Reasons, reviewDisagreement)
+				item.Alternative = alternative.Text
+				item.Score++
+			}
+			if len(item.Reasons) > 0 {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// comparisonConfigs are the session's pipeline settings, and the same with the
+// engine its reading is compared with: the one asked for, or by default the
+// other of llm and tesseract
+func (h *Handler) comparisonConfigs(session *models.CorrectionSession, compare string) (SessionConfig, SessionConfig, error) {
+	config := h.resolveEngine(sessionConfigOf(session))
+	if compare == "" {
+		compare = hocr.EngineTesseract
+		if config.Engine == hocr.EngineTesseract {
+			compare = hocr.EngineLLM
+		}
+	}
+	if compare != hocr.EngineLLM && compare != hocr.EngineTesseract {
+		return config, config, errors.New("compare must be llm or tesseract")
+	}
+	if compare == config.Engine {
+		return config, config, errors.New("compare must name an engine other than the session's")
+	}
+	other := config
+	other.Engine = compare
+	return config, other, nil
+}
+
+// otherReading is an image's cached hOCR from another engine, when that engine
+// has been run on it. Nothing is run here, so asking for the queue stays cheap.
+func (h *Handler) otherReading(image *models.ImageItem, config SessionConfig) []models.HOCRLine {
+	data, err := os.ReadFile(filepath.Join(h.dirs.Uploads, hocrCacheFilename(imageHash(image), config)))
+	if err != nil {
+		return nil
+	}
+	lines, err := hocr.ParseHOCRLines(string(data))
+	if err != nil {
+		return nil
+	}
+	return lines
+}
+
+// wordAt is the word in lines that best overlaps box, if any overlaps enough
+// to be taken for the same word
+func wordAt(lines []models.HOCRLine, box models.BBox) (models.HOCRWord, bool) {
+	var found models.HOCRWord
+	best, ok := minReadingOverlap, false
+	for _, line := range lines {
+		if metrics.BoxOverlap(line.BBox, box) == 0 && line.BBox != (models.BBox{}) {
+			continue
+		}
+		for _, word := range line.Words {
+			if overlap := metrics.BoxOverlap(word.BBox, box); overlap > best {
+				found, best, ok = word, overlap, true
+			}
+		}
+	}
+	return found, ok
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// effectiveRights merges image-level rights over the session defaults
+func effectiveRights(session *models.CorrectionSession, image *models.ImageItem) models.Rights {
+	rights := session.Rights
+	if image == nil || image.Rights == nil {
+		return rights
+	}
+
+	if image.Rights.Statement != "" {
+		rights.Statement = image.Rights.Statement
+	}
+	if image.Rights.EmbargoUntil != nil {
+		rights.EmbargoUntil = image.Rights.EmbargoUntil
+	}
+	if image.Rights.AccessLevel != "" {
+		rights.AccessLevel = image.Rights.AccessLevel
+	}
+
+	return rights
+}
+
+func isEmbargoed(rights models.Rights, now time.Time) bool {
+	return rights.EmbargoUntil != nil && now.Before(*rights.EmbargoUntil)
+}
+
+// isPubliclyViewable reports whether material may be shown to anonymous users
+func isPubliclyViewable(rights models.Rights, now time.Time) bool {
+	if isEmbargoed(rights, now) {
+		return false
+	}
+	return rights.AccessLevel == "" || rights.AccessLevel == models.AccessPublic
+}
+
+// rightsRefusal explains why an image may not be published or exported: it's
+// under embargo or restricted to staff
+func rightsRefusal(session *models.CorrectionSession, image *models.ImageItem, action string) error {
+	rights := effectiveRights(session, image)
+	if isEmbargoed(rights, time.Now()) {
+		return fmt.Errorf("image is under embargo until %s", rights.EmbargoUntil.Format(time.RFC3339))
+	}
+	if rights.AccessLevel == models.AccessRestricted {
+		return fmt.Errorf("image is restricted and cannot be %s", action)
+	}
+	return nil
+}
+
+// exportRefusal is the first rights refusal among the images being exported
+func exportRefusal(session *models.CorrectionSession, images []*models.ImageItem) error {
+	for _, image := range images {
+		if err := rightsRefusal(session, image, "exported"); err != nil {
+			return fmt.Errorf("%s: %w", image.ID, err)
+		}
+	}
+	return nil
+}
+
+func validateRights(rights models.Rights) error {
+	switch rights.AccessLevel {
+	case "", models.AccessPublic, models.AccessCampus, models.AccessRestricted:
+		return nil
+	}
+	return fmt.Errorf("invalid access_level: %s", rights.AccessLevel)
+}
+
+// uploadIsPublic reports whether any session shows the upload, named by its hash,
+// to anonymous users
+func (h *Handler) uploadIsPublic(hash string) bool {
+	now := time.Now()
+	for _, session := range h.sessionStore.GetAll() {
+		for i := range session.Images {
+			image := &session.Images[i]
+			name := strings.TrimSuffix(image.ImagePath, filepath.Ext(image.ImagePath))
+			if name == hash && isPubliclyViewable(effectiveRights(session, image), now) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mayViewUpload writes a 403 unless the caller may see an upload and what is
+// derived from it. Authenticated users see every upload; anyone else only those
+// a session shows publicly, so embargoed and restricted scans can't be fetched
+// by their hash.
+func (h *Handler) mayViewUpload(w http.ResponseWriter, r *http.Request, hash string) bool {
+	if requestUser(r) != "" || h.uploadIsPublic(hash) {
+		return true
+	}
+	h.writeError(w, "Image is not publicly available", http.StatusForbidden)
+	return false
+}
+
+// handleRights sets rights metadata on a session, or on a single image when image_id is given
+func (h *Handler) handleRights(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	switch r.Method {
+	case "GET":
+		images := make(map[string]models.Rights, len(session.Images))
+		for i := range session.Images {
+			images[session.Images[i].ID] = effectiveRights(session, &session.Images[i])
+		}
+		h.writeJSON(w, RightsResponse{Session: session.Rights, Images: images})
+	case "PUT":
+		var request RightsRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := validateRights(request.Rights); err != nil {
+			h.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if request.ImageID == "" {
+			session.Rights = request.Rights
+		} else {
+			image := findImage(session, request.ImageID)
+			if image == nil {
+				h.writeError(w, "Image not found", http.StatusNotFound)
+				return
+			}
+			rights := request.Rights
+			image.Rights = &rights
+		}
+
+		h.sessionStore.Set(session.ID, session)
+		h.writeJSON(w, statusSuccess)
+	default:
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandlePublicSession is the read-only public viewer. It only exposes images whose
+// rights allow anonymous access and strips everything else.
+func (h *Handler) HandlePublicSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := strings.TrimPrefix(r.URL.Path, APIPrefix+"/public/sessions/")
+	session, ok := h.getSessionOrError(w, sessionID)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	var images []PublicImage
+	for i := range session.Images {
+		image := &session.Images[i]
+		rights := effectiveRights(session, image)
+		if !isPubliclyViewable(rights, now) {
+			continue
+		}
+
+		hocrXML := image.CorrectedHOCR
+		if hocrXML == "" {
+			hocrXML = image.OriginalHOCR
+		}
+		images = append(images, PublicImage{
+			ID:          image.ID,
+			ImageURL:    image.ImageURL,
+			HOCR:        hocrXML,
+			ImageWidth:  image.ImageWidth,
+			ImageHeight: image.ImageHeight,
+			Rights:      rights,
+		})
+	}
+
+	if len(images) == 0 {
+		h.writeError(w, "Session is not publicly available", http.StatusForbidden)
+		return
+	}
+
+	h.writeJSON(w, PublicSession{ID: session.ID, Images: images})
+}
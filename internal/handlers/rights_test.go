@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+func TestRightsGateExportsAndUploads(t *testing.T) {
+	embargo := time.Now().Add(24 * time.Hour)
+	session := &models.CorrectionSession{
+		ID:         "s1",
+		Collection: "special",
+		Images: []models.ImageItem{
+			{ID: "open", ImagePath: "aaa.png", OriginalHOCR: "<html/>"},
+			{ID: "embargoed", ImagePath: "bbb.png", OriginalHOCR: "<html/>", Rights: &models.Rights{EmbargoUntil: &embargo}},
+		},
+	}
+	uploads := t.TempDir()
+	for _, name := range []string{"aaa.png", "bbb.png"} {
+		if err := os.WriteFile(filepath.Join(uploads, name), []byte("scan"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &Handler{
+		sessionStore: storage.New(),
+		dirs:         Dirs{Uploads: uploads},
+		permissions: &auth.Policy{
+			Users: map[string][]string{"curator": {"staff"}},
+			Roles: map[string]auth.Grants{"staff": {auth.ExportPDF: true}},
+		},
+	}
+	h.sessionStore.Set(session.ID, session)
+
+	exports := []struct {
+		query, user string
+		want        int
+	}{
+		{"?image_id=open", "curator", http.StatusOK},
+		{"?image_id=open", "jdoe", http.StatusForbidden},
+		{"?image_id=embargoed", "curator", http.StatusForbidden},
+		{"", "curator", http.StatusForbidden},
+	}
+	for _, test := range exports {
+		r := httptest.NewRequest("GET", APIPrefix+"/sessions/s1/export"+test.query, nil)
+		r.Header.Set(auth.UserHeader, test.user)
+		w := httptest.NewRecorder()
+		h.handleExport(w, r, session)
+		if w.Code != test.want {
+			t.Errorf("export%s as %s: got %d, want %d", test.query, test.user, w.Code, test.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", APIPrefix+"/sessions/s1/report", nil)
+	r.Header.Set(auth.UserHeader, "curator")
+	h.handleReport(w, r, session)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("report with an embargoed page: got %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	scans := []struct {
+		name, user string
+		want       int
+	}{
+		{"aaa.png", "", http.StatusOK},
+		{"bbb.png", "", http.StatusForbidden},
+		{"bbb.png", "curator", http.StatusOK},
+	}
+	for _, test := range scans {
+		r := httptest.NewRequest("GET", "/static/uploads/"+test.name, nil)
+		if test.user != "" {
+			r.Header.Set(auth.UserHeader, test.user)
+		}
+		w := httptest.NewRecorder()
+		h.HandleStatic(w, r)
+		if w.Code != test.want {
+			t.Errorf("upload %s as %q: got %d, want %d", test.name, test.user, w.Code, test.want)
+		}
+	}
+}
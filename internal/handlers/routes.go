@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIPrefix is where the current version of the API is mounted. Handlers parse
+// paths relative to it, so a future version can be mounted beside it.
+const APIPrefix = "/api/v1"
+
+// legacyPrefix is the unversioned mount kept for clients written before the API
+// was versioned. It serves the same handlers with deprecation headers, and can
+// be switched off with API_LEGACY_ROUTES=false.
+const legacyPrefix = "/api"
+
+// apiHandlers maps patterns relative to the API prefix to handlers
+func (h *Handler) apiHandlers() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/sessions":            h.HandleSessions,
+		"/sessions/":           h.HandleSessionDetail,
+		"/public/sessions/":    h.HandlePublicSession,
+		"/macros":              h.HandleMacros,
+		"/macros/":             h.HandleMacroDetail,
+		"/authority":           h.HandleAuthorityLookup,
+		"/prefetch":            h.HandlePrefetch,
+		"/prefetch/":           h.HandlePrefetchStatus,
+		"/tiles/":              h.HandleTiles,
+		"/webhooks/ocr/":       h.HandleOCRWebhook,
+		"/config":              h.HandleConfig,
+		"/openapi.json":        h.HandleOpenAPI,
+		"/permissions":         h.HandlePermissions,
+		"/accessibility/check": h.HandleAccessibilityCheck,
+		"/qa":                  h.HandleQA,
+		"/spellcheck":          h.HandleSpellcheck,
+		"/upload":              h.HandleUpload,
+		"/upload/batch":        h.HandleBatchUpload,
+		"/estimate":            h.HandleEstimate,
+		"/jobs/":               h.HandleJobs,
+		"/hocr/parse":          h.HandleHOCRParse,
+		"/hocr/update":         h.HandleHOCRUpdate,
+		"/alignment":           h.HandleAlignment,
+		"/admin/metrics":       h.HandleAggregateMetrics,
+		"/admin/storage":       h.HandleStorageCleanup,
+		"/admin/backup":        h.HandleBackup,
+		"/repository/sessions": h.HandleRepositorySessions,
+		"/drupal/books":        h.HandleDrupalBook,
+		"/drupal/sync":         h.HandleDrupalSync,
+	}
+}
+
+// Routes mounts the API under APIPrefix, the legacy unversioned paths when they
+// are enabled, the live update socket, the probes and the editor
+func (h *Handler) Routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	legacy := os.Getenv("API_LEGACY_ROUTES") != "false"
+	sunset := legacySunset()
+	public := publicRoutes()
+
+	for pattern, handle := range h.apiHandlers() {
+		handle = h.authenticate(public[pattern], handle)
+		mux.HandleFunc(APIPrefix+pattern, handle)
+		if legacy {
+			mux.HandleFunc(legacyPrefix+pattern, deprecatedRoute(pattern, sunset, handle))
+		}
+	}
+
+	mux.HandleFunc("/ws", h.authenticate(false, h.HandleWebSocket))
+	mux.HandleFunc("/healthz", h.HandleHealthz)
+	mux.HandleFunc("/readyz", h.HandleReadyz)
+	protectedStatic := h.authenticate(false, h.HandleStatic)
+	uploads := h.authenticate(true, h.HandleStatic)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Uploads are rights checked against whoever the credentials identify
+		if strings.HasPrefix(r.URL.Path, "/static/uploads/") {
+			uploads(w, r)
+			return
+		}
+		// Opening the editor with ?image= or ?nid= runs OCR, so it needs the same
+		// credentials as an upload
+		if query := r.URL.Query(); query.Has("image") || query.Has("nid") {
+			protectedStatic(w, r)
+			return
+		}
+		h.HandleStatic(w, r)
+	})
+	return mux
+}
+
+// legacySunset reads API_LEGACY_SUNSET (YYYY-MM-DD), the date after which the
+// unversioned paths may be removed
+func legacySunset() string {
+	value := os.Getenv("API_LEGACY_SUNSET")
+	if value == "" {
+		return ""
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		slog.Warn("Ignoring invalid API_LEGACY_SUNSET", "value", value, "err", err)
+		return ""
+	}
+	return date.UTC().Format(http.TimeFormat)
+}
+
+var legacyWarned sync.Map
+
+// deprecatedRoute serves a legacy path with the versioned handler. Responses
+// carry Deprecation, Sunset and successor Link headers (RFC 8594), and the first
+// request for each route is logged so operators can find clients to update.
+func deprecatedRoute(pattern, sunset string, handle http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		successor := APIPrefix + strings.TrimPrefix(r.URL.Path, legacyPrefix)
+
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		if sunset != "" {
+			w.Header().Set("Sunset", sunset)
+		}
+		if _, warned := legacyWarned.LoadOrStore(pattern, true); !warned {
+			slog.Warn("Deprecated unversioned API path in use", "path", r.URL.Path, "successor", successor, "user_agent", r.UserAgent())
+		}
+
+		versioned := new(http.Request)
+		*versioned = *r
+		u := *r.URL
+		u.Path = successor
+		u.RawPath = ""
+		versioned.URL = &u
+		handle(w, versioned)
+	}
+}
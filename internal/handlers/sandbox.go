@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/sandbox"
+)
+
+// sandboxDataDirs hold everything a demo visitor can leave behind
+func (h *Handler) sandboxDataDirs() []string {
+	return []string{h.dirs.Uploads, h.dirs.Cache, h.dirs.Archive}
+}
+
+// checkSandboxPages refuses sessions larger than a sandbox instance allows
+func checkSandboxPages(pages int) error {
+	if sandbox.Enabled() && pages > sandbox.MaxPages() {
+		return fmt.Errorf("this demo instance is limited to %d pages per session", sandbox.MaxPages())
+	}
+	return nil
+}
+
+// RunSandboxPurge deletes every session, stored file, macro, job and prefetch run each
+// night at SANDBOX_PURGE_HOUR. It never returns.
+func (h *Handler) RunSandboxPurge() {
+	for {
+		next := sandbox.NextPurge(time.Now(), sandbox.PurgeHour())
+		slog.Info("Sandbox purge scheduled", "at", next)
+		time.Sleep(time.Until(next))
+		h.purgeSandboxData()
+	}
+}
+
+// purgeSandboxData removes what visitors left behind. Only the schema version
+// in the data directory is kept, and jobs still running, which are purged the
+// next night; their inputs are gone with the sessions and uploads.
+func (h *Handler) purgeSandboxData() {
+	sessions := h.sessionStore.GetAll()
+	for sessionID := range sessions {
+		h.sessionStore.Delete(sessionID)
+	}
+	macros := h.macroStore.Clear()
+	externalJobs := h.externalJobStore.Clear()
+	prefetchRuns := h.prefetchStore.Clear()
+	jobs := h.jobStore.DeleteFinished()
+	if path := jobStorePath(h.dirs.Data); path != "" {
+		if err := h.jobStore.Save(path); err != nil {
+			slog.Warn("Failed to save purged job store", "path", path, "err", err)
+		}
+	}
+
+	files := 0
+	for _, dir := range h.sandboxDataDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				slog.Warn("Failed to list sandbox data", "dir", dir, "err", err)
+			}
+			continue
+		}
+		for _, entry := range entries {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				slog.Warn("Failed to purge sandbox data", "path", filepath.Join(dir, entry.Name()), "err", err)
+				continue
+			}
+			files++
+		}
+	}
+
+	slog.Info("Sandbox data purged", "sessions", len(sessions), "macros", macros, "jobs", jobs, "external_jobs", externalJobs, "prefetch_runs", prefetchRuns, "entries", files)
+}
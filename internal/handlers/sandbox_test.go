@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+func TestSandboxPurgeLeavesOnlyRunningJobs(t *testing.T) {
+	data := t.TempDir()
+	macros, err := storage.NewPersistentMacroStore(filepath.Join(data, "macros.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	externalJobs, err := storage.NewPersistentExternalJobStore(filepath.Join(data, "external_jobs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{
+		dirs:             Dirs{Uploads: t.TempDir(), Cache: t.TempDir(), Archive: t.TempDir(), Data: data},
+		sessionStore:     storage.New(),
+		macroStore:       macros,
+		externalJobStore: externalJobs,
+		jobStore:         storage.NewJobStore(),
+		prefetchStore:    storage.NewPrefetchStore(),
+	}
+
+	h.sessionStore.Set("s1", &models.CorrectionSession{ID: "s1"})
+	h.macroStore.Set(&models.CorrectionMacro{ID: "m1", Scope: models.MacroScopeGlobal})
+	h.externalJobStore.Set(models.ExternalJob{ID: "ext_1", Status: models.ExternalJobPending})
+	h.jobStore.Set(&models.Job{ID: "done", Status: models.JobSucceeded})
+	h.jobStore.Set(&models.Job{ID: "running", Status: models.JobRunning})
+	h.prefetchStore.Set(models.PrefetchRun{ID: "prefetch_1"})
+	for _, dir := range h.sandboxDataDirs() {
+		if err := os.WriteFile(filepath.Join(dir, "left.txt"), []byte("visitor data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h.purgeSandboxData()
+
+	if len(h.sessionStore.GetAll()) != 0 {
+		t.Error("sessions survived the purge")
+	}
+	if reloaded, _ := storage.NewPersistentMacroStore(filepath.Join(data, "macros.json")); len(reloaded.Visible("", "")) != 0 {
+		t.Error("macros survived the purge")
+	}
+	if reloaded, _ := storage.NewPersistentExternalJobStore(filepath.Join(data, "external_jobs.json")); len(reloaded.ForImage("", "")) != 0 {
+		t.Error("external jobs survived the purge")
+	}
+	if _, ok := h.prefetchStore.Get("prefetch_1"); ok {
+		t.Error("prefetch run survived the purge")
+	}
+	saved, err := storage.LoadJobStore(filepath.Join(data, "jobs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := saved.Get("done"); ok {
+		t.Error("finished job survived the purge")
+	}
+	if _, ok := saved.Get("running"); !ok {
+		t.Error("running job was purged")
+	}
+	for _, dir := range h.sandboxDataDirs() {
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("%s still holds %d entries", dir, len(entries))
+		}
+	}
+}
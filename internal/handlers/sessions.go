@@ -2,9 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/lehigh-university-libraries/hOCRedit/internal/auth"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/export"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
 	"github.com/lehigh-university-libraries/hOCRedit/internal/metrics"
 	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
 )
@@ -24,14 +30,13 @@ func (h *Handler) HandleSessions(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) HandleSessionDetail(w http.ResponseWriter, r *http.Request) {
-	sessionID := strings.TrimPrefix(r.URL.Path, "/api/sessions/")
+	path := strings.TrimPrefix(r.URL.Path, APIPrefix+"/sessions/")
+	sessionID, action, _ := strings.Cut(path, "/")
+	action, subpath, _ := strings.Cut(action, "/")
 
-	if strings.HasSuffix(sessionID, "/metrics") {
-		sessionID = strings.TrimSuffix(sessionID, "/metrics")
-		if r.Method == "POST" {
-			h.handleMetrics(w, r, sessionID)
-			return
-		}
+	if action == "metrics" && r.Method == "POST" {
+		h.handleMetrics(w, r, sessionID)
+		return
 	}
 
 	session, ok := h.getSessionOrError(w, sessionID)
@@ -39,6 +44,55 @@ func (h *Handler) HandleSessionDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	switch action {
+	case "":
+	case "rights":
+		h.handleRights(w, r, session)
+		return
+	case "publish":
+		h.handlePublish(w, r, session)
+		return
+	case "images":
+		h.handleImageRoute(w, r, session, subpath)
+		return
+	case "clone":
+		h.handleClone(w, r, session)
+		return
+	case "merge":
+		h.handleMerge(w, r, session)
+		return
+	case "contact-sheet":
+		h.handleContactSheet(w, r, session)
+		return
+	case "export":
+		h.handleExport(w, r, session)
+		return
+	case "summary":
+		h.handleSummary(w, r, session)
+		return
+	case "accessibility":
+		h.handleAccessibility(w, r, session)
+		return
+	case "report":
+		h.handleReport(w, r, session)
+		return
+	case "review-queue":
+		h.handleReviewQueue(w, r, session)
+		return
+	case "calibration":
+		h.handleCalibration(w, r, session)
+		return
+	case "callback":
+		h.handleCallback(w, r, session)
+		return
+	case "sync":
+		h.handleDrupalSync(w, r, session, subpath)
+		return
+	default:
+		h.writeError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
 	switch r.Method {
 	case "GET":
 		h.writeJSON(w, session)
@@ -48,24 +102,271 @@ func (h *Handler) HandleSessionDetail(w http.ResponseWriter, r *http.Request) {
 			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		var changed []*models.ImageItem
+		for i := range updatedSession.Images {
+			image := &updatedSession.Images[i]
+			if previous := findImage(session, image.ID); previous == nil || previous.CorrectedHOCR != image.CorrectedHOCR {
+				markDrupalSyncPending(image)
+				changed = append(changed, image)
+			}
+		}
 		h.sessionStore.Set(sessionID, &updatedSession)
+		for _, image := range changed {
+			h.publishHOCRUpdate(r, &updatedSession, image)
+		}
+		h.notifyCompletion(r, &updatedSession)
 		h.writeJSON(w, updatedSession)
+	case "DELETE":
+		if !h.requirePermission(w, r, session.Collection, auth.DeleteSession) {
+			return
+		}
+		h.sessionStore.Delete(sessionID)
+		slog.Info("Session deleted", "session_id", sessionID, "user", requestUser(r))
+		h.writeJSON(w, statusSuccess)
 	default:
 		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
 func (h *Handler) handleMetrics(w http.ResponseWriter, r *http.Request, _ string) {
-	var request struct {
-		Original  string `json:"original"`
-		Corrected string `json:"corrected"`
-	}
+	var request MetricsRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	metricsResult := metrics.CalculateAccuracyMetrics(request.Original, request.Corrected)
-	h.writeJSON(w, metricsResult)
+	response, err := compareHOCR(request)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.writeJSON(w, response)
+}
+
+// compareHOCR measures a correction, aligning the two versions line by line
+// when their hOCR is given
+func compareHOCR(request MetricsRequest) (MetricsResponse, error) {
+	if err := metrics.ValidateNormalization(request.Normalization); err != nil {
+		return MetricsResponse{}, err
+	}
+	language, err := hocr.ParseLanguage(request.Language)
+	if err != nil {
+		return MetricsResponse{}, err
+	}
+	if request.OriginalHOCR == "" || request.CorrectedHOCR == "" {
+		return MetricsResponse{EvalResult: metrics.CalculateAccuracyMetrics(request.Original, request.Corrected, request.Normalization)}, nil
+	}
+
+	originalLines, err := hocr.ParseHOCRLines(request.OriginalHOCR)
+	if err != nil {
+		return MetricsResponse{}, fmt.Errorf("failed to parse original hOCR: %w", err)
+	}
+	correctedLines, err := hocr.ParseHOCRLines(request.CorrectedHOCR)
+	if err != nil {
+		return MetricsResponse{}, fmt.Errorf("failed to parse corrected hOCR: %w", err)
+	}
+
+	original, corrected := request.Original, request.Corrected
+	if original == "" {
+		original = export.PlainText(hocr.MarkHyphenation(originalLines, language))
+	}
+	if corrected == "" {
+		corrected = export.PlainText(hocr.MarkHyphenation(correctedLines, language))
+	}
+	return MetricsResponse{
+		EvalResult: metrics.CalculateAccuracyMetrics(original, corrected, request.Normalization),
+		Lines:      metrics.CompareLines(originalLines, correctedLines, request.Normalization),
+	}, nil
+}
+
+// handleClone queues a branch of a session so experiments don't touch the
+// mainline correction. Without a config the corrections are copied as-is; with
+// one, every image is re-run through the pipeline using the new settings, and
+// the LLM's model, prompt and temperature when given, and corrections start
+// fresh. The job's result is the branch.
+func (h *Handler) handleClone(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request CloneRequest
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var config *SessionConfig
+	if request.Config != nil {
+		validated, err := h.cloneConfig(*request.Config)
+		if err != nil {
+			h.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		config = &validated
+	}
+
+	job, err := h.enqueueJob("clone_session", requestUser(r), h.cloneJob(session.ID, request.Name, config))
+	h.writeJobAccepted(w, job, err)
+}
+
+// cloneConfig checks the settings a branch is re-run with, taking the model,
+// prompt and temperature as what the LLM is asked with
+func (h *Handler) cloneConfig(config SessionConfig) (SessionConfig, error) {
+	language, err := hocr.ParseLanguage(config.Language)
+	if err != nil {
+		return config, err
+	}
+	if config.DocumentType, err = hocr.ParseDocumentType(config.DocumentType); err != nil {
+		return config, err
+	}
+	if _, err := hocr.ParseCharset(config.Characters); err != nil {
+		return config, err
+	}
+	if err := h.hocrService.ValidateEngine(config.Engine); err != nil {
+		return config, err
+	}
+	config.Language = language
+	config = h.resolveEngine(config)
+	config.LLM = models.LLMConfig{Model: config.Model, Prompt: config.Prompt, Temperature: config.Temperature}
+	if config.LLM != (models.LLMConfig{}) && config.Engine != hocr.EngineLLM {
+		return config, fmt.Errorf("model, prompt and temperature only apply to the llm engine")
+	}
+	return config, nil
+}
+
+// cloneJob branches the session as it stands when the job runs, re-running
+// its images when config is set
+func (h *Handler) cloneJob(sessionID, name string, config *SessionConfig) jobFunc {
+	return func(trace *jobTrace) (string, any, error) {
+		trace.input("parent_id", sessionID)
+		trace.input("name", name)
+		trace.input("config", config)
+
+		session, ok := h.sessionStore.Get(sessionID)
+		if !ok {
+			return "", nil, fmt.Errorf("session %s no longer exists", sessionID)
+		}
+
+		branch := cloneSession(session)
+		suffix := name
+		if suffix == "" {
+			suffix = "branch"
+		}
+		branch.ID = fmt.Sprintf("%s_%s_%d", session.ID, suffix, time.Now().Unix())
+		branch.ParentID = session.ID
+		branch.CreatedAt = time.Now()
+		// The parent's callback waits on the parent, not on experiments with it,
+		// and only the parent's pages are published to its Drupal nodes
+		branch.Callback = nil
+		for i := range branch.Images {
+			clearPublishState(&branch.Images[i])
+		}
+
+		if config != nil {
+			config := *config
+			config.trace = trace
+			branch.Config.Binarization = config.Binarization
+			branch.Config.Engine = config.Engine
+			branch.Config.Language = config.Language
+			branch.Config.DocumentType = config.DocumentType
+			branch.Config.Characters = config.Characters
+			branch.Config.LLM = config.LLM
+			if config.Model != "" {
+				branch.Config.Model = config.Model
+			}
+			if config.Prompt != "" {
+				branch.Config.Prompt = config.Prompt
+			}
+			branch.Config.Temperature = config.Temperature
+			branch.Results = nil
+
+			for i := range branch.Images {
+				image := &branch.Images[i]
+				hocrXML, err := h.processHOCR(h.imageFilePath(image), imageHash(image), config)
+				if err != nil {
+					return "", nil, fmt.Errorf("failed to process %s: %w", image.ID, err)
+				}
+				image.OriginalHOCR = hocrXML
+				image.CorrectedHOCR = ""
+				image.Completed = false
+			}
+		}
+
+		h.sessionStore.Set(branch.ID, branch)
+		trace.log().Info("Session cloned", "session_id", branch.ID, "parent_id", session.ID, "reprocessed", config != nil)
+		return branch.ID, branch, nil
+	}
+}
+
+// cloneSession deep copies a session so edits to the copy don't alias the original
+func cloneSession(session *models.CorrectionSession) *models.CorrectionSession {
+	clone := *session
+	clone.Rights.EmbargoUntil = copyOf(session.Rights.EmbargoUntil)
+	if session.Callback != nil {
+		callback := *session.Callback
+		callback.SentAt = copyOf(callback.SentAt)
+		clone.Callback = &callback
+	}
+	clone.Images = make([]models.ImageItem, len(session.Images))
+	for i, image := range session.Images {
+		clone.Images[i] = cloneImage(image)
+	}
+	clone.Results = append([]models.EvalResult(nil), session.Results...)
+	clone.Config.TestRows = append([]int(nil), session.Config.TestRows...)
+	return &clone
+}
+
+// cloneImage copies an image with everything it points to, so that removing
+// an annotation, recording a publish or syncing to Drupal on one copy leaves
+// the other as it was
+func cloneImage(image models.ImageItem) models.ImageItem {
+	image.PublishedAt = copyOf(image.PublishedAt)
+	if image.Rights != nil {
+		rights := *image.Rights
+		rights.EmbargoUntil = copyOf(rights.EmbargoUntil)
+		image.Rights = &rights
+	}
+	if image.Annotations != nil {
+		annotations := make([]models.Annotation, len(image.Annotations))
+		for i, annotation := range image.Annotations {
+			annotation.WordIDs = append([]string(nil), annotation.WordIDs...)
+			annotations[i] = annotation
+		}
+		image.Annotations = annotations
+	}
+	image.IIIF = copyOf(image.IIIF)
+	image.LineOrder = copyOf(image.LineOrder)
+	image.Repository = copyOf(image.Repository)
+	if image.DrupalSync != nil {
+		sync := *image.DrupalSync
+		sync.LastAttempt = copyOf(sync.LastAttempt)
+		image.DrupalSync = &sync
+	}
+	return image
+}
+
+// clearPublishState forgets the Drupal node an image is published to and how
+// its last publish went
+func clearPublishState(image *models.ImageItem) {
+	image.DrupalUploadURL = ""
+	image.DrupalNid = ""
+	image.PublishedAt = nil
+	image.DrupalMediaPending = false
+	image.DrupalMediaURL = ""
+	image.DrupalRevisionID = ""
+	image.DrupalSync = nil
+}
+
+// copyOf is a pointer to a copy of what p points to, or nil
+func copyOf[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	copied := *p
+	return &copied
 }
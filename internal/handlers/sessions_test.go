@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+func TestCloneSessionDoesNotAlias(t *testing.T) {
+	published := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	embargo := published.AddDate(1, 0, 0)
+	parent := &models.CorrectionSession{
+		ID:       "parent",
+		Rights:   models.Rights{EmbargoUntil: &embargo},
+		Callback: &models.Callback{URL: "https://example.edu/done", SentAt: &published},
+		Images: []models.ImageItem{{
+			ID:          "img_1",
+			PublishedAt: &published,
+			Rights:      &models.Rights{AccessLevel: models.AccessCampus, EmbargoUntil: &embargo},
+			Annotations: []models.Annotation{
+				{ID: "a1", WordIDs: []string{"word_1"}},
+				{ID: "a2", WordIDs: []string{"word_2", "word_3"}},
+				{ID: "a3", WordIDs: []string{"word_4"}},
+			},
+			IIIF:            &models.IIIFSource{CanvasID: "canvas/1"},
+			LineOrder:       &models.LineOrderCheck{Lines: 12},
+			Repository:      &models.RepositorySource{Kind: "ocfl", Object: "obj", Path: "p1.tif"},
+			DrupalUploadURL: "https://drupal.example.edu/node/1/media/hocr",
+			DrupalSync:      &models.DrupalSync{State: models.SyncUploaded, Attempts: 1, LastAttempt: &published},
+		}},
+	}
+	before, err := json.Marshal(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	branch := cloneSession(parent)
+	image := &branch.Images[0]
+	// Removing an annotation the way the annotations endpoint does shifts the
+	// rest down within the backing array
+	image.Annotations = append(image.Annotations[:0], image.Annotations[1:]...)
+	image.Annotations[0].WordIDs[0] = "word_9"
+	*image.PublishedAt = published.AddDate(0, 1, 0)
+	*image.Rights.EmbargoUntil = embargo.AddDate(1, 0, 0)
+	image.Rights.AccessLevel = models.AccessPublic
+	image.IIIF.CanvasID = "canvas/2"
+	image.LineOrder.Flagged = true
+	image.Repository.Path = "p2.tif"
+	markDrupalSyncPending(image)
+	image.DrupalSync.Attempts++
+	*image.DrupalSync.LastAttempt = published.AddDate(0, 0, 1)
+	*branch.Rights.EmbargoUntil = embargo.AddDate(2, 0, 0)
+	*branch.Callback.SentAt = published.AddDate(0, 2, 0)
+	branch.Callback.SentAt = nil
+
+	after, err := json.Marshal(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("editing the branch changed the parent:\n%s\nwas\n%s", after, before)
+	}
+}
+
+func TestCloneJobClearsPublishState(t *testing.T) {
+	published := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	h := &Handler{sessionStore: storage.New()}
+	h.sessionStore.Set("parent", &models.CorrectionSession{
+		ID: "parent",
+		Images: []models.ImageItem{{
+			ID:               "img_1",
+			DrupalUploadURL:  "https://drupal.example.edu/node/1/media/hocr",
+			DrupalNid:        "1",
+			PublishedAt:      &published,
+			DrupalMediaURL:   "https://drupal.example.edu/media/7",
+			DrupalRevisionID: "42",
+			DrupalSync:       &models.DrupalSync{State: models.SyncUploaded},
+		}},
+	})
+
+	branchID, _, err := h.cloneJob("parent", "trial", nil)(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch, _ := h.sessionStore.Get(branchID)
+	if image := branch.Images[0]; image.DrupalUploadURL != "" || image.DrupalNid != "" || image.PublishedAt != nil ||
+		image.DrupalMediaURL != "" || image.DrupalRevisionID != "" || image.DrupalSync != nil {
+		t.Errorf("branch kept its parent's publish state: %+v", image)
+	}
+	if parent, _ := h.sessionStore.Get("parent"); parent.Images[0].DrupalNid != "1" {
+		t.Error("cloning cleared the parent's publish state")
+	}
+}
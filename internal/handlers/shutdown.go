@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Drain stops accepting jobs and waits for the queued and running ones to
+// finish. Jobs still unfinished when ctx ends are marked as interrupted, so
+// clients polling them after a restart are told to resubmit. The API keeps
+// serving meanwhile, letting editors follow their jobs to the end.
+func (h *Handler) Drain(ctx context.Context) {
+	h.queueMu.Lock()
+	if h.draining {
+		h.queueMu.Unlock()
+		return
+	}
+	h.draining = true
+	close(h.jobQueue)
+	h.queueMu.Unlock()
+
+	pending := len(h.jobStore.Unfinished())
+	slog.Info("Draining background jobs", "unfinished", pending)
+
+	done := make(chan struct{})
+	go func() {
+		h.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("All background jobs finished")
+	case <-ctx.Done():
+		interrupted := h.jobStore.Unfinished()
+		for _, id := range interrupted {
+			h.finishJob(id, "", nil, errJobInterrupted)
+			h.publishJob(id)
+		}
+		slog.Warn("Shutdown timeout reached, interrupting unfinished jobs", "jobs", interrupted)
+		// Their OCR may still be writing to its workspaces, which are only
+		// removed once the workers return, if the process is still running
+		go func() {
+			<-done
+			h.hocrService.Cleanup()
+		}()
+	}
+}
+
+// Flush writes sessions and jobs to persistent storage before the process exits
+func (h *Handler) Flush() error {
+	var errs []error
+	if err := h.sessionStore.Flush(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to flush sessions: %w", err))
+	}
+	if path := jobStorePath(h.dirs.Data); path != "" {
+		if err := h.jobStore.Save(path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save jobs: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isDraining reports whether a shutdown has begun
+func (h *Handler) isDraining() bool {
+	h.queueMu.RLock()
+	defer h.queueMu.RUnlock()
+	return h.draining
+}
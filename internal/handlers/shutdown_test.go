@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+func TestDrainInterruptsJobsAtItsDeadline(t *testing.T) {
+	t.Setenv("JOB_WORKERS", "1")
+	h := &Handler{
+		jobStore:    storage.NewJobStore(),
+		live:        newLiveHub(),
+		hocrService: hocr.NewService(hocr.Dirs{Temp: t.TempDir()}),
+	}
+	h.startJobWorkers()
+
+	release := make(chan struct{})
+	defer close(release)
+	job, err := h.enqueueJob("test", "", func(trace *jobTrace) (string, any, error) {
+		<-release
+		return "", nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	h.Drain(ctx)
+
+	// The rest of the shutdown gets the time left, however long the job runs
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Drain took %s past its deadline", elapsed)
+	}
+	if got, _ := h.jobStore.Get(job.ID); got.Status != models.JobFailed || got.Error != errJobInterrupted.Error() {
+		t.Errorf("job is %s (%s), want interrupted", got.Status, got.Error)
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/spell"
+)
+
+const (
+	defaultSuggestions = 5
+	maxSuggestions     = 20
+)
+
+// HandleSpellcheck lists the dictionary and lexicons on GET, and on POST flags
+// the words of a transcription missing from them, with suggestions
+func (h *Handler) HandleSpellcheck(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		h.writeJSON(w, SpellcheckInfo{Words: h.spellService.Words(), Lexicons: h.spellService.Lexicons()})
+	case "POST":
+		var request SpellcheckRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		lexicons := request.Lexicons
+		if lexicons == nil {
+			lexicons = []string{}
+		}
+		if request.SessionID != "" {
+			session, ok := h.getSessionOrError(w, request.SessionID)
+			if !ok {
+				return
+			}
+			lexicons = h.sessionLexicons(session, lexicons)
+		}
+		checker, err := h.spellService.Checker(lexicons...)
+		if err != nil {
+			h.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		limit := suggestionLimit(request.Limit)
+		response := SpellcheckResponse{Lexicons: lexicons, Checked: len(request.Words), Flagged: []SpellcheckFlag{}}
+		for i, text := range request.Words {
+			if !checker.Known(text) {
+				response.Flagged = append(response.Flagged, SpellcheckFlag{Index: i, Text: text, Suggestions: suggestions(checker, text, limit)})
+			}
+		}
+		h.writeJSON(w, response)
+	default:
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleImageSpellcheck flags the misspelled words of an image's current hOCR
+// at GET /sessions/{id}/images/{imageID}/spellcheck
+func (h *Handler) handleImageSpellcheck(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	var requested []string
+	if value := query.Get("lexicons"); value != "" {
+		requested = strings.Split(value, ",")
+	}
+	lexicons := h.sessionLexicons(session, requested)
+	checker, err := h.spellService.Checker(lexicons...)
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	limit = suggestionLimit(limit)
+
+	lines, err := hocr.ParseHOCRLines(currentHOCR(image))
+	if err != nil {
+		h.writeError(w, "Failed to parse hOCR: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	response := SpellcheckResponse{Lexicons: lexicons, Flagged: []SpellcheckFlag{}}
+	for _, line := range lines {
+		for _, word := range line.Words {
+			if !checker.Known(word.Text) {
+				box := word.BBox
+				response.Flagged = append(response.Flagged, SpellcheckFlag{
+					Index:       response.Checked,
+					Text:        word.Text,
+					WordID:      word.ID,
+					LineID:      line.ID,
+					BBox:        &box,
+					Suggestions: suggestions(checker, word.Text, limit),
+				})
+			}
+			response.Checked++
+		}
+	}
+	h.writeJSON(w, response)
+}
+
+// sessionLexicons adds the lexicon named after a session's collection, when
+// there is one, to those asked for
+func (h *Handler) sessionLexicons(session *models.CorrectionSession, lexicons []string) []string {
+	if lexicons == nil {
+		lexicons = []string{}
+	}
+	if session.Collection == "" || !h.spellService.HasLexicon(session.Collection) {
+		return lexicons
+	}
+	for _, name := range lexicons {
+		if name == session.Collection {
+			return lexicons
+		}
+	}
+	return append(lexicons, session.Collection)
+}
+
+// suggestionLimit bounds the suggestions asked for per word
+func suggestionLimit(limit int) int {
+	if limit <= 0 {
+		return defaultSuggestions
+	}
+	return min(limit, maxSuggestions)
+}
+
+func suggestions(checker *spell.Checker, text string, limit int) []string {
+	suggested := checker.Suggest(text, limit)
+	if suggested == nil {
+		return []string{}
+	}
+	return suggested
+}
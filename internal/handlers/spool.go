@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+)
+
+// spooledFile is an upload or download written to disk as it arrives and
+// digested on the way, so a 300 MB TIFF is never held in memory
+type spooledFile struct {
+	path   string
+	size   int64
+	digest string
+	// legacyDigest is the MD5 that named uploads before they were keyed by SHA-256
+	legacyDigest string
+	// head is the start of the content, enough to sniff its type
+	head []byte
+}
+
+// spool copies r to a file under the temp directory, failing with
+// errUploadTooLarge once more than limit bytes arrive. The caller removes the
+// file when it's done with it.
+func (h *Handler) spool(r io.Reader, limit int64) (*spooledFile, error) {
+	if err := os.MkdirAll(h.dirs.Temp, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	file, err := os.CreateTemp(h.dirs.Temp, "spool_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	spooled := &spooledFile{path: file.Name()}
+
+	hash := storage.NewContentHash()
+	legacy := md5.New()
+	head := &headWriter{limit: 512}
+	spooled.size, err = io.Copy(io.MultiWriter(file, hash, legacy, head), io.LimitReader(r, limit+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && spooled.size > limit {
+		err = fmt.Errorf("%w (the limit is %d bytes)", errUploadTooLarge, limit)
+	}
+	if err != nil {
+		spooled.remove()
+		return nil, err
+	}
+
+	spooled.digest = hash.Digest()
+	spooled.legacyDigest = hex.EncodeToString(legacy.Sum(nil))
+	spooled.head = head.data
+	return spooled, nil
+}
+
+// open reads the spooled content from the start
+func (f *spooledFile) open() (*os.File, error) {
+	return os.Open(f.path)
+}
+
+// remove deletes the spooled content, once it's stored or no longer wanted
+func (f *spooledFile) remove() {
+	if f != nil {
+		os.Remove(f.path)
+	}
+}
+
+// headWriter keeps the first limit bytes written to it and discards the rest
+type headWriter struct {
+	data  []byte
+	limit int
+}
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	if room := w.limit - len(w.data); room > 0 {
+		w.data = append(w.data, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// saveFrom writes an image being stored from r
+func saveFrom(path string, r io.Reader) error {
+	if err := writeFrom(path, r); err != nil {
+		return fmt.Errorf("failed to save image: %w", err)
+	}
+	return nil
+}
+
+// writeFrom copies r to a new file at path
+func writeFrom(path string, r io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
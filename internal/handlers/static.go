@@ -1,16 +1,33 @@
 package handlers
 
 import (
+	"io/fs"
 	"log/slog"
 	"net/http"
+	"os"
+	"path"
 	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/static"
 )
 
+// staticFiles are the editor's files: those embedded in the binary, or the
+// ones in dir when set, so the front-end can be worked on without rebuilding
+func staticFiles(dir string) fs.FS {
+	if dir == "" {
+		return static.Files
+	}
+	return os.DirFS(dir)
+}
+
 func (h *Handler) HandleStatic(w http.ResponseWriter, r *http.Request) {
 	filepath := strings.TrimPrefix(r.URL.Path, "/static/")
 
-	if strings.HasPrefix(filepath, "uploads/") {
-		http.ServeFile(w, r, filepath)
+	if upload, ok := strings.CutPrefix(filepath, "uploads/"); ok {
+		if !h.mayViewUpload(w, r, strings.TrimSuffix(upload, path.Ext(upload))) {
+			return
+		}
+		http.ServeFile(w, r, path.Join(h.dirs.Uploads, upload))
 		return
 	}
 
@@ -23,7 +40,7 @@ func (h *Handler) HandleStatic(w http.ResponseWriter, r *http.Request) {
 	imageURL := r.URL.Query().Get("image")
 	if imageURL != "" {
 		// Create session from image URL
-		sessionID, err := h.createSessionFromURL(imageURL)
+		sessionID, err := h.createSessionFromURL(imageURL, "", SessionConfig{})
 		if err != nil {
 			slog.Error("Failed to create session from URL", "url", imageURL, "error", err)
 			http.Error(w, "Failed to process image URL: "+err.Error(), http.StatusBadRequest)
@@ -42,7 +59,7 @@ func (h *Handler) HandleStatic(w http.ResponseWriter, r *http.Request) {
 		sessionID, err := h.createSessionFromDrupalNode(nid)
 		if err != nil {
 			slog.Error("Failed to create session from Drupal node", "nid", nid, "error", err)
-			http.Error(w, "Failed to process Drupal node: "+err.Error(), http.StatusBadRequest)
+			http.Error(w, "Failed to process Drupal node: "+err.Error(), drupalErrorStatus(err, http.StatusBadRequest))
 			return
 		}
 
@@ -67,7 +84,5 @@ func (h *Handler) HandleStatic(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 	}
 
-	// Serve files from the static directory
-	fullPath := "static/" + filepath
-	http.ServeFile(w, r, fullPath)
+	http.ServeFileFS(w, r, h.static, filepath)
 }
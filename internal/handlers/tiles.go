@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/tiles"
+)
+
+// HandleTiles serves Deep Zoom (DZI) pyramids for uploaded images:
+//
+//	/api/v1/tiles/{hash}.dzi
+//	/api/v1/tiles/{hash}_files/{level}/{col}_{row}.jpg
+//
+// The pyramid is built on first request and cached, so large scans can be
+// panned and zoomed without downloading the original file. The editor shows
+// scans wider than its viewer from the smallest level that fills it.
+// Tiles follow the rights of the upload they are cut from, as /static/uploads does.
+func (h *Handler) HandleTiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, APIPrefix+"/tiles/")
+
+	var hash, tilePath string
+	if name, ok := strings.CutSuffix(path, ".dzi"); ok {
+		hash = name
+	} else if name, rest, ok := strings.Cut(path, "_files/"); ok {
+		hash, tilePath = name, rest
+	}
+
+	if uploadHash, ok := uploadHashOf(hash); !ok || uploadHash != hash {
+		h.writeError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if !h.mayViewUpload(w, r, hash) {
+		return
+	}
+
+	dir, err := h.ensureTilePyramid(hash)
+	if err != nil {
+		if os.IsNotExist(err) {
+			h.writeError(w, "Image not found", http.StatusNotFound)
+			return
+		}
+		h.writeError(w, "Failed to generate tiles: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if tilePath == "" {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Header().Set("Content-Type", "application/xml")
+		http.ServeFile(w, r, filepath.Join(dir, tiles.DescriptorName))
+		return
+	}
+
+	var level, col, row int
+	if _, err := fmt.Sscanf(tilePath, "%d/%d_%d.jpg", &level, &col, &row); err != nil {
+		h.writeError(w, "Invalid tile path", http.StatusBadRequest)
+		return
+	}
+
+	tileFile := tiles.TilePath(filepath.Join(dir, tiles.FilesDir), level, col, row)
+	if _, err := os.Stat(tileFile); err != nil {
+		h.writeError(w, "Tile not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeFile(w, r, tileFile)
+}
+
+// ensureTilePyramid builds the pyramid for an upload once; its directory is
+// moved into place complete, so a descriptor means every tile exists
+func (h *Handler) ensureTilePyramid(hash string) (string, error) {
+	dir := filepath.Join(h.tilesDir(), hash)
+	descriptor := filepath.Join(dir, tiles.DescriptorName)
+	if _, err := os.Stat(descriptor); err == nil {
+		return dir, nil
+	}
+
+	unlock := h.blobs.Lock("tiles/" + hash)
+	defer unlock()
+
+	// Another request may have finished it while we waited
+	if _, err := os.Stat(descriptor); err == nil {
+		return dir, nil
+	}
+
+	sourcePath, err := h.findUploadedImage(hash)
+	if err != nil {
+		return "", err
+	}
+
+	slog.Info("Generating tile pyramid", "image", sourcePath)
+	if err := tiles.Generate(sourcePath, dir); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// findUploadedImage locates the image file for an upload hash, skipping its cached hOCR
+func (h *Handler) findUploadedImage(hash string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(h.dirs.Uploads, hash+".*"))
+	if err != nil {
+		return "", err
+	}
+
+	for _, match := range matches {
+		if filepath.Ext(match) != ".xml" {
+			return match, nil
+		}
+	}
+
+	return "", os.ErrNotExist
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTilesRejectMalformedHashes(t *testing.T) {
+	h := &Handler{}
+	hash := strings.Repeat("ab", 32)
+	for _, path := range []string{
+		"..%2f..%2fdata.dzi",
+		"abc.dzi",
+		hash[:63] + "g.dzi",
+		hash + ".png.dzi",
+		hash + "x_files/0/0_0.jpg",
+		"_files/0/0_0.jpg",
+	} {
+		w := httptest.NewRecorder()
+		h.HandleTiles(w, httptest.NewRequest("GET", APIPrefix+"/tiles/"+path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("tiles/%s: got %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
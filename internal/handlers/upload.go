@@ -2,12 +2,19 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
 )
 
 func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
@@ -15,6 +22,9 @@ func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if !h.requireStorage(w) {
+		return
+	}
 
 	// Check if this is a JSON request with image URL
 	contentType := r.Header.Get("Content-Type")
@@ -28,9 +38,7 @@ func (h *Handler) HandleUpload(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) handleURLUpload(w http.ResponseWriter, r *http.Request) {
-	var request struct {
-		ImageURL string `json:"image_url"`
-	}
+	var request UploadURLRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
@@ -42,67 +50,225 @@ func (h *Handler) handleURLUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionID, err := h.createSessionFromURL(request.ImageURL)
+	config, err := pipelineConfig(request.Engine, request.Language, request.DocumentType, request.Characters, request.Binarization)
+	if err == nil {
+		err = h.hocrService.ValidateEngine(config.Engine)
+	}
 	if err != nil {
-		h.writeError(w, "Failed to process image URL: "+err.Error(), http.StatusBadRequest)
+		h.writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	response := map[string]any{
-		"session_id": sessionID,
-		"message":    "Successfully processed image from URL",
-		"images":     1,
-		"cache_used": false,
-		"source":     "url",
-	}
-
-	h.writeJSON(w, response)
+	job, err := h.enqueueJob("upload_url", requestUser(r), h.urlUploadJob(request.ImageURL, "", config))
+	h.writeJobAccepted(w, job, err)
 }
 
 func (h *Handler) handleFileUpload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes()+uploadFormOverhead)
+	if err := r.ParseMultipartForm(uploadMemory); err != nil {
+		h.writeError(w, "Failed to read upload: "+err.Error(), uploadErrorStatus(err))
+		return
+	}
 
-	file, header, err := r.FormFile("files")
+	_, header, err := r.FormFile("files")
 	if err != nil {
-		file, header, err = r.FormFile("file")
+		_, header, err = r.FormFile("file")
 		if err != nil {
 			h.writeError(w, "Failed to read file: "+err.Error(), http.StatusBadRequest)
 			return
 		}
 	}
-	defer file.Close()
 
 	if err := h.ensureUploadsDir(); err != nil {
 		h.writeError(w, "Failed to create uploads directory: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	fileData, err := io.ReadAll(file)
+	config, err := sessionConfigFromForm(r)
+	if err == nil {
+		err = h.hocrService.ValidateEngine(config.Engine)
+	}
 	if err != nil {
-		h.writeError(w, "Failed to read file contents: "+err.Error(), http.StatusInternalServerError)
+		h.writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	result, err := h.processImageFile(fileData, header.Filename)
+	file, filename, err := h.spoolUpload(header)
+	if err == nil {
+		err = h.checkStorageQuota(file.size)
+	}
 	if err != nil {
-		h.writeError(w, err.Error(), http.StatusInternalServerError)
+		file.remove()
+		h.writeError(w, err.Error(), uploadErrorStatus(err))
 		return
 	}
 
 	// Use filename (without extension) as session name, with timestamp for uniqueness
-	baseFilename := strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename))
-	sessionID := fmt.Sprintf("%s_%d", baseFilename, time.Now().Unix())
+	sessionID := fmt.Sprintf("%s_%d", fileSessionName(filename), time.Now().Unix())
+	job, err := h.enqueueJob("upload_file", requestUser(r), h.fileUploadJob(file, filename, sessionID, config))
+	if err != nil {
+		file.remove()
+	}
+	h.writeJobAccepted(w, job, err)
+}
+
+const (
+	// uploadMemory is how much of a multipart form is held in memory; larger
+	// files are spooled to temporary files by the parser
+	uploadMemory = 8 << 20
+	// uploadFormOverhead allows for the form fields and part headers around a file
+	uploadFormOverhead = 1 << 20
+)
 
-	config := SessionConfig{}
-	session := h.createImageSession(sessionID, result, config)
-	h.sessionStore.Set(sessionID, session)
+var (
+	errUploadTooLarge    = errors.New("file is too large")
+	errUnsupportedUpload = errors.New("file is not a supported image or PDF")
+)
+
+// maxUploadBytes is the largest file accepted, UPLOAD_MAX_BYTES (default 200 MB)
+func maxUploadBytes() int64 {
+	return int64(utils.GetEnvInt("UPLOAD_MAX_BYTES", 200<<20))
+}
+
+// uploadErrorStatus maps upload failures to 413, 415 and 507 so clients can
+// tell a payload problem or a full disk from a malformed request
+func uploadErrorStatus(err error) int {
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.Is(err, errUploadTooLarge), errors.As(err, &maxBytes):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errUnsupportedUpload):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errStorageFull):
+		return http.StatusInsufficientStorage
+	}
+	return http.StatusBadRequest
+}
+
+// spoolUpload checks an uploaded file's size and streams it to disk, sniffing
+// its type rather than trusting the extension. The returned filename carries
+// the extension of the detected type, which decides how the file is stored and
+// split into pages. The job the file is queued for removes it.
+func (h *Handler) spoolUpload(header *multipart.FileHeader) (*spooledFile, string, error) {
+	limit := maxUploadBytes()
+	if header.Size > limit {
+		return nil, "", fmt.Errorf("%s: %w (%d bytes, the limit is %d)", header.Filename, errUploadTooLarge, header.Size, limit)
+	}
 
-	response := map[string]any{
-		"session_id": sessionID,
-		"message":    "Successfully processed 1 file",
-		"images":     1,
-		"cache_used": h.wasCacheUsed(result.MD5Hash),
-		"md5_hash":   result.MD5Hash,
+	upload, err := header.Open()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", header.Filename, err)
+	}
+	defer upload.Close()
+
+	file, err := h.spool(upload, limit)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", header.Filename, err)
+	}
+
+	contentType, ext, ok := utils.SniffImageType(file.head)
+	if !ok {
+		file.remove()
+		return nil, "", fmt.Errorf("%s: %w (detected %s)", header.Filename, errUnsupportedUpload, http.DetectContentType(file.head))
+	}
+	filename := fileSessionName(header.Filename) + ext
+	if !strings.EqualFold(filepath.Ext(header.Filename), ext) {
+		slog.Info("Upload extension does not match its content", "filename", header.Filename, "content_type", contentType)
+	}
+	return file, filename, nil
+}
+
+func fileSessionName(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename))
+}
+
+// urlUploadJob processes an image URL into a session, named after the image
+// when sessionID is empty
+func (h *Handler) urlUploadJob(imageURL, sessionID string, config SessionConfig) jobFunc {
+	return func(trace *jobTrace) (string, any, error) {
+		trace.input("image_url", imageURL)
+		trace.input("config", config)
+		config.trace = trace
+
+		created, err := h.createSessionFromURL(imageURL, sessionID, config)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to process image URL: %w", err)
+		}
+
+		return created, UploadResult{
+			SessionID: created,
+			Message:   "Successfully processed image from URL",
+			Images:    1,
+			Source:    "url",
+		}, nil
+	}
+}
+
+// fileUploadJob processes an uploaded file into a session, removing the
+// spooled file once it's stored
+func (h *Handler) fileUploadJob(file *spooledFile, filename, sessionID string, config SessionConfig) jobFunc {
+	return func(trace *jobTrace) (string, any, error) {
+		defer file.remove()
+		trace.input("filename", filename)
+		trace.input("size", file.size)
+		trace.input("digest", file.digest)
+		trace.input("config", config)
+		config.trace = trace
+
+		results, err := h.processUploadedFile(file, filename, config)
+		if err != nil {
+			return "", nil, err
+		}
+
+		session := h.createMultiImageSession(sessionID, results, config)
+		h.sessionStore.Set(sessionID, session)
+
+		return sessionID, UploadResult{
+			SessionID: sessionID,
+			Message:   "Successfully processed 1 file",
+			Images:    len(results),
+			CacheUsed: h.wasCacheUsed(results[0].Digest, config),
+			Digest:    results[0].Digest,
+		}, nil
+	}
+}
+
+// sessionConfigFromForm reads optional pipeline settings from multipart form fields
+func sessionConfigFromForm(r *http.Request) (SessionConfig, error) {
+	binarization, err := binarizationFromValues(r.Form, models.BinarizationConfig{})
+	if err != nil {
+		return SessionConfig{}, err
+	}
+
+	return pipelineConfig(r.Form.Get("engine"), r.Form.Get("language"), r.Form.Get("document_type"), models.CharacterConfig{
+		Allowed:   r.Form.Get("allowed_characters"),
+		Forbidden: r.Form.Get("forbidden_characters"),
+	}, binarization)
+}
+
+// binarizationFromValues overrides base with the binarization, threshold, window_size and k values
+func binarizationFromValues(values url.Values, base models.BinarizationConfig) (models.BinarizationConfig, error) {
+	binarization := base
+	if method := values.Get("binarization"); method != "" {
+		binarization = models.BinarizationConfig{Method: method}
+	}
+
+	var err error
+	if v := values.Get("threshold"); v != "" {
+		if binarization.Threshold, err = strconv.ParseFloat(v, 64); err != nil {
+			return binarization, fmt.Errorf("invalid threshold: %w", err)
+		}
+	}
+	if v := values.Get("window_size"); v != "" {
+		if binarization.WindowSize, err = strconv.Atoi(v); err != nil {
+			return binarization, fmt.Errorf("invalid window_size: %w", err)
+		}
+	}
+	if v := values.Get("k"); v != "" {
+		if binarization.K, err = strconv.ParseFloat(v, 64); err != nil {
+			return binarization, fmt.Errorf("invalid k: %w", err)
+		}
 	}
 
-	h.writeJSON(w, response)
+	return binarization, nil
 }
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/webhook"
+)
+
+// maxWebhookBody bounds callback payloads; a large page of hOCR is a few MB
+const maxWebhookBody = 32 << 20
+
+// handleExternalJobs registers OCR submitted to an asynchronous engine for an
+// image, returning the callback URL the engine should notify on completion
+func (h *Handler) handleExternalJobs(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem) {
+	switch r.Method {
+	case "GET":
+		h.writeJSON(w, h.externalJobStore.ForImage(session.ID, image.ID))
+	case "POST":
+		var request ExternalJobRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.Engine == "" {
+			h.writeError(w, "engine is required", http.StatusBadRequest)
+			return
+		}
+
+		job := models.ExternalJob{
+			ID:        fmt.Sprintf("ext_%d", time.Now().UnixNano()),
+			SessionID: session.ID,
+			ImageID:   image.ID,
+			Engine:    request.Engine,
+			RemoteID:  request.RemoteID,
+			Status:    models.ExternalJobPending,
+			CreatedAt: time.Now(),
+		}
+		h.externalJobStore.Set(job)
+
+		slog.Info("External OCR job registered", "job_id", job.ID, "session_id", session.ID, "image_id", image.ID, "engine", job.Engine)
+		h.writeJSONStatus(w, http.StatusCreated, ExternalJobCreated{
+			Job:         &job,
+			CallbackURL: fmt.Sprintf("%s%s/webhooks/ocr/%s", publicBaseURL(r), APIPrefix, job.ID),
+		})
+	default:
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleOCRWebhook receives signed completion callbacks from asynchronous OCR
+// engines at /api/v1/webhooks/ocr/{jobID} and attaches the result to the waiting image.
+// Requests must be signed with OCR_WEBHOOK_SECRET (see package webhook), and
+// the signed payload must name the job in the URL, so a captured callback
+// can't be replayed against another job.
+//
+// The result replaces the image's OCR output, not its corrections: an image
+// that was already corrected keeps showing the corrected hOCR, and the engine's
+// result only shows once those corrections are reverted.
+func (h *Handler) HandleOCRWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		h.writeError(w, "Failed to read body: "+err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	secret := os.Getenv("OCR_WEBHOOK_SECRET")
+	if secret == "" {
+		h.writeError(w, "OCR webhooks are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	if err := webhook.Verify(secret, r.Header.Get(webhook.SignatureHeader), r.Header.Get(webhook.TimestampHeader), body, time.Now()); err != nil {
+		slog.Warn("Rejected OCR webhook", "path", r.URL.Path, "err", err)
+		h.writeError(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	jobID := strings.TrimPrefix(r.URL.Path, APIPrefix+"/webhooks/ocr/")
+	job, ok := h.externalJobStore.Get(jobID)
+	if !ok {
+		h.writeError(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if job.Status != models.ExternalJobPending {
+		h.writeError(w, "Job already "+job.Status, http.StatusConflict)
+		return
+	}
+
+	var payload OCRWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if payload.JobID != jobID {
+		slog.Warn("Rejected OCR webhook for another job", "path", r.URL.Path, "payload_job_id", payload.JobID)
+		h.writeError(w, "job_id does not match the callback URL", http.StatusBadRequest)
+		return
+	}
+
+	var session *models.CorrectionSession
+	var image *models.ImageItem
+	switch payload.Status {
+	case models.ExternalJobCompleted:
+		if _, err := hocr.ParseHOCRLines(payload.HOCR); err != nil || payload.HOCR == "" {
+			h.writeError(w, "Completed job must include valid hocr", http.StatusBadRequest)
+			return
+		}
+
+		session, ok = h.sessionStore.Get(job.SessionID)
+		if !ok {
+			h.writeError(w, "Session no longer exists", http.StatusGone)
+			return
+		}
+		image = findImage(session, job.ImageID)
+		if image == nil {
+			h.writeError(w, "Image no longer exists", http.StatusGone)
+			return
+		}
+	case models.ExternalJobFailed:
+		payload.HOCR = ""
+	default:
+		h.writeError(w, "status must be completed or failed", http.StatusBadRequest)
+		return
+	}
+
+	// A retried callback may race this one; only the first to complete the job
+	// attaches its result
+	job, err = h.externalJobStore.Complete(jobID, payload.Status, payload.Error, time.Now())
+	if errors.Is(err, storage.ErrExternalJobFinished) {
+		h.writeError(w, "Job already "+job.Status, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if image != nil {
+		h.parsed.forget(currentHOCR(image))
+		image.OriginalHOCR = payload.HOCR
+		h.sessionStore.Set(session.ID, session)
+		h.publishHOCRUpdate(nil, session, image)
+	}
+
+	slog.Info("External OCR job finished", "job_id", job.ID, "status", job.Status, "engine", job.Engine)
+	h.writeJSON(w, job)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/storage"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/webhook"
+)
+
+func TestOCRWebhookIsBoundToItsJob(t *testing.T) {
+	t.Setenv("OCR_WEBHOOK_SECRET", "s3cret")
+	h := &Handler{externalJobStore: storage.NewExternalJobStore()}
+	for _, id := range []string{"ext_1", "ext_2"} {
+		h.externalJobStore.Set(models.ExternalJob{ID: id, Status: models.ExternalJobPending})
+	}
+
+	now := time.Now()
+	body := []byte(`{"job_id":"ext_1","status":"failed","error":"engine crashed"}`)
+	post := func(jobID string) int {
+		r := httptest.NewRequest("POST", APIPrefix+"/webhooks/ocr/"+jobID, bytes.NewReader(body))
+		r.Header.Set(webhook.SignatureHeader, webhook.Sign("s3cret", now, body))
+		r.Header.Set(webhook.TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		w := httptest.NewRecorder()
+		h.HandleOCRWebhook(w, r)
+		return w.Code
+	}
+
+	if code := post("ext_2"); code != http.StatusBadRequest {
+		t.Errorf("callback replayed to another job: got %d, want %d", code, http.StatusBadRequest)
+	}
+	if job, _ := h.externalJobStore.Get("ext_2"); job.Status != models.ExternalJobPending {
+		t.Errorf("replayed callback left the other job %s", job.Status)
+	}
+	if code := post("ext_1"); code != http.StatusOK {
+		t.Errorf("callback for its own job: got %d, want %d", code, http.StatusOK)
+	}
+}
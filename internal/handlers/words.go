@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/hocr"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// handleWords splits, merges, moves and adds words at
+// /sessions/{id}/images/{imageID}/words/{split,merge,move,add}, saving the
+// result as the image's corrected hOCR
+func (h *Handler) handleWords(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem, subpath string) {
+	if r.Method == "PATCH" {
+		h.handleUpdateWord(w, r, session, image, subpath)
+		return
+	}
+	if r.Method != "POST" {
+		h.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subpath == "add" {
+		h.handleAddWord(w, r, session, image)
+		return
+	}
+
+	lines, err := hocr.ParseHOCRLines(currentHOCR(image))
+	if err != nil {
+		h.writeError(w, "Failed to parse hOCR: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var wordIDs []string
+	switch subpath {
+	case "split":
+		var request SplitWordRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Words on vertical lines are split down their height
+		at := request.X
+		if request.Y != 0 {
+			at = request.Y
+		}
+		lines, wordIDs, err = hocr.SplitWord(lines, request.WordID, at, request.Texts)
+	case "merge":
+		var request MergeWordsRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		var wordID string
+		lines, wordID, err = hocr.MergeWords(lines, request.WordIDs, request.Separator)
+		wordIDs = []string{wordID}
+	case "move":
+		var request MoveWordRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		index := -1
+		if request.Index != nil {
+			index = *request.Index
+		}
+		lines, err = hocr.MoveWord(lines, request.WordID, request.LineID, index)
+		wordIDs = []string{request.WordID}
+	default:
+		h.writeError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, hocr.ErrWordNotFound) || errors.Is(err, hocr.ErrLineNotFound) {
+		h.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hocrXML := h.saveEditedLines(r, session, image, lines)
+	slog.Info("Words edited", "session_id", session.ID, "image_id", image.ID, "edit", subpath, "word_ids", wordIDs)
+	h.writeJSON(w, WordEditResponse{HOCR: hocrXML, WordIDs: wordIDs})
+}
+
+// handleUpdateWord changes one word's box and/or text at PATCH
+// /sessions/{id}/images/{imageID}/words/{wordID}, so a small tweak doesn't
+// mean sending the whole document
+func (h *Handler) handleUpdateWord(w http.ResponseWriter, r *http.Request, session *models.CorrectionSession, image *models.ImageItem, wordID string) {
+	var request UpdateWordRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.BBox == nil && request.Text == nil {
+		h.writeError(w, "bbox or text is required", http.StatusBadRequest)
+		return
+	}
+	if box := request.BBox; box != nil && image.ImageWidth > 0 && image.ImageHeight > 0 && (box.X2 > image.ImageWidth || box.Y2 > image.ImageHeight) {
+		h.writeError(w, fmt.Sprintf("bbox must lie within the %dx%d page", image.ImageWidth, image.ImageHeight), http.StatusBadRequest)
+		return
+	}
+
+	lines, err := hocr.ParseHOCRLines(currentHOCR(image))
+	if err != nil {
+		h.writeError(w, "Failed to parse hOCR: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	lines, err = hocr.UpdateWord(lines, wordID, request.BBox, request.Text)
+	if errors.Is(err, hocr.ErrWordNotFound) {
+		h.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hocrXML := h.saveEditedLines(r, session, image, lines)
+	slog.Info("Word updated", "session_id", session.ID, "image_id", image.ID, "word_id", wordID, "bbox", request.BBox != nil, "text", request.Text != nil)
+	h.writeJSON(w, WordEditResponse{HOCR: hocrXML, WordIDs: []string{wordID}})
+}
+
+// sessionConverter writes hOCR marked with the session's language, and its
+// direction
+func sessionConverter(session *models.CorrectionSession) *hocr.Converter {
+	converter := hocr.NewConverter()
+	converter.Language = session.Config.Language
+	return converter
+}
+
+// saveEditedLines stores lines as an image's corrected hOCR and tells other
+// editors of the page, returning the hOCR
+func (h *Handler) saveEditedLines(r *http.Request, session *models.CorrectionSession, image *models.ImageItem, lines []models.HOCRLine) string {
+	hocrXML := sessionConverter(session).ConvertHOCRLinesToXML(lines, image.ImageWidth, image.ImageHeight)
+	h.parsed.forget(currentHOCR(image))
+	image.CorrectedHOCR = hocrXML
+	markDrupalSyncPending(image)
+	h.sessionStore.Set(session.ID, session)
+	h.publishHOCRUpdate(r, session, image)
+	return hocrXML
+}
@@ -0,0 +1,195 @@
+package hocr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+const (
+	defaultFixedThreshold = 75.0
+	defaultSauvolaWindow  = 25
+	defaultSauvolaK       = 0.34
+	sauvolaDynamicRange   = 128.0
+)
+
+// NormalizeBinarization fills in defaults, drops settings the method doesn't
+// use and validates the method, so equivalent configs compare equal
+func NormalizeBinarization(cfg models.BinarizationConfig) (models.BinarizationConfig, error) {
+	if cfg.Method == "" {
+		cfg.Method = models.BinarizeFixed
+	}
+
+	switch cfg.Method {
+	case models.BinarizeFixed:
+		if cfg.Threshold <= 0 || cfg.Threshold >= 100 {
+			cfg.Threshold = defaultFixedThreshold
+		}
+		cfg.WindowSize, cfg.K = 0, 0
+	case models.BinarizeOtsu:
+		cfg.Threshold, cfg.WindowSize, cfg.K = 0, 0, 0
+	case models.BinarizeSauvola:
+		if cfg.WindowSize <= 0 {
+			cfg.WindowSize = defaultSauvolaWindow
+		}
+		// Window must be odd so it is centered on the pixel
+		if cfg.WindowSize%2 == 0 {
+			cfg.WindowSize++
+		}
+		if cfg.K <= 0 {
+			cfg.K = defaultSauvolaK
+		}
+		cfg.Threshold = 0
+	default:
+		return cfg, fmt.Errorf("unknown binarization method: %s", cfg.Method)
+	}
+
+	return cfg, nil
+}
+
+// isAdaptive reports whether thresholding happens in Go rather than in ImageMagick
+func isAdaptive(cfg models.BinarizationConfig) bool {
+	return cfg.Method == models.BinarizeOtsu || cfg.Method == models.BinarizeSauvola
+}
+
+// binarize thresholds a grayscale image, returning text pixels as black and background as white
+func binarize(img image.Image, cfg models.BinarizationConfig) *image.Gray {
+	gray := toGray(img)
+
+	switch cfg.Method {
+	case models.BinarizeSauvola:
+		return binarizeSauvola(gray, cfg.WindowSize, cfg.K)
+	default:
+		return binarizeGlobal(gray, otsuThreshold(gray))
+	}
+}
+
+func toGray(img image.Image) *image.Gray {
+	if gray, ok := img.(*image.Gray); ok {
+		return gray
+	}
+
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			gray.Set(x, y, color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)))
+		}
+	}
+	return gray
+}
+
+// otsuThreshold picks the global threshold that maximizes between-class variance
+func otsuThreshold(gray *image.Gray) uint8 {
+	var histogram [256]int
+	bounds := gray.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			histogram[gray.GrayAt(x, y).Y]++
+		}
+	}
+
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 {
+		return 128
+	}
+
+	sum := 0.0
+	for i, count := range histogram {
+		sum += float64(i * count)
+	}
+
+	var sumBackground, bestVariance float64
+	var weightBackground int
+	threshold := 0
+
+	for i, count := range histogram {
+		weightBackground += count
+		if weightBackground == 0 {
+			continue
+		}
+		weightForeground := total - weightBackground
+		if weightForeground == 0 {
+			break
+		}
+
+		sumBackground += float64(i * count)
+		meanBackground := sumBackground / float64(weightBackground)
+		meanForeground := (sum - sumBackground) / float64(weightForeground)
+
+		variance := float64(weightBackground) * float64(weightForeground) * (meanBackground - meanForeground) * (meanBackground - meanForeground)
+		if variance > bestVariance {
+			bestVariance = variance
+			threshold = i
+		}
+	}
+
+	return uint8(threshold)
+}
+
+func binarizeGlobal(gray *image.Gray, threshold uint8) *image.Gray {
+	bounds := gray.Bounds()
+	out := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			if gray.GrayAt(bounds.Min.X+x, bounds.Min.Y+y).Y <= threshold {
+				out.SetGray(x, y, color.Gray{Y: 0})
+			} else {
+				out.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return out
+}
+
+// binarizeSauvola applies Sauvola local thresholding using integral images so the
+// cost is independent of the window size:
+//
+//	T(x,y) = mean * (1 + k * (stddev/R - 1))
+func binarizeSauvola(gray *image.Gray, window int, k float64) *image.Gray {
+	bounds := gray.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	stride := width + 1
+
+	integral := make([]float64, stride*(height+1))
+	integralSq := make([]float64, stride*(height+1))
+	for y := 0; y < height; y++ {
+		var rowSum, rowSumSq float64
+		for x := 0; x < width; x++ {
+			v := float64(gray.GrayAt(bounds.Min.X+x, bounds.Min.Y+y).Y)
+			rowSum += v
+			rowSumSq += v * v
+			integral[(y+1)*stride+x+1] = integral[y*stride+x+1] + rowSum
+			integralSq[(y+1)*stride+x+1] = integralSq[y*stride+x+1] + rowSumSq
+		}
+	}
+
+	half := window / 2
+	out := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y1 := max(0, y-half)
+		y2 := min(height, y+half+1)
+		for x := 0; x < width; x++ {
+			x1 := max(0, x-half)
+			x2 := min(width, x+half+1)
+			count := float64((x2 - x1) * (y2 - y1))
+
+			sum := integral[y2*stride+x2] - integral[y1*stride+x2] - integral[y2*stride+x1] + integral[y1*stride+x1]
+			sumSq := integralSq[y2*stride+x2] - integralSq[y1*stride+x2] - integralSq[y2*stride+x1] + integralSq[y1*stride+x1]
+
+			mean := sum / count
+			stddev := math.Sqrt(math.Max(0, sumSq/count-mean*mean))
+			threshold := mean * (1 + k*(stddev/sauvolaDynamicRange-1))
+
+			if float64(gray.GrayAt(bounds.Min.X+x, bounds.Min.Y+y).Y) <= threshold {
+				out.SetGray(x, y, color.Gray{Y: 0})
+			} else {
+				out.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package hocr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestOtsuThreshold(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			v := uint8(200)
+			if x < 3 {
+				v = 40
+			}
+			gray.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+
+	threshold := otsuThreshold(gray)
+	if threshold < 40 || threshold >= 200 {
+		t.Fatalf("otsuThreshold() = %d; want between 40 and 200", threshold)
+	}
+
+	out := binarizeGlobal(gray, threshold)
+	if out.GrayAt(0, 0).Y != 0 || out.GrayAt(9, 9).Y != 255 {
+		t.Errorf("binarizeGlobal() did not separate dark and light regions")
+	}
+}
+
+func TestSauvolaHandlesUnevenLighting(t *testing.T) {
+	// Background brightens left to right; a dark stroke sits in each half
+	gray := image.NewGray(image.Rect(0, 0, 60, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 60; x++ {
+			gray.SetGray(x, y, color.Gray{Y: uint8(120 + x*2)})
+		}
+	}
+	for y := 5; y < 15; y++ {
+		gray.SetGray(10, y, color.Gray{Y: 60})
+		gray.SetGray(50, y, color.Gray{Y: 150})
+	}
+
+	cfg, err := NormalizeBinarization(models.BinarizationConfig{Method: models.BinarizeSauvola, WindowSize: 14})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.WindowSize != 15 {
+		t.Errorf("WindowSize = %d; want odd window 15", cfg.WindowSize)
+	}
+
+	out := binarize(gray, cfg)
+	if out.GrayAt(10, 10).Y != 0 || out.GrayAt(50, 10).Y != 0 {
+		t.Errorf("expected both strokes to be detected as text")
+	}
+	if out.GrayAt(30, 10).Y != 255 {
+		t.Errorf("expected background to stay white")
+	}
+}
+
+func TestNormalizeBinarizationRejectsUnknownMethod(t *testing.T) {
+	if _, err := NormalizeBinarization(models.BinarizationConfig{Method: "magic"}); err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
@@ -0,0 +1,240 @@
+package hocr
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// maxTesseractCharacters bounds the whitelist or blacklist given to Tesseract.
+// Larger sets, such as all of Han, are still checked, just not passed on.
+const maxTesseractCharacters = 4096
+
+// asciiDigits are the digits 0 to 9, which unicode.Digit goes far beyond
+var asciiDigits = &unicode.RangeTable{R16: []unicode.Range16{{Lo: '0', Hi: '9', Stride: 1}}}
+
+// characterSets are the sets of characters a session can name in braces, as
+// in "{digits}", with how the LLM is told about them
+var characterSets = map[string]struct {
+	table       *unicode.RangeTable
+	description string
+}{
+	"digits":      {asciiDigits, "the digits 0-9"},
+	"latin":       {unicode.Latin, "Latin letters"},
+	"greek":       {unicode.Greek, "Greek letters"},
+	"cyrillic":    {unicode.Cyrillic, "Cyrillic letters"},
+	"hebrew":      {unicode.Hebrew, "Hebrew letters"},
+	"arabic":      {unicode.Arabic, "Arabic letters"},
+	"han":         {unicode.Han, "Chinese characters"},
+	"punctuation": {unicode.Punct, "punctuation"},
+}
+
+// wordSpanID finds the id of a word span
+var wordSpanID = regexp.MustCompile(`\bid=['"]([^'"]*)['"]`)
+
+// charClass is a set of characters: named sets and literal characters
+type charClass struct {
+	sets  []string
+	runes []rune
+}
+
+func (c charClass) empty() bool {
+	return len(c.sets) == 0 && len(c.runes) == 0
+}
+
+func (c charClass) contains(r rune) bool {
+	if slices.Contains(c.runes, r) {
+		return true
+	}
+	for _, name := range c.sets {
+		if unicode.Is(characterSets[name].table, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// characters lists every printable character of the class, or false when
+// there are more than maxTesseractCharacters
+func (c charClass) characters() (string, bool) {
+	seen := make(map[rune]bool)
+	var list []rune
+	add := func(r rune) {
+		if !seen[r] && unicode.IsGraphic(r) && !unicode.IsSpace(r) {
+			seen[r] = true
+			list = append(list, r)
+		}
+	}
+	for _, r := range c.runes {
+		add(r)
+	}
+	for _, name := range c.sets {
+		table := characterSets[name].table
+		for _, span := range table.R16 {
+			for r := rune(span.Lo); r <= rune(span.Hi); r += rune(span.Stride) {
+				add(r)
+			}
+		}
+		for _, span := range table.R32 {
+			for r := rune(span.Lo); r <= rune(span.Hi); r += rune(span.Stride) {
+				add(r)
+			}
+		}
+		if len(list) > maxTesseractCharacters {
+			return "", false
+		}
+	}
+	return string(list), len(list) <= maxTesseractCharacters
+}
+
+// describe tells the LLM what's in the class, as in "the digits 0-9 and
+// these characters: .,-"
+func (c charClass) describe() string {
+	var parts []string
+	for _, name := range c.sets {
+		parts = append(parts, characterSets[name].description)
+	}
+	if len(c.runes) > 0 {
+		parts = append(parts, "these characters: "+string(c.runes))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// Charset is the characters a session's text may hold: those allowed, when
+// any are, less those forbidden. Spaces are always allowed.
+type Charset struct {
+	allowed, forbidden charClass
+}
+
+// ParseCharset reads a session's allowed and forbidden characters. Each is a
+// string of literal characters and of sets named in braces: {digits},
+// {latin}, {greek}, {cyrillic}, {hebrew}, {arabic}, {han} and {punctuation}.
+// "{digits}.,-" allows ledger figures; a forbidden "{cyrillic}" rules out
+// Cyrillic look-alikes of Latin letters.
+func ParseCharset(config models.CharacterConfig) (Charset, error) {
+	allowed, err := parseCharClass(config.Allowed)
+	if err != nil {
+		return Charset{}, fmt.Errorf("allowed characters: %w", err)
+	}
+	forbidden, err := parseCharClass(config.Forbidden)
+	if err != nil {
+		return Charset{}, fmt.Errorf("forbidden characters: %w", err)
+	}
+	return Charset{allowed: allowed, forbidden: forbidden}, nil
+}
+
+func parseCharClass(value string) (charClass, error) {
+	var class charClass
+	for value != "" {
+		if strings.HasPrefix(value, "{") {
+			name, rest, ok := strings.Cut(value[1:], "}")
+			if !ok {
+				return class, fmt.Errorf("unclosed set name in %q", value)
+			}
+			name = strings.ToLower(strings.TrimSpace(name))
+			if _, known := characterSets[name]; !known {
+				return class, fmt.Errorf("unknown character set: {%s}", name)
+			}
+			if !slices.Contains(class.sets, name) {
+				class.sets = append(class.sets, name)
+			}
+			value = rest
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(value)
+		if !unicode.IsSpace(r) && !slices.Contains(class.runes, r) {
+			class.runes = append(class.runes, r)
+		}
+		value = value[size:]
+	}
+	return class, nil
+}
+
+// IsZero reports whether every character is allowed
+func (c Charset) IsZero() bool {
+	return c.allowed.empty() && c.forbidden.empty()
+}
+
+// Allows reports whether a character may appear in the text
+func (c Charset) Allows(r rune) bool {
+	if unicode.IsSpace(r) {
+		return true
+	}
+	if c.forbidden.contains(r) {
+		return false
+	}
+	return c.allowed.empty() || c.allowed.contains(r)
+}
+
+// Disallowed are the characters of text that aren't allowed, each once, in
+// the order they appear
+func (c Charset) Disallowed(text string) string {
+	var found []rune
+	for _, r := range text {
+		if !c.Allows(r) && !slices.Contains(found, r) {
+			found = append(found, r)
+		}
+	}
+	return string(found)
+}
+
+// tesseractArgs are the -c settings that keep Tesseract to the characters
+func (c Charset) tesseractArgs() []string {
+	var args []string
+	for _, setting := range []struct {
+		name  string
+		class charClass
+	}{{"tessedit_char_whitelist", c.allowed}, {"tessedit_char_blacklist", c.forbidden}} {
+		if setting.class.empty() {
+			continue
+		}
+		characters, ok := setting.class.characters()
+		if !ok {
+			slog.Warn("Too many characters to pass to Tesseract, checking them afterwards only", "setting", setting.name, "sets", setting.class.sets)
+			continue
+		}
+		args = append(args, "-c", setting.name+"="+characters)
+	}
+	return args
+}
+
+// hint tells the LLM which characters to use, or nothing when any may be
+func (c Charset) hint() string {
+	var hints []string
+	if !c.allowed.empty() {
+		hints = append(hints, "Use only "+c.allowed.describe()+", and spaces.")
+	}
+	if !c.forbidden.empty() {
+		hints = append(hints, "Never use "+c.forbidden.describe()+".")
+	}
+	return strings.Join(hints, "\n")
+}
+
+// check finds the word spans of LLM output with characters that aren't
+// allowed, to send back for another try
+func (c Charset) check(markup string) error {
+	if c.IsZero() {
+		return nil
+	}
+	var violations []string
+	for _, parts := range wordLanguageSpan.FindAllStringSubmatch(markup, -1) {
+		if disallowed := c.Disallowed(html.UnescapeString(parts[2])); disallowed != "" {
+			id := "a word"
+			if match := wordSpanID.FindStringSubmatch(parts[1]); match != nil {
+				id = match[1]
+			}
+			violations = append(violations, fmt.Sprintf("%s has %s", id, disallowed))
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("characters that aren't allowed: %s", strings.Join(violations, "; "))
+}
@@ -0,0 +1,79 @@
+package hocr
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestParseCharset(t *testing.T) {
+	for _, config := range []models.CharacterConfig{
+		{Allowed: "{digits"},
+		{Allowed: "{runes}"},
+		{Forbidden: "{cyrillic}{}"},
+	} {
+		if _, err := ParseCharset(config); err == nil {
+			t.Errorf("ParseCharset(%+v) accepted", config)
+		}
+	}
+
+	charset, err := ParseCharset(models.CharacterConfig{})
+	if err != nil || !charset.IsZero() || charset.Disallowed("Жλ1") != "" {
+		t.Errorf("empty config limits characters: %v", err)
+	}
+}
+
+func TestCharsetDisallowed(t *testing.T) {
+	for _, test := range []struct {
+		config     models.CharacterConfig
+		text, want string
+	}{
+		// A ledger of figures
+		{models.CharacterConfig{Allowed: "{Digits}.,-"}, "1,204.50", ""},
+		{models.CharacterConfig{Allowed: "{digits}.,-"}, "l2O4 ٣", "lO٣"},
+		// Cyrillic look-alikes of Latin letters
+		{models.CharacterConfig{Forbidden: "{cyrillic}"}, "Cоре ΑΒ", "оре"},
+		{models.CharacterConfig{Allowed: "{latin}{punctuation}", Forbidden: "ſ"}, "Meſſe, 1848", "ſ184"},
+	} {
+		charset, err := ParseCharset(test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := charset.Disallowed(test.text); got != test.want {
+			t.Errorf("%+v Disallowed(%q) = %q, want %q", test.config, test.text, got, test.want)
+		}
+	}
+}
+
+func TestCharsetTesseractArgs(t *testing.T) {
+	charset, _ := ParseCharset(models.CharacterConfig{Allowed: "{digits}.,", Forbidden: "O"})
+	want := []string{"-c", "tessedit_char_whitelist=.,0123456789", "-c", "tessedit_char_blacklist=O"}
+	if got := charset.tesseractArgs(); !slices.Equal(got, want) {
+		t.Errorf("tesseractArgs() = %q, want %q", got, want)
+	}
+
+	// Han is checked afterwards, being too big to pass on
+	charset, _ = ParseCharset(models.CharacterConfig{Allowed: "{han}"})
+	if got := charset.tesseractArgs(); len(got) != 0 {
+		t.Errorf("tesseractArgs() = %d args for Han", len(got))
+	}
+}
+
+func TestCharsetCheck(t *testing.T) {
+	charset, _ := ParseCharset(models.CharacterConfig{Allowed: "{digits}"})
+	markup := `<span class='ocrx_line' id='line_1' title='bbox 0 0 9 9'><span class='ocrx_word' id='word_1' title='bbox 0 0 9 9'>1848</span> <span class='ocrx_word' id='word_2' title='bbox 0 0 9 9'>l9O2</span></span>`
+	err := charset.check(markup)
+	if err == nil || !strings.Contains(err.Error(), "word_2 has lO") || strings.Contains(err.Error(), "word_1") {
+		t.Errorf("check() = %v", err)
+	}
+	if err := (Charset{}).check(markup); err != nil {
+		t.Errorf("unlimited check() = %v", err)
+	}
+
+	prompt := promptFor(Options{Characters: charset})
+	if !strings.HasSuffix(prompt, "Use only the digits 0-9, and spaces.") {
+		t.Errorf("prompt lacks the characters:\n%s", prompt)
+	}
+}
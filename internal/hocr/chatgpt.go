@@ -1,12 +1,16 @@
 package hocr
 
 import (
-	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
+	"image"
+	"image/draw"
+	"image/png"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
@@ -14,7 +18,11 @@ import (
 	"strings"
 	"time"
 
+	xdraw "golang.org/x/image/draw"
+
 	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/retry"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/textrender"
 	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
 )
 
@@ -47,17 +55,19 @@ type ChatGPTResponse struct {
 	} `json:"choices"`
 }
 
-func (s *Service) createStitchedImageWithHOCRMarkup(imagePath string, response models.OCRResponse) (string, error) {
-	tempDir := "/tmp"
+// createStitchedImageWithHOCRMarkup stacks the words in chunk, cut from the page
+// image, each wrapped in hOCR tags numbered by its position on the whole page.
+// The rows are laid out first, then drawn into one image that is written once.
+func (s *Service) createStitchedImageWithHOCRMarkup(ws *workspace, imagePath string, pageImage image.Image, response models.OCRResponse, chunk wordRange) (string, error) {
 	baseName := strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath))
-	stitchedPath := filepath.Join(tempDir, fmt.Sprintf("stitched_%s_%d.png", baseName, time.Now().Unix()))
-
-	var componentPaths []string
+	stitchedPath := ws.path(fmt.Sprintf("stitched_%s_%d.png", baseName, chunk.start))
 
 	if len(response.Responses) == 0 || response.Responses[0].FullTextAnnotation == nil {
 		return "", fmt.Errorf("no text annotation in response")
 	}
 
+	closeTag := stitchRow{markup: "</span>"}
+	var rows []stitchRow
 	wordIndex := 0
 	for _, page := range response.Responses[0].FullTextAnnotation.Pages {
 		for _, block := range page.Blocks {
@@ -66,177 +76,274 @@ func (s *Service) createStitchedImageWithHOCRMarkup(imagePath string, response m
 					if len(word.BoundingBox.Vertices) < 4 {
 						continue
 					}
-
-					bbox := word.BoundingBox
-
-					// Create hOCR line opening tag
-					lineTag := fmt.Sprintf(`<span class='ocrx_line' id='line_%d' title='bbox %d %d %d %d'>`,
-						wordIndex+1,
-						bbox.Vertices[0].X, bbox.Vertices[0].Y,
-						bbox.Vertices[2].X, bbox.Vertices[2].Y)
-					lineTagPath, err := s.createTextImage(lineTag, tempDir, fmt.Sprintf("line_%d", wordIndex))
-					if err != nil {
-						utils.ExitOnError("Unable to add line hOCR text to stitched image", err)
-					}
-
-					componentPaths = append(componentPaths, lineTagPath)
-
-					// Create hOCR word opening tag
-					wordTag := fmt.Sprintf(`<span class='ocrx_word' id='word_%d' title='bbox %d %d %d %d'>`,
-						wordIndex+1,
-						bbox.Vertices[0].X, bbox.Vertices[0].Y,
-						bbox.Vertices[2].X, bbox.Vertices[2].Y)
-					wordTagPath, err := s.createTextImage(wordTag, tempDir, fmt.Sprintf("word_%d", wordIndex))
-					if err != nil {
-						utils.ExitOnError("Unable to add word hOCR text to stitched image", err)
-					}
-					componentPaths = append(componentPaths, wordTagPath)
-
-					// Extract the actual word image
-					wordImagePath, err := s.extractWordImage(imagePath, bbox, tempDir, wordIndex)
-					if err != nil {
-						utils.ExitOnError("Unable to add image cutout to stitched image", err)
+					if wordIndex < chunk.start || wordIndex >= chunk.end {
+						wordIndex++
+						continue
 					}
-					componentPaths = append(componentPaths, wordImagePath)
 
-					// Create closing tags
-					wordClosePath, err := s.createTextImage("</span>", tempDir, fmt.Sprintf("word_close_%d", wordIndex))
-					if err != nil {
-						utils.ExitOnError("Unable to add closing word span to stitched image", err)
-					}
-					componentPaths = append(componentPaths, wordClosePath)
+					bbox := word.BoundingBox
 
-					lineClosePath, err := s.createTextImage("</span>", tempDir, fmt.Sprintf("line_close_%d", wordIndex))
+					// Cut the actual word out of the page
+					crop, size, err := wordCrop(pageImage, bbox)
 					if err != nil {
-						utils.ExitOnError("Unable to add closing line span to stitched image", err)
+						return "", fmt.Errorf("failed to add image cutout to stitched image: %w", err)
 					}
-					componentPaths = append(componentPaths, lineClosePath)
 
+					rows = append(rows,
+						stitchRow{markup: fmt.Sprintf(`<span class='ocrx_line' id='line_%d' title='bbox %d %d %d %d'>`,
+							wordIndex+1,
+							bbox.Vertices[0].X, bbox.Vertices[0].Y,
+							bbox.Vertices[2].X, bbox.Vertices[2].Y)},
+						stitchRow{markup: fmt.Sprintf(`<span class='ocrx_word' id='word_%d' title='bbox %d %d %d %d'>`,
+							wordIndex+1,
+							bbox.Vertices[0].X, bbox.Vertices[0].Y,
+							bbox.Vertices[2].X, bbox.Vertices[2].Y)},
+						stitchRow{crop: crop, size: size},
+						closeTag, closeTag)
 					wordIndex++
 				}
 			}
 		}
 	}
 
-	if len(componentPaths) == 0 {
+	if len(rows) == 0 {
 		return "", fmt.Errorf("no valid components were created")
 	}
 
-	// Stitch all components together vertically
-	args := append(componentPaths, "-append", stitchedPath)
-	cmd := exec.Command("magick", args...)
-	err := cmd.Run()
+	stitched, err := stitchRows(pageImage, rows)
+	if err != nil {
+		return "", fmt.Errorf("failed to stitch components: %w", err)
+	}
+	if err := writePNG(stitchedPath, stitched); err != nil {
+		return "", fmt.Errorf("failed to stitch components: %w", err)
+	}
+	return stitchedPath, nil
+}
+
+// Markup rows of the stitched image are a line of Go Mono this high, drawn
+// with a margin on either side
+const (
+	tagHeight   = 60
+	tagSize     = 24
+	tagMargin   = 10
+	tagBaseline = 40
+)
 
-	// Clean up component images
-	for _, componentPath := range componentPaths {
-		os.Remove(componentPath)
+// stitchRow is one row of the stitched image: a line of hOCR markup, or the
+// page region crop of a word, drawn at size
+type stitchRow struct {
+	markup string
+	crop   image.Rectangle
+	size   image.Point
+}
+
+func (row stitchRow) height() int {
+	if row.markup != "" {
+		return tagHeight
 	}
+	return row.size.Y
+}
 
-	if err != nil {
-		return "", fmt.Errorf("failed to stitch components: %w", err)
+// stitchRows stacks rows top to bottom, left-aligned on white, as ImageMagick's
+// -append does. The canvas is sized before anything is drawn and every row is
+// drawn straight into it, in grayscale, which is all transcription needs, so a
+// chunk costs one byte per pixel of its stitched image.
+func stitchRows(page image.Image, rows []stitchRow) (*image.Gray, error) {
+	width, height := 0, 0
+	for _, row := range rows {
+		rowWidth := row.size.X
+		if row.markup != "" {
+			textWidth, err := textrender.Width(row.markup, true, tagSize)
+			if err != nil {
+				return nil, err
+			}
+			rowWidth = tagMargin + textWidth + tagMargin
+		}
+		width = max(width, rowWidth)
+		height += row.height()
 	}
 
-	return stitchedPath, nil
+	stitched := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(stitched, stitched.Bounds(), image.White, image.Point{}, draw.Src)
+	y := 0
+	for _, row := range rows {
+		if row.markup != "" {
+			if err := textrender.Draw(stitched, row.markup, textrender.Options{Size: tagSize, X: tagMargin, Baseline: y + tagBaseline, Mono: true}); err != nil {
+				return nil, err
+			}
+		} else {
+			target := image.Rectangle{Min: image.Pt(0, y), Max: image.Pt(row.size.X, y+row.size.Y)}
+			if row.size == row.crop.Size() {
+				draw.Draw(stitched, target, page, row.crop.Min, draw.Src)
+			} else {
+				xdraw.CatmullRom.Scale(stitched, target, page, row.crop, draw.Src, nil)
+			}
+		}
+		y += row.height()
+	}
+	return stitched, nil
 }
 
-func (s *Service) createTextImage(text, tempDir, filename string) (string, error) {
-	outputPath := filepath.Join(tempDir, fmt.Sprintf("%s_%d.png", filename, time.Now().Unix()))
+// readImage decodes an image file
+func readImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
 
-	cmd := exec.Command("magick",
-		"-size", "2000x60",
-		"xc:white",
-		"-fill", "black",
-		"-font", "DejaVu-Sans-Mono",
-		"-pointsize", "24",
-		"-draw", fmt.Sprintf(`text 10,40 "%s"`, text),
-		outputPath)
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+	}
+	return img, nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to create text image: %w", err)
+// writePNG encodes an image to a PNG file
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
 
-	return outputPath, nil
+// loadPageImage decodes the page once for the words of every chunk to be cut
+// out of, converting it to PNG first when it's in a format Go can't read
+func (s *Service) loadPageImage(ws *workspace, imagePath string) (image.Image, error) {
+	if img, err := readImage(imagePath); err == nil {
+		return img, nil
+	}
+
+	convertedPath := ws.path(strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath)) + "_page.png")
+	defer ws.remove(convertedPath)
+	if output, err := exec.Command("magick", imagePath+"[0]", convertedPath).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to convert page image: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return readImage(convertedPath)
 }
 
-func (s *Service) extractWordImage(imagePath string, bbox models.BoundingPoly, tempDir string, wordIndex int) (string, error) {
+// wordCrop is the page region a word is cut from, with a little padding, and
+// the size it's drawn at: its own, or shrunk to fit under the tags when it's
+// too big
+func wordCrop(page image.Image, bbox models.BoundingPoly) (image.Rectangle, image.Point, error) {
 	if len(bbox.Vertices) < 4 {
-		return "", fmt.Errorf("invalid bounding box")
+		return image.Rectangle{}, image.Point{}, fmt.Errorf("invalid bounding box")
 	}
 
 	minX := bbox.Vertices[0].X
 	minY := bbox.Vertices[0].Y
 	maxX := bbox.Vertices[2].X
 	maxY := bbox.Vertices[2].Y
-
-	width := maxX - minX
-	height := maxY - minY
-
-	if width <= 0 || height <= 0 {
-		return "", fmt.Errorf("invalid dimensions")
+	if maxX <= minX || maxY <= minY {
+		return image.Rectangle{}, image.Point{}, fmt.Errorf("invalid dimensions")
 	}
 
-	// Add padding
+	// Add padding, keeping within the page
 	padding := 3
-	cropX := max(0, minX-padding)
-	cropY := max(0, minY-padding)
-	cropWidth := width + 2*padding
-	cropHeight := height + 2*padding
+	bounds := page.Bounds()
+	crop := image.Rect(minX-padding, minY-padding, maxX+padding, maxY+padding).Add(bounds.Min).Intersect(bounds)
+	if crop.Empty() {
+		return image.Rectangle{}, image.Point{}, fmt.Errorf("word lies outside the page")
+	}
 
-	outputPath := filepath.Join(tempDir, fmt.Sprintf("word_img_%d_%d.png", wordIndex, time.Now().Unix()))
+	width, height := crop.Dx(), crop.Dy()
+	if width <= maxWordCropWidth && height <= maxWordCropHeight {
+		return crop, crop.Size(), nil
+	}
 
-	cmd := exec.Command("magick", imagePath,
-		"-crop", fmt.Sprintf("%dx%d+%d+%d", cropWidth, cropHeight, cropX, cropY),
-		"+repage",
-		outputPath)
+	// Only oversized crops are shrunk, keeping their proportions
+	scale := min(float64(maxWordCropWidth)/float64(width), float64(maxWordCropHeight)/float64(height))
+	return crop, image.Pt(max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))), nil
+}
+
+// transcriptionPrompt asks the LLM to read the hOCR tags and word images off a
+// stitched image
+const transcriptionPrompt = `Read and transcribe all the hOCR markup overlaid on this image.
+You will see hOCR tags like:
+<span class='ocrx_line' id='line_X' title='bbox x y w h'>
+<span class='ocrx_word' id='word_X' title='bbox x y w h'>
+[word image that needs transcription]
+</span>
+</span>
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to extract word image: %w", err)
+Transcribe BOTH the hOCR tags AND the text content inside them.
+For each word image, read the text and include it between the word tags.
+If a word image has no legible text, omit that word's span entirely.
+IMPORTANT: If the transcribed text contains special characters like &, <, >, ", or ', 
+please replace them with their XML entities: &amp; &lt; &gt; &quot; &#39;
+Return only the hOCR markup with transcribed text content.`
+
+// transcribeWithChatGPT has the LLM transcribe a stitched image of the words in chunk.
+// Output that isn't well-formed or has bad word ids is sent back with the problem
+// for another try, up to OPENAI_VALIDATION_RETRIES (default 2) times. So is
+// output with characters opts.Characters doesn't allow, but that's kept after
+// the last try, for its words to be flagged for review.
+func (s *Service) transcribeWithChatGPT(ws *workspace, imagePath string, chunk wordRange, opts Options) (string, error) {
+	if !llmConfigured() {
+		return "", fmt.Errorf("OPENAI_API_KEY (or AZURE_OPENAI_API_KEY) environment variable not set")
 	}
 
-	return outputPath, nil
-}
+	// Identical stitched images sent with the same model, prompt and temperature
+	// get the same answer
+	cacheDir := s.dirs.LLMCache
+	cacheKey := ""
+	if cacheDir != "" {
+		imageHash, err := pixelHash(imagePath)
+		if err != nil {
+			opts.logger().Warn("Unable to hash stitched image, skipping LLM cache", "err", err)
+			cacheDir = ""
+		} else {
+			cacheKey = llmCacheKey(imageHash, s.modelFor(opts), promptFor(opts), opts.Temperature)
+		}
+	}
+	if content, ok := readLLMCache(cacheDir, cacheKey); ok && !opts.Refresh {
+		opts.logger().Info("Using cached LLM transcription", "key", cacheKey)
+		opts.archive("llm_cache_hit.txt", []byte(cacheKey))
+		return content, nil
+	}
 
-func (s *Service) transcribeWithChatGPT(imagePath string) (string, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("OPENAI_API_KEY environment variable not set")
+	fittedPath, err := s.fitImageForLLM(ws, imagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to fit image to LLM limits: %w", err)
+	}
+	if fittedPath != imagePath {
+		defer ws.remove(fittedPath)
 	}
 
 	// Encode image as base64
-	imageData, err := os.ReadFile(imagePath)
+	imageData, err := os.ReadFile(fittedPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read image: %w", err)
 	}
 	imageBase64 := base64.StdEncoding.EncodeToString(imageData)
 
+	mimeType := "image/png"
+	if strings.EqualFold(filepath.Ext(fittedPath), ".jpg") {
+		mimeType = "image/jpeg"
+	}
+	opts.archive("llm_input"+filepath.Ext(fittedPath), imageData)
+
 	// Create ChatGPT request
 	request := ChatGPTRequest{
-		Model: s.getModel(),
+		Model:       s.modelFor(opts),
+		Temperature: opts.Temperature,
 		Messages: []ChatGPTMessage{
 			{
 				Role: "user",
 				Content: []ChatGPTContent{
 					{
 						Type: "text",
-						Text: `Read and transcribe all the hOCR markup overlaid on this image.
-You will see hOCR tags like:
-<span class='ocrx_line' id='line_X' title='bbox x y w h'>
-<span class='ocrx_word' id='word_X' title='bbox x y w h'>
-[word image that needs transcription]
-</span>
-</span>
-
-Transcribe BOTH the hOCR tags AND the text content inside them.
-For each word image, read the text and include it between the word tags.
-If a word image has no legible text, omit that word's span entirely.
-IMPORTANT: If the transcribed text contains special characters like &, <, >, ", or ', 
-please replace them with their XML entities: &amp; &lt; &gt; &quot; &#39;
-Return only the hOCR markup with transcribed text content.`,
+						Text: promptFor(opts),
 					},
 					{
 						Type: "image_url",
 						ImageURL: &ChatGPTImageURL{
-							URL: fmt.Sprintf("data:image/png;base64,%s", imageBase64),
+							URL: fmt.Sprintf("data:%s;base64,%s", mimeType, imageBase64),
 						},
 					},
 				},
@@ -244,48 +351,184 @@ Return only the hOCR markup with transcribed text content.`,
 		},
 	}
 
-	return s.callChatGPT(request)
+	maxReprompts := utils.GetEnvInt("OPENAI_VALIDATION_RETRIES", 2)
+	for attempt := 0; ; attempt++ {
+		suffix := ""
+		if attempt > 0 {
+			suffix = fmt.Sprintf("_reprompt_%d", attempt)
+		}
+		opts.archive("llm_request"+suffix+".json", redactedRequest(request))
+
+		content, rawResponse, err := s.callChatGPT(request, opts)
+		if rawResponse != nil {
+			opts.archive("llm_response"+suffix+".json", rawResponse)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		invalid := validateTranscription(content, chunk)
+		if invalid == nil {
+			invalid = opts.Characters.check(content)
+			if invalid == nil || attempt >= maxReprompts {
+				if invalid != nil {
+					opts.logger().Warn("ChatGPT kept characters that aren't allowed", "err", invalid)
+				}
+				writeLLMCache(cacheDir, cacheKey, content)
+				return content, nil
+			}
+		} else if attempt >= maxReprompts {
+			return "", &invalidTranscriptionError{err: invalid}
+		}
+
+		opts.logger().Warn("ChatGPT returned invalid hOCR, re-prompting", "reprompt", attempt+1, "max_reprompts", maxReprompts, "err", invalid)
+		request.Messages = append(request.Messages,
+			ChatGPTMessage{Role: "assistant", Content: []ChatGPTContent{{Type: "text", Text: content}}},
+			ChatGPTMessage{Role: "user", Content: []ChatGPTContent{{Type: "text", Text: fmt.Sprintf(
+				`That markup could not be used: %v
+Return the complete corrected hOCR markup for the image, keeping the id of every word span exactly as shown on the image. Return only the hOCR markup.`, invalid)}}},
+		)
+	}
 }
 
-func (s *Service) callChatGPT(request ChatGPTRequest) (string, error) {
+// redactedRequest serializes a request with inline image data replaced by a
+// reference to the archived input image, which is stored separately
+func redactedRequest(request ChatGPTRequest) []byte {
+	redacted := request
+	redacted.Messages = make([]ChatGPTMessage, len(request.Messages))
+	for i, message := range request.Messages {
+		redacted.Messages[i] = ChatGPTMessage{Role: message.Role, Content: make([]ChatGPTContent, len(message.Content))}
+		for j, content := range message.Content {
+			if content.ImageURL != nil && strings.HasPrefix(content.ImageURL.URL, "data:") {
+				content.ImageURL = &ChatGPTImageURL{URL: "llm_input"}
+			}
+			redacted.Messages[i].Content[j] = content
+		}
+	}
+
+	data, _ := json.MarshalIndent(redacted, "", "  ")
+	return data
+}
+
+// callChatGPT returns the cleaned transcription along with the raw response body,
+// retrying rate limits, server errors and dropped connections with backoff
+func (s *Service) callChatGPT(request ChatGPTRequest, opts Options) (string, []byte, error) {
 	requestBody, err := json.Marshal(request)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(requestBody))
+	policy := retryPolicyFromEnv()
+	var rawResponse []byte
+	for attempt := 0; ; attempt++ {
+		release := s.acquireLLMSlot()
+		rawResponse, err = s.postChatGPT(requestBody)
+		release()
+		if err == nil {
+			if attempt > 0 {
+				opts.logger().Info("ChatGPT request succeeded after retries", "retries", attempt)
+			}
+			break
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt >= policy.MaxRetries {
+			if attempt > 0 {
+				err = fmt.Errorf("%w (after %d retries)", err, attempt)
+			}
+			return "", nil, err
+		}
+
+		delay := policy.Delay(attempt, retryable.retryAfter)
+		opts.logger().Warn("ChatGPT request failed, retrying", "retry", attempt+1, "max_retries", policy.MaxRetries, "delay", delay, "err", err)
+		opts.retried(attempt+1, err)
+		time.Sleep(delay)
+	}
+
+	var chatGPTResponse ChatGPTResponse
+	if err := json.Unmarshal(rawResponse, &chatGPTResponse); err != nil {
+		return "", rawResponse, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(chatGPTResponse.Choices) == 0 {
+		return "", rawResponse, fmt.Errorf("no response from ChatGPT")
+	}
+
+	content := strings.TrimSpace(chatGPTResponse.Choices[0].Message.Content)
+	content = s.cleanChatGPTResponse(content)
+
+	return content, rawResponse, nil
+}
+
+// acquireLLMSlot waits until fewer than OPENAI_MAX_CONCURRENCY requests are in
+// flight. Slots are held per attempt, not across retry backoff.
+func (s *Service) acquireLLMSlot() func() {
+	select {
+	case s.llmSlots <- struct{}{}:
+	default:
+		slog.Info("Waiting for an LLM request slot", "limit", cap(s.llmSlots))
+		s.llmSlots <- struct{}{}
+	}
+	return func() { <-s.llmSlots }
+}
+
+// postChatGPT makes a single API call, marking failures that are worth retrying
+func (s *Service) postChatGPT(requestBody []byte) ([]byte, error) {
+	endpoint, err := newLLMEndpoint()
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("OPENAI_API_KEY"))
+	req, err := endpoint.newRequest(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
 	client := &http.Client{Timeout: 300 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+		return nil, &retryableError{err: fmt.Errorf("failed to make request: %w", err)}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ChatGPT API returned status %d: %s", resp.StatusCode, string(body))
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		err := fmt.Errorf("ChatGPT API returned status %d: %s", resp.StatusCode, apiErrorMessage(body))
+		if isRetryableStatus(resp.StatusCode) {
+			return nil, &retryableError{err: err, retryAfter: retry.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+		}
+		return nil, err
 	}
 
-	var chatGPTResponse ChatGPTResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatGPTResponse); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	rawResponse, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &retryableError{err: fmt.Errorf("failed to read response: %w", err)}
 	}
 
-	if len(chatGPTResponse.Choices) == 0 {
-		return "", fmt.Errorf("no response from ChatGPT")
-	}
+	return rawResponse, nil
+}
 
-	content := strings.TrimSpace(chatGPTResponse.Choices[0].Message.Content)
-	content = s.cleanChatGPTResponse(content)
+// maxAPIErrorMessage bounds the API's own explanation quoted in errors
+const maxAPIErrorMessage = 200
+
+// apiErrorMessage is what an error response says went wrong, for errors that
+// end up in logs and job records. Only the message of an OpenAI-style error
+// object is kept, truncated, since other bodies may echo the prompt or page text.
+func apiErrorMessage(body []byte) string {
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Error.Message == "" {
+		return fmt.Sprintf("[%d byte response omitted]", len(body))
+	}
 
-	return content, nil
+	message := []rune(response.Error.Message)
+	if len(message) > maxAPIErrorMessage {
+		return string(message[:maxAPIErrorMessage]) + "…"
+	}
+	return string(message)
 }
 
 func (s *Service) cleanChatGPTResponse(content string) string {
@@ -393,19 +636,42 @@ func (s *Service) escapeTextInSpans(line string) string {
 	return strings.Join(parts, "</span>")
 }
 
+// Model is the LLM used for transcription
+func (s *Service) Model() string {
+	return s.getModel()
+}
+
+// modelFor is the model a pipeline run uses: its own, or the configured one
+func (s *Service) modelFor(opts Options) string {
+	if opts.Model != "" {
+		return opts.Model
+	}
+	return s.getModel()
+}
+
 func (s *Service) getModel() string {
 	model := os.Getenv("OPENAI_MODEL")
 	if model == "" {
+		// Azure routes by deployment, which is usually named after its model
+		if deployment := os.Getenv("AZURE_OPENAI_DEPLOYMENT"); deployment != "" && os.Getenv("AZURE_OPENAI_ENDPOINT") != "" {
+			return deployment
+		}
 		return "gpt-4o"
 	}
 	return model
 }
 
-func (s *Service) convertToBasicHOCR(response models.OCRResponse) string {
-	var lines []string
+func (s *Service) convertToBasicHOCR(response models.OCRResponse, language string) string {
+	return s.wrapInHOCRDocument(basicHOCRLines(response, wordRange{0, len(detectedBoxes(response))}), language)
+}
 
+// basicHOCRLines marks up the detected words in chunk one to a line, with the
+// text detection gave them and the numbering the stitched images use, so they
+// can stand in for a chunk the LLM couldn't transcribe
+func basicHOCRLines(response models.OCRResponse, chunk wordRange) string {
+	var lines []string
 	if len(response.Responses) == 0 || response.Responses[0].FullTextAnnotation == nil {
-		return s.wrapInHOCRDocument("")
+		return ""
 	}
 
 	wordIndex := 0
@@ -413,31 +679,44 @@ func (s *Service) convertToBasicHOCR(response models.OCRResponse) string {
 		for _, block := range page.Blocks {
 			for _, paragraph := range block.Paragraphs {
 				for _, word := range paragraph.Words {
-					if len(word.BoundingBox.Vertices) >= 4 && len(word.Symbols) > 0 {
-						bbox := word.BoundingBox
-						text := html.EscapeString(word.Symbols[0].Text) // Use detected text with XML escaping
-						line := fmt.Sprintf(`<span class='ocrx_line' id='line_%d' title='bbox %d %d %d %d'><span class='ocrx_word' id='word_%d' title='bbox %d %d %d %d'>%s</span></span>`,
-							wordIndex+1,
-							bbox.Vertices[0].X, bbox.Vertices[0].Y,
-							bbox.Vertices[2].X, bbox.Vertices[2].Y,
-							wordIndex+1,
-							bbox.Vertices[0].X, bbox.Vertices[0].Y,
-							bbox.Vertices[2].X, bbox.Vertices[2].Y,
-							text)
-						lines = append(lines, line)
-						wordIndex++
+					if len(word.BoundingBox.Vertices) < 4 {
+						continue
+					}
+					index := wordIndex
+					wordIndex++
+					if index < chunk.start || index >= chunk.end || len(word.Symbols) == 0 {
+						continue
 					}
+
+					bbox := word.BoundingBox
+					text := html.EscapeString(word.Symbols[0].Text) // Use detected text with XML escaping
+					textAngle := ""
+					if paragraph.TextAngle != 0 {
+						textAngle = fmt.Sprintf("; textangle %d", paragraph.TextAngle)
+					}
+					line := fmt.Sprintf(`<span class='ocrx_line' id='line_%d' title='bbox %d %d %d %d%s'><span class='ocrx_word' id='word_%d' title='bbox %d %d %d %d'>%s</span></span>`,
+						index+1,
+						bbox.Vertices[0].X, bbox.Vertices[0].Y,
+						bbox.Vertices[2].X, bbox.Vertices[2].Y,
+						textAngle,
+						index+1,
+						bbox.Vertices[0].X, bbox.Vertices[0].Y,
+						bbox.Vertices[2].X, bbox.Vertices[2].Y,
+						text)
+					lines = append(lines, line)
 				}
 			}
 		}
 	}
 
-	return s.wrapInHOCRDocument(strings.Join(lines, "\n"))
+	return strings.Join(lines, "\n")
 }
 
-func (s *Service) wrapInHOCRDocument(content string) string {
+// wrapInHOCRDocument makes a page of transcribed lines into a document, marked
+// with the language of the text and, when it's read right to left, its direction
+func (s *Service) wrapInHOCRDocument(content, language string) string {
 	return fmt.Sprintf(`<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
-<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en">
+<html xmlns="http://www.w3.org/1999/xhtml"%s>
 <head>
 <title></title>
 <meta http-equiv="Content-Type" content="text/html;charset=utf-8" />
@@ -448,5 +727,5 @@ func (s *Service) wrapInHOCRDocument(content string) string {
 %s
 </div>
 </body>
-</html>`, content)
+</html>`, languageAttributes(language, documentDirection(language, markupTags.ReplaceAllString(content, ""))), content)
 }
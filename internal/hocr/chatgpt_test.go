@@ -0,0 +1,114 @@
+package hocr
+
+import (
+	"image"
+	"image/draw"
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/textrender"
+)
+
+func TestStitchRows(t *testing.T) {
+	// A page with a black word on white
+	page := image.NewGray(image.Rect(0, 0, 100, 100))
+	draw.Draw(page, page.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(page, image.Rect(5, 5, 45, 25), image.Black, image.Point{}, draw.Src)
+
+	closeTag := stitchRow{markup: "</span>"}
+	word := stitchRow{crop: image.Rect(5, 5, 45, 25), size: image.Pt(40, 20)}
+	stitched, err := stitchRows(page, []stitchRow{closeTag, word, closeTag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	width, err := textrender.Width("</span>", true, tagSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stitched.Bounds(); got.Dx() != width+2*tagMargin || got.Dy() != 2*tagHeight+20 {
+		t.Fatalf("stitched image is %v", got)
+	}
+	// The word is drawn from its region of the page, left-aligned below the
+	// tag, with white beside it
+	if got := stitched.GrayAt(0, tagHeight); got.Y != 0 {
+		t.Errorf("word pixel is %v", got)
+	}
+	if got := stitched.GrayAt(50, tagHeight); got.Y != 255 {
+		t.Errorf("pixel beside the word is %v", got)
+	}
+	// The closing tag is drawn the same wherever it's used
+	dark := 0
+	for x := range stitched.Bounds().Dx() {
+		top, bottom := stitched.GrayAt(x, 35), stitched.GrayAt(x, tagHeight+20+35)
+		if top != bottom {
+			t.Fatalf("closing tags differ at x=%d", x)
+		}
+		if top.Y < 128 {
+			dark++
+		}
+	}
+	if dark == 0 {
+		t.Error("closing tag drew nothing")
+	}
+}
+
+func TestWordCrop(t *testing.T) {
+	page := image.NewGray(image.Rect(0, 0, 3000, 1000))
+	box := func(x1, y1, x2, y2 int) models.BoundingPoly {
+		return models.BoundingPoly{Vertices: []models.Vertex{{X: x1, Y: y1}, {X: x2, Y: y1}, {X: x2, Y: y2}, {X: x1, Y: y2}}}
+	}
+
+	// Padding is kept within the page
+	crop, size, err := wordCrop(page, box(1, 10, 41, 30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crop != image.Rect(0, 7, 44, 33) || size != image.Pt(44, 26) {
+		t.Errorf("crop at the page edge is %v drawn at %v", crop, size)
+	}
+
+	// Oversized words are shrunk to fit, keeping their proportions
+	_, size, err = wordCrop(page, box(3, 3, 2973, 303))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != image.Pt(1980, 203) {
+		t.Errorf("oversized crop is drawn at %v", size)
+	}
+
+	if _, _, err := wordCrop(page, box(3100, 10, 3200, 30)); err == nil {
+		t.Error("crop outside the page accepted")
+	}
+}
+
+func TestBasicHOCRLinesKeepsPageNumbering(t *testing.T) {
+	word := func(x int, text string) models.Word {
+		box := models.BoundingPoly{Vertices: []models.Vertex{{X: x, Y: 0}, {X: x + 20, Y: 0}, {X: x + 20, Y: 20}, {X: x, Y: 20}}}
+		return models.Word{BoundingBox: box, Symbols: []models.Symbol{{Text: text}}}
+	}
+	response := models.OCRResponse{Responses: []models.Response{{FullTextAnnotation: &models.FullTextAnnotation{Pages: []models.Page{{
+		Blocks: []models.Block{{Paragraphs: []models.Paragraph{{Words: []models.Word{word(0, "one"), word(30, "two"), word(60, "three")}}}}},
+	}}}}}}
+
+	// The second chunk of a page the LLM transcribed in two
+	lines := basicHOCRLines(response, wordRange{1, 3})
+	if strings.Contains(lines, "word_1'") || !strings.Contains(lines, "id='word_2' title='bbox 30 0 50 20'>two<") || !strings.Contains(lines, "id='word_3'") {
+		t.Errorf("chunk lines are\n%s", lines)
+	}
+}
+
+func TestAPIErrorMessageOmitsPayloads(t *testing.T) {
+	tests := []struct {
+		body, want string
+	}{
+		{`{"error":{"message":"Rate limit reached","type":"requests"}}`, "Rate limit reached"},
+		{`<html>upstream echoed: Dear Sir, the harvest</html>`, "[51 byte response omitted]"},
+		{`{"messages":[{"content":"Dear Sir, the harvest"}]}`, "[50 byte response omitted]"},
+	}
+	for _, test := range tests {
+		if got := apiErrorMessage([]byte(test.body)); got != test.want {
+			t.Errorf("apiErrorMessage(%s) = %q, want %q", test.body, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package hocr
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func fillRect(img *image.Gray, x1, y1, x2, y2 int) {
+	for y := y1; y < y2; y++ {
+		for x := x1; x < x2; x++ {
+			img.SetGray(x, y, color.Gray{Y: 0})
+		}
+	}
+}
+
+func blankPage(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	return img
+}
+
+func TestFindWordComponents(t *testing.T) {
+	img := blankPage(400, 400)
+	fillRect(img, 10, 10, 40, 30)
+	// U shape: the arms are only joined at the bottom, which must still be one component
+	fillRect(img, 100, 10, 105, 40)
+	fillRect(img, 130, 10, 135, 40)
+	fillRect(img, 100, 35, 135, 40)
+	// Diagonal neighbours are 8-connected
+	fillRect(img, 60, 60, 70, 75)
+	fillRect(img, 70, 75, 80, 90)
+
+	components := (&Service{}).findWordComponents(img)
+	want := []WordBox{
+		{X: 10, Y: 10, Width: 30, Height: 20},
+		{X: 100, Y: 10, Width: 35, Height: 30},
+		{X: 60, Y: 60, Width: 20, Height: 30},
+	}
+
+	if len(components) != len(want) {
+		t.Fatalf("found %d components; want %d: %+v", len(components), len(want), components)
+	}
+	for i, w := range want {
+		got := components[i]
+		if got.X != w.X || got.Y != w.Y || got.Width != w.Width || got.Height != w.Height {
+			t.Errorf("component %d = %+v; want %+v", i, got, w)
+		}
+	}
+}
+
+func TestFindWordComponentsLargeRegion(t *testing.T) {
+	// A solid region this size overflowed the stack with recursive flood fill
+	img := blankPage(4000, 4000)
+	fillRect(img, 0, 0, 3000, 3000)
+
+	if components := (&Service{}).findWordComponents(img); len(components) != 0 {
+		t.Errorf("expected oversized region to be filtered, got %d components", len(components))
+	}
+}
+
+// newspaperPage simulates a dense multi-column page at the given DPI with
+// randomly sized word blobs laid out in lines
+func newspaperPage(dpi int) *image.Gray {
+	width, height := 11*dpi, 17*dpi
+	img := blankPage(width, height)
+	rng := rand.New(rand.NewSource(1))
+
+	lineHeight := dpi / 6
+	glyphHeight := lineHeight * 2 / 3
+	columns := 6
+	columnWidth := width / columns
+	for col := 0; col < columns; col++ {
+		for y := lineHeight; y+glyphHeight < height-lineHeight; y += lineHeight {
+			x := col*columnWidth + dpi/10
+			for x < (col+1)*columnWidth-dpi/4 {
+				wordWidth := glyphHeight/2 + rng.Intn(glyphHeight*3)
+				fillRect(img, x, y, min(x+wordWidth, width), y+glyphHeight)
+				x += wordWidth + glyphHeight/2
+			}
+		}
+	}
+	return img
+}
+
+func benchmarkFindWordComponents(b *testing.B, dpi int) {
+	img := newspaperPage(dpi)
+	s := &Service{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.findWordComponents(img)
+	}
+}
+
+func BenchmarkFindWordComponentsNewspaper300DPI(b *testing.B) {
+	benchmarkFindWordComponents(b, 300)
+}
+
+func BenchmarkFindWordComponentsNewspaper600DPI(b *testing.B) {
+	benchmarkFindWordComponents(b, 600)
+}
@@ -4,12 +4,17 @@ import (
 	"fmt"
 	"html"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
 )
 
 type Converter struct {
+	// Language is what the text is in, as ParseLanguage returns it. It marks
+	// the document's lang, and its direction when the lines don't tell.
+	Language string
+
 	lineCounter int
 	wordCounter int
 }
@@ -47,7 +52,14 @@ func (h *Converter) ConvertHOCRLinesToXML(lines []models.HOCRLine, pageWidth, pa
 	hocr.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
 	hocr.WriteString("<!DOCTYPE html PUBLIC \"-//W3C//DTD XHTML 1.0 Transitional//EN\"\n")
 	hocr.WriteString("    \"http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd\">\n")
-	hocr.WriteString("<html xmlns=\"http://www.w3.org/1999/xhtml\" xml:lang=\"en\" lang=\"en\">\n")
+	var text strings.Builder
+	for _, line := range lines {
+		for _, word := range line.Words {
+			text.WriteString(word.Text)
+		}
+	}
+	direction := documentDirection(h.Language, text.String())
+	hocr.WriteString("<html xmlns=\"http://www.w3.org/1999/xhtml\"" + languageAttributes(h.Language, direction) + ">\n")
 	hocr.WriteString("<head>\n")
 	hocr.WriteString("<title></title>\n")
 	hocr.WriteString("<meta http-equiv=\"Content-Type\" content=\"text/html; charset=utf-8\" />\n")
@@ -59,8 +71,8 @@ func (h *Converter) ConvertHOCRLinesToXML(lines []models.HOCRLine, pageWidth, pa
 	bbox := fmt.Sprintf("bbox 0 0 %d %d", pageWidth, pageHeight)
 	hocr.WriteString(fmt.Sprintf("<div class='ocr_page' id='page_1' title='%s'>\n", bbox))
 
-	for _, line := range lines {
-		hocr.WriteString(h.convertHOCRLineToXML(line))
+	for _, line := range MarkLanguages(MarkHyphenation(lines, h.Language), h.Language) {
+		hocr.WriteString(h.convertHOCRLineToXML(line, direction))
 	}
 
 	hocr.WriteString("</div>\n")
@@ -70,14 +82,31 @@ func (h *Converter) ConvertHOCRLinesToXML(lines []models.HOCRLine, pageWidth, pa
 	return hocr.String()
 }
 
-func (h *Converter) convertHOCRLineToXML(line models.HOCRLine) string {
+// convertHOCRLineToXML writes a line, marking its direction when it's read
+// right to left or differs from the page's, its angle when it's set
+// vertically, and its language, and its words', where they differ from the page's
+func (h *Converter) convertHOCRLineToXML(line models.HOCRLine, pageDirection string) string {
 	bbox := fmt.Sprintf("bbox %d %d %d %d", line.BBox.X1, line.BBox.Y1, line.BBox.X2, line.BBox.Y2)
+	if line.TextAngle != 0 {
+		bbox += fmt.Sprintf("; textangle %d", line.TextAngle)
+	}
+	dir := ""
+	if direction := LineDirection(line, pageDirection); direction != DirectionTTB && (direction == DirectionRTL || pageDirection == DirectionRTL) {
+		dir = fmt.Sprintf(" dir='%s'", direction)
+	}
+
+	language := htmlLanguage(h.Language)
+	lang := ""
+	if line.Language != "" && line.Language != language {
+		language = line.Language
+		lang = fmt.Sprintf(" lang='%s'", line.Language)
+	}
 
 	var lineBuilder strings.Builder
-	lineBuilder.WriteString(fmt.Sprintf("<span class='ocr_line' id='%s' title='%s'>", line.ID, bbox))
+	lineBuilder.WriteString(fmt.Sprintf("<span class='ocr_line' id='%s' title='%s'%s%s>", line.ID, bbox, dir, lang))
 
 	for _, word := range line.Words {
-		wordXML := h.convertHOCRWordToXML(word)
+		wordXML := h.convertHOCRWordToXML(word, language)
 		lineBuilder.WriteString(wordXML)
 	}
 
@@ -85,13 +114,26 @@ func (h *Converter) convertHOCRLineToXML(line models.HOCRLine) string {
 	return lineBuilder.String()
 }
 
-func (h *Converter) convertHOCRWordToXML(word models.HOCRWord) string {
+// convertHOCRWordToXML writes a word, marking its language when it differs
+// from its line's
+func (h *Converter) convertHOCRWordToXML(word models.HOCRWord, lineLanguage string) string {
 	bbox := fmt.Sprintf("bbox %d %d %d %d", word.BBox.X1, word.BBox.Y1, word.BBox.X2, word.BBox.Y2)
 	confidence := fmt.Sprintf("; x_wconf %.0f", word.Confidence)
 	title := bbox + confidence
+	if word.Hyphenated {
+		title += "; x_hyphenated 1"
+	}
+	if word.AutoCorrected != "" {
+		title += "; x_autocorrected " + strconv.Quote(word.AutoCorrected)
+	}
+
+	lang := ""
+	if word.Language != "" && word.Language != lineLanguage {
+		lang = fmt.Sprintf(" lang='%s'", word.Language)
+	}
 
-	return fmt.Sprintf("<span class='ocrx_word' id='%s' title='%s'>%s</span> ",
-		word.ID, title, html.EscapeString(word.Text))
+	return fmt.Sprintf("<span class='ocrx_word' id='%s' title='%s'%s>%s</span> ",
+		word.ID, html.EscapeString(title), lang, html.EscapeString(word.Text))
 }
 
 func (h *Converter) ConvertToHOCR(ocrResponse models.OCRResponse) (string, error) {
@@ -125,6 +167,10 @@ func (h *Converter) convertBlockToLines(block models.Block) []models.HOCRLine {
 	var allLines []models.HOCRLine
 
 	for _, paragraph := range block.Paragraphs {
+		// A paragraph is in its block's language unless detected otherwise
+		if paragraph.Property == nil {
+			paragraph.Property = block.Property
+		}
 		paragraphLines := h.convertParagraphToLines(paragraph)
 		allLines = append(allLines, paragraphLines...)
 	}
@@ -140,26 +186,35 @@ func (h *Converter) convertParagraphToLines(paragraph models.Paragraph) []models
 	// Group words into lines based on Y-coordinate proximity
 	var lineGroups [][]models.Word
 
+	// Vertically set paragraphs are grouped the same way into columns, read
+	// top to bottom from the rightmost
+	vertical := paragraph.TextAngle%180 != 0
+	across, along := h.getWordCenterY, h.getWordCenterX
+	if vertical {
+		across = func(word models.Word) int { return -h.getWordCenterX(word) }
+		along = h.getWordCenterY
+	}
+
 	// Sort words by reading order (top to bottom, left to right)
 	sortedWords := make([]models.Word, len(paragraph.Words))
 	copy(sortedWords, paragraph.Words)
 	sort.Slice(sortedWords, func(i, j int) bool {
 		// First sort by Y coordinate (top to bottom)
-		yDiff := h.getWordCenterY(sortedWords[i]) - h.getWordCenterY(sortedWords[j])
+		yDiff := across(sortedWords[i]) - across(sortedWords[j])
 		if abs(yDiff) > 20 { // Same line threshold: 20 pixels
 			return yDiff < 0
 		}
 		// If roughly same Y, sort by X coordinate (left to right)
-		return h.getWordCenterX(sortedWords[i]) < h.getWordCenterX(sortedWords[j])
+		return along(sortedWords[i]) < along(sortedWords[j])
 	})
 
 	// Group words into lines
 	currentLine := []models.Word{sortedWords[0]}
-	currentLineY := h.getWordCenterY(sortedWords[0])
+	currentLineY := across(sortedWords[0])
 
 	for i := 1; i < len(sortedWords); i++ {
 		word := sortedWords[i]
-		wordY := h.getWordCenterY(word)
+		wordY := across(word)
 
 		// Check if this word belongs to the current line (within 20 pixels vertically)
 		if abs(wordY-currentLineY) <= 20 {
@@ -193,9 +248,25 @@ func (h *Converter) convertParagraphToLines(paragraph models.Paragraph) []models
 		}
 
 		line := models.HOCRLine{
-			ID:    lineID,
-			BBox:  lineBBox,
-			Words: hocrWords,
+			ID:       lineID,
+			BBox:     lineBBox,
+			Words:    hocrWords,
+			Language: detectedLanguage(paragraph.Property),
+		}
+		if vertical {
+			line.TextAngle = paragraph.TextAngle
+		}
+		// Words were gathered left to right; right-to-left lines are read the
+		// other way, and numbered in the order they're read
+		if !vertical && LineDirection(line, LanguageDirection(h.Language)) == DirectionRTL {
+			ids := make([]string, len(line.Words))
+			for k, word := range line.Words {
+				ids[k] = word.ID
+			}
+			sortReadingOrder(line.Words, DirectionRTL)
+			for k := range line.Words {
+				line.Words[k].ID = ids[k]
+			}
 		}
 
 		lines = append(lines, line)
@@ -270,7 +341,17 @@ func (h *Converter) convertOCRWordToHOCRWord(ocrWord models.Word, lineID string)
 		BBox:       bbox,
 		Confidence: confidence,
 		LineID:     lineID,
+		Language:   detectedLanguage(ocrWord.Property),
+	}
+}
+
+// detectedLanguage is the BCP 47 tag of the most likely of the languages an
+// engine detected, or "" when it detected none
+func detectedLanguage(property *models.Property) string {
+	if property == nil || len(property.DetectedLanguages) == 0 {
+		return ""
 	}
+	return languageTag(property.DetectedLanguages[0].LanguageCode)
 }
 
 func (h *Converter) boundingPolyToBBoxStruct(boundingPoly models.BoundingPoly) models.BBox {
@@ -0,0 +1,156 @@
+package hocr
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// Writing directions, as hOCR's dir attribute gives them, and top to bottom
+// for the words of vertically set lines, which hOCR gives by textangle instead
+const (
+	DirectionLTR = "ltr"
+	DirectionRTL = "rtl"
+	DirectionTTB = "ttb"
+)
+
+// rtlLanguages are the language packs of scripts written right to left
+var rtlLanguages = map[string]bool{
+	"ara": true, "div": true, "fas": true, "heb": true, "pus": true,
+	"snd": true, "syr": true, "uig": true, "urd": true, "yid": true,
+}
+
+// rtlScripts are the scripts whose letters are read right to left
+var rtlScripts = []*unicode.RangeTable{unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko}
+
+// markupTags strips the tags from a fragment of hOCR, leaving its text
+var markupTags = regexp.MustCompile(`<[^>]*>`)
+
+// LanguageDirection is the direction of the first, most common, of the
+// languages, or "" when the language isn't known
+func LanguageDirection(language string) string {
+	if language == "" {
+		return ""
+	}
+	first, _, _ := strings.Cut(language, "+")
+	if rtlLanguages[first] {
+		return DirectionRTL
+	}
+	return DirectionLTR
+}
+
+// TextDirection is the direction most of the letters of text are written in,
+// or "" when it has none, as for numbers and punctuation
+func TextDirection(text string) string {
+	rtl, ltr := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.In(r, rtlScripts...):
+			rtl++
+		case unicode.IsLetter(r):
+			ltr++
+		}
+	}
+	switch {
+	case rtl == 0 && ltr == 0:
+		return ""
+	case rtl > ltr:
+		return DirectionRTL
+	default:
+		return DirectionLTR
+	}
+}
+
+// LineDirection is the direction of a line's text, or fallback when its text
+// doesn't tell, as when it's still untranscribed. Vertical lines are read
+// top to bottom whatever their text.
+func LineDirection(line models.HOCRLine, fallback string) string {
+	if IsVertical(line) {
+		return DirectionTTB
+	}
+	var text strings.Builder
+	for _, word := range line.Words {
+		text.WriteString(word.Text)
+	}
+	if direction := TextDirection(text.String()); direction != "" {
+		return direction
+	}
+	return fallback
+}
+
+// documentDirection is the direction of a page: its language's, or else the
+// one most of its text is written in
+func documentDirection(language, text string) string {
+	if direction := LanguageDirection(language); direction != "" {
+		return direction
+	}
+	return TextDirection(text)
+}
+
+// languageAttributes are the lang attributes of a document's root element, and
+// dir when it's read right to left; left to right is what's assumed without it
+func languageAttributes(language, direction string) string {
+	tag := htmlLanguage(language)
+	attributes := fmt.Sprintf(` xml:lang="%s" lang="%s"`, tag, tag)
+	if direction == DirectionRTL {
+		attributes += ` dir="rtl"`
+	}
+	return attributes
+}
+
+// sortReadingOrder puts words in the order they're read: by their left edge
+// for left-to-right text, by their right edge from the right for right-to-left,
+// and by their top edge for top-to-bottom
+func sortReadingOrder(words []models.HOCRWord, direction string) {
+	sort.SliceStable(words, func(i, j int) bool {
+		return readsBefore(words[i], words[j], direction)
+	})
+}
+
+// readsBefore reports whether word a is read before word b on a line going
+// in direction
+func readsBefore(a, b models.HOCRWord, direction string) bool {
+	switch direction {
+	case DirectionRTL:
+		return a.BBox.X2 > b.BBox.X2
+	case DirectionTTB:
+		return a.BBox.Y1 < b.BBox.Y1
+	}
+	return a.BBox.X1 < b.BBox.X1
+}
+
+// languageTag is the BCP 47 tag of a lang attribute, which Tesseract writes
+// in its own codes, or "" when it names no language known
+func languageTag(value string) string {
+	code, err := ParseLanguage(value)
+	if err != nil || code == "" {
+		return ""
+	}
+	return htmlLanguage(code)
+}
+
+// htmlLanguage is the BCP 47 tag of the first of the languages, for the lang
+// attributes of a document: the two-letter code where there is one, as in
+// "ar" for "ara", or else the Tesseract code itself. "" gives "en", which
+// documents were always marked as before languages could be chosen.
+func htmlLanguage(language string) string {
+	first, _, _ := strings.Cut(language, "+")
+	first = strings.TrimSuffix(first, "_vert")
+	if first == "" {
+		return "en"
+	}
+	tag := ""
+	for alias, code := range languageAliases {
+		if code == first && len(alias) == 2 && (tag == "" || alias < tag) {
+			tag = alias
+		}
+	}
+	if tag == "" {
+		return first
+	}
+	return tag
+}
@@ -0,0 +1,76 @@
+package hocr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestTextDirection(t *testing.T) {
+	for text, want := range map[string]string{
+		"":             "",
+		"1848.":        "",
+		"quick":        DirectionLTR,
+		"שלום":         DirectionRTL,
+		"كتاب 12":      DirectionRTL,
+		"שלום world!":  DirectionLTR,
+		"שלום עולם ok": DirectionRTL,
+	} {
+		if got := TextDirection(text); got != want {
+			t.Errorf("TextDirection(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestHTMLLanguage(t *testing.T) {
+	for language, want := range map[string]string{
+		"":        "en",
+		"ara":     "ar",
+		"heb+eng": "he",
+		"frk":     "frk",
+	} {
+		if got := htmlLanguage(language); got != want {
+			t.Errorf("htmlLanguage(%q) = %q, want %q", language, got, want)
+		}
+	}
+}
+
+func TestConvertRTLLines(t *testing.T) {
+	converter := NewConverter()
+	converter.Language = "heb"
+	xml := converter.ConvertHOCRLinesToXML([]models.HOCRLine{{
+		ID:    "line_1",
+		BBox:  models.BBox{X1: 0, Y1: 0, X2: 100, Y2: 20},
+		Words: []models.HOCRWord{{ID: "word_1", Text: "שלום", BBox: models.BBox{X1: 50, Y1: 0, X2: 100, Y2: 20}}},
+	}}, 100, 20)
+	if !strings.Contains(xml, `lang="he" dir="rtl"`) || !strings.Contains(xml, "dir='rtl'") {
+		t.Errorf("missing rtl markup in %s", xml)
+	}
+}
+
+func TestSplitRTLWord(t *testing.T) {
+	lines := []models.HOCRLine{{
+		ID:    "line_1",
+		BBox:  models.BBox{X1: 0, Y1: 0, X2: 100, Y2: 20},
+		Words: []models.HOCRWord{{ID: "word_1", Text: "שלוםעולם", BBox: models.BBox{X1: 0, Y1: 0, X2: 100, Y2: 20}}},
+	}}
+	lines, ids, err := SplitWord(lines, "word_1", 50, []string{"שלום", "עולם"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := lines[0].Words
+	// The right half is read first, so it keeps the ID and the first text
+	if words[0].ID != ids[0] || words[0].Text != "שלום" || words[0].BBox.X1 != 50 {
+		t.Errorf("split into %+v", words)
+	}
+
+	lines, _, _, err = AddWord(lines, models.BBox{X1: 70, Y1: 0, X2: 80, Y2: 20}, "א", "line_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// It sits left of שלום's right edge, so it's read after it
+	if words := lines[0].Words; len(words) != 3 || words[1].Text != "א" {
+		t.Errorf("added out of reading order: %+v", words)
+	}
+}
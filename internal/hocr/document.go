@@ -0,0 +1,105 @@
+package hocr
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Document types, which tune the pipeline to how a page was printed. The
+// default, "", is modern roman type.
+const (
+	DocumentModern  = ""
+	DocumentFraktur = "fraktur"
+)
+
+// documentTypeAliases are the other names document types are asked for by
+var documentTypeAliases = map[string]string{
+	"modern":      DocumentModern,
+	"roman":       DocumentModern,
+	"antiqua":     DocumentModern,
+	"blackletter": DocumentFraktur,
+	"gothic":      DocumentFraktur,
+}
+
+// documentProfile is what a document type changes: the preprocessing before
+// word detection, and what the LLM is told about the type
+type documentProfile struct {
+	// contrast stretches the gray levels, clipping these percentages of the
+	// darkest and lightest pixels
+	contrast string
+	// closing is the kernel small gaps in strokes are closed with
+	closing string
+	// hint is added to the transcription prompt
+	hint string
+}
+
+var documentProfiles = map[string]documentProfile{
+	DocumentModern: {
+		contrast: "0.15x0.05%",
+		closing:  "rectangle:2x1",
+	},
+	// Old newsprint is foxed and shows through from the other side, and the
+	// hairlines of blackletter break up at low resolution, so the background
+	// is clipped harder and gaps are closed down strokes as well as across
+	DocumentFraktur: {
+		contrast: "2x10%",
+		closing:  "rectangle:3x2",
+		hint: `The text is set in Fraktur (blackletter) type.
+Transcribe long s as ſ and round s as s, exactly as printed; never write f for ſ.
+Write the umlaut printed as a small e above a vowel as ä, ö or ü, and r rotunda as r.
+Keep historical spellings, and abbreviations such as u. s. w., d. i., Hr., Ew. and ꝛc., as printed without expanding them.
+Transcribe the double hyphen ⸗ as ⸗.`,
+	},
+}
+
+// ParseDocumentType reads a document type: "fraktur", also called
+// "blackletter" or "gothic", or "" for modern type, also called "modern",
+// "roman" or "antiqua"
+func ParseDocumentType(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if alias, ok := documentTypeAliases[value]; ok {
+		return alias, nil
+	}
+	if _, ok := documentProfiles[value]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown document type: %s", value)
+}
+
+// profileOf is the profile of a document type, or modern type's when it's unknown
+func profileOf(documentType string) documentProfile {
+	if profile, ok := documentProfiles[documentType]; ok {
+		return profile
+	}
+	return documentProfiles[DocumentModern]
+}
+
+// frakturTraineddata is the Tesseract model for Fraktur, from
+// TESSERACT_FRAKTUR_TRAINEDDATA (default frk). Newer tessdata releases
+// replace it with deu_latf, and tessdata_best has script/Fraktur.
+func frakturTraineddata() string {
+	if traineddata := os.Getenv("TESSERACT_FRAKTUR_TRAINEDDATA"); traineddata != "" {
+		return traineddata
+	}
+	return "frk"
+}
+
+// tesseractLanguages are the models Tesseract loads for a page in language
+// of a document type. Fraktur pages are read with the Fraktur model first, in
+// place of modern German, keeping the other languages for passages set in
+// roman type, such as Latin quotations.
+func tesseractLanguages(language, documentType string) string {
+	if documentType != DocumentFraktur {
+		return language
+	}
+	fraktur := frakturTraineddata()
+	codes := []string{fraktur}
+	for _, code := range strings.Split(language, "+") {
+		if code != "" && code != "deu" && !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return strings.Join(codes, "+")
+}
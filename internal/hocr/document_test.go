@@ -0,0 +1,61 @@
+package hocr
+
+import "testing"
+
+func TestParseDocumentType(t *testing.T) {
+	for value, want := range map[string]string{
+		"":             DocumentModern,
+		"Modern":       DocumentModern,
+		"antiqua":      DocumentModern,
+		"fraktur":      DocumentFraktur,
+		" Blackletter": DocumentFraktur,
+		"gothic":       DocumentFraktur,
+	} {
+		if got, err := ParseDocumentType(value); err != nil || got != want {
+			t.Errorf("ParseDocumentType(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if got, err := ParseDocumentType("uncial"); err == nil {
+		t.Errorf("ParseDocumentType(uncial) = %q, want an error", got)
+	}
+}
+
+func TestTesseractLanguages(t *testing.T) {
+	for _, test := range []struct {
+		language, documentType, want string
+	}{
+		{"deu+lat", DocumentModern, "deu+lat"},
+		{"", DocumentFraktur, "frk"},
+		{"deu+lat", DocumentFraktur, "frk+lat"},
+		{"frk+dan", DocumentFraktur, "frk+dan"},
+	} {
+		if got := tesseractLanguages(test.language, test.documentType); got != test.want {
+			t.Errorf("tesseractLanguages(%q, %q) = %q, want %q", test.language, test.documentType, got, test.want)
+		}
+	}
+
+	t.Setenv("TESSERACT_FRAKTUR_TRAINEDDATA", "deu_latf")
+	if got := tesseractLanguages("deu", DocumentFraktur); got != "deu_latf" {
+		t.Errorf("configured Fraktur model gave %q", got)
+	}
+}
+
+func TestOptionsDocumentType(t *testing.T) {
+	for _, test := range []struct {
+		opts Options
+		want string
+	}{
+		{Options{}, DocumentModern},
+		{Options{Language: "deu"}, DocumentModern},
+		{Options{Language: "frk+lat"}, DocumentFraktur},
+		{Options{Language: "lat+frk"}, DocumentModern},
+		{Options{Language: "deu", DocumentType: DocumentFraktur}, DocumentFraktur},
+	} {
+		if got := test.opts.documentType(); got != test.want {
+			t.Errorf("%+v documentType() = %q, want %q", test.opts, got, test.want)
+		}
+	}
+	if profileOf(DocumentFraktur).closing == profileOf(DocumentModern).closing {
+		t.Error("Fraktur is preprocessed as modern type")
+	}
+}
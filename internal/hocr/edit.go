@@ -0,0 +1,550 @@
+package hocr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// ErrWordNotFound is returned when an edit names a word the page doesn't have
+var ErrWordNotFound = errors.New("word not found")
+
+// findWord returns the line and word indexes of a word
+func findWord(lines []models.HOCRLine, wordID string) (int, int, bool) {
+	for i, line := range lines {
+		for j, word := range line.Words {
+			if word.ID == wordID {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// newWordID derives an ID from base that no word on the page has yet
+func newWordID(lines []models.HOCRLine, base string) string {
+	for n := 2; ; n++ {
+		id := fmt.Sprintf("%s_%d", base, n)
+		if _, _, taken := findWord(lines, id); !taken {
+			return id
+		}
+	}
+}
+
+// SplitWord divides a word in two at page coordinate at, for words the
+// detector ran together: an x, or a y on a vertically set line. texts gives the
+// text of each half in reading order; without it the text is divided at the
+// character nearest at, in proportion to the word's length. The half read
+// first, the left one unless the line is read right to left and the top one
+// on a vertical line, keeps the word's ID. It returns the edited lines and
+// both IDs.
+func SplitWord(lines []models.HOCRLine, wordID string, at int, texts []string) ([]models.HOCRLine, []string, error) {
+	i, j, ok := findWord(lines, wordID)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrWordNotFound, wordID)
+	}
+	word := lines[i].Words[j]
+	direction := LineDirection(lines[i], "")
+	start, end, axis := &word.BBox.X1, &word.BBox.X2, "x"
+	if direction == DirectionTTB {
+		start, end, axis = &word.BBox.Y1, &word.BBox.Y2, "y"
+	}
+	if at <= *start || at >= *end {
+		return nil, nil, fmt.Errorf("%s must fall inside the word, between %d and %d", axis, *start, *end)
+	}
+
+	var left, right string
+	switch len(texts) {
+	case 0:
+		runes := []rune(strings.TrimSpace(word.Text))
+		read := at - *start
+		if direction == DirectionRTL {
+			read = *end - at
+		}
+		split := int(math.Round(float64(len(runes)) * float64(read) / float64(*end-*start)))
+		if len(runes) >= 2 {
+			split = min(max(split, 1), len(runes)-1)
+		}
+		split = min(split, len(runes))
+		left, right = string(runes[:split]), string(runes[split:])
+	case 2:
+		left, right = texts[0], texts[1]
+	default:
+		return nil, nil, fmt.Errorf("texts must give the text of both halves")
+	}
+
+	first, second := word, word
+	first.Text, second.ID, second.Text = left, newWordID(lines, word.ID), right
+	switch direction {
+	case DirectionRTL:
+		first.BBox.X1, second.BBox.X2 = at, at
+	case DirectionTTB:
+		first.BBox.Y2, second.BBox.Y1 = at, at
+	default:
+		first.BBox.X2, second.BBox.X1 = at, at
+	}
+
+	words := append([]models.HOCRWord{}, lines[i].Words[:j]...)
+	words = append(words, first, second)
+	lines[i].Words = append(words, lines[i].Words[j+1:]...)
+	return lines, []string{first.ID, second.ID}, nil
+}
+
+// MergeWords joins words the detector split apart into one, with their texts
+// joined by separator and a box covering them all. The words must be next to
+// each other on one line; the first keeps its ID and the lowest confidence of
+// them is kept. It returns the edited lines and the merged word's ID.
+func MergeWords(lines []models.HOCRLine, wordIDs []string, separator string) ([]models.HOCRLine, string, error) {
+	if len(wordIDs) < 2 {
+		return nil, "", fmt.Errorf("at least two words are needed to merge")
+	}
+
+	line, start, end := -1, -1, -1
+	for _, wordID := range wordIDs {
+		i, j, ok := findWord(lines, wordID)
+		if !ok {
+			return nil, "", fmt.Errorf("%w: %s", ErrWordNotFound, wordID)
+		}
+		if line != -1 && i != line {
+			return nil, "", fmt.Errorf("only words on the same line can be merged")
+		}
+		line = i
+		if start == -1 || j < start {
+			start = j
+		}
+		end = max(end, j)
+	}
+	if end-start+1 != len(wordIDs) {
+		return nil, "", fmt.Errorf("only adjacent words can be merged")
+	}
+
+	words := lines[line].Words
+	merged := words[start]
+	texts := []string{strings.TrimSpace(merged.Text)}
+	for _, word := range words[start+1 : end+1] {
+		texts = append(texts, strings.TrimSpace(word.Text))
+		merged.BBox = models.BBox{
+			X1: min(merged.BBox.X1, word.BBox.X1),
+			Y1: min(merged.BBox.Y1, word.BBox.Y1),
+			X2: max(merged.BBox.X2, word.BBox.X2),
+			Y2: max(merged.BBox.Y2, word.BBox.Y2),
+		}
+		merged.Confidence = math.Min(merged.Confidence, word.Confidence)
+	}
+	merged.Text = strings.Join(texts, separator)
+
+	result := append([]models.HOCRWord{}, words[:start]...)
+	result = append(result, merged)
+	lines[line].Words = append(result, words[end+1:]...)
+	return lines, merged.ID, nil
+}
+
+// ErrLineNotFound is returned when an edit names a line the page doesn't have
+var ErrLineNotFound = errors.New("line not found")
+
+func findLine(lines []models.HOCRLine, lineID string) (int, bool) {
+	for i, line := range lines {
+		if line.ID == lineID {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// newLineID derives an ID from base that no line on the page has yet
+func newLineID(lines []models.HOCRLine, base string) string {
+	for n := 2; ; n++ {
+		id := fmt.Sprintf("%s_%d", base, n)
+		if _, taken := findLine(lines, id); !taken {
+			return id
+		}
+	}
+}
+
+// fitLine sets a line's box to cover its words and points its words at it
+func fitLine(line *models.HOCRLine) {
+	for i := range line.Words {
+		word := &line.Words[i]
+		word.LineID = line.ID
+		if i == 0 {
+			line.BBox = word.BBox
+			continue
+		}
+		line.BBox = models.BBox{
+			X1: min(line.BBox.X1, word.BBox.X1),
+			Y1: min(line.BBox.Y1, word.BBox.Y1),
+			X2: max(line.BBox.X2, word.BBox.X2),
+			Y2: max(line.BBox.Y2, word.BBox.Y2),
+		}
+	}
+}
+
+// ReorderLines puts the lines in the order of lineIDs, which must name every
+// line exactly once
+func ReorderLines(lines []models.HOCRLine, lineIDs []string) ([]models.HOCRLine, error) {
+	if len(lineIDs) != len(lines) {
+		return nil, fmt.Errorf("line_ids must list all %d lines, got %d", len(lines), len(lineIDs))
+	}
+	seen := make(map[string]bool, len(lineIDs))
+	reordered := make([]models.HOCRLine, 0, len(lines))
+	for _, lineID := range lineIDs {
+		i, ok := findLine(lines, lineID)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrLineNotFound, lineID)
+		}
+		if seen[lineID] {
+			return nil, fmt.Errorf("line %s is listed twice", lineID)
+		}
+		seen[lineID] = true
+		reordered = append(reordered, lines[i])
+	}
+	return reordered, nil
+}
+
+// SplitLine starts a new line at a word, moving it and the words after it
+// off the line. The new line follows the old one; it returns the edited lines
+// and the new line's ID.
+func SplitLine(lines []models.HOCRLine, lineID, wordID string) ([]models.HOCRLine, string, error) {
+	i, ok := findLine(lines, lineID)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrLineNotFound, lineID)
+	}
+	at := -1
+	for j, word := range lines[i].Words {
+		if word.ID == wordID {
+			at = j
+		}
+	}
+	if at == -1 {
+		return nil, "", fmt.Errorf("%w: %s is not on line %s", ErrWordNotFound, wordID, lineID)
+	}
+	if at == 0 {
+		return nil, "", fmt.Errorf("a line can't be split at its first word")
+	}
+
+	words := lines[i].Words
+	first := lines[i]
+	first.Words = append([]models.HOCRWord{}, words[:at]...)
+	second := models.HOCRLine{ID: newLineID(lines, lineID), Words: append([]models.HOCRWord{}, words[at:]...)}
+	fitLine(&first)
+	fitLine(&second)
+
+	result := append([]models.HOCRLine{}, lines[:i]...)
+	result = append(result, first, second)
+	return append(result, lines[i+1:]...), second.ID, nil
+}
+
+// MergeLines joins lines into the first of them, in the order given, where the
+// first line stood. It returns the edited lines.
+func MergeLines(lines []models.HOCRLine, lineIDs []string) ([]models.HOCRLine, error) {
+	if len(lineIDs) < 2 {
+		return nil, fmt.Errorf("at least two lines are needed to merge")
+	}
+	target, ok := findLine(lines, lineIDs[0])
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrLineNotFound, lineIDs[0])
+	}
+	merged := lines[target]
+	merged.Words = append([]models.HOCRWord{}, merged.Words...)
+	absorbed := map[string]bool{}
+	for _, lineID := range lineIDs[1:] {
+		i, ok := findLine(lines, lineID)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrLineNotFound, lineID)
+		}
+		if i == target || absorbed[lineID] {
+			return nil, fmt.Errorf("line %s is listed twice", lineID)
+		}
+		absorbed[lineID] = true
+		merged.Words = append(merged.Words, lines[i].Words...)
+	}
+	fitLine(&merged)
+
+	var result []models.HOCRLine
+	for i, line := range lines {
+		switch {
+		case i == target:
+			result = append(result, merged)
+		case !absorbed[line.ID]:
+			result = append(result, line)
+		}
+	}
+	return result, nil
+}
+
+// MoveWord moves a word onto another line, at index among its words, or by
+// its x position when index is negative. A line left without words is removed.
+func MoveWord(lines []models.HOCRLine, wordID, lineID string, index int) ([]models.HOCRLine, error) {
+	from, j, ok := findWord(lines, wordID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrWordNotFound, wordID)
+	}
+	to, ok := findLine(lines, lineID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrLineNotFound, lineID)
+	}
+	room := len(lines[to].Words)
+	if from == to {
+		room--
+	}
+	if index > room {
+		return nil, fmt.Errorf("index must be at most %d", room)
+	}
+	word := lines[from].Words[j]
+	lines[from].Words = append(append([]models.HOCRWord{}, lines[from].Words[:j]...), lines[from].Words[j+1:]...)
+
+	words := lines[to].Words
+	if index < 0 {
+		index = len(words)
+		direction := LineDirection(lines[to], TextDirection(word.Text))
+		for k, other := range words {
+			if readsBefore(word, other, direction) {
+				index = k
+				break
+			}
+		}
+	}
+	moved := append([]models.HOCRWord{}, words[:index]...)
+	moved = append(moved, word)
+	lines[to].Words = append(moved, words[index:]...)
+
+	fitLine(&lines[from])
+	fitLine(&lines[to])
+	if len(lines[from].Words) == 0 {
+		lines = append(lines[:from], lines[from+1:]...)
+	}
+	return lines, nil
+}
+
+// UpdateWord replaces a word's box and/or text, refitting its line around the
+// new box. A nil box or text is left as it was.
+func UpdateWord(lines []models.HOCRLine, wordID string, bbox *models.BBox, text *string) ([]models.HOCRLine, error) {
+	i, j, ok := findWord(lines, wordID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrWordNotFound, wordID)
+	}
+	word := &lines[i].Words[j]
+	if bbox != nil {
+		if err := checkBox(*bbox, "bbox"); err != nil {
+			return nil, err
+		}
+		word.BBox = *bbox
+	}
+	if text != nil {
+		if strings.TrimSpace(*text) == "" {
+			return nil, fmt.Errorf("text can't be empty")
+		}
+		word.Text = *text
+		// Whoever set the text has looked at the word
+		word.AutoCorrected = ""
+	}
+	fitLine(&lines[i])
+	return lines, nil
+}
+
+// inside reports whether a box's center falls within a region
+func inside(box, region models.BBox) bool {
+	x, y := (box.X1+box.X2)/2, (box.Y1+box.Y2)/2
+	return x >= region.X1 && x < region.X2 && y >= region.Y1 && y < region.Y2
+}
+
+// ReplaceRegion swaps the words centered inside region for the lines of a
+// fresh reading of it, placed beside the first line it touched, or by their
+// height when nothing was replaced. Lines left without words are removed, and
+// the new lines and words get fresh IDs. It returns the edited lines and the
+// IDs of the new ones.
+func ReplaceRegion(lines []models.HOCRLine, region models.BBox, replacement []models.HOCRLine) ([]models.HOCRLine, []string) {
+	kept := make([]models.HOCRLine, 0, len(lines))
+	at := -1
+	for _, line := range lines {
+		words := make([]models.HOCRWord, 0, len(line.Words))
+		for _, word := range line.Words {
+			if !inside(word.BBox, region) {
+				words = append(words, word)
+			}
+		}
+		touched := len(words) < len(line.Words)
+		if touched && len(words) == 0 {
+			if at < 0 {
+				at = len(kept)
+			}
+			continue
+		}
+		if touched {
+			line.Words = words
+			fitLine(&line)
+		}
+		kept = append(kept, line)
+		if touched && at < 0 {
+			// What's left of a line read first comes before the region, the
+			// rest of a line read after it
+			at = len(kept) - 1
+			if line.BBox.X1 < region.X1 {
+				at++
+			}
+		}
+	}
+	if at < 0 {
+		at = heightIndex(kept, region.Y1)
+	}
+	// IDs of replaced words aren't reused, so nothing anchored to them moves
+	// onto a new word
+	return insertLines(kept, at, replacement, lines)
+}
+
+// heightIndex is where a line starting at y belongs: before the first line
+// starting below it
+func heightIndex(lines []models.HOCRLine, y int) int {
+	for i, line := range lines {
+		if line.BBox.Y1 > y {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// insertLines puts new lines at index at, giving them and their words IDs
+// unused by taken and by each other. Lines without words are skipped.
+func insertLines(lines []models.HOCRLine, at int, added []models.HOCRLine, taken []models.HOCRLine) ([]models.HOCRLine, []string) {
+	taken = append([]models.HOCRLine{}, taken...)
+	result := append([]models.HOCRLine{}, lines[:at]...)
+	lineIDs := make([]string, 0, len(added))
+	for _, line := range added {
+		if len(line.Words) == 0 {
+			continue
+		}
+		line.ID = newLineID(taken, "line")
+		words := make([]models.HOCRWord, len(line.Words))
+		for j, word := range line.Words {
+			// The new line already holds the words given IDs so far
+			line.Words = words[:j]
+			word.ID = newWordID(append(taken, line), "word")
+			words[j] = word
+		}
+		line.Words = words
+		fitLine(&line)
+		taken = append(taken, line)
+		result = append(result, line)
+		lineIDs = append(lineIDs, line.ID)
+	}
+	return append(result, lines[at:]...), lineIDs
+}
+
+// InsertLines adds lines read from a region the detector missed entirely,
+// placed by height, with fresh IDs. It returns the edited lines and the IDs
+// of the new ones.
+func InsertLines(lines []models.HOCRLine, region models.BBox, added []models.HOCRLine) ([]models.HOCRLine, []string) {
+	return insertLines(lines, heightIndex(lines, region.Y1), added, lines)
+}
+
+// checkBox rejects boxes that are empty, inverted or off the page
+func checkBox(box models.BBox, name string) error {
+	if box.X1 < 0 || box.Y1 < 0 || box.X2 <= box.X1 || box.Y2 <= box.Y1 {
+		return fmt.Errorf("%s must have x1 < x2 and y1 < y2, none negative", name)
+	}
+	return nil
+}
+
+// manualConfidence is given to words a person typed in
+const manualConfidence = 100
+
+// AddLine adds a line the detector missed, spreading its text across the box
+// as words sized by their length. It returns the edited lines and the new
+// line's ID.
+func AddLine(lines []models.HOCRLine, bbox models.BBox, text string) ([]models.HOCRLine, string, error) {
+	if err := checkBox(bbox, "bbox"); err != nil {
+		return nil, "", err
+	}
+	texts := strings.Fields(text)
+	if len(texts) == 0 {
+		return nil, "", fmt.Errorf("text can't be empty")
+	}
+
+	// Each word's share of the width counts one character of space after it
+	units := -1
+	for _, word := range texts {
+		units += utf8.RuneCountInString(word) + 1
+	}
+	width := float64(bbox.X2 - bbox.X1)
+	line := models.HOCRLine{Words: make([]models.HOCRWord, len(texts))}
+	position := 0
+	for i, word := range texts {
+		length := utf8.RuneCountInString(word)
+		x1 := bbox.X1 + int(width*float64(position)/float64(units))
+		x2 := bbox.X1 + int(width*float64(position+length)/float64(units))
+		if i == len(texts)-1 {
+			x2 = bbox.X2
+		}
+		line.Words[i] = models.HOCRWord{
+			Text:       word,
+			BBox:       models.BBox{X1: x1, Y1: bbox.Y1, X2: max(x2, x1+1), Y2: bbox.Y2},
+			Confidence: manualConfidence,
+		}
+		position += length + 1
+	}
+
+	lines, lineIDs := InsertLines(lines, bbox, []models.HOCRLine{line})
+	return lines, lineIDs[0], nil
+}
+
+// lineAt finds the line a box sits on: the one whose height overlaps the
+// box's most, by at least half the box's height, or for vertical lines whose
+// width does
+func lineAt(lines []models.HOCRLine, box models.BBox) (int, bool) {
+	best, bestShare := -1, 0.0
+	for i, line := range lines {
+		// Vertical lines are sat on across their width rather than their height
+		overlap, extent := min(box.Y2, line.BBox.Y2)-max(box.Y1, line.BBox.Y1), box.Y2-box.Y1
+		if IsVertical(line) {
+			overlap, extent = min(box.X2, line.BBox.X2)-max(box.X1, line.BBox.X1), box.X2-box.X1
+		}
+		if share := float64(overlap) / float64(extent); overlap > 0 && share > bestShare {
+			best, bestShare = i, share
+		}
+	}
+	return best, best >= 0 && bestShare >= 0.5
+}
+
+// AddWord adds a word the detector missed, with a fresh ID, to lineID, or
+// without one to the line it sits on, or else to a new line of its own. It
+// returns the edited lines and the IDs of the word and its line.
+func AddWord(lines []models.HOCRLine, bbox models.BBox, text, lineID string) ([]models.HOCRLine, string, string, error) {
+	if err := checkBox(bbox, "bbox"); err != nil {
+		return nil, "", "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, "", "", fmt.Errorf("text can't be empty")
+	}
+	word := models.HOCRWord{Text: strings.TrimSpace(text), BBox: bbox, Confidence: manualConfidence}
+
+	i, ok := -1, false
+	if lineID != "" {
+		if i, ok = findLine(lines, lineID); !ok {
+			return nil, "", "", fmt.Errorf("%w: %s", ErrLineNotFound, lineID)
+		}
+	} else if i, ok = lineAt(lines, bbox); !ok {
+		lines, lineIDs := InsertLines(lines, bbox, []models.HOCRLine{{Words: []models.HOCRWord{word}}})
+		i, _ = findLine(lines, lineIDs[0])
+		return lines, lines[i].Words[0].ID, lineIDs[0], nil
+	}
+
+	word.ID = newWordID(lines, "word")
+	words := lines[i].Words
+	index := len(words)
+	direction := LineDirection(lines[i], TextDirection(word.Text))
+	for k, other := range words {
+		if readsBefore(word, other, direction) {
+			index = k
+			break
+		}
+	}
+	placed := append([]models.HOCRWord{}, words[:index]...)
+	placed = append(placed, word)
+	lines[i].Words = append(placed, words[index:]...)
+	fitLine(&lines[i])
+	return lines, word.ID, lines[i].ID, nil
+}
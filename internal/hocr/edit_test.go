@@ -0,0 +1,277 @@
+package hocr
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func editLines() []models.HOCRLine {
+	return []models.HOCRLine{{
+		ID:   "line_1",
+		BBox: models.BBox{X1: 0, Y1: 0, X2: 300, Y2: 20},
+		Words: []models.HOCRWord{
+			{ID: "word_1", Text: "thequick", BBox: models.BBox{X1: 0, Y1: 0, X2: 80, Y2: 20}, Confidence: 90, LineID: "line_1"},
+			{ID: "word_2", Text: "bro", BBox: models.BBox{X1: 90, Y1: 2, X2: 120, Y2: 20}, Confidence: 70, LineID: "line_1"},
+			{ID: "word_3", Text: "wn", BBox: models.BBox{X1: 122, Y1: 0, X2: 140, Y2: 18}, Confidence: 80, LineID: "line_1"},
+		},
+	}, {
+		ID:    "line_2",
+		Words: []models.HOCRWord{{ID: "word_4", Text: "fox", BBox: models.BBox{X1: 0, Y1: 30, X2: 30, Y2: 50}}},
+	}}
+}
+
+func TestSplitWord(t *testing.T) {
+	lines, ids, err := SplitWord(editLines(), "word_1", 30, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"word_1", "word_1_2"}) {
+		t.Errorf("ids = %v", ids)
+	}
+	words := lines[0].Words
+	if len(words) != 4 || words[0].Text != "the" || words[1].Text != "quick" {
+		t.Fatalf("split into %+v", words)
+	}
+	if words[0].BBox.X2 != 30 || words[1].BBox.X1 != 30 || words[1].BBox.X2 != 80 || words[1].Confidence != 90 {
+		t.Errorf("boxes %+v and %+v", words[0].BBox, words[1].BBox)
+	}
+
+	lines, _, err = SplitWord(editLines(), "word_1", 40, []string{"the", "quick"})
+	if err != nil || lines[0].Words[0].Text != "the" || lines[0].Words[1].Text != "quick" {
+		t.Errorf("split with texts: %v %+v", err, lines)
+	}
+
+	if _, _, err := SplitWord(editLines(), "word_1", 80, nil); err == nil {
+		t.Error("expected an error splitting at the word's edge")
+	}
+	if _, _, err := SplitWord(editLines(), "missing", 10, nil); !errors.Is(err, ErrWordNotFound) {
+		t.Errorf("expected ErrWordNotFound, got %v", err)
+	}
+}
+
+func TestMergeWords(t *testing.T) {
+	lines, id, err := MergeWords(editLines(), []string{"word_3", "word_2"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "word_2" {
+		t.Errorf("merged id = %s", id)
+	}
+	words := lines[0].Words
+	want := models.HOCRWord{ID: "word_2", Text: "brown", BBox: models.BBox{X1: 90, Y1: 0, X2: 140, Y2: 20}, Confidence: 70, LineID: "line_1"}
+	if len(words) != 2 || !reflect.DeepEqual(words[1], want) {
+		t.Errorf("merged into %+v", words)
+	}
+
+	if _, _, err := MergeWords(editLines(), []string{"word_1", "word_3"}, ""); err == nil {
+		t.Error("expected an error merging words that aren't adjacent")
+	}
+	if _, _, err := MergeWords(editLines(), []string{"word_3", "word_4"}, ""); err == nil {
+		t.Error("expected an error merging words on different lines")
+	}
+	if _, _, err := MergeWords(editLines(), []string{"word_1"}, ""); err == nil {
+		t.Error("expected an error merging one word")
+	}
+}
+
+func lineIDs(lines []models.HOCRLine) []string {
+	var ids []string
+	for _, line := range lines {
+		ids = append(ids, line.ID)
+	}
+	return ids
+}
+
+func TestReorderLines(t *testing.T) {
+	lines, err := ReorderLines(editLines(), []string{"line_2", "line_1"})
+	if err != nil || !reflect.DeepEqual(lineIDs(lines), []string{"line_2", "line_1"}) {
+		t.Errorf("reordered to %v (%v)", lineIDs(lines), err)
+	}
+	if _, err := ReorderLines(editLines(), []string{"line_1"}); err == nil {
+		t.Error("expected an error when a line is left out")
+	}
+	if _, err := ReorderLines(editLines(), []string{"line_1", "line_1"}); err == nil {
+		t.Error("expected an error when a line is listed twice")
+	}
+}
+
+func TestSplitAndMergeLines(t *testing.T) {
+	lines, id, err := SplitLine(editLines(), "line_1", "word_2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "line_1_2" || !reflect.DeepEqual(lineIDs(lines), []string{"line_1", "line_1_2", "line_2"}) {
+		t.Fatalf("split into %v, new line %s", lineIDs(lines), id)
+	}
+	if want := (models.BBox{X1: 90, Y1: 0, X2: 140, Y2: 20}); lines[1].BBox != want || lines[1].Words[0].LineID != "line_1_2" {
+		t.Errorf("new line %+v", lines[1])
+	}
+	if want := (models.BBox{X1: 0, Y1: 0, X2: 80, Y2: 20}); lines[0].BBox != want {
+		t.Errorf("old line box %+v", lines[0].BBox)
+	}
+	if _, _, err := SplitLine(editLines(), "line_1", "word_1"); err == nil {
+		t.Error("expected an error splitting at the first word")
+	}
+
+	lines, err = MergeLines(lines, []string{"line_1", "line_2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lineIDs(lines), []string{"line_1", "line_1_2"}) || len(lines[0].Words) != 2 || lines[0].Words[1].LineID != "line_1" {
+		t.Errorf("merged into %+v", lines)
+	}
+}
+
+func TestMoveWord(t *testing.T) {
+	lines, err := MoveWord(editLines(), "word_4", "line_1", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lineIDs(lines), []string{"line_1"}) {
+		t.Fatalf("emptied line kept: %v", lineIDs(lines))
+	}
+	// fox starts level with thequick, so it goes after it
+	if words := lines[0].Words; words[1].ID != "word_4" || words[1].LineID != "line_1" || lines[0].BBox.Y2 != 50 {
+		t.Errorf("moved into %+v", lines[0])
+	}
+
+	lines, err = MoveWord(editLines(), "word_1", "line_1", 2)
+	if err != nil || lines[0].Words[2].ID != "word_1" {
+		t.Errorf("moved within the line to %+v (%v)", lines[0].Words, err)
+	}
+	if _, err := MoveWord(editLines(), "word_1", "line_2", 5); err == nil {
+		t.Error("expected an error for an index past the end")
+	}
+}
+
+func TestUpdateWord(t *testing.T) {
+	box := models.BBox{X1: 85, Y1: 0, X2: 160, Y2: 25}
+	text := "brown"
+	lines, err := UpdateWord(editLines(), "word_2", &box, &text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if word := lines[0].Words[1]; word.BBox != box || word.Text != "brown" || word.Confidence != 70 {
+		t.Errorf("updated to %+v", word)
+	}
+	if want := (models.BBox{X1: 0, Y1: 0, X2: 160, Y2: 25}); lines[0].BBox != want {
+		t.Errorf("line box %+v, want %+v", lines[0].BBox, want)
+	}
+
+	lines, err = UpdateWord(editLines(), "word_2", nil, &text)
+	if err != nil || lines[0].Words[1].BBox != editLines()[0].Words[1].BBox {
+		t.Errorf("text only update moved the box: %+v (%v)", lines[0].Words[1], err)
+	}
+	if _, err := UpdateWord(editLines(), "word_2", &models.BBox{X1: 10, X2: 5, Y2: 5}, nil); err == nil {
+		t.Error("expected an error for an inverted box")
+	}
+	empty := " "
+	if _, err := UpdateWord(editLines(), "word_2", nil, &empty); err == nil {
+		t.Error("expected an error for empty text")
+	}
+}
+
+func TestAutoCorrectedWord(t *testing.T) {
+	lines := editLines()
+	lines[0].Words[1].AutoCorrected = `qu'ick "1"`
+	parsed, err := ParseHOCRLines(NewConverter().ConvertHOCRLinesToXML(lines, 200, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed[0].Words[1].AutoCorrected; got != `qu'ick "1"` {
+		t.Errorf("parsed x_autocorrected %q", got)
+	}
+
+	// Setting the text, even to the correction, confirms it
+	text := parsed[0].Words[1].Text
+	updated, err := UpdateWord(parsed, "word_2", nil, &text)
+	if err != nil || updated[0].Words[1].AutoCorrected != "" {
+		t.Errorf("auto-correction kept after an edit: %+v (%v)", updated[0].Words[1], err)
+	}
+}
+
+func TestReplaceRegion(t *testing.T) {
+	region := models.BBox{X1: 85, Y1: 0, X2: 150, Y2: 25}
+	replacement := []models.HOCRLine{{
+		ID: "line_1",
+		Words: []models.HOCRWord{
+			{ID: "word_1", Text: "brown", BBox: models.BBox{X1: 88, Y1: 1, X2: 140, Y2: 21}, Confidence: 95},
+			{ID: "word_2", Text: "fox", BBox: models.BBox{X1: 142, Y1: 1, X2: 149, Y2: 21}, Confidence: 95},
+		},
+	}, {ID: "line_2"}}
+	lines, ids := ReplaceRegion(editLines(), region, replacement)
+	if !reflect.DeepEqual(ids, []string{"line_3"}) || len(lines) != 3 {
+		t.Fatalf("ids %v, %d lines", ids, len(lines))
+	}
+	if len(lines[0].Words) != 1 || lines[0].BBox != (models.BBox{X1: 0, Y1: 0, X2: 80, Y2: 20}) {
+		t.Errorf("kept line %+v", lines[0])
+	}
+	added := lines[1]
+	if added.ID != "line_3" || added.BBox != (models.BBox{X1: 88, Y1: 1, X2: 149, Y2: 21}) {
+		t.Errorf("added line %+v", added)
+	}
+	// Replaced words' IDs aren't handed out again
+	if added.Words[0].ID != "word_5" || added.Words[1].ID != "word_6" || added.Words[1].LineID != "line_3" {
+		t.Errorf("added words %+v", added.Words)
+	}
+	if lines[2].ID != "line_2" {
+		t.Errorf("line_2 moved to %+v", lines[2])
+	}
+
+	// A region holding no words gets its lines by height
+	lines, _ = ReplaceRegion(editLines(), models.BBox{X1: 0, Y1: 60, X2: 100, Y2: 80}, replacement[:1])
+	if len(lines) != 3 || lines[2].Words[0].Text != "brown" || len(lines[0].Words) != 3 {
+		t.Errorf("empty region replaced into %+v", lines)
+	}
+}
+
+func TestAddLine(t *testing.T) {
+	lines := editLines()
+	lines[1].BBox = lines[1].Words[0].BBox
+	lines, lineID, err := AddLine(lines, models.BBox{X1: 0, Y1: 22, X2: 90, Y2: 28}, " a  footnote ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Placed by height, between the two lines
+	if lineID != "line_3" || len(lines) != 3 || lines[1].ID != lineID {
+		t.Fatalf("added %s into %+v", lineID, lines)
+	}
+	words := lines[1].Words
+	if len(words) != 2 || words[0].Text != "a" || words[1].Text != "footnote" || words[0].ID != "word_5" || words[1].ID != "word_6" {
+		t.Fatalf("words %+v", words)
+	}
+	// "a" is one of ten characters and a space wide
+	if words[0].BBox != (models.BBox{X1: 0, Y1: 22, X2: 9, Y2: 28}) || words[1].BBox != (models.BBox{X1: 18, Y1: 22, X2: 90, Y2: 28}) {
+		t.Errorf("boxes %+v and %+v", words[0].BBox, words[1].BBox)
+	}
+	if _, _, err := AddLine(editLines(), models.BBox{X1: 0, Y1: 22, X2: 90, Y2: 28}, " "); err == nil {
+		t.Error("expected an error for empty text")
+	}
+}
+
+func TestAddWord(t *testing.T) {
+	// On line_1's height, between word_1 and word_2
+	lines, wordID, lineID, err := AddWord(editLines(), models.BBox{X1: 82, Y1: 2, X2: 88, Y2: 18}, "-", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wordID != "word_5" || lineID != "line_1" || lines[0].Words[1].ID != wordID || lines[0].Words[1].Confidence != 100 {
+		t.Errorf("added %s to %s: %+v", wordID, lineID, lines[0].Words)
+	}
+
+	// Off every line
+	lines, wordID, lineID, err = AddWord(editLines(), models.BBox{X1: 0, Y1: 100, X2: 30, Y2: 120}, "fin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lineID != "line_3" || wordID != "word_5" || lines[len(lines)-1].Words[0].Text != "fin" {
+		t.Errorf("added %s to %s: %+v", wordID, lineID, lines)
+	}
+
+	if _, _, _, err := AddWord(editLines(), models.BBox{X1: 0, Y1: 0, X2: 5, Y2: 5}, "x", "line_9"); !errors.Is(err, ErrLineNotFound) {
+		t.Errorf("err = %v, want ErrLineNotFound", err)
+	}
+}
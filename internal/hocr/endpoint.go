@@ -0,0 +1,115 @@
+package hocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	defaultOpenAIBaseURL   = "https://api.openai.com/v1"
+	defaultAzureAPIVersion = "2024-06-01"
+)
+
+// llmEndpoint is where chat completion requests are sent and how they authenticate.
+// Azure OpenAI is used when AZURE_OPENAI_ENDPOINT is set; otherwise requests go to
+// OPENAI_BASE_URL, which can point at a proxy or any OpenAI-compatible server.
+type llmEndpoint struct {
+	url     string
+	headers map[string]string
+}
+
+func newLLMEndpoint() (llmEndpoint, error) {
+	if azure := os.Getenv("AZURE_OPENAI_ENDPOINT"); azure != "" {
+		deployment := os.Getenv("AZURE_OPENAI_DEPLOYMENT")
+		if deployment == "" {
+			return llmEndpoint{}, fmt.Errorf("AZURE_OPENAI_DEPLOYMENT must be set when AZURE_OPENAI_ENDPOINT is")
+		}
+		apiVersion := os.Getenv("AZURE_OPENAI_API_VERSION")
+		if apiVersion == "" {
+			apiVersion = defaultAzureAPIVersion
+		}
+
+		apiKey := azureAPIKey()
+		return llmEndpoint{
+			url: fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+				strings.TrimRight(azure, "/"), url.PathEscape(deployment), url.QueryEscape(apiVersion)),
+			headers: map[string]string{"api-key": apiKey},
+		}, nil
+	}
+
+	baseURL := os.Getenv("OPENAI_BASE_URL")
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	endpoint := llmEndpoint{
+		url:     strings.TrimRight(baseURL, "/") + "/chat/completions",
+		headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+	if organization := os.Getenv("OPENAI_ORGANIZATION"); organization != "" {
+		endpoint.headers["OpenAI-Organization"] = organization
+	}
+	return endpoint, nil
+}
+
+// azureAPIKey falls back to OPENAI_API_KEY so existing deployments only need the endpoint
+func azureAPIKey() string {
+	if key := os.Getenv("AZURE_OPENAI_API_KEY"); key != "" {
+		return key
+	}
+	return os.Getenv("OPENAI_API_KEY")
+}
+
+// llmConfigured reports whether an API key is available for any endpoint
+func llmConfigured() bool {
+	if os.Getenv("AZURE_OPENAI_ENDPOINT") != "" {
+		return azureAPIKey() != ""
+	}
+	return os.Getenv("OPENAI_API_KEY") != ""
+}
+
+func (e llmEndpoint) newRequest(body []byte) (*http.Request, error) {
+	req, err := http.NewRequest("POST", e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
+
+// CheckLLM confirms the chat completions endpoint answers and accepts the
+// configured credentials. The probe has no model or messages, so it is rejected
+// before anything is generated or billed.
+func (s *Service) CheckLLM(ctx context.Context) error {
+	endpoint, err := newLLMEndpoint()
+	if err != nil {
+		return err
+	}
+	req, err := endpoint.newRequest([]byte("{}"))
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("LLM endpoint unreachable: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("LLM endpoint rejected the credentials (status %d)", resp.StatusCode)
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("LLM endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
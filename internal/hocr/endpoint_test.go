@@ -0,0 +1,86 @@
+package hocr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewLLMEndpoint(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("OPENAI_BASE_URL", "")
+	t.Setenv("OPENAI_ORGANIZATION", "")
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	t.Setenv("AZURE_OPENAI_API_KEY", "")
+
+	endpoint, err := newLLMEndpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.url != "https://api.openai.com/v1/chat/completions" {
+		t.Errorf("default url = %s", endpoint.url)
+	}
+	if endpoint.headers["Authorization"] != "Bearer sk-test" {
+		t.Errorf("authorization = %q", endpoint.headers["Authorization"])
+	}
+
+	t.Setenv("OPENAI_BASE_URL", "https://proxy.example.edu/openai/v1/")
+	t.Setenv("OPENAI_ORGANIZATION", "org-lehigh")
+	endpoint, err = newLLMEndpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.url != "https://proxy.example.edu/openai/v1/chat/completions" {
+		t.Errorf("proxy url = %s", endpoint.url)
+	}
+	if endpoint.headers["OpenAI-Organization"] != "org-lehigh" {
+		t.Errorf("organization = %q", endpoint.headers["OpenAI-Organization"])
+	}
+
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "https://lehigh.openai.azure.com/")
+	t.Setenv("AZURE_OPENAI_DEPLOYMENT", "")
+	if _, err := newLLMEndpoint(); err == nil {
+		t.Error("expected an error without a deployment")
+	}
+
+	t.Setenv("AZURE_OPENAI_DEPLOYMENT", "gpt-4o-ocr")
+	t.Setenv("AZURE_OPENAI_API_VERSION", "2024-10-21")
+	endpoint, err = newLLMEndpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://lehigh.openai.azure.com/openai/deployments/gpt-4o-ocr/chat/completions?api-version=2024-10-21"; endpoint.url != want {
+		t.Errorf("azure url = %s, want %s", endpoint.url, want)
+	}
+	if endpoint.headers["api-key"] != "sk-test" || endpoint.headers["Authorization"] != "" {
+		t.Errorf("azure headers = %v", endpoint.headers)
+	}
+}
+
+func TestCheckLLM(t *testing.T) {
+	status := http.StatusBadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	s := &Service{}
+
+	if err := s.CheckLLM(context.Background()); err != nil {
+		t.Errorf("a rejected probe means the endpoint is up: %v", err)
+	}
+
+	status = http.StatusUnauthorized
+	if err := s.CheckLLM(context.Background()); err == nil {
+		t.Error("expected an error when credentials are rejected")
+	}
+
+	status = http.StatusBadGateway
+	if err := s.CheckLLM(context.Background()); err == nil {
+		t.Error("expected an error for a server error")
+	}
+}
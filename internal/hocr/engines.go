@@ -0,0 +1,134 @@
+package hocr
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/sandbox"
+)
+
+// OCR engines a session can be processed with
+const (
+	// EngineLLM detects words locally and has an LLM transcribe them
+	EngineLLM = "llm"
+	// EngineTesseract runs Tesseract and uses its text and confidences as-is
+	EngineTesseract = "tesseract"
+	// EngineDetect only finds word boxes, leaving transcription to the editor
+	EngineDetect = "detect"
+)
+
+// Deployment profiles
+const (
+	ProfileFull  = "full"
+	ProfileNoLLM = "no-llm"
+)
+
+// EngineInfo describes an engine and whether this deployment can run it
+type EngineInfo struct {
+	Name        string `json:"name"`
+	Available   bool   `json:"available"`
+	Description string `json:"description"`
+}
+
+// detectProfile honors OCR_PROFILE when set, otherwise the LLM is only enabled
+// when an API key is configured
+func detectProfile() string {
+	// Demo instances must never incur API costs
+	if sandbox.Enabled() {
+		return ProfileNoLLM
+	}
+
+	switch profile := os.Getenv("OCR_PROFILE"); profile {
+	case ProfileFull, ProfileNoLLM:
+		return profile
+	case "", "auto":
+	default:
+		slog.Warn("Unknown OCR_PROFILE, detecting from environment", "profile", profile)
+	}
+
+	if !llmConfigured() {
+		return ProfileNoLLM
+	}
+	return ProfileFull
+}
+
+// Profile is the deployment profile detected at startup
+func (s *Service) Profile() string {
+	return s.profile
+}
+
+// Engines lists every engine along with whether it can run here
+func (s *Service) Engines() []EngineInfo {
+	return []EngineInfo{
+		{Name: EngineLLM, Available: s.profile == ProfileFull, Description: "Custom word detection with LLM transcription"},
+		{Name: EngineTesseract, Available: s.tesseractPath != "", Description: "Tesseract OCR"},
+		{Name: EngineDetect, Available: true, Description: "Word boxes only, transcribed by hand"},
+	}
+}
+
+// DefaultEngine is the best engine available in this deployment
+func (s *Service) DefaultEngine() string {
+	switch {
+	case s.profile == ProfileFull:
+		return EngineLLM
+	case s.tesseractPath != "":
+		return EngineTesseract
+	default:
+		return EngineDetect
+	}
+}
+
+// ValidateEngine rejects unknown engines and ones this deployment can't run
+func (s *Service) ValidateEngine(engine string) error {
+	if engine == "" {
+		return nil
+	}
+	for _, info := range s.Engines() {
+		if info.Name == engine {
+			if !info.Available {
+				return fmt.Errorf("engine %s is not available in the %s profile", engine, s.profile)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown engine: %s", engine)
+}
+
+// processWithTesseract returns Tesseract's own hOCR, keeping its text and word
+// confidences rather than treating them as placeholders. Its TSV output is
+// written alongside for the archive.
+func (s *Service) processWithTesseract(ws *workspace, imagePath string, opts Options) (string, error) {
+	if s.tesseractPath == "" {
+		return "", fmt.Errorf("tesseract is not installed")
+	}
+	release := s.acquireDetectionSlot()
+	defer release()
+
+	outputBase := ws.path("tesseract")
+	args := []string{imagePath, outputBase}
+	language := tesseractLanguages(opts.Language, opts.documentType())
+	if language != "" {
+		args = append(args, "-l", language)
+	}
+	args = append(args, opts.Characters.tesseractArgs()...)
+	cmd := exec.Command(s.tesseractPath, append(args, "hocr", "tsv")...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	output, err := os.ReadFile(outputBase + ".hocr")
+	if err != nil {
+		return "", fmt.Errorf("failed to read tesseract output: %w", err)
+	}
+	opts.archive("tesseract.hocr", output)
+	if tsv, err := os.ReadFile(outputBase + ".tsv"); err == nil {
+		opts.archive("tesseract.tsv", tsv)
+	}
+	opts.logger().Info("Tesseract OCR completed", "image", imagePath, "language", language, "bytes", len(output))
+	return string(output), nil
+}
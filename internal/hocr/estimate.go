@@ -0,0 +1,198 @@
+package hocr
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// Estimate is the expected size and cost of the LLM transcription of one or
+// more pages. Re-prompts for invalid output and retried calls aren't counted.
+type Estimate struct {
+	Words int `json:"words"`
+	// Requests are the transcription calls that would be made; Cached are
+	// chunks the LLM cache would answer instead
+	Requests     int     `json:"requests"`
+	Cached       int     `json:"cached"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// Add sums two estimates
+func (e Estimate) Add(other Estimate) Estimate {
+	return Estimate{
+		Words:        e.Words + other.Words,
+		Requests:     e.Requests + other.Requests,
+		Cached:       e.Cached + other.Cached,
+		InputTokens:  e.InputTokens + other.InputTokens,
+		OutputTokens: e.OutputTokens + other.OutputTokens,
+		CostUSD:      e.CostUSD + other.CostUSD,
+	}
+}
+
+// Pricing is what a model charges, in US dollars per million tokens, and how
+// it counts image tokens: a base charge plus one per 512px tile
+type Pricing struct {
+	Model            string  `json:"model"`
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+	// Known is false when the model has no listed price and none is configured
+	Known bool `json:"known"`
+
+	imageBaseTokens int
+	imageTileTokens int
+}
+
+// modelPricing lists published prices, matched by model name prefix so dated
+// snapshots share their family's price
+var modelPricing = map[string]Pricing{
+	"gpt-4o":      {InputPerMillion: 2.50, OutputPerMillion: 10.00, imageBaseTokens: 85, imageTileTokens: 170},
+	"gpt-4o-mini": {InputPerMillion: 0.15, OutputPerMillion: 0.60, imageBaseTokens: 2833, imageTileTokens: 5667},
+	"gpt-4.1":     {InputPerMillion: 2.00, OutputPerMillion: 8.00, imageBaseTokens: 85, imageTileTokens: 170},
+	"gpt-4-turbo": {InputPerMillion: 10.00, OutputPerMillion: 30.00, imageBaseTokens: 85, imageTileTokens: 170},
+}
+
+// Tokens that aren't measured directly
+const (
+	// charsPerToken is the usual ratio for English text and markup
+	charsPerToken = 4
+	// messageOverheadTokens frame each chat message
+	messageOverheadTokens = 10
+	// averageWordChars is the expected length of a transcribed word
+	averageWordChars = 6
+)
+
+// Pricing looks up the configured model's price. OPENAI_INPUT_PRICE_PER_MILLION
+// and OPENAI_OUTPUT_PRICE_PER_MILLION override it, or price unlisted models.
+func (s *Service) Pricing() Pricing {
+	return lookupPricing(s.getModel())
+}
+
+func lookupPricing(model string) Pricing {
+	pricing := Pricing{imageBaseTokens: 85, imageTileTokens: 170}
+	prefixes := make([]string, 0, len(modelPricing))
+	for prefix := range modelPricing {
+		prefixes = append(prefixes, prefix)
+	}
+	// The longest prefix wins, so gpt-4o-mini isn't priced as gpt-4o
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(model, prefix) {
+			pricing = modelPricing[prefix]
+			pricing.Known = true
+			break
+		}
+	}
+	pricing.Model = model
+
+	input, inputErr := strconv.ParseFloat(os.Getenv("OPENAI_INPUT_PRICE_PER_MILLION"), 64)
+	output, outputErr := strconv.ParseFloat(os.Getenv("OPENAI_OUTPUT_PRICE_PER_MILLION"), 64)
+	if inputErr == nil && outputErr == nil {
+		pricing.InputPerMillion, pricing.OutputPerMillion, pricing.Known = input, output, true
+	}
+	return pricing
+}
+
+// Cost prices a number of input and output tokens
+func (p Pricing) Cost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*p.InputPerMillion + float64(outputTokens)*p.OutputPerMillion) / 1_000_000
+}
+
+// imageTokens counts a high detail image the way OpenAI does: scaled to fit
+// 2048x2048, then so its shorter side is at most 768px, and split into 512px tiles
+func (p Pricing) imageTokens(width, height int) int {
+	w, h := float64(width), float64(height)
+	if scale := 2048 / math.Max(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	if scale := 768 / math.Min(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	tiles := int(math.Ceil(w/512) * math.Ceil(h/512))
+	return p.imageBaseTokens + tiles*p.imageTileTokens
+}
+
+// transcriptionOutputTokens estimates the markup returned for a chunk: the
+// line and word tags drawn on the stitched image, each around a word of text
+func transcriptionOutputTokens(boxes []models.BBox, chunk wordRange) int {
+	chars := 0
+	for i := chunk.start; i < chunk.end; i++ {
+		box := boxes[i]
+		chars += len(fmt.Sprintf(`<span class='ocrx_line' id='line_%d' title='bbox %d %d %d %d'>`, i+1, box.X1, box.Y1, box.X2, box.Y2))
+		chars += len(fmt.Sprintf(`<span class='ocrx_word' id='word_%d' title='bbox %d %d %d %d'>`, i+1, box.X1, box.Y1, box.X2, box.Y2))
+		chars += averageWordChars + len("</span></span>\n")
+	}
+	return (chars + charsPerToken - 1) / charsPerToken
+}
+
+// EstimateLLM detects words and stitches them exactly as the LLM engine does,
+// then estimates the tokens and cost of transcribing them, without calling the API
+func (s *Service) EstimateLLM(imagePath string, opts Options) (Estimate, error) {
+	ws, err := s.newWorkspace()
+	if err != nil {
+		return Estimate{}, err
+	}
+	defer ws.Close()
+
+	ocrResponse, err := s.detectWordBoundariesCustom(ws, imagePath, opts)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("failed to detect word boundaries: %w", err)
+	}
+	boxes := detectedBoxes(ocrResponse)
+	estimate := Estimate{Words: len(boxes)}
+	if len(boxes) == 0 {
+		// Pages without words fall back to basic hOCR without a request
+		return estimate, nil
+	}
+
+	pricing := lookupPricing(s.modelFor(opts))
+	cacheDir := s.dirs.LLMCache
+	prompt := promptFor(opts)
+	promptTokens := messageOverheadTokens + (len(prompt)+charsPerToken-1)/charsPerToken
+	maxDimension := utils.GetEnvInt("OPENAI_MAX_IMAGE_DIMENSION", defaultMaxLLMImageDimension)
+	pageImage, err := s.loadPageImage(ws, imagePath)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("failed to load page image: %w", err)
+	}
+	for _, chunk := range chunkRanges(len(boxes), utils.GetEnvInt("OPENAI_WORDS_PER_CHUNK", 150)) {
+		stitchedPath, err := s.createStitchedImageWithHOCRMarkup(ws, imagePath, pageImage, ocrResponse, chunk)
+		if err != nil {
+			return Estimate{}, fmt.Errorf("failed to create stitched image: %w", err)
+		}
+		cached := false
+		if cacheDir != "" {
+			if imageHash, err := pixelHash(stitchedPath); err == nil {
+				_, cached = readLLMCache(cacheDir, llmCacheKey(imageHash, s.modelFor(opts), prompt, opts.Temperature))
+			}
+		}
+		width, height, err := s.getImageDimensions(stitchedPath)
+		ws.remove(stitchedPath)
+		if err != nil {
+			return Estimate{}, err
+		}
+		if cached {
+			estimate.Cached++
+			continue
+		}
+
+		// Oversized images are downscaled before they're sent
+		if scale := float64(maxDimension) / float64(max(width, height)); scale < 1 {
+			width, height = int(float64(width)*scale), int(float64(height)*scale)
+		}
+		estimate.Requests++
+		estimate.InputTokens += promptTokens + pricing.imageTokens(width, height)
+		estimate.OutputTokens += transcriptionOutputTokens(boxes, chunk)
+	}
+	estimate.CostUSD = pricing.Cost(estimate.InputTokens, estimate.OutputTokens)
+
+	slog.Info("Estimated LLM transcription", "image", imagePath, "words", estimate.Words, "requests", estimate.Requests, "cached", estimate.Cached, "input_tokens", estimate.InputTokens, "output_tokens", estimate.OutputTokens, "cost_usd", estimate.CostUSD)
+	return estimate, nil
+}
@@ -0,0 +1,53 @@
+package hocr
+
+import (
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestLookupPricing(t *testing.T) {
+	if p := lookupPricing("gpt-4o-mini-2024-07-18"); !p.Known || p.InputPerMillion != 0.15 || p.imageTileTokens != 5667 {
+		t.Errorf("gpt-4o-mini snapshot priced as %+v", p)
+	}
+	if p := lookupPricing("gpt-4o"); !p.Known || p.InputPerMillion != 2.50 {
+		t.Errorf("gpt-4o priced as %+v", p)
+	}
+	if p := lookupPricing("llava"); p.Known || p.Cost(1000, 1000) != 0 {
+		t.Errorf("unlisted model priced as %+v", p)
+	}
+
+	t.Setenv("OPENAI_INPUT_PRICE_PER_MILLION", "1")
+	t.Setenv("OPENAI_OUTPUT_PRICE_PER_MILLION", "3")
+	p := lookupPricing("llava")
+	if !p.Known || p.Cost(1_000_000, 1_000_000) != 4 {
+		t.Errorf("configured price not used: %+v", p)
+	}
+}
+
+func TestImageTokens(t *testing.T) {
+	p := lookupPricing("gpt-4o")
+	tests := []struct {
+		width, height int
+		want          int
+	}{
+		{512, 512, 85 + 170},
+		{1024, 1024, 85 + 4*170},
+		// 2048x4096 fits to 1024x2048, then 768x1536: 2x3 tiles
+		{2048, 4096, 85 + 6*170},
+	}
+	for _, tt := range tests {
+		if got := p.imageTokens(tt.width, tt.height); got != tt.want {
+			t.Errorf("imageTokens(%d, %d) = %d, want %d", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestTranscriptionOutputTokens(t *testing.T) {
+	boxes := []models.BBox{{X1: 1, Y1: 2, X2: 30, Y2: 40}, {X1: 35, Y1: 2, X2: 90, Y2: 40}, {X1: 1, Y1: 50, X2: 60, Y2: 90}}
+	all := transcriptionOutputTokens(boxes, wordRange{0, 3})
+	first := transcriptionOutputTokens(boxes, wordRange{0, 1})
+	if first < 30 || all < 3*first-3 || all > 3*first+3 {
+		t.Errorf("tokens for one word %d, for three %d", first, all)
+	}
+}
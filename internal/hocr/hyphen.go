@@ -0,0 +1,114 @@
+package hocr
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// defaultHyphens are the marks that break a word at the end of a line: the
+// hyphen-minus, the hyphen, the not sign older printing used, and the soft
+// hyphen hOCR from other tools records breaks with
+const defaultHyphens = "-\u2010¬\u00ad"
+
+// languageHyphens are the marks of languages that break words differently:
+// Fraktur's double hyphen, which OCR often reads as "=", and the Hebrew maqaf.
+// Scripts that break lines anywhere, without a mark, have none.
+var languageHyphens = map[string]string{
+	"frk":          defaultHyphens + "⸗=",
+	"heb":          defaultHyphens + "־",
+	"yid":          defaultHyphens + "־",
+	"chi_sim":      "",
+	"chi_tra":      "",
+	"chi_sim_vert": "",
+	"chi_tra_vert": "",
+	"jpn":          "",
+	"jpn_vert":     "",
+	"kor":          "",
+	"kor_vert":     "",
+	"tha":          "",
+}
+
+// HyphenMarks are the marks that break a word across lines in text of the
+// language, the first of several. HYPHENATION_MARKS overrides them per
+// language, as in "frk=-⸗=;eng=-", where nothing after the "=" turns
+// hyphenation off and "*" stands for every language not listed.
+func HyphenMarks(language string) string {
+	first, _, _ := strings.Cut(language, "+")
+	overrides := hyphenOverrides()
+	if marks, ok := overrides[first]; ok {
+		return marks
+	}
+	if marks, ok := languageHyphens[first]; ok {
+		return marks
+	}
+	if marks, ok := overrides["*"]; ok {
+		return marks
+	}
+	return defaultHyphens
+}
+
+// hyphenOverrides reads HYPHENATION_MARKS
+func hyphenOverrides() map[string]string {
+	overrides := map[string]string{}
+	for _, entry := range strings.Split(os.Getenv("HYPHENATION_MARKS"), ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		language, marks, ok := strings.Cut(entry, "=")
+		if !ok {
+			slog.Warn("Ignoring HYPHENATION_MARKS entry without \"=\"", "entry", entry)
+			continue
+		}
+		overrides[strings.TrimSpace(language)] = strings.TrimSpace(marks)
+	}
+	return overrides
+}
+
+// MarkHyphenation returns a copy of lines with the words broken across a line
+// end marked Hyphenated: the last word of a line, ending in one of the
+// language's marks after a letter, when the next line goes on with a letter
+// that isn't a capital. Vertical lines aren't hyphenated.
+func MarkHyphenation(lines []models.HOCRLine, language string) []models.HOCRLine {
+	marks := HyphenMarks(language)
+	marked := make([]models.HOCRLine, len(lines))
+	for i, line := range lines {
+		line.Words = append([]models.HOCRWord{}, line.Words...)
+		for j := range line.Words {
+			line.Words[j].Hyphenated = false
+		}
+		marked[i] = line
+	}
+	if marks == "" {
+		return marked
+	}
+
+	for i := 0; i+1 < len(marked); i++ {
+		line, next := marked[i], marked[i+1]
+		if len(line.Words) == 0 || len(next.Words) == 0 || IsVertical(line) {
+			continue
+		}
+		last := &line.Words[len(line.Words)-1]
+		if breaksWord(last.Text, next.Words[0].Text, marks) {
+			last.Hyphenated = true
+		}
+	}
+	return marked
+}
+
+// breaksWord reports whether word ends in a hyphen that joins it to the start
+// of the next line
+func breaksWord(word, next, marks string) bool {
+	word = strings.TrimSpace(word)
+	mark, size := utf8.DecodeLastRuneInString(word)
+	if size == 0 || !strings.ContainsRune(marks, mark) {
+		return false
+	}
+	before, _ := utf8.DecodeLastRuneInString(word[:len(word)-size])
+	after, _ := utf8.DecodeRuneInString(strings.TrimSpace(next))
+	return unicode.IsLetter(before) && unicode.IsLetter(after) && !unicode.IsUpper(after)
+}
@@ -0,0 +1,79 @@
+package hocr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func hyphenLines(texts ...string) []models.HOCRLine {
+	var lines []models.HOCRLine
+	for i, text := range texts {
+		line := models.HOCRLine{ID: "line_" + string(rune('1'+i))}
+		for _, word := range strings.Fields(text) {
+			line.Words = append(line.Words, models.HOCRWord{ID: line.ID + "_" + word, Text: word})
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func hyphenated(lines []models.HOCRLine) []bool {
+	var marks []bool
+	for _, line := range lines {
+		marks = append(marks, len(line.Words) > 0 && line.Words[len(line.Words)-1].Hyphenated)
+	}
+	return marks
+}
+
+func TestMarkHyphenation(t *testing.T) {
+	lines := hyphenLines("the infor-", "mation age-", "Old and new ‐", "x", "Zei⸗", "tung")
+	got := hyphenated(MarkHyphenation(lines, "eng"))
+	// A capital after the break keeps the hyphen; a mark alone breaks nothing;
+	// the double hyphen isn't English
+	want := []bool{true, false, false, false, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("eng line %d hyphenated = %v, want %v", i+1, got[i], want[i])
+		}
+	}
+	if lines[0].Words[1].Hyphenated {
+		t.Error("MarkHyphenation changed the lines it was given")
+	}
+	if got := hyphenated(MarkHyphenation(lines, "frk")); !got[4] {
+		t.Error("Fraktur double hyphen not taken for a break")
+	}
+	if got := hyphenated(MarkHyphenation(lines, "jpn")); got[0] {
+		t.Error("Japanese hyphenated")
+	}
+
+	t.Setenv("HYPHENATION_MARKS", "*=;frk=-")
+	if got := hyphenated(MarkHyphenation(lines, "eng")); got[0] {
+		t.Error("hyphenation not turned off for every language")
+	}
+	if got := hyphenated(MarkHyphenation(lines, "frk")); !got[0] || got[4] {
+		t.Errorf("overridden Fraktur marks gave %v", got)
+	}
+}
+
+func TestHyphenationRoundTrip(t *testing.T) {
+	lines := hyphenLines("the infor-", "mation")
+	for i := range lines {
+		for j := range lines[i].Words {
+			lines[i].Words[j].BBox = models.BBox{X1: j * 10, Y1: i * 10, X2: j*10 + 9, Y2: i*10 + 9}
+		}
+	}
+	parsed, err := ParseHOCRLines(NewConverter().ConvertHOCRLinesToXML(lines, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hyphenated(parsed); !got[0] || got[1] {
+		t.Errorf("parsed hyphenation %v", got)
+	}
+
+	shy, err := ParseHOCRLines(`<div class='ocr_page'><span class='ocr_line' id='line_1' title='bbox 0 0 9 9'><span class='ocrx_word' id='word_1' title='bbox 0 0 9 9'>infor&#xad;</span></span></div>`)
+	if err != nil || len(shy) != 1 || !shy[0].Words[0].Hyphenated {
+		t.Errorf("soft hyphen not read as a break: %+v, %v", shy, err)
+	}
+}
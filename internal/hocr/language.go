@@ -0,0 +1,165 @@
+package hocr
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// languageNames are the Tesseract language packs collections most often need,
+// with the names the LLM is told the text is in
+var languageNames = map[string]string{
+	"ara":          "Arabic",
+	"ces":          "Czech",
+	"chi_sim":      "Simplified Chinese",
+	"chi_tra":      "Traditional Chinese",
+	"chi_sim_vert": "Vertical Simplified Chinese",
+	"chi_tra_vert": "Vertical Traditional Chinese",
+	"cym":          "Welsh",
+	"dan":          "Danish",
+	"deu":          "German",
+	"ell":          "Greek",
+	"eng":          "English",
+	"fas":          "Persian",
+	"fin":          "Finnish",
+	"fra":          "French",
+	"frk":          "German Fraktur",
+	"gle":          "Irish",
+	"grc":          "Ancient Greek",
+	"heb":          "Hebrew",
+	"hin":          "Hindi",
+	"hun":          "Hungarian",
+	"ita":          "Italian",
+	"jpn":          "Japanese",
+	"jpn_vert":     "Vertical Japanese",
+	"kor":          "Korean",
+	"kor_vert":     "Vertical Korean",
+	"lat":          "Latin",
+	"nld":          "Dutch",
+	"nor":          "Norwegian",
+	"pol":          "Polish",
+	"por":          "Portuguese",
+	"rus":          "Russian",
+	"spa":          "Spanish",
+	"swe":          "Swedish",
+	"tur":          "Turkish",
+	"ukr":          "Ukrainian",
+	"yid":          "Yiddish",
+}
+
+// languageAliases are the ISO 639-1 and Drupal language codes of those packs
+var languageAliases = map[string]string{
+	"ar": "ara", "cs": "ces", "cy": "cym", "da": "dan", "de": "deu", "el": "ell",
+	"en": "eng", "es": "spa", "fa": "fas", "fi": "fin", "fr": "fra", "ga": "gle",
+	"he": "heb", "hi": "hin", "hu": "hun", "it": "ita", "ja": "jpn", "ko": "kor",
+	"la": "lat", "nb": "nor", "nl": "nld", "nn": "nor", "no": "nor", "pl": "pol",
+	"pt": "por", "ru": "rus", "sv": "swe", "tr": "tur", "uk": "ukr", "yi": "yid",
+	"zh": "chi_sim", "zh-hans": "chi_sim", "zh-hant": "chi_tra",
+}
+
+// tesseractLanguage matches the names of language packs not listed here
+var tesseractLanguage = regexp.MustCompile(`^[a-z]{3}(_[a-z]+)?$`)
+
+// ParseLanguage reads the languages a text is in as Tesseract's -l takes them,
+// codes joined by "+" with the first the most common, as in "deu+lat". It also
+// takes ISO 639-1 and Drupal codes ("de", "pt-br") and the names above
+// ("German Fraktur"), separated by "+" or ",". "", "und" and "zxx" mean the
+// language isn't known, and are "".
+func ParseLanguage(value string) (string, error) {
+	var codes []string
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == '+' || r == ',' }) {
+		code, err := parseLanguageCode(strings.ToLower(strings.TrimSpace(part)))
+		if err != nil {
+			return "", err
+		}
+		if code != "" && !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return strings.Join(codes, "+"), nil
+}
+
+func parseLanguageCode(part string) (string, error) {
+	switch part {
+	case "", "und", "zxx":
+		return "", nil
+	}
+	if _, ok := languageNames[part]; ok {
+		return part, nil
+	}
+	if code, ok := languageAliases[part]; ok {
+		return code, nil
+	}
+	// A region doesn't change the language pack: pt-br is Portuguese
+	if base, _, ok := strings.Cut(part, "-"); ok {
+		if code, ok := languageAliases[base]; ok {
+			return code, nil
+		}
+	}
+	for code, name := range languageNames {
+		if strings.EqualFold(name, part) {
+			return code, nil
+		}
+	}
+	if tesseractLanguage.MatchString(part) {
+		return part, nil
+	}
+	return "", fmt.Errorf("unknown language: %s", part)
+}
+
+// languageHint tells the LLM what the text is in, as in "The text is in
+// German Fraktur and Latin.", or nothing when the language isn't known
+func languageHint(language string) string {
+	if language == "" {
+		return ""
+	}
+	var names []string
+	for _, code := range strings.Split(language, "+") {
+		names = append(names, languageName(code))
+	}
+	list := names[0]
+	if len(names) > 1 {
+		list = strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+	}
+	return "The text is in " + list + "."
+}
+
+// languageName is what the LLM is told a language pack is called
+func languageName(code string) string {
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return code
+}
+
+// languageMarkingHint asks the LLM to mark the words of a page in several
+// languages that aren't in the first, as in "Add lang='la' after the title of
+// each word span whose text is in Latin.", or nothing for a page in one
+func languageMarkingHint(language string) string {
+	codes := strings.Split(language, "+")
+	if len(codes) < 2 {
+		return ""
+	}
+	var hints []string
+	for _, code := range codes[1:] {
+		hints = append(hints, fmt.Sprintf("lang='%s' after the title of each word span whose text is in %s", htmlLanguage(code), languageName(code)))
+	}
+	return "Add " + strings.Join(hints, ", and ") + "."
+}
+
+// promptFor is the transcription prompt, or the one asked for, with the language hint when the
+// language is known, for a page in several how to mark the words in each, the
+// guidance of the document type and the characters the text may hold
+func promptFor(opts Options) string {
+	prompt := transcriptionPrompt
+	if opts.Prompt != "" {
+		prompt = opts.Prompt
+	}
+	for _, hint := range []string{languageHint(opts.Language), languageMarkingHint(opts.Language), profileOf(opts.documentType()).hint, opts.Characters.hint()} {
+		if hint != "" {
+			prompt += "\n" + hint
+		}
+	}
+	return prompt
+}
@@ -0,0 +1,52 @@
+package hocr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLanguage(t *testing.T) {
+	for value, want := range map[string]string{
+		"":               "",
+		"und":            "",
+		"deu":            "deu",
+		"de":             "deu",
+		"pt-BR":          "por",
+		"zh-Hant":        "chi_tra",
+		"German Fraktur": "frk",
+		"deu+lat":        "deu+lat",
+		"de, la, deu":    "deu+lat",
+		"san":            "san",
+		"frk, en+zxx":    "frk+eng",
+	} {
+		got, err := ParseLanguage(value)
+		if err != nil || got != want {
+			t.Errorf("ParseLanguage(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"Klingon", "de;rm -rf", "../deu"} {
+		if got, err := ParseLanguage(value); err == nil {
+			t.Errorf("ParseLanguage(%q) = %q, want an error", value, got)
+		}
+	}
+}
+
+func TestPromptFor(t *testing.T) {
+	if promptFor(Options{}) != transcriptionPrompt {
+		t.Error("prompt changed without a language")
+	}
+	if prompt := promptFor(Options{Language: "deu"}); !strings.HasSuffix(prompt, "\nThe text is in German.") {
+		t.Errorf("prompt ends %q", prompt[len(transcriptionPrompt):])
+	}
+	// Words in the languages after the first are to be marked, and German
+	// Fraktur is read as Fraktur
+	prompt := promptFor(Options{Language: "frk+lat+xyz"})
+	want := "\nThe text is in German Fraktur, Latin and xyz.\nAdd lang='la' after the title of each word span whose text is in Latin, and lang='xyz' after the title of each word span whose text is in xyz.\n" + documentProfiles[DocumentFraktur].hint
+	if !strings.HasSuffix(prompt, want) {
+		t.Errorf("prompt ends %q", prompt[len(transcriptionPrompt):])
+	}
+	// A prompt of its own replaces the instructions and keeps the hints
+	if prompt := promptFor(Options{Prompt: "Transcribe.", Language: "deu"}); prompt != "Transcribe.\nThe text is in German." {
+		t.Errorf("custom prompt is %q", prompt)
+	}
+}
@@ -0,0 +1,101 @@
+package hocr
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// LLMCacheDir holds transcriptions keyed by what was sent to the LLM, so re-runs
+// after a partial failure or of an unchanged chunk don't pay for the same request.
+// It comes from LLM_CACHE_DIR (default llm under cacheRoot); "off" disables the
+// cache and gives "".
+func LLMCacheDir(cacheRoot string) string {
+	switch dir := os.Getenv("LLM_CACHE_DIR"); dir {
+	case "off":
+		return ""
+	case "":
+		return filepath.Join(cacheRoot, "llm")
+	default:
+		return dir
+	}
+}
+
+// pixelHash hashes an image's decoded pixels, so encoder metadata such as the
+// timestamps ImageMagick writes into PNGs doesn't change the key
+func pixelHash(imagePath string) (string, error) {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	hash := sha256.New()
+	bounds := img.Bounds()
+	binary.Write(hash, binary.LittleEndian, [2]int64{int64(bounds.Dx()), int64(bounds.Dy())})
+	pixel := make([]byte, 8)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			binary.LittleEndian.PutUint16(pixel[0:], uint16(r))
+			binary.LittleEndian.PutUint16(pixel[2:], uint16(g))
+			binary.LittleEndian.PutUint16(pixel[4:], uint16(b))
+			binary.LittleEndian.PutUint16(pixel[6:], uint16(a))
+			hash.Write(pixel)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// llmCacheKey combines the stitched image, model, prompt and temperature into
+// a cache key. The API's default temperature leaves keys as they were before
+// temperature could be set.
+func llmCacheKey(imageHash, model, prompt string, temperature float64) string {
+	promptHash := sha256.Sum256([]byte(prompt))
+	settings := imageHash + "\n" + model + "\n" + hex.EncodeToString(promptHash[:])
+	if temperature != 0 {
+		settings += "\n" + strconv.FormatFloat(temperature, 'g', -1, 64)
+	}
+	key := sha256.Sum256([]byte(settings))
+	return hex.EncodeToString(key[:])
+}
+
+func llmCachePath(dir, key string) string {
+	return filepath.Join(dir, key[:2], key+".xml")
+}
+
+func readLLMCache(dir, key string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	data, err := os.ReadFile(llmCachePath(dir, key))
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+func writeLLMCache(dir, key, content string) {
+	if dir == "" {
+		return
+	}
+	path := llmCachePath(dir, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		slog.Warn("Failed to create LLM cache directory", "err", err)
+		return
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		slog.Warn("Failed to cache LLM transcription", "err", err)
+	}
+}
@@ -0,0 +1,67 @@
+package hocr
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPixelHashIgnoresEncoding(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewGray(image.Rect(0, 0, 4, 3))
+	img.SetGray(1, 1, color.Gray{Y: 200})
+
+	write := func(name string, level png.CompressionLevel) string {
+		path := filepath.Join(dir, name)
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if err := (&png.Encoder{CompressionLevel: level}).Encode(file, img); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	fast, err := pixelHash(write("fast.png", png.BestSpeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := pixelHash(write("small.png", png.BestCompression))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fast != small {
+		t.Error("re-encoding the same pixels changed the hash")
+	}
+
+	img.SetGray(2, 2, color.Gray{Y: 10})
+	changed, err := pixelHash(write("changed.png", png.BestSpeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == fast {
+		t.Error("changing a pixel kept the same hash")
+	}
+}
+
+func TestLLMCacheKey(t *testing.T) {
+	base := llmCacheKey("abc", "gpt-4o", transcriptionPrompt, 0)
+	if base != llmCacheKey("abc", "gpt-4o", transcriptionPrompt, 0) {
+		t.Error("key is not stable")
+	}
+	for _, other := range []string{
+		llmCacheKey("abd", "gpt-4o", transcriptionPrompt, 0),
+		llmCacheKey("abc", "gpt-4.1", transcriptionPrompt, 0),
+		llmCacheKey("abc", "gpt-4o", transcriptionPrompt+" ", 0),
+		llmCacheKey("abc", "gpt-4o", transcriptionPrompt, 0.7),
+	} {
+		if other == base {
+			t.Error("different inputs share a key")
+		}
+	}
+}
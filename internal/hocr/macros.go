@@ -0,0 +1,142 @@
+package hocr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// ValidateMacro checks that every step is known and every pattern compiles
+func ValidateMacro(macro models.CorrectionMacro) error {
+	if len(macro.Steps) == 0 {
+		return fmt.Errorf("macro has no steps")
+	}
+
+	for i, step := range macro.Steps {
+		switch step.Type {
+		case models.MacroStepReplace:
+			if step.Pattern == "" {
+				return fmt.Errorf("step %d: pattern is required", i+1)
+			}
+		case models.MacroStepRegex, models.MacroStepDelete:
+			if _, err := regexp.Compile(step.Pattern); err != nil {
+				return fmt.Errorf("step %d: invalid pattern: %w", i+1, err)
+			}
+		case models.MacroStepJoinHyphens:
+		default:
+			return fmt.Errorf("step %d: unknown step type %q", i+1, step.Type)
+		}
+	}
+
+	return nil
+}
+
+// ApplyMacro runs each macro step over the words in lines, limited to words whose
+// center falls inside region when one is given. It returns the edited lines and the
+// number of words changed.
+func ApplyMacro(lines []models.HOCRLine, macro models.CorrectionMacro, region *models.BBox) ([]models.HOCRLine, int, error) {
+	if err := ValidateMacro(macro); err != nil {
+		return nil, 0, err
+	}
+
+	changed := 0
+	for _, step := range macro.Steps {
+		var n int
+		switch step.Type {
+		case models.MacroStepReplace:
+			n = mapWords(lines, region, func(text string) string {
+				return strings.ReplaceAll(text, step.Pattern, step.Replacement)
+			})
+		case models.MacroStepRegex:
+			re := regexp.MustCompile(step.Pattern)
+			n = mapWords(lines, region, func(text string) string {
+				return re.ReplaceAllString(text, step.Replacement)
+			})
+		case models.MacroStepDelete:
+			re := regexp.MustCompile(step.Pattern)
+			n = mapWords(lines, region, func(text string) string {
+				if re.MatchString(text) {
+					return ""
+				}
+				return text
+			})
+		case models.MacroStepJoinHyphens:
+			n = joinLineEndHyphens(lines, region)
+		}
+		changed += n
+	}
+
+	return removeEmptyWords(lines), changed, nil
+}
+
+func mapWords(lines []models.HOCRLine, region *models.BBox, fn func(string) string) int {
+	changed := 0
+	for i := range lines {
+		for j := range lines[i].Words {
+			word := &lines[i].Words[j]
+			if !inRegion(word.BBox, region) {
+				continue
+			}
+			if text := fn(word.Text); text != word.Text {
+				word.Text = text
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+// joinLineEndHyphens moves the first word of the following line onto a word that
+// ends a line with a hyphen, e.g. "trans-" + "cription" becomes "transcription"
+func joinLineEndHyphens(lines []models.HOCRLine, region *models.BBox) int {
+	changed := 0
+	for i := 0; i < len(lines)-1; i++ {
+		if len(lines[i].Words) == 0 || len(lines[i+1].Words) == 0 {
+			continue
+		}
+
+		last := &lines[i].Words[len(lines[i].Words)-1]
+		next := &lines[i+1].Words[0]
+		if !inRegion(last.BBox, region) || !inRegion(next.BBox, region) {
+			continue
+		}
+		if len(last.Text) < 2 || !strings.HasSuffix(last.Text, "-") {
+			continue
+		}
+
+		last.Text = strings.TrimSuffix(last.Text, "-") + next.Text
+		next.Text = ""
+		changed++
+	}
+	return changed
+}
+
+func removeEmptyWords(lines []models.HOCRLine) []models.HOCRLine {
+	var result []models.HOCRLine
+	for _, line := range lines {
+		words := line.Words[:0]
+		for _, word := range line.Words {
+			if strings.TrimSpace(word.Text) != "" {
+				words = append(words, word)
+			}
+		}
+		if len(words) == 0 {
+			continue
+		}
+		line.Words = words
+		result = append(result, line)
+	}
+	return result
+}
+
+// inRegion reports whether the center of box lies inside region; a nil region matches everything
+func inRegion(box models.BBox, region *models.BBox) bool {
+	if region == nil {
+		return true
+	}
+	cx := (box.X1 + box.X2) / 2
+	cy := (box.Y1 + box.Y2) / 2
+	return cx >= region.X1 && cx <= region.X2 && cy >= region.Y1 && cy <= region.Y2
+}
@@ -0,0 +1,99 @@
+package hocr
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Ways transcribed quotes and ligatures are treated
+const (
+	textKeep     = "keep"
+	textStraight = "straight"
+	textExpand   = "expand"
+)
+
+// zeroWidth are invisible characters models scatter through their output,
+// which break search and word matching. The zero-width joiner and non-joiner
+// are kept: Persian and the Indic scripts spell with them.
+var zeroWidth = strings.NewReplacer("\u200b", "", "\u2060", "", "\ufeff", "")
+
+// curlyQuotes are typographic quotes and apostrophes, as their ASCII forms
+var curlyQuotes = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`,
+)
+
+// typographicLigatures are the presentation forms of Latin ligatures, spelled
+// out. Letters such as æ and œ are left alone; some languages spell with them.
+var typographicLigatures = strings.NewReplacer(
+	"ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "ﬅ", "st", "ﬆ", "st",
+)
+
+// textPolicy is how transcribed text is normalized before it becomes hOCR,
+// read from OPENAI_TEXT_FORM, OPENAI_TEXT_QUOTES and OPENAI_TEXT_LIGATURES
+type textPolicy struct {
+	form      string
+	quotes    string
+	ligatures string
+}
+
+func textPolicyFromEnv() textPolicy {
+	return textPolicy{
+		form:      envChoice("OPENAI_TEXT_FORM", models.NormalizeNFC, models.NormalizeNFKC, models.NormalizeNone),
+		quotes:    envChoice("OPENAI_TEXT_QUOTES", textKeep, textStraight),
+		ligatures: envChoice("OPENAI_TEXT_LIGATURES", textKeep, textExpand),
+	}
+}
+
+// envChoice is the value of an environment variable when it's one of choices,
+// or else the first of them, the default
+func envChoice(name string, choices ...string) string {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
+	if value == "" {
+		return choices[0]
+	}
+	for _, choice := range choices {
+		if value == choice {
+			return value
+		}
+	}
+	slog.Warn("Ignoring unknown setting", "name", name, "value", value, "default", choices[0])
+	return choices[0]
+}
+
+// normalizeText puts a transcribed text into one Unicode form, drops
+// zero-width characters, and maps quotes and ligatures as the policy says
+func (p textPolicy) normalizeText(text string) string {
+	switch p.form {
+	case models.NormalizeNFC:
+		text = norm.NFC.String(text)
+	case models.NormalizeNFKC:
+		text = norm.NFKC.String(text)
+	}
+	text = zeroWidth.Replace(text)
+	if p.quotes == textStraight {
+		text = curlyQuotes.Replace(text)
+	}
+	if p.ligatures == textExpand {
+		text = typographicLigatures.Replace(text)
+	}
+	return text
+}
+
+// normalizeMarkup normalizes the text of an hOCR fragment, leaving its tags
+// and their attributes as they are
+func (p textPolicy) normalizeMarkup(markup string) string {
+	var result strings.Builder
+	last := 0
+	for _, tag := range markupTags.FindAllStringIndex(markup, -1) {
+		result.WriteString(p.normalizeText(markup[last:tag[0]]))
+		result.WriteString(markup[tag[0]:tag[1]])
+		last = tag[1]
+	}
+	result.WriteString(p.normalizeText(markup[last:]))
+	return result.String()
+}
@@ -0,0 +1,37 @@
+package hocr
+
+import (
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestNormalizeMarkup(t *testing.T) {
+	// e and a combining acute, a zero-width space, curly quotes and a ligature
+	markup := "<span class='ocrx_word' id='word_1' title='bbox 0 0 9 9'>“cafe\u0301\u200b ﬁne’</span>"
+
+	kept := textPolicy{form: models.NormalizeNFC, quotes: textKeep, ligatures: textKeep}.normalizeMarkup(markup)
+	if want := "<span class='ocrx_word' id='word_1' title='bbox 0 0 9 9'>“café ﬁne’</span>"; kept != want {
+		t.Errorf("kept = %q, want %q", kept, want)
+	}
+
+	mapped := textPolicy{form: models.NormalizeNFC, quotes: textStraight, ligatures: textExpand}.normalizeMarkup(markup)
+	if want := "<span class='ocrx_word' id='word_1' title='bbox 0 0 9 9'>\"café fine'</span>"; mapped != want {
+		t.Errorf("mapped = %q, want %q", mapped, want)
+	}
+
+	// Joiners spell Persian and the Indic scripts
+	if text := (textPolicy{}).normalizeText("\u0645\u06cc\u200c\u062e\u0648\u0627\u0647\u0645"); text != "\u0645\u06cc\u200c\u062e\u0648\u0627\u0647\u0645" {
+		t.Errorf("zero-width non-joiner dropped: %q", text)
+	}
+}
+
+func TestTextPolicyFromEnv(t *testing.T) {
+	t.Setenv("OPENAI_TEXT_FORM", "NFKC")
+	t.Setenv("OPENAI_TEXT_QUOTES", "smart")
+	t.Setenv("OPENAI_TEXT_LIGATURES", "")
+	want := textPolicy{form: models.NormalizeNFKC, quotes: textKeep, ligatures: textKeep}
+	if policy := textPolicyFromEnv(); policy != want {
+		t.Errorf("policy = %+v, want %+v", policy, want)
+	}
+}
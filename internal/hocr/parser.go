@@ -27,11 +27,14 @@ func ParseHOCRLines(hocrXML string) ([]models.HOCRLine, error) {
 
 	var lines []models.HOCRLine
 
-	traverseLinesElements(doc, &lines)
+	traverseLinesElements(doc, &lines, "")
 
 	return lines, nil
 }
 
+// ParseHOCRWords reads the words of an hOCR document without their lines.
+// Each word's Language is the one marked on it or on an element around it,
+// other than the document's root.
 func ParseHOCRWords(hocrXML string) ([]models.HOCRWord, error) {
 	var doc XMLElement
 
@@ -42,25 +45,109 @@ func ParseHOCRWords(hocrXML string) ([]models.HOCRWord, error) {
 
 	var words []models.HOCRWord
 
-	traverseElementsWithLineContext(doc, &words, "")
+	traverseElementsWithLineContext(doc, &words, "", "")
 
 	return words, nil
 }
 
-func traverseLinesElements(element XMLElement, lines *[]models.HOCRLine) {
+// Page is the ocr_page of an hOCR document: its size in pixels, the image it
+// was read from, and the image's resolution when the engine recorded it
+type Page struct {
+	Width  int
+	Height int
+	Image  string
+	DPI    int
+}
+
+var (
+	pageImageRegex   = regexp.MustCompile(`image\s+"([^"]*)"`)
+	pageScanResRegex = regexp.MustCompile(`scan_res\s+(\d+)`)
+	textAngleRegex   = regexp.MustCompile(`textangle\s+(-?\d+)`)
+	hyphenatedRegex  = regexp.MustCompile(`x_hyphenated\s+1\b`)
+	autoCorrectRegex = regexp.MustCompile(`x_autocorrected\s+("(?:[^"\\]|\\.)*")`)
+)
+
+// ParsePage reads the first ocr_page of an hOCR document
+func ParsePage(hocrXML string) (Page, error) {
+	var doc XMLElement
+
+	decoder := xml.NewDecoder(strings.NewReader(hocrXML))
+	if err := decoder.Decode(&doc); err != nil {
+		return Page{}, fmt.Errorf("failed to parse XML: %w", err)
+	}
+
+	element := findPageElement(doc)
+	if element == nil {
+		return Page{}, fmt.Errorf("hOCR has no ocr_page")
+	}
+	var page Page
+	for _, attr := range element.Attrs {
+		if attr.Name.Local != "title" {
+			continue
+		}
+		var bbox models.HOCRLine
+		if err := parseLineTitleAttribute(attr.Value, &bbox); err != nil {
+			return Page{}, err
+		}
+		page.Width, page.Height = bbox.BBox.X2, bbox.BBox.Y2
+		if matches := pageImageRegex.FindStringSubmatch(attr.Value); len(matches) == 2 {
+			page.Image = matches[1]
+		}
+		if matches := pageScanResRegex.FindStringSubmatch(attr.Value); len(matches) == 2 {
+			page.DPI, _ = strconv.Atoi(matches[1])
+		}
+	}
+	return page, nil
+}
+
+func findPageElement(element XMLElement) *XMLElement {
+	for _, attr := range element.Attrs {
+		if attr.Name.Local == "class" && strings.Contains(attr.Value, "ocr_page") {
+			return &element
+		}
+	}
+	for _, child := range element.Children {
+		if page := findPageElement(child); page != nil {
+			return page
+		}
+	}
+	return nil
+}
+
+// elementLanguage is the BCP 47 tag of the language an element is in: its
+// lang attribute's, or else inherited, that of the element around it. The
+// document's own language, on its root, isn't inherited: lines and words are
+// only marked where they differ from it.
+func elementLanguage(element XMLElement, inherited string) string {
+	if element.XMLName.Local == "html" {
+		return inherited
+	}
+	for _, attr := range element.Attrs {
+		if attr.Name.Local == "lang" {
+			if tag := languageTag(attr.Value); tag != "" {
+				return tag
+			}
+		}
+	}
+	return inherited
+}
+
+func traverseLinesElements(element XMLElement, lines *[]models.HOCRLine, language string) {
+	language = elementLanguage(element, language)
 	if isLineElement(element) {
-		line, err := parseLineElement(element)
+		line, err := parseLineElement(element, language)
 		if err == nil && line.ID != "" {
 			*lines = append(*lines, line)
 		}
 	}
 
 	for _, child := range element.Children {
-		traverseLinesElements(child, lines)
+		traverseLinesElements(child, lines, language)
 	}
 }
 
-func traverseElementsWithLineContext(element XMLElement, words *[]models.HOCRWord, currentLineID string) {
+func traverseElementsWithLineContext(element XMLElement, words *[]models.HOCRWord, currentLineID, language string) {
+	language = elementLanguage(element, language)
 	// Update line ID if this element is a line element
 	if isLineElement(element) {
 		for _, attr := range element.Attrs {
@@ -76,19 +163,20 @@ func traverseElementsWithLineContext(element XMLElement, words *[]models.HOCRWor
 		word, err := parseWordElement(element)
 		if err == nil && word.ID != "" && isValidWordText(word.Text) {
 			word.LineID = currentLineID
+			word.Language = language
 			*words = append(*words, word)
 		}
 	}
 
 	// Recursively traverse children with current line context
 	for _, child := range element.Children {
-		traverseElementsWithLineContext(child, words, currentLineID)
+		traverseElementsWithLineContext(child, words, currentLineID, language)
 	}
 }
 
 func isLineElement(element XMLElement) bool {
 	for _, attr := range element.Attrs {
-		if attr.Name.Local == "class" && strings.Contains(attr.Value, "ocr_line") {
+		if attr.Name.Local == "class" && (strings.Contains(attr.Value, "ocr_line") || strings.Contains(attr.Value, "ocrx_line")) {
 			return true
 		}
 	}
@@ -104,8 +192,9 @@ func isWordElement(element XMLElement) bool {
 	return false
 }
 
-func parseLineElement(element XMLElement) (models.HOCRLine, error) {
-	line := models.HOCRLine{}
+// parseLineElement reads a line in language, marking the words in another
+func parseLineElement(element XMLElement, language string) (models.HOCRLine, error) {
+	line := models.HOCRLine{Language: language}
 
 	for _, attr := range element.Attrs {
 		switch attr.Name.Local {
@@ -120,13 +209,19 @@ func parseLineElement(element XMLElement) (models.HOCRLine, error) {
 
 	// Find ALL words in this line
 	var words []models.HOCRWord
-	findAllWordsInLine(element, &words, line.ID)
+	findAllWordsInLine(element, &words, line.ID, language)
+	for i := range words {
+		if words[i].Language == language {
+			words[i].Language = ""
+		}
+	}
 	line.Words = words
 
 	return line, nil
 }
 
-func findAllWordsInLine(element XMLElement, words *[]models.HOCRWord, lineID string) {
+func findAllWordsInLine(element XMLElement, words *[]models.HOCRWord, lineID, language string) {
+	language = elementLanguage(element, language)
 	if isWordElement(element) {
 		word, err := parseWordElement(element)
 		if err == nil && word.ID != "" && isValidWordText(word.Text) {
@@ -137,12 +232,13 @@ func findAllWordsInLine(element XMLElement, words *[]models.HOCRWord, lineID str
 				// Fallback: generate line ID if missing
 				word.LineID = "line_" + word.ID
 			}
+			word.Language = language
 			*words = append(*words, word)
 		}
 	}
 
 	for _, child := range element.Children {
-		findAllWordsInLine(child, words, lineID)
+		findAllWordsInLine(child, words, lineID, language)
 	}
 }
 
@@ -163,6 +259,9 @@ func parseLineTitleAttribute(title string, line *models.HOCRLine) error {
 			return fmt.Errorf("invalid bbox y2: %w", err)
 		}
 	}
+	if matches := textAngleRegex.FindStringSubmatch(title); len(matches) == 2 {
+		line.TextAngle, _ = strconv.Atoi(matches[1])
+	}
 
 	return nil
 }
@@ -182,6 +281,10 @@ func parseWordElement(element XMLElement) (models.HOCRWord, error) {
 	}
 
 	word.Text = strings.TrimSpace(element.Content)
+	// Other tools end a word broken across lines with a soft hyphen (&shy;)
+	if strings.HasSuffix(word.Text, "\u00ad") {
+		word.Hyphenated = true
+	}
 
 	return word, nil
 }
@@ -211,6 +314,16 @@ func parseTitleAttribute(title string, word *models.HOCRWord) error {
 			return fmt.Errorf("invalid confidence: %w", err)
 		}
 	}
+	if hyphenatedRegex.MatchString(title) {
+		word.Hyphenated = true
+	}
+	if matches := autoCorrectRegex.FindStringSubmatch(title); len(matches) == 2 {
+		original, err := strconv.Unquote(matches[1])
+		if err != nil {
+			return fmt.Errorf("invalid x_autocorrected: %w", err)
+		}
+		word.AutoCorrected = original
+	}
 
 	return nil
 }
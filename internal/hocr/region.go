@@ -0,0 +1,55 @@
+package hocr
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// ProcessRegion runs the pipeline on one region of an image, so a badly read
+// paragraph can be redone without paying for the whole page. The lines come
+// back in page coordinates.
+func (s *Service) ProcessRegion(imagePath string, region models.BBox, opts Options) ([]models.HOCRLine, error) {
+	if err := checkBox(region, "region"); err != nil {
+		return nil, err
+	}
+
+	ws, err := s.newWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+	crop := ws.path("region.png")
+
+	cmd := exec.Command("magick", imagePath,
+		"-crop", fmt.Sprintf("%dx%d+%d+%d", region.X2-region.X1, region.Y2-region.Y1, region.X1, region.Y1),
+		"+repage",
+		crop)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to crop region: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	hocrXML, err := s.processImage(ws, crop, opts)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := ParseHOCRLines(hocrXML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse region hOCR: %w", err)
+	}
+
+	offset := func(box models.BBox) models.BBox {
+		return models.BBox{X1: box.X1 + region.X1, Y1: box.Y1 + region.Y1, X2: box.X2 + region.X1, Y2: box.Y2 + region.Y1}
+	}
+	for i := range lines {
+		lines[i].BBox = offset(lines[i].BBox)
+		for j := range lines[i].Words {
+			lines[i].Words[j].BBox = offset(lines[i].Words[j].BBox)
+		}
+	}
+
+	opts.logger().Info("Region processed", "image", imagePath, "region", region, "engine", opts.Engine, "lines", len(lines))
+	return lines, nil
+}
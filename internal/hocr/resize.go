@@ -0,0 +1,150 @@
+package hocr
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+const (
+	// Word crops are stacked under lines of markup about 1000px wide; this keeps one
+	// oversized word from widening every row of the stitched image past 2000px
+	maxWordCropWidth  = 1980
+	maxWordCropHeight = 400
+
+	defaultMaxLLMImageBytes     = 20 * 1024 * 1024
+	defaultMaxLLMImageDimension = 16000
+)
+
+var jpegFallbackQualities = []int{85, 70, 55}
+
+// fitImageForLLM returns a path to a version of imagePath within the configured
+// OPENAI_MAX_IMAGE_BYTES and OPENAI_MAX_IMAGE_DIMENSION limits. The returned path
+// equals imagePath when no resizing was needed; otherwise it is in ws.
+func (s *Service) fitImageForLLM(ws *workspace, imagePath string) (string, error) {
+	maxBytes := int64(utils.GetEnvInt("OPENAI_MAX_IMAGE_BYTES", defaultMaxLLMImageBytes))
+	maxDimension := utils.GetEnvInt("OPENAI_MAX_IMAGE_DIMENSION", defaultMaxLLMImageDimension)
+
+	info, err := os.Stat(imagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat image: %w", err)
+	}
+
+	width, height, err := s.getImageDimensions(imagePath)
+	if err != nil {
+		return "", err
+	}
+
+	if info.Size() <= maxBytes && width <= maxDimension && height <= maxDimension {
+		return imagePath, nil
+	}
+
+	slog.Info("Image exceeds LLM limits, downscaling", "path", imagePath, "bytes", info.Size(), "width", width, "height", height)
+
+	base := strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath))
+	resizedPath := ws.path(base + "_resized.png")
+	geometry := fmt.Sprintf("%dx%d>", maxDimension, maxDimension)
+	if err := exec.Command("magick", imagePath, "-resize", geometry, resizedPath).Run(); err != nil {
+		return "", fmt.Errorf("failed to resize image: %w", err)
+	}
+
+	if info, err := os.Stat(resizedPath); err == nil && info.Size() <= maxBytes {
+		return resizedPath, nil
+	}
+	ws.remove(resizedPath)
+
+	// Still too large as PNG, fall back to progressively stronger JPEG compression
+	compressedPath := ws.path(base + "_resized.jpg")
+	for _, quality := range jpegFallbackQualities {
+		cmd := exec.Command("magick", imagePath, "-resize", geometry, "-quality", fmt.Sprintf("%d", quality), compressedPath)
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("failed to compress image: %w", err)
+		}
+		if info, err := os.Stat(compressedPath); err == nil && info.Size() <= maxBytes {
+			slog.Info("Compressed image for LLM", "path", compressedPath, "quality", quality, "bytes", info.Size())
+			return compressedPath, nil
+		}
+	}
+	ws.remove(compressedPath)
+
+	return "", fmt.Errorf("image could not be reduced below %d bytes", maxBytes)
+}
+
+var hocrElementTitle = regexp.MustCompile(`(id=['"])((?:line|word)_(\d+))(['"]\s+title=['"])bbox\s+\d+\s+\d+\s+\d+\s+\d+`)
+
+// restoreDetectedCoordinates rewrites the bbox of every line_N/word_N element with
+// the coordinates from detection. The model reads coordinates off the stitched image,
+// so once it has been downscaled the transcribed numbers can't be trusted.
+func (s *Service) restoreDetectedCoordinates(hocrXML string, response models.OCRResponse) string {
+	boxes := detectedBoxes(response)
+	if len(boxes) == 0 {
+		return hocrXML
+	}
+	angles := detectedTextAngles(response)
+
+	return hocrElementTitle.ReplaceAllStringFunc(hocrXML, func(match string) string {
+		parts := hocrElementTitle.FindStringSubmatch(match)
+		var index int
+		if _, err := fmt.Sscanf(parts[3], "%d", &index); err != nil || index < 1 || index > len(boxes) {
+			return match
+		}
+		box := boxes[index-1]
+		title := fmt.Sprintf("%s%s%sbbox %d %d %d %d", parts[1], parts[2], parts[4], box.X1, box.Y1, box.X2, box.Y2)
+		if strings.HasPrefix(parts[2], "line_") && angles[index-1] != 0 {
+			title += fmt.Sprintf("; textangle %d", angles[index-1])
+		}
+		return title
+	})
+}
+
+// detectedBoxes lists word boxes in the same order createStitchedImageWithHOCRMarkup numbers them
+func detectedBoxes(response models.OCRResponse) []models.BBox {
+	var boxes []models.BBox
+	eachDetectedWord(response, func(_ models.Paragraph, vertices []models.Vertex) {
+		boxes = append(boxes, models.BBox{
+			X1: vertices[0].X,
+			Y1: vertices[0].Y,
+			X2: vertices[2].X,
+			Y2: vertices[2].Y,
+		})
+	})
+	return boxes
+}
+
+// detectedTextAngles lists the textangle of each detected word's paragraph, in
+// the same order as detectedBoxes
+func detectedTextAngles(response models.OCRResponse) []int {
+	var angles []int
+	eachDetectedWord(response, func(paragraph models.Paragraph, _ []models.Vertex) {
+		angles = append(angles, paragraph.TextAngle)
+	})
+	return angles
+}
+
+// eachDetectedWord calls fn with every detected word that has a box, and its
+// paragraph, in the order createStitchedImageWithHOCRMarkup numbers them
+func eachDetectedWord(response models.OCRResponse, fn func(paragraph models.Paragraph, vertices []models.Vertex)) {
+	if len(response.Responses) == 0 || response.Responses[0].FullTextAnnotation == nil {
+		return
+	}
+
+	for _, page := range response.Responses[0].FullTextAnnotation.Pages {
+		for _, block := range page.Blocks {
+			for _, paragraph := range block.Paragraphs {
+				for _, word := range paragraph.Words {
+					if len(word.BoundingBox.Vertices) < 4 {
+						continue
+					}
+					fn(paragraph, word.BoundingBox.Vertices)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package hocr
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/retry"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// retryableError marks a failed API call worth trying again, carrying any
+// Retry-After delay the server asked for
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// retryPolicyFromEnv reads OPENAI_MAX_RETRIES, OPENAI_RETRY_BASE_DELAY_MS and
+// OPENAI_RETRY_MAX_DELAY_MS
+func retryPolicyFromEnv() retry.Backoff {
+	return retry.Backoff{
+		MaxRetries: utils.GetEnvInt("OPENAI_MAX_RETRIES", 3),
+		BaseDelay:  time.Duration(utils.GetEnvInt("OPENAI_RETRY_BASE_DELAY_MS", 1000)) * time.Millisecond,
+		MaxDelay:   time.Duration(utils.GetEnvInt("OPENAI_RETRY_MAX_DELAY_MS", 60000)) * time.Millisecond,
+	}
+}
+
+// isRetryableStatus reports whether a response status is worth retrying
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
@@ -0,0 +1,148 @@
+package hocr
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// languageScripts are the scripts of the languages not written in Latin
+// letters, for telling apart the languages of a mixed page
+var languageScripts = map[string][]*unicode.RangeTable{
+	"ara":     {unicode.Arabic},
+	"bel":     {unicode.Cyrillic},
+	"bul":     {unicode.Cyrillic},
+	"chi_sim": {unicode.Han},
+	"chi_tra": {unicode.Han},
+	"ell":     {unicode.Greek},
+	"fas":     {unicode.Arabic},
+	"grc":     {unicode.Greek},
+	"heb":     {unicode.Hebrew},
+	"hin":     {unicode.Devanagari},
+	"jpn":     {unicode.Han, unicode.Hiragana, unicode.Katakana},
+	"kor":     {unicode.Hangul, unicode.Han},
+	"mkd":     {unicode.Cyrillic},
+	"rus":     {unicode.Cyrillic},
+	"srp":     {unicode.Cyrillic},
+	"syr":     {unicode.Syriac},
+	"tha":     {unicode.Thai},
+	"ukr":     {unicode.Cyrillic},
+	"urd":     {unicode.Arabic},
+	"yid":     {unicode.Hebrew},
+}
+
+// wordLanguageSpan matches a word span of LLM output and its text
+var wordLanguageSpan = regexp.MustCompile(`(<span\s[^>]*class=['"]ocrx_word['"][^>]*)>([^<]*)</span>`)
+
+// scriptsOf are the scripts a language is written in; those not listed are
+// written in Latin letters
+func scriptsOf(code string) []*unicode.RangeTable {
+	if scripts, ok := languageScripts[strings.TrimSuffix(code, "_vert")]; ok {
+		return scripts
+	}
+	return []*unicode.RangeTable{unicode.Latin}
+}
+
+// writtenIn reports whether text has letters and all of them are in scripts.
+// Letters common to scripts, such as the Japanese long vowel mark, count for
+// any of them.
+func writtenIn(text string, scripts []*unicode.RangeTable) bool {
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) || unicode.In(r, unicode.Common, unicode.Inherited) {
+			continue
+		}
+		if !unicode.In(r, scripts...) {
+			return false
+		}
+		letters++
+	}
+	return letters > 0
+}
+
+// scriptLanguage is the BCP 47 tag of the one of the languages, joined by "+"
+// as ParseLanguage returns them, whose script text is written in. It's "" when
+// text has no letters, or is in the script of several, as German and Latin
+// share one.
+func scriptLanguage(text, language string) string {
+	found := ""
+	for _, code := range strings.Split(language, "+") {
+		if code == "" || !writtenIn(text, scriptsOf(code)) {
+			continue
+		}
+		tag := htmlLanguage(code)
+		if found != "" && found != tag {
+			return ""
+		}
+		found = tag
+	}
+	return found
+}
+
+// lineLanguage is the language every word of a line with letters is in, or ""
+// when they differ or some aren't known
+func lineLanguage(line models.HOCRLine) string {
+	found := ""
+	for _, word := range line.Words {
+		if !strings.ContainsFunc(word.Text, unicode.IsLetter) {
+			continue
+		}
+		if word.Language == "" || (found != "" && word.Language != found) {
+			return ""
+		}
+		found = word.Language
+	}
+	return found
+}
+
+// MarkLanguages returns a copy of lines with the languages of a page in
+// several, the language given joined by "+", told apart by their scripts: each
+// word not already marked gets the language whose script it's written in, and
+// each line whose words all share one gets it too. Pages in one language, and
+// words whose script several of the languages share, are left as they are.
+func MarkLanguages(lines []models.HOCRLine, language string) []models.HOCRLine {
+	marked := make([]models.HOCRLine, len(lines))
+	for i, line := range lines {
+		line.Words = append([]models.HOCRWord{}, line.Words...)
+		marked[i] = line
+	}
+	if !strings.Contains(language, "+") {
+		return marked
+	}
+
+	for i := range marked {
+		line := &marked[i]
+		for j := range line.Words {
+			if line.Words[j].Language == "" {
+				line.Words[j].Language = scriptLanguage(line.Words[j].Text, language)
+			}
+		}
+		if line.Language == "" {
+			line.Language = lineLanguage(*line)
+		}
+	}
+	return marked
+}
+
+// markWordLanguages adds a lang attribute to the word spans of LLM output on a
+// page in several languages, where the model didn't mark one and the word's
+// script tells which it's in
+func markWordLanguages(markup, language string) string {
+	if !strings.Contains(language, "+") {
+		return markup
+	}
+	return wordLanguageSpan.ReplaceAllStringFunc(markup, func(span string) string {
+		parts := wordLanguageSpan.FindStringSubmatch(span)
+		if strings.Contains(parts[1], "lang=") {
+			return span
+		}
+		tag := scriptLanguage(html.UnescapeString(parts[2]), language)
+		if tag == "" {
+			return span
+		}
+		return parts[1] + " lang='" + tag + "'>" + parts[2] + "</span>"
+	})
+}
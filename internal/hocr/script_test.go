@@ -0,0 +1,123 @@
+package hocr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestScriptLanguage(t *testing.T) {
+	for _, test := range []struct {
+		text, language, want string
+	}{
+		{"λόγος", "eng+ell", "el"},
+		{"word,", "eng+ell", "en"},
+		{"Москва", "deu+rus", "ru"},
+		{"שלום", "eng+heb", "he"},
+		{"カメラー", "jpn+eng", "ja"},
+		// German and Latin share a script, as Chinese and Japanese share Han
+		{"Deus", "deu+lat", ""},
+		{"東京", "jpn+chi_sim", ""},
+		// No letters, or letters of more than one language
+		{"1848", "eng+ell", ""},
+		{"αbc", "eng+ell", ""},
+		{"λόγος", "eng+deu", ""},
+	} {
+		if got := scriptLanguage(test.text, test.language); got != test.want {
+			t.Errorf("scriptLanguage(%q, %q) = %q, want %q", test.text, test.language, got, test.want)
+		}
+	}
+}
+
+func TestMarkLanguages(t *testing.T) {
+	lines := []models.HOCRLine{
+		{ID: "line_1", Words: []models.HOCRWord{{Text: "In"}, {Text: "principio"}, {Text: "ἦν"}}},
+		{ID: "line_2", Words: []models.HOCRWord{{Text: "ὁ"}, {Text: "λόγος,"}, {Text: "—"}}},
+		{ID: "line_3", Words: []models.HOCRWord{{Text: "Verbum", Language: "la"}}},
+	}
+	marked := MarkLanguages(lines, "lat+grc")
+	got := []string{marked[0].Language, marked[0].Words[0].Language, marked[0].Words[2].Language, marked[1].Language, marked[2].Language}
+	if want := []string{"", "la", "grc", "grc", "la"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("marked languages %q, want %q", got, want)
+	}
+	if lines[0].Words[0].Language != "" {
+		t.Error("lines were marked in place")
+	}
+	if marked := MarkLanguages(lines, "grc"); marked[1].Language != "" || marked[1].Words[1].Language != "" {
+		t.Error("a page in one language was marked")
+	}
+}
+
+func TestLanguageRoundTrip(t *testing.T) {
+	lines := []models.HOCRLine{
+		{ID: "line_1", BBox: models.BBox{X2: 90, Y2: 10}, Words: []models.HOCRWord{
+			{ID: "word_1", Text: "Homo", BBox: models.BBox{X2: 40, Y2: 10}},
+			{ID: "word_2", Text: "Mensch", BBox: models.BBox{X1: 50, X2: 90, Y2: 10}, Language: "de"},
+		}},
+		{ID: "line_2", BBox: models.BBox{Y1: 20, X2: 90, Y2: 30}, Words: []models.HOCRWord{
+			{ID: "word_3", Text: "Ἄνθρωπος", BBox: models.BBox{Y1: 20, X2: 90, Y2: 30}},
+		}},
+	}
+	converter := NewConverter()
+	converter.Language = "lat+deu+grc"
+	hocrXML := converter.ConvertHOCRLinesToXML(lines, 100, 40)
+	for _, want := range []string{`lang="la"`, `id='word_2' title='bbox 50 0 90 10; x_wconf 0' lang='de'`, `id='line_2' title='bbox 0 20 90 30' lang='grc'`} {
+		if !strings.Contains(hocrXML, want) {
+			t.Errorf("hOCR lacks %s:\n%s", want, hocrXML)
+		}
+	}
+	if strings.Contains(hocrXML, `id='word_3' title='bbox 0 20 90 30; x_wconf 0' lang`) {
+		t.Error("word marked with its line's language")
+	}
+
+	parsed, err := ParseHOCRLines(hocrXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed[0].Language != "" || parsed[0].Words[1].Language != "de" || parsed[1].Language != "grc" || parsed[1].Words[0].Language != "" {
+		t.Errorf("parsed languages %+v", parsed)
+	}
+
+	// Tesseract marks paragraphs in its own codes
+	words, err := ParseHOCRWords(`<html lang="en"><body><p class='ocr_par' lang='deu'><span class='ocr_line' id='line_1'><span class='ocrx_word' id='word_1'>Haus</span><span class='ocrx_word' id='word_2' lang='lat'>domus</span></span></p></body></html>`)
+	if err != nil || len(words) != 2 || words[0].Language != "de" || words[1].Language != "la" {
+		t.Errorf("parsed Tesseract languages %+v, %v", words, err)
+	}
+}
+
+func TestMarkWordLanguages(t *testing.T) {
+	markup := `<span class='ocrx_line' id='line_1' title='bbox 0 0 9 9'><span class='ocrx_word' id='word_1' title='bbox 0 0 9 9'>Athens</span></span>
+<span class='ocrx_line' id='line_2' title='bbox 0 0 9 9'><span class='ocrx_word' id='word_2' title='bbox 0 0 9 9'>Ἀθῆναι</span></span>
+<span class='ocrx_line' id='line_3' title='bbox 0 0 9 9'><span class='ocrx_word' id='word_3' title='bbox 0 0 9 9' lang='la'>Athenae</span></span>`
+	got := markWordLanguages(markup, "eng+grc+lat")
+	want := strings.Replace(markup, `title='bbox 0 0 9 9'>Ἀθῆναι`, `title='bbox 0 0 9 9' lang='grc'>Ἀθῆναι`, 1)
+	if got != want {
+		t.Errorf("marked\n%s\nwant\n%s", got, want)
+	}
+	if markWordLanguages(markup, "grc") != markup {
+		t.Error("a page in one language was marked")
+	}
+}
+
+func TestDetectedLanguage(t *testing.T) {
+	box := models.BoundingPoly{Vertices: []models.Vertex{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}}
+	response := models.OCRResponse{Responses: []models.Response{{FullTextAnnotation: &models.FullTextAnnotation{Pages: []models.Page{{
+		Blocks: []models.Block{{
+			BlockType: "TEXT",
+			Property:  &models.Property{DetectedLanguages: []models.DetectedLanguage{{LanguageCode: "de", Confidence: 0.9}}},
+			Paragraphs: []models.Paragraph{{Words: []models.Word{{
+				BoundingBox: box,
+				Symbols:     []models.Symbol{{Text: "Haus"}},
+				Property:    &models.Property{DetectedLanguages: []models.DetectedLanguage{{LanguageCode: "la", Confidence: 0.8}}},
+			}}}},
+		}},
+	}}}}}}
+	lines, err := NewConverter().ConvertToHOCRLines(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines[0].Language != "de" || lines[0].Words[0].Language != "la" {
+		t.Errorf("detected languages %q and %q", lines[0].Language, lines[0].Words[0].Language)
+	}
+}
@@ -1,6 +1,8 @@
 package hocr
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
@@ -11,44 +13,282 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
-	"time"
+	"sync"
+
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
 
 	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
 )
 
-type Service struct{}
+type Service struct {
+	profile       string
+	tesseractPath string
+	dirs          Dirs
+	// llmSlots bounds in-flight LLM requests; callers queue for a slot
+	llmSlots chan struct{}
+	// detectionSlots bounds the CPU-bound stages, word detection and Tesseract,
+	// separately, so pages go on being detected while others wait on the LLM
+	detectionSlots chan struct{}
+	// workspaces are the runs in progress, whose derivatives Cleanup removes
+	workspaces sync.Map
+}
+
+// Dirs are where the service writes files
+type Dirs struct {
+	// Temp holds a directory of scratch images for each page being processed
+	Temp string
+	// LLMCache holds transcriptions for reuse; empty turns the cache off
+	LLMCache string
+}
+
+// tempDir is Dirs.Temp, or the system temp directory when unset
+func (s *Service) tempDir() string {
+	if s.dirs.Temp == "" {
+		return os.TempDir()
+	}
+	return s.dirs.Temp
+}
+
+// DirsFromEnv reads TEMP_DIR (default the system temp directory) and the LLM
+// cache under CACHE_DIR (default cache), for commands run outside the server
+func DirsFromEnv() Dirs {
+	temp := os.Getenv("TEMP_DIR")
+	if temp == "" {
+		temp = os.TempDir()
+	}
+	cache := os.Getenv("CACHE_DIR")
+	if cache == "" {
+		cache = "cache"
+	}
+	return Dirs{Temp: temp, LLMCache: LLMCacheDir(cache)}
+}
+
+// Options carries per-session pipeline settings
+type Options struct {
+	// Engine defaults to the deployment's DefaultEngine when empty
+	Engine string
+	// Model overrides OPENAI_MODEL for the LLM engine
+	Model string
+	// Prompt replaces the LLM's transcription instructions; the language,
+	// document type and character hints are still added to it
+	Prompt string
+	// Temperature is sent to the LLM when set, or left to the API's default
+	Temperature  float64
+	Binarization models.BinarizationConfig
+	// Language is what the text is in, as ParseLanguage returns it. Tesseract
+	// loads those language packs and the LLM is told the language.
+	Language string
+	// DocumentType is how the page was printed, as ParseDocumentType returns
+	// it. It picks the preprocessing, Tesseract's models and what the LLM is
+	// told. Pages in German Fraktur are taken for Fraktur without it.
+	DocumentType string
+	// Characters limits what characters Tesseract reads and the LLM writes.
+	// Words the LLM won't keep within them are flagged for review.
+	Characters Charset
+	// Archive, when set, receives the raw output of each engine stage
+	Archive func(name string, data []byte)
+	// OnRetry, when set, is told about each retried API call
+	OnRetry func(retry int, err error)
+	// Logger, when set, takes the run's log records in place of the default
+	// logger, so they carry the attributes of the job they belong to
+	Logger *slog.Logger
+	// Refresh asks the LLM again instead of reusing a cached transcription,
+	// replacing what was cached
+	Refresh bool
+
+	// artifactSuffix distinguishes archived outputs of each transcription chunk
+	artifactSuffix string
+}
+
+func (o Options) logger() *slog.Logger {
+	if o.Logger == nil {
+		return slog.Default()
+	}
+	return o.Logger
+}
+
+// documentType is the document type asked for, or Fraktur for a page whose
+// first language is German Fraktur
+func (o Options) documentType() string {
+	if o.DocumentType == DocumentModern && strings.HasPrefix(o.Language+"+", "frk+") {
+		return DocumentFraktur
+	}
+	return o.DocumentType
+}
+
+func (o Options) archive(name string, data []byte) {
+	if o.Archive != nil {
+		ext := filepath.Ext(name)
+		o.Archive(strings.TrimSuffix(name, ext)+o.artifactSuffix+ext, data)
+	}
+}
 
-func NewService() *Service {
-	slog.Info("Initializing hOCR service (Custom word detection + ChatGPT transcription)")
-	return &Service{}
+// wordRange is a half-open range of word indexes in detection order
+type wordRange struct {
+	start, end int
 }
 
-func (s *Service) ProcessImageToHOCR(imagePath string) (string, error) {
-	ocrResponse, err := s.detectWordBoundariesCustom(imagePath)
+// chunkRanges splits n words into evenly sized chunks of at most size words,
+// so the last chunk isn't left with a handful of stragglers
+func chunkRanges(n, size int) []wordRange {
+	if size <= 0 || n <= size {
+		return []wordRange{{0, n}}
+	}
+
+	count := (n + size - 1) / size
+	ranges := make([]wordRange, count)
+	for i := range ranges {
+		ranges[i] = wordRange{start: i * n / count, end: (i + 1) * n / count}
+	}
+	return ranges
+}
+
+func (o Options) retried(retry int, err error) {
+	if o.OnRetry != nil {
+		o.OnRetry(retry, err)
+	}
+}
+
+func NewService(dirs Dirs) *Service {
+	s := &Service{
+		profile:  detectProfile(),
+		dirs:     dirs,
+		llmSlots: make(chan struct{}, max(1, utils.GetEnvInt("OPENAI_MAX_CONCURRENCY", 4))),
+		// Detection scales with cores, not with what the LLM provider allows
+		detectionSlots: make(chan struct{}, max(1, utils.GetEnvInt("DETECTION_WORKERS", runtime.NumCPU()))),
+	}
+	if path, err := exec.LookPath("tesseract"); err == nil {
+		s.tesseractPath = path
+	}
+
+	slog.Info("Initializing hOCR service", "profile", s.profile, "default_engine", s.DefaultEngine(), "tesseract", s.tesseractPath != "")
+	return s
+}
+
+// acquireDetectionSlot waits until fewer than DETECTION_WORKERS (default the
+// number of CPUs) pages are being detected or read by Tesseract
+func (s *Service) acquireDetectionSlot() func() {
+	select {
+	case s.detectionSlots <- struct{}{}:
+	default:
+		slog.Info("Waiting for a detection slot", "limit", cap(s.detectionSlots))
+		s.detectionSlots <- struct{}{}
+	}
+	return func() { <-s.detectionSlots }
+}
+
+func (s *Service) ProcessImageToHOCR(imagePath string, opts Options) (string, error) {
+	ws, err := s.newWorkspace()
 	if err != nil {
-		return "", fmt.Errorf("failed to detect word boundaries with both methods: %w", err)
+		return "", err
 	}
+	defer ws.Close()
+	return s.processImage(ws, imagePath, opts)
+}
 
-	stitchedImagePath, err := s.createStitchedImageWithHOCRMarkup(imagePath, ocrResponse)
+// processImage runs the engine, keeping its derivatives in ws
+func (s *Service) processImage(ws *workspace, imagePath string, opts Options) (string, error) {
+	engine := opts.Engine
+	if engine == "" {
+		engine = s.DefaultEngine()
+	}
+	if err := s.ValidateEngine(engine); err != nil {
+		return "", err
+	}
+
+	switch engine {
+	case EngineTesseract:
+		return s.processWithTesseract(ws, imagePath, opts)
+	case EngineDetect:
+		ocrResponse, err := s.detectWordBoundariesCustom(ws, imagePath, opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to detect word boundaries: %w", err)
+		}
+		return s.convertToBasicHOCR(ocrResponse, opts.Language), nil
+	default:
+		return s.processWithLLM(ws, imagePath, opts)
+	}
+}
+
+// processWithLLM detects word boxes, stitches them into an hOCR-annotated image
+// and has the LLM transcribe it
+func (s *Service) processWithLLM(ws *workspace, imagePath string, opts Options) (string, error) {
+	ocrResponse, err := s.detectWordBoundariesCustom(ws, imagePath, opts)
 	if err != nil {
-		slog.Warn("Failed to create stitched image, using basic hOCR output only", "error", err)
-		return s.convertToBasicHOCR(ocrResponse), nil
+		return "", fmt.Errorf("failed to detect word boundaries with both methods: %w", err)
 	}
-	defer os.Remove(stitchedImagePath)
 
-	slog.Info("Created stitched image with hOCR markup", "path", stitchedImagePath)
+	// Long pages are split into several stitched images; the model drops words
+	// from a single enormous one. Word numbering stays global across chunks.
+	chunks := chunkRanges(len(detectedBoxes(ocrResponse)), utils.GetEnvInt("OPENAI_WORDS_PER_CHUNK", 150))
 
-	hocrResult, err := s.transcribeWithChatGPT(stitchedImagePath)
+	pageImage, err := s.loadPageImage(ws, imagePath)
 	if err != nil {
-		slog.Warn("ChatGPT transcription failed", "err", err)
-		return "", err
+		opts.logger().Warn("Failed to load page image, using basic hOCR output only", "error", err)
+		return s.convertToBasicHOCR(ocrResponse, opts.Language), nil
 	}
 
-	slog.Info("ChatGPT transcription completed", "result_length", hocrResult)
+	stitchedPaths := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		stitchedImagePath, err := s.createStitchedImageWithHOCRMarkup(ws, imagePath, pageImage, ocrResponse, chunk)
+		if err != nil {
+			opts.logger().Warn("Failed to create stitched image, using basic hOCR output only", "error", err)
+			return s.convertToBasicHOCR(ocrResponse, opts.Language), nil
+		}
+		stitchedPaths = append(stitchedPaths, stitchedImagePath)
+	}
+
+	opts.logger().Info("Created stitched images with hOCR markup", "chunks", len(chunks))
+
+	// Chunks are transcribed concurrently, bounded by the LLM request limit
+	results := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	for i, stitchedImagePath := range stitchedPaths {
+		chunk := chunks[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chunkOpts := opts
+			if len(chunks) > 1 {
+				chunkOpts.artifactSuffix = fmt.Sprintf("_chunk_%d", i+1)
+			}
+			results[i], errs[i] = s.transcribeWithChatGPT(ws, stitchedImagePath, chunk, chunkOpts)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		// The words of a chunk the model kept getting wrong fall back to
+		// detection alone; the other chunks keep their transcriptions
+		var invalid *invalidTranscriptionError
+		if errors.As(err, &invalid) {
+			opts.logger().Warn("ChatGPT transcription stayed invalid, using basic hOCR output for the chunk", "chunk", i+1, "chunks", len(chunks), "err", err)
+			results[i] = basicHOCRLines(ocrResponse, chunks[i])
+			continue
+		}
+		if err != nil {
+			opts.logger().Warn("ChatGPT transcription failed", "chunk", i+1, "chunks", len(chunks), "err", err)
+			return "", err
+		}
+	}
+
+	hocrResult := strings.Join(results, "\n")
+	opts.logger().Info("ChatGPT transcription completed", "result_length", len(hocrResult), "chunks", len(chunks))
+
+	// Models mix Unicode forms, quotes and stray zero-width characters, and
+	// cached transcriptions follow the policy in force now
+	hocrResult = textPolicyFromEnv().normalizeMarkup(hocrResult)
+	hocrResult = s.restoreDetectedCoordinates(hocrResult, ocrResponse)
+	// The words the model left unmarked on a mixed page are told apart by script
+	hocrResult = markWordLanguages(hocrResult, opts.Language)
 
-	return s.wrapInHOCRDocument(hocrResult), nil
+	return s.wrapInHOCRDocument(hocrResult, opts.Language), nil
 }
 
 func (s *Service) getImageDimensions(imagePath string) (int, int, error) {
@@ -69,7 +309,10 @@ func (s *Service) getImageDimensions(imagePath string) (int, int, error) {
 }
 
 // detectWordBoundariesCustom uses our own image processing algorithm to find word boundaries
-func (s *Service) detectWordBoundariesCustom(imagePath string) (models.OCRResponse, error) {
+func (s *Service) detectWordBoundariesCustom(ws *workspace, imagePath string, opts Options) (models.OCRResponse, error) {
+	release := s.acquireDetectionSlot()
+	defer release()
+
 	// Get image dimensions first
 	width, height, err := s.getImageDimensions(imagePath)
 	if err != nil {
@@ -77,19 +320,29 @@ func (s *Service) detectWordBoundariesCustom(imagePath string) (models.OCRRespon
 	}
 
 	// Step 1: Detect individual words using image processing
-	words, err := s.detectWords(imagePath, width, height)
+	words, vertical, err := s.detectWords(ws, imagePath, width, height, opts)
 	if err != nil {
 		return models.OCRResponse{}, fmt.Errorf("failed to detect words: %w", err)
 	}
 
-	slog.Info("Custom word detection completed", "word_count", len(words), "image_size", fmt.Sprintf("%dx%d", width, height))
+	opts.logger().Info("Custom word detection completed", "word_count", len(words), "vertical", vertical, "image_size", fmt.Sprintf("%dx%d", width, height))
 
-	// Step 2: Group words into lines based on coordinates
-	lines := s.groupWordsIntoLines(words)
-	slog.Info("Grouped words into lines", "line_count", len(lines))
+	// Step 2: Group words into lines based on coordinates, or into columns
+	// when the text is set vertically
+	var lines []LineBox
+	if vertical {
+		lines = s.groupWordsIntoColumns(words)
+	} else {
+		lines = s.groupWordsIntoLines(words)
+	}
+	opts.logger().Info("Grouped words into lines", "line_count", len(lines))
 
 	// Step 3: Convert to OCR response format
-	return s.convertWordsAndLinesToOCRResponse(lines, width, height), nil
+	response := s.convertWordsAndLinesToOCRResponse(lines, width, height)
+	if detection, err := json.MarshalIndent(response, "", "  "); err == nil {
+		opts.archive("detection.json", detection)
+	}
+	return response, nil
 }
 
 // WordBox represents a detected word with its bounding box
@@ -101,17 +354,57 @@ type WordBox struct {
 // LineBox represents a line of text containing multiple words
 type LineBox struct {
 	Words               []WordBox
-	X, Y, Width, Height int // Bounding box of the entire line
+	X, Y, Width, Height int  // Bounding box of the entire line
+	Vertical            bool // Set top to bottom rather than across
+}
+
+// detectWords finds individual word regions using image processing, and
+// whether the text is set vertically, as the language says or the glyphs
+// being stacked in columns shows
+func (s *Service) detectWords(ws *workspace, imagePath string, imgWidth, imgHeight int, opts Options) ([]WordBox, bool, error) {
+	img, err := s.binarize(ws, imagePath, opts.Binarization, opts.documentType())
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Find connected components (potential words)
+	components := s.findWordComponents(img)
+
+	// Glyphs stacked in a column are merged down it rather than across
+	if verticalLanguage(opts.Language) || verticalLayout(components) {
+		words := s.refineComponentsToWords(transposeBoxes(components), imgHeight, imgWidth)
+		return transposeBoxes(words), true, nil
+	}
+
+	// Filter and refine components to get word boxes
+	wordBoxes := s.refineComponentsToWords(components, imgWidth, imgHeight)
+
+	return wordBoxes, false, nil
 }
 
-// detectWords finds individual word regions using image processing
-func (s *Service) detectWords(imagePath string, imgWidth, imgHeight int) ([]WordBox, error) {
+// BinarizeImage runs the word detection preprocessing for a document type and
+// returns the thresholded image
+func (s *Service) BinarizeImage(imagePath string, binarization models.BinarizationConfig, documentType string) (image.Image, error) {
+	ws, err := s.newWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+	return s.binarize(ws, imagePath, binarization, documentType)
+}
+
+func (s *Service) binarize(ws *workspace, imagePath string, binarization models.BinarizationConfig, documentType string) (image.Image, error) {
+	binarization, err := NormalizeBinarization(binarization)
+	if err != nil {
+		return nil, err
+	}
+
 	// Preprocess the image
-	processedPath, err := s.preprocessImageForWordDetection(imagePath)
+	processedPath, err := s.preprocessImageForWordDetection(ws, imagePath, binarization, profileOf(documentType))
 	if err != nil {
 		return nil, fmt.Errorf("failed to preprocess image: %w", err)
 	}
-	defer os.Remove(processedPath)
+	defer ws.remove(processedPath)
 
 	// Load processed image
 	file, err := os.Open(processedPath)
@@ -125,30 +418,37 @@ func (s *Service) detectWords(imagePath string, imgWidth, imgHeight int) ([]Word
 		return nil, fmt.Errorf("failed to decode processed image: %w", err)
 	}
 
-	// Find connected components (potential words)
-	components := s.findWordComponents(img)
-
-	// Filter and refine components to get word boxes
-	wordBoxes := s.refineComponentsToWords(components, imgWidth, imgHeight)
+	if isAdaptive(binarization) {
+		return binarize(img, binarization), nil
+	}
 
-	return wordBoxes, nil
+	return img, nil
 }
 
-// preprocessImageForWordDetection preprocesses the image for better word detection
-func (s *Service) preprocessImageForWordDetection(imagePath string) (string, error) {
-	tempDir := "/tmp"
+// preprocessImageForWordDetection preprocesses the image for better word detection,
+// with the contrast and closing of the document type's profile.
+// Adaptive methods skip the ImageMagick threshold and are applied in Go afterwards.
+func (s *Service) preprocessImageForWordDetection(ws *workspace, imagePath string, binarization models.BinarizationConfig, profile documentProfile) (string, error) {
 	baseName := strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath))
-	processedPath := filepath.Join(tempDir, fmt.Sprintf("processed_words_%s_%d.jpg", baseName, time.Now().Unix()))
 
 	// Preprocess: grayscale, enhance contrast, sharpen, threshold
-	cmd := exec.Command("magick", imagePath,
+	args := []string{imagePath,
 		"-colorspace", "Gray", // Convert to grayscale
-		"-contrast-stretch", "0.15x0.05%", // Enhance contrast
+		"-contrast-stretch", profile.contrast, // Enhance contrast
 		"-sharpen", "0x1", // Sharpen slightly
-		"-morphology", "close", "rectangle:2x1", // Close small gaps horizontally
-		"-threshold", "75%", // Apply threshold
-		processedPath)
+		"-morphology", "close", profile.closing, // Close small gaps in strokes
+	}
+
+	ext := "png"
+	if !isAdaptive(binarization) {
+		args = append(args, "-threshold", fmt.Sprintf("%g%%", binarization.Threshold)) // Apply threshold
+		ext = "jpg"
+	}
+
+	processedPath := ws.path(fmt.Sprintf("processed_words_%s.%s", baseName, ext))
+	args = append(args, processedPath)
 
+	cmd := exec.Command("magick", args...)
 	if err := cmd.Run(); err != nil {
 		return "", fmt.Errorf("imagemagick preprocessing failed: %w", err)
 	}
@@ -156,73 +456,184 @@ func (s *Service) preprocessImageForWordDetection(imagePath string) (string, err
 	return processedPath, nil
 }
 
-// findWordComponents finds connected components that could be words
+// findWordComponents finds connected components that could be words.
+//
+// Components are labeled with 8-connectivity in a single raster scan using union-find,
+// so there is no recursion depth to overflow on large high-DPI scans. Besides three
+// width-sized rows, it keeps a parent and a bounding box for every provisional label.
+// Labels are never reused, so that grows with the number of text runs started on the
+// page (at worst with its area), not with the final number of components.
 func (s *Service) findWordComponents(img image.Image) []WordBox {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
-
-	visited := make([][]bool, height)
-	for i := range visited {
-		visited[i] = make([]bool, width)
+	if width == 0 || height == 0 {
+		return nil
 	}
 
-	var components []WordBox
+	labels := newComponentLabels()
+	prevRow := make([]int32, width)
+	currRow := make([]int32, width)
+	textRow := make([]bool, width)
 
-	// Find all connected components using flood fill
 	for y := 0; y < height; y++ {
+		s.textPixelsInRow(img, y, textRow)
+
 		for x := 0; x < width; x++ {
-			if !visited[y][x] && s.isTextPixel(img.At(x, y)) {
-				minX, minY, maxX, maxY := x, y, x, y
-				s.floodFillComponent(img, visited, x, y, &minX, &minY, &maxX, &maxY)
-
-				// Filter by size to get potential words
-				w := maxX - minX + 1
-				h := maxY - minY + 1
-				if s.isValidWordSize(w, h, width, height) {
-					components = append(components, WordBox{
-						X:      minX,
-						Y:      minY,
-						Width:  w,
-						Height: h,
-						Text:   fmt.Sprintf("word_%d", len(components)+1),
-					})
+			if !textRow[x] {
+				currRow[x] = 0
+				continue
+			}
+
+			// Neighbors already visited in raster order: W, NW, N, NE
+			label := int32(0)
+			neighbors := [4]int32{}
+			if x > 0 {
+				neighbors[0] = currRow[x-1]
+			}
+			if y > 0 {
+				if x > 0 {
+					neighbors[1] = prevRow[x-1]
+				}
+				neighbors[2] = prevRow[x]
+				if x < width-1 {
+					neighbors[3] = prevRow[x+1]
 				}
 			}
+
+			for _, n := range neighbors {
+				if n == 0 {
+					continue
+				}
+				if label == 0 {
+					label = n
+				} else {
+					labels.union(label, n)
+				}
+			}
+
+			if label == 0 {
+				label = labels.add(x, y)
+			} else {
+				labels.extend(label, x, y)
+			}
+			currRow[x] = label
+		}
+
+		prevRow, currRow = currRow, prevRow
+	}
+
+	var components []WordBox
+	for _, box := range labels.boxes() {
+		// Filter by size to get potential words
+		w := box.maxX - box.minX + 1
+		h := box.maxY - box.minY + 1
+		if s.isValidWordSize(w, h, width, height) {
+			components = append(components, WordBox{
+				X:      box.minX,
+				Y:      box.minY,
+				Width:  w,
+				Height: h,
+				Text:   fmt.Sprintf("word_%d", len(components)+1),
+			})
 		}
 	}
 
 	return components
 }
 
-// floodFillComponent performs flood fill to find connected text pixels
-func (s *Service) floodFillComponent(img image.Image, visited [][]bool, x, y int, minX, minY, maxX, maxY *int) {
+// textPixelsInRow fills row with whether each pixel in image row y is text
+func (s *Service) textPixelsInRow(img image.Image, y int, row []bool) {
 	bounds := img.Bounds()
-	if x < 0 || x >= bounds.Dx() || y < 0 || y >= bounds.Dy() || visited[y][x] || !s.isTextPixel(img.At(x, y)) {
+
+	// Fast path for the grayscale images produced by preprocessing
+	if gray, ok := img.(*image.Gray); ok {
+		offset := gray.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+		for x := range row {
+			row[x] = gray.Pix[offset+x] < 128
+		}
 		return
 	}
 
-	visited[y][x] = true
+	for x := range row {
+		row[x] = s.isTextPixel(img.At(bounds.Min.X+x, bounds.Min.Y+y))
+	}
+}
+
+type componentBox struct {
+	minX, minY, maxX, maxY int
+}
 
-	// Update bounding box
-	if x < *minX {
-		*minX = x
+// componentLabels is a union-find over component labels tracking each set's bounding box.
+// Label 0 is reserved for background.
+type componentLabels struct {
+	parent []int32
+	bbox   []componentBox
+}
+
+func newComponentLabels() *componentLabels {
+	return &componentLabels{
+		parent: []int32{0},
+		bbox:   []componentBox{{}},
+	}
+}
+
+func (c *componentLabels) add(x, y int) int32 {
+	label := int32(len(c.parent))
+	c.parent = append(c.parent, label)
+	c.bbox = append(c.bbox, componentBox{minX: x, minY: y, maxX: x, maxY: y})
+	return label
+}
+
+func (c *componentLabels) find(label int32) int32 {
+	root := label
+	for c.parent[root] != root {
+		root = c.parent[root]
 	}
-	if x > *maxX {
-		*maxX = x
+	// Path compression
+	for c.parent[label] != root {
+		next := c.parent[label]
+		c.parent[label] = root
+		label = next
 	}
-	if y < *minY {
-		*minY = y
+	return root
+}
+
+// union merges two sets, keeping the smaller label as root so roots stay in raster order
+func (c *componentLabels) union(a, b int32) {
+	rootA, rootB := c.find(a), c.find(b)
+	if rootA == rootB {
+		return
 	}
-	if y > *maxY {
-		*maxY = y
+	if rootB < rootA {
+		rootA, rootB = rootB, rootA
 	}
 
-	// Check 8 neighbors
-	directions := [][]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
-	for _, dir := range directions {
-		s.floodFillComponent(img, visited, x+dir[0], y+dir[1], minX, minY, maxX, maxY)
+	c.parent[rootB] = rootA
+	boxA, boxB := &c.bbox[rootA], c.bbox[rootB]
+	boxA.minX = min(boxA.minX, boxB.minX)
+	boxA.minY = min(boxA.minY, boxB.minY)
+	boxA.maxX = max(boxA.maxX, boxB.maxX)
+	boxA.maxY = max(boxA.maxY, boxB.maxY)
+}
+
+func (c *componentLabels) extend(label int32, x, y int) {
+	box := &c.bbox[c.find(label)]
+	box.minX = min(box.minX, x)
+	box.minY = min(box.minY, y)
+	box.maxX = max(box.maxX, x)
+	box.maxY = max(box.maxY, y)
+}
+
+// boxes returns the bounding box of every component in the raster order of its first pixel
+func (c *componentLabels) boxes() []componentBox {
+	var result []componentBox
+	for label := int32(1); label < int32(len(c.parent)); label++ {
+		if c.find(label) == label {
+			result = append(result, c.bbox[label])
+		}
 	}
+	return result
 }
 
 // isTextPixel determines if a pixel is likely part of text (dark pixel)
@@ -489,6 +900,9 @@ func (s *Service) convertWordsAndLinesToOCRResponse(lines []LineBox, width, heig
 			},
 			Words: []models.Word{word}, // Single word per paragraph (line-level detection)
 		}
+		if line.Vertical {
+			paragraph.TextAngle = verticalTextAngle
+		}
 		paragraphs = append(paragraphs, paragraph)
 	}
 
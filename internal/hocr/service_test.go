@@ -0,0 +1,29 @@
+package hocr
+
+import "testing"
+
+func TestChunkRanges(t *testing.T) {
+	tests := []struct {
+		n, size int
+		want    []wordRange
+	}{
+		{n: 10, size: 150, want: []wordRange{{0, 10}}},
+		{n: 400, size: 0, want: []wordRange{{0, 400}}},
+		{n: 300, size: 150, want: []wordRange{{0, 150}, {150, 300}}},
+		{n: 301, size: 150, want: []wordRange{{0, 100}, {100, 200}, {200, 301}}},
+	}
+
+	for _, tt := range tests {
+		got := chunkRanges(tt.n, tt.size)
+		if len(got) != len(tt.want) {
+			t.Errorf("chunkRanges(%d, %d) = %v, want %v", tt.n, tt.size, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("chunkRanges(%d, %d) = %v, want %v", tt.n, tt.size, got, tt.want)
+				break
+			}
+		}
+	}
+}
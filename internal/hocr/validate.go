@@ -0,0 +1,65 @@
+package hocr
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// invalidTranscriptionError marks LLM output that stayed unusable after every re-prompt
+type invalidTranscriptionError struct {
+	err error
+}
+
+func (e *invalidTranscriptionError) Error() string { return "invalid transcription: " + e.err.Error() }
+func (e *invalidTranscriptionError) Unwrap() error { return e.err }
+
+// validateTranscription checks that cleaned LLM output is well-formed markup and
+// that every word span carries a word id from the chunk it was asked to transcribe.
+// Words may be missing, since illegible ones are omitted on purpose.
+func validateTranscription(content string, chunk wordRange) error {
+	decoder := xml.NewDecoder(strings.NewReader("<root>" + content + "</root>"))
+	decoder.Entity = xml.HTMLEntity
+
+	seen := make(map[int]bool)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("markup is not well-formed: %w", err)
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok || !isWordElement(XMLElement{Attrs: element.Attr}) {
+			continue
+		}
+
+		id := ""
+		for _, attr := range element.Attr {
+			if attr.Name.Local == "id" {
+				id = attr.Value
+			}
+		}
+		number, err := strconv.Atoi(strings.TrimPrefix(id, "word_"))
+		if !strings.HasPrefix(id, "word_") || err != nil {
+			return fmt.Errorf("word span has a missing or malformed id %q", id)
+		}
+		if number <= chunk.start || number > chunk.end {
+			return fmt.Errorf("word id %s was not in the image", id)
+		}
+		if seen[number] {
+			return fmt.Errorf("word id %s appears more than once", id)
+		}
+		seen[number] = true
+	}
+
+	if len(seen) == 0 && chunk.end > chunk.start {
+		return fmt.Errorf("no ocrx_word spans were returned")
+	}
+	return nil
+}
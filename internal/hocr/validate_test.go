@@ -0,0 +1,38 @@
+package hocr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTranscription(t *testing.T) {
+	chunk := wordRange{start: 10, end: 12}
+	line := func(id, text string) string {
+		return "<span class='ocrx_line' id='line_" + strings.TrimPrefix(id, "word_") + "' title='bbox 0 0 1 1'>" +
+			"<span class='ocrx_word' id='" + id + "' title='bbox 0 0 1 1'>" + text + "</span></span>"
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", line("word_11", "Dear") + "\n" + line("word_12", "Sir&nbsp;&amp;"), ""},
+		{"omitted word", line("word_12", "Sir"), ""},
+		{"unclosed span", "<span class='ocrx_word' id='word_11'>Dear", "not well-formed"},
+		{"missing id", "<span class='ocrx_word'>Dear</span>", "missing or malformed"},
+		{"id outside chunk", line("word_3", "Dear"), "not in the image"},
+		{"duplicate id", line("word_11", "Dear") + line("word_11", "Sir"), "more than once"},
+		{"no words", "I could not read this image.", "no ocrx_word"},
+	}
+
+	for _, tt := range tests {
+		err := validateTranscription(tt.content, chunk)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package hocr
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// verticalTextAngle is the textangle of vertically set lines, as Tesseract
+// writes it for them: the text runs down the page, a quarter turn from across
+const verticalTextAngle = 90
+
+// IsVertical reports whether a line is set vertically, read top to bottom
+func IsVertical(line models.HOCRLine) bool {
+	return line.TextAngle%180 != 0
+}
+
+// verticalLanguage reports whether the first of the languages is a vertical
+// pack, as in "jpn_vert"
+func verticalLanguage(language string) bool {
+	first, _, _ := strings.Cut(language, "+")
+	return strings.HasSuffix(first, "_vert")
+}
+
+// verticalLayout reports whether glyphs are mostly stacked in columns rather
+// than set side by side in rows: whether, for most of them, the nearest glyph
+// in line with them is above or below rather than beside them
+func verticalLayout(boxes []WordBox) bool {
+	votes := 0
+	for i, a := range boxes {
+		across, down := -1, -1
+		for j, b := range boxes {
+			if i == j {
+				continue
+			}
+			if spansOverlap(a.Y, a.Height, b.Y, b.Height) {
+				if gap := spanGap(a.X, a.Width, b.X, b.Width); across < 0 || gap < across {
+					across = gap
+				}
+			}
+			if spansOverlap(a.X, a.Width, b.X, b.Width) {
+				if gap := spanGap(a.Y, a.Height, b.Y, b.Height); down < 0 || gap < down {
+					down = gap
+				}
+			}
+		}
+		switch {
+		case down >= 0 && (across < 0 || down < across):
+			votes++
+		case across >= 0:
+			votes--
+		}
+	}
+	return votes > 0
+}
+
+// spansOverlap reports whether the spans starting at a and b overlap
+func spansOverlap(a, aLength, b, bLength int) bool {
+	return a < b+bLength && b < a+aLength
+}
+
+// spanGap is the distance between the spans starting at a and b, 0 when they
+// touch or overlap
+func spanGap(a, aLength, b, bLength int) int {
+	return max(max(b-(a+aLength), a-(b+bLength)), 0)
+}
+
+// transposeBoxes mirrors boxes across the diagonal, so columns become rows
+// that the row-wise merging and grouping can handle
+func transposeBoxes(boxes []WordBox) []WordBox {
+	transposed := make([]WordBox, len(boxes))
+	for i, box := range boxes {
+		transposed[i] = WordBox{X: box.Y, Y: box.X, Width: box.Height, Height: box.Width, Text: box.Text}
+	}
+	return transposed
+}
+
+// groupWordsIntoColumns groups words set vertically into lines running top to
+// bottom, the rightmost column first as Chinese, Japanese and Korean are read
+func (s *Service) groupWordsIntoColumns(words []WordBox) []LineBox {
+	lines := s.groupWordsIntoLines(transposeBoxes(words))
+	slices.Reverse(lines)
+	for i, line := range lines {
+		lines[i] = LineBox{
+			Words:    transposeBoxes(line.Words),
+			X:        line.Y,
+			Y:        line.X,
+			Width:    line.Height,
+			Height:   line.Width,
+			Vertical: true,
+		}
+	}
+	return lines
+}
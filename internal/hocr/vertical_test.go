@@ -0,0 +1,103 @@
+package hocr
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// glyphs lays out count square glyphs of side 20, 4 pixels apart, from x, y
+// going down a column or across a row
+func glyphs(x, y, count int, down bool) []WordBox {
+	var boxes []WordBox
+	for i := range count {
+		box := WordBox{X: x, Y: y, Width: 20, Height: 20}
+		if down {
+			box.Y += i * 24
+		} else {
+			box.X += i * 24
+		}
+		boxes = append(boxes, box)
+	}
+	return boxes
+}
+
+func TestVerticalLayout(t *testing.T) {
+	// Two columns 30 pixels apart, the way vertical Japanese is set
+	columns := append(glyphs(100, 0, 6, true), glyphs(50, 0, 6, true)...)
+	if !verticalLayout(columns) {
+		t.Error("columns not taken for vertical text")
+	}
+	// Two rows 30 pixels apart
+	rows := append(glyphs(0, 0, 6, false), glyphs(0, 50, 6, false)...)
+	if verticalLayout(rows) {
+		t.Error("rows taken for vertical text")
+	}
+}
+
+func TestGroupWordsIntoColumns(t *testing.T) {
+	words := append(glyphs(50, 0, 3, true), glyphs(100, 0, 3, true)...)
+	lines := (&Service{}).groupWordsIntoColumns(words)
+	if len(lines) != 2 {
+		t.Fatalf("grouped into %d lines: %+v", len(lines), lines)
+	}
+	// The rightmost column is read first, top to bottom
+	right := lines[0]
+	if !right.Vertical || right.X != 100 || right.Y != 0 || right.Width != 20 || right.Height != 68 {
+		t.Errorf("first line %+v", right)
+	}
+	if right.Words[0].Y != 0 || right.Words[2].Y != 48 || right.Words[0].X != 100 {
+		t.Errorf("words %+v", right.Words)
+	}
+}
+
+func TestConvertVerticalParagraph(t *testing.T) {
+	word := func(x, y int, text string) models.Word {
+		box := models.BoundingPoly{Vertices: []models.Vertex{{X: x, Y: y}, {X: x + 20, Y: y}, {X: x + 20, Y: y + 20}, {X: x, Y: y + 20}}}
+		return models.Word{BoundingBox: box, Symbols: []models.Symbol{{Text: text}}}
+	}
+	lines := NewConverter().convertParagraphToLines(models.Paragraph{
+		TextAngle: verticalTextAngle,
+		Words:     []models.Word{word(50, 0, "三"), word(100, 24, "二"), word(100, 0, "一")},
+	})
+	var texts [][]string
+	for _, line := range lines {
+		if line.TextAngle != verticalTextAngle {
+			t.Errorf("line %s has textangle %d", line.ID, line.TextAngle)
+		}
+		var words []string
+		for _, word := range line.Words {
+			words = append(words, word.Text)
+		}
+		texts = append(texts, words)
+	}
+	if !reflect.DeepEqual(texts, [][]string{{"一", "二"}, {"三"}}) {
+		t.Errorf("read as %v", texts)
+	}
+}
+
+func TestVerticalRoundTrip(t *testing.T) {
+	lines := []models.HOCRLine{{
+		ID:        "line_1",
+		BBox:      models.BBox{X1: 100, Y1: 0, X2: 120, Y2: 100},
+		TextAngle: verticalTextAngle,
+		Words:     []models.HOCRWord{{ID: "word_1", Text: "一二三四", BBox: models.BBox{X1: 100, Y1: 0, X2: 120, Y2: 100}}},
+	}}
+	parsed, err := ParseHOCRLines(NewConverter().ConvertHOCRLinesToXML(lines, 200, 200))
+	if err != nil || len(parsed) != 1 || !IsVertical(parsed[0]) {
+		t.Fatalf("parsed %+v, %v", parsed, err)
+	}
+
+	split, ids, err := SplitWord(parsed, "word_1", 50, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := split[0].Words
+	if words[0].ID != ids[0] || words[0].Text != "一二" || words[0].BBox.Y2 != 50 || words[1].BBox.Y1 != 50 || words[1].BBox.X1 != 100 {
+		t.Errorf("split into %+v", words)
+	}
+	if _, _, err := SplitWord(lines, "word_1", 110, nil); err == nil {
+		t.Error("expected an error splitting below the word")
+	}
+}
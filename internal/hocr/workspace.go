@@ -0,0 +1,62 @@
+package hocr
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// workspace is a directory of its own for the derivative files one run makes,
+// such as the preprocessed page, tag and word images, and stitched and
+// downscaled images. Runs on same-named files at the same moment can't collide,
+// and all of it is removed when the run ends, however it ends.
+type workspace struct {
+	service *Service
+	dir     string
+}
+
+// newWorkspace creates a run's directory under Dirs.Temp; the caller closes it
+func (s *Service) newWorkspace() (*workspace, error) {
+	dir, err := os.MkdirTemp(s.tempDir(), "hocredit-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	ws := &workspace{service: s, dir: dir}
+	s.workspaces.Store(ws, true)
+	return ws, nil
+}
+
+// path is where a derivative named name is written
+func (ws *workspace) path(name string) string {
+	return filepath.Join(ws.dir, name)
+}
+
+// remove deletes a derivative that's no longer needed before the run ends
+func (ws *workspace) remove(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		slog.Warn("Failed to remove derivative", "path", path, "err", err)
+	}
+}
+
+// Close removes the workspace and everything in it
+func (ws *workspace) Close() {
+	if err := os.RemoveAll(ws.dir); err != nil {
+		slog.Warn("Failed to remove workspace", "dir", ws.dir, "err", err)
+	}
+	ws.service.workspaces.Delete(ws)
+}
+
+// Cleanup removes the workspaces of runs still going, for shutdown, when they
+// won't get to finish
+func (s *Service) Cleanup() {
+	count := 0
+	s.workspaces.Range(func(key, _ any) bool {
+		key.(*workspace).Close()
+		count++
+		return true
+	})
+	if count > 0 {
+		slog.Info("Removed workspaces of unfinished runs", "runs", count)
+	}
+}
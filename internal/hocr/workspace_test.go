@@ -0,0 +1,55 @@
+package hocr
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWorkspaceRemovesDerivatives(t *testing.T) {
+	s := &Service{dirs: Dirs{Temp: t.TempDir()}}
+
+	newWorkspace := func() *workspace {
+		ws, err := s.newWorkspace()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ws
+	}
+	write := func(ws *workspace, name string) string {
+		path := ws.path(name)
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	// Runs on the same file at once each get their own copy of every derivative
+	first, second := newWorkspace(), newWorkspace()
+	if write(first, "stitched_page_0.png") == write(second, "stitched_page_0.png") {
+		t.Fatal("two workspaces share a derivative path")
+	}
+
+	kept := write(first, "word_img_1.png")
+	dropped := write(first, "word_img_2.png")
+	first.remove(dropped)
+	if exists(dropped) || !exists(kept) {
+		t.Fatal("remove should delete only the one derivative")
+	}
+	first.Close()
+	if exists(first.dir) {
+		t.Error("Close left the workspace behind")
+	}
+
+	s.Cleanup()
+	if exists(second.dir) {
+		t.Error("Cleanup left a running workspace behind")
+	}
+	s.workspaces.Range(func(any, any) bool {
+		t.Error("Cleanup left a workspace registered")
+		return false
+	})
+}
@@ -0,0 +1,180 @@
+package iiif
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// imageInfo covers the identifying fields of an Image API 2.x or 3.0 info.json
+type imageInfo struct {
+	ID       string `json:"id"`
+	LegacyID string `json:"@id"`
+	Type     string `json:"type"`
+	Protocol string `json:"protocol"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+// canvas covers the parts of a Presentation 2.x or 3.0 canvas needed to find its image service
+type canvas struct {
+	ID       string `json:"id"`
+	LegacyID string `json:"@id"`
+	Type     string `json:"type"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Images   []struct {
+		Resource struct {
+			ID      string          `json:"@id"`
+			Service json.RawMessage `json:"service"`
+		} `json:"resource"`
+	} `json:"images"`
+	Items []struct {
+		Items []struct {
+			Body json.RawMessage `json:"body"`
+		} `json:"items"`
+	} `json:"items"`
+}
+
+type serviceRef struct {
+	ID       string `json:"id"`
+	LegacyID string `json:"@id"`
+	Type     string `json:"type"`
+}
+
+type bodyWithService struct {
+	ID      string          `json:"id"`
+	Service json.RawMessage `json:"service"`
+}
+
+// ResolveSource identifies a IIIF info.json or canvas document and returns the
+// image service it points at along with the URL of the full-resolution image
+func ResolveSource(data []byte) (*models.IIIFSource, string, error) {
+	var info imageInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, "", fmt.Errorf("failed to parse IIIF document: %w", err)
+	}
+
+	if info.Protocol == "http://iiif.io/api/image" || strings.HasPrefix(info.Type, "ImageService") {
+		source := &models.IIIFSource{
+			ImageService: strings.TrimSuffix(firstNonEmpty(info.ID, info.LegacyID), "/"),
+			Version:      imageAPIVersion(info.Type, info.ID),
+		}
+		return source, FullImageURL(*source), nil
+	}
+
+	var c canvas
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, "", fmt.Errorf("failed to parse IIIF canvas: %w", err)
+	}
+
+	if c.Type != "Canvas" && c.Type != "sc:Canvas" && len(c.Images) == 0 && len(c.Items) == 0 {
+		return nil, "", fmt.Errorf("document is neither a IIIF image info.json nor a canvas")
+	}
+
+	source := &models.IIIFSource{
+		CanvasID:     firstNonEmpty(c.ID, c.LegacyID),
+		CanvasWidth:  c.Width,
+		CanvasHeight: c.Height,
+	}
+
+	// Presentation 2.x: images -> resource -> service
+	for _, image := range c.Images {
+		if service, ok := parseService(image.Resource.Service); ok {
+			source.ImageService = strings.TrimSuffix(firstNonEmpty(service.ID, service.LegacyID), "/")
+			source.Version = imageAPIVersion(service.Type, service.ID)
+			return source, FullImageURL(*source), nil
+		}
+		if image.Resource.ID != "" {
+			return source, image.Resource.ID, nil
+		}
+	}
+
+	// Presentation 3.0: items (annotation pages) -> items (annotations) -> body -> service
+	for _, page := range c.Items {
+		for _, annotation := range page.Items {
+			var body bodyWithService
+			if err := json.Unmarshal(annotation.Body, &body); err != nil {
+				continue
+			}
+			if service, ok := parseService(body.Service); ok {
+				source.ImageService = strings.TrimSuffix(firstNonEmpty(service.ID, service.LegacyID), "/")
+				source.Version = imageAPIVersion(service.Type, service.ID)
+				return source, FullImageURL(*source), nil
+			}
+			if body.ID != "" {
+				return source, body.ID, nil
+			}
+		}
+	}
+
+	return nil, "", fmt.Errorf("no image found on IIIF canvas")
+}
+
+// FullImageURL requests the full region at full size from an image service
+func FullImageURL(source models.IIIFSource) string {
+	size := "max"
+	if source.Version == 2 {
+		size = "full"
+	}
+	return fmt.Sprintf("%s/full/%s/0/default.jpg", source.ImageService, size)
+}
+
+// ScaleToCanvas maps a box in image pixels onto the canvas coordinate space.
+// Without known canvas dimensions the box is returned unchanged.
+func ScaleToCanvas(source models.IIIFSource, box models.BBox, imageWidth, imageHeight int) models.BBox {
+	if source.CanvasWidth == 0 || source.CanvasHeight == 0 || imageWidth == 0 || imageHeight == 0 {
+		return box
+	}
+
+	sx := float64(source.CanvasWidth) / float64(imageWidth)
+	sy := float64(source.CanvasHeight) / float64(imageHeight)
+	return models.BBox{
+		X1: int(float64(box.X1)*sx + 0.5),
+		Y1: int(float64(box.Y1)*sy + 0.5),
+		X2: int(float64(box.X2)*sx + 0.5),
+		Y2: int(float64(box.Y2)*sy + 0.5),
+	}
+}
+
+// parseService returns the first image service in a service object or array
+func parseService(raw json.RawMessage) (serviceRef, bool) {
+	if len(raw) == 0 {
+		return serviceRef{}, false
+	}
+
+	var services []serviceRef
+	var single serviceRef
+	if err := json.Unmarshal(raw, &single); err == nil {
+		services = []serviceRef{single}
+	} else if err := json.Unmarshal(raw, &services); err != nil {
+		return serviceRef{}, false
+	}
+
+	for _, service := range services {
+		if service.ID != "" || service.LegacyID != "" {
+			return service, true
+		}
+	}
+	return serviceRef{}, false
+}
+
+// imageAPIVersion infers the Image API version; 3.0 services carry an "id" and
+// a type of ImageService3, earlier ones only "@id"
+func imageAPIVersion(serviceType, id string) int {
+	if serviceType == "ImageService3" || (serviceType == "" && id != "") {
+		return 3
+	}
+	return 2
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package iiif
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// manifest covers the parts of IIIF Presentation 2.x and 3.0 manifests needed to
+// find each canvas's image
+type manifest struct {
+	Sequences []struct {
+		Canvases []struct {
+			Images []struct {
+				Resource struct {
+					ID string `json:"@id"`
+				} `json:"resource"`
+			} `json:"images"`
+		} `json:"canvases"`
+	} `json:"sequences"`
+	Items []struct {
+		Items []struct {
+			Items []struct {
+				Body json.RawMessage `json:"body"`
+			} `json:"items"`
+		} `json:"items"`
+	} `json:"items"`
+}
+
+type v3Body struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// ImageURLsFromManifest returns the image URL painted on every canvas, in canvas order
+func ImageURLsFromManifest(data []byte) ([]string, error) {
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse IIIF manifest: %w", err)
+	}
+
+	var urls []string
+
+	// Presentation 2.x: sequences -> canvases -> images -> resource
+	for _, sequence := range m.Sequences {
+		for _, canvas := range sequence.Canvases {
+			for _, image := range canvas.Images {
+				if image.Resource.ID != "" {
+					urls = append(urls, image.Resource.ID)
+				}
+			}
+		}
+	}
+
+	// Presentation 3.0: items (canvases) -> items (annotation pages) -> items (annotations) -> body
+	for _, canvas := range m.Items {
+		for _, page := range canvas.Items {
+			for _, annotation := range page.Items {
+				for _, body := range parseBodies(annotation.Body) {
+					if body.ID != "" && (body.Type == "" || body.Type == "Image") {
+						urls = append(urls, body.ID)
+					}
+				}
+			}
+		}
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no images found in IIIF manifest")
+	}
+
+	return urls, nil
+}
+
+// parseBodies handles annotation bodies given as either an object or an array
+func parseBodies(raw json.RawMessage) []v3Body {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var single v3Body
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []v3Body{single}
+	}
+
+	var multiple []v3Body
+	if err := json.Unmarshal(raw, &multiple); err == nil {
+		return multiple
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package lm
+
+import "testing"
+
+func TestCrossEntropy(t *testing.T) {
+	model := Default()
+
+	familiar := model.CrossEntropy("government of the ", "people")
+	gibberish := model.CrossEntropy("government of the ", "xqzvkj")
+	if familiar >= gibberish {
+		t.Errorf("familiar text scored %.2f bits/char, gibberish %.2f", familiar, gibberish)
+	}
+	if got := model.CrossEntropy("context", ""); got != 0 {
+		t.Errorf("empty text scored %.2f", got)
+	}
+}
+
+func TestScoreLineOrder(t *testing.T) {
+	model := Default()
+
+	ordered := [][]string{
+		{"Dear Sarah, I write to tell you that", "we arrived safely in Philadelphia on", "Saturday after a long journey. The",
+			"weather was fine and the roads were", "much better than we had expected, so", "we made good time. Give my love to",
+			"the children and write to me soon."},
+		{"The committee met on Wednesday even-", "ing at the house of Mr. Johnson. The", "minutes of the last meeting were read",
+			"and approved. It was resolved that the", "secretary be instructed to write to the", "trustees of the church concerning the",
+			"repairs to the roof of the building."},
+	}
+	for _, lines := range ordered {
+		score := model.ScoreLineOrder(lines)
+		if score.Lines != len(lines) {
+			t.Errorf("scored %d lines, want %d", score.Lines, len(lines))
+		}
+		if score.Plausibility < 0.9 {
+			t.Errorf("ordered page scored plausibility %.2f: %q", score.Plausibility, lines[0])
+		}
+		if score.CrossEntropy >= score.ShuffledCrossEntropy {
+			t.Errorf("ordered page read no better than shuffled ones: %+v", score)
+		}
+	}
+
+	if score := model.ScoreLineOrder([]string{"one line", "", "two lines"}); score.Plausibility != 1 || score.Lines != 2 {
+		t.Errorf("short page: %+v", score)
+	}
+}
@@ -0,0 +1,97 @@
+// Package lm is a small character n-gram language model used for sanity checks on
+// transcriptions. It is trained on a bundled English corpus, runs locally, and is
+// cheap enough to score every page.
+package lm
+
+import (
+	"math"
+)
+
+// start pads the beginning of training text so the first characters have a history
+const start = '\x02'
+
+// Model is a character n-gram model with Witten-Bell smoothing. It is safe for
+// concurrent scoring once training is done.
+type Model struct {
+	order int
+	// counts[history][next] is how often next followed history
+	counts map[string]map[rune]int
+	totals map[string]int
+	vocab  map[rune]bool
+}
+
+func New(order int) *Model {
+	return &Model{
+		order:  max(1, order),
+		counts: make(map[string]map[rune]int),
+		totals: make(map[string]int),
+		vocab:  make(map[rune]bool),
+	}
+}
+
+// Train adds a text's n-gram counts to the model
+func (m *Model) Train(text string) {
+	runes := []rune(text)
+	for i, r := range runes {
+		m.vocab[r] = true
+		context := history(runes, i, m.order-1)
+		for n := 0; n <= len(context); n++ {
+			h := string(context[len(context)-n:])
+			if m.counts[h] == nil {
+				m.counts[h] = make(map[rune]int)
+			}
+			m.counts[h][r]++
+			m.totals[h]++
+		}
+	}
+}
+
+// history returns up to n runes before position i, padded with start markers
+func history(runes []rune, i, n int) []rune {
+	context := make([]rune, 0, n)
+	for j := i - n; j < i; j++ {
+		if j < 0 {
+			context = append(context, start)
+		} else {
+			context = append(context, runes[j])
+		}
+	}
+	return context
+}
+
+// prob is the smoothed probability of r following context, backing off to shorter
+// histories and finally to a uniform distribution over the vocabulary
+func (m *Model) prob(context []rune, r rune) float64 {
+	p := 1.0 / float64(len(m.vocab)+1)
+	for n := 0; n <= len(context); n++ {
+		h := string(context[len(context)-n:])
+		total := m.totals[h]
+		if total == 0 {
+			break
+		}
+		types := float64(len(m.counts[h]))
+		p = (float64(m.counts[h][r]) + types*p) / (float64(total) + types)
+	}
+	return p
+}
+
+// CrossEntropy is the average number of bits per character needed to encode text
+// when it follows context. Lower means the text is a more plausible continuation.
+func (m *Model) CrossEntropy(context, text string) float64 {
+	runes := []rune(context + text)
+	offset := len([]rune(context))
+	if offset == len(runes) {
+		return 0
+	}
+
+	bits := 0.0
+	for i := offset; i < len(runes); i++ {
+		bits -= math.Log2(m.prob(history(runes, i, m.order-1), runes[i]))
+	}
+	return bits / float64(len(runes)-offset)
+}
+
+// Perplexity is 2 raised to the cross entropy of text following context
+func (m *Model) Perplexity(context, text string) float64 {
+	return math.Pow(2, m.CrossEntropy(context, text))
+}
@@ -0,0 +1,146 @@
+package lm
+
+import (
+	_ "embed"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+// DefaultOrder is the n-gram order of the bundled model
+const DefaultOrder = 5
+
+// Language is the Tesseract code of the language the bundled corpus is in
+const Language = "eng"
+
+const (
+	// boundaryContext and boundaryText are how many characters either side of a line
+	// break are scored; the break is where a scrambled order shows
+	boundaryContext = 16
+	boundaryText    = 8
+	// shuffles is how many random orders the actual order is ranked against
+	shuffles = 200
+)
+
+//go:embed corpus.txt
+var corpus string
+
+var (
+	defaultModel     *Model
+	defaultModelOnce sync.Once
+)
+
+// Default is a model trained on the bundled corpus
+func Default() *Model {
+	defaultModelOnce.Do(func() {
+		defaultModel = New(DefaultOrder)
+		for _, paragraph := range strings.Split(corpus, "\n") {
+			if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+				defaultModel.Train(paragraph)
+			}
+		}
+	})
+	return defaultModel
+}
+
+// OrderScore describes how plausible a page's line order is
+type OrderScore struct {
+	Lines int `json:"lines"`
+	// CrossEntropy is the mean bits per character across line breaks in the given
+	// order, relative to each line's start read without a preceding line
+	CrossEntropy float64 `json:"cross_entropy"`
+	// ShuffledCrossEntropy is the same measure averaged over random line orders
+	ShuffledCrossEntropy float64 `json:"shuffled_cross_entropy"`
+	// Plausibility is the share of random orders that read worse than the given one;
+	// a well ordered page scores near 1, a scrambled one anywhere
+	Plausibility float64 `json:"plausibility"`
+}
+
+// joinBoundary renders the text around the break between two lines, rejoining
+// words hyphenated across it
+func joinBoundary(before, after string) (string, string) {
+	context := []rune(before)
+	if strings.HasSuffix(before, "-") {
+		context = context[:len(context)-1]
+	} else {
+		context = append(context, ' ')
+	}
+	if len(context) > boundaryContext {
+		context = context[len(context)-boundaryContext:]
+	}
+
+	text := []rune(after)
+	if len(text) > boundaryText {
+		text = text[:boundaryText]
+	}
+	return string(context), string(text)
+}
+
+// ScoreLineOrder compares how well the lines read in the given order against random
+// orders of the same lines. Pages with fewer than three lines can't be judged and
+// score a plausibility of 1.
+func (m *Model) ScoreLineOrder(lines []string) OrderScore {
+	var kept []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+
+	n := len(kept)
+	score := OrderScore{Lines: n, Plausibility: 1}
+	if n < 3 {
+		return score
+	}
+
+	// entropy scores line j following line i, net of how predictable the start of
+	// line j is on its own, so only the information the break carries counts.
+	// Pairs are scored as the orders need them, since word-per-line pages can be long.
+	scored := make(map[[2]int]float64)
+	entropy := func(i, j int) float64 {
+		if value, ok := scored[[2]int{i, j}]; ok {
+			return value
+		}
+		context, text := joinBoundary(kept[i], kept[j])
+		value := m.CrossEntropy(context, text) - m.CrossEntropy(" ", text)
+		scored[[2]int{i, j}] = value
+		return value
+	}
+
+	orderEntropy := func(order []int) float64 {
+		total := 0.0
+		for k := 1; k < len(order); k++ {
+			total += entropy(order[k-1], order[k])
+		}
+		return total / float64(len(order)-1)
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	score.CrossEntropy = orderEntropy(order)
+
+	// Seeded by the text so a page always gets the same score
+	seed := fnv.New64a()
+	seed.Write([]byte(strings.Join(kept, "\n")))
+	random := rand.New(rand.NewSource(int64(seed.Sum64())))
+
+	worse := 0.0
+	for range shuffles {
+		shuffled := random.Perm(n)
+		shuffledEntropy := orderEntropy(shuffled)
+		score.ShuffledCrossEntropy += shuffledEntropy
+		switch {
+		case shuffledEntropy > score.CrossEntropy:
+			worse++
+		case shuffledEntropy == score.CrossEntropy:
+			worse += 0.5
+		}
+	}
+	score.ShuffledCrossEntropy /= shuffles
+	score.Plausibility = worse / shuffles
+
+	return score
+}
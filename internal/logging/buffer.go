@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Entry is a log record kept in memory so it can be attached to a diagnostic bundle
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// Buffer keeps the most recent log entries in a ring
+type Buffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+// Recent holds the entries diagnostic bundles draw from. It is nil, and keeps
+// nothing, until main sets it up.
+var Recent *Buffer
+
+func NewBuffer(size int) *Buffer {
+	return &Buffer{entries: make([]Entry, max(1, size))}
+}
+
+func (b *Buffer) add(entry Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Between returns the entries logged in [from, to], oldest first, that keep accepts
+func (b *Buffer) Between(from, to time.Time, keep func(Entry) bool) []Entry {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ordered := b.entries[:b.next]
+	if b.full {
+		ordered = append(append([]Entry{}, b.entries[b.next:]...), b.entries[:b.next]...)
+	}
+
+	var result []Entry
+	for _, entry := range ordered {
+		if entry.Time.Before(from) || entry.Time.After(to) {
+			continue
+		}
+		if keep == nil || keep(entry) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// BufferHandler passes records on and copies them into a Buffer. Wrap it in the
+// RedactingHandler so the buffer only ever holds redacted values.
+type BufferHandler struct {
+	next   slog.Handler
+	buffer *Buffer
+	attrs  []slog.Attr
+	group  string
+}
+
+func NewBufferHandler(next slog.Handler, buffer *Buffer) *BufferHandler {
+	return &BufferHandler{next: next, buffer: buffer}
+}
+
+func (h *BufferHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *BufferHandler) Handle(ctx context.Context, record slog.Record) error {
+	entry := Entry{Time: record.Time, Level: record.Level.String(), Message: record.Message, Attrs: make(map[string]string)}
+	for _, attr := range h.attrs {
+		flatten(entry.Attrs, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		flatten(entry.Attrs, h.group, attr)
+		return true
+	})
+	h.buffer.add(entry)
+
+	return h.next.Handle(ctx, record)
+}
+
+func (h *BufferHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefixed := slices.Clone(h.attrs)
+	for _, attr := range attrs {
+		if h.group != "" {
+			attr.Key = h.group + "." + attr.Key
+		}
+		prefixed = append(prefixed, attr)
+	}
+	return &BufferHandler{next: h.next.WithAttrs(attrs), buffer: h.buffer, attrs: prefixed, group: h.group}
+}
+
+func (h *BufferHandler) WithGroup(name string) slog.Handler {
+	group := name
+	if h.group != "" {
+		group = h.group + "." + name
+	}
+	return &BufferHandler{next: h.next.WithGroup(name), buffer: h.buffer, attrs: h.attrs, group: group}
+}
+
+// flatten writes an attribute as text, naming group members like llm.model
+func flatten(attrs map[string]string, prefix string, attr slog.Attr) {
+	key := attr.Key
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+
+	value := attr.Value.Resolve()
+	if value.Kind() != slog.KindGroup {
+		attrs[key] = value.String()
+		return
+	}
+	for _, child := range value.Group() {
+		flatten(attrs, key, child)
+	}
+}
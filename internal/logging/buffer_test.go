@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBufferHandler(t *testing.T) {
+	buffer := NewBuffer(3)
+	policy := Policy{Mode: PolicyOmit, Keys: map[string]bool{"content": true}}
+	logger := slog.New(NewRedactingHandler(NewBufferHandler(slog.NewTextHandler(io.Discard, nil), buffer), policy))
+
+	start := time.Now()
+	logger.Info("first")
+	logger.With("job_id", "job_1").Info("second", "content", "private page text")
+	logger.Info("third", slog.Group("llm", "model", "gpt-4o"))
+	logger.Info("fourth", "job_id", "job_2")
+
+	entries := buffer.Between(start, time.Now(), nil)
+	if len(entries) != 3 || entries[0].Message != "second" || entries[2].Message != "fourth" {
+		t.Fatalf("expected the last three entries in order, got %+v", entries)
+	}
+	if entries[0].Attrs["job_id"] != "job_1" || strings.Contains(entries[0].Attrs["content"], "private") {
+		t.Errorf("attrs not kept redacted: %v", entries[0].Attrs)
+	}
+	if entries[1].Attrs["llm.model"] != "gpt-4o" {
+		t.Errorf("group attrs not flattened: %v", entries[1].Attrs)
+	}
+
+	job1 := buffer.Between(start, time.Now(), func(entry Entry) bool { return entry.Attrs["job_id"] == "job_1" })
+	if len(job1) != 1 {
+		t.Errorf("expected one entry for job_1, got %d", len(job1))
+	}
+
+	var unset *Buffer
+	if unset.Between(start, time.Now(), nil) != nil {
+		t.Error("nil buffer should hold nothing")
+	}
+}
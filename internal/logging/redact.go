@@ -0,0 +1,151 @@
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// Redaction policies for log attributes that may carry page text
+const (
+	PolicyNone     = "none"
+	PolicyTruncate = "truncate"
+	PolicyHash     = "hash"
+	PolicyOmit     = "omit"
+)
+
+// defaultSensitiveKeys are attribute keys that hold transcriptions or API payloads
+var defaultSensitiveKeys = []string{"hocr", "content", "text", "response", "body", "payload", "prompt", "result", "ground_truth"}
+
+// Policy decides how sensitive attribute values are written
+type Policy struct {
+	Mode      string
+	MaxLength int
+	Keys      map[string]bool
+}
+
+// PolicyFromEnv reads LOG_REDACTION, LOG_REDACTION_MAX_LENGTH and LOG_REDACTION_KEYS
+func PolicyFromEnv() Policy {
+	mode := strings.ToLower(os.Getenv("LOG_REDACTION"))
+	switch mode {
+	case PolicyNone, PolicyTruncate, PolicyHash, PolicyOmit:
+	case "":
+		mode = PolicyHash
+	default:
+		fmt.Fprintf(os.Stderr, "unknown LOG_REDACTION %q, using %s\n", mode, PolicyHash)
+		mode = PolicyHash
+	}
+
+	keys := defaultSensitiveKeys
+	if value := os.Getenv("LOG_REDACTION_KEYS"); value != "" {
+		keys = strings.Split(value, ",")
+	}
+
+	policy := Policy{
+		Mode:      mode,
+		MaxLength: utils.GetEnvInt("LOG_REDACTION_MAX_LENGTH", 32),
+		Keys:      make(map[string]bool, len(keys)),
+	}
+	for _, key := range keys {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			policy.Keys[key] = true
+		}
+	}
+
+	return policy
+}
+
+// Redact returns the value to log in place of a sensitive string
+func (p Policy) Redact(value string) string {
+	switch p.Mode {
+	case PolicyNone:
+		return value
+	case PolicyTruncate:
+		if utf8.RuneCountInString(value) <= p.MaxLength {
+			return value
+		}
+		runes := []rune(value)
+		return fmt.Sprintf("%s…[%d chars]", string(runes[:p.MaxLength]), len(runes))
+	case PolicyOmit:
+		return fmt.Sprintf("[redacted %d chars]", utf8.RuneCountInString(value))
+	default:
+		sum := sha256.Sum256([]byte(value))
+		return fmt.Sprintf("sha256:%s [%d chars]", hex.EncodeToString(sum[:])[:12], utf8.RuneCountInString(value))
+	}
+}
+
+// RedactingHandler rewrites sensitive attributes before passing records on
+type RedactingHandler struct {
+	next   slog.Handler
+	policy Policy
+}
+
+func NewRedactingHandler(next slog.Handler, policy Policy) *RedactingHandler {
+	return &RedactingHandler{next: next, policy: policy}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if h.policy.Mode == PolicyNone {
+		return h.next.Handle(ctx, record)
+	}
+
+	redacted := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactAttr(attr)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(redacted), policy: h.policy}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name), policy: h.policy}
+}
+
+func (h *RedactingHandler) redactAttr(attr slog.Attr) slog.Attr {
+	if h.policy.Mode == PolicyNone {
+		return attr
+	}
+
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		group := value.Group()
+		redacted := make([]any, len(group))
+		for i, child := range group {
+			redacted[i] = h.redactAttr(child)
+		}
+		return slog.Group(attr.Key, redacted...)
+	}
+
+	if !h.policy.Keys[strings.ToLower(attr.Key)] {
+		return attr
+	}
+
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, h.policy.Redact(value.String()))
+	case slog.KindAny:
+		return slog.String(attr.Key, h.policy.Redact(fmt.Sprint(value.Any())))
+	default:
+		// Numbers, durations and the like can't carry page text
+		return attr
+	}
+}
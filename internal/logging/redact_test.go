@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactingHandler(t *testing.T) {
+	page := "Dear Sir, the enclosed letter concerns a private matter of some delicacy."
+
+	tests := []struct {
+		mode     string
+		contains string
+	}{
+		{PolicyNone, page},
+		{PolicyTruncate, "Dear Sir…[73 chars]"},
+		{PolicyHash, "sha256:"},
+		{PolicyOmit, "[redacted 73 chars]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			var buf bytes.Buffer
+			policy := Policy{Mode: tt.mode, MaxLength: 8, Keys: map[string]bool{"content": true}}
+			logger := slog.New(NewRedactingHandler(slog.NewTextHandler(&buf, nil), policy))
+
+			logger.Info("transcribed", "content", page, "session_id", "abc", "result_length", len(page))
+			logger.With("content", page).Info("with attrs")
+			logger.Info("grouped", slog.Group("llm", "content", page))
+
+			out := buf.String()
+			if !strings.Contains(out, tt.contains) {
+				t.Errorf("output missing %q:\n%s", tt.contains, out)
+			}
+			if tt.mode != PolicyNone && strings.Contains(out, "delicacy") {
+				t.Errorf("page text leaked:\n%s", out)
+			}
+			if !strings.Contains(out, "session_id=abc") || !strings.Contains(out, "result_length=73") {
+				t.Errorf("non-sensitive attributes changed:\n%s", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Alignment operations
+const (
+	OpEqual      = "equal"
+	OpSubstitute = "substitute"
+	OpInsert     = "insert"
+	OpDelete     = "delete"
+)
+
+// Units texts can be aligned by
+const (
+	UnitWord = "word"
+	UnitChar = "char"
+)
+
+// Span is a run of one operation. Start and end are half-open token indexes:
+// words, or characters (code points) when aligning by character. A deletion
+// has an empty corrected range, and an insertion an empty original range.
+type Span struct {
+	Op             string `json:"op"`
+	OriginalStart  int    `json:"original_start"`
+	OriginalEnd    int    `json:"original_end"`
+	CorrectedStart int    `json:"corrected_start"`
+	CorrectedEnd   int    `json:"corrected_end"`
+	Original       string `json:"original"`
+	Corrected      string `json:"corrected"`
+}
+
+// AlignText aligns two texts by word (split on whitespace) or by character.
+// Tokens are compared exactly, so a diff shows changes of case and accents.
+func AlignText(original, corrected, unit string) ([]Span, error) {
+	switch unit {
+	case "", UnitWord:
+		return Align(strings.Fields(original), strings.Fields(corrected), " "), nil
+	case UnitChar:
+		return Align(splitRunes(original), splitRunes(corrected), ""), nil
+	}
+	return nil, fmt.Errorf("unknown alignment unit: %s", unit)
+}
+
+func splitRunes(text string) []string {
+	tokens := make([]string, 0, len(text))
+	for _, r := range text {
+		tokens = append(tokens, string(r))
+	}
+	return tokens
+}
+
+// Align finds a minimal Levenshtein alignment of two token sequences and merges
+// consecutive tokens with the same operation into spans, joining their text
+// with sep
+func Align(original, corrected []string, sep string) []Span {
+	m, n := len(original), len(corrected)
+	dist := make([][]int, m+1)
+	for i := range dist {
+		dist[i] = make([]int, n+1)
+		dist[i][0] = i
+	}
+	for j := 0; j <= n; j++ {
+		dist[0][j] = j
+	}
+	for i := 1; i <= m; i++ {
+		for j := 1; j <= n; j++ {
+			cost := 1
+			if original[i-1] == corrected[j-1] {
+				cost = 0
+			}
+			dist[i][j] = min(dist[i-1][j]+1, dist[i][j-1]+1, dist[i-1][j-1]+cost)
+		}
+	}
+
+	// Walk back from the end, collecting operations in reverse. Preferring
+	// deletions and insertions among equally short alignments pairs a misread
+	// token with its correction rather than with a neighbor.
+	var ops []string
+	for i, j := m, n; i > 0 || j > 0; {
+		switch {
+		case i > 0 && j > 0 && original[i-1] == corrected[j-1] && dist[i][j] == dist[i-1][j-1]:
+			ops = append(ops, OpEqual)
+			i, j = i-1, j-1
+		case i > 0 && dist[i][j] == dist[i-1][j]+1:
+			ops = append(ops, OpDelete)
+			i--
+		case j > 0 && dist[i][j] == dist[i][j-1]+1:
+			ops = append(ops, OpInsert)
+			j--
+		default:
+			ops = append(ops, OpSubstitute)
+			i, j = i-1, j-1
+		}
+	}
+
+	var spans []Span
+	i, j := 0, 0
+	for k := len(ops) - 1; k >= 0; k-- {
+		op := ops[k]
+		if len(spans) == 0 || spans[len(spans)-1].Op != op {
+			spans = append(spans, Span{Op: op, OriginalStart: i, OriginalEnd: i, CorrectedStart: j, CorrectedEnd: j})
+		}
+		span := &spans[len(spans)-1]
+		if op != OpInsert {
+			i++
+			span.OriginalEnd = i
+		}
+		if op != OpDelete {
+			j++
+			span.CorrectedEnd = j
+		}
+	}
+	for k := range spans {
+		spans[k].Original = strings.Join(original[spans[k].OriginalStart:spans[k].OriginalEnd], sep)
+		spans[k].Corrected = strings.Join(corrected[spans[k].CorrectedStart:spans[k].CorrectedEnd], sep)
+	}
+	return spans
+}
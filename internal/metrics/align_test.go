@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAlignText(t *testing.T) {
+	spans, err := AlignText("The qnick brown fox jumps", "The quick fox jumps high", UnitWord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Span{
+		{Op: OpEqual, OriginalStart: 0, OriginalEnd: 1, CorrectedStart: 0, CorrectedEnd: 1, Original: "The", Corrected: "The"},
+		{Op: OpSubstitute, OriginalStart: 1, OriginalEnd: 2, CorrectedStart: 1, CorrectedEnd: 2, Original: "qnick", Corrected: "quick"},
+		{Op: OpDelete, OriginalStart: 2, OriginalEnd: 3, CorrectedStart: 2, CorrectedEnd: 2, Original: "brown"},
+		{Op: OpEqual, OriginalStart: 3, OriginalEnd: 5, CorrectedStart: 2, CorrectedEnd: 4, Original: "fox jumps", Corrected: "fox jumps"},
+		{Op: OpInsert, OriginalStart: 5, OriginalEnd: 5, CorrectedStart: 4, CorrectedEnd: 5, Corrected: "high"},
+	}
+	if !reflect.DeepEqual(spans, want) {
+		t.Errorf("spans = %+v\nwant %+v", spans, want)
+	}
+}
+
+func TestAlignTextByCharacter(t *testing.T) {
+	spans, err := AlignText("cafés", "cafe", UnitChar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Span{
+		{Op: OpEqual, OriginalStart: 0, OriginalEnd: 3, CorrectedStart: 0, CorrectedEnd: 3, Original: "caf", Corrected: "caf"},
+		{Op: OpSubstitute, OriginalStart: 3, OriginalEnd: 4, CorrectedStart: 3, CorrectedEnd: 4, Original: "é", Corrected: "e"},
+		{Op: OpDelete, OriginalStart: 4, OriginalEnd: 5, CorrectedStart: 4, CorrectedEnd: 4, Original: "s"},
+	}
+	if !reflect.DeepEqual(spans, want) {
+		t.Errorf("spans = %+v\nwant %+v", spans, want)
+	}
+
+	if _, err := AlignText("a", "b", "line"); err == nil {
+		t.Error("expected an error for an unknown unit")
+	}
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import "github.com/lehigh-university-libraries/hOCRedit/internal/models"
+
+// ConfidenceBucket counts the OCR words whose confidence falls in [Min, Max)
+// and how many of them an editor changed. The last bucket includes 100.
+type ConfidenceBucket struct {
+	Min            float64 `json:"min"`
+	Max            float64 `json:"max"`
+	Words          int     `json:"words"`
+	Corrected      int     `json:"corrected"`
+	CorrectionRate float64 `json:"correction_rate"`
+	// CorrectionRateAbove covers every word at or above Min, the share an
+	// auto-accept threshold of Min would have let through wrongly
+	CorrectionRateAbove float64 `json:"correction_rate_above"`
+}
+
+// Calibration compares engine confidence with what editors actually corrected
+type Calibration struct {
+	Pages   int                `json:"pages"`
+	Skipped int                `json:"skipped"`
+	Words   int                `json:"words"`
+	Buckets []ConfidenceBucket `json:"buckets"`
+}
+
+// NewCalibration divides the 0-100 confidence range into buckets of the given
+// width
+func NewCalibration(width int) *Calibration {
+	width = min(max(width, 1), 100)
+	c := &Calibration{}
+	for low := 0; low < 100; low += width {
+		c.Buckets = append(c.Buckets, ConfidenceBucket{Min: float64(low), Max: float64(min(low+width, 100))})
+	}
+	return c
+}
+
+// AddPage counts the words of a page's OCR against its correction. Pages whose
+// words carry no confidence at all are skipped, since the engine didn't score them.
+func (c *Calibration) AddPage(original, corrected []models.HOCRLine, normalization models.NormalizationConfig) {
+	confidences := map[string]float64{}
+	scored := false
+	for _, line := range original {
+		for _, word := range line.Words {
+			confidences[word.ID] = word.Confidence
+			scored = scored || word.Confidence > 0
+		}
+	}
+	if !scored {
+		c.Skipped++
+		return
+	}
+
+	c.Pages++
+	for _, line := range CompareLines(original, corrected, normalization) {
+		for _, word := range line.Words {
+			// Inserted words were missed by the engine, so they have no confidence
+			if word.Status == models.WordInserted {
+				continue
+			}
+			c.add(confidences[word.ID], word.Status != models.WordCorrect)
+		}
+	}
+	c.rates()
+}
+
+func (c *Calibration) add(confidence float64, corrected bool) {
+	c.Words++
+	i := len(c.Buckets) - 1
+	for confidence < c.Buckets[i].Min && i > 0 {
+		i--
+	}
+	c.Buckets[i].Words++
+	if corrected {
+		c.Buckets[i].Corrected++
+	}
+}
+
+func (c *Calibration) rates() {
+	words, corrected := 0, 0
+	for i := len(c.Buckets) - 1; i >= 0; i-- {
+		bucket := &c.Buckets[i]
+		words += bucket.Words
+		corrected += bucket.Corrected
+		bucket.CorrectionRate, bucket.CorrectionRateAbove = 0, 0
+		if bucket.Words > 0 {
+			bucket.CorrectionRate = float64(bucket.Corrected) / float64(bucket.Words)
+		}
+		if words > 0 {
+			bucket.CorrectionRateAbove = float64(corrected) / float64(words)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestCalibration(t *testing.T) {
+	original := []models.HOCRLine{testLine("line_1", 0, "The", "qnick", "brown", "fox")}
+	for i, confidence := range []float64{96, 40, 55, 100} {
+		original[0].Words[i].Confidence = confidence
+	}
+	corrected := []models.HOCRLine{testLine("line_1", 0, "The", "quick", "brown", "fox", "jumps")}
+
+	calibration := NewCalibration(50)
+	calibration.AddPage(original, corrected, models.NormalizationConfig{})
+	// A page without confidences says nothing about calibration
+	calibration.AddPage([]models.HOCRLine{testLine("line_1", 0, "fox")}, nil, models.NormalizationConfig{})
+
+	if calibration.Pages != 1 || calibration.Skipped != 1 || calibration.Words != 4 {
+		t.Fatalf("calibration = %+v", calibration)
+	}
+	if len(calibration.Buckets) != 2 {
+		t.Fatalf("expected 2 buckets, got %+v", calibration.Buckets)
+	}
+
+	low, high := calibration.Buckets[0], calibration.Buckets[1]
+	if low.Words != 1 || low.Corrected != 1 || !approx(low.CorrectionRate, 1) || !approx(low.CorrectionRateAbove, 0.25) {
+		t.Errorf("low bucket = %+v", low)
+	}
+	if high.Min != 50 || high.Max != 100 || high.Words != 3 || high.Corrected != 0 || high.CorrectionRateAbove != 0 {
+		t.Errorf("high bucket = %+v", high)
+	}
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"strings"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+// minLineOverlap is the intersection over union above which two lines with
+// different IDs are treated as the same line
+const minLineOverlap = 0.5
+
+// CompareLines aligns the lines of the original OCR with those of the correction
+// and reports how each line and word fared. Lines are matched by ID, then by
+// bounding box overlap, so re-created lines still line up. Original lines with
+// no counterpart were deleted, and corrected lines with none were added.
+func CompareLines(original, corrected []models.HOCRLine, normalization models.NormalizationConfig) []models.LineAccuracy {
+	matched := make([]int, len(original))
+	used := make([]bool, len(corrected))
+	byID := make(map[string]int, len(corrected))
+	for j, line := range corrected {
+		byID[line.ID] = j
+	}
+
+	for i, line := range original {
+		matched[i] = -1
+		if j, ok := byID[line.ID]; ok && line.ID != "" {
+			matched[i] = j
+			used[j] = true
+		}
+	}
+	for i, line := range original {
+		if matched[i] >= 0 {
+			continue
+		}
+		best, bestOverlap := -1, minLineOverlap
+		for j, candidate := range corrected {
+			if overlap := BoxOverlap(line.BBox, candidate.BBox); !used[j] && overlap > bestOverlap {
+				best, bestOverlap = j, overlap
+			}
+		}
+		if best >= 0 {
+			matched[i] = best
+			used[best] = true
+		}
+	}
+
+	results := make([]models.LineAccuracy, 0, len(original))
+	for i, line := range original {
+		var counterpart models.HOCRLine
+		if matched[i] >= 0 {
+			counterpart = corrected[matched[i]]
+		}
+		results = append(results, compareLine(line, counterpart, normalization))
+	}
+	for j, line := range corrected {
+		if !used[j] {
+			results = append(results, compareLine(models.HOCRLine{BBox: line.BBox}, line, normalization))
+		}
+	}
+	return results
+}
+
+func compareLine(original, corrected models.HOCRLine, normalization models.NormalizationConfig) models.LineAccuracy {
+	words, edits := alignWords(original.Words, corrected.Words, normalization)
+	originalText := lineText(original)
+	correctedText := lineText(corrected)
+	originalChars := []rune(normalizeText(originalText, normalization))
+
+	result := models.LineAccuracy{
+		ID:                 original.ID,
+		BBox:               original.BBox,
+		Original:           originalText,
+		Corrected:          correctedText,
+		WordErrorRate:      errorRate(edits, len(original.Words)),
+		CharacterErrorRate: errorRate(runeDistance(originalChars, []rune(normalizeText(correctedText, normalization))), len(originalChars)),
+		Words:              words,
+	}
+	if corrected.ID != original.ID {
+		result.CorrectedID = corrected.ID
+	}
+	if result.ID == "" {
+		result.ID = corrected.ID
+	}
+	return result
+}
+
+// alignWords pairs the words of two versions of a line, returning each word's
+// outcome and the number of word edits. Substituting a word costs its character
+// error rate, so a misread word pairs with its correction rather than a neighbor.
+func alignWords(original, corrected []models.HOCRWord, normalization models.NormalizationConfig) ([]models.WordAccuracy, int) {
+	m, n := len(original), len(corrected)
+	substitution := func(i, j int) float64 {
+		a, b := []rune(normalizeText(original[i].Text, normalization)), []rune(normalizeText(corrected[j].Text, normalization))
+		return min(1, errorRate(runeDistance(a, b), len(a)))
+	}
+
+	cost := make([][]float64, m+1)
+	for i := range cost {
+		cost[i] = make([]float64, n+1)
+		cost[i][0] = float64(i)
+	}
+	for j := 0; j <= n; j++ {
+		cost[0][j] = float64(j)
+	}
+	for i := 1; i <= m; i++ {
+		for j := 1; j <= n; j++ {
+			cost[i][j] = min(cost[i-1][j]+1, cost[i][j-1]+1, cost[i-1][j-1]+substitution(i-1, j-1))
+		}
+	}
+
+	var words []models.WordAccuracy
+	edits := 0
+	for i, j := m, n; i > 0 || j > 0; {
+		switch {
+		case i > 0 && j > 0 && cost[i][j] == cost[i-1][j-1]+substitution(i-1, j-1):
+			word := models.WordAccuracy{ID: original[i-1].ID, Status: models.WordCorrect,
+				Original: original[i-1].Text, Corrected: corrected[j-1].Text}
+			if substitution(i-1, j-1) > 0 {
+				word.Status = models.WordSubstituted
+				edits++
+			}
+			words = append(words, word)
+			i, j = i-1, j-1
+		case i > 0 && cost[i][j] == cost[i-1][j]+1:
+			words = append(words, models.WordAccuracy{ID: original[i-1].ID, Status: models.WordDeleted, Original: original[i-1].Text})
+			edits++
+			i--
+		default:
+			words = append(words, models.WordAccuracy{ID: corrected[j-1].ID, Status: models.WordInserted, Corrected: corrected[j-1].Text})
+			edits++
+			j--
+		}
+	}
+
+	// The backtrace runs from the end of the line
+	for left, right := 0, len(words)-1; left < right; left, right = left+1, right-1 {
+		words[left], words[right] = words[right], words[left]
+	}
+	return words, edits
+}
+
+func lineText(line models.HOCRLine) string {
+	texts := make([]string, len(line.Words))
+	for i, word := range line.Words {
+		texts[i] = word.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+// BoxOverlap is the intersection over union of two boxes
+func BoxOverlap(a, b models.BBox) float64 {
+	width := min(a.X2, b.X2) - max(a.X1, b.X1)
+	height := min(a.Y2, b.Y2) - max(a.Y1, b.Y1)
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+	intersection := width * height
+	union := (a.X2-a.X1)*(a.Y2-a.Y1) + (b.X2-b.X1)*(b.Y2-b.Y1) - intersection
+	if union <= 0 {
+		return 0
+	}
+	return float64(intersection) / float64(union)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func testLine(id string, y int, words ...string) models.HOCRLine {
+	line := models.HOCRLine{ID: id, BBox: models.BBox{X1: 0, Y1: y, X2: 500, Y2: y + 40}}
+	for i, text := range words {
+		line.Words = append(line.Words, models.HOCRWord{ID: id + "_w" + string(rune('a'+i)), Text: text, LineID: id})
+	}
+	return line
+}
+
+func TestCompareLines(t *testing.T) {
+	original := []models.HOCRLine{
+		testLine("line_1", 0, "The", "qnick", "brown", "fox"),
+		testLine("line_2", 50, "jumps", "over"),
+		testLine("line_3", 100, "smudge"),
+	}
+	corrected := []models.HOCRLine{
+		testLine("line_1", 0, "The", "quick", "fox"),
+		// Re-created by the editor with a new ID in the same place
+		testLine("line_new_1", 52, "jumps", "over"),
+		testLine("line_new_2", 200, "the", "lazy", "dog"),
+	}
+
+	lines := CompareLines(original, corrected, models.NormalizationConfig{})
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(lines))
+	}
+
+	first := lines[0]
+	statuses := []string{}
+	for _, word := range first.Words {
+		statuses = append(statuses, word.Status)
+	}
+	want := []string{models.WordCorrect, models.WordSubstituted, models.WordDeleted, models.WordCorrect}
+	if len(statuses) != len(want) {
+		t.Fatalf("line 1 statuses = %v; want %v", statuses, want)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("line 1 statuses = %v; want %v", statuses, want)
+		}
+	}
+	if !approx(first.WordErrorRate, 0.5) {
+		t.Errorf("line 1 WER = %.3f; want 0.5", first.WordErrorRate)
+	}
+
+	if lines[1].CorrectedID != "line_new_1" || lines[1].WordErrorRate != 0 {
+		t.Errorf("line 2 should match the re-created line by position: %+v", lines[1])
+	}
+	if lines[2].Corrected != "" || lines[2].WordErrorRate != 1 || lines[2].Words[0].Status != models.WordDeleted {
+		t.Errorf("line 3 should be deleted: %+v", lines[2])
+	}
+	if lines[3].ID != "line_new_2" || len(lines[3].Words) != 3 || lines[3].Words[0].Status != models.WordInserted {
+		t.Errorf("the added line should be reported as inserted: %+v", lines[3])
+	}
+}
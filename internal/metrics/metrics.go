@@ -1,15 +1,36 @@
 package metrics
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+	"golang.org/x/text/unicode/norm"
 )
 
-func CalculateAccuracyMetrics(original, transcribed string) models.EvalResult {
-	origNorm := normalizeText(original)
-	transNorm := normalizeText(transcribed)
+var whitespace = regexp.MustCompile(`\s+`)
+
+// historicalForms spells out long s and typographic ligatures
+var historicalForms = strings.NewReplacer(
+	"ſ", "s", "ẛ", "ṡ",
+	"ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "ﬅ", "st", "ﬆ", "st",
+	"æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE",
+)
+
+// ValidateNormalization rejects unknown Unicode normalization forms
+func ValidateNormalization(config models.NormalizationConfig) error {
+	switch config.Unicode {
+	case "", models.NormalizeNFC, models.NormalizeNFKC, models.NormalizeNone:
+		return nil
+	}
+	return fmt.Errorf("unknown unicode normalization: %s", config.Unicode)
+}
+
+func CalculateAccuracyMetrics(original, transcribed string, normalization models.NormalizationConfig) models.EvalResult {
+	origNorm := normalizeText(original, normalization)
+	transNorm := normalizeText(transcribed, normalization)
 	charSim := calculateSimilarity(origNorm, transNorm)
 	origWords := strings.Fields(origNorm)
 	transWords := strings.Fields(transNorm)
@@ -18,26 +39,104 @@ func CalculateAccuracyMetrics(original, transcribed string) models.EvalResult {
 
 	wer := 1.0 - wordAcc
 
+	origChars := []rune(origNorm)
+	charErrors := runeDistance(origChars, []rune(transNorm))
+	origBare := bareRunes(origNorm)
+	bareErrors := runeDistance(origBare, bareRunes(transNorm))
+
 	return models.EvalResult{
-		CharacterSimilarity:   charSim,
-		WordSimilarity:        wordSim,
-		WordAccuracy:          wordAcc,
-		WordErrorRate:         wer,
-		TotalWordsOriginal:    len(origWords),
-		TotalWordsTranscribed: len(transWords),
-		CorrectWords:          correct,
-		Substitutions:         subs,
-		Deletions:             dels,
-		Insertions:            ins,
+		CharacterSimilarity:    charSim,
+		WordSimilarity:         wordSim,
+		WordAccuracy:           wordAcc,
+		WordErrorRate:          wer,
+		CharacterErrorRate:     errorRate(charErrors, len(origChars)),
+		CharacterErrorRateBare: errorRate(bareErrors, len(origBare)),
+		TotalCharsOriginal:     len(origChars),
+		CharacterErrors:        charErrors,
+		TotalWordsOriginal:     len(origWords),
+		TotalWordsTranscribed:  len(transWords),
+		CorrectWords:           correct,
+		Substitutions:          subs,
+		Deletions:              dels,
+		Insertions:             ins,
 	}
 }
 
-func normalizeText(text string) string {
-	re := regexp.MustCompile(`\s+`)
-	text = re.ReplaceAllString(strings.TrimSpace(text), " ")
+func normalizeText(text string, config models.NormalizationConfig) string {
+	// NFC by default so a precomposed é and e plus a combining accent count as
+	// one character; NFKC also folds compatibility forms such as ﬁ and ²
+	switch config.Unicode {
+	case models.NormalizeNFKC:
+		text = norm.NFKC.String(text)
+	case models.NormalizeNone:
+	default:
+		text = norm.NFC.String(text)
+	}
+	if config.FoldHistorical {
+		text = historicalForms.Replace(text)
+	}
+	if config.StripPunctuation {
+		text = strings.Map(func(r rune) rune {
+			if unicode.IsPunct(r) {
+				return -1
+			}
+			return r
+		}, text)
+	}
+	text = whitespace.ReplaceAllString(strings.TrimSpace(text), " ")
+	if config.PreserveCase {
+		return text
+	}
 	return strings.ToLower(text)
 }
 
+// bareRunes drops whitespace and punctuation, leaving the characters OCR is
+// usually judged on
+func bareRunes(text string) []rune {
+	var runes []rune
+	for _, r := range text {
+		if !unicode.IsSpace(r) && !unicode.IsPunct(r) {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+// errorRate is edits per reference character. An empty reference has no errors
+// when the hypothesis is empty too, and is entirely wrong otherwise.
+func errorRate(edits, length int) float64 {
+	if length == 0 {
+		if edits == 0 {
+			return 0
+		}
+		return 1
+	}
+	return float64(edits) / float64(length)
+}
+
+// runeDistance is the Levenshtein distance over characters rather than bytes,
+// using two rows since only the distance is needed
+func runeDistance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
 func levenshteinDistance(s1, s2 string) int {
 	len1, len2 := len(s1), len(s2)
 	if len1 == 0 {
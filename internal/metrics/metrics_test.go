@@ -1,6 +1,10 @@
 package metrics
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
 
 func TestLevenshteinDistance(t *testing.T) {
 	tests := []struct {
@@ -37,3 +41,68 @@ func TestLevenshteinDistance(t *testing.T) {
 		}
 	}
 }
+
+func TestCharacterErrorRate(t *testing.T) {
+	tests := []struct {
+		original, transcribed string
+		cer, bare             float64
+	}{
+		{"The quick fox", "The quick fox", 0, 0},
+		{"The quick fox", "the  quick fox", 0, 0},
+		{"kitten", "sitting", 3.0 / 6, 3.0 / 6},
+		{"Hello, world!", "Hello world", 2.0 / 13, 0},
+		{"New York", "NewYork", 1.0 / 8, 0},
+		// Decomposed and precomposed accents are the same character
+		{"caf\u00e9", "cafe\u0301", 0, 0},
+		{"", "", 0, 0},
+		{"", "extra", 1, 1},
+	}
+
+	for _, tt := range tests {
+		result := CalculateAccuracyMetrics(tt.original, tt.transcribed, models.NormalizationConfig{})
+		if !approx(result.CharacterErrorRate, tt.cer) || !approx(result.CharacterErrorRateBare, tt.bare) {
+			t.Errorf("CER(%q, %q) = %.4f bare %.4f; want %.4f bare %.4f",
+				tt.original, tt.transcribed, result.CharacterErrorRate, result.CharacterErrorRateBare, tt.cer, tt.bare)
+		}
+	}
+}
+
+func TestRuneDistanceCountsCharacters(t *testing.T) {
+	if got := runeDistance([]rune("Straße"), []rune("Strasse")); got != 2 {
+		t.Errorf("runeDistance = %d; want 2", got)
+	}
+}
+
+func approx(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
+
+func TestNormalization(t *testing.T) {
+	tests := []struct {
+		name                  string
+		config                models.NormalizationConfig
+		original, transcribed string
+		cer                   float64
+	}{
+		{"case ignored by default", models.NormalizationConfig{}, "London", "LONDON", 0},
+		{"case preserved", models.NormalizationConfig{PreserveCase: true}, "London", "london", 1.0 / 6},
+		{"ligature kept under NFC", models.NormalizationConfig{}, "\ufb01nd", "find", 2.0 / 3},
+		{"ligature folded by NFKC", models.NormalizationConfig{Unicode: models.NormalizeNFKC}, "\ufb01nd", "find", 0},
+		{"decomposed accent without normalization", models.NormalizationConfig{Unicode: models.NormalizeNone}, "caf\u00e9", "cafe\u0301", 2.0 / 4},
+		{"long s kept", models.NormalizationConfig{}, "\u017fhall", "shall", 1.0 / 5},
+		{"long s folded", models.NormalizationConfig{FoldHistorical: true}, "\u017fhall", "shall", 0},
+		{"ae ligature folded", models.NormalizationConfig{FoldHistorical: true}, "medi\u00e6val", "mediaeval", 0},
+		{"punctuation stripped", models.NormalizationConfig{StripPunctuation: true}, "Yours, &c.", "Yours &c", 0},
+	}
+
+	for _, tt := range tests {
+		result := CalculateAccuracyMetrics(tt.original, tt.transcribed, tt.config)
+		if !approx(result.CharacterErrorRate, tt.cer) {
+			t.Errorf("%s: CER = %.4f; want %.4f", tt.name, result.CharacterErrorRate, tt.cer)
+		}
+	}
+
+	if err := ValidateNormalization(models.NormalizationConfig{Unicode: "nfd"}); err == nil {
+		t.Error("expected an error for an unknown normalization form")
+	}
+}
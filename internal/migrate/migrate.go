@@ -0,0 +1,221 @@
+// Package migrate applies versioned upgrades to on-disk state (the session
+// store, cache layout and artifact naming) at startup.
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const stateFile = "data/schema.json"
+
+// Migration moves on-disk state from Version-1 to Version. Down reverses it and
+// may be nil when there is nothing to undo.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx *Context) error
+	Down    func(ctx *Context) error
+}
+
+// Applied records a migration that has run, along with any notes it left for its rollback
+type Applied struct {
+	Version   int               `json:"version"`
+	Name      string            `json:"name"`
+	AppliedAt time.Time         `json:"applied_at"`
+	Notes     map[string]string `json:"notes,omitempty"`
+}
+
+// State is the persisted schema version of a deployment
+type State struct {
+	Version int       `json:"version"`
+	Applied []Applied `json:"applied"`
+}
+
+// Context gives migrations file operations that honor dry-run mode.
+// All paths are relative to Root, unless they start with a directory in Dirs.
+type Context struct {
+	Root   string
+	DryRun bool
+	// Dirs relocates directories, such as "uploads" or "data/sessions", kept
+	// outside Root; the longest one a path starts with wins
+	Dirs map[string]string
+	// Notes are kept with the applied migration so Down can undo exactly what Up did
+	Notes map[string]string
+}
+
+func (c *Context) Path(rel string) string {
+	rel = filepath.ToSlash(rel)
+	prefix := ""
+	for dir := range c.Dirs {
+		if (rel == dir || strings.HasPrefix(rel, dir+"/")) && len(dir) > len(prefix) {
+			prefix = dir
+		}
+	}
+	if prefix != "" {
+		return filepath.Join(c.Dirs[prefix], filepath.FromSlash(strings.TrimPrefix(rel[len(prefix):], "/")))
+	}
+	return filepath.Join(c.Root, filepath.FromSlash(rel))
+}
+
+func (c *Context) MkdirAll(rel string) error {
+	if c.DryRun {
+		slog.Info("Migration would create directory", "path", rel)
+		return nil
+	}
+	return os.MkdirAll(c.Path(rel), 0755)
+}
+
+func (c *Context) Rename(from, to string) error {
+	if c.DryRun {
+		slog.Info("Migration would rename", "from", from, "to", to)
+		return nil
+	}
+	return os.Rename(c.Path(from), c.Path(to))
+}
+
+func (c *Context) WriteFile(rel string, data []byte) error {
+	if c.DryRun {
+		slog.Info("Migration would rewrite file", "path", rel)
+		return nil
+	}
+	return os.WriteFile(c.Path(rel), data, 0644)
+}
+
+// LoadState reads the schema state, treating a missing file as version 0
+func LoadState(root string) (*State, error) {
+	return loadState(filepath.Join(root, stateFile))
+}
+
+// statePath is where the schema state is kept, following a relocated data directory
+func statePath(root string, dirs map[string]string) string {
+	return (&Context{Root: root, Dirs: dirs}).Path(stateFile)
+}
+
+func loadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", stateFile, err)
+	}
+	return &state, nil
+}
+
+func saveState(path string, state *State) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Latest is the highest version in a set of migrations
+func Latest(migrations []Migration) int {
+	latest := 0
+	for _, m := range migrations {
+		latest = max(latest, m.Version)
+	}
+	return latest
+}
+
+// Run brings the deployment at root to the target version, applying Up
+// migrations in order or rolling back with Down in reverse. State is saved after
+// every step so a failure leaves an accurate record of what ran.
+func Run(root string, dirs map[string]string, migrations []Migration, target int, dryRun bool) error {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i := range sorted {
+		if sorted[i].Version != i+1 {
+			return fmt.Errorf("migration versions must be sequential from 1, found %d at position %d", sorted[i].Version, i+1)
+		}
+	}
+
+	if target < 0 || target > len(sorted) {
+		return fmt.Errorf("unknown target version %d (latest is %d)", target, len(sorted))
+	}
+
+	path := statePath(root, dirs)
+	state, err := loadState(path)
+	if err != nil {
+		return err
+	}
+	if state.Version > len(sorted) {
+		return fmt.Errorf("schema version %d is newer than this build supports (%d)", state.Version, len(sorted))
+	}
+
+	// In a dry run state is never saved, so track the version separately
+	version := state.Version
+
+	for version < target {
+		m := sorted[version]
+		ctx := &Context{Root: root, DryRun: dryRun, Dirs: dirs, Notes: map[string]string{}}
+
+		slog.Info("Applying migration", "version", m.Version, "name", m.Name, "dry_run", dryRun)
+		if err := m.Up(ctx); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		version = m.Version
+		if dryRun {
+			continue
+		}
+
+		state.Version = m.Version
+		state.Applied = append(state.Applied, Applied{Version: m.Version, Name: m.Name, AppliedAt: time.Now(), Notes: ctx.Notes})
+		if err := saveState(path, state); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
+	}
+
+	for version > target {
+		m := sorted[version-1]
+		ctx := &Context{Root: root, DryRun: dryRun, Dirs: dirs, Notes: map[string]string{}}
+		for _, applied := range state.Applied {
+			if applied.Version == m.Version && applied.Notes != nil {
+				ctx.Notes = applied.Notes
+			}
+		}
+
+		slog.Info("Rolling back migration", "version", m.Version, "name", m.Name, "dry_run", dryRun)
+		if m.Down != nil {
+			if err := m.Down(ctx); err != nil {
+				return fmt.Errorf("rollback of migration %d (%s) failed: %w", m.Version, m.Name, err)
+			}
+		}
+		version = m.Version - 1
+		if dryRun {
+			continue
+		}
+
+		state.Version = version
+		state.Applied = slices.DeleteFunc(state.Applied, func(a Applied) bool { return a.Version == m.Version })
+		if err := saveState(path, state); err != nil {
+			return fmt.Errorf("failed to record rollback of migration %d: %w", m.Version, err)
+		}
+	}
+
+	return nil
+}
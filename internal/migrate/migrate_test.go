@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunUpDryRunAndRollback(t *testing.T) {
+	root := t.TempDir()
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0, 0}
+	writeFile(t, filepath.Join(root, "uploads/abc.tif"), jpeg)
+	writeFile(t, filepath.Join(root, "uploads/real.tif"), []byte("II*\x00"))
+	writeFile(t, filepath.Join(root, "data/sessions/s1.json"), []byte(`{"session":{"images":[{"image_path":"abc.tif"}]}}`))
+
+	// Dry run changes nothing and records nothing
+	if err := Run(root, nil, All(), Latest(All()), true); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "uploads/abc.tif")); err != nil {
+		t.Fatalf("dry run renamed upload: %v", err)
+	}
+	if state, _ := LoadState(root); state.Version != 0 {
+		t.Fatalf("dry run recorded version %d", state.Version)
+	}
+
+	if err := Run(root, nil, All(), Latest(All()), false); err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "uploads/abc.jpg")); err != nil {
+		t.Errorf("converted upload not renamed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "uploads/real.tif")); err != nil {
+		t.Errorf("genuine TIFF was renamed: %v", err)
+	}
+	session, _ := os.ReadFile(filepath.Join(root, "data/sessions/s1.json"))
+	if !strings.Contains(string(session), "abc.jpg") {
+		t.Errorf("session reference not updated: %s", session)
+	}
+
+	// Running again is a no-op
+	if err := Run(root, nil, All(), Latest(All()), false); err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+
+	if err := Run(root, nil, All(), 1, false); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "uploads/abc.tif")); err != nil {
+		t.Errorf("rollback did not restore upload name: %v", err)
+	}
+	session, _ = os.ReadFile(filepath.Join(root, "data/sessions/s1.json"))
+	if !strings.Contains(string(session), "abc.tif") {
+		t.Errorf("rollback did not restore session reference: %s", session)
+	}
+	if state, _ := LoadState(root); state.Version != 1 || len(state.Applied) != 1 {
+		t.Errorf("state after rollback = %+v", state)
+	}
+}
+
+func TestRunRewritesRelocatedSessions(t *testing.T) {
+	root, sessions := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(root, "uploads/abc.tif"), []byte{0xFF, 0xD8, 0xFF, 0xE0, 0, 0})
+	writeFile(t, filepath.Join(sessions, "s1.json"), []byte(`{"session":{"images":[{"image_path":"abc.tif"}]}}`))
+
+	if err := Run(root, map[string]string{"data/sessions": sessions}, All(), Latest(All()), false); err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+	session, _ := os.ReadFile(filepath.Join(sessions, "s1.json"))
+	if !strings.Contains(string(session), "abc.jpg") {
+		t.Errorf("relocated session reference not updated: %s", session)
+	}
+}
+
+func TestRunRejectsNewerSchema(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, stateFile), []byte(`{"version": 99}`))
+
+	if err := Run(root, nil, All(), Latest(All()), false); err == nil {
+		t.Error("expected error for schema newer than build")
+	}
+}
+
+func TestContextPathDirs(t *testing.T) {
+	ctx := &Context{Root: "/srv/hocredit", Dirs: map[string]string{
+		"uploads":       "/data/uploads",
+		"data":          "/var/lib/hocredit",
+		"data/sessions": "/mnt/sessions",
+	}}
+	tests := map[string]string{
+		"uploads":                "/data/uploads",
+		"uploads/abc.jpg":        "/data/uploads/abc.jpg",
+		"uploads-old/abc.jpg":    "/srv/hocredit/uploads-old/abc.jpg",
+		"data/schema.json":       "/var/lib/hocredit/schema.json",
+		"data/sessions":          "/mnt/sessions",
+		"data/sessions/s1.json":  "/mnt/sessions/s1.json",
+		"data/sessions-old/x.js": "/var/lib/hocredit/sessions-old/x.js",
+	}
+	for rel, want := range tests {
+		if got := ctx.Path(rel); got != want {
+			t.Errorf("Path(%q) = %q, want %q", rel, got, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package migrate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// All lists every migration in version order. Append new ones; never renumber
+// or edit one that has shipped.
+func All() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Name:    "create_data_layout",
+			Up:      createDataLayout,
+		},
+		{
+			Version: 2,
+			Name:    "rename_converted_uploads",
+			Up:      renameConvertedUploads,
+			Down:    restoreConvertedUploads,
+		},
+	}
+}
+
+// createDataLayout creates the directories the server writes to, including the
+// persistent session store
+func createDataLayout(ctx *Context) error {
+	for _, dir := range []string{"uploads", "cache", "archive", "data/sessions"} {
+		if err := ctx.MkdirAll(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertedExtensions are source extensions older releases kept on files that
+// had already been converted to JPEG via Houdini
+var convertedExtensions = map[string]bool{
+	".tif": true, ".tiff": true, ".jp2": true, ".jpx": true, ".j2k": true,
+}
+
+// renameConvertedUploads gives converted uploads a .jpg extension so browsers
+// and the tiler recognize them, updating every reference to the old name
+func renameConvertedUploads(ctx *Context) error {
+	entries, err := os.ReadDir(ctx.Path("uploads"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	renames := map[string]string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if entry.IsDir() || !convertedExtensions[ext] || !isJPEG(ctx.Path(filepath.Join("uploads", name))) {
+			continue
+		}
+
+		newName := strings.TrimSuffix(name, filepath.Ext(name)) + ".jpg"
+		if _, err := os.Stat(ctx.Path(filepath.Join("uploads", newName))); err == nil {
+			continue
+		}
+		if err := ctx.Rename(filepath.Join("uploads", name), filepath.Join("uploads", newName)); err != nil {
+			return err
+		}
+		renames[name] = newName
+		ctx.Notes[name] = newName
+	}
+
+	return rewriteReferences(ctx, renames)
+}
+
+func restoreConvertedUploads(ctx *Context) error {
+	renames := map[string]string{}
+	for oldName, newName := range ctx.Notes {
+		if err := ctx.Rename(filepath.Join("uploads", newName), filepath.Join("uploads", oldName)); err != nil {
+			return err
+		}
+		renames[newName] = oldName
+	}
+
+	return rewriteReferences(ctx, renames)
+}
+
+// rewriteReferences updates stored sessions and the prefetch index. Upload names
+// are content hashes, so a plain substitution can't hit anything else.
+func rewriteReferences(ctx *Context, renames map[string]string) error {
+	if len(renames) == 0 {
+		return nil
+	}
+
+	for _, dir := range []string{"data/sessions", "cache/prefetch"} {
+		files, err := filepath.Glob(filepath.Join(ctx.Path(dir), "*.json"))
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			updated := data
+			for from, to := range renames {
+				updated = bytes.ReplaceAll(updated, []byte(from), []byte(to))
+			}
+			if bytes.Equal(updated, data) {
+				continue
+			}
+
+			if err := ctx.WriteFile(dir+"/"+filepath.Base(file), updated); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func isJPEG(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, 3)
+	if _, err := f.Read(header); err != nil {
+		return false
+	}
+	return bytes.Equal(header, []byte{0xFF, 0xD8, 0xFF})
+}
@@ -3,59 +3,305 @@ package models
 import "time"
 
 type EvalConfig struct {
-	Model       string  `json:"model"`
-	Prompt      string  `json:"prompt"`
-	Temperature float64 `json:"temperature"`
-	CSVPath     string  `json:"csv_path"`
-	TestRows    []int   `json:"rows"`
-	Timestamp   string  `json:"timestamp"`
+	Model        string             `json:"model"`
+	Prompt       string             `json:"prompt"`
+	Temperature  float64            `json:"temperature"`
+	CSVPath      string             `json:"csv_path"`
+	TestRows     []int              `json:"rows"`
+	Timestamp    string             `json:"timestamp"`
+	Engine       string             `json:"engine,omitempty"`
+	Binarization BinarizationConfig `json:"binarization"`
+	// Language is what the text is in, as Tesseract language codes such as
+	// "deu+lat"; empty when it isn't known
+	Language string `json:"language,omitempty"`
+	// DocumentType is how the pages were printed, such as "fraktur"; empty for
+	// modern type
+	DocumentType string `json:"document_type,omitempty"`
+	// Characters limits what characters the text may hold
+	Characters CharacterConfig `json:"characters"`
+	// LLM overrides how the LLM engine is asked, for a branch trying another
+	// model or prompt. Model, Prompt and Temperature above describe where the
+	// transcription came from and aren't sent.
+	LLM LLMConfig `json:"llm"`
+	// Normalization applies to the text before it's scored
+	Normalization NormalizationConfig `json:"normalization"`
+}
+
+// Binarization methods used when preprocessing images for word detection
+const (
+	BinarizeFixed   = "fixed"
+	BinarizeOtsu    = "otsu"
+	BinarizeSauvola = "sauvola"
+)
+
+// BinarizationConfig selects how the preprocessed image is thresholded.
+// The zero value keeps the original fixed 75% threshold.
+type BinarizationConfig struct {
+	Method     string  `json:"method,omitempty"`
+	Threshold  float64 `json:"threshold,omitempty"`
+	WindowSize int     `json:"window_size,omitempty"`
+	K          float64 `json:"k,omitempty"`
+}
+
+// CharacterConfig limits the characters of a transcription, such as to
+// digits for a ledger or away from Cyrillic look-alikes. Each is literal
+// characters and sets named in braces, as hocr.ParseCharset reads them. The
+// zero value allows every character.
+type CharacterConfig struct {
+	Allowed   string `json:"allowed,omitempty"`
+	Forbidden string `json:"forbidden,omitempty"`
+}
+
+// LLMConfig overrides the model, prompt and temperature the LLM engine is
+// asked with. The zero value uses the deployment's model, the built-in prompt
+// and the API's default temperature.
+type LLMConfig struct {
+	Model       string  `json:"model,omitempty"`
+	Prompt      string  `json:"prompt,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+// Unicode normalization forms applied before texts are compared
+const (
+	NormalizeNFC  = "nfc"
+	NormalizeNFKC = "nfkc"
+	NormalizeNone = "none"
+)
+
+// NormalizationConfig selects how texts are folded before they're compared.
+// The zero value applies NFC and ignores case.
+type NormalizationConfig struct {
+	Unicode          string `json:"unicode,omitempty"`
+	PreserveCase     bool   `json:"preserve_case,omitempty"`
+	StripPunctuation bool   `json:"strip_punctuation,omitempty"`
+	// FoldHistorical reads long s as s and splits typographic ligatures such as
+	// ﬁ and æ, for ground truth that modernizes historical orthography
+	FoldHistorical bool `json:"fold_historical,omitempty"`
 }
 
 type EvalResult struct {
-	Identifier            string  `json:"identifier"`
-	ImagePath             string  `json:"image_path"`
-	TranscriptPath        string  `json:"transcript_path"`
-	Public                bool    `json:"public"`
-	OpenAIResponse        string  `json:"openai_response"`
-	CharacterSimilarity   float64 `json:"character_similarity"`
-	WordSimilarity        float64 `json:"word_similarity"`
-	WordAccuracy          float64 `json:"word_accuracy"`
-	WordErrorRate         float64 `json:"word_error_rate"`
-	TotalWordsOriginal    int     `json:"total_words_original"`
-	TotalWordsTranscribed int     `json:"total_words_transcribed"`
-	CorrectWords          int     `json:"correct_words"`
-	Substitutions         int     `json:"substitutions"`
-	Deletions             int     `json:"deletions"`
-	Insertions            int     `json:"insertions"`
+	Identifier          string  `json:"identifier"`
+	ImagePath           string  `json:"image_path"`
+	TranscriptPath      string  `json:"transcript_path"`
+	Public              bool    `json:"public"`
+	OpenAIResponse      string  `json:"openai_response"`
+	CharacterSimilarity float64 `json:"character_similarity"`
+	WordSimilarity      float64 `json:"word_similarity"`
+	WordAccuracy        float64 `json:"word_accuracy"`
+	WordErrorRate       float64 `json:"word_error_rate"`
+	// CharacterErrorRate counts character edits, including spaces, against the
+	// original's length; the bare variant ignores whitespace and punctuation
+	CharacterErrorRate     float64 `json:"character_error_rate"`
+	CharacterErrorRateBare float64 `json:"character_error_rate_bare"`
+	TotalCharsOriginal     int     `json:"total_chars_original"`
+	CharacterErrors        int     `json:"character_errors"`
+	TotalWordsOriginal     int     `json:"total_words_original"`
+	TotalWordsTranscribed  int     `json:"total_words_transcribed"`
+	CorrectWords           int     `json:"correct_words"`
+	Substitutions          int     `json:"substitutions"`
+	Deletions              int     `json:"deletions"`
+	Insertions             int     `json:"insertions"`
 }
 
 type CorrectionSession struct {
-	ID        string       `json:"id"`
-	Images    []ImageItem  `json:"images"`
-	Current   int          `json:"current"`
-	Results   []EvalResult `json:"results"`
-	Config    EvalConfig   `json:"config"`
-	CreatedAt time.Time    `json:"created_at"`
+	ID         string       `json:"id"`
+	Images     []ImageItem  `json:"images"`
+	Current    int          `json:"current"`
+	Results    []EvalResult `json:"results"`
+	Config     EvalConfig   `json:"config"`
+	Rights     Rights       `json:"rights"`
+	Collection string       `json:"collection,omitempty"`
+	ParentID   string       `json:"parent_id,omitempty"`
+	Callback   *Callback    `json:"callback,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// Callback is a URL notified once every image of a session is completed.
+// SentAt is cleared when an image is reopened, so finishing again notifies again.
+type Callback struct {
+	URL    string     `json:"url"`
+	SentAt *time.Time `json:"sent_at,omitempty"`
+}
+
+// Access levels for archival material
+const (
+	AccessPublic     = "public"
+	AccessCampus     = "campus"
+	AccessRestricted = "restricted"
+)
+
+// Rights describes how a session or image may be viewed and published.
+// Empty fields on an image inherit the session's values.
+type Rights struct {
+	Statement    string     `json:"statement,omitempty"`
+	EmbargoUntil *time.Time `json:"embargo_until,omitempty"`
+	AccessLevel  string     `json:"access_level,omitempty"`
 }
 
 type ImageItem struct {
-	ID              string `json:"id"`
-	ImagePath       string `json:"image_path"`
-	ImageURL        string `json:"image_url"`
-	OriginalHOCR    string `json:"original_hocr"`
-	CorrectedHOCR   string `json:"corrected_hocr"`
-	GroundTruth     string `json:"ground_truth"`
-	Completed       bool   `json:"completed"`
-	ImageWidth      int    `json:"image_width"`
-	ImageHeight     int    `json:"image_height"`
-	DrupalUploadURL string `json:"drupal_upload_url,omitempty"`
-	DrupalNid       string `json:"drupal_nid,omitempty"`
+	ID              string          `json:"id"`
+	ImagePath       string          `json:"image_path"`
+	ImageURL        string          `json:"image_url"`
+	OriginalHOCR    string          `json:"original_hocr"`
+	CorrectedHOCR   string          `json:"corrected_hocr"`
+	GroundTruth     string          `json:"ground_truth"`
+	Completed       bool            `json:"completed"`
+	ImageWidth      int             `json:"image_width"`
+	ImageHeight     int             `json:"image_height"`
+	DrupalUploadURL string          `json:"drupal_upload_url,omitempty"`
+	DrupalNid       string          `json:"drupal_nid,omitempty"`
+	PublishedAt     *time.Time      `json:"published_at,omitempty"`
+	Rights          *Rights         `json:"rights,omitempty"`
+	Annotations     []Annotation    `json:"annotations,omitempty"`
+	IIIF            *IIIFSource     `json:"iiif,omitempty"`
+	LineOrder       *LineOrderCheck `json:"line_order,omitempty"`
+
+	// DrupalMediaPending marks a page whose node has no hOCR media yet; the
+	// first publish creates it
+	DrupalMediaPending bool `json:"drupal_media_pending,omitempty"`
+	// DrupalMediaURL is the hOCR media entity, and DrupalRevisionID the revision
+	// the last publish saved of it
+	DrupalMediaURL   string `json:"drupal_media_url,omitempty"`
+	DrupalRevisionID string `json:"drupal_revision_id,omitempty"`
+	// DrupalSync tracks whether the corrected hOCR has made it back to Drupal
+	DrupalSync *DrupalSync `json:"drupal_sync,omitempty"`
+	// Repository is where the image was fetched from, when that was Fedora or OCFL
+	Repository *RepositorySource `json:"repository,omitempty"`
+}
+
+// Drupal sync states of an image's hOCR
+const (
+	SyncPending  = "pending"
+	SyncUploaded = "uploaded"
+	SyncFailed   = "failed"
+)
+
+// DrupalSync is the outcome of the last upload of an image's hOCR to Drupal.
+// Corrections saved after an upload put it back to pending.
+type DrupalSync struct {
+	State       string     `json:"state"`
+	Attempts    int        `json:"attempts"`
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	// Response is Drupal's status line, with the body when it refused the upload
+	Response string `json:"response,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// JobID is the failed job holding the diagnostics of the last failure
+	JobID string `json:"job_id,omitempty"`
+}
+
+// RepositorySource identifies a page image in a Fedora or OCFL repository
+type RepositorySource struct {
+	Kind   string `json:"kind"`
+	Object string `json:"object"`
+	Path   string `json:"path"`
+}
+
+// LineOrderCheck records how plausible the OCR output's line order looked to the
+// local language model. Flagged pages are listed in the QA queue.
+type LineOrderCheck struct {
+	Lines        int       `json:"lines"`
+	Plausibility float64   `json:"plausibility"`
+	Flagged      bool      `json:"flagged"`
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+// IIIFSource records where an image was fetched from via the IIIF Image API, so
+// hOCR coordinates can be mapped back onto the canvas it was painted on
+type IIIFSource struct {
+	ImageService string `json:"image_service,omitempty"`
+	Version      int    `json:"version,omitempty"`
+	CanvasID     string `json:"canvas_id,omitempty"`
+	CanvasWidth  int    `json:"canvas_width,omitempty"`
+	CanvasHeight int    `json:"canvas_height,omitempty"`
+}
+
+// Annotation links a run of words to an authority record
+type Annotation struct {
+	ID        string    `json:"id"`
+	WordIDs   []string  `json:"word_ids"`
+	Text      string    `json:"text"`
+	Source    string    `json:"source"`
+	URI       string    `json:"uri"`
+	Label     string    `json:"label"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Macro scopes control who can see and run a correction macro
+const (
+	MacroScopeGlobal     = "global"
+	MacroScopeUser       = "user"
+	MacroScopeCollection = "collection"
+)
+
+// Macro step types
+const (
+	MacroStepReplace     = "replace"
+	MacroStepRegex       = "regex"
+	MacroStepDelete      = "delete"
+	MacroStepJoinHyphens = "join_hyphens"
+)
+
+// CorrectionMacro is a named, reusable sequence of word-level corrections
+type CorrectionMacro struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Scope       string      `json:"scope"`
+	Owner       string      `json:"owner,omitempty"`
+	Steps       []MacroStep `json:"steps"`
+	CreatedBy   string      `json:"created_by,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// MacroStep is a single operation. Pattern and Replacement are literal strings for
+// replace steps and regular expressions for regex and delete steps.
+type MacroStep struct {
+	Type        string `json:"type"`
+	Pattern     string `json:"pattern,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Word alignment outcomes, relative to the original OCR
+const (
+	WordCorrect     = "correct"
+	WordSubstituted = "substituted"
+	WordDeleted     = "deleted"
+	WordInserted    = "inserted"
+)
+
+// WordAccuracy is how one word of the original OCR fared in the correction.
+// Inserted words exist only in the correction and carry its ID.
+type WordAccuracy struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	Original  string `json:"original,omitempty"`
+	Corrected string `json:"corrected,omitempty"`
+}
+
+// LineAccuracy compares one line of the original OCR with the corrected line it
+// was aligned to
+type LineAccuracy struct {
+	ID                 string         `json:"id"`
+	CorrectedID        string         `json:"corrected_id,omitempty"`
+	BBox               BBox           `json:"bbox"`
+	Original           string         `json:"original"`
+	Corrected          string         `json:"corrected"`
+	WordErrorRate      float64        `json:"word_error_rate"`
+	CharacterErrorRate float64        `json:"character_error_rate"`
+	Words              []WordAccuracy `json:"words"`
 }
 
 type HOCRLine struct {
 	ID    string     `json:"id"`
 	BBox  BBox       `json:"bbox"`
 	Words []HOCRWord `json:"words"`
+	// TextAngle is the hOCR textangle of the line, in degrees counterclockwise;
+	// vertically set lines carry 90
+	TextAngle int `json:"textangle,omitempty"`
+	// Language is the BCP 47 tag of the language the line is in, when it was
+	// detected or marked; otherwise it's in the document's
+	Language string `json:"language,omitempty"`
 }
 
 type HOCRWord struct {
@@ -64,6 +310,15 @@ type HOCRWord struct {
 	BBox       BBox    `json:"bbox"`
 	Confidence float64 `json:"confidence"`
 	LineID     string  `json:"line_id"`
+	// Hyphenated is set on the last word of a line when it's the first part
+	// of a word broken across the line end, hyphen and all
+	Hyphenated bool `json:"hyphenated,omitempty"`
+	// AutoCorrected is what the engine read, when post-correction replaced it
+	// with a dictionary word a reviewer hasn't yet confirmed
+	AutoCorrected string `json:"auto_corrected,omitempty"`
+	// Language is the BCP 47 tag of the language the word is in, when it was
+	// detected or marked; otherwise it's in its line's
+	Language string `json:"language,omitempty"`
 }
 
 type BBox struct {
@@ -95,14 +350,18 @@ type Page struct {
 }
 
 type Block struct {
+	Property    *Property    `json:"property,omitempty"`
 	BoundingBox BoundingPoly `json:"boundingBox"`
 	Paragraphs  []Paragraph  `json:"paragraphs"`
 	BlockType   string       `json:"blockType"`
 }
 
 type Paragraph struct {
+	Property    *Property    `json:"property,omitempty"`
 	BoundingBox BoundingPoly `json:"boundingBox"`
 	Words       []Word       `json:"words"`
+	// TextAngle is 90 for a paragraph set vertically, read top to bottom
+	TextAngle int `json:"textAngle,omitempty"`
 }
 
 type Word struct {
@@ -134,3 +393,61 @@ type Vertex struct {
 	X int `json:"x"`
 	Y int `json:"y"`
 }
+
+// External OCR job states
+const (
+	ExternalJobPending   = "pending"
+	ExternalJobCompleted = "completed"
+	ExternalJobFailed    = "failed"
+)
+
+// PrefetchRun tracks a background cache warm-up
+type PrefetchRun struct {
+	ID         string     `json:"id"`
+	Total      int        `json:"total"`
+	Completed  int        `json:"completed"`
+	Failed     int        `json:"failed"`
+	Errors     []string   `json:"errors,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// ExternalJob tracks OCR running on an asynchronous remote engine until its
+// completion webhook arrives
+type ExternalJob struct {
+	ID          string     `json:"id"`
+	SessionID   string     `json:"session_id"`
+	ImageID     string     `json:"image_id"`
+	Engine      string     `json:"engine"`
+	RemoteID    string     `json:"remote_id,omitempty"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Background job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job tracks work accepted by the server and run in the background, such as
+// processing an upload into a session. Retries counts the API calls, such as
+// to the LLM, it had to retry.
+type Job struct {
+	ID          string     `json:"id"`
+	Kind        string     `json:"kind"`
+	Status      string     `json:"status"`
+	User        string     `json:"user,omitempty"`
+	SessionID   string     `json:"session_id,omitempty"`
+	Result      any        `json:"result,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	Diagnostics string     `json:"diagnostics,omitempty"`
+	Retries     int        `json:"retries,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,168 @@
+// Package notify tells operators when background work fails, pointing them at the
+// diagnostic bundle collected for it.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/webhook"
+)
+
+// Failure describes a job that failed and where its diagnostic bundle is kept
+type Failure struct {
+	JobID          string    `json:"job_id"`
+	Kind           string    `json:"kind"`
+	SessionID      string    `json:"session_id,omitempty"`
+	User           string    `json:"user,omitempty"`
+	Error          string    `json:"error"`
+	DiagnosticsURL string    `json:"diagnostics_url"`
+	FailedAt       time.Time `json:"failed_at"`
+}
+
+// Notifier delivers failure notifications to one destination
+type Notifier interface {
+	Name() string
+	Notify(failure Failure) error
+}
+
+type Service struct {
+	notifiers []Notifier
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// NewService enables the notifiers listed in FAILURE_NOTIFIERS (default "log").
+// Notifiers that are listed but not configured are skipped with a warning.
+func NewService() *Service {
+	s := &Service{}
+
+	names := os.Getenv("FAILURE_NOTIFIERS")
+	if names == "" {
+		names = "log"
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		var notifier Notifier
+		switch name {
+		case "":
+			continue
+		case "log":
+			notifier = Log{}
+		case "webhook":
+			url := os.Getenv("FAILURE_WEBHOOK_URL")
+			if url == "" {
+				slog.Warn("Webhook failure notifier requires FAILURE_WEBHOOK_URL, skipping")
+				continue
+			}
+			notifier = &Webhook{URL: url, Secret: os.Getenv("FAILURE_WEBHOOK_SECRET")}
+		case "slack":
+			url := os.Getenv("SLACK_WEBHOOK_URL")
+			if url == "" {
+				slog.Warn("Slack failure notifier requires SLACK_WEBHOOK_URL, skipping")
+				continue
+			}
+			notifier = &Slack{URL: url}
+		default:
+			slog.Warn("Unknown failure notifier", "name", name)
+			continue
+		}
+
+		s.notifiers = append(s.notifiers, notifier)
+	}
+
+	return s
+}
+
+// Notify sends the failure to every notifier; delivery problems are only logged
+// so they never mask the failure being reported
+func (s *Service) Notify(failure Failure) {
+	for _, notifier := range s.notifiers {
+		if err := notifier.Notify(failure); err != nil {
+			slog.Warn("Failure notification not delivered", "notifier", notifier.Name(), "job_id", failure.JobID, "err", err)
+		}
+	}
+}
+
+// Log writes the failure to the server log
+type Log struct{}
+
+func (Log) Name() string { return "log" }
+
+func (Log) Notify(failure Failure) error {
+	slog.Error("Job failed", "job_id", failure.JobID, "kind", failure.Kind, "session_id", failure.SessionID,
+		"err", failure.Error, "diagnostics", failure.DiagnosticsURL)
+	return nil
+}
+
+// Webhook posts the failure as JSON, signed like the OCR completion webhooks when
+// a secret is set
+type Webhook struct {
+	URL    string
+	Secret string
+}
+
+func (n *Webhook) Name() string { return "webhook" }
+
+func (n *Webhook) Notify(failure Failure) error {
+	body, err := json.Marshal(failure)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.Secret != "" {
+		now := time.Now()
+		req.Header.Set(webhook.TimestampHeader, fmt.Sprintf("%d", now.Unix()))
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(n.Secret, now, body))
+	}
+
+	return post(req)
+}
+
+// Slack posts a short message to an incoming webhook
+type Slack struct {
+	URL string
+}
+
+func (n *Slack) Name() string { return "slack" }
+
+func (n *Slack) Notify(failure Failure) error {
+	text := fmt.Sprintf("hOCRedit %s job %s failed: %s\nDiagnostics: %s", failure.Kind, failure.JobID, failure.Error, failure.DiagnosticsURL)
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return post(req)
+}
+
+func post(req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/webhook"
+)
+
+func TestWebhookSignsFailure(t *testing.T) {
+	var received Failure
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify("secret", r.Header.Get(webhook.SignatureHeader), r.Header.Get(webhook.TimestampHeader), body, time.Now()); err != nil {
+			t.Errorf("signature did not verify: %v", err)
+		}
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("invalid body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	failure := Failure{JobID: "job_1", Kind: "upload_url", Error: "boom", DiagnosticsURL: "/api/jobs/job_1/diagnostics"}
+	if err := (&Webhook{URL: server.URL, Secret: "secret"}).Notify(failure); err != nil {
+		t.Fatal(err)
+	}
+	if received.JobID != "job_1" || received.DiagnosticsURL != failure.DiagnosticsURL {
+		t.Errorf("unexpected payload: %+v", received)
+	}
+}
+
+func TestNewServiceSkipsUnconfigured(t *testing.T) {
+	t.Setenv("FAILURE_NOTIFIERS", "log, webhook, slack, pager")
+	t.Setenv("FAILURE_WEBHOOK_URL", "")
+	t.Setenv("SLACK_WEBHOOK_URL", "https://hooks.example.com/x")
+
+	s := NewService()
+	if len(s.notifiers) != 2 || s.notifiers[0].Name() != "log" || s.notifiers[1].Name() != "slack" {
+		t.Errorf("unexpected notifiers: %v", s.notifiers)
+	}
+}
+
+func TestWebhookReportsHTTPErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := (&Webhook{URL: server.URL}).Notify(Failure{JobID: "job_1"}); err == nil {
+		t.Error("expected an error for HTTP 500")
+	}
+}
@@ -0,0 +1,78 @@
+package openapi
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type node struct {
+	base
+	Name     string    `json:"name,omitempty"`
+	Created  time.Time `json:"created"`
+	Parent   *node     `json:"parent"`
+	Children []node    `json:"children,omitempty"`
+	Score    *float64  `json:"score"`
+	Hidden   string    `json:"-"`
+	File     []byte    `json:"file" openapi:"binary"`
+}
+
+func TestSchemaForStruct(t *testing.T) {
+	builder := New(Info{Title: "test", Version: "1"})
+	schema := builder.SchemaFor(node{})
+	if schema.Ref != "#/components/schemas/node" {
+		t.Fatalf("expected a component reference, got %+v", schema)
+	}
+
+	component := builder.Document().Components.Schemas["node"]
+	if component == nil {
+		t.Fatal("node was not registered as a component")
+	}
+	if _, ok := component.Properties["id"]; !ok {
+		t.Error("embedded struct fields should be flattened")
+	}
+	if _, ok := component.Properties["Hidden"]; ok {
+		t.Error("fields tagged json:\"-\" should be skipped")
+	}
+	if got := component.Properties["created"]; got.Type != "string" || got.Format != "date-time" {
+		t.Errorf("time.Time should be a date-time string, got %+v", got)
+	}
+	if got := component.Properties["parent"]; got.Ref != "#/components/schemas/node" {
+		t.Errorf("recursive pointer should reference the component, got %+v", got)
+	}
+	if got := component.Properties["children"]; got.Type != "array" || got.Items.Ref != "#/components/schemas/node" {
+		t.Errorf("slice should be an array of references, got %+v", got)
+	}
+	if got := component.Properties["score"]; got.Type != "number" || !got.Nullable {
+		t.Errorf("pointer to float should be a nullable number, got %+v", got)
+	}
+	if got := component.Properties["file"]; got.Type != "string" || got.Format != "binary" {
+		t.Errorf("binary tag should produce a binary string, got %+v", got)
+	}
+
+	for _, name := range []string{"id", "created", "file"} {
+		if !slices.Contains(component.Required, name) {
+			t.Errorf("%s should be required, required = %v", name, component.Required)
+		}
+	}
+	for _, name := range []string{"name", "children", "parent", "score"} {
+		if slices.Contains(component.Required, name) {
+			t.Errorf("%s should be optional", name)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	builder := New(Info{Title: "test", Version: "1"})
+	builder.Add("GET", "/items", &Operation{OperationID: "listItems"})
+	builder.Add("POST", "/items", &Operation{OperationID: "createItem"})
+
+	item := builder.Document().Paths["/items"]
+	if item["get"].OperationID != "listItems" || item["post"].OperationID != "createItem" {
+		t.Errorf("unexpected path item: %+v", item)
+	}
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Fedora reads and writes binaries through a Fedora 6 server's LDP API
+type Fedora struct {
+	baseURL  string
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewFedora connects to the REST endpoint, such as http://localhost:8080/fcrepo/rest.
+// Requests use basic auth when a username is given.
+func NewFedora(baseURL, username, password string) *Fedora {
+	return &Fedora{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		username: username,
+		password: password,
+		http:     &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+func (f *Fedora) Kind() string { return KindFedora }
+
+func (f *Fedora) resourceURL(object, path string) (string, error) {
+	object, path = strings.Trim(object, "/"), strings.Trim(path, "/")
+	if object == "" || path == "" {
+		return "", fmt.Errorf("fedora object and path are required")
+	}
+	var segments []string
+	for _, segment := range strings.Split(object+"/"+path, "/") {
+		if segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid fedora path: %s/%s", object, path)
+		}
+		segments = append(segments, url.PathEscape(segment))
+	}
+	return f.baseURL + "/" + strings.Join(segments, "/"), nil
+}
+
+func (f *Fedora) do(req *http.Request) (*http.Response, error) {
+	if f.username != "" {
+		req.SetBasicAuth(f.username, f.password)
+	}
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fedora %s %s: %w", req.Method, req.URL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("fedora %s %s: HTTP %d - %s", req.Method, req.URL, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// Fetch downloads a binary
+func (f *Fedora) Fetch(object, path string) ([]byte, string, error) {
+	resourceURL, err := f.resourceURL(object, path)
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequest("GET", resourceURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := f.do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", resourceURL, err)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// Put creates or replaces a binary. Fedora versions resources itself, so the
+// message is not sent.
+func (f *Fedora) Put(object, path string, data []byte, mediaType, _ string) error {
+	resourceURL, err := f.resourceURL(object, path)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("PUT", resourceURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mediaType)
+	req.Header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, path[strings.LastIndex(path, "/")+1:]))
+	resp, err := f.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFedora(t *testing.T) {
+	files := map[string]string{"/rest/books/b1/page.jpg": "jpeg bytes"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "fedoraAdmin" || password != "pw" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case "GET":
+			content, ok := files[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "image/jpeg")
+			io.WriteString(w, content)
+		case "PUT":
+			body, _ := io.ReadAll(r.Body)
+			files[r.URL.Path] = r.Header.Get("Content-Type") + " " + string(body)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	store := NewFedora(server.URL+"/rest/", "fedoraAdmin", "pw")
+	data, mediaType, err := store.Fetch("/books/b1", "page.jpg")
+	if err != nil || string(data) != "jpeg bytes" || mediaType != "image/jpeg" {
+		t.Fatalf("Fetch = %q, %q, %v", data, mediaType, err)
+	}
+	if err := store.Put("books/b1", "page.hocr", []byte("<html/>"), "text/vnd.hocr+html", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := files["/rest/books/b1/page.hocr"]; got != "text/vnd.hocr+html <html/>" {
+		t.Errorf("stored %q", got)
+	}
+	if _, _, err := store.Fetch("books/b1", "missing.jpg"); err == nil {
+		t.Error("expected an error for a missing binary")
+	}
+	if _, _, err := store.Fetch("books/b1", "../../admin"); err == nil {
+		t.Error("expected paths leaving the object to be refused")
+	}
+}
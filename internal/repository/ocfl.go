@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage layout extensions OCFL roots may declare in ocfl_layout.json
+const (
+	LayoutFlatDirect    = "0002-flat-direct-storage-layout"
+	LayoutHashedNTuple  = "0004-hashed-n-tuple-storage-layout"
+	ocflLayoutFile      = "ocfl_layout.json"
+	ocflInventoryFile   = "inventory.json"
+	ocflDefaultContent  = "content"
+	ocflVersionUserName = "hOCRedit"
+)
+
+// OCFL reads and writes files of objects in an OCFL storage root. Each Put
+// adds a version to the object, carrying its other files forward.
+//
+// Don't point it at the storage root of a running Fedora: Fedora keeps its own
+// index of what the objects hold and won't see versions added behind its back.
+type OCFL struct {
+	root   string
+	layout ocflLayout
+
+	// mu serializes version writes; objects are small enough that one lock will do
+	mu sync.Mutex
+}
+
+type ocflLayout struct {
+	Extension       string `json:"extensionName"`
+	DigestAlgorithm string `json:"digestAlgorithm"`
+	TupleSize       int    `json:"tupleSize"`
+	NumberOfTuples  int    `json:"numberOfTuples"`
+	ShortObjectRoot bool   `json:"shortObjectRoot"`
+}
+
+type ocflInventory struct {
+	ID               string                 `json:"id"`
+	Type             string                 `json:"type"`
+	DigestAlgorithm  string                 `json:"digestAlgorithm"`
+	Head             string                 `json:"head"`
+	ContentDirectory string                 `json:"contentDirectory,omitempty"`
+	Manifest         map[string][]string    `json:"manifest"`
+	Versions         map[string]ocflVersion `json:"versions"`
+	Fixity           json.RawMessage        `json:"fixity,omitempty"`
+}
+
+type ocflVersion struct {
+	Created time.Time           `json:"created"`
+	State   map[string][]string `json:"state"`
+	Message string              `json:"message,omitempty"`
+	User    *ocflUser           `json:"user,omitempty"`
+}
+
+type ocflUser struct {
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+}
+
+// OpenOCFL reads the storage root's layout. Roots without ocfl_layout.json
+// are taken to use Fedora's default hashed n-tuple layout.
+func OpenOCFL(root string) (*OCFL, error) {
+	layout := ocflLayout{Extension: LayoutHashedNTuple}
+	data, err := os.ReadFile(filepath.Join(root, ocflLayoutFile))
+	switch {
+	case err == nil:
+		var declared struct {
+			Extension string `json:"extension"`
+		}
+		if err := json.Unmarshal(data, &declared); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ocflLayoutFile, err)
+		}
+		layout.Extension = declared.Extension
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	if config, err := os.ReadFile(filepath.Join(root, "extensions", layout.Extension, "config.json")); err == nil {
+		if err := json.Unmarshal(config, &layout); err != nil {
+			return nil, fmt.Errorf("invalid %s config: %w", layout.Extension, err)
+		}
+	}
+	switch layout.Extension {
+	case LayoutFlatDirect:
+	case LayoutHashedNTuple:
+		if layout.DigestAlgorithm == "" {
+			layout.DigestAlgorithm = "sha256"
+		}
+		if layout.TupleSize == 0 && layout.NumberOfTuples == 0 {
+			layout.TupleSize, layout.NumberOfTuples = 3, 3
+		}
+		h, err := newDigest(layout.DigestAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		if layout.TupleSize < 0 || layout.NumberOfTuples < 0 || layout.TupleSize*layout.NumberOfTuples > h.Size()*2 {
+			return nil, fmt.Errorf("invalid %s config: %d tuples of %d", layout.Extension, layout.NumberOfTuples, layout.TupleSize)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported OCFL storage layout: %s", layout.Extension)
+	}
+	return &OCFL{root: root, layout: layout}, nil
+}
+
+func (o *OCFL) Kind() string { return KindOCFL }
+
+func newDigest(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha512":
+		return sha512.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported OCFL digest algorithm: %s", algorithm)
+}
+
+func digestOf(algorithm string, data []byte) (string, error) {
+	h, err := newDigest(algorithm)
+	if err != nil {
+		return "", err
+	}
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// objectRoot maps an object id to its directory under the storage root
+func (o *OCFL) objectRoot(id string) (string, error) {
+	if id == "" {
+		return "", fmt.Errorf("OCFL object id is required")
+	}
+	if o.layout.Extension == LayoutFlatDirect {
+		if id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+			return "", fmt.Errorf("object id %q can't be used with the flat direct layout", id)
+		}
+		return filepath.Join(o.root, id), nil
+	}
+
+	digest, _ := digestOf(o.layout.DigestAlgorithm, []byte(id))
+	parts := make([]string, 0, o.layout.NumberOfTuples+1)
+	for i := 0; i < o.layout.NumberOfTuples; i++ {
+		parts = append(parts, digest[i*o.layout.TupleSize:(i+1)*o.layout.TupleSize])
+	}
+	if o.layout.ShortObjectRoot {
+		parts = append(parts, digest[o.layout.NumberOfTuples*o.layout.TupleSize:])
+	} else {
+		parts = append(parts, digest)
+	}
+	return filepath.Join(append([]string{o.root}, parts...)...), nil
+}
+
+func (o *OCFL) readInventory(objectRoot string) (*ocflInventory, error) {
+	data, err := os.ReadFile(filepath.Join(objectRoot, ocflInventoryFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCFL inventory: %w", err)
+	}
+	var inventory ocflInventory
+	if err := json.Unmarshal(data, &inventory); err != nil {
+		return nil, fmt.Errorf("invalid OCFL inventory: %w", err)
+	}
+	if _, ok := inventory.Versions[inventory.Head]; !ok {
+		return nil, fmt.Errorf("OCFL inventory has no head version %q", inventory.Head)
+	}
+	return &inventory, nil
+}
+
+// headDigest finds the digest of a logical path in the head version
+func (inventory *ocflInventory) headDigest(logicalPath string) string {
+	for digest, paths := range inventory.Versions[inventory.Head].State {
+		for _, p := range paths {
+			if p == logicalPath {
+				return digest
+			}
+		}
+	}
+	return ""
+}
+
+func cleanLogicalPath(logicalPath string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(logicalPath, "/"))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid path: %s", logicalPath)
+	}
+	return cleaned, nil
+}
+
+// Fetch reads a file from the object's head version
+func (o *OCFL) Fetch(object, logicalPath string) ([]byte, string, error) {
+	logicalPath, err := cleanLogicalPath(logicalPath)
+	if err != nil {
+		return nil, "", err
+	}
+	objectRoot, err := o.objectRoot(object)
+	if err != nil {
+		return nil, "", err
+	}
+	inventory, err := o.readInventory(objectRoot)
+	if err != nil {
+		return nil, "", err
+	}
+	digest := inventory.headDigest(logicalPath)
+	if digest == "" || len(inventory.Manifest[digest]) == 0 {
+		return nil, "", fmt.Errorf("object %s has no file %s", object, logicalPath)
+	}
+
+	data, err := os.ReadFile(filepath.Join(objectRoot, filepath.FromSlash(inventory.Manifest[digest][0])))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s of object %s: %w", logicalPath, object, err)
+	}
+	return data, mime.TypeByExtension(path.Ext(logicalPath)), nil
+}
+
+// Put writes a new version of an existing object in which the file has the
+// given contents
+func (o *OCFL) Put(object, logicalPath string, data []byte, _, message string) error {
+	logicalPath, err := cleanLogicalPath(logicalPath)
+	if err != nil {
+		return err
+	}
+	objectRoot, err := o.objectRoot(object)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	inventory, err := o.readInventory(objectRoot)
+	if err != nil {
+		return err
+	}
+	digest, err := digestOf(inventory.DigestAlgorithm, data)
+	if err != nil {
+		return err
+	}
+	if inventory.headDigest(logicalPath) == digest {
+		return nil
+	}
+
+	version, err := nextVersion(inventory.Head)
+	if err != nil {
+		return err
+	}
+	versionDir := filepath.Join(objectRoot, version)
+	if _, err := os.Stat(versionDir); err == nil {
+		return fmt.Errorf("object %s already has a %s directory not in its inventory", object, version)
+	}
+
+	if _, ok := inventory.Manifest[digest]; !ok {
+		contentDir := inventory.ContentDirectory
+		if contentDir == "" {
+			contentDir = ocflDefaultContent
+		}
+		contentPath := path.Join(version, contentDir, logicalPath)
+		target := filepath.Join(objectRoot, filepath.FromSlash(contentPath))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create OCFL version: %w", err)
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return fmt.Errorf("failed to write OCFL content: %w", err)
+		}
+		inventory.Manifest[digest] = []string{contentPath}
+	} else if err := os.MkdirAll(versionDir, 0755); err != nil {
+		return fmt.Errorf("failed to create OCFL version: %w", err)
+	}
+
+	// The new state is the head's, with the path moved to the new digest
+	state := map[string][]string{}
+	for existing, paths := range inventory.Versions[inventory.Head].State {
+		for _, p := range paths {
+			if p != logicalPath {
+				state[existing] = append(state[existing], p)
+			}
+		}
+	}
+	state[digest] = append(state[digest], logicalPath)
+	inventory.Versions[version] = ocflVersion{
+		Created: time.Now().UTC().Truncate(time.Second),
+		State:   state,
+		Message: message,
+		User:    &ocflUser{Name: ocflVersionUserName},
+	}
+	inventory.Head = version
+
+	encoded, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode OCFL inventory: %w", err)
+	}
+	inventoryDigest, _ := digestOf(inventory.DigestAlgorithm, encoded)
+	sidecar := []byte(inventoryDigest + " " + ocflInventoryFile + "\n")
+	// The version's copy is written first so a failure leaves the old head in place
+	for _, dir := range []string{versionDir, objectRoot} {
+		if err := os.WriteFile(filepath.Join(dir, ocflInventoryFile), encoded, 0644); err != nil {
+			return fmt.Errorf("failed to write OCFL inventory: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ocflInventoryFile+"."+inventory.DigestAlgorithm), sidecar, 0644); err != nil {
+			return fmt.Errorf("failed to write OCFL inventory digest: %w", err)
+		}
+	}
+	return nil
+}
+
+// nextVersion follows a version name, keeping any zero padding: v2 -> v3, v002 -> v003
+func nextVersion(head string) (string, error) {
+	number, err := strconv.Atoi(strings.TrimPrefix(head, "v"))
+	if err != nil || !strings.HasPrefix(head, "v") {
+		return "", fmt.Errorf("invalid OCFL version: %s", head)
+	}
+	if strings.HasPrefix(head, "v0") {
+		return fmt.Sprintf("v%0*d", len(head)-1, number+1), nil
+	}
+	return "v" + strconv.Itoa(number+1), nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeObject(t *testing.T, objectRoot string, files map[string]string) {
+	t.Helper()
+	inventory := ocflInventory{
+		ID: "book1", Type: "https://ocfl.io/1.1/spec/#inventory", DigestAlgorithm: "sha512", Head: "v1",
+		Manifest: map[string][]string{},
+		Versions: map[string]ocflVersion{"v1": {State: map[string][]string{}}},
+	}
+	for name, content := range files {
+		digest, _ := digestOf("sha512", []byte(content))
+		contentPath := "v1/content/" + name
+		os.MkdirAll(filepath.Join(objectRoot, "v1", "content"), 0755)
+		os.WriteFile(filepath.Join(objectRoot, filepath.FromSlash(contentPath)), []byte(content), 0644)
+		inventory.Manifest[digest] = []string{contentPath}
+		inventory.Versions["v1"].State[digest] = []string{name}
+	}
+	data, _ := json.Marshal(inventory)
+	os.WriteFile(filepath.Join(objectRoot, ocflInventoryFile), data, 0644)
+}
+
+func TestOCFLPutAddsVersions(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, ocflLayoutFile), []byte(`{"extension": "`+LayoutFlatDirect+`"}`), 0644)
+	writeObject(t, filepath.Join(root, "book1"), map[string]string{"page.jpg": "jpeg bytes"})
+
+	store, err := OpenOCFL(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, mediaType, err := store.Fetch("book1", "page.jpg")
+	if err != nil || string(data) != "jpeg bytes" || mediaType != "image/jpeg" {
+		t.Fatalf("Fetch = %q, %q, %v", data, mediaType, err)
+	}
+
+	if err := store.Put("book1", "page.hocr", []byte("<html>1</html>"), "text/vnd.hocr+html", "first"); err != nil {
+		t.Fatal(err)
+	}
+	// Unchanged content doesn't add a version
+	store.Put("book1", "page.hocr", []byte("<html>1</html>"), "text/vnd.hocr+html", "again")
+	if err := store.Put("book1", "page.hocr", []byte("<html>2</html>"), "text/vnd.hocr+html", "second"); err != nil {
+		t.Fatal(err)
+	}
+
+	inventory, err := store.readInventory(filepath.Join(root, "book1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inventory.Head != "v3" || inventory.Versions["v3"].Message != "second" {
+		t.Errorf("head %s, versions %v", inventory.Head, inventory.Versions)
+	}
+	if inventory.headDigest("page.jpg") == "" {
+		t.Error("page.jpg was not carried forward")
+	}
+	if data, _, _ := store.Fetch("book1", "page.hocr"); string(data) != "<html>2</html>" {
+		t.Errorf("page.hocr = %q", data)
+	}
+	sidecar, _ := os.ReadFile(filepath.Join(root, "book1", "v3", "inventory.json.sha512"))
+	if !strings.HasSuffix(string(sidecar), " inventory.json\n") {
+		t.Errorf("sidecar = %q", sidecar)
+	}
+
+	if _, _, err := store.Fetch("book1", "../book2/page.jpg"); err == nil {
+		t.Error("expected paths outside the object to be refused")
+	}
+}
+
+func TestOCFLHashedLayout(t *testing.T) {
+	store, err := OpenOCFL(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sha256("info:fedora/book1")
+	got, _ := store.objectRoot("info:fedora/book1")
+	digest, _ := digestOf("sha256", []byte("info:fedora/book1"))
+	want := filepath.Join(store.root, digest[0:3], digest[3:6], digest[6:9], digest)
+	if got != want {
+		t.Errorf("objectRoot = %s; want %s", got, want)
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	for head, want := range map[string]string{"v1": "v2", "v9": "v10", "v002": "v003", "v009": "v010", "v09": "v10"} {
+		if got, err := nextVersion(head); err != nil || got != want {
+			t.Errorf("nextVersion(%s) = %s, %v; want %s", head, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Package repository reads page images from, and writes corrected hOCR and
+// ALTO back to, preservation repositories that aren't fronted by Drupal:
+// a Fedora 6 server over its HTTP API, or an OCFL storage root on disk.
+//
+// Files are addressed by an object (a Fedora resource path such as
+// "books/b1/pages/p1", or an OCFL object id) and a path within it.
+package repository
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Repository kinds
+const (
+	KindFedora = "fedora"
+	KindOCFL   = "ocfl"
+)
+
+// Store reads and writes files of repository objects
+type Store interface {
+	Kind() string
+	// Fetch returns a file's contents and, when the repository records one,
+	// its media type
+	Fetch(object, path string) ([]byte, string, error)
+	// Put creates or replaces a file; message describes the change where the
+	// repository keeps versions
+	Put(object, path string, data []byte, mediaType, message string) error
+}
+
+// FromEnv configures Fedora when FEDORA_BASE_URL is set (with FEDORA_USERNAME
+// and FEDORA_PASSWORD) and OCFL when OCFL_STORAGE_ROOT is set
+func FromEnv() (map[string]Store, error) {
+	stores := map[string]Store{}
+	if baseURL := os.Getenv("FEDORA_BASE_URL"); baseURL != "" {
+		stores[KindFedora] = NewFedora(baseURL, os.Getenv("FEDORA_USERNAME"), os.Getenv("FEDORA_PASSWORD"))
+		slog.Info("Fedora repository configured", "url", baseURL)
+	}
+	if root := os.Getenv("OCFL_STORAGE_ROOT"); root != "" {
+		store, err := OpenOCFL(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open OCFL storage root: %w", err)
+		}
+		stores[KindOCFL] = store
+		slog.Info("OCFL storage root configured", "root", root)
+	}
+	return stores, nil
+}
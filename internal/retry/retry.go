@@ -0,0 +1,63 @@
+// Package retry holds the backoff shared by the clients that retry calls to
+// services that are overloaded or briefly unreachable.
+package retry
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Backoff bounds how often and how soon a failed call is tried again. The zero
+// Backoff never retries.
+type Backoff struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// Delay returns how long to wait before the given retry (0-based). A server
+// supplied Retry-After wins; otherwise it is exponential with jitter so
+// concurrent workers don't retry in lockstep.
+func (b Backoff) Delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	backoff := b.BaseDelay << min(attempt, 30)
+	if backoff <= 0 || backoff > b.MaxDelay {
+		backoff = b.MaxDelay
+	}
+
+	// Equal jitter: at least half the backoff, up to all of it
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
+
+// ParseRetryAfter accepts both forms of the header: delay seconds or an HTTP date
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if when, err := http.ParseTime(value); err == nil && when.After(now) {
+		return when.Sub(now)
+	}
+
+	return 0
+}
+
+// Idempotent reports whether sending a request with the method twice has the
+// same effect as sending it once, so it can be retried whatever became of it
+func Idempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{MaxRetries: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		want *= time.Millisecond
+		if attempt == 5 {
+			want = time.Second
+		}
+		got := backoff.Delay(attempt, 0)
+		if got < want/2 || got > want {
+			t.Errorf("attempt %d: delay %v outside [%v, %v]", attempt, got, want/2, want)
+		}
+	}
+
+	if got := backoff.Delay(0, 7*time.Second); got != 7*time.Second {
+		t.Errorf("Retry-After not honored: got %v", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"garbage", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+
+	for _, tt := range tests {
+		if got := ParseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	for _, method := range []string{"GET", "HEAD", "PUT", "DELETE"} {
+		if !Idempotent(method) {
+			t.Errorf("%s should be idempotent", method)
+		}
+	}
+	for _, method := range []string{"POST", "PATCH"} {
+		if Idempotent(method) {
+			t.Errorf("%s should not be idempotent", method)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Package sandbox holds the settings of a public demo instance. With SANDBOX_MODE
+// enabled only local engines run, sessions are capped at SANDBOX_MAX_PAGES pages,
+// exports carry a watermark, and all data is purged every night at
+// SANDBOX_PURGE_HOUR (server local time).
+package sandbox
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/utils"
+)
+
+// Watermark marks every export made on a sandbox instance
+const Watermark = "hOCRedit demo - not for distribution - data is deleted nightly"
+
+func Enabled() bool {
+	return os.Getenv("SANDBOX_MODE") == "true"
+}
+
+// MaxPages is the most pages one session may hold
+func MaxPages() int {
+	return max(1, utils.GetEnvInt("SANDBOX_MAX_PAGES", 5))
+}
+
+// PurgeHour is the hour of the day data is purged
+func PurgeHour() int {
+	hour := utils.GetEnvInt("SANDBOX_PURGE_HOUR", 0)
+	if hour < 0 || hour > 23 {
+		return 0
+	}
+	return hour
+}
+
+// NextPurge is the first purge time strictly after now
+func NextPurge(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// WatermarkText prefixes a plain text export with the watermark
+func WatermarkText(text string) string {
+	return Watermark + "\n\n" + text
+}
+
+// WatermarkHOCR adds the watermark to an hOCR document as a comment and a meta tag
+func WatermarkHOCR(hocrXML string) string {
+	meta := `<meta name="hocredit-watermark" content="` + Watermark + `" />`
+	if head := strings.Index(hocrXML, "<head>"); head >= 0 {
+		head += len("<head>")
+		hocrXML = hocrXML[:head] + "\n" + meta + hocrXML[head:]
+	}
+	if html := strings.Index(hocrXML, "<html"); html >= 0 {
+		return hocrXML[:html] + "<!-- " + Watermark + " -->\n" + hocrXML[html:]
+	}
+	return "<!-- " + Watermark + " -->\n" + hocrXML
+}
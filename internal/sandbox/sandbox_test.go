@@ -0,0 +1,39 @@
+package sandbox
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNextPurge(t *testing.T) {
+	location := time.FixedZone("EST", -5*60*60)
+	tests := []struct {
+		now  time.Time
+		hour int
+		want time.Time
+	}{
+		{time.Date(2024, 3, 1, 22, 30, 0, 0, location), 0, time.Date(2024, 3, 2, 0, 0, 0, 0, location)},
+		{time.Date(2024, 3, 1, 1, 0, 0, 0, location), 3, time.Date(2024, 3, 1, 3, 0, 0, 0, location)},
+		{time.Date(2024, 3, 1, 3, 0, 0, 0, location), 3, time.Date(2024, 3, 2, 3, 0, 0, 0, location)},
+		{time.Date(2024, 12, 31, 23, 0, 0, 0, location), 0, time.Date(2025, 1, 1, 0, 0, 0, 0, location)},
+	}
+
+	for _, tt := range tests {
+		if got := NextPurge(tt.now, tt.hour); !got.Equal(tt.want) {
+			t.Errorf("NextPurge(%v, %d) = %v, want %v", tt.now, tt.hour, got, tt.want)
+		}
+	}
+}
+
+func TestWatermarkHOCR(t *testing.T) {
+	hocrXML := "<?xml version=\"1.0\"?>\n<html>\n<head>\n<title>OCR</title>\n</head>\n<body></body>\n</html>"
+	got := WatermarkHOCR(hocrXML)
+
+	if !strings.HasPrefix(got, "<?xml version=\"1.0\"?>\n<!-- "+Watermark) {
+		t.Errorf("comment should follow the XML declaration:\n%s", got)
+	}
+	if !strings.Contains(got, `<head>`+"\n"+`<meta name="hocredit-watermark"`) {
+		t.Errorf("meta tag missing from head:\n%s", got)
+	}
+}
@@ -0,0 +1,288 @@
+package spell
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// confusions are misreadings OCR engines make often enough that undoing one
+// costs half an ordinary edit
+var confusions = [][2]string{
+	{"rn", "m"}, {"m", "rn"}, {"cl", "d"}, {"d", "cl"}, {"vv", "w"}, {"w", "vv"},
+	{"li", "h"}, {"ii", "u"}, {"ri", "n"}, {"c", "e"}, {"e", "c"}, {"ſ", "s"},
+	{"0", "o"}, {"1", "l"}, {"1", "i"}, {"5", "s"}, {"8", "b"},
+}
+
+const (
+	confusionCost = 0.5
+	editCost      = 1.0
+	// maxSecondEditAlphabet bounds the second round of edits, which grows with
+	// the square of the alphabet
+	maxSecondEditAlphabet = 64
+
+	// maxCorrectionCost is as far as an automatic correction strays from what
+	// was read: two confusions, or one ordinary edit
+	maxCorrectionCost = 1.0
+	// minCorrectionLength keeps short words, with too many near neighbours to
+	// choose between, from being corrected
+	minCorrectionLength = 4
+	// correctionDominance is how many times more often the best of equally
+	// near candidates must occur than the next for it to be chosen
+	correctionDominance = 10
+)
+
+// Checker checks words against a dictionary together with domain lexicons
+type Checker struct {
+	dictionaries []*Dictionary
+	alphabet     []rune
+}
+
+func NewChecker(dictionaries ...*Dictionary) *Checker {
+	c := &Checker{}
+	letters := make(map[rune]bool)
+	for _, d := range dictionaries {
+		if d == nil {
+			continue
+		}
+		c.dictionaries = append(c.dictionaries, d)
+		for r := range d.alphabet {
+			letters[r] = true
+		}
+	}
+	for r := range letters {
+		c.alphabet = append(c.alphabet, r)
+	}
+	sort.Slice(c.alphabet, func(i, j int) bool { return c.alphabet[i] < c.alphabet[j] })
+	return c
+}
+
+func (c *Checker) contains(word string) bool {
+	for _, d := range c.dictionaries {
+		if d.Contains(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsLower looks up a word already in lower case without allocating
+func (c *Checker) containsLower(word []byte) bool {
+	for _, d := range c.dictionaries {
+		if d.words[string(word)] {
+			return true
+		}
+	}
+	return false
+}
+
+// split separates a transcribed word from the punctuation around it
+func split(text string) (prefix, word, suffix string) {
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	start := strings.IndexFunc(text, isWordRune)
+	if start < 0 {
+		return text, "", ""
+	}
+	end := strings.LastIndexFunc(text, isWordRune)
+	end += len(string([]rune(text[end:])[0]))
+	return text[:start], text[start:end], text[end:]
+}
+
+// Known reports whether a transcribed word is spelled correctly. Words without
+// letters, such as numbers, are always known; a possessive 's is ignored, and
+// hyphenated words are known when each part is.
+func (c *Checker) Known(text string) bool {
+	_, word, _ := split(text)
+	if strings.IndexFunc(word, unicode.IsLetter) < 0 || c.contains(word) {
+		return true
+	}
+	for _, possessive := range []string{"'s", "’s"} {
+		if stem, ok := strings.CutSuffix(word, possessive); ok && c.contains(stem) {
+			return true
+		}
+	}
+	if parts := strings.Split(word, "-"); len(parts) > 1 {
+		for _, part := range parts {
+			if part == "" || !c.Known(part) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Suggest lists up to limit known words a misspelled word was most likely
+// misread from, best first, in the word's case and with its punctuation
+func (c *Checker) Suggest(text string, limit int) []string {
+	prefix, word, suffix := split(text)
+	if word == "" || limit <= 0 {
+		return nil
+	}
+
+	candidates, _ := c.candidates(strings.ToLower(word), limit)
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	for i, candidate := range candidates {
+		candidates[i] = prefix + matchCase(word, candidate) + suffix
+	}
+	return candidates
+}
+
+// Correct is the known word a misspelled transcription was surely misread
+// from, in its case and with its punctuation. It's sure when the word is long
+// enough, the best candidate is within maxCorrectionCost, and it's nearer
+// than any other or, among the equally near, occurs correctionDominance times
+// as often as the next. Words that are known, or have no letters, are left.
+func (c *Checker) Correct(text string) (string, bool) {
+	prefix, word, suffix := split(text)
+	if utf8.RuneCountInString(word) < minCorrectionLength || c.Known(text) {
+		return "", false
+	}
+
+	candidates, costs := c.candidates(strings.ToLower(word), 2)
+	if len(candidates) == 0 || costs[candidates[0]] > maxCorrectionCost {
+		return "", false
+	}
+	if len(candidates) > 1 && costs[candidates[1]] == costs[candidates[0]] {
+		best, next := c.frequency(candidates[0]), c.frequency(candidates[1])
+		if best == 0 || best < correctionDominance*next {
+			return "", false
+		}
+	}
+	return prefix + matchCase(word, candidates[0]) + suffix, true
+}
+
+// candidates are the known words within two edits of a word in lower case,
+// nearest first and then most frequent, with what reaching each costs. The
+// second round of edits is only tried for fewer than want candidates.
+func (c *Checker) candidates(lower string, want int) ([]string, map[string]float64) {
+	costs := map[string]float64{lower: 0}
+	frontier := c.expand(map[string]float64{lower: 0}, costs, true)
+	if c.countKnown(costs, lower) < want && len(c.alphabet) <= maxSecondEditAlphabet {
+		c.expand(frontier, costs, false)
+	}
+
+	var candidates []string
+	for candidate := range costs {
+		if candidate != lower && c.contains(candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if costs[a] != costs[b] {
+			return costs[a] < costs[b]
+		}
+		if fa, fb := c.frequency(a), c.frequency(b); fa != fb {
+			return fa > fb
+		}
+		return a < b
+	})
+	return candidates, costs
+}
+
+// frequency is how often a word occurs, by the most telling of the dictionaries
+func (c *Checker) frequency(word string) int {
+	most := 0
+	for _, d := range c.dictionaries {
+		most = max(most, d.Frequency(word))
+	}
+	return most
+}
+
+func (c *Checker) countKnown(costs map[string]float64, original string) int {
+	known := 0
+	for candidate := range costs {
+		if candidate != original && c.contains(candidate) {
+			known++
+		}
+	}
+	return known
+}
+
+// expand applies one confusion or edit to every word of a frontier, recording
+// the cheapest cost of reaching each result, and returns the new words. The
+// last round only needs to record known words.
+func (c *Checker) expand(frontier, costs map[string]float64, keepUnknown bool) map[string]float64 {
+	next := make(map[string]float64)
+	add := func(candidate []byte, cost float64) {
+		if !keepUnknown && !c.containsLower(candidate) {
+			return
+		}
+		if previous, seen := costs[string(candidate)]; seen && previous <= cost {
+			return
+		}
+		costs[string(candidate)] = cost
+		next[string(candidate)] = cost
+	}
+	var buf []byte
+	for word, cost := range frontier {
+		for _, confusion := range confusions {
+			for i := 0; ; {
+				at := strings.Index(word[i:], confusion[0])
+				if at < 0 {
+					break
+				}
+				at += i
+				buf = append(append(append(buf[:0], word[:at]...), confusion[1]...), word[at+len(confusion[0]):]...)
+				add(buf, cost+confusionCost)
+				i = at + len(confusion[0])
+			}
+		}
+		c.edits(word, buf, func(candidate []byte) { add(candidate, cost+editCost) })
+	}
+	return next
+}
+
+// edits calls fn with every deletion, transposition, substitution and
+// insertion of one letter, built in buf
+func (c *Checker) edits(word string, buf []byte, fn func([]byte)) {
+	for i := 0; i <= len(word); i++ {
+		if i < len(word) && !utf8.RuneStart(word[i]) {
+			continue
+		}
+		head, tail := word[:i], word[i:]
+		first, size := utf8.DecodeRuneInString(tail)
+		if size > 0 {
+			rest := tail[size:]
+			fn(append(append(buf[:0], head...), rest...))
+			for _, r := range c.alphabet {
+				if r != first {
+					fn(append(utf8.AppendRune(append(buf[:0], head...), r), rest...))
+				}
+			}
+			if second, secondSize := utf8.DecodeRuneInString(rest); secondSize > 0 {
+				fn(append(utf8.AppendRune(utf8.AppendRune(append(buf[:0], head...), second), first), rest[secondSize:]...))
+			}
+		}
+		for _, r := range c.alphabet {
+			fn(append(utf8.AppendRune(append(buf[:0], head...), r), tail...))
+		}
+	}
+}
+
+// matchCase spells a suggestion in the case of the word it replaces
+func matchCase(original, suggestion string) string {
+	runes := []rune(original)
+	letters, upper := 0, 0
+	for _, r := range runes {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	switch {
+	case letters > 1 && upper == letters:
+		return strings.ToUpper(suggestion)
+	case unicode.IsUpper(runes[0]):
+		first := []rune(suggestion)
+		first[0] = unicode.ToUpper(first[0])
+		return string(first)
+	}
+	return suggestion
+}
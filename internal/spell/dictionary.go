@@ -0,0 +1,156 @@
+// Package spell flags transcribed words missing from a dictionary and suggests
+// the words they were most likely misread from, weighting the confusions OCR
+// engines typically make.
+package spell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Dictionary is a set of known words, compared case insensitively
+type Dictionary struct {
+	words map[string]bool
+	// alphabet holds every letter seen, so suggestions can be spelled in the
+	// dictionary's own script
+	alphabet map[rune]bool
+	// counts holds how often words occur, for those a frequency list gave
+	counts map[string]int
+}
+
+func NewDictionary(words ...string) *Dictionary {
+	d := &Dictionary{words: make(map[string]bool), alphabet: make(map[rune]bool), counts: make(map[string]int)}
+	for _, word := range words {
+		d.Add(word)
+	}
+	return d
+}
+
+// Add makes a word known
+func (d *Dictionary) Add(word string) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	if word == "" {
+		return
+	}
+	d.words[word] = true
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			d.alphabet[r] = true
+		}
+	}
+}
+
+// AddCount makes a word known, occurring count times in some corpus
+func (d *Dictionary) AddCount(word string, count int) {
+	d.Add(word)
+	if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+		d.counts[word] += count
+	}
+}
+
+// Frequency is how often a word occurs, ignoring case, or 0 when no frequency
+// list gave it
+func (d *Dictionary) Frequency(word string) int {
+	return d.counts[strings.ToLower(word)]
+}
+
+// merge adds the words of another dictionary, with their counts
+func (d *Dictionary) merge(other *Dictionary) {
+	for word := range other.words {
+		d.Add(word)
+	}
+	for word, count := range other.counts {
+		d.counts[word] += count
+	}
+}
+
+// Contains reports whether a word is known, ignoring case
+func (d *Dictionary) Contains(word string) bool {
+	return d.words[strings.ToLower(word)]
+}
+
+// Len is the number of known words
+func (d *Dictionary) Len() int {
+	return len(d.words)
+}
+
+// LoadDictionary reads a word list with one entry per line, or a hunspell .dic
+// file. Hunspell affix rules aren't applied, so only the listed stems are known;
+// a list expanded with unmunch recognizes inflected forms too.
+func LoadDictionary(path string) (*Dictionary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	d := NewDictionary()
+	if err := d.read(file, strings.EqualFold(filepath.Ext(path), ".dic")); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// LoadFrequencies reads a frequency list, a word and how often it occurs on
+// each line, as in "the 23135851162". Its words become known.
+func LoadFrequencies(path string) (*Dictionary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	d := NewDictionary()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		count, err := strconv.Atoi(fields[1])
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("failed to read %s: bad count for %q", path, fields[0])
+		}
+		d.AddCount(fields[0], count)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// read adds the words of a list. Lines starting with # are comments; in a
+// word list every word of a line is added, so lexicons can hold phrases.
+func (d *Dictionary) read(r io.Reader, hunspell bool) error {
+	scanner := bufio.NewScanner(r)
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !hunspell {
+			for _, word := range strings.Fields(line) {
+				d.Add(word)
+			}
+			continue
+		}
+
+		// A .dic file starts with its entry count, and entries are a stem
+		// followed by /flags and tab separated morphological fields
+		if first && strings.IndexFunc(line, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+			first = false
+			continue
+		}
+		first = false
+		stem, _, _ := strings.Cut(strings.Fields(line)[0], "/")
+		d.Add(stem)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,159 @@
+package spell
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultDictionaries are tried in order when SPELLCHECK_DICTIONARY is unset
+var defaultDictionaries = []string{"/usr/share/dict/words", "/usr/share/hunspell/en_US.dic"}
+
+// lexiconName keeps lexicon names to plain file names inside the lexicon directory
+var lexiconName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Service holds the dictionary and loads domain lexicons as they're asked for
+type Service struct {
+	dictionary *Dictionary
+	lexiconDir string
+
+	mu       sync.Mutex
+	lexicons map[string]cachedLexicon
+}
+
+type cachedLexicon struct {
+	dictionary *Dictionary
+	modTime    time.Time
+}
+
+// NewService loads the comma separated word lists in SPELLCHECK_DICTIONARY, or
+// the system word list, and the frequency lists in SPELLCHECK_FREQUENCIES, and
+// finds domain lexicons in SPELLCHECK_LEXICON_DIR (default lexicons): one
+// <name>.txt word list per collection or subject.
+func NewService() *Service {
+	s := &Service{
+		dictionary: NewDictionary(),
+		lexiconDir: os.Getenv("SPELLCHECK_LEXICON_DIR"),
+		lexicons:   make(map[string]cachedLexicon),
+	}
+	if s.lexiconDir == "" {
+		s.lexiconDir = "lexicons"
+	}
+
+	paths := defaultDictionaries
+	configured := os.Getenv("SPELLCHECK_DICTIONARY") != ""
+	if configured {
+		paths = strings.Split(os.Getenv("SPELLCHECK_DICTIONARY"), ",")
+	}
+	var loaded []string
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		d, err := LoadDictionary(path)
+		if err != nil {
+			if configured {
+				slog.Warn("Unable to load spellcheck dictionary, skipping", "path", path, "err", err)
+			}
+			continue
+		}
+		s.dictionary.merge(d)
+		loaded = append(loaded, path)
+		if !configured {
+			break
+		}
+	}
+	for _, path := range strings.Split(os.Getenv("SPELLCHECK_FREQUENCIES"), ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		d, err := LoadFrequencies(path)
+		if err != nil {
+			slog.Warn("Unable to load word frequencies, skipping", "path", path, "err", err)
+			continue
+		}
+		s.dictionary.merge(d)
+		loaded = append(loaded, path)
+	}
+
+	if s.dictionary.Len() == 0 {
+		slog.Warn("No spellcheck dictionary loaded, only lexicon words are known", "lexicon_dir", s.lexiconDir)
+	} else {
+		slog.Info("Spellcheck dictionary loaded", "paths", loaded, "words", s.dictionary.Len())
+	}
+	return s
+}
+
+// Words is the size of the dictionary, without lexicons
+func (s *Service) Words() int {
+	return s.dictionary.Len()
+}
+
+// Lexicons lists the lexicons available in the lexicon directory
+func (s *Service) Lexicons() []string {
+	paths, _ := filepath.Glob(filepath.Join(s.lexiconDir, "*.txt"))
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, strings.TrimSuffix(filepath.Base(path), ".txt"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasLexicon reports whether a lexicon of the name exists
+func (s *Service) HasLexicon(name string) bool {
+	if !lexiconName.MatchString(name) {
+		return false
+	}
+	_, err := os.Stat(s.lexiconPath(name))
+	return err == nil
+}
+
+func (s *Service) lexiconPath(name string) string {
+	return filepath.Join(s.lexiconDir, name+".txt")
+}
+
+// Checker checks against the dictionary and the named lexicons
+func (s *Service) Checker(lexicons ...string) (*Checker, error) {
+	dictionaries := []*Dictionary{s.dictionary}
+	for _, name := range lexicons {
+		lexicon, err := s.lexicon(name)
+		if err != nil {
+			return nil, err
+		}
+		dictionaries = append(dictionaries, lexicon)
+	}
+	return NewChecker(dictionaries...), nil
+}
+
+// lexicon loads a lexicon, reading it again once its file changes
+func (s *Service) lexicon(name string) (*Dictionary, error) {
+	if !lexiconName.MatchString(name) {
+		return nil, fmt.Errorf("invalid lexicon name %q", name)
+	}
+	path := s.lexiconPath(name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("lexicon %q not found", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.lexicons[name]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.dictionary, nil
+	}
+	d, err := LoadDictionary(path)
+	if err != nil {
+		return nil, err
+	}
+	s.lexicons[name] = cachedLexicon{dictionary: d, modTime: info.ModTime()}
+	slog.Info("Spellcheck lexicon loaded", "name", name, "words", d.Len())
+	return d, nil
+}
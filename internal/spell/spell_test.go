@@ -0,0 +1,157 @@
+package spell
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKnown(t *testing.T) {
+	checker := NewChecker(NewDictionary("the", "modern", "well", "known", "church"), NewDictionary("Bethlehem"))
+	for text, want := range map[string]bool{
+		"The":        true,
+		"modern,":    true,
+		"(1848)":     true,
+		"church's":   true,
+		"well-known": true,
+		"BETHLEHEM.": true,
+		"tbe":        false,
+		"well-knwn":  false,
+		"rnodern":    false,
+	} {
+		if got := checker.Known(text); got != want {
+			t.Errorf("Known(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	checker := NewChecker(NewDictionary("modern", "modem", "little", "the", "she", "church", "churches"))
+	tests := []struct {
+		text  string
+		limit int
+		want  []string
+	}{
+		// An OCR confusion outranks an ordinary edit
+		{"rnodern", 2, []string{"modern", "modem"}},
+		{"1ittle", 1, []string{"little"}},
+		{"Tbe,", 1, []string{"The,"}},
+		{"CHURCB", 1, []string{"CHURCH"}},
+		// Two edits away
+		{"chrch", 3, []string{"church"}},
+		{"zzzzzz", 3, nil},
+	}
+	for _, test := range tests {
+		if got := checker.Suggest(test.text, test.limit); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Suggest(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	words := NewDictionary("modern", "modem", "little", "the", "church")
+	words.AddCount("house", 50000)
+	words.AddCount("horse", 1000)
+	words.AddCount("barn", 900)
+	words.AddCount("bark", 800)
+	checker := NewChecker(words)
+	tests := []struct {
+		text string
+		want string
+		ok   bool
+	}{
+		{"rnodern,", "modern,", true},
+		{"1ittle", "little", true},
+		{"Tlie", "The", true},
+		{"CHURCB", "CHURCH", true},
+		// Equally near, but house is far more common
+		{"hovse", "house", true},
+		// Equally near and about as common
+		{"barx", "", false},
+		// Known, too short, or too far
+		{"modern", "", false},
+		{"tbe", "", false},
+		{"chrh", "", false},
+		{"1848", "", false},
+	}
+	for _, test := range tests {
+		if got, ok := checker.Correct(test.text); got != test.want || ok != test.ok {
+			t.Errorf("Correct(%q) = %q, %v, want %q, %v", test.text, got, ok, test.want, test.ok)
+		}
+	}
+
+	// Without counts a tie is left alone
+	if got, ok := NewChecker(NewDictionary("house", "horse")).Correct("hovse"); ok {
+		t.Errorf("Correct(hovse) without frequencies = %q", got)
+	}
+}
+
+func TestLoadFrequencies(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "en.freq")
+	os.WriteFile(path, []byte("# word count\nthe 23135851162\nThe 10\nhouse 5000\n"), 0644)
+	d, err := LoadFrequencies(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 2 || d.Frequency("THE") != 23135851172 || d.Frequency("house") != 5000 || d.Frequency("horse") != 0 {
+		t.Errorf("frequency list holds %v", d.counts)
+	}
+
+	os.WriteFile(path, []byte("the many\n"), 0644)
+	if _, err := LoadFrequencies(path); err == nil {
+		t.Error("bad count was accepted")
+	}
+}
+
+func TestLoadDictionary(t *testing.T) {
+	dir := t.TempDir()
+	hunspell := filepath.Join(dir, "en.dic")
+	os.WriteFile(hunspell, []byte("3\nhello/S\nworld\tpo:noun\nBethlehem/M\n"), 0644)
+	d, err := LoadDictionary(hunspell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 3 || !d.Contains("hello") || !d.Contains("world") || !d.Contains("bethlehem") || d.Contains("3") {
+		t.Errorf("hunspell dictionary holds %v", d.words)
+	}
+
+	list := filepath.Join(dir, "places.txt")
+	os.WriteFile(list, []byte("# Lehigh Valley\nSouth Bethlehem\nAllentown\n"), 0644)
+	d, err = LoadDictionary(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 3 || !d.Contains("south") || d.Contains("#") {
+		t.Errorf("word list holds %v", d.words)
+	}
+}
+
+func TestServiceLexicons(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SPELLCHECK_LEXICON_DIR", dir)
+	t.Setenv("SPELLCHECK_DICTIONARY", filepath.Join(dir, "missing.txt"))
+	os.WriteFile(filepath.Join(dir, "moravian.txt"), []byte("Gemeinhaus\n"), 0644)
+
+	service := NewService()
+	if got := service.Lexicons(); !reflect.DeepEqual(got, []string{"moravian"}) {
+		t.Errorf("Lexicons() = %v", got)
+	}
+	checker, err := service.Checker("moravian")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !checker.Known("Gemeinhaus") {
+		t.Error("lexicon word is unknown")
+	}
+	for _, name := range []string{"missing", "../moravian"} {
+		if _, err := service.Checker(name); err == nil || !strings.Contains(err.Error(), "lexicon") {
+			t.Errorf("Checker(%q) err = %v", name, err)
+		}
+		if service.HasLexicon(name) {
+			t.Errorf("HasLexicon(%q) = true", name)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BlobStore keeps uploaded images in one directory, named by the SHA-256 of
+// their source content, so a page uploaded into several sessions is stored
+// once. Each file is written once and appears complete or not at all, and the
+// store tracks which sessions hold each one, so shared files and everything
+// derived from them stay until no session uses them.
+type BlobStore struct {
+	dir string
+
+	locksMu sync.Mutex
+	locks   map[string]*keyLock
+
+	mu      sync.RWMutex
+	holders map[string]map[string]bool
+	held    map[string][]string
+}
+
+func NewBlobStore(dir string) *BlobStore {
+	return &BlobStore{
+		dir:     dir,
+		locks:   make(map[string]*keyLock),
+		holders: make(map[string]map[string]bool),
+		held:    make(map[string][]string),
+	}
+}
+
+// ContentDigest is the key content is stored under
+func ContentDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ContentHash digests content as it is written, for streams too big to hold
+// in memory, to the same key as ContentDigest
+type ContentHash struct {
+	hash.Hash
+}
+
+func NewContentHash() ContentHash {
+	return ContentHash{sha256.New()}
+}
+
+// Digest is the key of everything written so far
+func (h ContentHash) Digest() string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// keyLock is the lock for one key and the number of callers holding or
+// waiting for it, so it can be dropped once nobody is
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Lock serializes work on one key, such as producing a file derived from a
+// blob, until the returned function is called
+func (s *BlobStore) Lock(key string) func() {
+	s.locksMu.Lock()
+	lock := s.locks[key]
+	if lock == nil {
+		lock = &keyLock{}
+		s.locks[key] = lock
+	}
+	lock.refs++
+	s.locksMu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		s.locksMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(s.locks, key)
+		}
+		s.locksMu.Unlock()
+	}
+}
+
+// Path is where a blob named digest+ext is kept
+func (s *BlobStore) Path(digest, ext string) string {
+	return filepath.Join(s.dir, digest+ext)
+}
+
+// Put stores a blob unless it already exists. fill writes the content to a
+// temporary path, converting it as needed, and the result is moved into place
+// only once fill succeeds. created reports whether fill ran. A blob that is
+// reused has its modification time refreshed, so cleanup counts its retention
+// from now while the session reusing it isn't saved yet.
+func (s *BlobStore) Put(digest, ext string, fill func(path string) error) (created bool, err error) {
+	unlock := s.Lock(digest)
+	defer unlock()
+
+	path := s.Path(digest, ext)
+	if _, err := os.Stat(path); err == nil {
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			return false, fmt.Errorf("failed to refresh blob: %w", err)
+		}
+		return false, nil
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".blob-*"+ext)
+	if err != nil {
+		return false, fmt.Errorf("failed to create blob: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := fill(tmp.Name()); err != nil {
+		return false, err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return false, fmt.Errorf("failed to store blob: %w", err)
+	}
+	return true, nil
+}
+
+// Hold records that owner uses exactly these digests, releasing any others it
+// held before. Holding none releases the owner entirely.
+func (s *BlobStore) Hold(owner string, digests []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, digest := range s.held[owner] {
+		delete(s.holders[digest], owner)
+		if len(s.holders[digest]) == 0 {
+			delete(s.holders, digest)
+		}
+	}
+	delete(s.held, owner)
+
+	for _, digest := range digests {
+		if s.holders[digest] == nil {
+			s.holders[digest] = make(map[string]bool)
+		}
+		if !s.holders[digest][owner] {
+			s.holders[digest][owner] = true
+			s.held[owner] = append(s.held[owner], digest)
+		}
+	}
+}
+
+// Holders counts the owners holding a digest
+func (s *BlobStore) Holders(digest string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.holders[digest])
+}
+
+// Held counts the owners of every digest held by at least one
+func (s *BlobStore) Held() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]int, len(s.holders))
+	for digest, owners := range s.holders {
+		counts[digest] = len(owners)
+	}
+	return counts
+}
+
+// WriteFileAtomic replaces a file so readers see the old content or the new,
+// never part of it
+func WriteFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBlobStorePutDedupes(t *testing.T) {
+	s := NewBlobStore(t.TempDir())
+	digest := ContentDigest([]byte("scan"))
+	fills := 0
+	fill := func(path string) error {
+		fills++
+		return os.WriteFile(path, []byte("scan"), 0644)
+	}
+
+	for i, want := range []bool{true, false} {
+		created, err := s.Put(digest, ".png", fill)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created != want {
+			t.Errorf("put %d: created %v, want %v", i+1, created, want)
+		}
+	}
+	if fills != 1 {
+		t.Errorf("fill ran %d times, want once", fills)
+	}
+	if len(s.locks) != 0 {
+		t.Errorf("%d locks left after every caller released them", len(s.locks))
+	}
+}
+
+func TestBlobStorePutRefreshesReusedBlob(t *testing.T) {
+	s := NewBlobStore(t.TempDir())
+	digest := ContentDigest([]byte("scan"))
+	path := s.Path(digest, ".png")
+	if err := os.WriteFile(path, []byte("scan"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Put(digest, ".png", func(string) error {
+		t.Error("fill ran for a blob that exists")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(info.ModTime()) > time.Minute {
+		t.Errorf("reused blob still dated %s", info.ModTime())
+	}
+}
+
+func TestBlobStoreHold(t *testing.T) {
+	s := NewBlobStore(t.TempDir())
+	s.Hold("s1", []string{"a", "b"})
+	s.Hold("s2", []string{"b"})
+	if s.Holders("a") != 1 || s.Holders("b") != 2 {
+		t.Errorf("holders are %v", s.Held())
+	}
+
+	// Holding a new set releases what the owner held before
+	s.Hold("s1", []string{"b"})
+	s.Hold("s2", nil)
+	if held := s.Held(); len(held) != 1 || held["b"] != 1 {
+		t.Errorf("holders are %v, want only b held once", held)
+	}
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+var (
+	ErrExternalJobNotFound = errors.New("job not found")
+	// ErrExternalJobFinished is a second completion for a job that was already
+	// completed or failed
+	ErrExternalJobFinished = errors.New("job already finished")
+)
+
+type ExternalJobStore struct {
+	jobs map[string]*models.ExternalJob
+	mu   sync.RWMutex
+	// path persists every job to a JSON file when set
+	path string
+}
+
+func NewExternalJobStore() *ExternalJobStore {
+	return &ExternalJobStore{
+		jobs: make(map[string]*models.ExternalJob),
+	}
+}
+
+// NewPersistentExternalJobStore loads the jobs stored at path and writes every
+// change back to it, so callbacks for jobs registered before a restart still land
+func NewPersistentExternalJobStore(path string) (*ExternalJobStore, error) {
+	s := NewExternalJobStore()
+	s.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read external job store: %w", err)
+	}
+
+	var jobs []*models.ExternalJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse external job store: %w", err)
+	}
+	for _, job := range jobs {
+		s.jobs[job.ID] = job
+	}
+
+	slog.Info("Loaded persisted external jobs", "path", path, "count", len(s.jobs))
+	return s, nil
+}
+
+// Get returns a copy of the job, so callers can read it while a callback completes it
+func (s *ExternalJobStore) Get(jobID string) (models.ExternalJob, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, exists := s.jobs[jobID]
+	if !exists {
+		return models.ExternalJob{}, false
+	}
+	return *job, true
+}
+
+func (s *ExternalJobStore) Set(job models.ExternalJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = &job
+	s.persist()
+}
+
+// Complete records how a pending job ended, failing with ErrExternalJobFinished
+// when another callback got there first. The check and the change are made
+// under one lock, so only one callback ever completes a job.
+func (s *ExternalJobStore) Complete(jobID, status, errMessage string, at time.Time) (models.ExternalJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, exists := s.jobs[jobID]
+	if !exists {
+		return models.ExternalJob{}, ErrExternalJobNotFound
+	}
+	if job.Status != models.ExternalJobPending {
+		return *job, fmt.Errorf("%w: %s", ErrExternalJobFinished, job.Status)
+	}
+
+	job.Status = status
+	job.Error = errMessage
+	job.CompletedAt = &at
+	s.persist()
+	return *job, nil
+}
+
+// ForImage returns copies of the jobs submitted for one image of a session
+func (s *ExternalJobStore) ForImage(sessionID, imageID string) []models.ExternalJob {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []models.ExternalJob
+	for _, job := range s.jobs {
+		if job.SessionID == sessionID && job.ImageID == imageID {
+			result = append(result, *job)
+		}
+	}
+	return result
+}
+
+// Reassign points jobs at renumbered images, e.g. after sessions are merged
+func (s *ExternalJobStore) Reassign(fromSession, toSession string, imageIDs map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.SessionID != fromSession {
+			continue
+		}
+		if newID, ok := imageIDs[job.ImageID]; ok {
+			job.SessionID = toSession
+			job.ImageID = newID
+		}
+	}
+	s.persist()
+}
+
+// Clear deletes every job, returning how many there were
+func (s *ExternalJobStore) Clear() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := len(s.jobs)
+	clear(s.jobs)
+	s.persist()
+	return deleted
+}
+
+// persist writes the jobs to the store's file, if it has one. The caller holds
+// the write lock.
+func (s *ExternalJobStore) persist() {
+	if s.path == "" {
+		return
+	}
+	if err := s.write(); err != nil {
+		slog.Error("Failed to persist external jobs", "path", s.path, "err", err)
+	}
+}
+
+// write replaces the job file atomically so a crash never leaves it half written
+func (s *ExternalJobStore) write() error {
+	jobs := make([]*models.ExternalJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
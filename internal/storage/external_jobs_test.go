@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+func TestExternalJobCompletesOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "external_jobs.json")
+	store, err := NewPersistentExternalJobStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set(models.ExternalJob{ID: "ext_1", SessionID: "s1", ImageID: "img_1", Status: models.ExternalJobPending})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	completed, finished := 0, 0
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.Complete("ext_1", models.ExternalJobCompleted, "", time.Now())
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				completed++
+			case errors.Is(err, ErrExternalJobFinished):
+				finished++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if completed != 1 || finished != 7 {
+		t.Errorf("%d callbacks completed the job and %d were refused; want 1 and 7", completed, finished)
+	}
+
+	if _, err := store.Complete("ext_2", models.ExternalJobFailed, "", time.Now()); !errors.Is(err, ErrExternalJobNotFound) {
+		t.Errorf("completing an unknown job: %v", err)
+	}
+
+	reloaded, err := NewPersistentExternalJobStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, ok := reloaded.Get("ext_1"); !ok || job.Status != models.ExternalJobCompleted || job.CompletedAt == nil {
+		t.Errorf("job reloaded as %+v, %v", job, ok)
+	}
+}
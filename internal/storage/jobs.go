@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+type JobStore struct {
+	jobs map[string]*models.Job
+	mu   sync.RWMutex
+}
+
+func NewJobStore() *JobStore {
+	return &JobStore{
+		jobs: make(map[string]*models.Job),
+	}
+}
+
+// LoadJobStore restores the jobs saved at the last shutdown, so clients polling
+// across a restart learn how their jobs ended
+func LoadJobStore(path string) (*JobStore, error) {
+	s := NewJobStore()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job store: %w", err)
+	}
+
+	var jobs []*models.Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse job store: %w", err)
+	}
+	for _, job := range jobs {
+		s.jobs[job.ID] = job
+	}
+	return s, nil
+}
+
+// Save writes every job to path, replacing the file atomically
+func (s *JobStore) Save(path string) error {
+	s.mu.RLock()
+	jobs := make([]*models.Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	data, err := json.Marshal(jobs)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Unfinished lists the IDs of jobs that are queued or running
+func (s *JobStore) Unfinished() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+	for id, job := range s.jobs {
+		if job.Status == models.JobQueued || job.Status == models.JobRunning {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// DeleteFinished forgets every job that is no longer queued or running,
+// returning how many there were
+func (s *JobStore) DeleteFinished() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := 0
+	for id, job := range s.jobs {
+		if job.Status != models.JobQueued && job.Status != models.JobRunning {
+			delete(s.jobs, id)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Get returns a copy of the job, so callers can read it while a worker updates it
+func (s *JobStore) Get(jobID string) (models.Job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, exists := s.jobs[jobID]
+	if !exists {
+		return models.Job{}, false
+	}
+	return *job, true
+}
+
+func (s *JobStore) Set(job *models.Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+}
+
+// Update applies a change to a stored job under the store's lock
+func (s *JobStore) Update(jobID string, update func(job *models.Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, exists := s.jobs[jobID]; exists {
+		update(job)
+	}
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+type MacroStore struct {
+	macros map[string]*models.CorrectionMacro
+	mu     sync.RWMutex
+	// path persists every macro to a JSON file when set
+	path string
+}
+
+func NewMacroStore() *MacroStore {
+	return &MacroStore{
+		macros: make(map[string]*models.CorrectionMacro),
+	}
+}
+
+// NewPersistentMacroStore loads the macros stored at path and writes every
+// change back to it
+func NewPersistentMacroStore(path string) (*MacroStore, error) {
+	s := NewMacroStore()
+	s.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read macro store: %w", err)
+	}
+
+	var macros []*models.CorrectionMacro
+	if err := json.Unmarshal(data, &macros); err != nil {
+		return nil, fmt.Errorf("failed to parse macro store: %w", err)
+	}
+	for _, macro := range macros {
+		s.macros[macro.ID] = macro
+	}
+
+	slog.Info("Loaded persisted macros", "path", path, "count", len(s.macros))
+	return s, nil
+}
+
+func (s *MacroStore) Get(macroID string) (*models.CorrectionMacro, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	macro, exists := s.macros[macroID]
+	return macro, exists
+}
+
+func (s *MacroStore) Set(macro *models.CorrectionMacro) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.macros[macro.ID] = macro
+	s.persist()
+}
+
+func (s *MacroStore) Delete(macroID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.macros, macroID)
+	s.persist()
+}
+
+// Clear deletes every macro, returning how many there were
+func (s *MacroStore) Clear() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := len(s.macros)
+	clear(s.macros)
+	s.persist()
+	return deleted
+}
+
+// persist writes the macros to the store's file, if it has one. The caller
+// holds the write lock.
+func (s *MacroStore) persist() {
+	if s.path == "" {
+		return
+	}
+	if err := s.write(); err != nil {
+		slog.Error("Failed to persist macros", "path", s.path, "err", err)
+	}
+}
+
+// write replaces the macro file atomically so a crash never leaves it half written
+func (s *MacroStore) write() error {
+	macros := make([]*models.CorrectionMacro, 0, len(s.macros))
+	for _, macro := range s.macros {
+		macros = append(macros, macro)
+	}
+	data, err := json.Marshal(macros)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Visible returns the macros a user can run against a session in the given collection
+func (s *MacroStore) Visible(user, collection string) []*models.CorrectionMacro {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*models.CorrectionMacro
+	for _, macro := range s.macros {
+		if MacroVisible(macro, user, collection) {
+			result = append(result, macro)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// MacroVisible applies the scope rules: global macros are shared, user macros are
+// private to their owner, and collection macros apply to sessions in that collection
+func MacroVisible(macro *models.CorrectionMacro, user, collection string) bool {
+	switch macro.Scope {
+	case models.MacroScopeGlobal:
+		return true
+	case models.MacroScopeUser:
+		return user != "" && macro.Owner == user
+	case models.MacroScopeCollection:
+		return collection != "" && macro.Owner == collection
+	}
+	return false
+}
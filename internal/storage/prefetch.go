@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
+)
+
+type PrefetchStore struct {
+	runs map[string]*models.PrefetchRun
+	mu   sync.RWMutex
+	// path persists every run to a JSON file when set
+	path string
+}
+
+func NewPrefetchStore() *PrefetchStore {
+	return &PrefetchStore{
+		runs: make(map[string]*models.PrefetchRun),
+	}
+}
+
+// NewPersistentPrefetchStore loads the runs stored at path and writes every
+// change back to it, so a run's outcome can still be polled after a restart
+func NewPersistentPrefetchStore(path string) (*PrefetchStore, error) {
+	s := NewPrefetchStore()
+	s.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prefetch store: %w", err)
+	}
+
+	var runs []*models.PrefetchRun
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, fmt.Errorf("failed to parse prefetch store: %w", err)
+	}
+	for _, run := range runs {
+		s.runs[run.ID] = run
+	}
+
+	slog.Info("Loaded persisted prefetch runs", "path", path, "count", len(s.runs))
+	return s, nil
+}
+
+// Get returns a copy of the run, so callers can read it while workers update it
+func (s *PrefetchStore) Get(runID string) (models.PrefetchRun, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	run, exists := s.runs[runID]
+	if !exists {
+		return models.PrefetchRun{}, false
+	}
+	result := *run
+	result.Errors = slices.Clone(run.Errors)
+	return result, true
+}
+
+func (s *PrefetchStore) Set(run models.PrefetchRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[run.ID] = &run
+	s.persist()
+}
+
+// Update applies a change to a stored run under the store's lock
+func (s *PrefetchStore) Update(runID string, update func(run *models.PrefetchRun)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run, exists := s.runs[runID]; exists {
+		update(run)
+		s.persist()
+	}
+}
+
+// Unfinished lists the runs that haven't finished
+func (s *PrefetchStore) Unfinished() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+	for id, run := range s.runs {
+		if run.FinishedAt == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Clear deletes every run, returning how many there were
+func (s *PrefetchStore) Clear() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := len(s.runs)
+	clear(s.runs)
+	s.persist()
+	return deleted
+}
+
+// persist writes the runs to the store's file, if it has one. The caller holds
+// the write lock.
+func (s *PrefetchStore) persist() {
+	if s.path == "" {
+		return
+	}
+	if err := s.write(); err != nil {
+		slog.Error("Failed to persist prefetch runs", "path", s.path, "err", err)
+	}
+}
+
+// write replaces the run file atomically so a crash never leaves it half written
+func (s *PrefetchStore) write() error {
+	runs := make([]*models.PrefetchRun, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, run)
+	}
+	data, err := json.Marshal(runs)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return WriteFileAtomic(s.path, data)
+}
@@ -1,14 +1,36 @@
 package storage
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/lehigh-university-libraries/hOCRedit/internal/models"
 )
 
+// SessionSchemaVersion is bumped whenever the stored session format changes,
+// alongside a migration that upgrades existing files
+const SessionSchemaVersion = 1
+
 type SessionStore struct {
 	sessions map[string]*models.CorrectionSession
 	mu       sync.RWMutex
+	// dir persists sessions as JSON files when set
+	dir string
+	// observers are told of every Set and Delete, with a nil session for a delete
+	observers []func(sessionID string, session *models.CorrectionSession)
+}
+
+// sessionFile is the on-disk form of a persisted session
+type sessionFile struct {
+	SchemaVersion int                       `json:"schema_version"`
+	Session       *models.CorrectionSession `json:"session"`
 }
 
 func New() *SessionStore {
@@ -17,6 +39,43 @@ func New() *SessionStore {
 	}
 }
 
+// NewPersistent loads the sessions stored in dir and writes every change back to it
+func NewPersistent(dir string) (*SessionStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session store: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	s := New()
+	s.dir = dir
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var stored sessionFile
+		if err := json.Unmarshal(data, &stored); err != nil {
+			slog.Warn("Skipping unreadable session file", "path", file, "err", err)
+			continue
+		}
+		if stored.SchemaVersion > SessionSchemaVersion {
+			return nil, fmt.Errorf("session %s has schema version %d, newer than this build supports (%d)", file, stored.SchemaVersion, SessionSchemaVersion)
+		}
+		if stored.Session == nil {
+			continue
+		}
+		s.sessions[stored.Session.ID] = stored.Session
+	}
+
+	slog.Info("Loaded persisted sessions", "dir", dir, "count", len(s.sessions))
+	return s, nil
+}
+
 func (s *SessionStore) Get(sessionID string) (*models.CorrectionSession, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -26,8 +85,27 @@ func (s *SessionStore) Get(sessionID string) (*models.CorrectionSession, bool) {
 
 func (s *SessionStore) Set(sessionID string, session *models.CorrectionSession) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.sessions[sessionID] = session
+
+	if s.dir != "" {
+		if err := s.write(sessionID, session); err != nil {
+			slog.Error("Failed to persist session", "session_id", sessionID, "err", err)
+		}
+	}
+	observers := s.observers
+	s.mu.Unlock()
+
+	for _, observe := range observers {
+		observe(sessionID, session)
+	}
+}
+
+// OnChange registers a function told of every Set and Delete after it is made,
+// with a nil session for a delete
+func (s *SessionStore) OnChange(observe func(sessionID string, session *models.CorrectionSession)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observers = append(s.observers, observe)
 }
 
 func (s *SessionStore) GetAll() map[string]*models.CorrectionSession {
@@ -43,6 +121,72 @@ func (s *SessionStore) GetAll() map[string]*models.CorrectionSession {
 
 func (s *SessionStore) Delete(sessionID string) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	delete(s.sessions, sessionID)
+
+	if s.dir != "" {
+		if err := os.Remove(s.path(sessionID)); err != nil && !os.IsNotExist(err) {
+			slog.Error("Failed to remove persisted session", "session_id", sessionID, "err", err)
+		}
+	}
+	observers := s.observers
+	s.mu.Unlock()
+
+	for _, observe := range observers {
+		observe(sessionID, nil)
+	}
+}
+
+// Flush rewrites every persisted session, catching changes made to a session in
+// place without a Set
+func (s *SessionStore) Flush() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.dir == "" {
+		return nil
+	}
+
+	var errs []error
+	for id, session := range s.sessions {
+		if err := s.write(id, session); err != nil {
+			errs = append(errs, fmt.Errorf("session %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Check confirms the store isn't deadlocked and, when persistent, that its
+// directory still accepts writes
+func (s *SessionStore) Check() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.dir == "" {
+		return nil
+	}
+
+	probe, err := os.CreateTemp(s.dir, ".health-*")
+	if err != nil {
+		return fmt.Errorf("session store is not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+func (s *SessionStore) path(sessionID string) string {
+	name := strings.ReplaceAll(url.PathEscape(sessionID), "..", "%2E%2E")
+	return filepath.Join(s.dir, name+".json")
+}
+
+// write replaces the session file atomically so a crash never leaves it half written
+func (s *SessionStore) write(sessionID string, session *models.CorrectionSession) error {
+	data, err := json.Marshal(sessionFile{SchemaVersion: SessionSchemaVersion, Session: session})
+	if err != nil {
+		return err
+	}
+
+	path := s.path(sessionID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
 }
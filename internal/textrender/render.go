@@ -0,0 +1,152 @@
+// Package textrender draws text into images in-process with the Go fonts embedded
+// in golang.org/x/image, so overlays don't depend on fonts installed for ImageMagick
+// and render Latin, Greek and Cyrillic text correctly.
+package textrender
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Options places text on a blank canvas
+type Options struct {
+	Width, Height int
+	// Size is the font size in pixels
+	Size float64
+	// X and Baseline are where the first glyph is drawn
+	X, Baseline int
+	// Mono selects Go Mono instead of Go Regular
+	Mono       bool
+	Foreground color.Color
+	Background color.Color
+}
+
+type faceKey struct {
+	mono bool
+	size float64
+}
+
+var (
+	fonts     = map[bool]*opentype.Font{}
+	fontsErr  error
+	fontsOnce sync.Once
+
+	faces   = map[faceKey]font.Face{}
+	facesMu sync.Mutex
+)
+
+func loadFonts() error {
+	fontsOnce.Do(func() {
+		for mono, data := range map[bool][]byte{true: gomono.TTF, false: goregular.TTF} {
+			parsed, err := opentype.Parse(data)
+			if err != nil {
+				fontsErr = fmt.Errorf("failed to parse embedded font: %w", err)
+				return
+			}
+			fonts[mono] = parsed
+		}
+	})
+	return fontsErr
+}
+
+// face returns a cached face; faces aren't safe for concurrent use, so callers
+// hold facesMu while drawing
+func face(mono bool, size float64) (font.Face, error) {
+	if err := loadFonts(); err != nil {
+		return nil, err
+	}
+
+	key := faceKey{mono: mono, size: size}
+	if f, ok := faces[key]; ok {
+		return f, nil
+	}
+	f, err := opentype.NewFace(fonts[mono], &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font face: %w", err)
+	}
+	faces[key] = f
+	return f, nil
+}
+
+// Render draws a single line of text. Text wider than the canvas is clipped.
+func Render(text string, opts Options) (*image.RGBA, error) {
+	if opts.Width <= 0 || opts.Height <= 0 {
+		return nil, fmt.Errorf("invalid canvas size %dx%d", opts.Width, opts.Height)
+	}
+	if opts.Background == nil {
+		opts.Background = color.White
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+	if err := Draw(img, text, opts); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// Draw writes a line of text onto an existing image, with X and Baseline in its
+// coordinates; Width, Height and Background are ignored. Only drawing the
+// glyphs holds the lock the cached faces share.
+func Draw(dst draw.Image, text string, opts Options) error {
+	if opts.Foreground == nil {
+		opts.Foreground = color.Black
+	}
+
+	facesMu.Lock()
+	defer facesMu.Unlock()
+	f, err := face(opts.Mono, opts.Size)
+	if err != nil {
+		return err
+	}
+
+	drawer := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(opts.Foreground),
+		Face: f,
+		Dot:  fixed.P(opts.X, opts.Baseline),
+	}
+	drawer.DrawString(text)
+	return nil
+}
+
+// Width measures how wide text renders, in pixels
+func Width(text string, mono bool, size float64) (int, error) {
+	facesMu.Lock()
+	defer facesMu.Unlock()
+
+	f, err := face(mono, size)
+	if err != nil {
+		return 0, err
+	}
+	return font.MeasureString(f, text).Ceil(), nil
+}
+
+// WritePNG renders text straight to a PNG file
+func WritePNG(path, text string, opts Options) error {
+	img, err := Render(text, opts)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create text image: %w", err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode text image: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,70 @@
+package textrender
+
+import (
+	"image/color"
+	"testing"
+)
+
+func darkPixels(text string, t *testing.T) int {
+	t.Helper()
+	img, err := Render(text, Options{Width: 400, Height: 60, Size: 24, X: 10, Baseline: 40, Mono: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dark := 0
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray); gray.Y < 128 {
+				dark++
+			}
+		}
+	}
+	return dark
+}
+
+func TestRender(t *testing.T) {
+	if darkPixels("", t) != 0 {
+		t.Error("empty text drew pixels")
+	}
+	for _, text := range []string{"<span class='ocrx_word' id='word_1'>", "Ελληνικά", "Кириллица", "naïve café"} {
+		if darkPixels(text, t) == 0 {
+			t.Errorf("%q drew nothing", text)
+		}
+	}
+
+	if _, err := Render("x", Options{Size: 12}); err == nil {
+		t.Error("expected an error for an empty canvas")
+	}
+}
+
+func TestFontsCoverUnicode(t *testing.T) {
+	facesMu.Lock()
+	defer facesMu.Unlock()
+
+	for _, mono := range []bool{true, false} {
+		f, err := face(mono, 24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range "Ωжé—" {
+			if _, ok := f.GlyphAdvance(r); !ok {
+				t.Errorf("mono=%v font has no glyph for %q", mono, r)
+			}
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	short, err := Width("ab", false, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	long, err := Width("abcdef", false, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if short <= 0 || long <= short {
+		t.Errorf("widths %d and %d", short, long)
+	}
+}
@@ -0,0 +1,59 @@
+package tiles
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// TileSize and Overlap follow the Deep Zoom defaults OpenSeadragon expects
+	TileSize = 254
+	Overlap  = 1
+
+	// DescriptorName and FilesDir are where a pyramid's .dzi and tiles live in its directory
+	DescriptorName = "image.dzi"
+	FilesDir       = "files"
+
+	jpegQuality = 85
+)
+
+// TilePath is where a tile lives below a pyramid's _files directory
+func TilePath(dir string, level, col, row int) string {
+	return filepath.Join(dir, fmt.Sprint(level), fmt.Sprintf("%d_%d.jpg", col, row))
+}
+
+// Generate builds the pyramid for the image at sourcePath in dir with vips,
+// which reads the image a region at a time rather than holding it in memory.
+// The pyramid is built beside dir and renamed into place, so dir only ever
+// exists complete.
+func Generate(sourcePath, dir string) error {
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return fmt.Errorf("failed to create tile directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	base := filepath.Join(tmp, strings.TrimSuffix(DescriptorName, ".dzi"))
+	cmd := exec.Command("vips", "dzsave", sourcePath, base,
+		"--tile-size", strconv.Itoa(TileSize),
+		"--overlap", strconv.Itoa(Overlap),
+		"--suffix", fmt.Sprintf(".jpg[Q=%d]", jpegQuality))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("vips dzsave failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	if err := os.Rename(base+"_files", filepath.Join(tmp, FilesDir)); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dir)
+}
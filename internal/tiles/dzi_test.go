@@ -0,0 +1,57 @@
+package tiles
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	if _, err := exec.LookPath("vips"); err != nil {
+		t.Skip("vips is not installed")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 600; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	source := filepath.Join(t.TempDir(), "scan.png")
+	f, err := os.Create(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	dir := filepath.Join(t.TempDir(), "pyramid")
+	if err := Generate(source, dir); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, DescriptorName)); err != nil {
+		t.Errorf("missing descriptor: %v", err)
+	}
+	// Full resolution is level 10 with 3x2 tiles; level 0 is a single pixel
+	for _, tile := range []struct{ level, col, row int }{
+		{10, 0, 0}, {10, 2, 1}, {9, 1, 0}, {0, 0, 0},
+	} {
+		if _, err := os.Stat(TilePath(filepath.Join(dir, FilesDir), tile.level, tile.col, tile.row)); err != nil {
+			t.Errorf("missing tile %+v: %v", tile, err)
+		}
+	}
+
+	if _, err := os.Stat(TilePath(filepath.Join(dir, FilesDir), 10, 3, 0)); err == nil {
+		t.Error("unexpected tile beyond image width")
+	}
+	if _, err := os.Stat(dir + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("working directory left behind: %v", err)
+	}
+}
@@ -3,9 +3,26 @@ package utils
 import (
 	"log/slog"
 	"os"
+	"strconv"
 )
 
 func ExitOnError(msg string, err error) {
 	slog.Error(msg, "err", err)
 	os.Exit(1)
 }
+
+// GetEnvInt reads an integer environment variable, returning fallback when unset or invalid
+func GetEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid integer environment variable, using default", "key", key, "value", value, "default", fallback)
+		return fallback
+	}
+
+	return parsed
+}
@@ -0,0 +1,42 @@
+package utils
+
+import "bytes"
+
+// SniffLength is how many leading bytes SniffImageType needs
+const SniffLength = 16
+
+// imageSignatures are the magic numbers of the formats uploads may be in.
+// net/http's DetectContentType misses TIFF and JPEG 2000, which archives use most.
+var imageSignatures = []struct {
+	prefix      []byte
+	offset      int
+	contentType string
+	ext         string
+}{
+	{prefix: []byte("\xFF\xD8\xFF"), contentType: "image/jpeg", ext: ".jpg"},
+	{prefix: []byte("\x89PNG\r\n\x1A\n"), contentType: "image/png", ext: ".png"},
+	{prefix: []byte("GIF87a"), contentType: "image/gif", ext: ".gif"},
+	{prefix: []byte("GIF89a"), contentType: "image/gif", ext: ".gif"},
+	{prefix: []byte("WEBP"), offset: 8, contentType: "image/webp", ext: ".webp"},
+	{prefix: []byte("BM"), contentType: "image/bmp", ext: ".bmp"},
+	{prefix: []byte("II*\x00"), contentType: "image/tiff", ext: ".tif"},
+	{prefix: []byte("MM\x00*"), contentType: "image/tiff", ext: ".tif"},
+	{prefix: []byte("\x00\x00\x00\x0CjP  \r\n\x87\n"), contentType: "image/jp2", ext: ".jp2"},
+	{prefix: []byte("\xFF\x4F\xFF\x51"), contentType: "image/jp2", ext: ".j2k"},
+	{prefix: []byte("%PDF-"), contentType: "application/pdf", ext: ".pdf"},
+}
+
+// SniffImageType identifies an image or PDF from its first bytes, returning its
+// content type and the extension it should be stored with
+func SniffImageType(head []byte) (contentType, ext string, ok bool) {
+	for _, signature := range imageSignatures {
+		if len(head) >= signature.offset+len(signature.prefix) &&
+			bytes.Equal(head[signature.offset:signature.offset+len(signature.prefix)], signature.prefix) {
+			if signature.contentType == "image/webp" && !bytes.HasPrefix(head, []byte("RIFF")) {
+				continue
+			}
+			return signature.contentType, signature.ext, true
+		}
+	}
+	return "", "", false
+}
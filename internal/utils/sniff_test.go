@@ -0,0 +1,31 @@
+package utils
+
+import "testing"
+
+func TestSniffImageType(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+		ok   bool
+	}{
+		{"jpeg", "\xFF\xD8\xFF\xE0\x00\x10JFIF", "image/jpeg", true},
+		{"png", "\x89PNG\r\n\x1A\n\x00\x00\x00\rIHDR", "image/png", true},
+		{"little endian tiff", "II*\x00\x08\x00\x00\x00", "image/tiff", true},
+		{"big endian tiff", "MM\x00*\x00\x00\x00\x08", "image/tiff", true},
+		{"jp2", "\x00\x00\x00\x0CjP  \r\n\x87\n\x00\x00", "image/jp2", true},
+		{"webp", "RIFF\x24\x00\x00\x00WEBPVP8 ", "image/webp", true},
+		{"wav is not webp", "RIFF\x24\x00\x00\x00WAVEfmt ", "", false},
+		{"pdf", "%PDF-1.7\n", "application/pdf", true},
+		{"html", "<!DOCTYPE html>", "", false},
+		{"zip", "PK\x03\x04", "", false},
+		{"empty", "", "", false},
+	}
+
+	for _, test := range tests {
+		contentType, _, ok := SniffImageType([]byte(test.head))
+		if contentType != test.want || ok != test.ok {
+			t.Errorf("%s: got %q %v, want %q %v", test.name, contentType, ok, test.want, test.ok)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Package webhook signs and verifies webhook payloads with HMAC-SHA256.
+//
+// The signature covers the timestamp and body as "{timestamp}.{body}" and is sent as
+//
+//	X-Hocredit-Timestamp: 1717171717
+//	X-Hocredit-Signature: sha256=<hex>
+//
+// so a captured request can't be replayed outside the allowed clock skew.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Hocredit-Signature"
+	TimestampHeader = "X-Hocredit-Timestamp"
+
+	// MaxSkew is how far a timestamp may be from the receiver's clock
+	MaxSkew = 5 * time.Minute
+)
+
+// Sign returns the signature header value for a body sent at the given time
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp.Unix())
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and timestamp headers against the body
+func Verify(secret, signature, timestamp string, body []byte, now time.Time) error {
+	if secret == "" {
+		return fmt.Errorf("webhook secret not configured")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s", TimestampHeader)
+	}
+	sent := time.Unix(seconds, 0)
+	if now.Sub(sent) > MaxSkew || sent.Sub(now) > MaxSkew {
+		return fmt.Errorf("timestamp outside allowed skew")
+	}
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		return fmt.Errorf("invalid %s", SignatureHeader)
+	}
+
+	expected := Sign(secret, sent, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("signature mismatch")
+	}
+
+	return nil
+}
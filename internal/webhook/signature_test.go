@@ -0,0 +1,39 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"status":"completed"}`)
+	signature := Sign("s3cret", now, body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	if err := Verify("s3cret", signature, timestamp, body, now.Add(time.Minute)); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		now       time.Time
+	}{
+		{"wrong secret", "other", signature, timestamp, body, now},
+		{"tampered body", "s3cret", signature, timestamp, []byte(`{"status":"failed"}`), now},
+		{"replayed", "s3cret", signature, timestamp, body, now.Add(10 * time.Minute)},
+		{"missing timestamp", "s3cret", signature, "", body, now},
+		{"no secret configured", "", signature, timestamp, body, now},
+	}
+
+	for _, tt := range tests {
+		if err := Verify(tt.secret, tt.signature, tt.timestamp, tt.body, tt.now); err == nil {
+			t.Errorf("%s: expected rejection", tt.name)
+		}
+	}
+}
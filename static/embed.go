@@ -0,0 +1,10 @@
+// Package static holds the editor's front-end, embedded so the binary serves
+// it without a static directory beside it
+package static
+
+import "embed"
+
+// Files are the editor's pages, scripts and styles
+//
+//go:embed *.html *.js *.css
+var Files embed.FS
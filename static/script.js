@@ -14,6 +14,27 @@ let currentLineWords = [];
 let currentLineId = null;
 let allLines = [];
 let currentLineIndex = -1;
+let lineErrorRates = {};
+
+// Live updates from other editors of the same session
+const clientId =
+  window.crypto && crypto.randomUUID
+    ? crypto.randomUUID()
+    : String(Date.now()) + Math.random();
+let liveSocket = null;
+
+// Edits are saved as drafts shortly after they're made; only "Save & Next"
+// marks an image completed
+const DRAFT_SAVE_DELAY_MS = 2000;
+let draftSaveTimer = null;
+
+// Suggestions for words the spellchecker flags, by word id
+let misspellings = {};
+
+// The page shown is a Deep Zoom level of the upload composed into an object URL,
+// revoked when the next page replaces it
+let tiledImageURL = null;
+let imageLoadToken = 0;
 
 // Drawing mode state
 let drawingMode = false;
@@ -35,8 +56,65 @@ document.addEventListener("DOMContentLoaded", function () {
   } else {
     loadSessions();
   }
+  loadConfig();
 });
 
+// loadConfig offers only the OCR engines this deployment can run, so sites
+// without an LLM never see modes that would fail
+async function loadConfig() {
+  try {
+    const response = await fetch("api/v1/config");
+    if (!response.ok) return;
+    const config = await response.json();
+
+    const select = document.getElementById("engine-select");
+    if (!select) return;
+    select.innerHTML = "";
+    for (const engine of config.engines) {
+      if (!engine.available) continue;
+      const option = document.createElement("option");
+      option.value = engine.name;
+      option.textContent = engine.description;
+      option.selected = engine.name === config.default_engine;
+      select.appendChild(option);
+    }
+
+    let note = config.llm_enabled ? "" : "LLM transcription is not configured on this server.";
+    if (config.sandbox) {
+      note = `Demo instance: up to ${config.sandbox.max_pages} pages per session, and all data is deleted nightly.`;
+    }
+    document.getElementById("profile-note").textContent = note;
+  } catch (error) {
+    console.warn("Unable to load server config", error);
+  }
+}
+
+function selectedEngine() {
+  const select = document.getElementById("engine-select");
+  return select ? select.value : "";
+}
+
+function selectedLanguage() {
+  const input = document.getElementById("language-input");
+  return input ? input.value.trim() : "";
+}
+
+function selectedDocumentType() {
+  const select = document.getElementById("document-type-select");
+  return select ? select.value : "";
+}
+
+// selectedCharacters is the characters the text is limited to, as the API's
+// characters object
+function selectedCharacters() {
+  const allowed = document.getElementById("allowed-characters-input");
+  const forbidden = document.getElementById("forbidden-characters-input");
+  return {
+    allowed: allowed ? allowed.value.trim() : "",
+    forbidden: forbidden ? forbidden.value.trim() : "",
+  };
+}
+
 document.addEventListener("keydown", function (e) {
   // Only handle navigation when correction interface is visible
   if (
@@ -206,8 +284,9 @@ function endDrawing(e) {
 
   // Store the bounding box coordinates
   const img = document.getElementById("current-image");
-  const scaleX = img.naturalWidth / img.clientWidth;
-  const scaleY = img.naturalHeight / img.clientHeight;
+  const size = pageSize(img);
+  const scaleX = size.width / img.clientWidth;
+  const scaleY = size.height / img.clientHeight;
 
   const left = parseFloat(currentDrawingBox.style.left);
   const top = parseFloat(currentDrawingBox.style.top);
@@ -558,7 +637,7 @@ function applyWordChanges() {
 
 async function loadSessions() {
   try {
-    const response = await fetch("api/sessions");
+    const response = await fetch("api/v1/sessions");
     const sessions = await response.json();
     displaySessions(sessions);
   } catch (error) {
@@ -600,6 +679,10 @@ async function handleUpload() {
     return;
   }
 
+  const engine = selectedEngine();
+  const language = selectedLanguage();
+  const documentType = selectedDocumentType();
+  const characters = selectedCharacters();
   const uploadArea = document.getElementById("upload-area");
   uploadArea.innerHTML =
     "<h3>Processing files...</h3><p>Please wait while files are uploaded and processed with OCR.</p>";
@@ -608,19 +691,42 @@ async function handleUpload() {
   for (let file of files) {
     formData.append("files", file);
   }
+  if (engine) {
+    formData.append("engine", engine);
+  }
+  if (language) {
+    formData.append("language", language);
+  }
+  if (documentType) {
+    formData.append("document_type", documentType);
+  }
+  if (characters.allowed) {
+    formData.append("allowed_characters", characters.allowed);
+  }
+  if (characters.forbidden) {
+    formData.append("forbidden_characters", characters.forbidden);
+  }
+
+  // Several files are queued as one batch and become pages of a single session
+  const batch = files.length > 1;
+  if (batch) {
+    formData.append("mode", "single");
+  }
 
   try {
-    const response = await fetch("api/upload", {
+    const response = await fetch(batch ? "api/v1/upload/batch" : "api/v1/upload", {
       method: "POST",
       body: formData,
     });
 
-    const result = await response.json();
-
     if (!response.ok) {
-      throw new Error(result.error || "Upload failed");
+      throw new Error((await response.text()) || "Upload failed");
     }
 
+    const accepted = await response.json();
+    const jobId = batch ? accepted.sessions[0].job_id : accepted.job_id;
+    const result = await waitForJob(jobId);
+
     if (result.session_id) {
       console.log("Upload successful:", result.message);
       loadSession(result.session_id);
@@ -651,27 +757,35 @@ async function handleUrlUpload() {
     return;
   }
 
+  const engine = selectedEngine();
+  const language = selectedLanguage();
+  const documentType = selectedDocumentType();
+  const characters = selectedCharacters();
   const uploadArea = document.getElementById("upload-area");
   uploadArea.innerHTML =
     "<h3>Processing image URL...</h3><p>Please wait while the image is downloaded and processed with OCR.</p>";
 
   try {
-    const response = await fetch("api/upload", {
+    const response = await fetch("api/v1/upload", {
       method: "POST",
       headers: {
         "Content-Type": "application/json",
       },
       body: JSON.stringify({
         image_url: imageUrl,
+        engine: engine,
+        language: language,
+        document_type: documentType,
+        characters: characters,
       }),
     });
 
-    const result = await response.json();
-
     if (!response.ok) {
-      throw new Error(result.error || "URL processing failed");
+      throw new Error((await response.text()) || "URL processing failed");
     }
 
+    const result = await waitForJob((await response.json()).job_id);
+
     if (result.session_id) {
       console.log("URL processing successful:", result.message);
       loadSession(result.session_id);
@@ -685,6 +799,33 @@ async function handleUrlUpload() {
   }
 }
 
+// Polls a background job until it finishes, resolving with its result
+async function waitForJob(jobId) {
+  if (!jobId) {
+    throw new Error("No job ID received");
+  }
+
+  for (;;) {
+    const response = await fetch(`api/v1/jobs/${encodeURIComponent(jobId)}`);
+    if (!response.ok) {
+      throw new Error((await response.text()) || "Unable to check job status");
+    }
+
+    const job = await response.json();
+    if (job.status === "succeeded") {
+      return job.result || {};
+    }
+    if (job.status === "failed") {
+      const details = job.diagnostics
+        ? `\n\nDiagnostics for support: ${job.diagnostics}`
+        : "";
+      throw new Error((job.error || "Processing failed") + details);
+    }
+
+    await new Promise((resolve) => setTimeout(resolve, 1000));
+  }
+}
+
 function resetUploadArea() {
   const uploadArea = document.getElementById("upload-area");
   uploadArea.innerHTML = `
@@ -737,18 +878,17 @@ async function saveToIslandora() {
   button.disabled = true;
 
   try {
-    const drupalUploadURL = currentSession.images[0].drupal_upload_url;
-    const response = await fetch(drupalUploadURL, {
-      method: "POST",
-      headers: {
-        "Content-Type": "text/vnd.hocr+html",
-        "Content-Location":
-          "private://derivatives/hocr/gcloud/" +
-          currentSession.images[0].drupal_nid +
-          ".hocr",
-      },
-      body: hocrData,
-    });
+    const response = await fetch(
+      "api/v1/sessions/" + currentSession.id + "/publish",
+      {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          image_id: currentSession.images[0].id,
+          hocr: hocrData,
+        }),
+      }
+    );
 
     if (!response.ok) {
       const errorText = await response.text();
@@ -779,7 +919,7 @@ function getCurrentHOCR() {
   return currentImage.corrected_hocr || currentImage.original_hocr;
 }
 
-function checkForDrupalSession() {
+async function checkForDrupalSession() {
   const button = document.getElementById("save-islandora-btn");
   if (!button) return;
 
@@ -789,18 +929,36 @@ function checkForDrupalSession() {
     currentSession.images.length > 0 &&
     currentSession.images[0].drupal_upload_url;
 
-  if (isDrupalSession) {
+  if (isDrupalSession && (await canPerform("can_publish_drupal"))) {
     button.classList.remove("hidden");
   } else {
     button.classList.add("hidden");
   }
 }
 
+// canPerform asks the server whether the current user holds a permission for
+// the open session's collection
+async function canPerform(permission) {
+  try {
+    const collection = (currentSession && currentSession.collection) || "";
+    const response = await fetch(
+      "api/v1/permissions?collection=" + encodeURIComponent(collection)
+    );
+    if (!response.ok) return true;
+    const result = await response.json();
+    return result.permissions[permission] !== false;
+  } catch (error) {
+    console.warn("Unable to load permissions", error);
+    return true;
+  }
+}
+
 async function loadSession(sessionId) {
   try {
-    const response = await fetch("api/sessions/" + sessionId);
+    const response = await fetch("api/v1/sessions/" + sessionId);
     currentSession = await response.json();
     currentImageIndex = currentSession.current || 0;
+    connectLiveUpdates(currentSession.id);
     showCorrectionInterface();
     loadCurrentImage();
   } catch (error) {
@@ -808,6 +966,94 @@ async function loadSession(sessionId) {
   }
 }
 
+// ============================================================================
+// LIVE UPDATES
+// ============================================================================
+
+// Subscribes to saves and metrics for a session, reconnecting if the link drops
+function connectLiveUpdates(sessionId) {
+  if (liveSocket) {
+    liveSocket.onclose = null;
+    liveSocket.close();
+  }
+
+  const url = new URL("ws", window.location.href);
+  url.protocol = url.protocol === "https:" ? "wss:" : "ws:";
+  url.searchParams.set("session", sessionId);
+  url.searchParams.set("client", clientId);
+
+  liveSocket = new WebSocket(url);
+  liveSocket.onmessage = (message) => handleLiveEvent(JSON.parse(message.data));
+  liveSocket.onclose = () => {
+    setTimeout(() => {
+      if (currentSession && currentSession.id === sessionId) {
+        connectLiveUpdates(sessionId);
+      }
+    }, 5000);
+  };
+}
+
+function handleLiveEvent(event) {
+  if (!currentSession || event.session_id !== currentSession.id) return;
+
+  const index = currentSession.images.findIndex(
+    (image) => image.id === event.image_id
+  );
+  if (index < 0) return;
+
+  if (event.type === "hocr_updated") {
+    const image = currentSession.images[index];
+    image.corrected_hocr = event.data.corrected_hocr;
+    image.original_hocr = event.data.original_hocr;
+    image.completed = event.data.completed;
+
+    if (index === currentImageIndex) {
+      console.log(`Image updated by ${event.user || "another editor"}`);
+      parseAndDisplayHOCR(image.corrected_hocr || image.original_hocr);
+    }
+  } else if (event.type === "metrics" && index === currentImageIndex) {
+    showAccuracyMetrics(event.data);
+  }
+}
+
+function showAccuracyMetrics(metrics) {
+  document.getElementById("char-similarity").textContent =
+    metrics.character_similarity.toFixed(3);
+  document.getElementById("word-accuracy").textContent =
+    metrics.word_accuracy.toFixed(3);
+  document.getElementById("word-error-rate").textContent =
+    metrics.word_error_rate.toFixed(3);
+  document.getElementById("char-error-rate").textContent =
+    metrics.character_error_rate.toFixed(3);
+
+  // Lines are keyed by their id in the corrected hOCR, which is what the overlay shows
+  lineErrorRates = {};
+  (metrics.lines || []).forEach((line) => {
+    const id = line.corrected_id || line.id;
+    if (id) {
+      lineErrorRates[id] = line.character_error_rate;
+    }
+  });
+  applyLineHeatMap();
+}
+
+function applyLineHeatMap() {
+  document.querySelectorAll(".hocr-line-box").forEach((box) => {
+    const rate = lineErrorRates[box.getAttribute("data-line-id")];
+    if (box.dataset.baseTitle === undefined) {
+      box.dataset.baseTitle = box.title;
+    }
+    if (typeof rate === "number" && rate > 0) {
+      const alpha = Math.min(rate, 1) * 0.35;
+      box.style.backgroundColor = `rgba(239, 68, 68, ${alpha.toFixed(2)})`;
+      box.title = `${box.dataset.baseTitle} (CER: ${rate.toFixed(3)})`;
+    } else {
+      box.style.backgroundColor = "";
+      box.title = box.dataset.baseTitle;
+    }
+  });
+}
+
 function showCorrectionInterface() {
   document.getElementById("upload-section").classList.add("hidden");
   document.getElementById("correction-section").classList.remove("hidden");
@@ -837,7 +1083,106 @@ async function loadCurrentImage() {
     clearSelection();
   };
 
-  img.src = image.image_url || "/static/uploads/" + image.image_path;
+  const token = ++imageLoadToken;
+  delete img.dataset.pageWidth;
+  delete img.dataset.pageHeight;
+  if (image.image_path && (await loadTiledImage(img, image, token))) {
+    return;
+  }
+  if (token === imageLoadToken) {
+    img.src = image.image_url || "/static/uploads/" + image.image_path;
+  }
+}
+
+// ============================================================================
+// DEEP ZOOM TILES
+// ============================================================================
+
+// pageSize is the size of the page the hOCR describes, which the image shown
+// may be a smaller Deep Zoom level of
+function pageSize(img) {
+  return {
+    width: Number(img.dataset.pageWidth) || img.naturalWidth,
+    height: Number(img.dataset.pageHeight) || img.naturalHeight,
+  };
+}
+
+// loadTiledImage shows an upload from its Deep Zoom pyramid at the smallest
+// level that fills the viewer, so a large scan isn't downloaded in full to be
+// shown a few inches wide. It reports false when the original should be
+// loaded instead: the page is no larger than the viewer, or the tiles failed.
+async function loadTiledImage(img, image, token) {
+  const container = document.getElementById("image-container");
+  const wanted = Math.ceil(container.clientWidth * (window.devicePixelRatio || 1));
+  if (!wanted || (image.image_width && image.image_width <= wanted)) {
+    return false;
+  }
+
+  const hash = image.image_path.replace(/\.[^.]*$/, "");
+  try {
+    const response = await fetch(`api/v1/tiles/${hash}.dzi`);
+    if (!response.ok) {
+      throw new Error(`HTTP ${response.status}`);
+    }
+    const dzi = new DOMParser().parseFromString(await response.text(), "application/xml");
+    const descriptor = dzi.querySelector("Image");
+    const size = dzi.querySelector("Size");
+    const tileSize = Number(descriptor.getAttribute("TileSize"));
+    const overlap = Number(descriptor.getAttribute("Overlap"));
+    const format = descriptor.getAttribute("Format");
+    const width = Number(size.getAttribute("Width"));
+    const height = Number(size.getAttribute("Height"));
+
+    // Each level halves the one above it, rounding odd sizes up
+    let level = Math.ceil(Math.log2(Math.max(width, height, 1)));
+    let levelWidth = width;
+    let levelHeight = height;
+    while (level > 0 && Math.ceil(levelWidth / 2) >= wanted) {
+      level--;
+      levelWidth = Math.ceil(levelWidth / 2);
+      levelHeight = Math.ceil(levelHeight / 2);
+    }
+    if (levelWidth === width) {
+      return false;
+    }
+
+    const canvas = document.createElement("canvas");
+    canvas.width = levelWidth;
+    canvas.height = levelHeight;
+    const context = canvas.getContext("2d");
+    const tiles = [];
+    for (let row = 0; row * tileSize < levelHeight; row++) {
+      for (let col = 0; col * tileSize < levelWidth; col++) {
+        const tile = new Image();
+        tile.src = `api/v1/tiles/${hash}_files/${level}/${col}_${row}.${format}`;
+        tiles.push(
+          tile.decode().then(() => {
+            // Tiles carry the overlap on their interior edges
+            const x = col === 0 ? 0 : col * tileSize - overlap;
+            const y = row === 0 ? 0 : row * tileSize - overlap;
+            context.drawImage(tile, x, y);
+          }),
+        );
+      }
+    }
+    await Promise.all(tiles);
+
+    const blob = await new Promise((resolve) => canvas.toBlob(resolve, "image/jpeg", 0.9));
+    if (!blob || token !== imageLoadToken) {
+      return token !== imageLoadToken;
+    }
+    if (tiledImageURL) {
+      URL.revokeObjectURL(tiledImageURL);
+    }
+    tiledImageURL = URL.createObjectURL(blob);
+    img.dataset.pageWidth = width;
+    img.dataset.pageHeight = height;
+    img.src = tiledImageURL;
+    return true;
+  } catch (error) {
+    console.warn("Unable to load Deep Zoom tiles, loading the original image:", error);
+    return false;
+  }
 }
 
 // ============================================================================
@@ -846,7 +1191,7 @@ async function loadCurrentImage() {
 
 async function parseAndDisplayHOCR(hocrXML) {
   try {
-    const response = await fetch("api/hocr/parse", {
+    const response = await fetch("api/v1/hocr/parse", {
       method: "POST",
       headers: { "Content-Type": "application/json" },
       body: JSON.stringify({ hocr: hocrXML }),
@@ -860,6 +1205,7 @@ async function parseAndDisplayHOCR(hocrXML) {
 
     renderHOCROverlay();
     updateWordCounter();
+    checkSpelling();
   } catch (error) {
     console.error("Error parsing hOCR:", error);
   }
@@ -922,8 +1268,9 @@ function renderHOCROverlay() {
     return;
   }
 
-  const scaleX = img.clientWidth / img.naturalWidth;
-  const scaleY = img.clientHeight / img.naturalHeight;
+  const size = pageSize(img);
+  const scaleX = img.clientWidth / size.width;
+  const scaleY = img.clientHeight / size.height;
 
   updateLineData();
   allLines.forEach((line, lineIndex) => {
@@ -957,6 +1304,21 @@ function renderHOCROverlay() {
 
     overlay.appendChild(lineBox);
   });
+  applyLineHeatMap();
+  applyMisspellings();
+}
+
+// applyMisspellings marks the lines holding words the spellchecker flagged
+function applyMisspellings() {
+  allLines.forEach((line) => {
+    const box = document.getElementById("line-box-" + line.id);
+    if (box) {
+      box.classList.toggle(
+        "has-misspellings",
+        line.words.some((w) => misspellings[w.id])
+      );
+    }
+  });
 }
 
 function calculateLineBoundingBox(words) {
@@ -1178,6 +1540,12 @@ function displayLineEditor(selectedWord) {
     }
 
     button.onclick = () => selectWordInLine(word.id);
+    if (misspellings[word.id]) {
+      button.classList.add("misspelled");
+      button.title = misspellings[word.id].length
+        ? "Did you mean: " + misspellings[word.id].join(", ")
+        : "Not in the dictionary";
+    }
 
     const textSpan = document.createElement("span");
     textSpan.textContent = word.text;
@@ -1313,16 +1681,40 @@ function updateHOCRSource() {
   // Update session data
   if (currentSession && currentSession.images[currentImageIndex]) {
     currentSession.images[currentImageIndex].corrected_hocr = hocr;
+    scheduleDraftSave();
   }
 }
 
+// textDirection is "rtl" when most letters of text are Hebrew or Arabic
+// script, "ltr" when most are of other scripts, and "" without letters
+function textDirection(text) {
+  const rtl = (text.match(/[\u0590-\u08FF\uFB1D-\uFDFF\uFE70-\uFEFF]/g) || []).length;
+  const letters = (text.match(/\p{L}/gu) || []).length;
+  if (letters === 0) return "";
+  return rtl > letters - rtl ? "rtl" : "ltr";
+}
+
+// documentAttributes keeps the lang and dir the server marked the page with
+function documentAttributes() {
+  const image = currentSession.images[currentImageIndex];
+  const match = /<html[^>]*?(\s+xml:lang="[^"]*"\s+lang="[^"]*"(?:\s+dir="rtl")?)/.exec(
+    image.original_hocr || "",
+  );
+  return match ? match[1] : ' xml:lang="en" lang="en"';
+}
+
 function generateHOCRXML(data) {
   // Basic hOCR XML generation
+  const attributes = documentAttributes();
+  // Words in another language than the page's are marked with theirs
+  const documentLanguage = (/\slang="([^"]*)"/.exec(attributes) || [])[1];
+  const pageDirection = attributes.includes('dir="rtl"')
+    ? "rtl"
+    : textDirection(data.words.map((w) => w.text).join(""));
   let xml = '<?xml version="1.0" encoding="UTF-8"?>\n';
   xml +=
     '<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">\n';
-  xml +=
-    '<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en">\n';
+  xml += '<html xmlns="http://www.w3.org/1999/xhtml"' + attributes + ">\n";
   xml += "<head>\n<title></title>\n</head>\n<body>\n";
 
   xml +=
@@ -1332,14 +1724,15 @@ function generateHOCRXML(data) {
     (currentSession.images[currentImageIndex].image_height || 1000) +
     '">\n';
 
-  // Sort words by reading order (top to bottom, left to right)
+  // Sort words by reading order (top to bottom, then across in the page's
+  // direction)
   const sortedWords = [...data.words].sort((a, b) => {
     const yDiff = a.bbox[1] - b.bbox[1];
     if (Math.abs(yDiff) > 10) {
       // Allow some tolerance for same line
       return yDiff;
     }
-    return a.bbox[0] - b.bbox[0];
+    return pageDirection === "rtl" ? b.bbox[2] - a.bbox[2] : a.bbox[0] - b.bbox[0];
   });
 
   // Group words by line_id, but ensure each line has proper structure
@@ -1363,8 +1756,15 @@ function generateHOCRXML(data) {
     const words = lineGroups[lineId];
     if (words.length === 0) return;
 
-    // Sort words within line by X position
-    words.sort((a, b) => a.bbox[0] - b.bbox[0]);
+    // Sort words within line by X position, from the right when it's read
+    // right to left
+    const lineDirection =
+      textDirection(words.map((w) => w.text).join("")) || pageDirection;
+    if (lineDirection === "rtl") {
+      words.sort((a, b) => b.bbox[2] - a.bbox[2]);
+    } else {
+      words.sort((a, b) => a.bbox[0] - b.bbox[0]);
+    }
 
     // For proper hOCR structure: each word should be in its own line
     words.forEach((word) => {
@@ -1386,7 +1786,14 @@ function generateHOCRXML(data) {
       const currentWordId = `word_${wordCounter}`;
 
       // Generate line with single word
-      xml += `  <span class="ocr_line" id="${currentLineId}" title="bbox ${x1} ${y1} ${x2} ${y2}">\n`;
+      const direction = textDirection(word.text) || lineDirection;
+      const dir =
+        direction === "rtl" || pageDirection === "rtl" ? ` dir="${direction}"` : "";
+      const lang =
+        word.language && word.language !== documentLanguage
+          ? ` lang="${escapeXML(word.language)}"`
+          : "";
+      xml += `  <span class="ocr_line" id="${currentLineId}" title="bbox ${x1} ${y1} ${x2} ${y2}"${dir}${lang}>\n`;
       xml += `    <span class="ocrx_word" id="${currentWordId}" title="bbox ${x1} ${y1} ${x2} ${y2}; x_wconf ${
         word.confidence || 95
       }">${escapeXML(word.text)}</span>\n`;
@@ -1432,24 +1839,21 @@ async function updateMetrics() {
 
   try {
     const response = await fetch(
-      "api/sessions/" + currentSession.id + "/metrics",
+      "api/v1/sessions/" + currentSession.id + "/metrics",
       {
         method: "POST",
         headers: { "Content-Type": "application/json" },
         body: JSON.stringify({
           original: originalText,
           corrected: correctedText,
+          original_hocr: currentSession.images[currentImageIndex].original_hocr,
+          corrected_hocr: generateHOCRXML(hocrData),
         }),
       }
     );
     const metrics = await response.json();
 
-    document.getElementById("char-similarity").textContent =
-      metrics.character_similarity.toFixed(3);
-    document.getElementById("word-accuracy").textContent =
-      metrics.word_accuracy.toFixed(3);
-    document.getElementById("word-error-rate").textContent =
-      metrics.word_error_rate.toFixed(3);
+    showAccuracyMetrics(metrics);
     document.getElementById("total-words").textContent = hocrData.words.length;
 
     // Calculate confidence metrics
@@ -1480,6 +1884,8 @@ function extractTextFromHOCR(hocrXML) {
 }
 
 async function saveAndNext() {
+  clearTimeout(draftSaveTimer);
+  draftSaveTimer = null;
   const hocrXML = generateHOCRXML(hocrData);
   currentSession.images[currentImageIndex].corrected_hocr = hocrXML;
   currentSession.images[currentImageIndex].completed = true;
@@ -1498,6 +1904,8 @@ async function saveAndNext() {
 
 function previousImage() {
   if (currentImageIndex > 0) {
+    // Pending edits belong to the image being left
+    if (draftSaveTimer) saveDraft();
     currentImageIndex--;
     resetNavigationState();
     allLines = [];
@@ -1509,9 +1917,9 @@ function previousImage() {
 
 async function saveSession() {
   try {
-    await fetch("api/sessions/" + currentSession.id, {
+    await fetch("api/v1/sessions/" + currentSession.id, {
       method: "PUT",
-      headers: { "Content-Type": "application/json" },
+      headers: { "Content-Type": "application/json", "X-Client-ID": clientId },
       body: JSON.stringify(currentSession),
     });
   } catch (error) {
@@ -1519,6 +1927,71 @@ async function saveSession() {
   }
 }
 
+// scheduleDraftSave saves the current image's hOCR once edits pause, without
+// marking it completed
+function scheduleDraftSave() {
+  clearTimeout(draftSaveTimer);
+  draftSaveTimer = setTimeout(saveDraft, DRAFT_SAVE_DELAY_MS);
+}
+
+async function saveDraft() {
+  clearTimeout(draftSaveTimer);
+  draftSaveTimer = null;
+  const image = currentSession && currentSession.images[currentImageIndex];
+  if (!image || !image.corrected_hocr) return;
+  checkSpelling();
+
+  try {
+    await fetch("api/v1/hocr/update", {
+      method: "POST",
+      headers: { "Content-Type": "application/json", "X-Client-ID": clientId },
+      body: JSON.stringify({
+        session_id: currentSession.id,
+        image_id: image.id,
+        hocr: image.corrected_hocr,
+        draft: true,
+      }),
+    });
+  } catch (error) {
+    console.error("Error saving draft:", error);
+  }
+}
+
+// checkSpelling flags the current words missing from the dictionary and the
+// session's lexicons, so probable OCR errors stand out
+async function checkSpelling() {
+  if (!hocrData || !hocrData.words || !currentSession) return;
+
+  const words = hocrData.words;
+  try {
+    const response = await fetch("api/v1/spellcheck", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        session_id: currentSession.id,
+        words: words.map((w) => w.text),
+      }),
+    });
+    if (!response.ok) return;
+    const result = await response.json();
+
+    misspellings = {};
+    result.flagged.forEach((flag) => {
+      const word = words[flag.index];
+      if (word) {
+        misspellings[word.id] = flag.suggestions;
+      }
+    });
+    applyMisspellings();
+    const selectedWord = hocrData.words.find((w) => w.id === selectedWordId);
+    if (selectedWord) {
+      displayLineEditor(selectedWord);
+    }
+  } catch (error) {
+    console.error("Error checking spelling:", error);
+  }
+}
+
 async function finishSession() {
   await saveSession();
   alert("Session completed! hOCR corrections have been saved.");